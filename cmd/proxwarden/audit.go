@@ -0,0 +1,116 @@
+package proxwarden
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/audit"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Show the actions taken on the cluster",
+	Long: `Show every stop, start, migration, backup, restore and hook run by the daemon
+or CLI commands, and every change requested through the daemon API, newest first,
+with who took it and the outcome, as recorded in the audit log.`,
+	RunE: runAudit,
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+
+	auditCmd.Flags().Int("container", 0, "only show actions on this container")
+	auditCmd.Flags().String("action", "", "only show this action: start, stop, migrate, backup, restore, delete_backup, protect_backup, hook or api_request")
+	auditCmd.Flags().String("actor", "", "only show actions of this actor, such as auto or user:root")
+	auditCmd.Flags().Duration("since", 0, "only show actions taken within this duration")
+	auditCmd.Flags().Int("limit", 50, "maximum number of actions shown (0 for all)")
+	addOutputFlags(auditCmd)
+}
+
+// loadConfig loads the configuration and records the actions the command
+// takes on the cluster in the audit log, as taken by the user running it.
+func loadConfig() (*config.Config, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := audit.OpenConfig(cfg, audit.UserActor(), logrus.StandardLogger()); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func runAudit(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	log, err := audit.OpenLog(cfg)
+	if err != nil {
+		return err
+	}
+	if log == nil {
+		return fmt.Errorf("the audit log is disabled: set audit.enabled")
+	}
+
+	filter := audit.Filter{}
+	filter.ContainerID, _ = cmd.Flags().GetInt("container")
+	filter.Action, _ = cmd.Flags().GetString("action")
+	filter.Actor, _ = cmd.Flags().GetString("actor")
+	filter.Limit, _ = cmd.Flags().GetInt("limit")
+	if since, _ := cmd.Flags().GetDuration("since"); since > 0 {
+		filter.Since = time.Now().Add(-since)
+	}
+
+	entries, err := log.List(filter)
+	if err != nil {
+		return err
+	}
+
+	format := outputOf(cmd)
+	if format != outputTable {
+		return printStructured(format, entries)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No actions recorded")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tACTOR\tACTION\tCONTAINER\tNODE\tPARAMETERS\tDURATION\tRESULT")
+	fmt.Fprintln(w, "----\t-----\t------\t---------\t----\t----------\t--------\t------")
+
+	for _, entry := range entries {
+		container := "-"
+		if entry.ContainerID != 0 {
+			container = fmt.Sprint(entry.ContainerID)
+		}
+		result := "success"
+		if !entry.Success {
+			result = "failed: " + entry.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.Time.Format(time.RFC3339), entry.Actor, entry.Action, container,
+			entry.Node, formatParameters(entry.Parameters), entry.Duration.Round(time.Millisecond), result)
+	}
+
+	return w.Flush()
+}
+
+// formatParameters lists parameters as name=value pairs, sorted by name.
+func formatParameters(parameters map[string]string) string {
+	pairs := make([]string, 0, len(parameters))
+	for name, value := range parameters {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
@@ -2,13 +2,16 @@ package proxwarden
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/backup"
+	"github.com/jbutlerdev/proxwarden/internal/catalog"
 	"github.com/jbutlerdev/proxwarden/internal/config"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -40,20 +43,76 @@ var backupRestoreCmd = &cobra.Command{
 	RunE:  runBackupRestore,
 }
 
+var backupPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete backups outside the retention",
+	Long: `Delete backups of monitored containers that are older than retention_days
+and not among the keep_last newest. The newest backup of a container, and
+protected and pinned backups, are always kept.`,
+	RunE: runBackupPrune,
+}
+
+var backupProtectCmd = &cobra.Command{
+	Use:   "protect [backup-path]",
+	Short: "Protect a backup from deletion",
+	Long: `Protect a backup in Proxmox, so it is never pruned or deleted until it is
+unprotected.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBackupProtect,
+}
+
+var backupUnprotectCmd = &cobra.Command{
+	Use:   "unprotect [backup-path]",
+	Short: "Lift the protection of a backup",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runBackupProtect,
+}
+
+var backupPinCmd = &cobra.Command{
+	Use:   "pin [container-id] [backup-path]",
+	Short: "Restore this backup in failovers of a container",
+	Long: `Pin the failovers of a container to a backup, for example a known-good one
+taken before a bad deploy. Failovers restore the pinned backup instead of the
+latest one, and it is never pruned, until the container is unpinned.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runBackupPin,
+}
+
+var backupUnpinCmd = &cobra.Command{
+	Use:   "unpin [container-id]",
+	Short: "Restore the latest backup in failovers of a container again",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runBackupUnpin,
+}
+
 func init() {
 	rootCmd.AddCommand(backupCmd)
 	backupCmd.AddCommand(backupCreateCmd)
 	backupCmd.AddCommand(backupListCmd)
 	backupCmd.AddCommand(backupRestoreCmd)
+	backupCmd.AddCommand(backupPruneCmd)
+	backupCmd.AddCommand(backupProtectCmd)
+	backupCmd.AddCommand(backupUnprotectCmd)
+	backupCmd.AddCommand(backupPinCmd)
+	backupCmd.AddCommand(backupUnpinCmd)
 
 	backupCreateCmd.Flags().String("storage", "", "backup storage (uses config default)")
+	backupCreateCmd.Flags().String("mode", "", "vzdump mode: snapshot, suspend or stop (uses config default)")
+	backupCreateCmd.Flags().String("compress", "", "compression: zstd, gzip, lzo or none (uses config default)")
+	backupCreateCmd.Flags().Int("bwlimit", 0, "I/O limit in KiB/s (uses config default)")
+	backupCreateCmd.Flags().Bool("no-hooks", false, "skip the configured pre- and post-backup hooks")
 	
 	backupListCmd.Flags().String("storage", "", "storage to list backups from")
-	backupListCmd.Flags().Bool("json", false, "output in JSON format")
+	addOutputFlags(backupListCmd)
+	backupListCmd.Flags().Int("container", 0, "only list backups of this container")
 	
 	backupRestoreCmd.Flags().String("target-node", "", "target node for restore")
 	backupRestoreCmd.Flags().String("storage", "", "storage for restored container")
 	backupRestoreCmd.Flags().Bool("force", false, "force restore (overwrite existing)")
+	addConfirmFlag(backupRestoreCmd)
+
+	backupPruneCmd.Flags().Bool("dry-run", false, "only list the backups that would be deleted")
+	addOutputFlags(backupPruneCmd)
 }
 
 func runBackupCreate(cmd *cobra.Command, args []string) error {
@@ -66,9 +125,14 @@ func runBackupCreate(cmd *cobra.Command, args []string) error {
 	}
 
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	backupCatalog, err := catalog.Open(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
+		return err
 	}
 
 	// Create API client
@@ -77,21 +141,74 @@ func runBackupCreate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create API client: %w", err)
 	}
 
+	// Back up configured containers with their own settings
+	container := config.ContainerConfig{ID: containerID}
+	for _, c := range cfg.Monitoring.Containers {
+		if c.ID == containerID {
+			container = c
+		}
+	}
+
 	storage, _ := cmd.Flags().GetString("storage")
+	if storage == "" {
+		storage = container.BackupStorage
+	}
 	if storage == "" {
 		storage = cfg.Backup.Storage
 	}
 
+	if cmd.Flags().Changed("mode") {
+		container.BackupMode, _ = cmd.Flags().GetString("mode")
+	}
+	if cmd.Flags().Changed("compress") {
+		container.BackupCompress, _ = cmd.Flags().GetString("compress")
+	}
+	if cmd.Flags().Changed("bwlimit") {
+		container.BackupBwLimit, _ = cmd.Flags().GetInt("bwlimit")
+	}
+	options := cfg.Backup.VzdumpFor(container)
+
 	logger.WithFields(logrus.Fields{
 		"container_id": containerID,
 		"storage":      storage,
+		"mode":         options.Mode,
+		"compress":     options.Compress,
 	}).Info("Creating backup")
 
-	backupPath, err := apiClient.BackupContainer(ctx, containerID, storage, cfg.Backup.BackupDir)
+	// Hooks are told the node the container is on, when it can be found
+	target := backup.Target{Container: container, Storage: storage, Origin: catalog.OriginManual}
+	if info, err := apiClient.GetContainer(ctx, containerID); err == nil {
+		target.Node = info.Node
+	}
+	if noHooks, _ := cmd.Flags().GetBool("no-hooks"); noHooks {
+		cfg.Backup.PreHooks, cfg.Backup.PostHooks = nil, nil
+		target.Container.PreBackupHooks, target.Container.PostBackupHooks = nil, nil
+	}
+
+	started := time.Now()
+	backupPath, err := backup.WithHooks(ctx, &cfg.Backup, target, logger, func(ctx context.Context) (string, error) {
+		if cfg.Backup.BackupTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, cfg.Backup.BackupTimeout)
+			defer cancel()
+		}
+		return apiClient.BackupContainer(ctx, containerID, storage, cfg.Backup.BackupDir, options)
+	})
 	if err != nil {
 		return fmt.Errorf("backup failed: %w", err)
 	}
 
+	err = backupCatalog.Record(catalog.Entry{
+		VolID:       backupPath,
+		ContainerID: containerID,
+		Storage:     storage,
+		Created:     started,
+		Origin:      catalog.OriginManual,
+	})
+	if err != nil {
+		logger.WithField("error", err).Warn("Failed to record backup in catalog")
+	}
+
 	fmt.Printf("Backup created successfully: %s\n", backupPath)
 	return nil
 }
@@ -102,9 +219,14 @@ func runBackupList(cmd *cobra.Command, args []string) error {
 	logger.SetLevel(logrus.WarnLevel) // Reduce noise
 
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := loadConfig()
 	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
+		return err
+	}
+
+	backupCatalog, err := catalog.Open(cfg)
+	if err != nil {
+		return err
 	}
 
 	// Create API client
@@ -118,31 +240,62 @@ func runBackupList(cmd *cobra.Command, args []string) error {
 		storage = cfg.Backup.Storage
 	}
 
-	jsonOutput, _ := cmd.Flags().GetBool("json")
+	format := outputOf(cmd)
+	containerID, _ := cmd.Flags().GetInt("container")
 
 	backups, err := apiClient.GetBackups(ctx, storage)
 	if err != nil {
 		return fmt.Errorf("failed to get backups: %w", err)
 	}
 
-	if jsonOutput {
-		output, err := json.MarshalIndent(backups, "", "  ")
-		if err != nil {
-			return fmt.Errorf("failed to marshal JSON: %w", err)
+	// The catalog adds where backups came from and whether they restored
+	entries, err := backupCatalog.Sync(storage, backups)
+	if err != nil {
+		logger.WithField("error", err).Warn("Failed to update backup catalog")
+		entries = catalog.Entries(backups)
+	}
+	if containerID != 0 {
+		var filtered []catalog.Entry
+		for _, entry := range entries {
+			if entry.ContainerID == containerID {
+				filtered = append(filtered, entry)
+			}
 		}
-		fmt.Println(string(output))
-		return nil
+		entries = filtered
+	}
+
+	if format != outputTable {
+		return printStructured(format, entries)
 	}
 
 	// Text output
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "NODE\tSTORAGE\tFILENAME\tSIZE\tFORMAT")
-	fmt.Fprintln(w, "----\t-------\t--------\t----\t------")
-
-	for _, backup := range backups {
-		sizeStr := fmt.Sprintf("%.2f MB", float64(backup.Size)/(1024*1024))
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
-			backup.Node, backup.Storage, backup.Filename, sizeStr, backup.Format)
+	fmt.Fprintln(w, "CONTAINER\tCREATED\tNODE\tSTORAGE\tFILENAME\tSIZE\tORIGIN\tVERIFIED\tFLAGS")
+	fmt.Fprintln(w, "---------\t-------\t----\t-------\t--------\t----\t------\t--------\t-----")
+
+	for _, entry := range entries {
+		sizeStr := fmt.Sprintf("%.2f MB", float64(entry.Size)/(1024*1024))
+		origin := entry.Origin
+		if origin == "" {
+			origin = "-"
+		}
+		verified := "no"
+		if entry.Verified() {
+			verified = entry.VerifiedAt.Format("2006-01-02 15:04")
+		}
+		var flags []string
+		if entry.Protected {
+			flags = append(flags, "protected")
+		}
+		if entry.Pinned {
+			flags = append(flags, "pinned")
+		}
+		if len(flags) == 0 {
+			flags = append(flags, "-")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.ContainerID, entry.Created.Format("2006-01-02 15:04:05"), entry.Node, entry.Storage,
+			entry.Filename, sizeStr, origin, verified, strings.Join(flags, ","))
 	}
 
 	return w.Flush()
@@ -160,9 +313,14 @@ func runBackupRestore(cmd *cobra.Command, args []string) error {
 	backupPath := args[1]
 
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := loadConfig()
 	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
+		return err
+	}
+
+	backupCatalog, err := catalog.Open(cfg)
+	if err != nil {
+		return err
 	}
 
 	// Create API client
@@ -184,12 +342,35 @@ func runBackupRestore(cmd *cobra.Command, args []string) error {
 		targetNode = nodes[0].Name
 	}
 
+	// Fit configured containers to the target node as failovers do
+	var override config.NodeOverride
+	for _, container := range cfg.Monitoring.Containers {
+		if container.ID == containerID {
+			override = container.NodeOverride(targetNode)
+		}
+	}
+
 	storage, _ := cmd.Flags().GetString("storage")
+	if storage == "" {
+		storage = override.Storage
+	}
 	if storage == "" {
 		storage = "local-lvm" // Default storage
 	}
 
 	force, _ := cmd.Flags().GetBool("force")
+	if force {
+		// Without --force Proxmox refuses to replace an existing container,
+		// so only overwriting one needs asking
+		err := confirm(cmd, fmt.Sprintf("Container %d will be overwritten with a backup:", containerID),
+			"Backup:  "+backupPath,
+			"Node:    "+targetNode,
+			"Storage: "+storage,
+			fmt.Sprintf("An existing container %d and its disks are destroyed", containerID))
+		if err != nil {
+			return err
+		}
+	}
 
 	logger.WithFields(logrus.Fields{
 		"container_id": containerID,
@@ -199,11 +380,210 @@ func runBackupRestore(cmd *cobra.Command, args []string) error {
 		"force":        force,
 	}).Info("Restoring from backup")
 
-	err = apiClient.RestoreContainerFromBackup(ctx, containerID, targetNode, storage, backupPath, force)
+	if cfg.Failover.RestoreTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Failover.RestoreTimeout)
+		defer cancel()
+	}
+
+	bar := newProgressBar(os.Stderr)
+	err = apiClient.RestoreContainerFromBackup(ctx, containerID, targetNode, backupPath, api.RestoreOptions{
+		Storage: storage,
+		Force:   force,
+		Cores:   override.Cores,
+		Memory:  override.Memory,
+		Bridge:  override.Bridge,
+		Progress: func(progress api.RestoreProgress) {
+			bar.update("Restoring: "+progress.Phase, progress.Percent)
+		},
+	})
+	bar.done()
 	if err != nil {
 		return fmt.Errorf("restore failed: %w", err)
 	}
+	if err := backupCatalog.MarkVerified(backupPath, time.Now()); err != nil {
+		logger.WithField("error", err).Warn("Failed to record verified backup in catalog")
+	}
 
 	fmt.Printf("Container %d restored successfully on node %s\n", containerID, targetNode)
 	return nil
-}
\ No newline at end of file
+}
+func runBackupPrune(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel) // Reduce noise
+
+	// Load configuration
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	backupCatalog, err := catalog.Open(cfg)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Backup.RetentionDays <= 0 && cfg.Backup.KeepLast <= 0 {
+		return fmt.Errorf("no retention configured: set backup retention_days or keep_last")
+	}
+
+	// Create API client
+	apiClient, err := api.NewClient(&cfg.Proxmox)
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	format := outputOf(cmd)
+
+	pruner := backup.NewPruner(cfg, apiClient, logger)
+	pruner.SetCatalog(backupCatalog)
+	pruned, pruneErr := pruner.Prune(ctx, dryRun)
+
+	if format != outputTable {
+		if err := printStructured(format, pruned); err != nil {
+			return err
+		}
+	} else if len(pruned) == 0 {
+		fmt.Println("No backups to prune")
+	} else {
+		if dryRun {
+			fmt.Println("Backups that would be deleted:")
+		} else {
+			fmt.Println("Deleted backups:")
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CONTAINER\tNODE\tSTORAGE\tFILENAME\tCREATED")
+		fmt.Fprintln(w, "---------\t----\t-------\t--------\t-------")
+		for _, info := range pruned {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n",
+				info.ContainerID, info.Node, info.Storage, info.Filename, info.Created.Format("2006-01-02 15:04:05"))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if pruneErr != nil {
+		return fmt.Errorf("pruning incomplete: %w", pruneErr)
+	}
+	return nil
+}
+
+func runBackupProtect(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	protect := cmd.Name() == "protect"
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	apiClient, err := api.NewClient(&cfg.Proxmox)
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	info, _, err := lookupBackup(ctx, apiClient, args[0])
+	if err != nil {
+		return err
+	}
+	if err := apiClient.SetBackupProtected(ctx, info.Node, info.Storage, info.VolID, protect); err != nil {
+		return err
+	}
+
+	if protect {
+		fmt.Printf("Protected backup %s\n", info.VolID)
+	} else {
+		fmt.Printf("Backup %s is no longer protected\n", info.VolID)
+	}
+	return nil
+}
+
+func runBackupPin(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	containerID, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid container ID: %w", err)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	backupCatalog, err := catalog.Open(cfg)
+	if err != nil {
+		return err
+	}
+
+	apiClient, err := api.NewClient(&cfg.Proxmox)
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	info, backups, err := lookupBackup(ctx, apiClient, args[1])
+	if err != nil {
+		return err
+	}
+	if info.ContainerID != containerID {
+		return fmt.Errorf("backup %s is of container %d, not %d", info.VolID, info.ContainerID, containerID)
+	}
+
+	// Pins are kept in the catalog, which needs to know the backup
+	if _, err := backupCatalog.Sync(info.Storage, backups); err != nil {
+		return err
+	}
+	if err := backupCatalog.Pin(containerID, info.VolID); err != nil {
+		return err
+	}
+
+	fmt.Printf("Failovers of container %d will restore %s\n", containerID, info.VolID)
+	return nil
+}
+
+func runBackupUnpin(cmd *cobra.Command, args []string) error {
+	containerID, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid container ID: %w", err)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	backupCatalog, err := catalog.Open(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := backupCatalog.Unpin(containerID); err != nil {
+		return err
+	}
+
+	fmt.Printf("Failovers of container %d will restore its latest backup\n", containerID)
+	return nil
+}
+
+// lookupBackup finds a backup by its volume ID on the storage the ID names,
+// and returns it with the storage's listing.
+func lookupBackup(ctx context.Context, apiClient *api.Client, volID string) (api.BackupInfo, []api.BackupInfo, error) {
+	storage, _, found := strings.Cut(volID, ":")
+	if !found {
+		return api.BackupInfo{}, nil, fmt.Errorf("invalid backup path %q: expected storage:backup/...", volID)
+	}
+
+	backups, err := apiClient.GetBackups(ctx, storage)
+	if err != nil {
+		return api.BackupInfo{}, nil, fmt.Errorf("failed to get backups: %w", err)
+	}
+	for _, info := range backups {
+		if info.VolID == volID {
+			return info, backups, nil
+		}
+	}
+	return api.BackupInfo{}, nil, fmt.Errorf("backup %s not found", volID)
+}
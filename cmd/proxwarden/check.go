@@ -0,0 +1,250 @@
+package proxwarden
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/health"
+	"github.com/jbutlerdev/proxwarden/internal/monitor"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Health check operations",
+}
+
+var checkRunCmd = &cobra.Command{
+	Use:   "run [container-id]",
+	Short: "Run health checks once and show the results",
+	Long: `Run the health checks of a container once, as the daemon would, and show
+each result with its latency, attempts and error. With --type, run the
+check the flags describe instead; the container is then optional and only
+needed by metric checks such as disk or memory.
+
+The command fails when a critical check fails.`,
+	Example: `  proxwarden check run 100
+  proxwarden check run --type http --target 192.168.1.100 --port 8080 --path /health
+  proxwarden check run 100 --type disk --threshold 90`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runCheckRun,
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+	checkCmd.AddCommand(checkRunCmd)
+	addCheckFlags(checkRunCmd)
+	addOutputFlags(checkRunCmd)
+}
+
+// addCheckFlags adds the flags describing an inline health check to cmd.
+func addCheckFlags(cmd *cobra.Command) {
+	cmd.Flags().String("type", "", "run this type of check instead of the configured ones, such as tcp, http or ping")
+	cmd.Flags().String("target", "", "host of the check")
+	cmd.Flags().Int("port", 0, "port of tcp, http and database checks")
+	cmd.Flags().String("path", "", "path of http checks")
+	cmd.Flags().Duration("timeout", 0, "timeout of each attempt (default: monitoring.timeout)")
+	cmd.Flags().Int("retries", 0, "immediate re-attempts before the check fails")
+	cmd.Flags().Float64("threshold", 0, "usage percentage at which metric checks fail")
+	cmd.Flags().String("address-family", "", "any, ipv4, ipv6 or dual")
+	cmd.Flags().Bool("verbose", false, "log every attempt")
+}
+
+// checkRunResult is a health check result as check run reports it.
+type checkRunResult struct {
+	Type     string        `json:"type"`
+	Target   string        `json:"target"`
+	Port     int           `json:"port,omitempty"`
+	Severity string        `json:"severity"`
+	Success  bool          `json:"success"`
+	Degraded bool          `json:"degraded,omitempty"`
+	Attempts int           `json:"attempts"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+func runCheckRun(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	containerID := 0
+	if len(args) > 0 {
+		if containerID, err = strconv.Atoi(args[0]); err != nil {
+			return fmt.Errorf("invalid container ID: %w", err)
+		}
+	}
+	checkType, _ := cmd.Flags().GetString("type")
+	if checkType == "" && containerID == 0 {
+		return fmt.Errorf("a container ID or --type is required")
+	}
+
+	ctx := context.Background()
+	apiClient, err := api.NewClient(&cfg.Proxmox)
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	var checks []config.HealthCheck
+	target := health.Container{ID: containerID}
+	if containerID != 0 {
+		info, err := apiClient.GetContainer(ctx, containerID)
+		if err != nil {
+			return err
+		}
+		target.Node = info.Node
+
+		if checkType == "" {
+			container, ok, err := monitoredContainer(ctx, cfg, apiClient, containerID)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("container %d is not monitored, use --type to run a check of your own", containerID)
+			}
+			checks = container.HealthChecks
+			fmt.Printf("Container %d (%s) on %s, %s\n\n", containerID, container.Name, info.Node, info.Status)
+		}
+	}
+	if checkType != "" {
+		check, err := inlineCheck(cmd, checkType)
+		if err != nil {
+			return err
+		}
+		checks = []config.HealthCheck{check}
+	}
+	if len(checks) == 0 {
+		return fmt.Errorf("container %d has no health checks", containerID)
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	if verbose, _ := cmd.Flags().GetBool("verbose"); verbose {
+		logger.SetLevel(logrus.DebugLevel)
+	}
+	checker := health.NewChecker(logger)
+	checker.SetProxy(cfg.Monitoring.Proxy)
+	checker.SetMetricsSource(apiClient)
+
+	var results []checkRunResult
+	failed := 0
+	for _, check := range checks {
+		if check.Timeout <= 0 {
+			check.Timeout = cfg.Monitoring.Timeout
+		}
+		result := checker.RunContainerCheck(ctx, target, check)
+		entry := checkRunResult{
+			Type:     check.Type,
+			Target:   check.Target,
+			Port:     check.Port,
+			Severity: result.Severity,
+			Success:  result.Success,
+			Degraded: result.Degraded,
+			Attempts: result.Attempts,
+			Duration: result.Duration,
+		}
+		if result.Error != nil {
+			entry.Error = result.Error.Error()
+		}
+		if !result.Success && result.Severity != config.SeverityWarning {
+			failed++
+		}
+		results = append(results, entry)
+	}
+
+	format := outputOf(cmd)
+	if format != outputTable {
+		if err := printStructured(format, results); err != nil {
+			return err
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TYPE\tTARGET\tSEVERITY\tRESULT\tLATENCY\tATTEMPTS\tERROR")
+		fmt.Fprintln(w, "----\t------\t--------\t------\t-------\t--------\t-----")
+		for _, result := range results {
+			outcome := "ok"
+			switch {
+			case result.Degraded:
+				outcome = "slow"
+			case !result.Success:
+				outcome = "FAIL"
+			}
+			target := result.Target
+			if result.Port != 0 {
+				target += ":" + strconv.Itoa(result.Port)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n",
+				result.Type, target, result.Severity, outcome, formatLatency(result.Duration), result.Attempts, result.Error)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d critical checks failed", failed, len(results))
+	}
+	return nil
+}
+
+// monitoredContainer returns the configuration the daemon monitors a
+// container with, whether configured or enrolled by tag, pool or discovery.
+func monitoredContainer(ctx context.Context, cfg *config.Config, apiClient *api.Client, containerID int) (config.ContainerConfig, bool, error) {
+	for _, container := range cfg.Monitoring.Containers {
+		if container.ID == containerID {
+			return container, true, nil
+		}
+	}
+	if !cfg.Monitoring.Discovers() {
+		return config.ContainerConfig{}, false, nil
+	}
+
+	resources, err := apiClient.GetClusterResources(ctx)
+	if err != nil {
+		return config.ContainerConfig{}, false, err
+	}
+	for _, resource := range resources {
+		if resource.ID != containerID {
+			continue
+		}
+		container, enrollment := monitor.Enrolled(cfg.Monitoring, resource)
+		return container, enrollment != "", nil
+	}
+	return config.ContainerConfig{}, false, nil
+}
+
+// inlineCheck returns the health check the flags of cmd describe.
+func inlineCheck(cmd *cobra.Command, checkType string) (config.HealthCheck, error) {
+	check := config.HealthCheck{Type: checkType}
+	check.Target, _ = cmd.Flags().GetString("target")
+	check.Port, _ = cmd.Flags().GetInt("port")
+	check.Path, _ = cmd.Flags().GetString("path")
+	check.Timeout, _ = cmd.Flags().GetDuration("timeout")
+	check.Retries, _ = cmd.Flags().GetInt("retries")
+	check.UsageThreshold, _ = cmd.Flags().GetFloat64("threshold")
+	check.AddressFamily, _ = cmd.Flags().GetString("address-family")
+
+	if check.Target == "" && !config.IsMetricCheck(check.Type) {
+		return check, fmt.Errorf("--target is required for %s checks", check.Type)
+	}
+	if check.Retries < 0 {
+		return check, fmt.Errorf("--retries must not be negative")
+	}
+	return check, nil
+}
+
+// formatLatency rounds a latency to a precision worth reading.
+func formatLatency(d time.Duration) string {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond).String()
+	}
+	return d.Round(100 * time.Microsecond).String()
+}
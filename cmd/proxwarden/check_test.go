@@ -0,0 +1,98 @@
+package proxwarden
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func TestInlineCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		checkType string
+		args      []string
+		expected  config.HealthCheck
+		expectErr bool
+	}{
+		{
+			name:      "http",
+			checkType: "http",
+			args:      []string{"--target", "192.168.1.100", "--port", "8080", "--path", "/health", "--timeout", "3s", "--retries", "2"},
+			expected:  config.HealthCheck{Type: "http", Target: "192.168.1.100", Port: 8080, Path: "/health", Timeout: 3 * time.Second, Retries: 2},
+		},
+		{
+			name:      "ping over IPv6",
+			checkType: "ping",
+			args:      []string{"--target", "web.example.com", "--address-family", "ipv6"},
+			expected:  config.HealthCheck{Type: "ping", Target: "web.example.com", AddressFamily: "ipv6"},
+		},
+		{
+			name:      "metric check without target",
+			checkType: "disk",
+			args:      []string{"--threshold", "90"},
+			expected:  config.HealthCheck{Type: "disk", UsageThreshold: 90},
+		},
+		{
+			name:      "target required",
+			checkType: "tcp",
+			args:      []string{"--port", "22"},
+			expectErr: true,
+		},
+		{
+			name:      "negative retries",
+			checkType: "tcp",
+			args:      []string{"--target", "192.168.1.100", "--retries", "-1"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "run"}
+			addCheckFlags(cmd)
+			if err := cmd.Flags().Parse(tt.args); err != nil {
+				t.Fatalf("Failed to parse flags: %v", err)
+			}
+
+			check, err := inlineCheck(cmd, tt.checkType)
+			if tt.expectErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("inlineCheck failed: %v", err)
+			}
+			if !reflect.DeepEqual(check, tt.expected) {
+				t.Errorf("Expected check %+v, got %+v", tt.expected, check)
+			}
+		})
+	}
+}
+
+func TestFormatLatency(t *testing.T) {
+	tests := []struct {
+		latency  time.Duration
+		expected string
+	}{
+		{0, "0s"},
+		{420 * time.Nanosecond, "0s"},
+		{5600 * time.Nanosecond, "6µs"},
+		{999 * time.Microsecond, "999µs"},
+		{1234567 * time.Nanosecond, "1.2ms"},
+		{250 * time.Millisecond, "250ms"},
+		{1500*time.Millisecond + 49*time.Microsecond, "1.5s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			if latency := formatLatency(tt.latency); latency != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, latency)
+			}
+		})
+	}
+}
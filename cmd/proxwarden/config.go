@@ -0,0 +1,167 @@
+package proxwarden
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/configcheck"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Configuration operations",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the configuration for errors",
+	Long: `Parse and validate the configuration, then check it against the Proxmox
+cluster: that the API is reachable and that the failover nodes, storages and
+containers it refers to exist. Each problem is reported with the YAML path of
+the setting. Exits non-zero when any problem is found.`,
+	Args: cobra.NoArgs,
+	RunE: runConfigValidate,
+}
+
+var configEncryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "Encrypt the Proxmox credentials in the configuration file",
+	Long: `Encrypt the Proxmox password and token secret of the configuration file in
+place, so copies of the file do not reveal them. The key is read from a key
+file or the user keyring of the kernel, taken from the flags or the proxmox
+key_file and keyring settings; with --generate-key a new key is created there
+first. The daemon decrypts the credentials when it loads the configuration.`,
+	Args: cobra.NoArgs,
+	RunE: runConfigEncrypt,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configEncryptCmd)
+
+	configValidateCmd.Flags().Bool("offline", false, "skip the checks against the Proxmox cluster")
+	configValidateCmd.Flags().Duration("timeout", 30*time.Second, "timeout for the checks against the Proxmox cluster")
+
+	configEncryptCmd.Flags().String("key-file", "", "file holding the key (default: proxmox.key_file)")
+	configEncryptCmd.Flags().String("keyring", "", "name of the key in the user keyring (default: proxmox.keyring)")
+	configEncryptCmd.Flags().Bool("generate-key", false, "create a new key in the key file or keyring")
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	offline, _ := cmd.Flags().GetBool("offline")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	if err := viper.ReadInConfig(); err != nil {
+		fmt.Printf("Config file: FAILED\n  %v\n", err)
+		return fmt.Errorf("configuration could not be read")
+	}
+	fmt.Printf("Config file: %s\n", viper.ConfigFileUsed())
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Settings: FAILED\n  %v\n", err)
+		return fmt.Errorf("configuration is invalid")
+	}
+	fmt.Println("Settings: OK")
+
+	if remote := cfg.Remote; remote.Backend != "" {
+		if state := cfg.RemoteState; state.Error != nil {
+			fallback := "the configuration file only"
+			if state.Source != "" {
+				fallback = "the cached copy in " + remote.CacheFile
+			}
+			fmt.Printf("Remote config: FAILED\n  %v\n  settings checked with %s\n", state.Error, fallback)
+			return fmt.Errorf("remote configuration could not be read")
+		}
+		fmt.Printf("Remote config: OK (%s key %s, version %d)\n", remote.Backend, remote.Key, cfg.RemoteState.Version)
+	}
+
+	if offline {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	apiClient, err := api.NewClient(&cfg.Proxmox)
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	cluster, problems, err := configcheck.Check(ctx, cfg, apiClient)
+	if err != nil {
+		fmt.Printf("Proxmox API: FAILED\n  %v\n", err)
+		return fmt.Errorf("configuration could not be checked against the cluster")
+	}
+	fmt.Printf("Proxmox API: OK (%d nodes, %d storages, %d containers)\n", len(cluster.Nodes), len(cluster.Storages), len(cluster.Containers))
+
+	if len(problems) == 0 {
+		fmt.Println("Cluster references: OK")
+		return nil
+	}
+
+	fmt.Printf("Cluster references: %d problems\n", len(problems))
+	for _, problem := range problems {
+		fmt.Printf("  %s\n", problem)
+	}
+	return fmt.Errorf("configuration has %d problems", len(problems))
+}
+
+func runConfigEncrypt(cmd *cobra.Command, args []string) error {
+	keyFile, _ := cmd.Flags().GetString("key-file")
+	keyring, _ := cmd.Flags().GetString("keyring")
+	generate, _ := cmd.Flags().GetBool("generate-key")
+
+	if err := viper.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	path := viper.ConfigFileUsed()
+	if keyFile == "" && keyring == "" {
+		keyFile = viper.GetString("proxmox.key_file")
+		keyring = viper.GetString("proxmox.keyring")
+	}
+	if keyFile == "" && keyring == "" {
+		return fmt.Errorf("no key: set --key-file or --keyring")
+	}
+	if keyFile != "" && keyring != "" {
+		return fmt.Errorf("--key-file and --keyring are mutually exclusive")
+	}
+
+	if generate {
+		encoded, err := config.GenerateKey()
+		if err != nil {
+			return err
+		}
+		if err := config.StoreKey(encoded, keyFile, keyring); err != nil {
+			return err
+		}
+		if keyFile != "" {
+			fmt.Printf("Generated key in %s, keep a copy apart from the configuration\n", keyFile)
+		} else {
+			fmt.Printf("Generated key %q in the user keyring, which does not survive a reboot: keep a copy (keyctl print %%user:%s) apart from the configuration\n", keyring, keyring)
+		}
+	}
+
+	key, err := config.ReadKey(keyFile, keyring)
+	if err != nil {
+		return err
+	}
+	encrypted, err := config.EncryptFile(path, key, keyFile, keyring)
+	if err != nil {
+		return err
+	}
+	if len(encrypted) == 0 {
+		fmt.Printf("Nothing to encrypt in %s\n", path)
+		return nil
+	}
+	for _, setting := range encrypted {
+		fmt.Printf("Encrypted %s in %s\n", setting, path)
+	}
+	return nil
+}
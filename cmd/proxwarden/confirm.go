@@ -0,0 +1,45 @@
+package proxwarden
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// addConfirmFlag adds --yes to a command that asks before it does something
+// destructive.
+func addConfirmFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolP("yes", "y", false, "do not ask for confirmation")
+}
+
+// confirm prints what a command is about to do and asks whether to go on,
+// unless --yes was given. Without a terminal to ask on it refuses, so a
+// script that forgot --yes fails instead of hanging or acting unasked.
+func confirm(cmd *cobra.Command, summary string, details ...string) error {
+	if yes, _ := cmd.Flags().GetBool("yes"); yes {
+		return nil
+	}
+	if !isTerminal(os.Stdin) {
+		return fmt.Errorf("confirmation required: run in a terminal or pass --yes")
+	}
+
+	fmt.Println(summary)
+	for _, detail := range details {
+		fmt.Printf("  %s\n", detail)
+	}
+	fmt.Print("Continue? [y/N] ")
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Println()
+		return fmt.Errorf("aborted")
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return fmt.Errorf("aborted")
+}
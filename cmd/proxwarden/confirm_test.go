@@ -0,0 +1,61 @@
+package proxwarden
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		expectErr string
+	}{
+		{name: "yes", args: []string{"--yes"}},
+		{name: "yes shorthand", args: []string{"-y"}},
+		{name: "no terminal", expectErr: "confirmation required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A pipe is not a terminal, and answers yes if it is read
+			r, w, err := os.Pipe()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			if _, err := w.WriteString("y\n"); err != nil {
+				t.Fatal(err)
+			}
+			w.Close()
+			stdin := os.Stdin
+			os.Stdin = r
+			defer func() { os.Stdin = stdin }()
+
+			cmd := &cobra.Command{Use: "drain"}
+			addConfirmFlag(cmd)
+			if err := cmd.Flags().Parse(tt.args); err != nil {
+				t.Fatalf("Failed to parse flags: %v", err)
+			}
+
+			output := captureStdout(t, func() {
+				err = confirm(cmd, "1 monitored containers will be moved off node a:", "Container 101 (web) to b")
+			})
+			if output != "" {
+				t.Errorf("Expected nothing printed, got %q", output)
+			}
+			if tt.expectErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.expectErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package proxwarden
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/monitor"
+	"github.com/spf13/cobra"
+)
+
+var containersCmd = &cobra.Command{
+	Use:   "containers",
+	Short: "Cluster guest operations",
+}
+
+var containersListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the containers and VMs of the cluster",
+	Long: `List every LXC container and virtual machine of the cluster with its node,
+status and tags, and whether ProxWarden monitors it: listed in the
+configuration, selected by a containers entry matching tags or pool, or
+found by discovery. Virtual machines are never monitored.`,
+	Args: cobra.NoArgs,
+	RunE: runContainersList,
+}
+
+func init() {
+	rootCmd.AddCommand(containersCmd)
+	containersCmd.AddCommand(containersListCmd)
+
+	addOutputFlags(containersListCmd)
+	containersListCmd.Flags().Bool("unmonitored", false, "only list containers ProxWarden does not monitor")
+}
+
+// guest is a container or VM as containers list reports it.
+type guest struct {
+	ID        int      `json:"id"`
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	Node      string   `json:"node"`
+	Status    string   `json:"status"`
+	Pool      string   `json:"pool,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	Monitored bool     `json:"monitored"`
+	// Enrollment is how a monitored container is monitored: configured,
+	// selector or discovery
+	Enrollment string `json:"enrollment,omitempty"`
+}
+
+func runContainersList(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	apiClient, err := api.NewClient(&cfg.Proxmox)
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	resources, err := apiClient.GetGuests(context.Background())
+	if err != nil {
+		return err
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].ID < resources[j].ID
+	})
+
+	unmonitored, _ := cmd.Flags().GetBool("unmonitored")
+	guests := make([]guest, 0, len(resources))
+	found := make(map[int]bool)
+	for _, resource := range resources {
+		found[resource.ID] = true
+		enrollment := monitor.Enrollment(cfg.Monitoring, resource)
+		if unmonitored && (enrollment != "" || resource.Type != "lxc") {
+			continue
+		}
+		guests = append(guests, guest{
+			ID:         resource.ID,
+			Name:       resource.Name,
+			Type:       resource.Type,
+			Node:       resource.Node,
+			Status:     resource.Status,
+			Pool:       resource.Pool,
+			Tags:       resource.TagList(),
+			Monitored:  enrollment != "",
+			Enrollment: enrollment,
+		})
+	}
+
+	format := outputOf(cmd)
+	if format != outputTable {
+		return printStructured(format, guests)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tTYPE\tNODE\tSTATUS\tTAGS\tMONITORED")
+	fmt.Fprintln(w, "--\t----\t----\t----\t------\t----\t---------")
+
+	monitored := 0
+	for _, g := range guests {
+		status := "no"
+		if g.Monitored {
+			status = "yes (" + g.Enrollment + ")"
+			monitored++
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			g.ID, g.Name, g.Type, g.Node, g.Status, strings.Join(g.Tags, ","), status)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if !unmonitored {
+		fmt.Printf("\n%d of %d containers and VMs monitored\n", monitored, len(guests))
+	}
+	for _, container := range cfg.Monitoring.Containers {
+		if !found[container.ID] {
+			fmt.Printf("Warning: configured container %d is not in the cluster\n", container.ID)
+		}
+	}
+	return nil
+}
@@ -44,5 +44,16 @@ func runDaemon(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	// Reload the configuration on SIGHUP
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+
+	go func() {
+		for range hupChan {
+			logger.Info("Received SIGHUP, reloading configuration")
+			d.RequestReload()
+		}
+	}()
+
 	return d.Start(ctx)
 }
\ No newline at end of file
@@ -0,0 +1,82 @@
+package proxwarden
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/doctor"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that this host and the cluster are ready for failovers",
+	Long: `Diagnose the environment ProxWarden runs in and print a checklist: whether
+the Proxmox API answers, whether the configured user or token may monitor,
+stop and start, back up, restore and migrate the monitored containers,
+whether the storages failovers restore to and from are active on the
+failover nodes, whether the clocks of the nodes agree with this host, and
+whether the programs hooks and plugins run are installed.
+
+Warnings point at things to look into; the command fails when any check
+fails.`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	doctorCmd.Flags().Duration("timeout", 30*time.Second, "timeout for the checks against the Proxmox cluster")
+	addOutputFlags(doctorCmd)
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	apiClient, err := api.NewClient(&cfg.Proxmox)
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	results := doctor.Diagnose(ctx, cfg, apiClient)
+
+	format := outputOf(cmd)
+	if format != outputTable {
+		if err := printStructured(format, results); err != nil {
+			return err
+		}
+	} else {
+		for _, result := range results {
+			fmt.Printf("[%s] %s: %s\n", strings.ToUpper(result.Status), result.Check, result.Detail)
+		}
+	}
+
+	failed, warned := 0, 0
+	for _, result := range results {
+		switch result.Status {
+		case doctor.StatusFail:
+			failed++
+		case doctor.StatusWarn:
+			warned++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(results))
+	}
+	if format == outputTable {
+		fmt.Printf("\n%d checks passed, %d with warnings\n", len(results), warned)
+	}
+	return nil
+}
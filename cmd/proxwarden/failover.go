@@ -1,9 +1,20 @@
 package proxwarden
 
 import (
+	"context"
+	"fmt"
+	"os"
 	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
 
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/catalog"
+	"github.com/jbutlerdev/proxwarden/internal/config"
 	"github.com/jbutlerdev/proxwarden/internal/failover"
+	"github.com/jbutlerdev/proxwarden/internal/history"
+	"github.com/jbutlerdev/proxwarden/internal/server"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -21,12 +32,112 @@ var triggerCmd = &cobra.Command{
 	RunE:  runTrigger,
 }
 
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show past failover attempts",
+	Long: `Show manual and automatic failover attempts, newest first, as recorded in
+the data directory.`,
+	RunE: runHistory,
+}
+
+var cancelCmd = &cobra.Command{
+	Use:   "cancel [container-id]",
+	Short: "Cancel a failover the daemon is running",
+	Long: `Cancel a queued or running failover of a container in the daemon. Steps
+already sent to Proxmox finish there, but no further steps are started. Failovers
+started with 'failover trigger' run in that command and are not listed here.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runCancel,
+}
+
+var approveCmd = &cobra.Command{
+	Use:   "approve [container-id]",
+	Short: "Approve an automatic failover awaiting approval",
+	Long: `Let an automatic failover held by failover.approval go ahead. It then waits
+for a failover slot like any other failover.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runApprove,
+}
+
+var rejectCmd = &cobra.Command{
+	Use:   "reject [container-id]",
+	Short: "Reject an automatic failover awaiting approval",
+	Long: `Drop an automatic failover held by failover.approval. The container stays
+where it is and is not failed over automatically again until its cooldown ends.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReject,
+}
+
+var pendingCmd = &cobra.Command{
+	Use:   "pending",
+	Short: "List automatic failovers awaiting approval",
+	RunE:  runPending,
+}
+
+var interruptedCmd = &cobra.Command{
+	Use:   "interrupted",
+	Short: "List failovers a daemon restart left unfinished",
+	RunE:  runInterrupted,
+}
+
+var resumeFailoverCmd = &cobra.Command{
+	Use:   "resume [container-id]",
+	Short: "Resume a failover a daemon restart left unfinished",
+	Long: `Run an interrupted failover again, from its first step, with the source node,
+target node and strategy it had. A container already running on the target node
+is taken as failed over.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runResumeFailover,
+}
+
+var discardCmd = &cobra.Command{
+	Use:   "discard [container-id]",
+	Short: "Forget a failover a daemon restart left unfinished",
+	Long: `Forget an interrupted failover once the container has been cleaned up by hand.
+Until then, the container is not failed over automatically.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDiscard,
+}
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback [container-id]",
+	Short: "Return a container to the node it failed over from",
+	Long: `Return a container to the node it was on before its last successful failover,
+as recorded in the failover history. The container must still be on the node it
+failed over to, and the original node must be online in a quorate cluster. The
+container is migrated where possible and restored from a fresh backup otherwise;
+a standby failover is undone by stopping the standby and starting the original.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRollback,
+}
+
 func init() {
 	rootCmd.AddCommand(failoverCmd)
 	failoverCmd.AddCommand(triggerCmd)
+	failoverCmd.AddCommand(historyCmd)
+	failoverCmd.AddCommand(cancelCmd)
+	failoverCmd.AddCommand(rollbackCmd)
+	failoverCmd.AddCommand(approveCmd)
+	failoverCmd.AddCommand(rejectCmd)
+	failoverCmd.AddCommand(pendingCmd)
+	failoverCmd.AddCommand(interruptedCmd)
+	failoverCmd.AddCommand(resumeFailoverCmd)
+	failoverCmd.AddCommand(discardCmd)
 	
 	triggerCmd.Flags().String("target-node", "", "target node for failover (optional)")
-	triggerCmd.Flags().Bool("force", false, "force failover even if container is healthy")
+	triggerCmd.Flags().Bool("force", false, "force failover even if container is healthy or cannot be confirmed stopped on its node")
+	triggerCmd.Flags().String("strategy", "", "failover strategy, not falling back if it is not possible: restore, migrate, replica, standby or auto (default from config)")
+	addConfirmFlag(triggerCmd)
+
+	rollbackCmd.Flags().String("strategy", "", "failover strategy used to move the container back (default migrate, falling back to restore)")
+
+	historyCmd.Flags().Int("container", 0, "only show failovers of this container")
+	historyCmd.Flags().Duration("since", 0, "only show failovers started within this duration")
+	historyCmd.Flags().Int("limit", 20, "maximum number of failovers shown (0 for all)")
+	historyCmd.Flags().Bool("wide", false, "also show end times, container names, backups and their age, and hooks")
+	addCSVOutputFlags(historyCmd)
+	addOutputFlags(pendingCmd)
+	addOutputFlags(interruptedCmd)
 }
 
 func runTrigger(cmd *cobra.Command, args []string) error {
@@ -39,11 +150,456 @@ func runTrigger(cmd *cobra.Command, args []string) error {
 
 	targetNode, _ := cmd.Flags().GetString("target-node")
 	force, _ := cmd.Flags().GetBool("force")
+	strategy, _ := cmd.Flags().GetString("strategy")
+	if strategy != "" && !config.ValidStrategy(strategy) {
+		return fmt.Errorf("invalid strategy %q: must be restore, migrate, replica, standby or auto", strategy)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	apiClient, err := api.NewClient(&cfg.Proxmox)
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+	info, err := apiClient.GetContainer(context.Background(), containerID)
+	if err != nil {
+		return fmt.Errorf("failed to get container info: %w", err)
+	}
+	if !force && info.Status == "running" {
+		return fmt.Errorf("container is running and force flag not set")
+	}
+	summary, details := triggerSummary(cfg, info, targetNode, strategy, force)
+	if err := confirm(cmd, summary, details...); err != nil {
+		return err
+	}
+
+	engine, err := failover.New(cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	// Show how far the failover is while it runs
+	bar := newProgressBar(os.Stderr)
+	stop := make(chan struct{})
+	watching := make(chan struct{})
+	go func() {
+		defer close(watching)
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			for _, running := range engine.InFlight() {
+				if running.ContainerID == containerID && running.Progress.Step != "" {
+					bar.update(progressLabel(running.Progress), running.Progress.Percent)
+				}
+			}
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	err = engine.TriggerFailover(containerID, targetNode, strategy, force)
+	close(stop)
+	<-watching
+	bar.done()
+	return err
+}
+
+// triggerSummary describes what failover trigger is about to do.
+func triggerSummary(cfg *config.Config, info *api.ContainerInfo, targetNode, strategy string, force bool) (string, []string) {
+	var container config.ContainerConfig
+	for _, c := range cfg.Monitoring.Containers {
+		if c.ID == info.ID {
+			container = c
+		}
+	}
+	if targetNode == "" {
+		targetNode = "best of " + strings.Join(container.FailoverNodes, ", ")
+		if len(container.FailoverNodes) == 0 {
+			targetNode = "none, no failover nodes are configured"
+		}
+	}
+	if strategy == "" {
+		strategy = container.Strategy
+	}
+	if strategy == "" {
+		strategy = cfg.Failover.Strategy
+	}
+	if strategy == "" {
+		strategy = config.StrategyRestore
+	}
+
+	name := info.Name
+	if name == "" {
+		name = container.Name
+	}
+	details := []string{
+		fmt.Sprintf("Container: %d (%s), %s on %s", info.ID, name, info.Status, info.Node),
+		"Target:    " + targetNode,
+		"Strategy:  " + strategy,
+	}
+	if cfg.Failover.BackupBeforeFailover {
+		details = append(details, "Backup:    taken before the container is moved")
+	}
+	if force && info.Status == "running" {
+		details = append(details, "The container is running and will be stopped on "+info.Node)
+	}
+	return fmt.Sprintf("Container %d will be failed over:", info.ID), details
+}
+
+// progressLabel describes the progress of a failover.
+func progressLabel(progress failover.Progress) string {
+	label := strings.ReplaceAll(progress.Step, "_", " ")
+	if progress.Detail != "" {
+		label += ": " + progress.Detail
+	}
+	return label
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	filter := history.Filter{}
+	filter.ContainerID, _ = cmd.Flags().GetInt("container")
+	filter.Limit, _ = cmd.Flags().GetInt("limit")
+	if since, _ := cmd.Flags().GetDuration("since"); since > 0 {
+		filter.Since = time.Now().Add(-since)
+	}
+
+	failoverHistory, err := history.Open(cfg)
+	if err != nil {
+		return err
+	}
+	records, err := failoverHistory.List(filter)
+	if err != nil {
+		return err
+	}
+
+	format := outputOf(cmd)
+	wide, _ := cmd.Flags().GetBool("wide")
+	switch format {
+	case outputTable:
+	case outputCSV:
+		return printHistoryCSV(records, backupTimes(cfg))
+	default:
+		return printStructured(format, records)
+	}
+
+	if len(records) == 0 {
+		fmt.Println("No failovers recorded")
+		return nil
+	}
+
+	var created map[string]time.Time
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if wide {
+		created = backupTimes(cfg)
+		fmt.Fprintln(w, "STARTED\tENDED\tCONTAINER\tNAME\tTRIGGER\tSTRATEGY\tSOURCE\tTARGET\tBACKUP\tBACKUP AGE\tHOOKS\tDURATION\tRESULT")
+		fmt.Fprintln(w, "-------\t-----\t---------\t----\t-------\t--------\t------\t------\t------\t----------\t-----\t--------\t------")
+	} else {
+		fmt.Fprintln(w, "STARTED\tCONTAINER\tTRIGGER\tSTRATEGY\tSOURCE\tTARGET\tDURATION\tRESULT")
+		fmt.Fprintln(w, "-------\t---------\t-------\t--------\t------\t------\t--------\t------")
+	}
+
+	for _, record := range records {
+		result := "success"
+		if !record.Success {
+			result = "failed: " + record.Error
+		}
+		for _, warning := range record.Warnings {
+			result += "; warning: " + warning
+		}
+		if !wide {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				record.StartTime.Format(time.RFC3339), record.ContainerID, record.Trigger, record.Strategy,
+				record.SourceNode, record.TargetNode, record.Duration.Round(time.Second), result)
+			continue
+		}
+
+		name, backup, backupAge := valueOrDash(record.ContainerName), valueOrDash(record.Backup), "-"
+		if taken, ok := created[record.Backup]; ok {
+			backupAge = formatAge(record.StartTime.Sub(taken))
+		}
+		hooks := strconv.Itoa(len(record.Hooks))
+		if failed := failedHooks(record); failed > 0 {
+			hooks += fmt.Sprintf(" (%d failed)", failed)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			record.StartTime.Format(time.RFC3339), record.EndTime.Format(time.RFC3339), record.ContainerID, name,
+			record.Trigger, record.Strategy, record.SourceNode, record.TargetNode, backup, backupAge,
+			hooks, record.Duration.Round(time.Second), result)
+	}
+
+	return w.Flush()
+}
+
+// backupTimes returns when backups were taken, by volume ID, as far as the
+// backup catalog knows.
+func backupTimes(cfg *config.Config) map[string]time.Time {
+	created := make(map[string]time.Time)
+	backupCatalog, err := catalog.Open(cfg)
+	if err != nil {
+		return created
+	}
+	entries, err := backupCatalog.List(catalog.Filter{})
+	if err != nil {
+		return created
+	}
+	for _, entry := range entries {
+		created[entry.VolID] = entry.Created
+	}
+	return created
+}
+
+// printHistoryCSV writes failover records as CSV, with the columns of
+// --wide. Backup ages are how old the backup was when the failover
+// started, as the data lost by restoring it.
+func printHistoryCSV(records []history.Record, created map[string]time.Time) error {
+	header := []string{"started", "ended", "container_id", "container_name", "trigger", "strategy",
+		"source_node", "target_node", "backup", "backup_age_seconds", "hooks", "hooks_failed",
+		"duration_seconds", "success", "error", "warnings"}
+	rows := make([][]string, 0, len(records))
+	for _, record := range records {
+		backupAge := ""
+		if taken, ok := created[record.Backup]; ok {
+			backupAge = strconv.Itoa(int(record.StartTime.Sub(taken).Seconds()))
+		}
+		rows = append(rows, []string{
+			formatTime(record.StartTime), formatTime(record.EndTime), strconv.Itoa(record.ContainerID),
+			record.ContainerName, record.Trigger, record.Strategy, record.SourceNode, record.TargetNode,
+			record.Backup, backupAge, strconv.Itoa(len(record.Hooks)), strconv.Itoa(failedHooks(record)),
+			strconv.FormatFloat(record.Duration.Seconds(), 'f', 1, 64), strconv.FormatBool(record.Success),
+			record.Error, strings.Join(record.Warnings, "; "),
+		})
+	}
+	return printCSV(header, rows)
+}
+
+func failedHooks(record history.Record) int {
+	failed := 0
+	for _, hook := range record.Hooks {
+		if !hook.Success {
+			failed++
+		}
+	}
+	return failed
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func runCancel(cmd *cobra.Command, args []string) error {
+	containerID, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid container ID: %w", err)
+	}
 
-	engine, err := failover.New(logger)
+	client, err := newDaemonClient()
 	if err != nil {
 		return err
 	}
 
-	return engine.TriggerFailover(containerID, targetNode, force)
-}
\ No newline at end of file
+	if err := client.CancelFailover(context.Background(), containerID); err != nil {
+		return fmt.Errorf("failed to cancel failover: %w", err)
+	}
+
+	fmt.Printf("Failover of container %d cancelled\n", containerID)
+	return nil
+}
+
+func runApprove(cmd *cobra.Command, args []string) error {
+	containerID, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid container ID: %w", err)
+	}
+
+	client, err := newDaemonClient()
+	if err != nil {
+		return err
+	}
+
+	if err := client.ApproveFailover(context.Background(), containerID); err != nil {
+		return fmt.Errorf("failed to approve failover: %w", err)
+	}
+
+	fmt.Printf("Failover of container %d approved\n", containerID)
+	return nil
+}
+
+func runReject(cmd *cobra.Command, args []string) error {
+	containerID, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid container ID: %w", err)
+	}
+
+	client, err := newDaemonClient()
+	if err != nil {
+		return err
+	}
+
+	if err := client.RejectFailover(context.Background(), containerID); err != nil {
+		return fmt.Errorf("failed to reject failover: %w", err)
+	}
+
+	fmt.Printf("Failover of container %d rejected\n", containerID)
+	return nil
+}
+
+// awaitingApproval returns the failovers of failovers awaiting approval,
+// an empty list rather than nil if there are none so it is written as [].
+func awaitingApproval(failovers []server.FailoverStatus) []server.FailoverStatus {
+	pending := []server.FailoverStatus{}
+	for _, running := range failovers {
+		if running.Approval != nil {
+			pending = append(pending, running)
+		}
+	}
+	return pending
+}
+
+func runPending(cmd *cobra.Command, args []string) error {
+	client, err := newDaemonClient()
+	if err != nil {
+		return err
+	}
+
+	failovers, err := client.Failovers(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to get failovers: %w", err)
+	}
+
+	pending := awaitingApproval(failovers)
+	format := outputOf(cmd)
+	if format != outputTable {
+		return printStructured(format, pending)
+	}
+	if len(pending) == 0 {
+		fmt.Println("No failovers awaiting approval")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CONTAINER\tTRIGGER\tSOURCE\tTARGET\tEXPIRES\tAUTO-APPROVE")
+	fmt.Fprintln(w, "---------\t-------\t------\t------\t-------\t------------")
+	for _, running := range pending {
+		autoApprove := "never"
+		if running.Approval.AutoApproveAt != nil {
+			autoApprove = time.Until(*running.Approval.AutoApproveAt).Round(time.Second).String()
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
+			running.ContainerID, running.Trigger, running.SourceNode, running.TargetNode,
+			time.Until(running.Approval.ExpiresAt).Round(time.Second), autoApprove)
+	}
+	return w.Flush()
+}
+
+func runInterrupted(cmd *cobra.Command, args []string) error {
+	client, err := newDaemonClient()
+	if err != nil {
+		return err
+	}
+
+	failovers, err := client.InterruptedFailovers(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to get interrupted failovers: %w", err)
+	}
+
+	format := outputOf(cmd)
+	if format != outputTable {
+		return printStructured(format, failovers)
+	}
+	if len(failovers) == 0 {
+		fmt.Println("No interrupted failovers")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CONTAINER\tTRIGGER\tSOURCE\tTARGET\tSTRATEGY\tSTEP\tINTERRUPTED")
+	fmt.Fprintln(w, "---------\t-------\t------\t------\t--------\t----\t-----------")
+	for _, interrupted := range failovers {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			interrupted.ContainerID, interrupted.Trigger, interrupted.SourceNode, interrupted.TargetNode,
+			interrupted.Strategy, interrupted.Step, interrupted.InterruptedAt.Format("2006-01-02 15:04:05"))
+	}
+	return w.Flush()
+}
+
+func runResumeFailover(cmd *cobra.Command, args []string) error {
+	containerID, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid container ID: %w", err)
+	}
+
+	client, err := newDaemonClient()
+	if err != nil {
+		return err
+	}
+
+	if err := client.ResumeFailover(context.Background(), containerID); err != nil {
+		return fmt.Errorf("failed to resume failover: %w", err)
+	}
+
+	fmt.Printf("Failover of container %d resumed\n", containerID)
+	return nil
+}
+
+func runDiscard(cmd *cobra.Command, args []string) error {
+	containerID, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid container ID: %w", err)
+	}
+
+	client, err := newDaemonClient()
+	if err != nil {
+		return err
+	}
+
+	if err := client.DiscardFailover(context.Background(), containerID); err != nil {
+		return fmt.Errorf("failed to discard failover: %w", err)
+	}
+
+	fmt.Printf("Interrupted failover of container %d discarded\n", containerID)
+	return nil
+}
+
+func runRollback(cmd *cobra.Command, args []string) error {
+	containerID, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid container ID: %w", err)
+	}
+
+	strategy, _ := cmd.Flags().GetString("strategy")
+	if strategy != "" && !config.ValidStrategy(strategy) {
+		return fmt.Errorf("invalid strategy %q: must be restore, migrate, replica, standby or auto", strategy)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	engine, err := failover.New(cfg, logrus.New())
+	if err != nil {
+		return err
+	}
+
+	result, err := engine.Rollback(context.Background(), containerID, strategy)
+	if err != nil {
+		return fmt.Errorf("rollback of container %d failed: %w", containerID, err)
+	}
+
+	fmt.Printf("Container %d rolled back from %s to %s (%s, %s)\n",
+		containerID, result.SourceNode, result.TargetNode, result.Strategy, result.Duration.Round(time.Second))
+	return nil
+}
@@ -0,0 +1,228 @@
+package proxwarden
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/failover"
+	"github.com/jbutlerdev/proxwarden/internal/history"
+	"github.com/jbutlerdev/proxwarden/internal/hook"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var hooksCmd = &cobra.Command{
+	Use:   "hooks",
+	Short: "Failover hook operations",
+}
+
+var hooksTestCmd = &cobra.Command{
+	Use:   "test <container-id>",
+	Short: "Run the failover hooks as a failover of a container would",
+	Long: `Run the configured pre- and post-failover hooks with the environment a
+failover of the container would give them, and show the exit code and
+output of each, so broken hooks are found before a real failover. Nothing
+is failed over; HOOK_TEST=true tells hooks that the failover is simulated.
+
+Every hook runs, also after one failed whose on_failure policy would stop
+a real failover. The command fails when any hook fails.`,
+	Example: `  proxwarden hooks test 100
+  proxwarden hooks test 100 --phase pre --target-node pve2`,
+	Args: cobra.ExactArgs(1),
+	RunE: runHooksTest,
+}
+
+func init() {
+	rootCmd.AddCommand(hooksCmd)
+	hooksCmd.AddCommand(hooksTestCmd)
+
+	hooksTestCmd.Flags().String("phase", "all", "hooks to run: pre, post or all")
+	hooksTestCmd.Flags().String("source-node", "", "node the container fails over from (default: where it runs)")
+	hooksTestCmd.Flags().String("target-node", "", "node the container fails over to (default: its first failover node)")
+	hooksTestCmd.Flags().String("strategy", "", "strategy of the failover (default: the configured one)")
+	hooksTestCmd.Flags().String("trigger", history.TriggerAutomatic, "what started the failover, such as automatic, manual or node")
+	addOutputFlags(hooksTestCmd)
+}
+
+// hookTestResult is a hook run as hooks test reports it.
+type hookTestResult struct {
+	Phase     string        `json:"phase"`
+	Command   string        `json:"command"`
+	OnFailure string        `json:"on_failure"`
+	Success   bool          `json:"success"`
+	ExitCode  int           `json:"exit_code"`
+	Duration  time.Duration `json:"duration"`
+	Output    string        `json:"output,omitempty"`
+	Error     string        `json:"error,omitempty"`
+}
+
+func runHooksTest(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	containerID, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid container ID: %w", err)
+	}
+
+	phase, _ := cmd.Flags().GetString("phase")
+	var phases []string
+	switch phase {
+	case "pre", "post":
+		phases = []string{phase}
+	case "all":
+		phases = []string{"pre", "post"}
+	default:
+		return fmt.Errorf("invalid phase %q: must be pre, post or all", phase)
+	}
+
+	ctx := context.Background()
+	apiClient, err := api.NewClient(&cfg.Proxmox)
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+	container, ok, err := monitoredContainer(ctx, cfg, apiClient, containerID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("container %d is not monitored, so it is never failed over", containerID)
+	}
+
+	plan := &failover.Plan{Container: &container}
+	plan.SourceNode, _ = cmd.Flags().GetString("source-node")
+	plan.TargetNode, _ = cmd.Flags().GetString("target-node")
+	plan.Trigger, _ = cmd.Flags().GetString("trigger")
+	if plan.SourceNode == "" {
+		info, err := apiClient.GetContainer(ctx, containerID)
+		if err != nil {
+			return err
+		}
+		plan.SourceNode = info.Node
+	}
+	if plan.TargetNode == "" {
+		for _, node := range container.FailoverNodes {
+			if node != plan.SourceNode {
+				plan.TargetNode = node
+				break
+			}
+		}
+	}
+	strategy, _ := cmd.Flags().GetString("strategy")
+	if strategy == "" {
+		strategy = container.Strategy
+	}
+	if strategy == "" {
+		strategy = cfg.Failover.Strategy
+	}
+	if strategy == "" {
+		strategy = config.StrategyRestore
+	}
+	if !config.ValidStrategy(strategy) {
+		return fmt.Errorf("invalid strategy %q", strategy)
+	}
+
+	// Failing hooks are reported below rather than logged
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	entry := logrus.NewEntry(logger).WithField("container_id", containerID)
+
+	format := outputOf(cmd)
+	if format == outputTable {
+		target := plan.TargetNode
+		if target == "" {
+			target = "-"
+		}
+		fmt.Printf("Simulating failover of container %d (%s) from %s to %s by %s, trigger %s\n\n",
+			containerID, container.Name, plan.SourceNode, target, strategy, plan.Trigger)
+	}
+
+	var results []hookTestResult
+	failed := 0
+	for _, phase := range phases {
+		hooks, onFailure := cfg.Failover.PreFailoverHooks, config.HookAbort
+		if phase == "post" {
+			hooks, onFailure = cfg.Failover.PostFailoverHooks, config.HookContinue
+		}
+		env := failover.SimulatedHookEnv(phase, plan, strategy)
+		for _, h := range hooks {
+			run := hook.Run(ctx, h, env, entry.WithField("phase", phase))
+			result := hookTestResult{
+				Phase:     phase,
+				Command:   run.Command,
+				OnFailure: h.OnFailure,
+				Success:   run.Success,
+				ExitCode:  run.ExitCode,
+				Duration:  run.Duration,
+				Output:    run.Output,
+			}
+			if result.OnFailure == "" {
+				result.OnFailure = onFailure
+			}
+			if run.Error != nil {
+				result.Error = run.Error.Error()
+				failed++
+			}
+			results = append(results, result)
+		}
+	}
+
+	if format != outputTable {
+		if err := printStructured(format, results); err != nil {
+			return err
+		}
+	} else {
+		if len(results) == 0 {
+			fmt.Println("No failover hooks configured")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PHASE\tCOMMAND\tON FAILURE\tEXIT\tRESULT\tTIME")
+		fmt.Fprintln(w, "-----\t-------\t----------\t----\t------\t----")
+		for _, result := range results {
+			outcome := "ok"
+			if !result.Success {
+				outcome = "FAIL"
+			}
+			exitCode := strconv.Itoa(result.ExitCode)
+			if result.ExitCode < 0 {
+				exitCode = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				result.Phase, result.Command, result.OnFailure, exitCode, outcome, result.Duration.Round(time.Millisecond))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		for _, result := range results {
+			if result.Output == "" && result.Error == "" {
+				continue
+			}
+			fmt.Printf("\n%s: %s\n", result.Phase, result.Command)
+			if result.Error != "" {
+				fmt.Printf("  error: %s\n", result.Error)
+			}
+			for _, line := range strings.Split(strings.TrimRight(result.Output, "\n"), "\n") {
+				if line != "" {
+					fmt.Printf("  | %s\n", line)
+				}
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d hooks failed", failed, len(results))
+	}
+	return nil
+}
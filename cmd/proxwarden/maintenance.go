@@ -0,0 +1,259 @@
+package proxwarden
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/maintenance"
+	"github.com/jbutlerdev/proxwarden/internal/server"
+	"github.com/spf13/cobra"
+)
+
+var maintenanceCmd = &cobra.Command{
+	Use:   "maintenance",
+	Short: "Maintenance mode operations",
+	Long: `Put containers or nodes into maintenance mode. Health failures during
+maintenance are still reported but never trigger a failover.`,
+}
+
+var maintenanceEnableCmd = &cobra.Command{
+	Use:     "enable [container-id|node]",
+	Aliases: []string{"on"},
+	Short:   "Enable maintenance for a container or node",
+	Example: `  proxwarden maintenance on 100 --reason "database upgrade"
+  proxwarden maintenance on pve2 --for 2h`,
+	Args: cobra.ExactArgs(1),
+	RunE: runMaintenanceEnable,
+}
+
+var maintenanceDisableCmd = &cobra.Command{
+	Use:     "disable [container-id|node]",
+	Aliases: []string{"off"},
+	Short:   "Disable maintenance for a container or node",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runMaintenanceDisable,
+}
+
+var maintenanceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List active maintenance windows",
+	RunE:  runMaintenanceList,
+}
+
+var maintenanceStatusCmd = &cobra.Command{
+	Use:   "status [container-id|node]",
+	Short: "Show whether a container or node is in maintenance",
+	Long: `Show whether a container or node is in maintenance, and until when. A
+container is also in maintenance while the node it runs on is. Without an
+argument, list the active maintenance windows.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runMaintenanceStatus,
+}
+
+func init() {
+	rootCmd.AddCommand(maintenanceCmd)
+	maintenanceCmd.AddCommand(maintenanceEnableCmd)
+	maintenanceCmd.AddCommand(maintenanceDisableCmd)
+	maintenanceCmd.AddCommand(maintenanceListCmd)
+	maintenanceCmd.AddCommand(maintenanceStatusCmd)
+
+	maintenanceEnableCmd.Flags().Duration("duration", 0, "how long maintenance lasts (uses config default, negative for no expiry)")
+	maintenanceEnableCmd.Flags().Duration("for", 0, "how long maintenance lasts, same as --duration")
+	maintenanceEnableCmd.Flags().String("reason", "", "reason shown in status output")
+	maintenanceEnableCmd.MarkFlagsMutuallyExclusive("duration", "for")
+
+	addOutputFlags(maintenanceListCmd)
+	addOutputFlags(maintenanceStatusCmd)
+}
+
+// maintenanceTarget treats numeric arguments as container IDs and anything
+// else as a node name.
+func maintenanceTarget(arg string) maintenance.Kind {
+	if _, err := strconv.Atoi(arg); err == nil {
+		return maintenance.KindContainer
+	}
+	return maintenance.KindNode
+}
+
+// newDaemonClient returns a client for commands that only work against a
+// running daemon.
+func newDaemonClient() (*server.Client, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if !cfg.Server.Enabled {
+		return nil, fmt.Errorf("this command requires the daemon API server to be enabled")
+	}
+	return server.NewClient(&cfg.Server)
+}
+
+func maintenanceSummary(w maintenance.Window) string {
+	summary := "maintenance"
+	if !w.Expires.IsZero() {
+		summary += " until " + w.Expires.Format(time.RFC3339)
+	}
+	if w.Reason != "" {
+		summary += ": " + w.Reason
+	}
+	return summary
+}
+
+func runMaintenanceEnable(cmd *cobra.Command, args []string) error {
+	client, err := newDaemonClient()
+	if err != nil {
+		return err
+	}
+
+	duration, _ := cmd.Flags().GetDuration("duration")
+	if cmd.Flags().Changed("for") {
+		duration, _ = cmd.Flags().GetDuration("for")
+	}
+	reason, _ := cmd.Flags().GetString("reason")
+
+	request := server.MaintenanceRequest{
+		Kind:   maintenanceTarget(args[0]),
+		Target: args[0],
+		Reason: reason,
+	}
+	if duration != 0 {
+		request.Duration = duration.String()
+	}
+
+	window, err := client.EnableMaintenance(context.Background(), request)
+	if err != nil {
+		return fmt.Errorf("failed to enable maintenance: %w", err)
+	}
+
+	if window.Expires.IsZero() {
+		fmt.Printf("Maintenance enabled for %s %s until disabled\n", window.Kind, window.Target)
+	} else {
+		fmt.Printf("Maintenance enabled for %s %s until %s\n", window.Kind, window.Target, window.Expires.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func runMaintenanceDisable(cmd *cobra.Command, args []string) error {
+	client, err := newDaemonClient()
+	if err != nil {
+		return err
+	}
+
+	kind := maintenanceTarget(args[0])
+	if err := client.DisableMaintenance(context.Background(), kind, args[0]); err != nil {
+		return fmt.Errorf("failed to disable maintenance: %w", err)
+	}
+
+	fmt.Printf("Maintenance disabled for %s %s\n", kind, args[0])
+	return nil
+}
+
+func runMaintenanceList(cmd *cobra.Command, args []string) error {
+	client, err := newDaemonClient()
+	if err != nil {
+		return err
+	}
+
+	windows, err := client.Maintenance(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to list maintenance: %w", err)
+	}
+
+	format := outputOf(cmd)
+	if format != outputTable {
+		return printStructured(format, windows)
+	}
+
+	if len(windows) == 0 {
+		fmt.Println("No active maintenance")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tTARGET\tSINCE\tEXPIRES\tREASON")
+	fmt.Fprintln(w, "----\t------\t-----\t-------\t------")
+
+	for _, window := range windows {
+		expires := "never"
+		if !window.Expires.IsZero() {
+			expires = window.Expires.Format(time.RFC3339)
+		}
+		if window.Configured {
+			expires = "config"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			window.Kind, window.Target, window.Start.Format(time.RFC3339), expires, window.Reason)
+	}
+
+	return w.Flush()
+}
+
+// maintenanceState is whether a container or node is in maintenance, as
+// maintenance status reports it.
+type maintenanceState struct {
+	Kind          maintenance.Kind    `json:"kind"`
+	Target        string              `json:"target"`
+	InMaintenance bool                `json:"in_maintenance"`
+	Window        *maintenance.Window `json:"window,omitempty"`
+}
+
+func runMaintenanceStatus(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return runMaintenanceList(cmd, args)
+	}
+
+	client, err := newDaemonClient()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	state := maintenanceState{Kind: maintenanceTarget(args[0]), Target: args[0]}
+	if state.Kind == maintenance.KindContainer {
+		// The daemon tells which window covers the container, its own or
+		// that of its node
+		containerID, _ := strconv.Atoi(args[0])
+		container, err := client.Container(ctx, containerID)
+		if err != nil {
+			return fmt.Errorf("failed to get container %d: %w", containerID, err)
+		}
+		state.Window = container.Maintenance
+	} else {
+		windows, err := client.Maintenance(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list maintenance: %w", err)
+		}
+		for i, window := range windows {
+			if window.Kind == maintenance.KindNode && window.Target == args[0] {
+				state.Window = &windows[i]
+			}
+		}
+	}
+	state.InMaintenance = state.Window != nil
+
+	format := outputOf(cmd)
+	if format != outputTable {
+		return printStructured(format, state)
+	}
+
+	fmt.Println(describeMaintenanceState(state))
+	return nil
+}
+
+// describeMaintenanceState tells whether a container or node is in
+// maintenance, and through which window.
+func describeMaintenanceState(state maintenanceState) string {
+	if state.Window == nil {
+		return fmt.Sprintf("%s %s is not in maintenance", state.Kind, state.Target)
+	}
+	via := ""
+	if state.Window.Kind != state.Kind {
+		via = fmt.Sprintf(" through %s %s", state.Window.Kind, state.Window.Target)
+	}
+	return fmt.Sprintf("%s %s is in %s%s", state.Kind, state.Target, maintenanceSummary(*state.Window), via)
+}
@@ -0,0 +1,72 @@
+package proxwarden
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/maintenance"
+)
+
+func TestMaintenanceTarget(t *testing.T) {
+	tests := []struct {
+		arg      string
+		expected maintenance.Kind
+	}{
+		{"100", maintenance.KindContainer},
+		{"pve1", maintenance.KindNode},
+		{"100a", maintenance.KindNode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			if kind := maintenanceTarget(tt.arg); kind != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, kind)
+			}
+		})
+	}
+}
+
+func TestDescribeMaintenanceState(t *testing.T) {
+	expires := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		state    maintenanceState
+		expected string
+	}{
+		{
+			name:     "not in maintenance",
+			state:    maintenanceState{Kind: maintenance.KindContainer, Target: "100"},
+			expected: "container 100 is not in maintenance",
+		},
+		{
+			name: "own window",
+			state: maintenanceState{Kind: maintenance.KindContainer, Target: "100", Window: &maintenance.Window{
+				Kind: maintenance.KindContainer, Target: "100", Reason: "kernel upgrade", Expires: expires,
+			}},
+			expected: "container 100 is in maintenance until 2026-10-16T12:00:00Z: kernel upgrade",
+		},
+		{
+			name: "through its node",
+			state: maintenanceState{Kind: maintenance.KindContainer, Target: "100", Window: &maintenance.Window{
+				Kind: maintenance.KindNode, Target: "pve1",
+			}},
+			expected: "container 100 is in maintenance through node pve1",
+		},
+		{
+			name: "node",
+			state: maintenanceState{Kind: maintenance.KindNode, Target: "pve1", Window: &maintenance.Window{
+				Kind: maintenance.KindNode, Target: "pve1", Reason: "drained",
+			}},
+			expected: "node pve1 is in maintenance: drained",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if description := describeMaintenanceState(tt.state); description != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, description)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package proxwarden
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/monitor"
+	"github.com/sirupsen/logrus"
+)
+
+// Exit codes of status --nagios, as Nagios and Icinga plugins return them.
+const (
+	nagiosOK       = 0
+	nagiosWarning  = 1
+	nagiosCritical = 2
+	nagiosUnknown  = 3
+)
+
+var nagiosLabels = []string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}
+
+// runStatusNagios prints the status of the monitored containers as a
+// single line with performance data and returns the exit code of the
+// worst state. Failing to find out is UNKNOWN.
+func runStatusNagios() int {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("PROXWARDEN UNKNOWN - failed to load configuration: %v\n", err)
+		return nagiosUnknown
+	}
+	report, err := collectStatus(context.Background(), cfg, logger)
+	if err != nil {
+		fmt.Printf("PROXWARDEN UNKNOWN - %v\n", err)
+		return nagiosUnknown
+	}
+
+	code, summary := nagiosSummary(report, cfg.Server.Enabled)
+	fmt.Printf("PROXWARDEN %s - %s\n", nagiosLabels[code], summary)
+	return code
+}
+
+// nagiosSummary returns the worst state of the containers of report, or
+// UNKNOWN if the daemon should have answered but did not, with a summary and
+// performance data.
+func nagiosSummary(report *statusReport, daemonExpected bool) (int, string) {
+	code := nagiosOK
+	counts := make([]int, 3)
+	failures := 0
+	var problems []string
+	for _, status := range report.Containers {
+		state := nagiosState(status)
+		counts[state]++
+		failures += status.FailureCount
+		if state > code {
+			code = state
+		}
+		if state != nagiosOK {
+			problems = append(problems, fmt.Sprintf("%d (%s) %s", status.ID, status.Name, nagiosHealth(status)))
+		}
+	}
+
+	var notes []string
+	if report.Pause != nil && report.Pause.Paused {
+		notes = append(notes, "automatic failover paused")
+		if code == nagiosOK {
+			code = nagiosWarning
+		}
+	}
+	// Without the daemon the health shown is only what Proxmox reports
+	if daemonExpected && !report.DaemonReachable {
+		notes = append([]string{"daemon not reachable"}, notes...)
+		code = nagiosUnknown
+	}
+
+	var summary string
+	switch {
+	case len(report.Containers) == 0:
+		summary = "no containers monitored"
+	case code == nagiosOK || len(problems) == 0:
+		summary = fmt.Sprintf("%d of %d containers OK", counts[nagiosOK], len(report.Containers))
+	default:
+		summary = fmt.Sprintf("%d critical, %d warning of %d containers: %s",
+			counts[nagiosCritical], counts[nagiosWarning], len(report.Containers), strings.Join(problems, ", "))
+	}
+	if len(notes) > 0 {
+		summary += "; " + strings.Join(notes, ", ")
+	}
+
+	perfdata := fmt.Sprintf("containers=%d;;;0 ok=%d;;;0 warning=%d;;;0 critical=%d;;;0 failures=%d;;;0",
+		len(report.Containers), counts[nagiosOK], counts[nagiosWarning], counts[nagiosCritical], failures)
+	return code, summary + " | " + perfdata
+}
+
+// nagiosState returns whether a container is OK, WARNING or CRITICAL.
+func nagiosState(status containerStatus) int {
+	switch monitor.State(status.HealthStatus) {
+	case monitor.StateHealthy:
+		return nagiosOK
+	case monitor.StateFailing, monitor.StateFailoverInProgress, monitor.StateFailedOver:
+		return nagiosCritical
+	}
+	switch status.HealthStatus {
+	case "reachable":
+		// Only Proxmox could be asked, so a running container is all there
+		// is to tell
+		if status.Status == "running" {
+			return nagiosOK
+		}
+		return nagiosCritical
+	case "error":
+		return nagiosCritical
+	}
+	// In maintenance, degraded, flapping, skipped or not checked yet
+	return nagiosWarning
+}
+
+// nagiosHealth describes the health of a container that is not OK.
+func nagiosHealth(status containerStatus) string {
+	if status.HealthStatus == "reachable" {
+		return status.Status
+	}
+	return status.HealthStatus
+}
@@ -0,0 +1,110 @@
+package proxwarden
+
+import (
+	"testing"
+
+	"github.com/jbutlerdev/proxwarden/internal/monitor"
+	"github.com/jbutlerdev/proxwarden/internal/server"
+)
+
+func TestNagiosState(t *testing.T) {
+	tests := []struct {
+		name     string
+		health   string
+		status   string
+		expected int
+	}{
+		{"healthy", string(monitor.StateHealthy), "running", nagiosOK},
+		{"maintenance", string(monitor.StateMaintenance), "running", nagiosWarning},
+		{"degraded", string(monitor.StateDegraded), "running", nagiosWarning},
+		{"not checked yet", string(monitor.StateUnknown), "running", nagiosWarning},
+		{"failing", string(monitor.StateFailing), "running", nagiosCritical},
+		{"failover in progress", string(monitor.StateFailoverInProgress), "stopped", nagiosCritical},
+		{"failed over", string(monitor.StateFailedOver), "running", nagiosCritical},
+		{"running without the daemon", "reachable", "running", nagiosOK},
+		{"stopped without the daemon", "reachable", "stopped", nagiosCritical},
+		{"proxmox error", "error", "", nagiosCritical},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := nagiosState(containerStatus{ID: 100, HealthStatus: tt.health, Status: tt.status})
+			if state != tt.expected {
+				t.Errorf("Expected %s, got %s", nagiosLabels[tt.expected], nagiosLabels[state])
+			}
+		})
+	}
+}
+
+func TestNagiosSummary(t *testing.T) {
+	healthy := containerStatus{ID: 100, Name: "web", Status: "running", HealthStatus: string(monitor.StateHealthy)}
+	maintenance := containerStatus{ID: 101, Name: "db", Status: "running", HealthStatus: string(monitor.StateMaintenance)}
+	degraded := containerStatus{ID: 102, Name: "cache", Status: "running", HealthStatus: string(monitor.StateDegraded), FailureCount: 1}
+	failing := containerStatus{ID: 103, Name: "api", Status: "running", HealthStatus: string(monitor.StateFailing), FailureCount: 3}
+	failedOver := containerStatus{ID: 104, Name: "mail", Status: "running", HealthStatus: string(monitor.StateFailedOver)}
+
+	tests := []struct {
+		name           string
+		report         statusReport
+		daemonExpected bool
+		code           int
+		summary        string
+	}{
+		{
+			name:    "no containers",
+			report:  statusReport{DaemonReachable: true},
+			code:    nagiosOK,
+			summary: "no containers monitored | containers=0;;;0 ok=0;;;0 warning=0;;;0 critical=0;;;0 failures=0;;;0",
+		},
+		{
+			name:           "ok",
+			report:         statusReport{Containers: []containerStatus{healthy}, DaemonReachable: true},
+			daemonExpected: true,
+			code:           nagiosOK,
+			summary:        "1 of 1 containers OK | containers=1;;;0 ok=1;;;0 warning=0;;;0 critical=0;;;0 failures=0;;;0",
+		},
+		{
+			name:    "warning",
+			report:  statusReport{Containers: []containerStatus{healthy, maintenance, degraded}, DaemonReachable: true},
+			code:    nagiosWarning,
+			summary: "0 critical, 2 warning of 3 containers: 101 (db) maintenance, 102 (cache) degraded | containers=3;;;0 ok=1;;;0 warning=2;;;0 critical=0;;;0 failures=1;;;0",
+		},
+		{
+			name:    "critical",
+			report:  statusReport{Containers: []containerStatus{degraded, failing, failedOver}, DaemonReachable: true},
+			code:    nagiosCritical,
+			summary: "2 critical, 1 warning of 3 containers: 102 (cache) degraded, 103 (api) failing, 104 (mail) failed_over | containers=3;;;0 ok=0;;;0 warning=1;;;0 critical=2;;;0 failures=4;;;0",
+		},
+		{
+			name:    "paused",
+			report:  statusReport{Containers: []containerStatus{healthy}, Pause: &server.PauseStatus{Paused: true}, DaemonReachable: true},
+			code:    nagiosWarning,
+			summary: "1 of 1 containers OK; automatic failover paused | containers=1;;;0 ok=1;;;0 warning=0;;;0 critical=0;;;0 failures=0;;;0",
+		},
+		{
+			name:           "daemon unreachable",
+			report:         statusReport{Containers: []containerStatus{healthy, failing}},
+			daemonExpected: true,
+			code:           nagiosUnknown,
+			summary:        "1 critical, 0 warning of 2 containers: 103 (api) failing; daemon not reachable | containers=2;;;0 ok=1;;;0 warning=0;;;0 critical=1;;;0 failures=3;;;0",
+		},
+		{
+			name:    "daemon not expected",
+			report:  statusReport{Containers: []containerStatus{healthy}},
+			code:    nagiosOK,
+			summary: "1 of 1 containers OK | containers=1;;;0 ok=1;;;0 warning=0;;;0 critical=0;;;0 failures=0;;;0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, summary := nagiosSummary(&tt.report, tt.daemonExpected)
+			if code != tt.code {
+				t.Errorf("Expected %s, got %s", nagiosLabels[tt.code], nagiosLabels[code])
+			}
+			if summary != tt.summary {
+				t.Errorf("Expected summary\n%q\ngot\n%q", tt.summary, summary)
+			}
+		})
+	}
+}
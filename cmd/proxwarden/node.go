@@ -0,0 +1,316 @@
+package proxwarden
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/failover"
+	"github.com/jbutlerdev/proxwarden/internal/maintenance"
+	"github.com/jbutlerdev/proxwarden/internal/monitor"
+	"github.com/jbutlerdev/proxwarden/internal/server"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var nodeCmd = &cobra.Command{
+	Use:     "node",
+	Aliases: []string{"nodes"},
+	Short:   "Node operations",
+}
+
+var nodeListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the nodes of the cluster",
+	Long: `List the nodes of the cluster with their status, CPU and memory usage,
+the monitored containers they host, and the monitored containers that fail
+over to them.`,
+	Args: cobra.NoArgs,
+	RunE: runNodeList,
+}
+
+var nodeDrainCmd = &cobra.Command{
+	Use:   "drain [node]",
+	Short: "Move every monitored container off a node",
+	Long: `Move every monitored container off a node for planned maintenance, in
+priority order. Containers are migrated where possible and restored from
+backup otherwise. The node is put into maintenance first, when the daemon is
+running, so the daemon does not fail over containers while they move.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runNodeDrain,
+}
+
+func init() {
+	rootCmd.AddCommand(nodeCmd)
+	nodeCmd.AddCommand(nodeDrainCmd)
+	nodeCmd.AddCommand(nodeListCmd)
+
+	nodeDrainCmd.Flags().String("target-node", "", "move all containers to this node instead of selecting one per container")
+	nodeDrainCmd.Flags().Bool("no-maintenance", false, "do not put the node into maintenance first")
+	addConfirmFlag(nodeDrainCmd)
+
+	addOutputFlags(nodeListCmd)
+}
+
+// nodeEntry is a node as node list reports it.
+type nodeEntry struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Online bool   `json:"online"`
+	// CPU is the load as a fraction of MaxCPU cores
+	CPU    float64 `json:"cpu"`
+	MaxCPU int     `json:"max_cpu"`
+	Mem    uint64  `json:"mem"`
+	MaxMem uint64  `json:"max_mem"`
+	// Containers are the monitored containers running on the node
+	Containers []int `json:"containers"`
+	// FailoverFor are the monitored containers listing the node in their
+	// failover nodes
+	FailoverFor []int `json:"failover_for"`
+}
+
+func runNodeList(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	apiClient, err := api.NewClient(&cfg.Proxmox)
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	ctx := context.Background()
+	nodes, err := apiClient.GetNodes(ctx)
+	if err != nil {
+		return err
+	}
+	resources, err := apiClient.GetGuests(ctx)
+	if err != nil {
+		return err
+	}
+
+	hosted := make(map[string][]int)
+	targets := make(map[string][]int)
+	for _, resource := range resources {
+		container, enrollment := monitor.Enrolled(cfg.Monitoring, resource)
+		if enrollment == "" {
+			continue
+		}
+		hosted[resource.Node] = append(hosted[resource.Node], resource.ID)
+		for _, node := range container.FailoverNodes {
+			targets[node] = append(targets[node], resource.ID)
+		}
+	}
+
+	entries := make([]nodeEntry, 0, len(nodes))
+	for _, node := range nodes {
+		entry := nodeEntry{
+			Name:        node.Name,
+			Status:      node.Status,
+			Online:      node.Online,
+			CPU:         node.CPU,
+			MaxCPU:      node.MaxCPU,
+			Mem:         node.Mem,
+			MaxMem:      node.MaxMem,
+			Containers:  append([]int{}, hosted[node.Name]...),
+			FailoverFor: append([]int{}, targets[node.Name]...),
+		}
+		sort.Ints(entry.Containers)
+		sort.Ints(entry.FailoverFor)
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+
+	format := outputOf(cmd)
+	if format != outputTable {
+		return printStructured(format, entries)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tSTATUS\tCPU\tMEMORY\tMONITORED\tFAILOVER TARGET")
+	fmt.Fprintln(w, "----\t------\t---\t------\t---------\t---------------")
+
+	for _, entry := range entries {
+		cpu, memory := "-", "-"
+		if entry.Online {
+			cpu = fmt.Sprintf("%.1f%% of %d", entry.CPU*100, entry.MaxCPU)
+			memory = fmt.Sprintf("%s / %s", formatBytes(entry.Mem), formatBytes(entry.MaxMem))
+		}
+		target := "no"
+		if len(entry.FailoverFor) > 0 {
+			target = fmt.Sprintf("yes (%d containers)", len(entry.FailoverFor))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n",
+			entry.Name, entry.Status, cpu, memory, len(entry.Containers), target)
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	var missing []string
+	for node := range targets {
+		if !hasNode(entries, node) {
+			missing = append(missing, node)
+		}
+	}
+	sort.Strings(missing)
+	for _, node := range missing {
+		fmt.Printf("Warning: failover node %s is not in the cluster\n", node)
+	}
+	return nil
+}
+
+func hasNode(entries []nodeEntry, name string) bool {
+	for _, entry := range entries {
+		if entry.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// formatBytes returns a size in binary units, such as "12.5 GiB".
+func formatBytes(size uint64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value, exp := float64(size)/unit, 0
+	for value >= unit && exp < 4 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGTP"[exp])
+}
+
+func runNodeDrain(cmd *cobra.Command, args []string) error {
+	node := args[0]
+	targetNode, _ := cmd.Flags().GetString("target-node")
+	noMaintenance, _ := cmd.Flags().GetBool("no-maintenance")
+
+	ctx := context.Background()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	summary, details, err := drainSummary(ctx, cfg, node, targetNode, noMaintenance)
+	if err != nil {
+		return err
+	}
+	if err := confirm(cmd, summary, details...); err != nil {
+		return err
+	}
+
+	if !noMaintenance {
+		if err := enableNodeMaintenance(ctx, node); err != nil {
+			fmt.Printf("Warning: could not put node %s into maintenance: %v\n", node, err)
+		} else {
+			fmt.Printf("Node %s is in maintenance until disabled\n", node)
+		}
+	}
+
+	engine, err := failover.New(cfg, logrus.New())
+	if err != nil {
+		return err
+	}
+
+	err = engine.EvacuateNode(ctx, node, targetNode, func(progress failover.EvacuationProgress) {
+		fmt.Println(drainProgress(progress))
+	})
+	if err != nil {
+		return fmt.Errorf("drain of node %s incomplete: %w", node, err)
+	}
+
+	fmt.Printf("Node %s drained\n", node)
+	return nil
+}
+
+// drainProgress describes a container of a drain that finished.
+func drainProgress(progress failover.EvacuationProgress) string {
+	result := progress.Result
+	if !result.Success {
+		return fmt.Sprintf("[%d/%d] container %d failed: %v", progress.Done, progress.Total, result.ContainerID, result.Error)
+	}
+	return fmt.Sprintf("[%d/%d] container %d moved to %s (%s, %s)",
+		progress.Done, progress.Total, result.ContainerID, result.TargetNode, result.Strategy, result.Duration.Round(time.Second))
+}
+
+// drainSummary describes what node drain is about to do: the monitored
+// containers on node, in the order they are moved.
+func drainSummary(ctx context.Context, cfg *config.Config, node, targetNode string, noMaintenance bool) (string, []string, error) {
+	apiClient, err := api.NewClient(&cfg.Proxmox)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create API client: %w", err)
+	}
+	resources, err := apiClient.GetGuests(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	summary, details := describeDrain(cfg, resources, node, targetNode, noMaintenance)
+	return summary, details, nil
+}
+
+// describeDrain lists the monitored containers of resources on node in the
+// order a drain moves them, with where they go.
+func describeDrain(cfg *config.Config, resources []api.ClusterResource, node, targetNode string, noMaintenance bool) (string, []string) {
+	var containers []config.ContainerConfig
+	for _, resource := range resources {
+		if resource.Node != node {
+			continue
+		}
+		// Drains move the configured containers only, as the engine of the
+		// CLI knows no discovered ones
+		for _, container := range cfg.Monitoring.Containers {
+			if container.ID == resource.ID {
+				if container.Name == "" {
+					container.Name = resource.Name
+				}
+				containers = append(containers, container)
+			}
+		}
+	}
+	sort.SliceStable(containers, func(i, j int) bool {
+		if containers[i].Priority != containers[j].Priority {
+			return containers[i].Priority < containers[j].Priority
+		}
+		return containers[i].ID < containers[j].ID
+	})
+
+	var details []string
+	for _, container := range containers {
+		target := targetNode
+		if target == "" {
+			target = strings.Join(container.FailoverNodes, ", ")
+		}
+		details = append(details, fmt.Sprintf("Container %d (%s) to %s", container.ID, container.Name, target))
+	}
+	if !noMaintenance {
+		details = append(details, fmt.Sprintf("Node %s stays in maintenance until disabled", node))
+	}
+	return fmt.Sprintf("%d monitored containers will be moved off node %s:", len(containers), node), details
+}
+
+// enableNodeMaintenance puts node into maintenance without expiry through
+// the daemon.
+func enableNodeMaintenance(ctx context.Context, node string) error {
+	client, err := newDaemonClient()
+	if err != nil {
+		return err
+	}
+	_, err = client.EnableMaintenance(ctx, server.MaintenanceRequest{
+		Kind:     maintenance.KindNode,
+		Target:   node,
+		Duration: "-1s",
+		Reason:   "drain",
+	})
+	return err
+}
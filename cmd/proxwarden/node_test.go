@@ -0,0 +1,100 @@
+package proxwarden
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/failover"
+)
+
+func TestDescribeDrain(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Monitoring.Containers = []config.ContainerConfig{
+		{ID: 101, Priority: 2, FailoverNodes: []string{"a", "b"}},
+		{ID: 102, Name: "db", Priority: 1, FailoverNodes: []string{"a"}},
+		{ID: 103, Priority: 1, FailoverNodes: []string{"b"}},
+		{ID: 104, Priority: 0, FailoverNodes: []string{"a"}},
+	}
+	resources := []api.ClusterResource{
+		{ID: 101, Name: "web", Node: "source"},
+		{ID: 103, Name: "cache", Node: "source"},
+		{ID: 102, Name: "postgres", Node: "source"},
+		// Not on the node, and not monitored
+		{ID: 104, Name: "dns", Node: "a"},
+		{ID: 200, Name: "scratch", Node: "source"},
+	}
+
+	tests := []struct {
+		name          string
+		targetNode    string
+		noMaintenance bool
+		expected      []string
+	}{
+		{
+			name: "failover nodes",
+			expected: []string{
+				"Container 102 (db) to a",
+				"Container 103 (cache) to b",
+				"Container 101 (web) to a, b",
+				"Node source stays in maintenance until disabled",
+			},
+		},
+		{
+			name:          "target override without maintenance",
+			targetNode:    "c",
+			noMaintenance: true,
+			expected: []string{
+				"Container 102 (db) to c",
+				"Container 103 (cache) to c",
+				"Container 101 (web) to c",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary, details := describeDrain(cfg, resources, "source", tt.targetNode, tt.noMaintenance)
+			if expected := "3 monitored containers will be moved off node source:"; summary != expected {
+				t.Errorf("Expected summary %q, got %q", expected, summary)
+			}
+			if !reflect.DeepEqual(details, tt.expected) {
+				t.Errorf("Expected details %q, got %q", tt.expected, details)
+			}
+		})
+	}
+}
+
+func TestDrainProgress(t *testing.T) {
+	tests := []struct {
+		name     string
+		progress failover.EvacuationProgress
+		expected string
+	}{
+		{
+			name: "moved",
+			progress: failover.EvacuationProgress{Done: 1, Total: 3, Result: &failover.FailoverResult{
+				ContainerID: 102, TargetNode: "a", Strategy: "migrate", Success: true, Duration: 42400 * time.Millisecond,
+			}},
+			expected: "[1/3] container 102 moved to a (migrate, 42s)",
+		},
+		{
+			name: "failed",
+			progress: failover.EvacuationProgress{Done: 3, Total: 3, Result: &failover.FailoverResult{
+				ContainerID: 101, Error: errors.New("no backup found"),
+			}},
+			expected: "[3/3] container 101 failed: no backup found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if line := drainProgress(tt.progress); line != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, line)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package proxwarden
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/notify"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var notifyCmd = &cobra.Command{
+	Use:   "notify",
+	Short: "Notification operations",
+}
+
+var notifyTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Send a test notification through every provider",
+	Long: `Send a test event about a made-up failover of a container through every
+configured notification provider, whatever the routes, and report whether
+each one accepted it. The event is marked as a test, so incident providers
+open a separate incident for it rather than updating a real one.
+
+With --render, show the event as every provider would send it, with the
+provider's templates applied, without sending it.`,
+	Args: cobra.NoArgs,
+	RunE: runNotifyTest,
+}
+
+var notifyEscalationsCmd = &cobra.Command{
+	Use:   "escalations",
+	Short: "List open notification escalations",
+	Args:  cobra.NoArgs,
+	RunE:  runNotifyEscalations,
+}
+
+var notifyAckCmd = &cobra.Command{
+	Use:   "ack [escalation-id]",
+	Short: "Acknowledge an escalation",
+	Long: `Acknowledge an escalation so that its later steps are not notified. The
+escalation stays listed until the failure is resolved or for a day.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runNotifyAck,
+}
+
+func init() {
+	rootCmd.AddCommand(notifyCmd)
+	notifyCmd.AddCommand(notifyTestCmd)
+	notifyCmd.AddCommand(notifyEscalationsCmd)
+	notifyCmd.AddCommand(notifyAckCmd)
+
+	notifyTestCmd.Flags().Bool("render", false, "show the rendered notifications instead of sending them")
+	notifyTestCmd.Flags().String("event", string(notify.EventFailoverFailed), "type of the test event, such as failover_failed or check_warning")
+	notifyTestCmd.Flags().Int("container", 0, "container the test event is about (default: the first monitored container)")
+	notifyTestCmd.Flags().StringSlice("provider", nil, "only test these providers (default: all)")
+
+	addOutputFlags(notifyEscalationsCmd)
+}
+
+func runNotifyTest(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	names, _ := cmd.Flags().GetStringSlice("provider")
+	providers, err := selectProviders(cfg.Notifications.Providers, names)
+	if err != nil {
+		return err
+	}
+	cfg.Notifications.Providers = providers
+	if len(providers) == 0 {
+		return fmt.Errorf("no notification providers configured")
+	}
+
+	event, err := testEvent(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	dispatcher, err := notify.NewDispatcher(&cfg.Notifications, logrus.StandardLogger())
+	if err != nil {
+		return err
+	}
+
+	render, _ := cmd.Flags().GetBool("render")
+	if render {
+		renderNotifications(dispatcher, providers, event)
+		return nil
+	}
+
+	fmt.Printf("Sending a test %s event about container %d to %d provider(s)\n\n", event.Type, event.ContainerID, len(providers))
+	failed := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROVIDER\tTYPE\tRESULT\tTIME")
+	fmt.Fprintln(w, "--------\t----\t------\t----")
+	for i, delivery := range dispatcher.SendTest(context.Background(), event) {
+		result := "ok"
+		if delivery.Err != nil {
+			result = "FAILED: " + delivery.Err.Error()
+			failed++
+		}
+		if !delivery.Routed {
+			result += " (not routed this event)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", delivery.Provider, providers[i].Type, result, delivery.Duration.Round(time.Millisecond))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d providers failed", failed, len(providers))
+	}
+	return nil
+}
+
+// selectProviders returns the providers named names, or all of them
+// without names.
+func selectProviders(providers []config.NotificationProvider, names []string) ([]config.NotificationProvider, error) {
+	if len(names) == 0 {
+		return providers, nil
+	}
+	var selected []config.NotificationProvider
+	for _, name := range names {
+		found := false
+		for _, provider := range providers {
+			if provider.Name == name {
+				selected = append(selected, provider)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown notification provider %q", name)
+		}
+	}
+	return selected, nil
+}
+
+// renderNotifications prints the event as every provider would send it.
+func renderNotifications(dispatcher *notify.Dispatcher, providers []config.NotificationProvider, event notify.Event) {
+	for i, rendering := range dispatcher.Render(event) {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("Provider %s (%s)", rendering.Provider, providers[i].Type)
+		if !rendering.Routed {
+			fmt.Print(", not routed this event")
+		}
+		fmt.Println()
+		if rendering.Err != nil {
+			fmt.Printf("  Error:   %v (the default is sent)\n", rendering.Err)
+		}
+		title := rendering.Event.Title
+		if title == "" {
+			title = "(default)"
+		}
+		fmt.Printf("  Title:   %s\n", title)
+		fmt.Printf("  Message: %s\n", rendering.Event.Message)
+	}
+}
+
+// testEvent returns the test event the flags of cmd ask for.
+func testEvent(cmd *cobra.Command, cfg *config.Config) (notify.Event, error) {
+	name, _ := cmd.Flags().GetString("event")
+	eventType, err := notify.ParseEventType(name)
+	if err != nil {
+		return notify.Event{}, err
+	}
+
+	containerID, _ := cmd.Flags().GetInt("container")
+	containerName := ""
+	for _, container := range cfg.Monitoring.Containers {
+		if containerID == 0 || container.ID == containerID {
+			containerID, containerName = container.ID, container.Name
+			break
+		}
+	}
+	if containerID == 0 {
+		containerID = 100
+	}
+	return notify.SampleEvent(eventType, containerID, containerName), nil
+}
+
+func runNotifyEscalations(cmd *cobra.Command, args []string) error {
+	client, err := newDaemonClient()
+	if err != nil {
+		return err
+	}
+
+	escalations, err := client.Escalations(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to list escalations: %w", err)
+	}
+
+	format := outputOf(cmd)
+	if format != outputTable {
+		return printStructured(format, escalations)
+	}
+
+	if len(escalations) == 0 {
+		fmt.Println("No open escalations")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tPOLICY\tEVENT\tCONTAINER\tSTARTED\tSTEP\tACKNOWLEDGED")
+	fmt.Fprintln(w, "--\t------\t-----\t---------\t-------\t----\t------------")
+
+	for _, escalation := range escalations {
+		container := "-"
+		if escalation.Event.ContainerID != 0 {
+			container = strconv.Itoa(escalation.Event.ContainerID)
+		}
+		acknowledged := "no"
+		if escalation.Acknowledged() {
+			acknowledged = escalation.AcknowledgedBy + " at " + escalation.AcknowledgedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%d/%d\t%s\n",
+			escalation.ID, escalation.Policy, escalation.Event.Type, container,
+			escalation.Started.Format(time.RFC3339), escalation.Step, escalation.Steps, acknowledged)
+	}
+
+	return w.Flush()
+}
+
+func runNotifyAck(cmd *cobra.Command, args []string) error {
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid escalation ID: %s", args[0])
+	}
+	client, err := newDaemonClient()
+	if err != nil {
+		return err
+	}
+
+	escalation, err := client.AcknowledgeEscalation(context.Background(), id)
+	if err != nil {
+		return fmt.Errorf("failed to acknowledge escalation: %w", err)
+	}
+	fmt.Printf("Escalation %d acknowledged by %s\n", escalation.ID, escalation.AcknowledgedBy)
+	return nil
+}
@@ -0,0 +1,121 @@
+package proxwarden
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats of --output.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+	outputCSV   = "csv"
+)
+
+// outputFormat is the value of --output, checked as the flag is parsed so
+// that a mistyped format fails a command before it does anything.
+type outputFormat struct {
+	value   string
+	formats []string
+}
+
+func (f *outputFormat) String() string { return f.value }
+
+func (f *outputFormat) Type() string { return "format" }
+
+func (f *outputFormat) Set(value string) error {
+	for _, format := range f.formats {
+		if value == format {
+			f.value = value
+			return nil
+		}
+	}
+	return fmt.Errorf("must be %s", formatList(f.formats))
+}
+
+// formatList joins formats as "table, json or yaml".
+func formatList(formats []string) string {
+	last := len(formats) - 1
+	return strings.Join(formats[:last], ", ") + " or " + formats[last]
+}
+
+// addOutputFlags adds --output to cmd, and --json as its shorthand.
+func addOutputFlags(cmd *cobra.Command) {
+	addFormatFlags(cmd, outputTable, outputJSON, outputYAML)
+}
+
+// addCSVOutputFlags adds --output to a command whose rows can also be
+// written as CSV, and --json as its shorthand.
+func addCSVOutputFlags(cmd *cobra.Command) {
+	addFormatFlags(cmd, outputTable, outputJSON, outputYAML, outputCSV)
+}
+
+func addFormatFlags(cmd *cobra.Command, formats ...string) {
+	format := &outputFormat{value: outputTable, formats: formats}
+	cmd.Flags().VarP(format, "output", "o", "output format: "+formatList(formats))
+	cmd.Flags().Bool("json", false, "output in JSON format, short for --output json")
+	cmd.MarkFlagsMutuallyExclusive("output", "json")
+}
+
+// outputOf returns the format the flags of cmd ask for.
+func outputOf(cmd *cobra.Command) string {
+	if jsonOutput, _ := cmd.Flags().GetBool("json"); jsonOutput {
+		return outputJSON
+	}
+	return cmd.Flags().Lookup("output").Value.String()
+}
+
+// printStructured writes v to stdout as JSON or YAML. Both use the JSON
+// field names, so scripts can switch between them.
+func printStructured(format string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	if format == outputJSON {
+		fmt.Println(string(data))
+		return nil
+	}
+
+	// JSON is YAML, so decoding it as a YAML document keeps the order of
+	// the fields; only the JSON flow style is dropped
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to convert to YAML: %w", err)
+	}
+	blockStyle(&doc)
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+	return encoder.Close()
+}
+
+// printCSV writes a header and rows to stdout as CSV.
+func printCSV(header []string, rows [][]string) error {
+	w := csv.NewWriter(os.Stdout)
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+// blockStyle resets the style of node and its children, so they are
+// written in YAML's block style with only the quoting YAML needs.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
@@ -0,0 +1,184 @@
+package proxwarden
+
+import (
+	"io"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/jbutlerdev/proxwarden/internal/server"
+	"github.com/spf13/cobra"
+)
+
+// captureStdout returns what f writes to stdout.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+	f()
+	w.Close()
+	return <-output
+}
+
+func TestFormatList(t *testing.T) {
+	tests := []struct {
+		formats  []string
+		expected string
+	}{
+		{[]string{outputTable, outputJSON}, "table or json"},
+		{[]string{outputTable, outputJSON, outputYAML}, "table, json or yaml"},
+		{[]string{outputTable, outputJSON, outputYAML, outputCSV}, "table, json, yaml or csv"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			if list := formatList(tt.formats); list != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, list)
+			}
+		})
+	}
+}
+
+func TestOutputOf(t *testing.T) {
+	tests := []struct {
+		name      string
+		csv       bool
+		args      []string
+		expected  string
+		expectErr bool
+	}{
+		{name: "table by default", expected: outputTable},
+		{name: "output", args: []string{"--output", "yaml"}, expected: outputYAML},
+		{name: "shorthand", args: []string{"-o", "json"}, expected: outputJSON},
+		{name: "json flag", args: []string{"--json"}, expected: outputJSON},
+		{name: "csv", csv: true, args: []string{"-o", "csv"}, expected: outputCSV},
+		{name: "csv not supported", args: []string{"-o", "csv"}, expectErr: true},
+		{name: "unknown format", args: []string{"-o", "xml"}, expectErr: true},
+		{name: "output and json", args: []string{"-o", "yaml", "--json"}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var format string
+			cmd := &cobra.Command{
+				Use:           "test",
+				SilenceErrors: true,
+				SilenceUsage:  true,
+				RunE: func(cmd *cobra.Command, args []string) error {
+					format = outputOf(cmd)
+					return nil
+				},
+			}
+			if tt.csv {
+				addCSVOutputFlags(cmd)
+			} else {
+				addOutputFlags(cmd)
+			}
+			cmd.SetArgs(tt.args)
+
+			err := cmd.Execute()
+			if tt.expectErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if format != tt.expected {
+				t.Errorf("Expected format %q, got %q", tt.expected, format)
+			}
+		})
+	}
+}
+
+func TestPrintStructured(t *testing.T) {
+	value := struct {
+		Name  string   `json:"name"`
+		Count int      `json:"count"`
+		Tags  []string `json:"tags,omitempty"`
+		Empty []string `json:"empty"`
+	}{Name: "web", Count: 2, Tags: []string{"prod", "db"}, Empty: []string{}}
+
+	tests := []struct {
+		name     string
+		format   string
+		value    interface{}
+		expected string
+	}{
+		{
+			name:     "json",
+			format:   outputJSON,
+			value:    value,
+			expected: "{\n  \"name\": \"web\",\n  \"count\": 2,\n  \"tags\": [\n    \"prod\",\n    \"db\"\n  ],\n  \"empty\": []\n}\n",
+		},
+		{
+			name:     "yaml keeps the JSON names and order",
+			format:   outputYAML,
+			value:    value,
+			expected: "name: web\ncount: 2\ntags:\n  - prod\n  - db\nempty: []\n",
+		},
+		{
+			name:     "no failovers awaiting approval",
+			format:   outputJSON,
+			value:    awaitingApproval(nil),
+			expected: "[]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			output := captureStdout(t, func() { err = printStructured(tt.format, tt.value) })
+			if err != nil {
+				t.Fatalf("printStructured failed: %v", err)
+			}
+			if output != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, output)
+			}
+		})
+	}
+}
+
+func TestPrintCSV(t *testing.T) {
+	var err error
+	output := captureStdout(t, func() {
+		err = printCSV([]string{"id", "error"}, [][]string{
+			{"101", ""},
+			{"102", "ping failed, \"timeout\""},
+		})
+	})
+	if err != nil {
+		t.Fatalf("printCSV failed: %v", err)
+	}
+	if expected := "id,error\n101,\n102,\"ping failed, \"\"timeout\"\"\"\n"; output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+}
+
+func TestAwaitingApproval(t *testing.T) {
+	failovers := []server.FailoverStatus{
+		{ContainerID: 101},
+		{ContainerID: 102, Approval: &server.ApprovalStatus{}},
+		{ContainerID: 103, Queued: true},
+	}
+	var ids []int
+	for _, pending := range awaitingApproval(failovers) {
+		ids = append(ids, pending.ContainerID)
+	}
+	if expected := []int{102}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Expected failovers %v, got %v", expected, ids)
+	}
+}
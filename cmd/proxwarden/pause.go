@@ -0,0 +1,63 @@
+package proxwarden
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var pauseCmd = &cobra.Command{
+	Use:   "pause",
+	Short: "Pause automatic failover cluster-wide",
+	Long: `Pause automatic failover without stopping the daemon. Health checks keep
+running and failures are still reported, but no container or node is failed
+over until resume is run. Manual failovers are not affected.`,
+	Args: cobra.NoArgs,
+	RunE: runPause,
+}
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume automatic failover after a pause",
+	Args:  cobra.NoArgs,
+	RunE:  runResume,
+}
+
+func init() {
+	rootCmd.AddCommand(pauseCmd)
+	rootCmd.AddCommand(resumeCmd)
+
+	pauseCmd.Flags().String("reason", "", "reason shown in status output")
+}
+
+func runPause(cmd *cobra.Command, args []string) error {
+	client, err := newDaemonClient()
+	if err != nil {
+		return err
+	}
+
+	reason, _ := cmd.Flags().GetString("reason")
+	status, err := client.Pause(context.Background(), reason)
+	if err != nil {
+		return fmt.Errorf("failed to pause failover: %w", err)
+	}
+
+	fmt.Printf("Automatic failover paused since %s\n", status.Since.Format(time.RFC3339))
+	return nil
+}
+
+func runResume(cmd *cobra.Command, args []string) error {
+	client, err := newDaemonClient()
+	if err != nil {
+		return err
+	}
+
+	if _, err := client.Resume(context.Background()); err != nil {
+		return fmt.Errorf("failed to resume failover: %w", err)
+	}
+
+	fmt.Println("Automatic failover resumed")
+	return nil
+}
@@ -0,0 +1,65 @@
+package proxwarden
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// progressWidth is the number of cells of a progress bar.
+const progressWidth = 30
+
+// progressBar redraws a single progress line on a terminal. Elsewhere it
+// prints a line whenever the label changes.
+type progressBar struct {
+	mu       sync.Mutex
+	out      *os.File
+	terminal bool
+	start    time.Time
+	label    string
+	drawn    bool
+}
+
+func newProgressBar(out *os.File) *progressBar {
+	bar := &progressBar{out: out, start: time.Now()}
+	if info, err := out.Stat(); err == nil {
+		bar.terminal = info.Mode()&os.ModeCharDevice != 0
+	}
+	return bar
+}
+
+// update shows label at percent, which is -1 when unknown.
+func (b *progressBar) update(label string, percent int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	elapsed := time.Since(b.start).Round(time.Second)
+	if !b.terminal {
+		if label != b.label {
+			fmt.Fprintf(b.out, "%s (%s)\n", label, elapsed)
+		}
+		b.label = label
+		return
+	}
+
+	line := fmt.Sprintf("%s (%s)", label, elapsed)
+	if percent >= 0 {
+		filled := percent * progressWidth / 100
+		line = fmt.Sprintf("[%s%s] %3d%% %s", strings.Repeat("#", filled), strings.Repeat("-", progressWidth-filled), percent, line)
+	}
+	fmt.Fprintf(b.out, "\r\033[K%s", line)
+	b.label = label
+	b.drawn = true
+}
+
+// done ends the progress line.
+func (b *progressBar) done() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.drawn {
+		fmt.Fprintln(b.out)
+		b.drawn = false
+	}
+}
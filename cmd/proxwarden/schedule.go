@@ -0,0 +1,173 @@
+package proxwarden
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/server"
+	"github.com/spf13/cobra"
+)
+
+var backupScheduleCmd = &cobra.Command{
+	Use:     "schedule",
+	Aliases: []string{"schedules"},
+	Short:   "Manage the daemon's scheduled backups",
+	Long: `List, add, remove and run the scheduled backups of the daemon's backup
+scheduler. Schedules come from backup.schedule and the backup_schedule of
+each container; those added or removed here override them, are kept in the
+data directory and survive restarts of the daemon. These commands need the
+daemon API server.`,
+}
+
+var backupScheduleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List scheduled backups with their next run",
+	Args:  cobra.NoArgs,
+	RunE:  runBackupScheduleList,
+}
+
+var backupScheduleAddCmd = &cobra.Command{
+	Use:   "add [container-id] [schedule]",
+	Short: "Back up a container on a schedule",
+	Long: `Back up a monitored container at every activation of a standard cron
+expression, replacing its schedule if it has one.`,
+	Example: `  proxwarden backup schedule add 100 "0 3 * * *"`,
+	Args:    cobra.ExactArgs(2),
+	RunE:    runBackupScheduleAdd,
+}
+
+var backupScheduleRemoveCmd = &cobra.Command{
+	Use:   "remove [container-id]",
+	Short: "Stop the scheduled backups of a container",
+	Long: `Stop the scheduled backups of a container, including a schedule from the
+config file, until one is added again.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBackupScheduleRemove,
+}
+
+var backupScheduleRunCmd = &cobra.Command{
+	Use:     "run [container-id]",
+	Aliases: []string{"run-now"},
+	Short:   "Take the scheduled backup of a container now",
+	Long: `Start the scheduled backup of a container right away, with its storage,
+vzdump options and hooks, without moving its next run. The daemon takes the
+backup; check its log or "proxwarden backup list" for the result.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBackupScheduleRun,
+}
+
+func init() {
+	backupCmd.AddCommand(backupScheduleCmd)
+	backupScheduleCmd.AddCommand(backupScheduleListCmd)
+	backupScheduleCmd.AddCommand(backupScheduleAddCmd)
+	backupScheduleCmd.AddCommand(backupScheduleRemoveCmd)
+	backupScheduleCmd.AddCommand(backupScheduleRunCmd)
+
+	addOutputFlags(backupScheduleListCmd)
+}
+
+func runBackupScheduleList(cmd *cobra.Command, args []string) error {
+	client, err := newDaemonClient()
+	if err != nil {
+		return err
+	}
+
+	jobs, err := client.BackupSchedules(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to list backup schedules: %w", err)
+	}
+
+	format := outputOf(cmd)
+	if format != outputTable {
+		return printStructured(format, jobs)
+	}
+
+	if len(jobs) == 0 {
+		fmt.Println("No scheduled backups")
+		return nil
+	}
+
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CONTAINER\tNAME\tSCHEDULE\tSTORAGE\tNEXT RUN\tSOURCE\tSTATE")
+	fmt.Fprintln(w, "---------\t----\t--------\t-------\t--------\t------\t-----")
+	for _, job := range jobs {
+		source := "runtime"
+		if job.Configured {
+			source = "config"
+		}
+		state := "idle"
+		if job.Running {
+			state = "running"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s (in %s)\t%s\t%s\n",
+			job.ContainerID, valueOrDash(job.ContainerName), job.Schedule, job.Storage,
+			job.Next.Format(time.RFC3339), formatAge(job.Next.Sub(now)), source, state)
+	}
+	return w.Flush()
+}
+
+func runBackupScheduleAdd(cmd *cobra.Command, args []string) error {
+	containerID, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid container ID: %w", err)
+	}
+	client, err := newDaemonClient()
+	if err != nil {
+		return err
+	}
+
+	job, err := client.AddBackupSchedule(context.Background(), server.BackupScheduleRequest{
+		ContainerID: containerID,
+		Schedule:    args[1],
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add backup schedule: %w", err)
+	}
+
+	fmt.Printf("Container %d is backed up at %q to %s, next at %s\n",
+		job.ContainerID, job.Schedule, job.Storage, job.Next.Format(time.RFC3339))
+	return nil
+}
+
+func runBackupScheduleRemove(cmd *cobra.Command, args []string) error {
+	containerID, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid container ID: %w", err)
+	}
+	client, err := newDaemonClient()
+	if err != nil {
+		return err
+	}
+
+	if err := client.RemoveBackupSchedule(context.Background(), containerID); err != nil {
+		return fmt.Errorf("failed to remove backup schedule: %w", err)
+	}
+
+	fmt.Printf("Scheduled backups of container %d stopped\n", containerID)
+	return nil
+}
+
+func runBackupScheduleRun(cmd *cobra.Command, args []string) error {
+	containerID, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid container ID: %w", err)
+	}
+	client, err := newDaemonClient()
+	if err != nil {
+		return err
+	}
+
+	job, err := client.RunBackupSchedule(context.Background(), containerID)
+	if err != nil {
+		return fmt.Errorf("failed to start backup: %w", err)
+	}
+
+	fmt.Printf("Backup of container %d to %s started; the next scheduled run is still at %s\n",
+		job.ContainerID, job.Storage, job.Next.Format(time.RFC3339))
+	return nil
+}
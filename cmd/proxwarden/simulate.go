@@ -0,0 +1,73 @@
+package proxwarden
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/failover"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var simulateCmd = &cobra.Command{
+	Use:   "simulate",
+	Short: "Failover drills",
+}
+
+var simulateFailureCmd = &cobra.Command{
+	Use:   "failure [container-id]",
+	Short: "Run a failover drill of a test container",
+	Long: `Fail a test container over as if it had failed, through the whole
+failover pipeline including backups, hooks, DNS and notifications. The drill
+is recorded in the failover history with trigger "drill", and with
+failover.drill.move_back the container is rolled back afterwards. Only
+containers listed in failover.drill.containers are drilled unless --force is
+given.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSimulateFailure,
+}
+
+func init() {
+	rootCmd.AddCommand(simulateCmd)
+	simulateCmd.AddCommand(simulateFailureCmd)
+
+	simulateFailureCmd.Flags().Bool("force", false, "drill a container not listed in failover.drill.containers")
+}
+
+func runSimulateFailure(cmd *cobra.Command, args []string) error {
+	containerID, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid container ID: %w", err)
+	}
+	force, _ := cmd.Flags().GetBool("force")
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if !force && !cfg.Failover.Drill.Includes(containerID) {
+		return fmt.Errorf("container %d is not a drill container; add it to failover.drill.containers or pass --force", containerID)
+	}
+
+	engine, err := failover.New(cfg, logrus.New())
+	if err != nil {
+		return err
+	}
+
+	result, err := engine.Drill(context.Background(), containerID)
+	if result != nil && result.Success {
+		fmt.Printf("Container %d failed over from %s to %s (%s, %s)\n",
+			containerID, result.SourceNode, result.TargetNode, result.Strategy, result.Duration.Round(time.Second))
+	}
+	if err != nil {
+		return fmt.Errorf("drill of container %d failed: %w", containerID, err)
+	}
+
+	if cfg.Failover.Drill.MoveBack {
+		fmt.Printf("Container %d moved back to %s\n", containerID, result.SourceNode)
+	}
+	fmt.Println("Drill passed")
+	return nil
+}
@@ -0,0 +1,336 @@
+package proxwarden
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:     "snapshot",
+	Aliases: []string{"snapshots"},
+	Short:   "Snapshot operations for monitored containers",
+	Long: `Take, list, roll back and delete snapshots of monitored containers.
+
+Snapshots named prefailover-<time> are pre-failover snapshots: the daemon
+takes one before failing a container over when
+failover.snapshot_before_failover is set, and --pre-failover takes, lists,
+rolls back to or deletes them by that convention.`,
+}
+
+var snapshotCreateCmd = &cobra.Command{
+	Use:   "create [container-id] [name]",
+	Short: "Take a snapshot of a container",
+	Long: `Take a snapshot of a monitored container. With --pre-failover the snapshot
+is named as a pre-failover snapshot, so a later rollback --pre-failover
+finds it, as before failing a container over by hand.`,
+	Example: `  proxwarden snapshot create 100 before-upgrade --description "before the 2.4 upgrade"
+  proxwarden snapshot create 100 --pre-failover`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runSnapshotCreate,
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:   "list [container-id]",
+	Short: "List the snapshots of monitored containers",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runSnapshotList,
+}
+
+var snapshotRollbackCmd = &cobra.Command{
+	Use:   "rollback [container-id] [name]",
+	Short: "Roll a container back to a snapshot",
+	Long: `Roll a monitored container back to a snapshot, discarding every change
+since it was taken. With --pre-failover the newest pre-failover snapshot is
+used, to undo a failover that left the container worse off.`,
+	Example: `  proxwarden snapshot rollback 100 before-upgrade
+  proxwarden snapshot rollback 100 --pre-failover --start`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runSnapshotRollback,
+}
+
+var snapshotDeleteCmd = &cobra.Command{
+	Use:   "delete [container-id] [name]",
+	Short: "Delete a snapshot of a container",
+	Long: `Delete a snapshot of a monitored container. With --pre-failover every
+pre-failover snapshot of the container is deleted.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runSnapshotDelete,
+}
+
+func init() {
+	rootCmd.AddCommand(snapshotCmd)
+	snapshotCmd.AddCommand(snapshotCreateCmd)
+	snapshotCmd.AddCommand(snapshotListCmd)
+	snapshotCmd.AddCommand(snapshotRollbackCmd)
+	snapshotCmd.AddCommand(snapshotDeleteCmd)
+
+	snapshotCreateCmd.Flags().String("description", "", "description of the snapshot")
+	snapshotCreateCmd.Flags().Bool("pre-failover", false, "name the snapshot as a pre-failover snapshot")
+
+	snapshotListCmd.Flags().Bool("pre-failover", false, "only list pre-failover snapshots")
+	addOutputFlags(snapshotListCmd)
+
+	snapshotRollbackCmd.Flags().Bool("pre-failover", false, "roll back to the newest pre-failover snapshot")
+	snapshotRollbackCmd.Flags().Bool("start", false, "start the container after the rollback")
+	addConfirmFlag(snapshotRollbackCmd)
+
+	snapshotDeleteCmd.Flags().Bool("pre-failover", false, "delete every pre-failover snapshot")
+	addConfirmFlag(snapshotDeleteCmd)
+}
+
+// snapshotTarget returns the monitored container and snapshot name the
+// arguments of a snapshot command name. Exactly one of a name and
+// --pre-failover must be given.
+func snapshotTarget(cmd *cobra.Command, cfg *config.Config, args []string) (config.ContainerConfig, string, error) {
+	containerID, err := strconv.Atoi(args[0])
+	if err != nil {
+		return config.ContainerConfig{}, "", fmt.Errorf("invalid container ID: %w", err)
+	}
+	container, ok := configuredContainer(cfg, containerID)
+	if !ok {
+		return config.ContainerConfig{}, "", fmt.Errorf("container %d is not monitored", containerID)
+	}
+
+	name := ""
+	if len(args) > 1 {
+		name = args[1]
+	}
+	preFailover, _ := cmd.Flags().GetBool("pre-failover")
+	switch {
+	case preFailover && name != "":
+		return container, "", fmt.Errorf("a snapshot name and --pre-failover are mutually exclusive")
+	case !preFailover && name == "":
+		return container, "", fmt.Errorf("a snapshot name or --pre-failover is required")
+	}
+	return container, name, nil
+}
+
+func configuredContainer(cfg *config.Config, containerID int) (config.ContainerConfig, bool) {
+	for _, container := range cfg.Monitoring.Containers {
+		if container.ID == containerID {
+			return container, true
+		}
+	}
+	return config.ContainerConfig{}, false
+}
+
+func runSnapshotCreate(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	container, name, err := snapshotTarget(cmd, cfg, args)
+	if err != nil {
+		return err
+	}
+	description, _ := cmd.Flags().GetString("description")
+	if name == "" {
+		name = api.PreFailoverSnapshotName(time.Now())
+		if description == "" {
+			description = "Taken by hand before a failover"
+		}
+	}
+
+	apiClient, err := api.NewClient(&cfg.Proxmox)
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+	if err := apiClient.CreateSnapshot(context.Background(), container.ID, name, description); err != nil {
+		return err
+	}
+
+	fmt.Printf("Snapshot %s of container %d taken\n", name, container.ID)
+	return nil
+}
+
+// snapshotEntry is a snapshot as snapshot list reports it.
+type snapshotEntry struct {
+	ContainerID   int    `json:"container_id"`
+	ContainerName string `json:"container_name"`
+	api.Snapshot
+	PreFailover bool `json:"pre_failover"`
+}
+
+func runSnapshotList(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	preFailover, _ := cmd.Flags().GetBool("pre-failover")
+
+	containers := cfg.Monitoring.Containers
+	if len(args) > 0 {
+		containerID, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid container ID: %w", err)
+		}
+		container, ok := configuredContainer(cfg, containerID)
+		if !ok {
+			return fmt.Errorf("container %d is not monitored", containerID)
+		}
+		containers = []config.ContainerConfig{container}
+	}
+
+	apiClient, err := api.NewClient(&cfg.Proxmox)
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	ctx := context.Background()
+	entries := []snapshotEntry{}
+	for _, container := range containers {
+		snapshots, err := apiClient.GetSnapshots(ctx, container.ID)
+		if err != nil {
+			return err
+		}
+		for _, snapshot := range snapshots {
+			if preFailover && !snapshot.PreFailover() {
+				continue
+			}
+			entries = append(entries, snapshotEntry{
+				ContainerID:   container.ID,
+				ContainerName: container.Name,
+				Snapshot:      snapshot,
+				PreFailover:   snapshot.PreFailover(),
+			})
+		}
+	}
+
+	format := outputOf(cmd)
+	if format != outputTable {
+		return printStructured(format, entries)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No snapshots")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CONTAINER\tNAME\tSNAPSHOT\tTAKEN\tPARENT\tDESCRIPTION")
+	fmt.Fprintln(w, "---------\t----\t--------\t-----\t------\t-----------")
+	for _, entry := range entries {
+		parent := entry.Parent
+		if parent == "" {
+			parent = "-"
+		}
+		description := entry.Description
+		if description == "" {
+			description = "-"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
+			entry.ContainerID, entry.ContainerName, entry.Name, entry.Taken.Format("2006-01-02 15:04:05"), parent, description)
+	}
+	return w.Flush()
+}
+
+func runSnapshotRollback(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	container, name, err := snapshotTarget(cmd, cfg, args)
+	if err != nil {
+		return err
+	}
+	start, _ := cmd.Flags().GetBool("start")
+
+	apiClient, err := api.NewClient(&cfg.Proxmox)
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	ctx := context.Background()
+	snapshots, err := apiClient.GetSnapshots(ctx, container.ID)
+	if err != nil {
+		return err
+	}
+	var snapshot *api.Snapshot
+	if name == "" {
+		if snapshot = api.LatestPreFailover(snapshots); snapshot == nil {
+			return fmt.Errorf("container %d has no pre-failover snapshot", container.ID)
+		}
+	} else {
+		for i := range snapshots {
+			if snapshots[i].Name == name {
+				snapshot = &snapshots[i]
+			}
+		}
+		if snapshot == nil {
+			return fmt.Errorf("container %d has no snapshot %s", container.ID, name)
+		}
+	}
+
+	details := []string{
+		fmt.Sprintf("Snapshot: %s, taken %s", snapshot.Name, snapshot.Taken.Format("2006-01-02 15:04:05")),
+		"Every change to the container since then is lost",
+	}
+	if start {
+		details = append(details, "The container is started afterwards")
+	}
+	if err := confirm(cmd, fmt.Sprintf("Container %d will be rolled back:", container.ID), details...); err != nil {
+		return err
+	}
+
+	if err := apiClient.RollbackSnapshot(ctx, container.ID, snapshot.Name, start); err != nil {
+		return err
+	}
+
+	fmt.Printf("Container %d rolled back to snapshot %s\n", container.ID, snapshot.Name)
+	return nil
+}
+
+func runSnapshotDelete(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	container, name, err := snapshotTarget(cmd, cfg, args)
+	if err != nil {
+		return err
+	}
+
+	apiClient, err := api.NewClient(&cfg.Proxmox)
+	if err != nil {
+		return fmt.Errorf("failed to create API client: %w", err)
+	}
+
+	ctx := context.Background()
+	names := []string{name}
+	if name == "" {
+		snapshots, err := apiClient.GetSnapshots(ctx, container.ID)
+		if err != nil {
+			return err
+		}
+		names = nil
+		for _, snapshot := range snapshots {
+			if snapshot.PreFailover() {
+				names = append(names, snapshot.Name)
+			}
+		}
+		if len(names) == 0 {
+			fmt.Printf("Container %d has no pre-failover snapshots\n", container.ID)
+			return nil
+		}
+	}
+
+	if err := confirm(cmd, fmt.Sprintf("%d snapshots of container %d will be deleted:", len(names), container.ID), names...); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if err := apiClient.DeleteSnapshot(ctx, container.ID, name); err != nil {
+			return err
+		}
+		fmt.Printf("Snapshot %s of container %d deleted\n", name, container.ID)
+	}
+	return nil
+}
@@ -2,14 +2,21 @@ package proxwarden
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"text/tabwriter"
 	"time"
 
 	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/catalog"
 	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/maintenance"
+	"github.com/jbutlerdev/proxwarden/internal/monitor"
+	"github.com/jbutlerdev/proxwarden/internal/server"
+	"github.com/jbutlerdev/proxwarden/internal/store"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -17,16 +24,34 @@ import (
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show status of monitored containers",
-	Long:  `Display the current status and health of all monitored containers.`,
-	RunE:  runStatus,
+	Long: `Display the current status and health of all monitored containers.
+
+With --nagios, print a single line with performance data instead and exit
+with the code of the worst container state, as a Nagios or Icinga plugin:
+0 if all are OK, 1 on warnings such as degraded containers or ones in
+maintenance, 2 if any is failing or failed over, and 3 if the status could
+not be determined or the daemon did not answer.`,
+	RunE: runStatus,
 }
 
 func init() {
 	rootCmd.AddCommand(statusCmd)
-	statusCmd.Flags().Bool("json", false, "output in JSON format")
+	addCSVOutputFlags(statusCmd)
+	statusCmd.Flags().Bool("wide", false, "also show failure counts, last checks, target candidates and backup ages")
+	statusCmd.Flags().Bool("checks", false, "show per-check statistics from the running daemon")
+	statusCmd.Flags().Bool("watch", false, "keep showing the daemon's view of the containers, refreshed every interval")
+	statusCmd.Flags().Duration("interval", 2*time.Second, "refresh interval of --watch")
+	statusCmd.Flags().Bool("nagios", false, "print a one-line summary with performance data and exit 0, 1, 2 or 3 (OK, WARNING, CRITICAL, UNKNOWN)")
+	statusCmd.MarkFlagsMutuallyExclusive("nagios", "watch")
+	statusCmd.MarkFlagsMutuallyExclusive("nagios", "output")
+	statusCmd.MarkFlagsMutuallyExclusive("nagios", "json")
 }
 
 func runStatus(cmd *cobra.Command, args []string) error {
+	if nagios, _ := cmd.Flags().GetBool("nagios"); nagios {
+		os.Exit(runStatusNagios())
+	}
+
 	ctx := context.Background()
 	logger := logrus.New()
 	logger.SetLevel(logrus.WarnLevel) // Reduce noise for status command
@@ -37,28 +62,206 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
+	if watch, _ := cmd.Flags().GetBool("watch"); watch {
+		if format := outputOf(cmd); format != outputTable {
+			return fmt.Errorf("--watch cannot be combined with --output %s", format)
+		}
+		interval, _ := cmd.Flags().GetDuration("interval")
+		return runStatusWatch(cfg, interval)
+	}
+
+	format := outputOf(cmd)
+	showChecks, _ := cmd.Flags().GetBool("checks")
+	wide, _ := cmd.Flags().GetBool("wide")
+
+	report, err := collectStatus(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	if wide || format == outputCSV {
+		addStatusDetails(ctx, cfg, report, logger)
+	}
+
+	switch format {
+	case outputTable:
+	case outputCSV:
+		return printStatusCSV(report)
+	default:
+		return printStructured(format, report.Containers)
+	}
+
+	// Text output
+	if report.Pause != nil && report.Pause.Paused {
+		banner := "Automatic failover is PAUSED since " + report.Pause.Since.Format(time.RFC3339)
+		if report.Pause.Reason != "" {
+			banner += ": " + report.Pause.Reason
+		}
+		fmt.Println(banner)
+		fmt.Println()
+	}
+
+	if !report.SavedAt.IsZero() {
+		fmt.Printf("Daemon not reachable, health as of %s\n\n", report.SavedAt.Format(time.RFC3339))
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if wide {
+		fmt.Fprintln(w, "ID\tNAME\tNODE\tSTATUS\tHEALTH\tFAILURES\tLAST CHECK\tTARGETS\tLAST BACKUP\tERROR")
+		fmt.Fprintln(w, "--\t----\t----\t------\t------\t--------\t----------\t-------\t-----------\t-----")
+	} else {
+		fmt.Fprintln(w, "ID\tNAME\tNODE\tSTATUS\tHEALTH\tERROR")
+		fmt.Fprintln(w, "--\t----\t----\t------\t------\t-----")
+	}
+
+	for _, status := range report.Containers {
+		errorStr := status.Error
+		if len(errorStr) > 50 {
+			errorStr = errorStr[:47] + "..."
+		}
+		if !wide {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
+				status.ID, status.Name, status.Node, status.Status, status.HealthStatus, errorStr)
+			continue
+		}
+
+		lastCheck, targets, lastBackup := "-", "-", "-"
+		if report.DaemonRunning || !report.SavedAt.IsZero() {
+			lastCheck = valueOrDash(formatTime(status.LastChecked))
+		}
+		if len(status.TargetCandidates) > 0 {
+			targets = strings.Join(status.TargetCandidates, ",")
+		}
+		if status.LastBackup != nil {
+			lastBackup = formatAge(time.Since(*status.LastBackup)) + " ago"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n",
+			status.ID, status.Name, status.Node, status.Status, status.HealthStatus,
+			status.FailureCount, lastCheck, targets, lastBackup, errorStr)
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if !showChecks {
+		return nil
+	}
+
+	if !report.DaemonRunning {
+		fmt.Println("\nPer-check statistics are only available while the daemon is running.")
+		return nil
+	}
+
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTYPE\tTARGET\tSEVERITY\tLAST\tSUCCESS\tP95\tSAMPLES\tLAST FAILURE")
+	fmt.Fprintln(w, "--\t----\t------\t--------\t----\t-------\t---\t-------\t------------")
+
+	for _, status := range report.Containers {
+		for _, check := range status.Checks {
+			last := "ok"
+			switch {
+			case check.Skipped:
+				last = "skipped"
+			case !check.Success:
+				last = "fail"
+			}
+			lastFailure := "-"
+			if !check.LastFailure.IsZero() {
+				lastFailure = fmt.Sprintf("%s (%s)", check.LastFailure.Format(time.RFC3339), check.LastFailureReason)
+				if len(lastFailure) > 70 {
+					lastFailure = lastFailure[:67] + "..."
+				}
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%.1f%%\t%s\t%d\t%s\n",
+				status.ID, check.Type, check.Target, check.Severity, last, check.SuccessRate*100,
+				check.P95Latency.Round(time.Millisecond), check.Samples, lastFailure)
+		}
+	}
+
+	return w.Flush()
+}
+
+// containerStatus is a monitored container as status reports it.
+type containerStatus struct {
+	ID           int       `json:"id"`
+	Name         string    `json:"name"`
+	Node         string    `json:"node"`
+	Status       string    `json:"status"`
+	LastChecked  time.Time `json:"last_checked,omitempty"`
+	HealthStatus string    `json:"health_status"`
+	Error        string    `json:"error,omitempty"`
+	FailureCount int       `json:"failure_count,omitempty"`
+	// Maintenance is the window the container is in maintenance through
+	Maintenance *maintenance.Window `json:"maintenance,omitempty"`
+	// TargetCandidates and LastBackup are only filled in with --wide or
+	// --output csv
+	TargetCandidates []string   `json:"target_candidates,omitempty"`
+	LastBackup       *time.Time `json:"last_backup,omitempty"`
+
+	Checks []server.CheckStatus `json:"checks,omitempty"`
+}
+
+// statusReport is the state of the monitored containers, and of the daemon
+// monitoring them, as status shows it.
+type statusReport struct {
+	Containers []containerStatus
+	Pause      *server.PauseStatus
+	// SavedAt is when the daemon saved the health shown, if it is not
+	// running
+	SavedAt time.Time
+	// DaemonReachable is whether the daemon API answered, and
+	// DaemonRunning whether it reported containers
+	DaemonReachable bool
+	DaemonRunning   bool
+}
+
+// collectStatus gathers the status of the monitored containers from
+// Proxmox and, preferably, the running daemon.
+func collectStatus(ctx context.Context, cfg *config.Config, logger *logrus.Logger) (*statusReport, error) {
 	// Create API client
 	apiClient, err := api.NewClient(&cfg.Proxmox)
 	if err != nil {
-		return fmt.Errorf("failed to create API client: %w", err)
+		return nil, fmt.Errorf("failed to create API client: %w", err)
 	}
 
-	jsonOutput, _ := cmd.Flags().GetBool("json")
+	report := &statusReport{}
 
-	type ContainerStatus struct {
-		ID           int       `json:"id"`
-		Name         string    `json:"name"`
-		Node         string    `json:"node"`
-		Status       string    `json:"status"`
-		LastChecked  time.Time `json:"last_checked,omitempty"`
-		HealthStatus string    `json:"health_status"`
-		Error        string    `json:"error,omitempty"`
+	// Prefer health information from the running daemon when available
+	daemonStates := make(map[int]server.ContainerStatus)
+	if cfg.Server.Enabled {
+		var states []server.ContainerStatus
+		daemonClient, err := server.NewClient(&cfg.Server)
+		if err == nil {
+			states, err = daemonClient.Containers(ctx)
+		}
+		if err != nil {
+			logger.WithField("error", err).Debug("Daemon not reachable, showing Proxmox status only")
+		}
+		for _, state := range states {
+			daemonStates[state.ID] = state
+		}
+		if err == nil {
+			report.DaemonReachable = true
+			report.Pause, _ = daemonClient.PauseStatus(ctx)
+		}
 	}
+	report.DaemonRunning = len(daemonStates) > 0
 
-	var containerStatuses []ContainerStatus
+	// Without the daemon, show the states it last saved in the store
+	if !report.DaemonRunning {
+		saved, at, err := savedStates(cfg)
+		if err != nil {
+			logger.WithField("error", err).Warn("Failed to read saved monitor state")
+		}
+		for id, state := range saved {
+			daemonStates[id] = state
+		}
+		report.SavedAt = at
+	}
 
 	for _, container := range cfg.Monitoring.Containers {
-		status := ContainerStatus{
+		status := containerStatus{
 			ID:           container.ID,
 			Name:         container.Name,
 			LastChecked:  time.Now(),
@@ -76,31 +279,190 @@ func runStatus(cmd *cobra.Command, args []string) error {
 			status.HealthStatus = "reachable"
 		}
 
-		containerStatuses = append(containerStatuses, status)
+		if state, ok := daemonStates[container.ID]; ok {
+			status.HealthStatus = state.Health
+			status.FailureCount = state.FailureCount
+			if state.SkippedReason != "" {
+				status.Error = state.SkippedReason
+			}
+			if state.Maintenance != nil {
+				status.Error = maintenanceSummary(*state.Maintenance)
+				status.Maintenance = state.Maintenance
+			}
+			status.Checks = state.Checks
+			// Not checked yet rather than checked now
+			status.LastChecked = state.LastHealthCheck
+		}
+
+		report.Containers = append(report.Containers, status)
+		delete(daemonStates, container.ID)
 	}
 
-	if jsonOutput {
-		output, err := json.MarshalIndent(containerStatuses, "", "  ")
-		if err != nil {
-			return fmt.Errorf("failed to marshal JSON: %w", err)
+	// Containers enrolled through discovery are only known to the daemon
+	var discovered []int
+	for id := range daemonStates {
+		discovered = append(discovered, id)
+	}
+	sort.Ints(discovered)
+	for _, id := range discovered {
+		state := daemonStates[id]
+		status := containerStatus{
+			ID:           state.ID,
+			Name:         state.Name,
+			Node:         state.Node,
+			Status:       state.Status,
+			LastChecked:  state.LastHealthCheck,
+			HealthStatus: state.Health,
+			FailureCount: state.FailureCount,
+			Error:        state.SkippedReason,
+			Checks:       state.Checks,
 		}
-		fmt.Println(string(output))
-		return nil
+		if state.Maintenance != nil {
+			status.Error = maintenanceSummary(*state.Maintenance)
+			status.Maintenance = state.Maintenance
+		}
+		report.Containers = append(report.Containers, status)
 	}
 
-	// Text output
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "ID\tNAME\tNODE\tSTATUS\tHEALTH\tERROR")
-	fmt.Fprintln(w, "--\t----\t----\t------\t------\t-----")
+	return report, nil
+}
 
-	for _, status := range containerStatuses {
-		errorStr := status.Error
-		if len(errorStr) > 50 {
-			errorStr = errorStr[:47] + "..."
+// addStatusDetails adds to report the failover nodes each container could
+// fail over to now, the online ones other than its own, and when its newest
+// backup in the backup catalog was taken.
+func addStatusDetails(ctx context.Context, cfg *config.Config, report *statusReport, logger *logrus.Logger) {
+	online := make(map[string]bool)
+	apiClient, err := api.NewClient(&cfg.Proxmox)
+	if err == nil {
+		var nodes []*api.NodeInfo
+		if nodes, err = apiClient.GetNodes(ctx); err == nil {
+			for _, node := range nodes {
+				online[node.Name] = node.Online
+			}
 		}
-		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
-			status.ID, status.Name, status.Node, status.Status, status.HealthStatus, errorStr)
+	}
+	if err != nil {
+		logger.WithField("error", err).Warn("Failed to get nodes, showing every failover node as a target")
 	}
 
-	return w.Flush()
-}
\ No newline at end of file
+	var entries []catalog.Entry
+	backupCatalog, err := catalog.Open(cfg)
+	if err == nil {
+		entries, err = backupCatalog.List(catalog.Filter{})
+	}
+	if err != nil {
+		logger.WithField("error", err).Warn("Failed to read backup catalog")
+	}
+	setStatusDetails(cfg, report, online, entries)
+}
+
+// setStatusDetails sets the target candidates and last backup of the
+// containers of report from the nodes online, all of them if online is
+// empty, and the entries of the backup catalog.
+func setStatusDetails(cfg *config.Config, report *statusReport, online map[string]bool, entries []catalog.Entry) {
+	for i := range report.Containers {
+		status := &report.Containers[i]
+		container, ok := configuredContainer(cfg, status.ID)
+		if ok {
+			for _, node := range container.FailoverNodes {
+				if node != status.Node && (len(online) == 0 || online[node]) {
+					status.TargetCandidates = append(status.TargetCandidates, node)
+				}
+			}
+		}
+		for _, entry := range entries {
+			if entry.ContainerID == status.ID && (status.LastBackup == nil || entry.Created.After(*status.LastBackup)) {
+				created := entry.Created
+				status.LastBackup = &created
+			}
+		}
+	}
+}
+
+// printStatusCSV writes the status of the containers of report as CSV, with
+// the columns of --wide.
+func printStatusCSV(report *statusReport) error {
+	header := []string{"id", "name", "node", "status", "health", "failure_count", "last_checked",
+		"target_candidates", "last_backup", "backup_age_seconds", "error"}
+	rows := make([][]string, 0, len(report.Containers))
+	for _, status := range report.Containers {
+		lastChecked, lastBackup, backupAge := "-", "", ""
+		if report.DaemonRunning || !report.SavedAt.IsZero() {
+			lastChecked = valueOrDash(formatTime(status.LastChecked))
+		}
+		if status.LastBackup != nil {
+			lastBackup = status.LastBackup.Format(time.RFC3339)
+			backupAge = strconv.Itoa(int(time.Since(*status.LastBackup).Seconds()))
+		}
+		rows = append(rows, []string{
+			strconv.Itoa(status.ID), status.Name, status.Node, status.Status, status.HealthStatus,
+			strconv.Itoa(status.FailureCount), lastChecked,
+			strings.Join(status.TargetCandidates, ","), lastBackup, backupAge, status.Error,
+		})
+	}
+	return printCSV(header, rows)
+}
+
+// formatTime formats t for CSV, leaving times never set empty.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// formatAge rounds an age to what is worth reading: minutes under an hour,
+// hours and minutes under a day, days and hours after that.
+func formatAge(age time.Duration) string {
+	minutes := int(age.Minutes())
+	switch {
+	case age < time.Hour:
+		return fmt.Sprintf("%dm", minutes)
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh%dm", minutes/60, minutes%60)
+	}
+	return fmt.Sprintf("%dd%dh", minutes/(24*60), minutes/60%24)
+}
+
+// savedStates returns the container states the daemon last saved in the
+// store database, and when it saved them. There are none with the file
+// backend.
+func savedStates(cfg *config.Config) (map[int]server.ContainerStatus, time.Time, error) {
+	db, err := store.OpenConfig(cfg)
+	if err != nil || db == nil {
+		return nil, time.Time{}, err
+	}
+	saved, err := monitor.LoadStates(db)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	states := make(map[int]server.ContainerStatus, len(saved))
+	var savedAt time.Time
+	for id, state := range saved {
+		status := server.ContainerStatus{
+			ID:              state.ID,
+			Name:            state.Name,
+			Node:            state.Node,
+			Status:          state.Status,
+			State:           string(state.State),
+			Health:          string(state.State),
+			FailureCount:    state.FailureCount,
+			SkippedReason:   state.SkippedReason,
+			Flapping:        state.Flapping,
+			LastSeen:        state.LastSeen,
+			LastHealthCheck: state.LastHealthCheck,
+		}
+		switch {
+		case state.SkippedReason != "":
+			status.Health = "skipped"
+		case state.Flapping:
+			status.Health = "flapping"
+		}
+		states[id] = status
+		if state.Saved.After(savedAt) {
+			savedAt = state.Saved
+		}
+	}
+	return states, savedAt, nil
+}
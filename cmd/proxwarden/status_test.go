@@ -0,0 +1,154 @@
+package proxwarden
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/catalog"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+func TestFormatAge(t *testing.T) {
+	tests := []struct {
+		age      time.Duration
+		expected string
+	}{
+		{0, "0m"},
+		{59*time.Minute + 59*time.Second, "59m"},
+		{time.Hour, "1h0m"},
+		{2*time.Hour + 5*time.Minute, "2h5m"},
+		{23*time.Hour + 59*time.Minute, "23h59m"},
+		{24 * time.Hour, "1d0h"},
+		{3*24*time.Hour + 7*time.Hour + 30*time.Minute, "3d7h"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.age.String(), func(t *testing.T) {
+			if age := formatAge(tt.age); age != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, age)
+			}
+		})
+	}
+}
+
+func TestSetStatusDetails(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Monitoring.Containers = []config.ContainerConfig{
+		{ID: 101, FailoverNodes: []string{"a", "b", "c"}},
+		{ID: 102, FailoverNodes: []string{"a"}},
+	}
+	older := time.Date(2026, 10, 1, 2, 0, 0, 0, time.UTC)
+	newer := time.Date(2026, 10, 2, 2, 0, 0, 0, time.UTC)
+	entries := []catalog.Entry{
+		{ContainerID: 101, Created: older},
+		{ContainerID: 101, Created: newer},
+		{ContainerID: 103, Created: newer},
+	}
+
+	tests := []struct {
+		name     string
+		online   map[string]bool
+		expected map[int][]string
+	}{
+		{
+			name:     "online nodes other than its own",
+			online:   map[string]bool{"a": true, "b": false, "c": true},
+			expected: map[int][]string{101: {"c"}, 102: nil, 103: nil},
+		},
+		{
+			name:     "nodes unknown",
+			expected: map[int][]string{101: {"b", "c"}, 102: nil, 103: nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := &statusReport{Containers: []containerStatus{
+				{ID: 101, Node: "a"},
+				{ID: 102, Node: "a"},
+				// Not configured
+				{ID: 103, Node: "a"},
+			}}
+			setStatusDetails(cfg, report, tt.online, entries)
+
+			for _, status := range report.Containers {
+				if !reflect.DeepEqual(status.TargetCandidates, tt.expected[status.ID]) {
+					t.Errorf("Expected container %d targets %v, got %v", status.ID, tt.expected[status.ID], status.TargetCandidates)
+				}
+			}
+			if last := report.Containers[0].LastBackup; last == nil || !last.Equal(newer) {
+				t.Errorf("Expected the newest backup %v, got %v", newer, last)
+			}
+			if last := report.Containers[1].LastBackup; last != nil {
+				t.Errorf("Expected no backup for container 102, got %v", last)
+			}
+			if last := report.Containers[2].LastBackup; last == nil || !last.Equal(newer) {
+				t.Errorf("Expected the backup of container 103, got %v", last)
+			}
+		})
+	}
+}
+
+func TestContainerStatus_LastBackupJSON(t *testing.T) {
+	data, err := json.Marshal(containerStatus{ID: 101})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "last_backup") {
+		t.Errorf("Expected no last_backup without a backup, got %s", data)
+	}
+}
+
+func TestPrintStatusCSV(t *testing.T) {
+	lastBackup := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+	report := &statusReport{
+		DaemonRunning: true,
+		Containers: []containerStatus{
+			{
+				ID: 101, Name: "web", Node: "a", Status: "running", HealthStatus: "healthy",
+				LastChecked:      time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC),
+				TargetCandidates: []string{"b", "c"},
+				LastBackup:       &lastBackup,
+			},
+			{
+				ID: 102, Name: "db", Node: "b", Status: "stopped", HealthStatus: "failing",
+				FailureCount: 3, Error: "ping failed, \"timeout\"",
+			},
+		},
+	}
+
+	var err error
+	output := captureStdout(t, func() { err = printStatusCSV(report) })
+	if err != nil {
+		t.Fatalf("printStatusCSV failed: %v", err)
+	}
+	records, err := csv.NewReader(strings.NewReader(output)).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read CSV %q: %v", output, err)
+	}
+
+	expected := [][]string{
+		{"id", "name", "node", "status", "health", "failure_count", "last_checked",
+			"target_candidates", "last_backup", "backup_age_seconds", "error"},
+		{"101", "web", "a", "running", "healthy", "0", "2026-10-16T08:00:00Z",
+			"b,c", lastBackup.Format(time.RFC3339), "", ""},
+		{"102", "db", "b", "stopped", "failing", "3", "-", "", "", "", "ping failed, \"timeout\""},
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("Expected %d records, got %d: %q", len(expected), len(records), records)
+	}
+	// The backup age depends on when the row is written
+	age, err := strconv.Atoi(records[1][9])
+	if err != nil || age < 7200 || age > 7260 {
+		t.Errorf("Expected a backup age of about 7200 seconds, got %q", records[1][9])
+	}
+	records[1][9] = ""
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("Expected records %q, got %q", expected, records)
+	}
+}
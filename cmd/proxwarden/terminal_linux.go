@@ -0,0 +1,16 @@
+//go:build linux
+
+package proxwarden
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// isTerminal reports whether f is a terminal. Unlike a character device
+// check, it is false for /dev/null.
+func isTerminal(f *os.File) bool {
+	_, err := unix.IoctlGetTermios(int(f.Fd()), unix.TCGETS)
+	return err == nil
+}
@@ -1,7 +1,16 @@
 package proxwarden
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -13,16 +22,216 @@ var (
 	BuildDate = "unknown"
 )
 
+// latestReleaseURL is where version --check looks up the latest release.
+const latestReleaseURL = "https://api.github.com/repos/jbutlerdev/proxwarden/releases/latest"
+
+// pseudoVersion matches the versions the Go toolchain makes up for
+// untagged commits, such as v0.0.0-20240101120000-abcdef123456.
+var pseudoVersion = regexp.MustCompile(`\d{14}-[0-9a-f]{12}(\+.*)?$`)
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print version information",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("ProxWarden %s\n", Version)
-		fmt.Printf("Git commit: %s\n", GitCommit)
-		fmt.Printf("Built: %s\n", BuildDate)
-	},
+	Long: `Print the version, git commit and build date of this binary and the Go
+version it was built with. With --check, also look up the latest release
+on GitHub and tell whether it is newer.`,
+	Args: cobra.NoArgs,
+	RunE: runVersion,
 }
 
 func init() {
 	rootCmd.AddCommand(versionCmd)
-}
\ No newline at end of file
+
+	versionCmd.Flags().Bool("check", false, "check GitHub for a newer release")
+	addOutputFlags(versionCmd)
+
+	info := currentVersion()
+	rootCmd.Version = info.Version
+	rootCmd.SetVersionTemplate(fmt.Sprintf("ProxWarden %s (commit %s, built %s, %s)\n",
+		info.Version, info.GitCommit, info.BuildDate, info.GoVersion))
+}
+
+// versionInfo describes this binary, and with version --check the latest
+// release.
+type versionInfo struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"git_commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+
+	LatestVersion   string `json:"latest_version,omitempty"`
+	LatestURL       string `json:"latest_url,omitempty"`
+	UpdateAvailable bool   `json:"update_available,omitempty"`
+}
+
+// currentVersion returns the build metadata of this binary. Metadata the
+// build system did not set is taken from what the Go toolchain recorded, so
+// binaries built with go install report their module version and commit.
+func currentVersion() versionInfo {
+	info := versionInfo{
+		Version:   Version,
+		GitCommit: GitCommit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	if info.Version == "dev" && build.Main.Version != "" && build.Main.Version != "(devel)" && !pseudoVersion.MatchString(build.Main.Version) {
+		info.Version = build.Main.Version
+	}
+	if info.GitCommit == "unknown" {
+		var revision string
+		modified := false
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				revision = setting.Value
+			case "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+		if len(revision) > 7 {
+			revision = revision[:7]
+		}
+		if revision != "" {
+			info.GitCommit = revision
+			if modified {
+				info.GitCommit += "-dirty"
+			}
+		}
+	}
+	return info
+}
+
+func runVersion(cmd *cobra.Command, args []string) error {
+	info := currentVersion()
+
+	check, _ := cmd.Flags().GetBool("check")
+	if check {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		latest, url, err := latestRelease(ctx, info.Version)
+		if err != nil {
+			return fmt.Errorf("failed to check for a newer release: %w", err)
+		}
+		info.LatestVersion = latest
+		info.LatestURL = url
+		if newer, ok := compareVersions(latest, info.Version); ok && newer > 0 {
+			info.UpdateAvailable = true
+		}
+	}
+
+	format := outputOf(cmd)
+	if format != outputTable {
+		return printStructured(format, info)
+	}
+
+	fmt.Printf("ProxWarden %s\n", info.Version)
+	fmt.Printf("Git commit: %s\n", info.GitCommit)
+	fmt.Printf("Built: %s\n", info.BuildDate)
+	fmt.Printf("Go version: %s %s\n", info.GoVersion, info.Platform)
+	if !check {
+		return nil
+	}
+
+	fmt.Println()
+	_, known := compareVersions(info.Version, info.Version)
+	switch {
+	case info.LatestVersion == "":
+		fmt.Println("No release has been published yet")
+	case info.UpdateAvailable:
+		fmt.Printf("A newer release is available: %s\n%s\n", info.LatestVersion, info.LatestURL)
+	case !known:
+		fmt.Printf("Latest release: %s (this is a development build)\n%s\n", info.LatestVersion, info.LatestURL)
+	default:
+		fmt.Printf("This is the latest release (%s)\n", info.LatestVersion)
+	}
+	return nil
+}
+
+// latestRelease returns the version and page of the latest GitHub release,
+// or "" if none was published.
+func latestRelease(ctx context.Context, current string) (string, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, latestReleaseURL, nil)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "proxwarden/"+current)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", "", nil
+	default:
+		return "", "", fmt.Errorf("GitHub returned %s", resp.Status)
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return "", "", fmt.Errorf("failed to decode release: %w", err)
+	}
+	return release.TagName, release.HTMLURL, nil
+}
+
+// compareVersions compares two semantic versions such as v1.2.3 or
+// 1.2.3-rc.1, returning -1, 0 or 1; ok is false if either is not one.
+// Pre-releases order before their release but not among each other.
+func compareVersions(a, b string) (result int, ok bool) {
+	aParts, aPre, aOK := parseVersion(a)
+	bParts, bPre, bOK := parseVersion(b)
+	if !aOK || !bOK {
+		return 0, false
+	}
+	for i := range aParts {
+		if aParts[i] != bParts[i] {
+			if aParts[i] < bParts[i] {
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	switch {
+	case aPre == "" && bPre != "":
+		return 1, true
+	case aPre != "" && bPre == "":
+		return -1, true
+	}
+	return 0, true
+}
+
+// parseVersion splits a semantic version into its major, minor and patch
+// numbers and its pre-release, ignoring build metadata.
+func parseVersion(version string) ([3]int, string, bool) {
+	var parts [3]int
+	version = strings.TrimPrefix(version, "v")
+	version, _, _ = strings.Cut(version, "+")
+	version, pre, _ := strings.Cut(version, "-")
+
+	fields := strings.Split(version, ".")
+	if len(fields) != 3 {
+		return parts, "", false
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return parts, "", false
+		}
+		parts[i] = n
+	}
+	return parts, pre, true
+}
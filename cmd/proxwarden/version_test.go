@@ -0,0 +1,78 @@
+package proxwarden
+
+import "testing"
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		version  string
+		parts    [3]int
+		pre      string
+		expectOK bool
+	}{
+		{"1.2.3", [3]int{1, 2, 3}, "", true},
+		{"v1.2.3", [3]int{1, 2, 3}, "", true},
+		{"v10.20.30-rc.1", [3]int{10, 20, 30}, "rc.1", true},
+		{"1.2.3+build.5", [3]int{1, 2, 3}, "", true},
+		{"1.2.3-beta+build.5", [3]int{1, 2, 3}, "beta", true},
+		{"1.2", [3]int{}, "", false},
+		{"1.2.3.4", [3]int{}, "", false},
+		{"1.x.3", [3]int{}, "", false},
+		{"1..3", [3]int{}, "", false},
+		{"dev", [3]int{}, "", false},
+		{"", [3]int{}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			parts, pre, ok := parseVersion(tt.version)
+			if ok != tt.expectOK {
+				t.Fatalf("Expected ok %v, got %v", tt.expectOK, ok)
+			}
+			if !ok {
+				return
+			}
+			if parts != tt.parts {
+				t.Errorf("Expected parts %v, got %v", tt.parts, parts)
+			}
+			if pre != tt.pre {
+				t.Errorf("Expected pre-release %q, got %q", tt.pre, pre)
+			}
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+		expectOK bool
+	}{
+		{"1.2.3", "1.2.3", 0, true},
+		{"v1.2.3", "1.2.3", 0, true},
+		{"1.2.3", "1.2.4", -1, true},
+		{"1.3.0", "1.2.9", 1, true},
+		{"2.0.0", "1.99.99", 1, true},
+		{"1.10.0", "1.9.0", 1, true},
+		{"1.2.3-rc.1", "1.2.3", -1, true},
+		{"v1.2.3", "v1.2.3-rc.1", 1, true},
+		{"1.2.3-alpha", "1.2.3-beta", 0, true},
+		{"1.2.4-rc.1", "1.2.3", 1, true},
+		{"1.2.3+build.1", "1.2.3+build.2", 0, true},
+		{"1.2", "1.2.0", 0, false},
+		{"1.2.0", "1.2.0.1", 0, false},
+		{"dev", "1.2.3", 0, false},
+		{"1.2.3", "latest", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			result, ok := compareVersions(tt.a, tt.b)
+			if ok != tt.expectOK {
+				t.Fatalf("Expected ok %v, got %v", tt.expectOK, ok)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %d, got %d", tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package proxwarden
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/server"
+)
+
+// watchChanges is the number of recent changes status --watch lists.
+const watchChanges = 10
+
+// ANSI sequences of the status --watch screen.
+const (
+	clearScreen = "\033[H\033[2J"
+	highlight   = "\033[1;33m"
+	resetStyle  = "\033[0m"
+)
+
+// watchChange is a change of a container between two refreshes.
+type watchChange struct {
+	at     time.Time
+	id     int
+	name   string
+	change string
+}
+
+// runStatusWatch shows the state of the containers the daemon monitors,
+// refreshed every interval until interrupted.
+func runStatusWatch(cfg *config.Config, interval time.Duration) error {
+	if !cfg.Server.Enabled {
+		return fmt.Errorf("status --watch requires the daemon API server to be enabled")
+	}
+	if interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+	client, err := server.NewClient(&cfg.Server)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	terminal := false
+	if info, err := os.Stdout.Stat(); err == nil {
+		terminal = info.Mode()&os.ModeCharDevice != 0
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var states []server.ContainerStatus
+	var changes []watchChange
+	previous := make(map[int]server.ContainerStatus)
+	for {
+		current, err := client.Containers(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		var pause *server.PauseStatus
+		changed := make(map[int]bool)
+		if err == nil {
+			pause, _ = client.PauseStatus(ctx)
+			now := time.Now()
+			for _, state := range current {
+				if before, ok := previous[state.ID]; ok {
+					if change := describeChange(before, state); change != "" {
+						changed[state.ID] = true
+						changes = append(changes, watchChange{at: now, id: state.ID, name: state.Name, change: change})
+					}
+				}
+			}
+			if len(changes) > watchChanges {
+				changes = changes[len(changes)-watchChanges:]
+			}
+			states = current
+			previous = make(map[int]server.ContainerStatus, len(current))
+			for _, state := range current {
+				previous[state.ID] = state
+			}
+		}
+
+		var out bytes.Buffer
+		if terminal {
+			out.WriteString(clearScreen)
+		} else {
+			out.WriteString("\n")
+		}
+		fmt.Fprintf(&out, "ProxWarden status every %s, %s (Ctrl-C to stop)\n\n", interval, time.Now().Format(time.RFC3339))
+		if err != nil {
+			fmt.Fprintf(&out, "Daemon not reachable: %v\n\n", err)
+		}
+		if pause != nil && pause.Paused {
+			fmt.Fprintf(&out, "Automatic failover is PAUSED since %s\n\n", pause.Since.Format(time.RFC3339))
+		}
+		renderWatchTable(&out, states, changed, terminal)
+		if len(changes) > 0 {
+			out.WriteString("\nRecent changes:\n")
+			for _, change := range changes {
+				fmt.Fprintf(&out, "  %s  %d (%s)  %s\n", change.at.Format("15:04:05"), change.id, change.name, change.change)
+			}
+		}
+		os.Stdout.Write(out.Bytes())
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// describeChange returns what changed about a container between two
+// refreshes, or "" if nothing worth showing did.
+func describeChange(before, after server.ContainerStatus) string {
+	var parts []string
+	if before.Health != after.Health {
+		parts = append(parts, fmt.Sprintf("health %s -> %s", before.Health, after.Health))
+	}
+	if before.Node != after.Node {
+		parts = append(parts, fmt.Sprintf("node %s -> %s", before.Node, after.Node))
+	}
+	if before.Status != after.Status {
+		parts = append(parts, fmt.Sprintf("status %s -> %s", before.Status, after.Status))
+	}
+	if after.FailureCount > before.FailureCount {
+		parts = append(parts, fmt.Sprintf("failures %d -> %d", before.FailureCount, after.FailureCount))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// renderWatchTable writes the table of containers, with the rows of those
+// that changed marked, and highlighted on terminals.
+func renderWatchTable(out *bytes.Buffer, states []server.ContainerStatus, changed map[int]bool, terminal bool) {
+	var table bytes.Buffer
+	w := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, " \tID\tNAME\tNODE\tSTATUS\tHEALTH\tFAILURES\tHEALTHY\tWARNINGS\tLAST CHECK")
+	fmt.Fprintln(w, " \t--\t----\t----\t------\t------\t--------\t-------\t--------\t----------")
+	for _, state := range states {
+		marker := " "
+		if changed[state.ID] {
+			marker = "*"
+		}
+		lastCheck := "-"
+		if !state.LastHealthCheck.IsZero() {
+			lastCheck = time.Since(state.LastHealthCheck).Round(time.Second).String() + " ago"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%d\t%d\t%d\t%s\n",
+			marker, state.ID, state.Name, state.Node, state.Status, state.Health,
+			state.FailureCount, state.HealthyCount, state.Warnings, lastCheck)
+	}
+	w.Flush()
+
+	lines := strings.SplitAfter(table.String(), "\n")
+	for i, line := range lines {
+		// The rows of containers follow the two header lines
+		if terminal && i >= 2 && i-2 < len(states) && changed[states[i-2].ID] {
+			line = highlight + strings.TrimSuffix(line, "\n") + resetStyle + "\n"
+		}
+		out.WriteString(line)
+	}
+}
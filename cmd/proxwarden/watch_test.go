@@ -0,0 +1,93 @@
+package proxwarden
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jbutlerdev/proxwarden/internal/server"
+)
+
+func TestDescribeChange(t *testing.T) {
+	before := server.ContainerStatus{ID: 101, Node: "a", Status: "running", Health: "healthy", FailureCount: 1}
+
+	tests := []struct {
+		name     string
+		after    func(server.ContainerStatus) server.ContainerStatus
+		expected string
+	}{
+		{
+			name:     "unchanged",
+			after:    func(s server.ContainerStatus) server.ContainerStatus { return s },
+			expected: "",
+		},
+		{
+			name: "health",
+			after: func(s server.ContainerStatus) server.ContainerStatus {
+				s.Health = "failing"
+				return s
+			},
+			expected: "health healthy -> failing",
+		},
+		{
+			name: "failed over",
+			after: func(s server.ContainerStatus) server.ContainerStatus {
+				s.Node, s.Status = "b", "stopped"
+				return s
+			},
+			expected: "node a -> b, status running -> stopped",
+		},
+		{
+			name: "more failures",
+			after: func(s server.ContainerStatus) server.ContainerStatus {
+				s.FailureCount = 3
+				return s
+			},
+			expected: "failures 1 -> 3",
+		},
+		{
+			name: "failures reset",
+			after: func(s server.ContainerStatus) server.ContainerStatus {
+				s.FailureCount = 0
+				return s
+			},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if change := describeChange(before, tt.after(before)); change != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, change)
+			}
+		})
+	}
+}
+
+func TestRenderWatchTable(t *testing.T) {
+	states := []server.ContainerStatus{
+		{ID: 101, Name: "web", Node: "a", Status: "running", Health: "healthy"},
+		{ID: 102, Name: "db", Node: "b", Status: "stopped", Health: "failing", FailureCount: 3},
+	}
+	changed := map[int]bool{102: true}
+
+	for _, terminal := range []bool{false, true} {
+		var out bytes.Buffer
+		renderWatchTable(&out, states, changed, terminal)
+		lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+		if len(lines) != 4 {
+			t.Fatalf("Expected 2 header lines and 2 rows, got %q", lines)
+		}
+
+		web, db := lines[2], lines[3]
+		if !strings.HasPrefix(web, "   101") || strings.Contains(web, highlight) {
+			t.Errorf("Expected container 101 unmarked, got %q", web)
+		}
+		if !strings.Contains(db, "*  102") || !strings.Contains(db, "failing") {
+			t.Errorf("Expected container 102 marked, got %q", db)
+		}
+		if highlighted := strings.HasPrefix(db, highlight) && strings.HasSuffix(db, resetStyle); highlighted != terminal {
+			t.Errorf("Expected container 102 highlighted %v, got %q", terminal, db)
+		}
+	}
+}
@@ -2,13 +2,53 @@ package api
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/jbutlerdev/proxwarden/internal/audit"
 	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/httpproxy"
 	proxmox "github.com/luthermonson/go-proxmox"
 )
 
+// ProxmoxClient is the set of Proxmox operations used by the monitor and
+// failover engine. Client is the production implementation.
+type ProxmoxClient interface {
+	GetContainer(ctx context.Context, containerID int) (*ContainerInfo, error)
+	GetContainerMetrics(ctx context.Context, node string, containerID int) (*ContainerMetrics, error)
+	GetContainerRRD(ctx context.Context, node string, containerID int) ([]RRDPoint, error)
+	GetContainerInterfaces(ctx context.Context, node string, containerID int) ([]ContainerInterface, error)
+	GetContainersByNode(ctx context.Context, nodeName string) ([]*ContainerInfo, error)
+	GetNodes(ctx context.Context) ([]*NodeInfo, error)
+	GetClusterTasks(ctx context.Context) ([]TaskInfo, error)
+	GetClusterResources(ctx context.Context) ([]ClusterResource, error)
+	GetReplicationJobs(ctx context.Context) ([]ReplicationJob, error)
+	GetClusterStatus(ctx context.Context) (*ClusterStatus, error)
+	GetHANodeStatus(ctx context.Context) (map[string]string, error)
+	GetHAPlacement(ctx context.Context, containerID int) (*HAPlacement, error)
+	MigrateContainer(ctx context.Context, containerID int, targetNode string) error
+	StopContainer(ctx context.Context, containerID int) error
+	StartContainer(ctx context.Context, containerID int) error
+	BackupContainer(ctx context.Context, containerID int, storage, backupDir string, options config.VzdumpOptions) (string, error)
+	RestoreContainerFromBackup(ctx context.Context, containerID int, targetNode, backupPath string, options RestoreOptions) error
+	GetBackups(ctx context.Context, storage string) ([]BackupInfo, error)
+	DeleteBackup(ctx context.Context, nodeName, storage, backupPath string) error
+	GetSnapshots(ctx context.Context, containerID int) ([]Snapshot, error)
+	CreateSnapshot(ctx context.Context, containerID int, name, description string) error
+	DeleteSnapshot(ctx context.Context, containerID int, name string) error
+}
+
+var _ ProxmoxClient = (*Client)(nil)
+
 type Client struct {
 	client *proxmox.Client
 	config *config.ProxmoxConfig
@@ -22,10 +62,210 @@ type ContainerInfo struct {
 	State  string
 }
 
+// ContainerMetrics is a container's current resource usage as reported by
+// Proxmox. Sizes are in bytes; CPU is the fraction of MaxCPU in use.
+type ContainerMetrics struct {
+	CPU     float64 `json:"cpu"`
+	MaxCPU  int     `json:"cpus"`
+	Mem     uint64  `json:"mem"`
+	MaxMem  uint64  `json:"maxmem"`
+	Disk    uint64  `json:"disk"`
+	MaxDisk uint64  `json:"maxdisk"`
+	Swap    uint64  `json:"swap"`
+	MaxSwap uint64  `json:"maxswap"`
+	Uptime  uint64  `json:"uptime"`
+}
+
+// DiskUsagePercent returns root filesystem usage as a percentage.
+func (m *ContainerMetrics) DiskUsagePercent() float64 {
+	return usagePercent(m.Disk, m.MaxDisk)
+}
+
+// MemoryUsagePercent returns memory usage as a percentage of the limit.
+func (m *ContainerMetrics) MemoryUsagePercent() float64 {
+	return usagePercent(m.Mem, m.MaxMem)
+}
+
+// SwapUsagePercent returns swap usage as a percentage of the limit.
+func (m *ContainerMetrics) SwapUsagePercent() float64 {
+	return usagePercent(m.Swap, m.MaxSwap)
+}
+
+// CPUUsagePercent returns CPU usage as a percentage of the container's cores.
+func (m *ContainerMetrics) CPUUsagePercent() float64 {
+	return m.CPU * 100
+}
+
+// RRDPoint is one sample of a container's round-robin statistics, averaged
+// over the sample's resolution. Proxmox omits the values of samples it has
+// no data for, which leaves them zero.
+type RRDPoint struct {
+	Time    int64   `json:"time"`
+	CPU     float64 `json:"cpu"`
+	MaxCPU  float64 `json:"maxcpu"`
+	Mem     float64 `json:"mem"`
+	MaxMem  float64 `json:"maxmem"`
+	Disk    float64 `json:"disk"`
+	MaxDisk float64 `json:"maxdisk"`
+}
+
+// Timestamp returns the start of the sample.
+func (p RRDPoint) Timestamp() time.Time {
+	return time.Unix(p.Time, 0)
+}
+
+func usagePercent(used, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(used) / float64(total) * 100
+}
+
 type NodeInfo struct {
 	Name   string
 	Status string
 	Online bool
+	// CPU is the load as a fraction of MaxCPU cores
+	CPU    float64
+	MaxCPU int
+	Mem    uint64
+	MaxMem uint64
+}
+
+// MemoryUsage returns the used fraction of the node's memory.
+func (n NodeInfo) MemoryUsage() float64 {
+	return usagePercent(n.Mem, n.MaxMem) / 100
+}
+
+// TaskInfo is an entry of the cluster-wide task list. Status is empty while
+// the task is running and "OK" when it finished successfully.
+type TaskInfo struct {
+	UPID      string `json:"upid"`
+	Node      string `json:"node"`
+	Type      string `json:"type"`
+	ID        string `json:"id"`
+	User      string `json:"user"`
+	Status    string `json:"status"`
+	StartTime int64  `json:"starttime"`
+	EndTime   int64  `json:"endtime"`
+}
+
+// ClusterResource is a guest entry of the cluster resource list. Tags is
+// the raw Proxmox tag string, separated by semicolons.
+type ClusterResource struct {
+	ID     int    `json:"vmid"`
+	Name   string `json:"name"`
+	Node   string `json:"node"`
+	Type   string `json:"type"`
+	Status string `json:"status"`
+	Pool   string `json:"pool"`
+	Tags   string `json:"tags"`
+}
+
+// TagList returns the resource's tags. Older Proxmox versions separate tags
+// with commas or spaces instead of semicolons.
+func (r ClusterResource) TagList() []string {
+	return strings.FieldsFunc(r.Tags, func(c rune) bool {
+		return c == ';' || c == ',' || c == ' '
+	})
+}
+
+// ContainerInterface is a network interface of a running container. Inet and
+// Inet6 are addresses in CIDR notation, empty when unset.
+type ContainerInterface struct {
+	Name   string `json:"name"`
+	HWAddr string `json:"hwaddr"`
+	Inet   string `json:"inet"`
+	Inet6  string `json:"inet6"`
+}
+
+// Addresses returns the interface's global unicast addresses, leaving out
+// loopback and link-local ones.
+func (i ContainerInterface) Addresses() []net.IP {
+	var result []net.IP
+	for _, cidr := range []string{i.Inet, i.Inet6} {
+		ip, _, err := net.ParseCIDR(cidr)
+		if err != nil {
+			continue
+		}
+		if ip.IsGlobalUnicast() {
+			result = append(result, ip)
+		}
+	}
+	return result
+}
+
+// ReplicationJob is a Proxmox storage replication job, which keeps copies
+// of a guest's ZFS volumes on Target.
+type ReplicationJob struct {
+	ID      string            `json:"id"`
+	Guest   int               `json:"guest"`
+	Target  string            `json:"target"`
+	Type    string            `json:"type"`
+	Disable proxmox.IntOrBool `json:"disable"`
+}
+
+// StorageInfo is a storage of the cluster's storage configuration. Content
+// and Nodes are comma-separated; empty Nodes means every node.
+type StorageInfo struct {
+	Name    string            `json:"storage"`
+	Type    string            `json:"type"`
+	Content string            `json:"content"`
+	Nodes   string            `json:"nodes"`
+	Disable proxmox.IntOrBool `json:"disable"`
+}
+
+// Holds reports whether the storage is configured for content, such as
+// "backup" or "rootdir".
+func (s StorageInfo) Holds(content string) bool {
+	for _, c := range strings.Split(s.Content, ",") {
+		if strings.TrimSpace(c) == content {
+			return true
+		}
+	}
+	return false
+}
+
+// AvailableOn reports whether node can use the storage.
+func (s StorageInfo) AvailableOn(node string) bool {
+	if strings.TrimSpace(s.Nodes) == "" {
+		return true
+	}
+	for _, n := range strings.Split(s.Nodes, ",") {
+		if strings.TrimSpace(n) == node {
+			return true
+		}
+	}
+	return false
+}
+
+// ClusterStatus is the corosync membership as seen by the node the API
+// talks to.
+type ClusterStatus struct {
+	Quorate bool
+	// Nodes maps every cluster member to whether it is online
+	Nodes map[string]bool
+}
+
+// HAPlacement is where Proxmox HA runs a guest: the nodes of its HA group,
+// or of a node affinity rule on Proxmox VE 9 and later.
+type HAPlacement struct {
+	// Source names the group or rule
+	Source string
+	// Nodes maps the member nodes to their priority; higher is preferred
+	Nodes map[string]int
+	// Restricted guests may only run on member nodes
+	Restricted bool
+}
+
+// Finished reports whether the task has ended.
+func (t TaskInfo) Finished() bool {
+	return t.EndTime > 0
+}
+
+// Failed reports whether the task ended with an error.
+func (t TaskInfo) Failed() bool {
+	return t.Finished() && t.Status != "OK"
 }
 
 type BackupInfo struct {
@@ -34,26 +274,39 @@ type BackupInfo struct {
 	Filename string
 	Size     int64
 	Format   string
+	// VolID is the backup's volume, as passed to restores
+	VolID       string
+	ContainerID int
+	Created     time.Time
+	// Protected backups cannot be deleted until unprotected
+	Protected bool
 }
 
 func NewClient(cfg *config.ProxmoxConfig) (*Client, error) {
 	var client *proxmox.Client
 
+	transport, err := httpproxy.Transport(cfg.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure proxy: %w", err)
+	}
+	if cfg.Insecure {
+		// Proxmox nodes serve self-signed certificates out of the box
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	httpClient := &http.Client{Transport: countingTransport{next: transport}}
+
 	if cfg.TokenID != "" && cfg.Secret != "" {
 		client = proxmox.NewClient(cfg.Endpoint,
+			proxmox.WithHTTPClient(httpClient),
 			proxmox.WithAPIToken(cfg.TokenID, cfg.Secret),
 		)
 	} else {
 		client = proxmox.NewClient(cfg.Endpoint,
+			proxmox.WithHTTPClient(httpClient),
 			proxmox.WithLogins(cfg.Username, cfg.Password),
 		)
 	}
 
-	// TODO: Handle insecure SSL - method may not be available in this version
-	// if cfg.Insecure {
-	//     client.InsecureSkipVerify = true
-	// }
-
 	return &Client{
 		client: client,
 		config: cfg,
@@ -90,7 +343,35 @@ func (c *Client) GetContainer(ctx context.Context, containerID int) (*ContainerI
 		}
 	}
 
-	return nil, fmt.Errorf("container %d not found", containerID)
+	return nil, fmt.Errorf("container %d: %w", containerID, ErrContainerNotFound)
+}
+
+func (c *Client) GetContainerMetrics(ctx context.Context, node string, containerID int) (*ContainerMetrics, error) {
+	var metrics ContainerMetrics
+	if err := c.client.Get(ctx, fmt.Sprintf("/nodes/%s/lxc/%d/status/current", node, containerID), &metrics); err != nil {
+		return nil, fmt.Errorf("failed to get container metrics: %w", err)
+	}
+	return &metrics, nil
+}
+
+// GetContainerRRD returns the container's statistics of the last hour at
+// one-minute resolution, oldest first.
+func (c *Client) GetContainerRRD(ctx context.Context, node string, containerID int) ([]RRDPoint, error) {
+	var points []RRDPoint
+	if err := c.client.Get(ctx, fmt.Sprintf("/nodes/%s/lxc/%d/rrddata?timeframe=hour&cf=AVERAGE", node, containerID), &points); err != nil {
+		return nil, fmt.Errorf("failed to get container RRD data: %w", err)
+	}
+	return points, nil
+}
+
+// GetContainerInterfaces returns the network interfaces of a running
+// container.
+func (c *Client) GetContainerInterfaces(ctx context.Context, node string, containerID int) ([]ContainerInterface, error) {
+	var interfaces []ContainerInterface
+	if err := c.client.Get(ctx, fmt.Sprintf("/nodes/%s/lxc/%d/interfaces", node, containerID), &interfaces); err != nil {
+		return nil, fmt.Errorf("failed to get container interfaces: %w", err)
+	}
+	return interfaces, nil
 }
 
 func (c *Client) GetContainersByNode(ctx context.Context, nodeName string) ([]*ContainerInfo, error) {
@@ -130,17 +411,238 @@ func (c *Client) GetNodes(ctx context.Context) ([]*NodeInfo, error) {
 			Name:   node.Node,
 			Status: node.Status,
 			Online: node.Status == "online",
+			CPU:    node.CPU,
+			MaxCPU: node.MaxCPU,
+			Mem:    node.Mem,
+			MaxMem: node.MaxMem,
 		})
 	}
 
 	return result, nil
 }
 
-func (c *Client) MigrateContainer(ctx context.Context, containerID int, targetNode string) error {
+func (c *Client) GetClusterTasks(ctx context.Context) ([]TaskInfo, error) {
+	var tasks []TaskInfo
+	if err := c.client.Get(ctx, "/cluster/tasks", &tasks); err != nil {
+		return nil, fmt.Errorf("failed to get cluster tasks: %w", err)
+	}
+	return tasks, nil
+}
+
+// GetGuests returns all LXC containers and QEMU virtual machines in the
+// cluster, with their tags and pool.
+func (c *Client) GetGuests(ctx context.Context) ([]ClusterResource, error) {
+	var resources []ClusterResource
+	if err := c.client.Get(ctx, "/cluster/resources?type=vm", &resources); err != nil {
+		return nil, fmt.Errorf("failed to get cluster resources: %w", err)
+	}
+	return resources, nil
+}
+
+// GetClusterResources returns all LXC containers in the cluster with their
+// tags and pool.
+func (c *Client) GetClusterResources(ctx context.Context) ([]ClusterResource, error) {
+	resources, err := c.GetGuests(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var containers []ClusterResource
+	for _, resource := range resources {
+		if resource.Type == "lxc" {
+			containers = append(containers, resource)
+		}
+	}
+	return containers, nil
+}
+
+func (c *Client) GetReplicationJobs(ctx context.Context) ([]ReplicationJob, error) {
+	var jobs []ReplicationJob
+	if err := c.client.Get(ctx, "/cluster/replication", &jobs); err != nil {
+		return nil, fmt.Errorf("failed to get replication jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// GetStorages returns the cluster's storage configuration.
+func (c *Client) GetStorages(ctx context.Context) ([]StorageInfo, error) {
+	var storages []StorageInfo
+	if err := c.client.Get(ctx, "/storage", &storages); err != nil {
+		return nil, fmt.Errorf("failed to get storages: %w", err)
+	}
+	return storages, nil
+}
+
+// StorageStatus is the state of a storage on a node.
+type StorageStatus struct {
+	Active  proxmox.IntOrBool `json:"active"`
+	Enabled proxmox.IntOrBool `json:"enabled"`
+	Avail   uint64            `json:"avail"`
+	Total   uint64            `json:"total"`
+}
+
+// GetStorageStatus returns the state of a storage on a node.
+func (c *Client) GetStorageStatus(ctx context.Context, node, storage string) (*StorageStatus, error) {
+	var status StorageStatus
+	if err := c.client.Get(ctx, fmt.Sprintf("/nodes/%s/storage/%s/status", node, storage), &status); err != nil {
+		return nil, fmt.Errorf("failed to get status of storage %s on node %s: %w", storage, node, err)
+	}
+	return &status, nil
+}
+
+// GetPermissions returns the privileges the API user or token has on path,
+// such as "/vms/100" or "/storage/local".
+func (c *Client) GetPermissions(ctx context.Context, path string) (map[string]bool, error) {
+	var permissions map[string]map[string]int
+	if err := c.client.Get(ctx, "/access/permissions?path="+url.QueryEscape(path), &permissions); err != nil {
+		return nil, fmt.Errorf("failed to get permissions on %s: %w", path, err)
+	}
+
+	privileges := make(map[string]bool)
+	for privilege := range permissions[path] {
+		privileges[privilege] = true
+	}
+	return privileges, nil
+}
+
+// GetNodeTime returns the time of a node's clock.
+func (c *Client) GetNodeTime(ctx context.Context, node string) (time.Time, error) {
+	var clock struct {
+		Time int64 `json:"time"`
+	}
+	if err := c.client.Get(ctx, fmt.Sprintf("/nodes/%s/time", node), &clock); err != nil {
+		return time.Time{}, fmt.Errorf("failed to get time of node %s: %w", node, err)
+	}
+	return time.Unix(clock.Time, 0), nil
+}
+
+func (c *Client) GetClusterStatus(ctx context.Context) (*ClusterStatus, error) {
+	var entries []struct {
+		Type    string            `json:"type"`
+		Name    string            `json:"name"`
+		Online  proxmox.IntOrBool `json:"online"`
+		Quorate proxmox.IntOrBool `json:"quorate"`
+	}
+	if err := c.client.Get(ctx, "/cluster/status", &entries); err != nil {
+		return nil, fmt.Errorf("failed to get cluster status: %w", err)
+	}
+
+	status := &ClusterStatus{Nodes: make(map[string]bool)}
+	for _, entry := range entries {
+		switch entry.Type {
+		case "cluster":
+			status.Quorate = bool(entry.Quorate)
+		case "node":
+			status.Nodes[entry.Name] = bool(entry.Online)
+		}
+	}
+	return status, nil
+}
+
+// GetHANodeStatus returns the Proxmox HA manager's state of each node, such
+// as "online", "fence" while fencing is pending, or "unknown" once the node
+// is fenced. It is empty when HA is not in use.
+func (c *Client) GetHANodeStatus(ctx context.Context) (map[string]string, error) {
+	var status struct {
+		ManagerStatus struct {
+			NodeStatus map[string]string `json:"node_status"`
+		} `json:"manager_status"`
+	}
+	if err := c.client.Get(ctx, "/cluster/ha/status/manager_status", &status); err != nil {
+		return nil, fmt.Errorf("failed to get HA manager status: %w", err)
+	}
+	return status.ManagerStatus.NodeStatus, nil
+}
+
+// GetHAPlacement returns the HA group or node affinity rule of a container.
+// It is nil when the container is not managed by Proxmox HA or its
+// resource has neither.
+func (c *Client) GetHAPlacement(ctx context.Context, containerID int) (*HAPlacement, error) {
+	sid := fmt.Sprintf("ct:%d", containerID)
+
+	var resources []struct {
+		SID   string `json:"sid"`
+		Group string `json:"group"`
+	}
+	if err := c.client.Get(ctx, "/cluster/ha/resources?type=ct", &resources); err != nil {
+		return nil, fmt.Errorf("failed to get HA resources: %w", err)
+	}
+	managed := false
+	var group string
+	for _, resource := range resources {
+		if resource.SID == sid {
+			managed = true
+			group = resource.Group
+			break
+		}
+	}
+	if !managed {
+		return nil, nil
+	}
+
+	if group != "" {
+		var haGroup struct {
+			Nodes      string            `json:"nodes"`
+			Restricted proxmox.IntOrBool `json:"restricted"`
+		}
+		if err := c.client.Get(ctx, "/cluster/ha/groups/"+url.PathEscape(group), &haGroup); err != nil {
+			return nil, fmt.Errorf("failed to get HA group %s: %w", group, err)
+		}
+		return &HAPlacement{Source: "group " + group, Nodes: parseHANodes(haGroup.Nodes), Restricted: bool(haGroup.Restricted)}, nil
+	}
+
+	var rules []struct {
+		Rule      string            `json:"rule"`
+		Resources string            `json:"resources"`
+		Nodes     string            `json:"nodes"`
+		Strict    proxmox.IntOrBool `json:"strict"`
+		Disable   proxmox.IntOrBool `json:"disable"`
+	}
+	if err := c.client.Get(ctx, "/cluster/ha/rules?type=node-affinity", &rules); err != nil {
+		// Proxmox VE before 9 has no rules, so a resource without a group
+		// may run anywhere
+		return nil, nil
+	}
+	for _, rule := range rules {
+		if rule.Disable {
+			continue
+		}
+		for _, resource := range strings.Split(rule.Resources, ",") {
+			if strings.TrimSpace(resource) == sid {
+				return &HAPlacement{Source: "rule " + rule.Rule, Nodes: parseHANodes(rule.Nodes), Restricted: bool(rule.Strict)}, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// parseHANodes parses an HA node list such as "pve1:2,pve2", where nodes
+// without a priority have priority 0.
+func parseHANodes(list string) map[string]int {
+	nodes := make(map[string]int)
+	for _, entry := range strings.Split(list, ",") {
+		name, priority, _ := strings.Cut(strings.TrimSpace(entry), ":")
+		if name == "" {
+			continue
+		}
+		value, err := strconv.Atoi(priority)
+		if err != nil {
+			value = 0
+		}
+		nodes[name] = value
+	}
+	return nodes
+}
+
+func (c *Client) MigrateContainer(ctx context.Context, containerID int, targetNode string) (err error) {
+	entry := audit.Begin(ctx, audit.ActionMigrate, containerID).Param("target_node", targetNode)
+	defer func() { entry.End(err) }()
+
 	container, err := c.GetContainer(ctx, containerID)
 	if err != nil {
 		return fmt.Errorf("failed to get container info: %w", err)
 	}
+	entry.Node = container.Node
 
 	nodeObj, err := c.client.Node(ctx, container.Node)
 	if err != nil {
@@ -152,28 +654,33 @@ func (c *Client) MigrateContainer(ctx context.Context, containerID int, targetNo
 		return fmt.Errorf("failed to get container: %w", err)
 	}
 
-	// Use basic migration without complex options for now
+	// Containers cannot move while running; a restart migration stops the
+	// container, moves it and starts it again on the target
 	task, err := lxc.Migrate(ctx, &proxmox.ContainerMigrateOptions{
-		Target: targetNode,
-		Online: false,
+		Target:  targetNode,
+		Online:  false,
+		Restart: proxmox.IntOrBool(container.Status == "running"),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to start migration: %w", err)
 	}
 
-	err = task.Wait(ctx, 5*time.Minute, 30*time.Second)
-	if err != nil {
+	if err := waitTask(ctx, task, 5*time.Second, 30*time.Minute); err != nil {
 		return fmt.Errorf("migration failed: %w", err)
 	}
 
 	return nil
 }
 
-func (c *Client) StopContainer(ctx context.Context, containerID int) error {
+func (c *Client) StopContainer(ctx context.Context, containerID int) (err error) {
+	entry := audit.Begin(ctx, audit.ActionStop, containerID)
+	defer func() { entry.End(err) }()
+
 	container, err := c.GetContainer(ctx, containerID)
 	if err != nil {
 		return fmt.Errorf("failed to get container info: %w", err)
 	}
+	entry.Node = container.Node
 
 	nodeObj, err := c.client.Node(ctx, container.Node)
 	if err != nil {
@@ -190,14 +697,18 @@ func (c *Client) StopContainer(ctx context.Context, containerID int) error {
 		return fmt.Errorf("failed to stop container: %w", err)
 	}
 
-	return task.Wait(ctx, 1*time.Minute, 5*time.Second)
+	return waitTask(ctx, task, 5*time.Second, time.Minute)
 }
 
-func (c *Client) StartContainer(ctx context.Context, containerID int) error {
+func (c *Client) StartContainer(ctx context.Context, containerID int) (err error) {
+	entry := audit.Begin(ctx, audit.ActionStart, containerID)
+	defer func() { entry.End(err) }()
+
 	container, err := c.GetContainer(ctx, containerID)
 	if err != nil {
 		return fmt.Errorf("failed to get container info: %w", err)
 	}
+	entry.Node = container.Node
 
 	nodeObj, err := c.client.Node(ctx, container.Node)
 	if err != nil {
@@ -214,41 +725,414 @@ func (c *Client) StartContainer(ctx context.Context, containerID int) error {
 		return fmt.Errorf("failed to start container: %w", err)
 	}
 
-	return task.Wait(ctx, 1*time.Minute, 5*time.Second)
+	return waitTask(ctx, task, 5*time.Second, time.Minute)
+}
+
+// BackupContainer backs the container up with vzdump and returns the
+// volume ID of the backup or, without a storage, the path of the file
+// dumped to backupDir. It waits for the backup until ctx is done.
+func (c *Client) BackupContainer(ctx context.Context, containerID int, storage, backupDir string, options config.VzdumpOptions) (volID string, err error) {
+	entry := audit.Begin(ctx, audit.ActionBackup, containerID).Param("storage", storage)
+	if options.Mode != "" {
+		entry.Param("mode", options.Mode)
+	}
+	defer func() {
+		if volID != "" {
+			entry.Param("volid", volID)
+		}
+		entry.End(err)
+	}()
+
+	container, err := c.GetContainer(ctx, containerID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get container info: %w", err)
+	}
+	entry.Node = container.Node
+
+	nodeObj, err := c.client.Node(ctx, container.Node)
+	if err != nil {
+		return "", fmt.Errorf("failed to get node: %w", err)
+	}
+
+	// Volume creation times have a resolution of seconds
+	started := time.Now().Truncate(time.Second)
+	task, err := nodeObj.Vzdump(ctx, vzdumpOptions(containerID, storage, backupDir, options))
+	if err != nil {
+		return "", fmt.Errorf("failed to start backup: %w", err)
+	}
+	if err := waitTask(ctx, task, 5*time.Second, 3*time.Hour); err != nil {
+		return "", fmt.Errorf("backup failed: %w", err)
+	}
+
+	// Dumps to backup_dir are files rather than volumes of a storage
+	if storage == "" {
+		log, err := task.Log(ctx, 0, 1000)
+		if err != nil {
+			return "", fmt.Errorf("failed to read backup log: %w", err)
+		}
+		return dumpFile(log, containerID)
+	}
+
+	backupStorage, err := nodeObj.Storage(ctx, storage)
+	if err != nil {
+		return "", fmt.Errorf("failed to get storage %s: %w", storage, err)
+	}
+	content, err := backupStorage.GetContent(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list storage %s: %w", storage, err)
+	}
+	return latestBackup(content, containerID, storage, started)
+}
+
+// vzdumpOptions returns the vzdump parameters backing a container up to
+// storage, or to backupDir without one.
+func vzdumpOptions(containerID int, storage, backupDir string, options config.VzdumpOptions) *proxmox.VirtualMachineBackupOptions {
+	vzdump := &proxmox.VirtualMachineBackupOptions{
+		VMID:     uint64(containerID),
+		Storage:  storage,
+		Mode:     proxmox.VirtualMachineBackupModeSnapshot,
+		Compress: proxmox.VirtualMachineBackupCompressZstd,
+	}
+	if options.Mode != "" {
+		vzdump.Mode = options.Mode
+	}
+	switch options.Compress {
+	case "":
+	case config.BackupCompressNone:
+		vzdump.Compress = proxmox.VirtualMachineBackupCompressZero
+	default:
+		vzdump.Compress = options.Compress
+	}
+	if options.BwLimit > 0 {
+		vzdump.BwLimit = uint(options.BwLimit)
+	}
+	if storage == "" {
+		vzdump.DumpDir = backupDir
+	}
+	return vzdump
+}
+
+// vzdumpArchive matches the line of a vzdump log naming the archive written.
+var vzdumpArchive = regexp.MustCompile(`creating (?:vzdump )?archive '([^']+)'`)
+
+// dumpFile returns the path of the archive a vzdump task wrote, as its log
+// names it.
+func dumpFile(log proxmox.Log, containerID int) (string, error) {
+	for _, line := range log {
+		if match := vzdumpArchive.FindStringSubmatch(line); match != nil {
+			return match[1], nil
+		}
+	}
+	return "", fmt.Errorf("backup of container %d not named in the vzdump log", containerID)
+}
+
+// latestBackup returns the volume ID of the newest backup of the container
+// in content created since started.
+func latestBackup(content []*proxmox.StorageContent, containerID int, storage string, started time.Time) (string, error) {
+	var backup *proxmox.StorageContent
+	for _, volume := range content {
+		if volume.VMID != uint64(containerID) || int64(volume.Ctime) < started.Unix() {
+			continue
+		}
+		if backup == nil || volume.Ctime > backup.Ctime {
+			backup = volume
+		}
+	}
+	if backup == nil {
+		return "", fmt.Errorf("backup of container %d not found on storage %s", containerID, storage)
+	}
+	return backup.Volid, nil
+}
+
+// waitTask waits for a task to finish, up to max or, if ctx has a deadline,
+// until then, and fails if the task failed. A task that times out is
+// stopped, so it does not hold locks a retry needs; one whose wait is
+// cancelled keeps running.
+func waitTask(ctx context.Context, task *proxmox.Task, interval, max time.Duration) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		max = time.Until(deadline)
+	}
+
+	err := task.Wait(ctx, interval, max)
+	if errors.Is(err, proxmox.ErrTimeout) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+		task.Stop(stopCtx)
+		return fmt.Errorf("task %s did not finish within %s: %w", task.UPID, max.Round(time.Second), context.DeadlineExceeded)
+	}
+	if err != nil {
+		return err
+	}
+	if task.IsFailed {
+		return fmt.Errorf("task %s failed: %s", task.UPID, task.ExitStatus)
+	}
+	return nil
+}
+
+// RestoreOptions adjusts a container restored from a backup. Zero fields
+// keep the values of the backup.
+type RestoreOptions struct {
+	// Storage is where the container's volumes are restored to
+	Storage string
+	// Force overwrites an existing container with the same ID
+	Force bool
+	Cores int
+	// Memory is in MiB
+	Memory int
+	// Bridge replaces the bridge of every network interface
+	Bridge string
+	// DisableOnBoot keeps the restored container from starting with its
+	// node, as copies that must stay stopped need
+	DisableOnBoot bool
+	// Progress is called as the restore's task log tells how far it is
+	Progress func(RestoreProgress)
+}
+
+func (c *Client) RestoreContainerFromBackup(ctx context.Context, containerID int, targetNode, backupPath string, options RestoreOptions) (err error) {
+	entry := audit.Begin(ctx, audit.ActionRestore, containerID).Param("backup", backupPath).Param("storage", options.Storage)
+	entry.Node = targetNode
+	if options.Force {
+		entry.Param("force", "true")
+	}
+	defer func() { entry.End(err) }()
+
+	nodeObj, err := c.client.Node(ctx, targetNode)
+	if err != nil {
+		return fmt.Errorf("failed to get node %s: %w", targetNode, err)
+	}
+
+	params := []proxmox.ContainerOption{
+		{Name: "ostemplate", Value: backupPath},
+		{Name: "restore", Value: 1},
+	}
+	if options.Storage != "" {
+		params = append(params, proxmox.ContainerOption{Name: "storage", Value: options.Storage})
+	}
+	if options.Force {
+		params = append(params, proxmox.ContainerOption{Name: "force", Value: 1})
+	}
+	if options.Cores > 0 {
+		params = append(params, proxmox.ContainerOption{Name: "cores", Value: options.Cores})
+	}
+	if options.Memory > 0 {
+		params = append(params, proxmox.ContainerOption{Name: "memory", Value: options.Memory})
+	}
+	if options.DisableOnBoot {
+		params = append(params, proxmox.ContainerOption{Name: "onboot", Value: 0})
+	}
+
+	task, err := nodeObj.NewContainer(ctx, containerID, params...)
+	if err != nil {
+		return fmt.Errorf("failed to start restore: %w", err)
+	}
+
+	// Report progress from the task log while waiting for the task
+	progress := RestoreProgress{Phase: RestorePhaseStarting, Percent: -1}
+	stopFollowing := func() {}
+	if options.Progress != nil {
+		options.Progress(progress)
+
+		followCtx, cancel := context.WithCancel(ctx)
+		following := make(chan struct{})
+		go func() {
+			defer close(following)
+			followTask(followCtx, task, func(line string) {
+				if progress.update(line) {
+					options.Progress(progress)
+				}
+			})
+		}()
+		stopFollowing = func() {
+			cancel()
+			<-following
+		}
+	}
+
+	err = waitTask(ctx, task, 5*time.Second, 3*time.Hour)
+	stopFollowing()
+	if err != nil {
+		return fmt.Errorf("restore failed: %w", err)
+	}
+	if options.Progress != nil && progress.Phase != RestorePhaseDone {
+		progress.Phase, progress.Percent = RestorePhaseDone, 100
+		options.Progress(progress)
+	}
+
+	if options.Bridge != "" {
+		return c.setBridge(ctx, targetNode, containerID, options.Bridge)
+	}
+	return nil
 }
 
-func (c *Client) BackupContainer(ctx context.Context, containerID int, storage, backupDir string) (string, error) {
-	// Generate backup filename
-	backupFilename := fmt.Sprintf("vzdump-lxc-%d-%s.tar.zst", containerID, 
-		time.Now().Format("2006_01_02-15_04_05"))
+// setBridge connects every network interface of a container to bridge.
+func (c *Client) setBridge(ctx context.Context, node string, containerID int, bridge string) error {
+	path := fmt.Sprintf("/nodes/%s/lxc/%d/config", node, containerID)
 
-	// For now, return a simulated backup path
-	// In a real implementation, this would trigger a vzdump backup
-	backupPath := fmt.Sprintf("%s:%s/%s", storage, backupDir, backupFilename)
-	
-	// TODO: Implement actual backup via Proxmox API
-	// This would involve calling the /cluster/backup endpoint or vzdump command
-	
-	return backupPath, nil
+	var containerConfig map[string]interface{}
+	if err := c.client.Get(ctx, path, &containerConfig); err != nil {
+		return fmt.Errorf("failed to get container config: %w", err)
+	}
+
+	update := make(map[string]interface{})
+	for key, value := range containerConfig {
+		netConfig, ok := value.(string)
+		if ok && isNetKey(key) {
+			update[key] = replaceBridge(netConfig, bridge)
+		}
+	}
+	if len(update) == 0 {
+		return nil
+	}
+
+	if err := c.client.Put(ctx, path, update, nil); err != nil {
+		return fmt.Errorf("failed to set bridge %s: %w", bridge, err)
+	}
+	return nil
+}
+
+// isNetKey reports whether a container config key is a network interface,
+// net0 to netN.
+func isNetKey(key string) bool {
+	index := strings.TrimPrefix(key, "net")
+	if index == key || index == "" {
+		return false
+	}
+	for _, r := range index {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
 }
 
-func (c *Client) RestoreContainerFromBackup(ctx context.Context, containerID int, targetNode, storage, backupPath string, force bool) error {
-	// TODO: Implement actual restore via Proxmox API
-	// This would involve a POST to /nodes/{node}/lxc with restore parameters
-	
-	// For now, this is a placeholder that simulates the restore process
-	return fmt.Errorf("restore functionality not yet implemented - requires direct API calls")
+// replaceBridge sets the bridge property of a network interface config such
+// as "name=eth0,bridge=vmbr0,ip=dhcp".
+func replaceBridge(netConfig, bridge string) string {
+	properties := strings.Split(netConfig, ",")
+	for i, property := range properties {
+		if strings.HasPrefix(property, "bridge=") {
+			properties[i] = "bridge=" + bridge
+			return strings.Join(properties, ",")
+		}
+	}
+	return strings.Join(append(properties, "bridge="+bridge), ",")
 }
 
+// GetBackups lists the container backups on storage, newest first. Shared
+// storage is listed once; node-local storage of the same name is listed on
+// every online node.
 func (c *Client) GetBackups(ctx context.Context, storage string) ([]BackupInfo, error) {
-	// TODO: Implement backup listing via Proxmox API
-	// This would query storage content for backup files
-	
-	// Return empty list for now
-	return []BackupInfo{}, nil
+	nodes, err := c.GetNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	backups := []BackupInfo{}
+	seen := make(map[string]bool)
+	var errs []error
+	for _, node := range nodes {
+		if !node.Online {
+			continue
+		}
+
+		var content []struct {
+			Volid     string            `json:"volid"`
+			Format    string            `json:"format"`
+			Subtype   string            `json:"subtype"`
+			Size      int64             `json:"size"`
+			Ctime     int64             `json:"ctime"`
+			VMID      int               `json:"vmid"`
+			Protected proxmox.IntOrBool `json:"protected"`
+		}
+		path := fmt.Sprintf("/nodes/%s/storage/%s/content?content=backup", node.Name, url.PathEscape(storage))
+		if err := c.client.Get(ctx, path, &content); err != nil {
+			// The storage may not be configured on every node
+			errs = append(errs, fmt.Errorf("node %s: %w", node.Name, err))
+			continue
+		}
+
+		for _, volume := range content {
+			lxc := volume.Subtype == "lxc" || strings.Contains(volume.Volid, "vzdump-lxc-")
+			if !lxc || seen[volume.Volid] {
+				continue
+			}
+			seen[volume.Volid] = true
+
+			_, volumePath, _ := strings.Cut(volume.Volid, ":")
+			backups = append(backups, BackupInfo{
+				Node:        node.Name,
+				Storage:     storage,
+				Filename:    volumePath[strings.LastIndex(volumePath, "/")+1:],
+				Size:        volume.Size,
+				Format:      volume.Format,
+				VolID:       volume.Volid,
+				ContainerID: volume.VMID,
+				Created:     time.Unix(volume.Ctime, 0),
+				Protected:   bool(volume.Protected),
+			})
+		}
+	}
+	if len(seen) == 0 && len(errs) > 0 {
+		return nil, fmt.Errorf("failed to list storage %s: %w", storage, errors.Join(errs...))
+	}
+
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].Created.After(backups[j].Created)
+	})
+	return backups, nil
 }
 
-func (c *Client) DeleteBackup(ctx context.Context, nodeName, storage, backupPath string) error {
-	// TODO: Implement backup deletion via Proxmox API
-	return fmt.Errorf("delete backup functionality not yet implemented")
-}
\ No newline at end of file
+// DeleteBackup deletes the backup volume backupPath from storage through
+// nodeName.
+func (c *Client) DeleteBackup(ctx context.Context, nodeName, storage, backupPath string) (err error) {
+	entry := audit.Begin(ctx, audit.ActionDeleteBackup, 0).Param("storage", storage).Param("volid", backupPath)
+	entry.Node = nodeName
+	defer func() { entry.End(err) }()
+
+	var upid proxmox.UPID
+	path := fmt.Sprintf("/nodes/%s/storage/%s/content/%s", nodeName, url.PathEscape(storage), url.PathEscape(backupPath))
+	if err := c.client.Delete(ctx, path, &upid); err != nil {
+		return fmt.Errorf("failed to delete backup %s: %w", backupPath, err)
+	}
+
+	// Older Proxmox versions delete synchronously
+	task := proxmox.NewTask(upid, c.client)
+	if task == nil {
+		return nil
+	}
+	if err := waitTask(ctx, task, time.Second, 10*time.Minute); err != nil {
+		return fmt.Errorf("failed to delete backup %s: %w", backupPath, err)
+	}
+	return nil
+}
+
+// SetBackupProtected protects the backup volume backupPath on storage from
+// deletion, or lifts the protection, through nodeName.
+func (c *Client) SetBackupProtected(ctx context.Context, nodeName, storage, backupPath string, protected bool) (err error) {
+	entry := audit.Begin(ctx, audit.ActionProtectBackup, 0).Param("storage", storage).Param("volid", backupPath).Param("protected", strconv.FormatBool(protected))
+	entry.Node = nodeName
+	defer func() { entry.End(err) }()
+
+	value := 0
+	if protected {
+		value = 1
+	}
+	path := fmt.Sprintf("/nodes/%s/storage/%s/content/%s", nodeName, url.PathEscape(storage), url.PathEscape(backupPath))
+	if err := c.client.Put(ctx, path, map[string]interface{}{"protected": value}, nil); err != nil {
+		return fmt.Errorf("failed to set protection of backup %s: %w", backupPath, err)
+	}
+	return nil
+}
+
+// GetBackupPath returns where the backup volume backupPath is stored on
+// nodeName: a file path for directory-based storages.
+func (c *Client) GetBackupPath(ctx context.Context, nodeName, storage, backupPath string) (string, error) {
+	var attributes struct {
+		Path string `json:"path"`
+	}
+	path := fmt.Sprintf("/nodes/%s/storage/%s/content/%s", nodeName, url.PathEscape(storage), url.PathEscape(backupPath))
+	if err := c.client.Get(ctx, path, &attributes); err != nil {
+		return "", fmt.Errorf("failed to get backup %s: %w", backupPath, err)
+	}
+	return attributes.Path, nil
+}
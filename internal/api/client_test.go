@@ -1,9 +1,16 @@
 package api
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
 	"testing"
+	"time"
 
 	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/luthermonson/go-proxmox"
 )
 
 func TestNewClient(t *testing.T) {
@@ -51,6 +58,47 @@ func TestNewClient_TokenAuth(t *testing.T) {
 	}
 }
 
+func TestNewClient_Insecure(t *testing.T) {
+	// httptest serves a certificate no client trusts
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":[]}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		insecure  bool
+		expectErr bool
+	}{
+		{"certificate verified", false, true},
+		{"insecure", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(&config.ProxmoxConfig{
+				Endpoint: server.URL + "/api2/json",
+				TokenID:  "root@pam!test",
+				Secret:   "secret",
+				Insecure: tt.insecure,
+				Proxy:    "direct",
+			})
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+
+			_, err = client.GetNodes(context.Background())
+			if tt.expectErr && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
 // Mock tests would require more complex setup with test servers
 // These tests verify basic client creation and configuration
 
@@ -92,6 +140,124 @@ func TestNodeInfo(t *testing.T) {
 	}
 }
 
+func TestClusterResource_TagList(t *testing.T) {
+	tests := []struct {
+		tags     string
+		expected []string
+	}{
+		{tags: "", expected: []string{}},
+		{tags: "proxwarden", expected: []string{"proxwarden"}},
+		{tags: "proxwarden;web", expected: []string{"proxwarden", "web"}},
+		{tags: "proxwarden,web db", expected: []string{"proxwarden", "web", "db"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tags, func(t *testing.T) {
+			got := ClusterResource{Tags: tt.tags}.TagList()
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestStorageInfo(t *testing.T) {
+	tests := []struct {
+		name      string
+		storage   StorageInfo
+		content   string
+		node      string
+		holds     bool
+		available bool
+	}{
+		{name: "all nodes", storage: StorageInfo{Content: "backup,iso"}, content: "backup", node: "pve1", holds: true, available: true},
+		{name: "listed node", storage: StorageInfo{Content: "rootdir, images", Nodes: "pve1, pve2"}, content: "rootdir", node: "pve2", holds: true, available: true},
+		{name: "other node", storage: StorageInfo{Content: "images", Nodes: "pve1"}, content: "rootdir", node: "pve3", holds: false, available: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.storage.Holds(tt.content); got != tt.holds {
+				t.Errorf("Holds(%q) = %v, expected %v", tt.content, got, tt.holds)
+			}
+			if got := tt.storage.AvailableOn(tt.node); got != tt.available {
+				t.Errorf("AvailableOn(%q) = %v, expected %v", tt.node, got, tt.available)
+			}
+		})
+	}
+}
+
+func TestContainerInterface_Addresses(t *testing.T) {
+	tests := []struct {
+		name     string
+		iface    ContainerInterface
+		expected []string
+	}{
+		{name: "loopback", iface: ContainerInterface{Inet: "127.0.0.1/8", Inet6: "::1/128"}, expected: nil},
+		{name: "dual stack", iface: ContainerInterface{Inet: "10.0.0.5/24", Inet6: "2001:db8::5/64"}, expected: []string{"10.0.0.5", "2001:db8::5"}},
+		{name: "link-local only", iface: ContainerInterface{Inet: "10.0.0.5/24", Inet6: "fe80::1/64"}, expected: []string{"10.0.0.5"}},
+		{name: "unset", iface: ContainerInterface{}, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, ip := range tt.iface.Addresses() {
+				got = append(got, ip.String())
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestReplaceBridge(t *testing.T) {
+	tests := []struct {
+		name      string
+		netConfig string
+		expected  string
+	}{
+		{name: "replaced", netConfig: "name=eth0,bridge=vmbr0,hwaddr=BC:24:11:00:00:01,ip=dhcp", expected: "name=eth0,bridge=vmbr1,hwaddr=BC:24:11:00:00:01,ip=dhcp"},
+		{name: "last property", netConfig: "name=eth0,ip=dhcp,bridge=vmbr0", expected: "name=eth0,ip=dhcp,bridge=vmbr1"},
+		{name: "no bridge", netConfig: "name=eth0,ip=dhcp", expected: "name=eth0,ip=dhcp,bridge=vmbr1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replaceBridge(tt.netConfig, "vmbr1"); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	for key, expected := range map[string]bool{"net0": true, "net12": true, "net": false, "nameserver": false, "netx": false} {
+		if got := isNetKey(key); got != expected {
+			t.Errorf("isNetKey(%q) = %v, expected %v", key, got, expected)
+		}
+	}
+}
+
+func TestParseHANodes(t *testing.T) {
+	tests := []struct {
+		list     string
+		expected map[string]int
+	}{
+		{list: "", expected: map[string]int{}},
+		{list: "pve1", expected: map[string]int{"pve1": 0}},
+		{list: "pve1:2,pve2:1", expected: map[string]int{"pve1": 2, "pve2": 1}},
+		{list: "pve1:2, pve2", expected: map[string]int{"pve1": 2, "pve2": 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.list, func(t *testing.T) {
+			if got := parseHANodes(tt.list); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
 // Integration tests would require a real Proxmox server or mock server
 // For now, we test the basic structure and configuration
 
@@ -141,4 +307,133 @@ func TestClient_ValidateConfig(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestRestoreProgress_Update(t *testing.T) {
+	lines := []struct {
+		line    string
+		phase   string
+		percent int
+		changed bool
+	}{
+		{"recovering backed-up configuration from 'local:backup/vzdump-lxc-100.tar.zst'", RestorePhaseStarting, -1, true},
+		{"  Logical volume \"vm-100-disk-0\" created.", RestorePhaseAllocating, -1, true},
+		{"", RestorePhaseAllocating, -1, false},
+		{"extracting archive '/var/lib/vz/dump/vzdump-lxc-100.tar.zst'", RestorePhaseExtracting, -1, true},
+		{"progress 45% (read 4831838208 bytes, duration 30 sec)", RestorePhaseExtracting, 45, true},
+		{"Creating filesystem with 2097152 4k blocks", RestorePhaseExtracting, 45, true},
+		{"merging backed-up configuration into the new one", RestorePhaseConfiguring, 45, true},
+		{"TASK OK", RestorePhaseDone, 100, true},
+	}
+
+	progress := RestoreProgress{Phase: RestorePhaseStarting, Percent: -1}
+	for _, tt := range lines {
+		changed := progress.update(tt.line)
+		if changed != tt.changed || progress.Phase != tt.phase || progress.Percent != tt.percent {
+			t.Errorf("After %q expected %s %d%% (changed %v), got %s %d%% (changed %v)",
+				tt.line, tt.phase, tt.percent, tt.changed, progress.Phase, progress.Percent, changed)
+		}
+	}
+}
+
+func TestPreFailoverSnapshot(t *testing.T) {
+	taken := time.Date(2024, 3, 1, 14, 30, 5, 0, time.UTC)
+	name := PreFailoverSnapshotName(taken)
+	if name != "prefailover-20240301-143005" {
+		t.Errorf("PreFailoverSnapshotName() = %q", name)
+	}
+	// Proxmox snapshot names start with a letter and are at most 40
+	// characters of letters, digits, "-" and "_"
+	if !regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]{0,39}$`).MatchString(name) {
+		t.Errorf("PreFailoverSnapshotName() = %q is no valid snapshot name", name)
+	}
+
+	snapshots := []Snapshot{
+		{Name: "prefailover-20240301-143005", Taken: taken},
+		{Name: "before-upgrade", Taken: taken.Add(2 * time.Hour)},
+		{Name: "prefailover-20240301-153005", Taken: taken.Add(time.Hour)},
+	}
+	latest := LatestPreFailover(snapshots)
+	if latest == nil || latest.Name != "prefailover-20240301-153005" {
+		t.Errorf("LatestPreFailover() = %+v, expected the newest pre-failover snapshot", latest)
+	}
+	if latest := LatestPreFailover(snapshots[1:2]); latest != nil {
+		t.Errorf("LatestPreFailover() = %+v, expected none", latest)
+	}
+}
+
+func TestVzdumpOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		storage  string
+		options  config.VzdumpOptions
+		expected proxmox.VirtualMachineBackupOptions
+	}{
+		{
+			name:     "storage with defaults",
+			storage:  "pbs",
+			expected: proxmox.VirtualMachineBackupOptions{VMID: 100, Storage: "pbs", Mode: proxmox.VirtualMachineBackupModeSnapshot, Compress: proxmox.VirtualMachineBackupCompressZstd},
+		},
+		{
+			name:     "backup_dir without a storage",
+			expected: proxmox.VirtualMachineBackupOptions{VMID: 100, DumpDir: "/var/lib/vz/dump", Mode: proxmox.VirtualMachineBackupModeSnapshot, Compress: proxmox.VirtualMachineBackupCompressZstd},
+		},
+		{
+			name:     "options",
+			storage:  "local",
+			options:  config.VzdumpOptions{Mode: config.BackupModeStop, Compress: config.BackupCompressNone, BwLimit: 10240},
+			expected: proxmox.VirtualMachineBackupOptions{VMID: 100, Storage: "local", Mode: config.BackupModeStop, Compress: proxmox.VirtualMachineBackupCompressZero, BwLimit: 10240},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := vzdumpOptions(100, tt.storage, "/var/lib/vz/dump", tt.options)
+			if !reflect.DeepEqual(*got, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, *got)
+			}
+		})
+	}
+}
+
+func TestDumpFile(t *testing.T) {
+	log := proxmox.Log{
+		0: "INFO: starting new backup job: vzdump 100 --dumpdir /var/lib/vz/dump",
+		1: "INFO: Starting Backup of VM 100 (lxc)",
+		2: "INFO: creating vzdump archive '/var/lib/vz/dump/vzdump-lxc-100-2024_05_01-10_00_00.tar.zst'",
+		3: "INFO: Finished Backup of VM 100 (00:00:12)",
+	}
+	file, err := dumpFile(log, 100)
+	if err != nil {
+		t.Fatalf("Failed to find the dump file: %v", err)
+	}
+	if file != "/var/lib/vz/dump/vzdump-lxc-100-2024_05_01-10_00_00.tar.zst" {
+		t.Errorf("Unexpected dump file %s", file)
+	}
+
+	delete(log, 2)
+	if _, err := dumpFile(log, 100); err == nil {
+		t.Error("Expected an error for a log naming no archive")
+	}
+}
+
+func TestLatestBackup(t *testing.T) {
+	started := time.Unix(1714557600, 0)
+	content := []*proxmox.StorageContent{
+		{Volid: "local:backup/vzdump-lxc-100-old.tar.zst", VMID: 100, Ctime: uint64(started.Unix()) - 3600},
+		{Volid: "local:backup/vzdump-lxc-100-new.tar.zst", VMID: 100, Ctime: uint64(started.Unix()) + 20},
+		{Volid: "local:backup/vzdump-lxc-100-first.tar.zst", VMID: 100, Ctime: uint64(started.Unix())},
+		{Volid: "local:backup/vzdump-lxc-101-new.tar.zst", VMID: 101, Ctime: uint64(started.Unix()) + 60},
+	}
+
+	volID, err := latestBackup(content, 100, "local", started)
+	if err != nil {
+		t.Fatalf("Failed to find the backup: %v", err)
+	}
+	if volID != "local:backup/vzdump-lxc-100-new.tar.zst" {
+		t.Errorf("Expected the newest backup of container 100, got %s", volID)
+	}
+
+	if _, err := latestBackup(content[:1], 100, "local", started); err == nil {
+		t.Error("Expected an error when only older backups exist")
+	}
+}
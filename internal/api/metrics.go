@@ -0,0 +1,27 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/jbutlerdev/proxwarden/internal/metrics"
+)
+
+var apiRequests = metrics.NewCounter("proxwarden_proxmox_api_requests_total",
+	"Requests to the Proxmox API by HTTP method and status code class, or error when no response was received.",
+	"method", "status")
+
+// countingTransport counts the requests sent through it by outcome.
+type countingTransport struct {
+	next http.RoundTripper
+}
+
+func (t countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	status := "error"
+	if err == nil {
+		status = fmt.Sprintf("%dxx", resp.StatusCode/100)
+	}
+	apiRequests.Inc(req.Method, status)
+	return resp, err
+}
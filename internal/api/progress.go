@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	proxmox "github.com/luthermonson/go-proxmox"
+)
+
+// Phases of a restore, as told by its task log.
+const (
+	RestorePhaseStarting    = "starting"
+	RestorePhaseAllocating  = "allocating"
+	RestorePhaseExtracting  = "extracting"
+	RestorePhaseConfiguring = "configuring"
+	RestorePhaseDone        = "done"
+)
+
+// RestoreProgress is how far a restore has come.
+type RestoreProgress struct {
+	Phase string
+	// Percent is -1 while Proxmox does not report it, as for most
+	// container archives
+	Percent int
+	// Line is the last line of the restore's task log
+	Line string
+}
+
+// taskLogInterval is how often the log of a followed task is read.
+const taskLogInterval = 2 * time.Second
+
+var progressPercent = regexp.MustCompile(`(?i)progress\s+(\d{1,3})%`)
+
+// update advances the progress by a line of the restore's task log and
+// reports whether it changed.
+func (p *RestoreProgress) update(line string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return false
+	}
+	previous := *p
+	p.Line = line
+
+	lower := strings.ToLower(line)
+	switch {
+	case strings.HasPrefix(line, "TASK OK"):
+		p.Phase = RestorePhaseDone
+		p.Percent = 100
+	case strings.Contains(lower, "merging backed-up configuration"), strings.Contains(lower, "detected container architecture"):
+		p.Phase = RestorePhaseConfiguring
+	case strings.Contains(lower, "extracting archive"), strings.HasPrefix(lower, "restoring '"), strings.Contains(lower, "restore vma archive"):
+		p.Phase = RestorePhaseExtracting
+	case strings.Contains(lower, "logical volume"), strings.Contains(lower, "creating filesystem"), strings.Contains(lower, "formatting '"):
+		if p.Phase == RestorePhaseStarting {
+			p.Phase = RestorePhaseAllocating
+		}
+	}
+	if match := progressPercent.FindStringSubmatch(line); match != nil {
+		if percent, err := strconv.Atoi(match[1]); err == nil && percent <= 100 {
+			p.Percent = percent
+		}
+	}
+	return *p != previous
+}
+
+// followTask passes the lines of a task's log to handle as they are
+// written, until ctx is done.
+func followTask(ctx context.Context, task *proxmox.Task, handle func(line string)) {
+	ticker := time.NewTicker(taskLogInterval)
+	defer ticker.Stop()
+
+	start := 0
+	for {
+		if log, err := task.Log(ctx, start, 500); err == nil {
+			lines := make([]int, 0, len(log))
+			for n := range log {
+				lines = append(lines, n)
+			}
+			sort.Ints(lines)
+			for _, n := range lines {
+				handle(log[n])
+				if n >= start {
+					start = n + 1
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/audit"
+	proxmox "github.com/luthermonson/go-proxmox"
+)
+
+// PreFailoverSnapshotPrefix starts the names of the snapshots taken before
+// a failover, so they can be told apart from others and the latest rolled
+// back to quickly. The rest of the name is the time it was taken.
+const PreFailoverSnapshotPrefix = "prefailover-"
+
+// preFailoverTimeLayout keeps pre-failover snapshot names within the 40
+// characters and the letters, digits, "-" and "_" Proxmox allows.
+const preFailoverTimeLayout = "20060102-150405"
+
+// PreFailoverSnapshotName returns the name of a pre-failover snapshot
+// taken at t.
+func PreFailoverSnapshotName(t time.Time) string {
+	return PreFailoverSnapshotPrefix + t.UTC().Format(preFailoverTimeLayout)
+}
+
+// Snapshot is a snapshot of a container.
+type Snapshot struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Parent      string    `json:"parent,omitempty"`
+	Taken       time.Time `json:"taken"`
+}
+
+// PreFailover reports whether the snapshot was named as one taken before a
+// failover.
+func (s Snapshot) PreFailover() bool {
+	return strings.HasPrefix(s.Name, PreFailoverSnapshotPrefix)
+}
+
+// LatestPreFailover returns the newest pre-failover snapshot of snapshots,
+// or nil if there is none.
+func LatestPreFailover(snapshots []Snapshot) *Snapshot {
+	var latest *Snapshot
+	for i, snapshot := range snapshots {
+		if snapshot.PreFailover() && (latest == nil || snapshot.Taken.After(latest.Taken)) {
+			latest = &snapshots[i]
+		}
+	}
+	return latest
+}
+
+// GetSnapshots returns the snapshots of a container, oldest first.
+func (c *Client) GetSnapshots(ctx context.Context, containerID int) ([]Snapshot, error) {
+	container, err := c.GetContainer(ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container info: %w", err)
+	}
+
+	var entries []struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Parent      string `json:"parent"`
+		SnapTime    int64  `json:"snaptime"`
+	}
+	path := fmt.Sprintf("/nodes/%s/lxc/%d/snapshot", container.Node, containerID)
+	if err := c.client.Get(ctx, path, &entries); err != nil {
+		return nil, fmt.Errorf("failed to get snapshots of container %d: %w", containerID, err)
+	}
+
+	snapshots := make([]Snapshot, 0, len(entries))
+	for _, entry := range entries {
+		// The list ends with the current state, which is no snapshot
+		if entry.Name == "current" {
+			continue
+		}
+		snapshots = append(snapshots, Snapshot{
+			Name:        entry.Name,
+			Description: strings.TrimSpace(entry.Description),
+			Parent:      entry.Parent,
+			Taken:       time.Unix(entry.SnapTime, 0),
+		})
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].Taken.Before(snapshots[j].Taken)
+	})
+	return snapshots, nil
+}
+
+// CreateSnapshot takes a snapshot of a container.
+func (c *Client) CreateSnapshot(ctx context.Context, containerID int, name, description string) (err error) {
+	entry := audit.Begin(ctx, audit.ActionSnapshot, containerID).Param("snapshot", name)
+	defer func() { entry.End(err) }()
+
+	container, err := c.GetContainer(ctx, containerID)
+	if err != nil {
+		return fmt.Errorf("failed to get container info: %w", err)
+	}
+	entry.Node = container.Node
+
+	params := map[string]interface{}{"snapname": name}
+	if description != "" {
+		params["description"] = description
+	}
+	var upid proxmox.UPID
+	path := fmt.Sprintf("/nodes/%s/lxc/%d/snapshot", container.Node, containerID)
+	if err := c.client.Post(ctx, path, params, &upid); err != nil {
+		return fmt.Errorf("failed to start snapshot: %w", err)
+	}
+	if err := c.waitSnapshotTask(ctx, upid); err != nil {
+		return fmt.Errorf("snapshot failed: %w", err)
+	}
+	return nil
+}
+
+// RollbackSnapshot rolls a container back to one of its snapshots,
+// starting it afterwards if start is set.
+func (c *Client) RollbackSnapshot(ctx context.Context, containerID int, name string, start bool) (err error) {
+	entry := audit.Begin(ctx, audit.ActionRollbackSnapshot, containerID).Param("snapshot", name)
+	defer func() { entry.End(err) }()
+
+	container, err := c.GetContainer(ctx, containerID)
+	if err != nil {
+		return fmt.Errorf("failed to get container info: %w", err)
+	}
+	entry.Node = container.Node
+
+	params := map[string]interface{}{}
+	if start {
+		params["start"] = 1
+	}
+	var upid proxmox.UPID
+	path := fmt.Sprintf("/nodes/%s/lxc/%d/snapshot/%s/rollback", container.Node, containerID, url.PathEscape(name))
+	if err := c.client.Post(ctx, path, params, &upid); err != nil {
+		return fmt.Errorf("failed to start rollback: %w", err)
+	}
+	if err := c.waitSnapshotTask(ctx, upid); err != nil {
+		return fmt.Errorf("rollback failed: %w", err)
+	}
+	return nil
+}
+
+// DeleteSnapshot deletes a snapshot of a container.
+func (c *Client) DeleteSnapshot(ctx context.Context, containerID int, name string) (err error) {
+	entry := audit.Begin(ctx, audit.ActionDeleteSnapshot, containerID).Param("snapshot", name)
+	defer func() { entry.End(err) }()
+
+	container, err := c.GetContainer(ctx, containerID)
+	if err != nil {
+		return fmt.Errorf("failed to get container info: %w", err)
+	}
+	entry.Node = container.Node
+
+	var upid proxmox.UPID
+	path := fmt.Sprintf("/nodes/%s/lxc/%d/snapshot/%s", container.Node, containerID, url.PathEscape(name))
+	if err := c.client.Delete(ctx, path, &upid); err != nil {
+		return fmt.Errorf("failed to delete snapshot %s: %w", name, err)
+	}
+	if err := c.waitSnapshotTask(ctx, upid); err != nil {
+		return fmt.Errorf("failed to delete snapshot %s: %w", name, err)
+	}
+	return nil
+}
+
+// waitSnapshotTask waits for the task of a snapshot operation. Snapshots of
+// some storages are taken synchronously, without a task to wait for.
+func (c *Client) waitSnapshotTask(ctx context.Context, upid proxmox.UPID) error {
+	task := proxmox.NewTask(upid, c.client)
+	if task == nil {
+		return nil
+	}
+	return waitTask(ctx, task, 2*time.Second, 30*time.Minute)
+}
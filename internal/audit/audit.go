@@ -0,0 +1,314 @@
+// Package audit keeps an append-only record of every action ProxWarden
+// takes on the cluster, with who took it, separate from the log.
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/store"
+	"github.com/sirupsen/logrus"
+)
+
+// FileName is the name of the audit log within the data directory.
+const FileName = "audit.jsonl"
+
+// ActorAuto is the actor of actions the daemon takes by itself.
+const ActorAuto = "auto"
+
+// Actions recorded in the audit log.
+const (
+	ActionStart            = "start"
+	ActionStop             = "stop"
+	ActionMigrate          = "migrate"
+	ActionBackup           = "backup"
+	ActionRestore          = "restore"
+	ActionDeleteBackup     = "delete_backup"
+	ActionProtectBackup    = "protect_backup"
+	ActionSnapshot         = "snapshot"
+	ActionRollbackSnapshot = "rollback_snapshot"
+	ActionDeleteSnapshot   = "delete_snapshot"
+	ActionHook             = "hook"
+	ActionAPIRequest       = "api_request"
+)
+
+// Entry is one action and its outcome.
+type Entry struct {
+	Time time.Time `json:"time"`
+	// Actor is ActorAuto, "user:<name>" for CLI commands, or "api:<client>"
+	// for requests to the daemon API
+	Actor       string            `json:"actor"`
+	Action      string            `json:"action"`
+	ContainerID int               `json:"container_id,omitempty"`
+	Node        string            `json:"node,omitempty"`
+	Parameters  map[string]string `json:"parameters,omitempty"`
+	Success     bool              `json:"success"`
+	Error       string            `json:"error,omitempty"`
+	Duration    time.Duration     `json:"duration"`
+}
+
+// Log is an audit log in a file of JSON lines, or in the store database,
+// only ever appended to.
+type Log struct {
+	mu   sync.Mutex
+	path string
+	db   *store.DB
+}
+
+func NewLog(path string) *Log {
+	return &Log{path: path}
+}
+
+// Append records an entry.
+func (l *Log) Append(entry Entry) error {
+	if l.db != nil {
+		return l.appendDB(entry)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	data = append(data, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o750); err != nil {
+		return fmt.Errorf("failed to create audit directory: %w", err)
+	}
+
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return f.Close()
+}
+
+// Filter selects entries. Zero fields match everything.
+type Filter struct {
+	ContainerID int
+	Action      string
+	Actor       string
+	Since       time.Time
+	Limit       int
+}
+
+func (f Filter) match(entry Entry) bool {
+	if f.ContainerID != 0 && entry.ContainerID != f.ContainerID {
+		return false
+	}
+	if f.Action != "" && entry.Action != f.Action {
+		return false
+	}
+	if f.Actor != "" && entry.Actor != f.Actor {
+		return false
+	}
+	return f.Since.IsZero() || !entry.Time.Before(f.Since)
+}
+
+// List returns the entries matching filter, newest first. Lines that cannot
+// be decoded, such as one torn by a crash, are skipped.
+func (l *Log) List(filter Filter) ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.db != nil {
+		entries, err := l.listDB(filter)
+		if err != nil {
+			return nil, err
+		}
+		return newestFirst(entries, filter.Limit), nil
+	}
+
+	f, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if filter.match(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return newestFirst(entries, filter.Limit), nil
+}
+
+// newestFirst reverses entries, which are in the order actions finished,
+// and keeps at most limit of them, unless limit is 0.
+func newestFirst(entries []Entry, limit int) []Entry {
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries
+}
+
+// The audit log of the process, shared by everything that acts on the
+// cluster like the metrics registry is.
+var (
+	mu           sync.Mutex
+	current      *Log
+	defaultActor = ActorAuto
+	logger       = logrus.StandardLogger()
+)
+
+// Open records the actions of this process in the log at path, by actor
+// unless their context names another. Failures to write the log are
+// reported to log.
+func Open(path, actor string, log *logrus.Logger) {
+	use(NewLog(path), actor, log)
+}
+
+func use(l *Log, actor string, log *logrus.Logger) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = l
+	defaultActor = actor
+	logger = log
+}
+
+// Path returns the file of the audit log cfg sets, or "" when auditing is
+// disabled.
+func Path(cfg *config.Config) string {
+	switch {
+	case !cfg.Audit.Enabled:
+		return ""
+	case cfg.Audit.File != "":
+		return cfg.Audit.File
+	default:
+		return filepath.Join(cfg.DataDir, FileName)
+	}
+}
+
+// OpenLog returns the audit log cfg sets, or nil when auditing is disabled.
+// With the bolt store backend it is kept in the store database, unless
+// audit.file is set.
+func OpenLog(cfg *config.Config) (*Log, error) {
+	if !cfg.Audit.Enabled {
+		return nil, nil
+	}
+	if cfg.Audit.File == "" {
+		db, err := store.OpenConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if db != nil {
+			return NewDBLog(db), nil
+		}
+	}
+	return NewLog(Path(cfg)), nil
+}
+
+// OpenConfig records the actions of this process in the audit log cfg
+// sets, or stops recording them when auditing is disabled.
+func OpenConfig(cfg *config.Config, actor string, log *logrus.Logger) error {
+	l, err := OpenLog(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	if l == nil {
+		Close()
+		return nil
+	}
+	use(l, actor, log)
+	return nil
+}
+
+// Close stops recording actions.
+func Close() {
+	mu.Lock()
+	defer mu.Unlock()
+	current = nil
+}
+
+// UserActor returns the actor of CLI commands run by the current user.
+func UserActor() string {
+	if u, err := user.Current(); err == nil {
+		return "user:" + u.Username
+	}
+	return "user:" + strconv.Itoa(os.Getuid())
+}
+
+type actorKey struct{}
+
+// WithActor returns a context whose actions are recorded as taken by actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor returns who takes the actions of ctx.
+func Actor(ctx context.Context) string {
+	if value, ok := ctx.Value(actorKey{}).(string); ok {
+		return value
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return defaultActor
+}
+
+// Begin starts recording an action, which End completes.
+func Begin(ctx context.Context, action string, containerID int) *Entry {
+	return &Entry{Time: time.Now(), Actor: Actor(ctx), Action: action, ContainerID: containerID}
+}
+
+// Param sets a parameter of the action and returns the entry.
+func (e *Entry) Param(name, value string) *Entry {
+	if e.Parameters == nil {
+		e.Parameters = make(map[string]string)
+	}
+	e.Parameters[name] = value
+	return e
+}
+
+// End records the action with its outcome, err, in the audit log of the
+// process, if one is open.
+func (e *Entry) End(err error) {
+	e.Duration = time.Since(e.Time)
+	e.Success = err == nil
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	mu.Lock()
+	log, errorLogger := current, logger
+	mu.Unlock()
+	if log == nil {
+		return
+	}
+	if err := log.Append(*e); err != nil {
+		errorLogger.WithFields(logrus.Fields{
+			"action": e.Action,
+			"error":  err,
+		}).Error("Failed to write audit log")
+	}
+}
@@ -0,0 +1,134 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+func TestEntry_End(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", FileName)
+	Open(path, ActorAuto, logrus.New())
+	defer Close()
+
+	ctx := context.Background()
+	Begin(ctx, ActionStop, 100).End(nil)
+	entry := Begin(WithActor(ctx, "user:alice"), ActionRestore, 100).Param("backup", "local:backup/vzdump-lxc-100.tar.zst")
+	entry.Node = "node2"
+	entry.End(errors.New("restore failed"))
+	Begin(ctx, ActionBackup, 101).End(nil)
+
+	// Without an open log nothing is recorded
+	Close()
+	Begin(ctx, ActionStart, 100).End(nil)
+
+	tests := []struct {
+		name    string
+		filter  Filter
+		actions []string
+	}{
+		{name: "all, newest first", filter: Filter{}, actions: []string{ActionBackup, ActionRestore, ActionStop}},
+		{name: "by container", filter: Filter{ContainerID: 100}, actions: []string{ActionRestore, ActionStop}},
+		{name: "by action", filter: Filter{Action: ActionStop}, actions: []string{ActionStop}},
+		{name: "by actor", filter: Filter{Actor: "user:alice"}, actions: []string{ActionRestore}},
+		{name: "since", filter: Filter{Since: time.Now().Add(time.Hour)}, actions: nil},
+		{name: "limit", filter: Filter{Limit: 1}, actions: []string{ActionBackup}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewLog(path).List(tt.filter)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if len(got) != len(tt.actions) {
+				t.Fatalf("List() returned %d entries, want %d", len(got), len(tt.actions))
+			}
+			for i, action := range tt.actions {
+				if got[i].Action != action {
+					t.Errorf("entry %d action = %s, want %s", i, got[i].Action, action)
+				}
+			}
+		})
+	}
+
+	restore, err := NewLog(path).List(Filter{Action: ActionRestore})
+	if err != nil || len(restore) != 1 {
+		t.Fatalf("List() = %v, %v", restore, err)
+	}
+	got := restore[0]
+	if got.Actor != "user:alice" || got.Node != "node2" || got.Success || got.Error != "restore failed" || got.Parameters["backup"] == "" {
+		t.Errorf("restore entry = %+v", got)
+	}
+}
+
+func TestPath(t *testing.T) {
+	tests := []struct {
+		name  string
+		audit config.AuditConfig
+		want  string
+	}{
+		{name: "default", audit: config.AuditConfig{Enabled: true}, want: filepath.Join("/var/lib/proxwarden", FileName)},
+		{name: "file", audit: config.AuditConfig{Enabled: true, File: "/var/log/proxwarden/audit.jsonl"}, want: "/var/log/proxwarden/audit.jsonl"},
+		{name: "disabled", audit: config.AuditConfig{File: "/var/log/proxwarden/audit.jsonl"}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{DataDir: "/var/lib/proxwarden", Audit: tt.audit}
+			if got := Path(cfg); got != tt.want {
+				t.Errorf("Path() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenLog(t *testing.T) {
+	dataDir := t.TempDir()
+	tests := []struct {
+		name   string
+		audit  config.AuditConfig
+		store  config.StoreConfig
+		nilLog bool
+		db     bool
+	}{
+		{name: "disabled", audit: config.AuditConfig{}, store: config.StoreConfig{Backend: config.StoreBolt}, nilLog: true},
+		{name: "file backend", audit: config.AuditConfig{Enabled: true}, store: config.StoreConfig{Backend: config.StoreFile}},
+		{name: "bolt backend", audit: config.AuditConfig{Enabled: true}, store: config.StoreConfig{Backend: config.StoreBolt}, db: true},
+		{name: "bolt backend with file", audit: config.AuditConfig{Enabled: true, File: filepath.Join(dataDir, "actions.jsonl")}, store: config.StoreConfig{Backend: config.StoreBolt}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, err := OpenLog(&config.Config{DataDir: dataDir, Audit: tt.audit, Store: tt.store})
+			if err != nil {
+				t.Fatalf("OpenLog() error = %v", err)
+			}
+			if (log == nil) != tt.nilLog {
+				t.Fatalf("OpenLog() = %v, want nil %v", log, tt.nilLog)
+			}
+			if log == nil {
+				return
+			}
+			if (log.db != nil) != tt.db {
+				t.Errorf("OpenLog() in the database = %v, want %v", log.db != nil, tt.db)
+			}
+
+			if err := log.Append(Entry{Action: ActionStop, ContainerID: 100}); err != nil {
+				t.Fatalf("Append() error = %v", err)
+			}
+			if err := log.Append(Entry{Action: ActionStart, ContainerID: 100}); err != nil {
+				t.Fatalf("Append() error = %v", err)
+			}
+			entries, err := log.List(Filter{Limit: 1})
+			if err != nil || len(entries) != 1 || entries[0].Action != ActionStart {
+				t.Errorf("List() = %v, %v, want the start", entries, err)
+			}
+		})
+	}
+}
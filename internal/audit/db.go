@@ -0,0 +1,32 @@
+package audit
+
+import (
+	"encoding/json"
+
+	"github.com/jbutlerdev/proxwarden/internal/store"
+)
+
+// NewDBLog returns a Log recording to db.
+func NewDBLog(db *store.DB) *Log {
+	return &Log{db: db}
+}
+
+func (l *Log) appendDB(entry Entry) error {
+	return l.db.Update(func(tx *store.Tx) error {
+		return tx.Append(store.BucketAudit, entry)
+	})
+}
+
+func (l *Log) listDB(filter Filter) ([]Entry, error) {
+	var entries []Entry
+	err := l.db.View(func(tx *store.Tx) error {
+		return tx.ForEach(store.BucketAudit, func(data []byte) error {
+			var entry Entry
+			if err := json.Unmarshal(data, &entry); err == nil && filter.match(entry) {
+				entries = append(entries, entry)
+			}
+			return nil
+		})
+	})
+	return entries, err
+}
@@ -0,0 +1,88 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/hook"
+	"github.com/sirupsen/logrus"
+)
+
+// Phases of a backup in which hooks run.
+const (
+	HookPhasePre  = "pre_backup"
+	HookPhasePost = "post_backup"
+)
+
+// Target is a backup about to be taken, as described to its hooks.
+type Target struct {
+	Container config.ContainerConfig
+	Node      string
+	Storage   string
+	// Origin is why the backup is taken, one of the catalog origins
+	Origin string
+}
+
+// WithHooks takes a backup with take, running the backup hooks of the
+// container around it, as failover hooks run around failovers: a failing
+// pre-backup hook fails the backup unless its failure policy is continue,
+// and post-backup hooks, whose failures are only logged, run after every
+// attempt, also when a pre-backup hook failed, so they can undo what the
+// others did.
+func WithHooks(ctx context.Context, cfg *config.BackupConfig, target Target, logger *logrus.Logger, take func(ctx context.Context) (string, error)) (string, error) {
+	pre, post := cfg.HooksFor(target.Container)
+	if len(pre) == 0 && len(post) == 0 {
+		return take(ctx)
+	}
+
+	entry := logger.WithFields(logrus.Fields{
+		"container_id": target.Container.ID,
+		"storage":      target.Storage,
+	})
+	env := []string{
+		fmt.Sprintf("CONTAINER_ID=%d", target.Container.ID),
+		fmt.Sprintf("CONTAINER_NAME=%s", target.Container.Name),
+		fmt.Sprintf("NODE=%s", target.Node),
+		fmt.Sprintf("BACKUP_STORAGE=%s", target.Storage),
+		fmt.Sprintf("BACKUP_ORIGIN=%s", target.Origin),
+	}
+
+	var (
+		volID string
+		err   error
+	)
+	runs, _ := hook.RunAll(ctx, pre, config.HookAbort, append(env, "PHASE="+HookPhasePre), entry.WithField("phase", HookPhasePre))
+	if err = aborted(pre, runs); err != nil {
+		err = fmt.Errorf("pre-backup hooks failed: %w", err)
+	} else {
+		volID, err = take(ctx)
+	}
+
+	status := "success"
+	if err != nil {
+		status = "failure"
+	}
+	env = append(env,
+		"PHASE="+HookPhasePost,
+		"BACKUP_STATUS="+status,
+		"BACKUP_VOLID="+volID,
+	)
+	// Run post-backup hooks even when ctx ended the backup, so applications
+	// are not left quiesced
+	postCtx := context.WithoutCancel(ctx)
+	if _, hookErr := hook.RunAll(postCtx, post, config.HookContinue, env, entry.WithField("phase", HookPhasePost)); hookErr != nil {
+		entry.WithField("error", hookErr).Warn("Post-backup hooks failed")
+	}
+	return volID, err
+}
+
+// aborted returns the error of the first failed hook not set to continue.
+func aborted(hooks []config.Hook, runs []hook.Result) error {
+	for i, run := range runs {
+		if !run.Success && hooks[i].OnFailure != config.HookContinue {
+			return run.Error
+		}
+	}
+	return nil
+}
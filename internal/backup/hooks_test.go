@@ -0,0 +1,73 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+func TestWithHooks(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	tests := []struct {
+		name      string
+		pre       string
+		onFailure string
+		takeErr   error
+		taken     bool
+		log       string
+		err       string
+	}{
+		{name: "success", pre: `echo "pre $NODE $BACKUP_ORIGIN" >> "$LOG"`, taken: true, log: "pre node1 scheduled\npost success local:backup/x\n"},
+		{name: "backup fails", pre: "true", takeErr: errors.New("storage offline"), taken: true, log: "post failure \n", err: "storage offline"},
+		{name: "pre-backup hook fails", pre: "exit 1", log: "post failure \n", err: "pre-backup hooks failed"},
+		{name: "pre-backup hook may fail", pre: "exit 1", onFailure: config.HookContinue, taken: true, log: "post success local:backup/x\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := filepath.Join(t.TempDir(), "hooks.log")
+			t.Setenv("LOG", log)
+			cfg := &config.BackupConfig{
+				PostHooks: []config.Hook{{Command: `echo "post $BACKUP_STATUS $BACKUP_VOLID" >> "$LOG"`}},
+			}
+			target := Target{
+				Container: config.ContainerConfig{ID: 100, PreBackupHooks: []config.Hook{{Command: tt.pre, OnFailure: tt.onFailure}}},
+				Node:      "node1",
+				Storage:   "local",
+				Origin:    "scheduled",
+			}
+
+			taken := false
+			volID, err := WithHooks(context.Background(), cfg, target, logger, func(ctx context.Context) (string, error) {
+				taken = true
+				if tt.takeErr != nil {
+					return "", tt.takeErr
+				}
+				return "local:backup/x", nil
+			})
+
+			if taken != tt.taken {
+				t.Errorf("Expected backup taken %v, got %v", tt.taken, taken)
+			}
+			if tt.err == "" && (err != nil || volID != "local:backup/x") {
+				t.Errorf("Expected the backup, got %q and %v", volID, err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("Expected error containing %q, got %v", tt.err, err)
+			}
+			data, _ := os.ReadFile(log)
+			if string(data) != tt.log {
+				t.Errorf("Expected hooks to log %q, got %q", tt.log, data)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package backup
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+// Limiter bounds how many backups run at once, in total and per node. A nil
+// Limiter does not limit.
+type Limiter struct {
+	// total is nil without a total limit
+	total   chan struct{}
+	perNode int
+
+	mu    sync.Mutex
+	nodes map[string]chan struct{}
+}
+
+func NewLimiter(cfg *config.BackupConfig) *Limiter {
+	l := &Limiter{perNode: cfg.MaxPerNode, nodes: make(map[string]chan struct{})}
+	if cfg.MaxConcurrent > 0 {
+		l.total = make(chan struct{}, cfg.MaxConcurrent)
+	}
+	return l
+}
+
+// Acquire waits for a slot to back up a container on node, until ctx is
+// done, and returns the function releasing it.
+func (l *Limiter) Acquire(ctx context.Context, node string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	// Wait for the node first, so waiting does not hold a slot others could use
+	nodeSlots := l.node(node)
+	if nodeSlots != nil {
+		select {
+		case nodeSlots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if l.total != nil {
+		select {
+		case l.total <- struct{}{}:
+		case <-ctx.Done():
+			if nodeSlots != nil {
+				<-nodeSlots
+			}
+			return nil, ctx.Err()
+		}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if l.total != nil {
+				<-l.total
+			}
+			if nodeSlots != nil {
+				<-nodeSlots
+			}
+		})
+	}, nil
+}
+
+// node returns the slots of a node, nil without a per-node limit.
+func (l *Limiter) node(name string) chan struct{} {
+	if l.perNode <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	slots, exists := l.nodes[name]
+	if !exists {
+		slots = make(chan struct{}, l.perNode)
+		l.nodes[name] = slots
+	}
+	return slots
+}
@@ -0,0 +1,53 @@
+package backup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+func TestLimiter_Acquire(t *testing.T) {
+	limiter := NewLimiter(&config.BackupConfig{MaxConcurrent: 2, MaxPerNode: 1})
+
+	acquire := func(node string) (func(), error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		return limiter.Acquire(ctx, node)
+	}
+
+	releaseFirst, err := acquire("node1")
+	if err != nil {
+		t.Fatalf("Expected a free slot, got %v", err)
+	}
+	if _, err := acquire("node1"); err == nil {
+		t.Error("Expected a second backup on node1 to wait")
+	}
+	releaseSecond, err := acquire("node2")
+	if err != nil {
+		t.Fatalf("Expected a slot on node2, got %v", err)
+	}
+	if _, err := acquire("node3"); err == nil {
+		t.Error("Expected a third backup to wait for the total limit")
+	}
+
+	releaseFirst()
+	releaseFirst()
+	release, err := acquire("node1")
+	if err != nil {
+		t.Fatalf("Expected node1 to be free again, got %v", err)
+	}
+	if _, err := acquire("node3"); err == nil {
+		t.Error("Expected releasing twice to free a single slot")
+	}
+	release()
+	releaseSecond()
+
+	var unlimited *Limiter
+	for i := 0; i < 3; i++ {
+		if _, err := unlimited.Acquire(context.Background(), "node1"); err != nil {
+			t.Errorf("Expected a nil limiter not to limit, got %v", err)
+		}
+	}
+}
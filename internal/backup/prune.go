@@ -0,0 +1,206 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/catalog"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// Store lists and deletes backups.
+type Store interface {
+	GetBackups(ctx context.Context, storage string) ([]api.BackupInfo, error)
+	DeleteBackup(ctx context.Context, nodeName, storage, backupPath string) error
+}
+
+// Policy decides which backups of a container are kept.
+type Policy struct {
+	// RetentionDays keeps backups younger than this many days
+	RetentionDays int
+	// KeepLast keeps the newest backups regardless of their age
+	KeepLast int
+}
+
+// expired returns the backups of a single container that fall outside the
+// policy, oldest first. A backup is kept while it is among the KeepLast
+// newest or younger than RetentionDays, and the newest backup is always
+// kept, so a container is never left without one. Protected backups are
+// always kept and do not count towards KeepLast. Without either limit
+// nothing expires.
+func (p Policy) expired(backups []api.BackupInfo, now time.Time) []api.BackupInfo {
+	if p.RetentionDays <= 0 && p.KeepLast <= 0 {
+		return nil
+	}
+
+	var sorted []api.BackupInfo
+	for _, backup := range backups {
+		if !backup.Protected {
+			sorted = append(sorted, backup)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Created.After(sorted[j].Created)
+	})
+
+	keep := p.KeepLast
+	if keep < 1 {
+		keep = 1
+	}
+	cutoff := now.AddDate(0, 0, -p.RetentionDays)
+
+	var result []api.BackupInfo
+	for i := len(sorted) - 1; i >= keep; i-- {
+		if p.RetentionDays > 0 && sorted[i].Created.After(cutoff) {
+			continue
+		}
+		result = append(result, sorted[i])
+	}
+	return result
+}
+
+// Pruner deletes backups of monitored containers that fall outside the
+// retention. Backups of other guests on the same storage are left alone.
+type Pruner struct {
+	// current is the configuration, replaced as a whole on reload
+	current atomic.Pointer[config.Config]
+	store   Store
+	catalog *catalog.Store
+	logger  *logrus.Logger
+}
+
+func NewPruner(cfg *config.Config, store Store, logger *logrus.Logger) *Pruner {
+	p := &Pruner{store: store, logger: logger}
+	p.current.Store(cfg)
+	return p
+}
+
+// Reload switches the pruner to a changed configuration, from the next
+// pruning on.
+func (p *Pruner) Reload(cfg *config.Config) {
+	p.current.Store(cfg)
+}
+
+// SetCatalog sets the catalog pruned backups are removed from.
+func (p *Pruner) SetCatalog(store *catalog.Store) {
+	p.catalog = store
+}
+
+// Enabled reports whether the daemon prunes backups.
+func (p *Pruner) Enabled() bool {
+	return p.current.Load().Backup.PruneInterval > 0
+}
+
+// Prune deletes the expired backups of every monitored container and
+// returns them. With dryRun it only returns them. Deletion continues past
+// failures, which are returned together.
+func (p *Pruner) Prune(ctx context.Context, dryRun bool) ([]api.BackupInfo, error) {
+	cfg := p.current.Load()
+	policy := Policy{RetentionDays: cfg.Backup.RetentionDays, KeepLast: cfg.Backup.KeepLast}
+	now := time.Now()
+
+	order, storages := containersByStorage(cfg)
+	// Copies are kept as long as the backups
+	if replica := cfg.Backup.Replication.Storage; replica != "" {
+		if _, exists := storages[replica]; !exists {
+			order = append(order, replica)
+		}
+		storages[replica] = nil
+		for _, container := range cfg.Monitoring.Containers {
+			storages[replica] = append(storages[replica], container.ID)
+		}
+	}
+
+	// Pinned backups are to be restored
+	entries, err := p.catalog.List(catalog.Filter{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pinned backups: %w", err)
+	}
+	pinned := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.Pinned {
+			pinned[entry.VolID] = true
+		}
+	}
+
+	var (
+		pruned []api.BackupInfo
+		errs   []error
+	)
+	for _, storage := range order {
+		backups, err := p.store.GetBackups(ctx, storage)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("storage %s: %w", storage, err))
+			continue
+		}
+
+		byContainer := make(map[int][]api.BackupInfo)
+		for _, backup := range backups {
+			if pinned[backup.VolID] {
+				continue
+			}
+			byContainer[backup.ContainerID] = append(byContainer[backup.ContainerID], backup)
+		}
+
+		for _, containerID := range storages[storage] {
+			for _, backup := range policy.expired(byContainer[containerID], now) {
+				logger := p.logger.WithFields(logrus.Fields{
+					"container_id": containerID,
+					"backup":       backup.VolID,
+					"created":      backup.Created,
+				})
+				if dryRun {
+					logger.Info("Backup would be pruned")
+					pruned = append(pruned, backup)
+					continue
+				}
+
+				if err := p.store.DeleteBackup(ctx, backup.Node, storage, backup.VolID); err != nil {
+					logger.WithField("error", err).Error("Failed to prune backup")
+					errs = append(errs, err)
+					continue
+				}
+				logger.Info("Pruned backup")
+				if err := p.catalog.Remove(backup.VolID); err != nil {
+					logger.WithField("error", err).Warn("Failed to remove backup from catalog")
+				}
+				pruned = append(pruned, backup)
+			}
+		}
+	}
+	return pruned, errors.Join(errs...)
+}
+
+// Start prunes every prune interval until ctx is cancelled. A reloaded
+// interval applies from the next pruning; one of zero pauses pruning.
+func (p *Pruner) Start(ctx context.Context) error {
+	interval := p.current.Load().Backup.PruneInterval
+	p.logger.WithField("interval", interval).Info("Starting backup pruning")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if reloaded := p.current.Load().Backup.PruneInterval; reloaded != interval && reloaded > 0 {
+			interval = reloaded
+			ticker.Reset(interval)
+		}
+		if p.Enabled() {
+			if _, err := p.Prune(ctx, false); err != nil && ctx.Err() == nil {
+				p.logger.WithField("error", err).Error("Backup pruning incomplete")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
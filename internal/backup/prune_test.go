@@ -0,0 +1,180 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/catalog"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeStore struct {
+	backups map[string][]api.BackupInfo
+	deleted []string
+	err     error
+}
+
+func (f *fakeStore) GetBackups(ctx context.Context, storage string) ([]api.BackupInfo, error) {
+	return f.backups[storage], nil
+}
+
+func (f *fakeStore) DeleteBackup(ctx context.Context, nodeName, storage, backupPath string) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.deleted = append(f.deleted, backupPath)
+	return nil
+}
+
+func backupAged(containerID int, volID string, days int, now time.Time) api.BackupInfo {
+	return api.BackupInfo{
+		ContainerID: containerID,
+		VolID:       volID,
+		Node:        "node1",
+		Created:     now.AddDate(0, 0, -days),
+	}
+}
+
+func volIDs(backups []api.BackupInfo) []string {
+	var result []string
+	for _, backup := range backups {
+		result = append(result, backup.VolID)
+	}
+	return result
+}
+
+func TestPolicy_Expired(t *testing.T) {
+	now := time.Now()
+	protected := backupAged(100, "b30", 30, now)
+	protected.Protected = true
+	backups := []api.BackupInfo{
+		backupAged(100, "b2", 2, now),
+		backupAged(100, "b10", 10, now),
+		backupAged(100, "b1", 1, now),
+		backupAged(100, "b20", 20, now),
+	}
+
+	tests := []struct {
+		name     string
+		policy   Policy
+		backups  []api.BackupInfo
+		expected []string
+	}{
+		{name: "no retention", policy: Policy{}, backups: backups, expected: nil},
+		{name: "retention days", policy: Policy{RetentionDays: 7}, backups: backups, expected: []string{"b20", "b10"}},
+		{name: "keep last", policy: Policy{KeepLast: 3}, backups: backups, expected: []string{"b20"}},
+		{name: "keep last beyond retention", policy: Policy{RetentionDays: 7, KeepLast: 3}, backups: backups, expected: []string{"b20"}},
+		{name: "only backup is kept", policy: Policy{RetentionDays: 7}, backups: []api.BackupInfo{backupAged(100, "b20", 20, now)}, expected: nil},
+		{name: "newest is kept when all expired", policy: Policy{RetentionDays: 7}, backups: []api.BackupInfo{backups[1], backups[3]}, expected: []string{"b20"}},
+		{name: "protected is kept", policy: Policy{KeepLast: 3}, backups: append([]api.BackupInfo{protected}, backups...), expected: []string{"b20"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := volIDs(tt.policy.expired(tt.backups, now))
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPruner_Prune(t *testing.T) {
+	now := time.Now()
+	cfg := &config.Config{
+		Backup: config.BackupConfig{Storage: "local", RetentionDays: 7},
+		Monitoring: config.MonitoringConfig{
+			Containers: []config.ContainerConfig{
+				{ID: 100},
+				{ID: 101, BackupStorage: "nfs"},
+			},
+		},
+	}
+	store := &fakeStore{backups: map[string][]api.BackupInfo{
+		"local": {
+			backupAged(100, "local:100-new", 1, now),
+			backupAged(100, "local:100-old", 10, now),
+			backupAged(200, "local:200-old", 10, now),
+			backupAged(200, "local:200-older", 20, now),
+		},
+		"nfs": {
+			backupAged(101, "nfs:101-old", 10, now),
+			backupAged(101, "nfs:101-older", 20, now),
+		},
+	}}
+	pruner := NewPruner(cfg, store, logrus.New())
+
+	pruned, err := pruner.Prune(context.Background(), true)
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	expected := []string{"local:100-old", "nfs:101-older"}
+	if got := volIDs(pruned); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected dry run to prune %v, got %v", expected, got)
+	}
+	if len(store.deleted) != 0 {
+		t.Errorf("Expected dry run not to delete, deleted %v", store.deleted)
+	}
+
+	if _, err := pruner.Prune(context.Background(), false); err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if !reflect.DeepEqual(store.deleted, expected) {
+		t.Errorf("Expected %v deleted, got %v", expected, store.deleted)
+	}
+
+	store.deleted = nil
+	store.err = errors.New("storage offline")
+	pruned, err = pruner.Prune(context.Background(), false)
+	if err == nil {
+		t.Error("Expected failed deletions to be returned")
+	}
+	if len(pruned) != 0 {
+		t.Errorf("Expected nothing pruned, got %v", volIDs(pruned))
+	}
+
+	// A pinned backup is kept
+	store.err = nil
+	backups := catalog.NewStore(filepath.Join(t.TempDir(), catalog.FileName))
+	if _, err := backups.Sync("nfs", store.backups["nfs"]); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if err := backups.Pin(101, "nfs:101-older"); err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+	pruner.SetCatalog(backups)
+	pruned, err = pruner.Prune(context.Background(), true)
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if got := volIDs(pruned); !reflect.DeepEqual(got, []string{"local:100-old"}) {
+		t.Errorf("Expected the pinned backup to be kept, pruned %v", got)
+	}
+
+	// Reloaded retention and containers apply to the next pruning
+	reloaded := *cfg
+	reloaded.Backup.RetentionDays = 30
+	reloaded.Monitoring.Containers = []config.ContainerConfig{{ID: 100}, {ID: 200}}
+	pruner.Reload(&reloaded)
+	pruned, err = pruner.Prune(context.Background(), true)
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if got := volIDs(pruned); len(got) != 0 {
+		t.Errorf("Expected nothing pruned with 30 days of retention, got %v", got)
+	}
+	reloaded.Backup.RetentionDays = 7
+	pruned, err = pruner.Prune(context.Background(), true)
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if got := volIDs(pruned); !reflect.DeepEqual(got, []string{"local:100-old", "local:200-older"}) {
+		t.Errorf("Expected the reloaded containers pruned, got %v", got)
+	}
+}
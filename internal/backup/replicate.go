@@ -0,0 +1,210 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/hook"
+	"github.com/jbutlerdev/proxwarden/internal/notify"
+	"github.com/sirupsen/logrus"
+)
+
+// ReplicaStore lists backups and resolves where they are stored.
+type ReplicaStore interface {
+	GetBackups(ctx context.Context, storage string) ([]api.BackupInfo, error)
+	GetBackupPath(ctx context.Context, nodeName, storage, backupPath string) (string, error)
+}
+
+// Replicator copies the latest backup of every monitored container to the
+// replication storage with the configured command, unless the storage
+// already holds it.
+type Replicator struct {
+	config   *config.Config
+	store    ReplicaStore
+	notifier *notify.Dispatcher
+	logger   *logrus.Logger
+}
+
+func NewReplicator(cfg *config.Config, store ReplicaStore, notifier *notify.Dispatcher, logger *logrus.Logger) *Replicator {
+	return &Replicator{config: cfg, store: store, notifier: notifier, logger: logger}
+}
+
+// Enabled reports whether the daemon copies backups.
+func (r *Replicator) Enabled() bool {
+	replication := r.config.Backup.Replication
+	return replication.Storage != "" && replication.Command.Command != ""
+}
+
+// Start replicates new backups every replication interval until ctx is
+// cancelled.
+func (r *Replicator) Start(ctx context.Context) error {
+	interval := r.config.Backup.Replication.Interval
+	r.logger.WithFields(logrus.Fields{
+		"storage":  r.config.Backup.Replication.Storage,
+		"interval": interval,
+	}).Info("Starting backup replication")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Replicate(ctx); err != nil && ctx.Err() == nil {
+			r.logger.WithField("error", err).Error("Backup replication incomplete")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Replicate copies the latest backups missing from the replication storage
+// and returns them. Failed copies are alerted on and returned together.
+func (r *Replicator) Replicate(ctx context.Context) ([]api.BackupInfo, error) {
+	replica := r.config.Backup.Replication.Storage
+	copies, err := r.store.GetBackups(ctx, replica)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list replication storage %s: %w", replica, err)
+	}
+
+	order, storages := containersByStorage(r.config)
+	var (
+		replicated []api.BackupInfo
+		errs       []error
+	)
+	for _, storage := range order {
+		if storage == replica {
+			continue
+		}
+		backups, err := r.store.GetBackups(ctx, storage)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("storage %s: %w", storage, err))
+			continue
+		}
+
+		for _, containerID := range storages[storage] {
+			latest, found := latestOf(backups, containerID)
+			if !found || hasCopy(copies, latest) {
+				continue
+			}
+			if err := r.replicate(ctx, storage, latest); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			replicated = append(replicated, latest)
+		}
+	}
+	return replicated, errors.Join(errs...)
+}
+
+// replicate runs the copy command for a backup and checks the copy arrived.
+func (r *Replicator) replicate(ctx context.Context, storage string, backup api.BackupInfo) error {
+	replica := r.config.Backup.Replication.Storage
+	logger := r.logger.WithFields(logrus.Fields{
+		"container_id": backup.ContainerID,
+		"backup":       backup.VolID,
+		"storage":      replica,
+	})
+	logger.Info("Replicating backup")
+
+	err := r.copy(ctx, storage, backup)
+	if err == nil {
+		var copies []api.BackupInfo
+		copies, err = r.store.GetBackups(ctx, replica)
+		if err == nil && !hasCopy(copies, backup) {
+			err = fmt.Errorf("copy command succeeded but storage %s holds no copy", replica)
+		}
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to replicate backup %s to %s: %w", backup.VolID, replica, err)
+		logger.WithField("error", err).Error("Backup replication failed")
+		r.notifier.Send(notify.Event{
+			Type:        notify.EventReplicationFailed,
+			Severity:    notify.SeverityWarning,
+			ContainerID: backup.ContainerID,
+			Node:        backup.Node,
+			Message:     fmt.Sprintf("Replicating backup of container %d failed: %v", backup.ContainerID, err),
+			Details:     map[string]string{"backup": backup.VolID, "storage": replica},
+		})
+		return err
+	}
+
+	logger.Info("Backup replicated")
+	return nil
+}
+
+// copy runs the copy command with the backup described in its environment.
+func (r *Replicator) copy(ctx context.Context, storage string, backup api.BackupInfo) error {
+	path, err := r.store.GetBackupPath(ctx, backup.Node, storage, backup.VolID)
+	if err != nil {
+		return err
+	}
+
+	env := []string{
+		fmt.Sprintf("CONTAINER_ID=%d", backup.ContainerID),
+		fmt.Sprintf("BACKUP_NODE=%s", backup.Node),
+		fmt.Sprintf("BACKUP_STORAGE=%s", storage),
+		fmt.Sprintf("BACKUP_VOLID=%s", backup.VolID),
+		fmt.Sprintf("BACKUP_FILENAME=%s", backup.Filename),
+		fmt.Sprintf("BACKUP_PATH=%s", path),
+		fmt.Sprintf("TARGET_STORAGE=%s", r.config.Backup.Replication.Storage),
+	}
+	logger := r.logger.WithField("container_id", backup.ContainerID)
+	if run := hook.Run(ctx, r.config.Backup.Replication.Command, env, logger); !run.Success {
+		return fmt.Errorf("copy command failed: %w: %s", run.Error, run.Output)
+	}
+	return nil
+}
+
+// containersByStorage groups the monitored containers by the storage their
+// backups go to, in the order the storages are first used.
+func containersByStorage(cfg *config.Config) ([]string, map[string][]int) {
+	storages := make(map[string][]int)
+	var order []string
+	for _, container := range cfg.Monitoring.Containers {
+		storage := container.BackupStorage
+		if storage == "" {
+			storage = cfg.Backup.Storage
+		}
+		if _, exists := storages[storage]; !exists {
+			order = append(order, storage)
+		}
+		storages[storage] = append(storages[storage], container.ID)
+	}
+	return order, storages
+}
+
+// latestOf returns the newest backup of a container.
+func latestOf(backups []api.BackupInfo, containerID int) (api.BackupInfo, bool) {
+	var (
+		latest api.BackupInfo
+		found  bool
+	)
+	for _, backup := range backups {
+		if backup.ContainerID != containerID {
+			continue
+		}
+		if !found || backup.Created.After(latest.Created) {
+			latest, found = backup, true
+		}
+	}
+	return latest, found
+}
+
+// hasCopy reports whether copies hold the backup or a newer one of the same
+// container. Backups are matched on their creation time, which vzdump
+// archives carry in their names and PBS snapshots in their IDs.
+func hasCopy(copies []api.BackupInfo, backup api.BackupInfo) bool {
+	for _, c := range copies {
+		if c.ContainerID == backup.ContainerID && !c.Created.Before(backup.Created) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package backup
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeReplicaStore struct {
+	backups map[string][]api.BackupInfo
+}
+
+func (f *fakeReplicaStore) GetBackups(ctx context.Context, storage string) ([]api.BackupInfo, error) {
+	return f.backups[storage], nil
+}
+
+func (f *fakeReplicaStore) GetBackupPath(ctx context.Context, nodeName, storage, backupPath string) (string, error) {
+	return "/var/lib/vz/dump/" + backupPath, nil
+}
+
+func TestReplicator_Replicate(t *testing.T) {
+	now := time.Now()
+	store := &fakeReplicaStore{backups: map[string][]api.BackupInfo{
+		"local": {
+			backupAged(100, "local:100-new", 1, now),
+			backupAged(100, "local:100-old", 10, now),
+			backupAged(101, "local:101", 2, now),
+		},
+		"offsite": {
+			backupAged(101, "offsite:101", 2, now),
+		},
+	}}
+	cfg := &config.Config{
+		Backup: config.BackupConfig{
+			Storage: "local",
+			Replication: config.ReplicationConfig{
+				Storage: "offsite",
+				Command: config.Hook{Command: `test "$BACKUP_PATH" = /var/lib/vz/dump/local:100-new`},
+			},
+		},
+		Monitoring: config.MonitoringConfig{
+			Containers: []config.ContainerConfig{{ID: 100}, {ID: 101}},
+		},
+	}
+	replicator := NewReplicator(cfg, store, nil, logrus.New())
+
+	// The command succeeds, but the copy never arrives
+	replicated, err := replicator.Replicate(context.Background())
+	if err == nil {
+		t.Error("Expected a missing copy to fail")
+	}
+	if len(replicated) != 0 {
+		t.Errorf("Expected nothing replicated, got %v", volIDs(replicated))
+	}
+
+	// Copied by someone else
+	store.backups["offsite"] = append(store.backups["offsite"], backupAged(100, "offsite:100-new", 1, now))
+	replicated, err = replicator.Replicate(context.Background())
+	if err != nil {
+		t.Fatalf("Replicate failed: %v", err)
+	}
+	if len(replicated) != 0 {
+		t.Errorf("Expected copies to be up to date, replicated %v", volIDs(replicated))
+	}
+
+	cfg.Backup.Replication.Command.Command = "exit 1"
+	store.backups["local"] = append(store.backups["local"], backupAged(101, "local:101-newer", 0, now.Add(time.Hour)))
+	if _, err := replicator.Replicate(context.Background()); err == nil {
+		t.Error("Expected a failing copy command to fail")
+	}
+}
+
+func TestLatestOf(t *testing.T) {
+	now := time.Now()
+	backups := []api.BackupInfo{
+		backupAged(100, "old", 3, now),
+		backupAged(100, "new", 1, now),
+		backupAged(101, "other", 0, now),
+	}
+
+	latest, found := latestOf(backups, 100)
+	if !found || latest.VolID != "new" {
+		t.Errorf("Expected latest backup new, got %q", latest.VolID)
+	}
+	if _, found := latestOf(backups, 102); found {
+		t.Error("Expected no backup of container 102")
+	}
+	if !hasCopy(backups[1:2], backups[0]) || hasCopy(backups[:1], backups[1]) {
+		t.Error("Expected a newer copy to count and an older one not to")
+	}
+}
+
+func TestContainersByStorage(t *testing.T) {
+	order, storages := containersByStorage(testConfig())
+	if !reflect.DeepEqual(order, []string{"local", "nfs"}) {
+		t.Errorf("Expected storages [local nfs], got %v", order)
+	}
+	if !reflect.DeepEqual(storages["local"], []int{100, 102}) {
+		t.Errorf("Expected containers [100 102] on local, got %v", storages["local"])
+	}
+}
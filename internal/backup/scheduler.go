@@ -0,0 +1,623 @@
+// Package backup takes scheduled vzdump backups of monitored containers and
+// prunes and replicates them, so backup-restore failovers always have a
+// recent archive to restore.
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/catalog"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/notify"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+)
+
+// Client creates backups.
+type Client interface {
+	GetContainer(ctx context.Context, containerID int) (*api.ContainerInfo, error)
+	BackupContainer(ctx context.Context, containerID int, storage, backupDir string, options config.VzdumpOptions) (string, error)
+}
+
+// ScheduleFile is the name of the file within the data directory that
+// keeps backup schedules added and removed at runtime.
+const ScheduleFile = "backup-schedules.json"
+
+var (
+	// ErrNotMonitored is returned for containers the daemon does not
+	// monitor, which cannot be scheduled.
+	ErrNotMonitored = errors.New("container is not monitored")
+	// ErrNoSchedule is returned for containers without a backup schedule.
+	ErrNoSchedule = errors.New("container has no backup schedule")
+	// ErrBackupRunning is returned when a backup of the container is
+	// already running.
+	ErrBackupRunning = errors.New("a backup of the container is already running")
+	// ErrNotRunning is returned while the scheduler does not run, as on a
+	// standby daemon.
+	ErrNotRunning = errors.New("backup scheduler is not running")
+)
+
+// job is the backup schedule of one container.
+type job struct {
+	container config.ContainerConfig
+	spec      string
+	schedule  cron.Schedule
+	next      time.Time
+}
+
+// Job is the backup schedule of a container as the scheduler reports it.
+type Job struct {
+	ContainerID   int       `json:"container_id"`
+	ContainerName string    `json:"container_name,omitempty"`
+	Schedule      string    `json:"schedule"`
+	Storage       string    `json:"storage"`
+	Next          time.Time `json:"next"`
+	Running       bool      `json:"running,omitempty"`
+	// Configured jobs come from the config file rather than being added
+	// at runtime
+	Configured bool `json:"configured,omitempty"`
+}
+
+// ContainerLookup returns the configuration of a monitored container that is
+// not in the config file, such as a discovered one.
+type ContainerLookup func(containerID int) (config.ContainerConfig, bool)
+
+// override is a schedule set at runtime, replacing the configured one of a
+// container; config.BackupScheduleOff removes it.
+type override struct {
+	ContainerID int    `json:"container_id"`
+	Schedule    string `json:"schedule"`
+}
+
+// Scheduler backs up containers on their schedules, as many at once as
+// its limiter allows. Schedules can be added and removed while it runs.
+type Scheduler struct {
+	config     *config.BackupConfig
+	containers []config.ContainerConfig
+	client     Client
+	notifier   *notify.Dispatcher
+	catalog    *catalog.Store
+	limiter    *Limiter
+	lookup     ContainerLookup
+	logger     *logrus.Logger
+
+	mu        sync.Mutex
+	jobs      []*job
+	overrides map[int]string
+	running   map[int]bool
+	path      string
+	ctx       context.Context
+	wake      chan struct{}
+}
+
+// NewScheduler schedules the monitored containers with a backup schedule,
+// their own or the global one.
+func NewScheduler(cfg *config.Config, client Client, notifier *notify.Dispatcher, logger *logrus.Logger) (*Scheduler, error) {
+	s := &Scheduler{
+		config:     &cfg.Backup,
+		containers: cfg.Monitoring.Containers,
+		client:     client,
+		notifier:   notifier,
+		limiter:    NewLimiter(&cfg.Backup),
+		logger:     logger,
+		overrides:  make(map[int]string),
+		running:    make(map[int]bool),
+		wake:       make(chan struct{}, 1),
+	}
+
+	now := time.Now()
+	for _, container := range cfg.Monitoring.Containers {
+		spec := cfg.Backup.ScheduleFor(container)
+		if spec == "" {
+			continue
+		}
+		schedule, err := cron.ParseStandard(spec)
+		if err != nil {
+			return nil, fmt.Errorf("container %d: invalid backup schedule %q: %w", container.ID, spec, err)
+		}
+		s.jobs = append(s.jobs, &job{container: container, spec: spec, schedule: schedule, next: schedule.Next(now)})
+	}
+	return s, nil
+}
+
+// SetCatalog sets the catalog backups are recorded to.
+func (s *Scheduler) SetCatalog(store *catalog.Store) {
+	s.catalog = store
+}
+
+// SetLimiter sets the limiter scheduled backups share with other backups.
+func (s *Scheduler) SetLimiter(limiter *Limiter) {
+	s.limiter = limiter
+}
+
+// SetContainerLookup sets where containers missing from the config file are
+// looked up, so schedules can be added for discovered containers too.
+func (s *Scheduler) SetContainerLookup(lookup ContainerLookup) {
+	s.lookup = lookup
+}
+
+// Reload applies the backup schedules of the containers of a changed
+// configuration. Schedules added or removed at runtime still override them,
+// except for containers no longer monitored, and backups whose schedule did
+// not change keep their next run. The global backup settings only change
+// on restart.
+func (s *Scheduler) Reload(cfg *config.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := make(map[int]*job, len(s.jobs))
+	for _, j := range s.jobs {
+		previous[j.container.ID] = j
+	}
+	s.containers = cfg.Monitoring.Containers
+	s.jobs = nil
+
+	now := time.Now()
+	configured := make(map[int]bool, len(s.containers))
+	for _, container := range s.containers {
+		configured[container.ID] = true
+		s.reschedule(container, s.config.ScheduleFor(container), previous[container.ID], now)
+	}
+	for containerID := range s.overrides {
+		if configured[containerID] {
+			continue
+		}
+		container, ok := s.container(containerID)
+		if !ok {
+			delete(s.overrides, containerID)
+			continue
+		}
+		s.reschedule(container, "", previous[containerID], now)
+	}
+
+	if err := s.save(); err != nil {
+		s.logger.WithField("error", err).Warn("Failed to save backup schedules")
+	}
+	s.signal()
+}
+
+// reschedule schedules a container with its override, if any, or else spec,
+// keeping the next run of its previous job when the schedule is the same.
+// Overrides that now match spec are dropped. Callers must hold mu.
+func (s *Scheduler) reschedule(container config.ContainerConfig, spec string, previous *job, now time.Time) {
+	if override, ok := s.overrides[container.ID]; ok {
+		if override == spec || (override == config.BackupScheduleOff && spec == "") {
+			delete(s.overrides, container.ID)
+		} else {
+			spec = override
+		}
+	}
+	if spec == "" || spec == config.BackupScheduleOff {
+		return
+	}
+
+	if previous != nil && previous.spec == spec {
+		s.jobs = append(s.jobs, &job{container: container, spec: spec, schedule: previous.schedule, next: previous.next})
+		return
+	}
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"container_id": container.ID,
+			"error":        err,
+		}).Warn("Ignoring invalid backup schedule")
+		return
+	}
+	s.jobs = append(s.jobs, &job{container: container, spec: spec, schedule: schedule, next: schedule.Next(now)})
+}
+
+// Persist applies the schedules added and removed at runtime saved to
+// path, and saves later changes there, so they survive daemon restarts.
+// Saved schedules of containers no longer monitored are dropped.
+func (s *Scheduler) Persist(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read backup schedules: %w", err)
+	}
+	var overrides []override
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &overrides); err != nil {
+			return fmt.Errorf("failed to decode backup schedules: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.path = path
+	now := time.Now()
+	for _, o := range overrides {
+		container, ok := s.container(o.ContainerID)
+		if !ok {
+			continue
+		}
+		if err := s.set(container, o.Schedule, now); err != nil {
+			s.logger.WithFields(logrus.Fields{
+				"container_id": o.ContainerID,
+				"error":        err,
+			}).Warn("Ignoring saved backup schedule")
+		}
+	}
+	return nil
+}
+
+// Enabled reports whether any container is backed up on a schedule.
+func (s *Scheduler) Enabled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.jobs) > 0
+}
+
+// Jobs returns the backup schedules, the next due first.
+func (s *Scheduler) Jobs() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, s.report(j))
+	}
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].Next.Before(jobs[j].Next)
+	})
+	return jobs
+}
+
+// Add schedules backups of a monitored container, replacing its schedule
+// if it has one.
+func (s *Scheduler) Add(containerID int, spec string) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	container, ok := s.container(containerID)
+	if !ok {
+		return Job{}, ErrNotMonitored
+	}
+	if spec == "" || spec == config.BackupScheduleOff {
+		return Job{}, fmt.Errorf("a backup schedule is required")
+	}
+	if err := s.set(container, spec, time.Now()); err != nil {
+		return Job{}, err
+	}
+	if err := s.save(); err != nil {
+		return Job{}, err
+	}
+	s.signal()
+	j, _ := s.job(containerID)
+	return s.report(j), nil
+}
+
+// Remove stops scheduled backups of a container, including those the config
+// file schedules, and reports whether it had a schedule.
+func (s *Scheduler) Remove(containerID int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	container, ok := s.container(containerID)
+	if !ok {
+		return false, nil
+	}
+	if _, ok := s.job(containerID); !ok {
+		return false, nil
+	}
+	if err := s.set(container, config.BackupScheduleOff, time.Now()); err != nil {
+		return false, err
+	}
+	if err := s.save(); err != nil {
+		return false, err
+	}
+	s.signal()
+	return true, nil
+}
+
+// RunNow starts a backup of a scheduled container right away, without
+// waiting for it. The schedule is not moved.
+func (s *Scheduler) RunNow(containerID int) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ctx == nil || s.ctx.Err() != nil {
+		return Job{}, ErrNotRunning
+	}
+	j, ok := s.job(containerID)
+	if !ok {
+		return Job{}, ErrNoSchedule
+	}
+	if s.running[containerID] {
+		return Job{}, ErrBackupRunning
+	}
+	s.running[containerID] = true
+	go s.run(s.ctx, j.container)
+	return s.report(j), nil
+}
+
+// Start runs scheduled backups until ctx is cancelled. Backups due together
+// run concurrently within the limits; those that come due while others run
+// are taken once all of them are done.
+func (s *Scheduler) Start(ctx context.Context) error {
+	s.mu.Lock()
+	s.ctx = ctx
+	jobs := len(s.jobs)
+	s.mu.Unlock()
+	s.logger.WithField("containers", jobs).Info("Starting backup scheduler")
+
+	for {
+		s.mu.Lock()
+		next := s.nextRun()
+		s.mu.Unlock()
+
+		// Without schedules the scheduler waits for one to be added
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if !next.IsZero() {
+			timer = time.NewTimer(time.Until(next))
+			timeout = timer.C
+		}
+		select {
+		case <-ctx.Done():
+			stopTimer(timer)
+			return nil
+		case <-s.wake:
+			stopTimer(timer)
+			continue
+		case <-timeout:
+		}
+
+		s.mu.Lock()
+		var due []config.ContainerConfig
+		for _, j := range s.due(time.Now()) {
+			// A backup started by hand counts as this run
+			if s.running[j.container.ID] {
+				continue
+			}
+			s.running[j.container.ID] = true
+			due = append(due, j.container)
+		}
+		s.mu.Unlock()
+
+		var wg sync.WaitGroup
+		for _, container := range due {
+			wg.Add(1)
+			go func(container config.ContainerConfig) {
+				defer wg.Done()
+				s.run(ctx, container)
+			}(container)
+		}
+		wg.Wait()
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+func stopTimer(timer *time.Timer) {
+	if timer != nil {
+		timer.Stop()
+	}
+}
+
+// run backs up a container marked as running, and clears the mark.
+func (s *Scheduler) run(ctx context.Context, container config.ContainerConfig) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, container.ID)
+		s.mu.Unlock()
+	}()
+	s.backup(ctx, container)
+}
+
+// nextRun returns when the next backup is due, or the zero time without
+// any schedule. Callers must hold mu.
+func (s *Scheduler) nextRun() time.Time {
+	if len(s.jobs) == 0 {
+		return time.Time{}
+	}
+	next := s.jobs[0].next
+	for _, j := range s.jobs[1:] {
+		if j.next.Before(next) {
+			next = j.next
+		}
+	}
+	return next
+}
+
+// due returns the jobs due at now, in schedule order, and moves them to
+// their next run. A job missing several runs is taken once. Callers must
+// hold mu.
+func (s *Scheduler) due(now time.Time) []*job {
+	var result []*job
+	for _, j := range s.jobs {
+		if j.next.After(now) {
+			continue
+		}
+		result = append(result, j)
+		j.next = j.schedule.Next(now)
+	}
+	return result
+}
+
+// set gives a container a schedule, or removes its schedule with
+// config.BackupScheduleOff, and records it as an override of the configured
+// one. Callers must hold mu.
+func (s *Scheduler) set(container config.ContainerConfig, spec string, now time.Time) error {
+	var schedule cron.Schedule
+	if spec != config.BackupScheduleOff {
+		var err error
+		if schedule, err = cron.ParseStandard(spec); err != nil {
+			return fmt.Errorf("invalid backup schedule %q: %w", spec, err)
+		}
+	}
+
+	jobs := s.jobs[:0]
+	for _, j := range s.jobs {
+		if j.container.ID != container.ID {
+			jobs = append(jobs, j)
+		}
+	}
+	s.jobs = jobs
+	if schedule != nil {
+		s.jobs = append(s.jobs, &job{container: container, spec: spec, schedule: schedule, next: schedule.Next(now)})
+	}
+
+	// Overrides that match the config file are not kept
+	configured := s.config.ScheduleFor(container)
+	if spec == configured || (spec == config.BackupScheduleOff && configured == "") {
+		delete(s.overrides, container.ID)
+	} else {
+		s.overrides[container.ID] = spec
+	}
+	return nil
+}
+
+// save writes the overrides to disk. Callers must hold mu.
+func (s *Scheduler) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	overrides := make([]override, 0, len(s.overrides))
+	for containerID, spec := range s.overrides {
+		overrides = append(overrides, override{ContainerID: containerID, Schedule: spec})
+	}
+	sort.Slice(overrides, func(i, j int) bool {
+		return overrides[i].ContainerID < overrides[j].ContainerID
+	})
+
+	data, err := json.MarshalIndent(overrides, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode backup schedules: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o750); err != nil {
+		return fmt.Errorf("failed to create backup schedule directory: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o640); err != nil {
+		return fmt.Errorf("failed to write backup schedules: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write backup schedules: %w", err)
+	}
+	return nil
+}
+
+// signal wakes Start to pick up changed schedules.
+func (s *Scheduler) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// container finds a monitored container, falling back to the container
+// lookup for containers not in the config file. Callers must hold mu.
+func (s *Scheduler) container(containerID int) (config.ContainerConfig, bool) {
+	for _, container := range s.containers {
+		if container.ID == containerID {
+			return container, true
+		}
+	}
+	if s.lookup != nil {
+		return s.lookup(containerID)
+	}
+	return config.ContainerConfig{}, false
+}
+
+func (s *Scheduler) job(containerID int) (*job, bool) {
+	for _, j := range s.jobs {
+		if j.container.ID == containerID {
+			return j, true
+		}
+	}
+	return nil, false
+}
+
+// report returns a job as the scheduler reports it. Callers must hold mu.
+func (s *Scheduler) report(j *job) Job {
+	storage := j.container.BackupStorage
+	if storage == "" {
+		storage = s.config.Storage
+	}
+	_, overridden := s.overrides[j.container.ID]
+	return Job{
+		ContainerID:   j.container.ID,
+		ContainerName: j.container.Name,
+		Schedule:      j.spec,
+		Storage:       storage,
+		Next:          j.next,
+		Running:       s.running[j.container.ID],
+		Configured:    !overridden,
+	}
+}
+
+// backup takes a backup of the container, alerting when it fails.
+func (s *Scheduler) backup(ctx context.Context, container config.ContainerConfig) {
+	storage := container.BackupStorage
+	if storage == "" {
+		storage = s.config.Storage
+	}
+	logger := s.logger.WithFields(logrus.Fields{
+		"container_id": container.ID,
+		"storage":      storage,
+	})
+
+	// Containers that cannot be found are limited together; their backups
+	// fail anyway
+	var node string
+	if info, err := s.client.GetContainer(ctx, container.ID); err == nil {
+		node = info.Node
+	}
+	release, err := s.limiter.Acquire(ctx, node)
+	if err != nil {
+		return
+	}
+	defer release()
+	logger.WithField("node", node).Info("Starting scheduled backup")
+
+	started := time.Now()
+	target := Target{Container: container, Node: node, Storage: storage, Origin: catalog.OriginScheduled}
+	backupPath, err := WithHooks(ctx, s.config, target, s.logger, func(ctx context.Context) (string, error) {
+		if s.config.BackupTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, s.config.BackupTimeout)
+			defer cancel()
+		}
+		return s.client.BackupContainer(ctx, container.ID, storage, s.config.BackupDir, s.config.VzdumpFor(container))
+	})
+	if err != nil {
+		logger.WithField("error", err).Error("Scheduled backup failed")
+		s.notifier.Send(notify.Event{
+			Type:          notify.EventBackupFailed,
+			Severity:      notify.SeverityWarning,
+			ContainerID:   container.ID,
+			ContainerName: container.Name,
+			Message:       fmt.Sprintf("Scheduled backup of container %d failed: %v", container.ID, err),
+			Details:       map[string]string{"storage": storage},
+		})
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"backup_path": backupPath,
+		"duration":    time.Since(started),
+	}).Info("Scheduled backup completed")
+
+	err = s.catalog.Record(catalog.Entry{
+		VolID:       backupPath,
+		ContainerID: container.ID,
+		Storage:     storage,
+		Created:     started,
+		Origin:      catalog.OriginScheduled,
+	})
+	if err != nil {
+		logger.WithField("error", err).Warn("Failed to record backup in catalog")
+	}
+}
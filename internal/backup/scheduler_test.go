@@ -0,0 +1,333 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeClient struct {
+	mu      sync.Mutex
+	calls   []string
+	options []config.VzdumpOptions
+	err     error
+}
+
+func (f *fakeClient) GetContainer(ctx context.Context, containerID int) (*api.ContainerInfo, error) {
+	return &api.ContainerInfo{ID: containerID, Node: "node1"}, nil
+}
+
+func (f *fakeClient) BackupContainer(ctx context.Context, containerID int, storage, backupDir string, options config.VzdumpOptions) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, storage)
+	f.options = append(f.options, options)
+	return "local:backup/vzdump-lxc-100.tar.zst", f.err
+}
+
+func testConfig() *config.Config {
+	return &config.Config{
+		Backup: config.BackupConfig{Storage: "local", Schedule: "0 2 * * *"},
+		Monitoring: config.MonitoringConfig{
+			Containers: []config.ContainerConfig{
+				{ID: 100},
+				{ID: 101, BackupSchedule: "30 * * * *", BackupStorage: "nfs", BackupMode: config.BackupModeStop, BackupBwLimit: 10240},
+				{ID: 102, BackupSchedule: config.BackupScheduleOff},
+			},
+		},
+	}
+}
+
+func TestNewScheduler(t *testing.T) {
+	s, err := NewScheduler(testConfig(), &fakeClient{}, nil, logrus.New())
+	if err != nil {
+		t.Fatalf("NewScheduler failed: %v", err)
+	}
+
+	var ids []int
+	for _, j := range s.jobs {
+		ids = append(ids, j.container.ID)
+	}
+	if !reflect.DeepEqual(ids, []int{100, 101}) {
+		t.Errorf("Expected jobs for containers [100 101], got %v", ids)
+	}
+	if !s.Enabled() {
+		t.Error("Expected scheduler to be enabled")
+	}
+
+	cfg := testConfig()
+	cfg.Monitoring.Containers[0].BackupSchedule = "not a schedule"
+	if _, err := NewScheduler(cfg, &fakeClient{}, nil, logrus.New()); err == nil {
+		t.Error("Expected an invalid schedule to fail")
+	}
+}
+
+func TestScheduler_Due(t *testing.T) {
+	s, err := NewScheduler(testConfig(), &fakeClient{}, nil, logrus.New())
+	if err != nil {
+		t.Fatalf("NewScheduler failed: %v", err)
+	}
+	start := time.Date(2024, 1, 1, 1, 0, 0, 0, time.Local)
+	for _, j := range s.jobs {
+		j.next = j.schedule.Next(start)
+	}
+
+	if next := s.nextRun(); !next.Equal(start.Add(30 * time.Minute)) {
+		t.Errorf("Expected next run at 01:30, got %v", next)
+	}
+
+	due := s.due(start.Add(30 * time.Minute))
+	if len(due) != 1 || due[0].container.ID != 101 {
+		t.Fatalf("Expected container 101 due at 01:30, got %d jobs", len(due))
+	}
+
+	// Missed runs are taken once
+	due = s.due(start.Add(5 * time.Hour))
+	if len(due) != 2 {
+		t.Fatalf("Expected both containers due at 06:00, got %d jobs", len(due))
+	}
+	if next := s.nextRun(); !next.Equal(start.Add(5*time.Hour + 30*time.Minute)) {
+		t.Errorf("Expected next run at 06:30, got %v", next)
+	}
+}
+
+func TestScheduler_Backup(t *testing.T) {
+	client := &fakeClient{}
+	s, err := NewScheduler(testConfig(), client, nil, logrus.New())
+	if err != nil {
+		t.Fatalf("NewScheduler failed: %v", err)
+	}
+
+	s.backup(context.Background(), s.jobs[0].container)
+	client.err = errors.New("storage offline")
+	s.backup(context.Background(), s.jobs[1].container)
+
+	if !reflect.DeepEqual(client.calls, []string{"local", "nfs"}) {
+		t.Errorf("Expected backups to local and nfs, got %v", client.calls)
+	}
+	options := []config.VzdumpOptions{
+		{Mode: config.BackupModeSnapshot, Compress: config.BackupCompressZstd},
+		{Mode: config.BackupModeStop, Compress: config.BackupCompressZstd, BwLimit: 10240},
+	}
+	if !reflect.DeepEqual(client.options, options) {
+		t.Errorf("Expected vzdump options %+v, got %+v", options, client.options)
+	}
+}
+
+func TestScheduler_AddRemove(t *testing.T) {
+	s, err := NewScheduler(testConfig(), &fakeClient{}, nil, logrus.New())
+	if err != nil {
+		t.Fatalf("NewScheduler failed: %v", err)
+	}
+
+	if _, err := s.Add(999, "0 3 * * *"); !errors.Is(err, ErrNotMonitored) {
+		t.Errorf("Expected ErrNotMonitored for an unmonitored container, got %v", err)
+	}
+	if _, err := s.Add(102, "not a schedule"); err == nil {
+		t.Error("Expected an invalid schedule to fail")
+	}
+
+	job, err := s.Add(102, "0 3 * * *")
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if job.ContainerID != 102 || job.Schedule != "0 3 * * *" || job.Storage != "local" || job.Configured {
+		t.Errorf("Unexpected job %+v", job)
+	}
+
+	removed, err := s.Remove(100)
+	if err != nil || !removed {
+		t.Fatalf("Expected the configured schedule of container 100 to be removed, got %v, %v", removed, err)
+	}
+	if removed, _ := s.Remove(100); removed {
+		t.Error("Expected a removed schedule not to be removed again")
+	}
+
+	var ids []int
+	for _, job := range s.Jobs() {
+		ids = append(ids, job.ContainerID)
+	}
+	sort.Ints(ids)
+	if !reflect.DeepEqual(ids, []int{101, 102}) {
+		t.Errorf("Expected jobs for containers [101 102], got %v", ids)
+	}
+	if !reflect.DeepEqual(s.overrides, map[int]string{100: config.BackupScheduleOff, 102: "0 3 * * *"}) {
+		t.Errorf("Unexpected overrides %v", s.overrides)
+	}
+
+	// Setting the configured schedule again drops the override
+	if _, err := s.Add(100, "0 2 * * *"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if _, ok := s.overrides[100]; ok {
+		t.Error("Expected the override of container 100 to be dropped")
+	}
+}
+
+func TestScheduler_Persist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ScheduleFile)
+
+	s, err := NewScheduler(testConfig(), &fakeClient{}, nil, logrus.New())
+	if err != nil {
+		t.Fatalf("NewScheduler failed: %v", err)
+	}
+	if err := s.Persist(path); err != nil {
+		t.Fatalf("Persist failed: %v", err)
+	}
+	if _, err := s.Add(102, "0 3 * * *"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if _, err := s.Remove(101); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+
+	restarted, err := NewScheduler(testConfig(), &fakeClient{}, nil, logrus.New())
+	if err != nil {
+		t.Fatalf("NewScheduler failed: %v", err)
+	}
+	if err := restarted.Persist(path); err != nil {
+		t.Fatalf("Persist failed: %v", err)
+	}
+
+	schedules := make(map[int]string)
+	for _, job := range restarted.Jobs() {
+		schedules[job.ContainerID] = job.Schedule
+	}
+	expected := map[int]string{100: "0 2 * * *", 102: "0 3 * * *"}
+	if !reflect.DeepEqual(schedules, expected) {
+		t.Errorf("Expected schedules %v after a restart, got %v", expected, schedules)
+	}
+}
+
+func TestScheduler_Reload(t *testing.T) {
+	s, err := NewScheduler(testConfig(), &fakeClient{}, nil, logrus.New())
+	if err != nil {
+		t.Fatalf("NewScheduler failed: %v", err)
+	}
+	if _, err := s.Add(100, "0 4 * * *"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if _, err := s.Add(102, "0 3 * * *"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	before, _ := s.job(100)
+	next := before.next
+
+	// 100 is configured as it was added, 101 changes, 102 is no longer
+	// monitored and 103 is new
+	cfg := testConfig()
+	cfg.Monitoring.Containers = []config.ContainerConfig{
+		{ID: 100, BackupSchedule: "0 4 * * *"},
+		{ID: 101, BackupSchedule: "15 * * * *", BackupStorage: "nfs"},
+		{ID: 103},
+	}
+	s.Reload(cfg)
+
+	schedules := make(map[int]string)
+	for _, job := range s.Jobs() {
+		schedules[job.ContainerID] = job.Schedule
+		if !job.Configured {
+			t.Errorf("Expected the schedule of container %d to be configured", job.ContainerID)
+		}
+	}
+	expected := map[int]string{100: "0 4 * * *", 101: "15 * * * *", 103: "0 2 * * *"}
+	if !reflect.DeepEqual(schedules, expected) {
+		t.Errorf("Expected schedules %v after a reload, got %v", expected, schedules)
+	}
+	if after, _ := s.job(100); !after.next.Equal(next) {
+		t.Errorf("Expected the unchanged schedule to keep its next run %v, got %v", next, after.next)
+	}
+	if len(s.overrides) != 0 {
+		t.Errorf("Expected no overrides left, got %v", s.overrides)
+	}
+	if _, err := s.Add(102, "0 3 * * *"); !errors.Is(err, ErrNotMonitored) {
+		t.Errorf("Expected ErrNotMonitored for a container no longer monitored, got %v", err)
+	}
+	if _, err := s.Add(103, "0 5 * * *"); err != nil {
+		t.Errorf("Expected a container added by a reload to be scheduled, got %v", err)
+	}
+}
+
+func TestScheduler_ContainerLookup(t *testing.T) {
+	s, err := NewScheduler(testConfig(), &fakeClient{}, nil, logrus.New())
+	if err != nil {
+		t.Fatalf("NewScheduler failed: %v", err)
+	}
+	discovered := true
+	s.SetContainerLookup(func(containerID int) (config.ContainerConfig, bool) {
+		return config.ContainerConfig{ID: 200, Name: "discovered"}, discovered && containerID == 200
+	})
+
+	job, err := s.Add(200, "0 3 * * *")
+	if err != nil {
+		t.Fatalf("Expected a discovered container to be scheduled, got %v", err)
+	}
+	if job.ContainerName != "discovered" {
+		t.Errorf("Expected the name of the discovered container, got %q", job.ContainerName)
+	}
+
+	// Reloads keep it while it is monitored
+	s.Reload(testConfig())
+	if _, ok := s.job(200); !ok {
+		t.Error("Expected the discovered container to keep its schedule across a reload")
+	}
+
+	discovered = false
+	s.Reload(testConfig())
+	if _, ok := s.job(200); ok {
+		t.Error("Expected the schedule of a container no longer monitored to be dropped")
+	}
+	if _, ok := s.overrides[200]; ok {
+		t.Error("Expected the override of a container no longer monitored to be dropped")
+	}
+}
+
+func TestScheduler_RunNow(t *testing.T) {
+	client := &fakeClient{}
+	s, err := NewScheduler(testConfig(), client, nil, logrus.New())
+	if err != nil {
+		t.Fatalf("NewScheduler failed: %v", err)
+	}
+	if _, err := s.RunNow(100); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("Expected ErrNotRunning before the scheduler starts, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Start(ctx)
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, err := s.RunNow(102); errors.Is(err, ErrNoSchedule) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected ErrNoSchedule for a container without a schedule")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, err := s.RunNow(100); err != nil {
+		t.Fatalf("RunNow failed: %v", err)
+	}
+	for {
+		client.mu.Lock()
+		calls := len(client.calls)
+		client.mu.Unlock()
+		if calls == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected a backup to be taken")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,330 @@
+// Package catalog keeps a persistent record of the backups of containers,
+// with what storage listings do not tell: where a backup came from and
+// whether it has been restored successfully.
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/store"
+)
+
+// FileName is the name of the catalog file within the data directory.
+const FileName = "backup-catalog.json"
+
+// Origins of a backup. Backups ProxWarden did not take have none.
+const (
+	OriginScheduled   = "scheduled"
+	OriginPreFailover = "pre_failover"
+	OriginManual      = "manual"
+)
+
+// Entry is a single backup.
+type Entry struct {
+	VolID       string    `json:"volid"`
+	ContainerID int       `json:"container_id"`
+	Node        string    `json:"node,omitempty"`
+	Storage     string    `json:"storage"`
+	Filename    string    `json:"filename,omitempty"`
+	Size        int64     `json:"size,omitempty"`
+	Format      string    `json:"format,omitempty"`
+	Created     time.Time `json:"created"`
+	Origin      string    `json:"origin,omitempty"`
+	// VerifiedAt is when the backup was last restored successfully
+	VerifiedAt time.Time `json:"verified_at,omitempty"`
+	// Protected is whether Proxmox refuses to delete the backup
+	Protected bool `json:"protected,omitempty"`
+	// Pinned backups are restored by failovers of their container instead
+	// of the latest one, and never pruned
+	Pinned bool `json:"pinned,omitempty"`
+}
+
+// Verified reports whether the backup has been restored successfully.
+func (e Entry) Verified() bool {
+	return !e.VerifiedAt.IsZero()
+}
+
+// Filter selects entries. Zero fields match everything.
+type Filter struct {
+	ContainerID int
+	Storage     string
+}
+
+func (f Filter) match(entry Entry) bool {
+	if f.ContainerID != 0 && entry.ContainerID != f.ContainerID {
+		return false
+	}
+	if f.Storage != "" && entry.Storage != f.Storage {
+		return false
+	}
+	return true
+}
+
+// Store keeps the catalog in a JSON file, rewritten on every change, or in
+// the store database. A nil Store records nothing.
+type Store struct {
+	mu   sync.Mutex
+	path string
+	db   *store.DB
+}
+
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Record adds a backup ProxWarden took, or updates the origin of a known
+// one.
+func (s *Store) Record(entry Entry) error {
+	if s == nil {
+		return nil
+	}
+	return s.update(func(entries map[string]Entry) {
+		if known, exists := entries[entry.VolID]; exists {
+			known.Origin = entry.Origin
+			entry = known
+		}
+		entries[entry.VolID] = entry
+	})
+}
+
+// Sync updates the catalog from a listing of storage and returns the
+// listed backups, newest first. Backups listed for the first time are
+// added without an origin. Entries of nodes in the listing that no longer
+// hold them are dropped; those of nodes missing from it, which may be
+// down, are kept.
+func (s *Store) Sync(storage string, backups []api.BackupInfo) ([]Entry, error) {
+	listed := Entries(backups)
+	if s != nil {
+		err := s.update(func(entries map[string]Entry) {
+			nodes := make(map[string]bool)
+			present := make(map[string]bool)
+			for i, entry := range listed {
+				nodes[entry.Node] = true
+				present[entry.VolID] = true
+				if known, exists := entries[entry.VolID]; exists {
+					entry.Origin = known.Origin
+					entry.VerifiedAt = known.VerifiedAt
+					entry.Pinned = known.Pinned
+					listed[i] = entry
+				}
+				entries[entry.VolID] = entry
+			}
+			for volID, entry := range entries {
+				if entry.Storage == storage && nodes[entry.Node] && !present[volID] {
+					delete(entries, volID)
+				}
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return listed, nil
+}
+
+// Entries returns a storage listing as entries without catalog details.
+func Entries(backups []api.BackupInfo) []Entry {
+	entries := make([]Entry, 0, len(backups))
+	for _, backup := range backups {
+		entries = append(entries, Entry{
+			VolID:       backup.VolID,
+			ContainerID: backup.ContainerID,
+			Node:        backup.Node,
+			Storage:     backup.Storage,
+			Filename:    backup.Filename,
+			Size:        backup.Size,
+			Format:      backup.Format,
+			Created:     backup.Created,
+			Protected:   backup.Protected,
+		})
+	}
+	sortNewestFirst(entries)
+	return entries
+}
+
+// MarkVerified marks a backup as restored successfully.
+func (s *Store) MarkVerified(volID string, at time.Time) error {
+	if s == nil {
+		return nil
+	}
+	return s.update(func(entries map[string]Entry) {
+		if entry, exists := entries[volID]; exists {
+			entry.VerifiedAt = at
+			entries[volID] = entry
+		}
+	})
+}
+
+// Pin pins a backup of a container in the catalog, unpinning any other.
+func (s *Store) Pin(containerID int, volID string) error {
+	if s == nil {
+		return fmt.Errorf("no backup catalog")
+	}
+	var found bool
+	err := s.update(func(entries map[string]Entry) {
+		entry, exists := entries[volID]
+		if !exists || entry.ContainerID != containerID {
+			return
+		}
+		found = true
+		for known, other := range entries {
+			if other.ContainerID == containerID && other.Pinned {
+				other.Pinned = false
+				entries[known] = other
+			}
+		}
+		entry.Pinned = true
+		entries[volID] = entry
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("backup %s of container %d is not in the catalog", volID, containerID)
+	}
+	return nil
+}
+
+// Unpin unpins the pinned backup of a container, if any.
+func (s *Store) Unpin(containerID int) error {
+	if s == nil {
+		return nil
+	}
+	return s.update(func(entries map[string]Entry) {
+		for volID, entry := range entries {
+			if entry.ContainerID == containerID && entry.Pinned {
+				entry.Pinned = false
+				entries[volID] = entry
+			}
+		}
+	})
+}
+
+// Pinned returns the pinned backup of a container, if any.
+func (s *Store) Pinned(containerID int) (Entry, bool, error) {
+	entries, err := s.List(Filter{ContainerID: containerID})
+	if err != nil {
+		return Entry{}, false, err
+	}
+	for _, entry := range entries {
+		if entry.Pinned {
+			return entry, true, nil
+		}
+	}
+	return Entry{}, false, nil
+}
+
+// Remove drops a deleted backup.
+func (s *Store) Remove(volID string) error {
+	if s == nil {
+		return nil
+	}
+	return s.update(func(entries map[string]Entry) {
+		delete(entries, volID)
+	})
+}
+
+// List returns the entries matching filter, newest first.
+func (s *Store) List(filter Filter) ([]Entry, error) {
+	if s == nil {
+		return nil, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Entry
+	for _, entry := range entries {
+		if filter.match(entry) {
+			result = append(result, entry)
+		}
+	}
+	sortNewestFirst(result)
+	return result, nil
+}
+
+func (s *Store) update(change func(entries map[string]Entry)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.db != nil {
+		return s.updateDB(change)
+	}
+	entries, err := s.load()
+	if err != nil {
+		return err
+	}
+	change(entries)
+	return s.save(entries)
+}
+
+func (s *Store) load() (map[string]Entry, error) {
+	if s.db != nil {
+		return s.loadDB()
+	}
+	entries := make(map[string]Entry)
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup catalog: %w", err)
+	}
+
+	var list []Entry
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to decode backup catalog: %w", err)
+	}
+	for _, entry := range list {
+		entries[entry.VolID] = entry
+	}
+	return entries, nil
+}
+
+func (s *Store) save(entries map[string]Entry) error {
+	list := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		list = append(list, entry)
+	}
+	sortNewestFirst(list)
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode backup catalog: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o750); err != nil {
+		return fmt.Errorf("failed to create catalog directory: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o640); err != nil {
+		return fmt.Errorf("failed to write backup catalog: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write backup catalog: %w", err)
+	}
+	return nil
+}
+
+func sortNewestFirst(entries []Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].Created.Equal(entries[j].Created) {
+			return entries[i].Created.After(entries[j].Created)
+		}
+		return entries[i].VolID < entries[j].VolID
+	})
+}
@@ -0,0 +1,179 @@
+package catalog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/store"
+)
+
+func TestStore_Sync(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), FileName))
+	now := time.Now().Truncate(time.Second)
+
+	if err := store.Record(Entry{VolID: "local:backup/a", ContainerID: 100, Storage: "local", Created: now.Add(-time.Hour), Origin: OriginScheduled}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	backups := []api.BackupInfo{
+		{VolID: "local:backup/a", ContainerID: 100, Node: "node1", Storage: "local", Size: 1024, Created: now.Add(-time.Hour)},
+		{VolID: "local:backup/b", ContainerID: 100, Node: "node1", Storage: "local", Created: now},
+		{VolID: "local:backup/c", ContainerID: 101, Node: "node2", Storage: "local", Created: now.Add(-2 * time.Hour)},
+	}
+	entries, err := store.Sync("local", backups)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if len(entries) != 3 || entries[0].VolID != "local:backup/b" {
+		t.Fatalf("Expected 3 entries, newest first, got %+v", entries)
+	}
+	if entries[1].Origin != OriginScheduled || entries[1].Size != 1024 {
+		t.Errorf("Expected the recorded backup to keep its origin and gain its size, got %+v", entries[1])
+	}
+	if entries[0].Origin != "" {
+		t.Errorf("Expected a discovered backup to have no origin, got %q", entries[0].Origin)
+	}
+
+	if err := store.MarkVerified("local:backup/a", now); err != nil {
+		t.Fatalf("MarkVerified failed: %v", err)
+	}
+
+	// node2 is down, a was deleted from node1
+	if _, err := store.Sync("local", backups[1:2]); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	entries, err = store.List(Filter{Storage: "local"})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].VolID != "local:backup/b" || entries[1].VolID != "local:backup/c" {
+		t.Errorf("Expected b and c to remain, got %+v", entries)
+	}
+
+	entries, err = store.List(Filter{ContainerID: 101})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Expected one backup of container 101, got %+v (%v)", entries, err)
+	}
+	if err := store.Remove("local:backup/c"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if entries, _ := store.List(Filter{ContainerID: 101}); len(entries) != 0 {
+		t.Errorf("Expected removed backup to be gone, got %+v", entries)
+	}
+}
+
+func TestStore_Verified(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	store := NewStore(path)
+	now := time.Now().Truncate(time.Second)
+
+	if err := store.Record(Entry{VolID: "pbs:backup/ct/100/x", ContainerID: 100, Storage: "pbs", Created: now, Origin: OriginPreFailover}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if err := store.MarkVerified("pbs:backup/ct/100/x", now); err != nil {
+		t.Fatalf("MarkVerified failed: %v", err)
+	}
+
+	// A new store reads what the last one wrote
+	entries, err := NewStore(path).List(Filter{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 1 || !entries[0].Verified() || !entries[0].VerifiedAt.Equal(now) {
+		t.Errorf("Expected a verified backup, got %+v", entries)
+	}
+
+	var nilStore *Store
+	if err := nilStore.Record(Entry{VolID: "x"}); err != nil {
+		t.Errorf("Expected a nil store to record nothing, got %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("not json"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.List(Filter{}); err == nil {
+		t.Error("Expected a corrupt catalog to fail")
+	}
+}
+
+func TestStore_Pin(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), FileName))
+	now := time.Now().Truncate(time.Second)
+
+	backups := []api.BackupInfo{
+		{VolID: "local:backup/a", ContainerID: 100, Node: "node1", Storage: "local", Created: now.Add(-time.Hour), Protected: true},
+		{VolID: "local:backup/b", ContainerID: 100, Node: "node1", Storage: "local", Created: now},
+	}
+	if _, err := store.Sync("local", backups); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	if err := store.Pin(101, "local:backup/a"); err == nil {
+		t.Error("Expected pinning another container's backup to fail")
+	}
+	if err := store.Pin(100, "local:backup/b"); err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+	if err := store.Pin(100, "local:backup/a"); err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+
+	// The pin survives listings
+	listed, err := store.Sync("local", backups)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if listed[1].VolID != "local:backup/a" || !listed[1].Pinned || !listed[1].Protected || listed[0].Pinned {
+		t.Errorf("Expected only a to be pinned and protected, got %+v", listed)
+	}
+
+	pinned, ok, err := store.Pinned(100)
+	if err != nil || !ok || pinned.VolID != "local:backup/a" {
+		t.Errorf("Expected a pinned, got %+v, %v (%v)", pinned, ok, err)
+	}
+	if err := store.Unpin(100); err != nil {
+		t.Fatalf("Unpin failed: %v", err)
+	}
+	if _, ok, _ := store.Pinned(100); ok {
+		t.Error("Expected no pinned backup after unpinning")
+	}
+}
+
+func TestDBStore(t *testing.T) {
+	db, err := store.Open(filepath.Join(t.TempDir(), store.FileName), "")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	s := NewDBStore(db)
+	now := time.Now().Truncate(time.Second)
+
+	backups := []api.BackupInfo{
+		{VolID: "local:backup/a", ContainerID: 100, Node: "node1", Storage: "local", Created: now.Add(-time.Hour)},
+		{VolID: "local:backup/b", ContainerID: 100, Node: "node1", Storage: "local", Created: now},
+	}
+	if _, err := s.Sync("local", backups); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if err := s.MarkVerified("local:backup/a", now); err != nil {
+		t.Fatalf("MarkVerified failed: %v", err)
+	}
+	if err := s.Pin(100, "local:backup/a"); err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+
+	// b was deleted
+	listed, err := NewDBStore(db).Sync("local", backups[:1])
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if len(listed) != 1 || !listed[0].Verified() || !listed[0].Pinned {
+		t.Errorf("Expected a verified and pinned, got %+v", listed)
+	}
+	entries, err := s.List(Filter{ContainerID: 100})
+	if err != nil || len(entries) != 1 {
+		t.Errorf("Expected b dropped from the catalog, got %+v (%v)", entries, err)
+	}
+}
@@ -0,0 +1,71 @@
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/store"
+)
+
+// NewDBStore returns a Store keeping the catalog in db.
+func NewDBStore(db *store.DB) *Store {
+	return &Store{db: db}
+}
+
+// Open returns the catalog of the store backend cfg sets.
+func Open(cfg *config.Config) (*Store, error) {
+	db, err := store.OpenConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if db != nil {
+		return NewDBStore(db), nil
+	}
+	return NewStore(filepath.Join(cfg.DataDir, FileName)), nil
+}
+
+func (s *Store) loadDB() (map[string]Entry, error) {
+	var entries map[string]Entry
+	err := s.db.View(func(tx *store.Tx) error {
+		var err error
+		entries, err = loadTx(tx)
+		return err
+	})
+	return entries, err
+}
+
+// updateDB changes the catalog in a single transaction, so that changes by
+// the daemon and CLI commands are not lost.
+func (s *Store) updateDB(change func(entries map[string]Entry)) error {
+	return s.db.Update(func(tx *store.Tx) error {
+		entries, err := loadTx(tx)
+		if err != nil {
+			return err
+		}
+		change(entries)
+		if err := tx.Clear(store.BucketCatalog); err != nil {
+			return err
+		}
+		for volID, entry := range entries {
+			if err := tx.Put(store.BucketCatalog, volID, entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func loadTx(tx *store.Tx) (map[string]Entry, error) {
+	entries := make(map[string]Entry)
+	err := tx.ForEach(store.BucketCatalog, func(data []byte) error {
+		var entry Entry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return fmt.Errorf("failed to decode backup catalog: %w", err)
+		}
+		entries[entry.VolID] = entry
+		return nil
+	})
+	return entries, err
+}
@@ -0,0 +1,134 @@
+package config
+
+import "fmt"
+
+// Roles of daemon API clients, each allowed what the ones before it are.
+const (
+	// RoleReadOnly can read state and metrics
+	RoleReadOnly = "read_only"
+	// RoleOperator can also manage maintenance, pause and resume automatic
+	// failover, and cancel or reject failovers
+	RoleOperator = "operator"
+	// RoleAdmin can also approve failovers
+	RoleAdmin = "admin"
+)
+
+// ServerTLSConfig serves the daemon API over HTTPS, verifying client
+// certificates signed by ClientCAFile when it is set.
+type ServerTLSConfig struct {
+	CertFile     string `yaml:"cert_file,omitempty"`
+	KeyFile      string `yaml:"key_file,omitempty"`
+	ClientCAFile string `yaml:"client_ca_file,omitempty"`
+}
+
+// Enabled reports whether the API is served over HTTPS.
+func (t ServerTLSConfig) Enabled() bool {
+	return t.CertFile != ""
+}
+
+// ServerAuthConfig lists the API keys and client certificates allowed to use
+// the daemon API and their roles. Without any, every client is an admin.
+type ServerAuthConfig struct {
+	APIKeys     []APIKeyConfig     `yaml:"api_keys,omitempty"`
+	ClientCerts []ClientCertConfig `yaml:"client_certs,omitempty"`
+}
+
+// Enabled reports whether clients have to authenticate.
+func (a ServerAuthConfig) Enabled() bool {
+	return len(a.APIKeys) > 0 || len(a.ClientCerts) > 0
+}
+
+// APIKeyConfig is a key sent as a bearer token.
+type APIKeyConfig struct {
+	// Name identifies the key in logs
+	Name string `yaml:"name"`
+	Key  string `yaml:"key"`
+	Role string `yaml:"role"`
+}
+
+// ClientCertConfig gives clients presenting a verified certificate with
+// this common name a role.
+type ClientCertConfig struct {
+	CommonName string `yaml:"common_name"`
+	Role       string `yaml:"role"`
+}
+
+// ServerClientConfig is how CLI commands authenticate to the daemon API.
+type ServerClientConfig struct {
+	APIKey   string `yaml:"api_key,omitempty"`
+	CertFile string `yaml:"cert_file,omitempty"`
+	KeyFile  string `yaml:"key_file,omitempty"`
+	// CAFile verifies the daemon's certificate instead of the system roots
+	CAFile string `yaml:"ca_file,omitempty"`
+	// ServerName is the name the daemon's certificate is verified against,
+	// when it is not the host of the listen address
+	ServerName string `yaml:"server_name,omitempty"`
+}
+
+// RoleAllows reports whether role may do what required needs.
+func RoleAllows(role, required string) bool {
+	return roleRank(role) >= roleRank(required) && roleRank(required) > 0
+}
+
+func roleRank(role string) int {
+	switch role {
+	case RoleReadOnly:
+		return 1
+	case RoleOperator:
+		return 2
+	case RoleAdmin:
+		return 3
+	}
+	return 0
+}
+
+func validateServerAuth(server *ServerConfig) error {
+	tls := server.TLS
+	if (tls.CertFile == "") != (tls.KeyFile == "") {
+		return fmt.Errorf("server tls cert_file and key_file must be set together")
+	}
+	if tls.ClientCAFile != "" && !tls.Enabled() {
+		return fmt.Errorf("server tls client_ca_file requires cert_file and key_file")
+	}
+
+	names := make(map[string]bool)
+	keys := make(map[string]bool)
+	for i, key := range server.Auth.APIKeys {
+		switch {
+		case key.Name == "":
+			return fmt.Errorf("server auth api_keys[%d] must have a name", i)
+		case names[key.Name]:
+			return fmt.Errorf("server auth api key name %q is used more than once", key.Name)
+		case key.Key == "":
+			return fmt.Errorf("server auth api key %q must have a key", key.Name)
+		case keys[key.Key]:
+			return fmt.Errorf("server auth api key %q has the key of another one", key.Name)
+		case roleRank(key.Role) == 0:
+			return fmt.Errorf("server auth api key %q has invalid role %q, expected %s, %s or %s", key.Name, key.Role, RoleReadOnly, RoleOperator, RoleAdmin)
+		}
+		names[key.Name] = true
+		keys[key.Key] = true
+	}
+
+	if len(server.Auth.ClientCerts) > 0 && tls.ClientCAFile == "" {
+		return fmt.Errorf("server auth client_certs require server tls client_ca_file")
+	}
+	commonNames := make(map[string]bool)
+	for i, cert := range server.Auth.ClientCerts {
+		switch {
+		case cert.CommonName == "":
+			return fmt.Errorf("server auth client_certs[%d] must have a common_name", i)
+		case commonNames[cert.CommonName]:
+			return fmt.Errorf("server auth client certificate %q is listed more than once", cert.CommonName)
+		case roleRank(cert.Role) == 0:
+			return fmt.Errorf("server auth client certificate %q has invalid role %q, expected %s, %s or %s", cert.CommonName, cert.Role, RoleReadOnly, RoleOperator, RoleAdmin)
+		}
+		commonNames[cert.CommonName] = true
+	}
+
+	client := server.Client
+	if (client.CertFile == "") != (client.KeyFile == "") {
+		return fmt.Errorf("server client cert_file and key_file must be set together")
+	}
+	return nil
+}
@@ -2,8 +2,15 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"text/template"
 	"time"
 
+	"github.com/jbutlerdev/proxwarden/internal/httpproxy"
+	"github.com/mitchellh/mapstructure"
+	"github.com/robfig/cron/v3"
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v3"
 )
@@ -14,6 +21,36 @@ type Config struct {
 	Monitoring MonitoringConfig `yaml:"monitoring"`
 	Failover  FailoverConfig  `yaml:"failover"`
 	Logging   LoggingConfig   `yaml:"logging"`
+	Server    ServerConfig    `yaml:"server"`
+
+	Notifications NotificationsConfig `yaml:"notifications"`
+	Maintenance   MaintenanceConfig   `yaml:"maintenance"`
+	DNS           DNSConfig           `yaml:"dns"`
+	// DataDir holds state that must survive daemon restarts
+	DataDir string `yaml:"data_dir"`
+	// WatchConfig reloads the daemon's configuration whenever its file
+	// changes, as SIGHUP does
+	WatchConfig bool `yaml:"watch_config"`
+	// Include lists glob patterns of files adding monitoring.containers
+	// entries, relative to the directory of this file
+	Include []string `yaml:"include,omitempty"`
+	// Remote reads further settings from Consul or etcd
+	Remote      RemoteConfig `yaml:"remote"`
+	RemoteState RemoteState  `yaml:"-"`
+	// LeaderElection lets redundant daemons run with one of them active
+	LeaderElection LeaderElectionConfig `yaml:"leader_election"`
+	// Audit records every action taken on the cluster
+	Audit AuditConfig `yaml:"audit"`
+	// Store keeps the records that must survive daemon restarts
+	Store StoreConfig `yaml:"store"`
+}
+
+// AuditConfig is where every action ProxWarden takes on the cluster, by
+// the daemon or CLI commands, is recorded.
+type AuditConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// File is the audit log; empty puts audit.jsonl in DataDir
+	File string `yaml:"file,omitempty"`
 }
 
 type ProxmoxConfig struct {
@@ -22,7 +59,18 @@ type ProxmoxConfig struct {
 	Password string `yaml:"password,omitempty"`
 	TokenID  string `yaml:"token_id,omitempty"`
 	Secret   string `yaml:"secret,omitempty"`
+	// PasswordFile and SecretFile name files holding the password or token
+	// secret, instead of setting them in the configuration
+	PasswordFile string `yaml:"password_file,omitempty"`
+	SecretFile   string `yaml:"secret_file,omitempty"`
+	// KeyFile or Keyring, the name of a key in the user keyring of the
+	// kernel, hold the key of an encrypted password or token secret
+	KeyFile string `yaml:"key_file,omitempty"`
+	Keyring string `yaml:"keyring,omitempty"`
 	Insecure bool   `yaml:"insecure"`
+	// Proxy is a proxy URL for API requests, "direct" to bypass proxies, or
+	// empty to use HTTPS_PROXY/NO_PROXY from the environment
+	Proxy string `yaml:"proxy,omitempty"`
 }
 
 type BackupConfig struct {
@@ -31,23 +79,490 @@ type BackupConfig struct {
 	RetentionDays int           `yaml:"retention_days"`
 	PreBackup     bool          `yaml:"pre_backup"`
 	BackupTimeout time.Duration `yaml:"backup_timeout"`
+	// KeepLast keeps this many of the newest backups of each container
+	// regardless of their age
+	KeepLast int `yaml:"keep_last"`
+	// PruneInterval is how often the daemon deletes backups outside the
+	// retention; 0 leaves pruning to "proxwarden backup prune"
+	PruneInterval time.Duration `yaml:"prune_interval"`
+	// Schedule is a standard cron expression at which the daemon backs up
+	// every monitored container; empty takes no scheduled backups
+	Schedule string `yaml:"schedule,omitempty"`
+	// Mode is the vzdump mode: snapshot (the default), suspend or stop
+	Mode string `yaml:"mode,omitempty"`
+	// Compress is the vzdump compression: zstd (the default), gzip, lzo or
+	// none
+	Compress string `yaml:"compress,omitempty"`
+	// BwLimit limits backup I/O in KiB/s; 0 leaves the node's limit
+	BwLimit int `yaml:"bwlimit,omitempty"`
+	// MaxConcurrent bounds how many backups run at once; 0 does not
+	MaxConcurrent int `yaml:"max_concurrent"`
+	// MaxPerNode bounds how many backups of containers on the same node
+	// run at once; 0 does not
+	MaxPerNode int `yaml:"max_per_node"`
+	// PreHooks and PostHooks run around every backup, before those of the
+	// container
+	PreHooks  []Hook `yaml:"pre_hooks"`
+	PostHooks []Hook `yaml:"post_hooks"`
+	// Replication keeps copies of backups on a second storage
+	Replication ReplicationConfig `yaml:"replication"`
+}
+
+// ReplicationConfig keeps copies of the latest backup of every monitored
+// container on a second storage, so failovers still find a backup when the
+// backup storage is lost with a node.
+type ReplicationConfig struct {
+	// Storage is the second storage; empty disables replication
+	Storage string `yaml:"storage,omitempty"`
+	// Command copies a backup to Storage. Without it the copies are left
+	// to the storage, such as a PBS sync job, and only restored from.
+	Command Hook `yaml:"command,omitempty"`
+	// Interval is how often new backups are looked for
+	Interval time.Duration `yaml:"interval"`
 }
 
+// Backup modes
+const (
+	BackupModeSnapshot = "snapshot"
+	BackupModeSuspend  = "suspend"
+	BackupModeStop     = "stop"
+)
+
+// Backup compressions
+const (
+	BackupCompressZstd = "zstd"
+	BackupCompressGzip = "gzip"
+	BackupCompressLzo  = "lzo"
+	BackupCompressNone = "none"
+)
+
+// VzdumpOptions are the vzdump settings of a backup.
+type VzdumpOptions struct {
+	Mode     string
+	Compress string
+	// BwLimit is in KiB/s
+	BwLimit int
+}
+
+// HooksFor returns the hooks run before and after backups of a container:
+// the global ones followed by its own.
+func (b BackupConfig) HooksFor(container ContainerConfig) (pre, post []Hook) {
+	pre = append(append(pre, b.PreHooks...), container.PreBackupHooks...)
+	post = append(append(post, b.PostHooks...), container.PostBackupHooks...)
+	return pre, post
+}
+
+// VzdumpFor returns the vzdump settings of a container: its own where set,
+// or else the global ones, or else snapshot mode with zstd compression.
+func (b BackupConfig) VzdumpFor(container ContainerConfig) VzdumpOptions {
+	options := VzdumpOptions{Mode: b.Mode, Compress: b.Compress, BwLimit: b.BwLimit}
+	if container.BackupMode != "" {
+		options.Mode = container.BackupMode
+	}
+	if container.BackupCompress != "" {
+		options.Compress = container.BackupCompress
+	}
+	if container.BackupBwLimit > 0 {
+		options.BwLimit = container.BackupBwLimit
+	}
+	if options.Mode == "" {
+		options.Mode = BackupModeSnapshot
+	}
+	if options.Compress == "" {
+		options.Compress = BackupCompressZstd
+	}
+	return options
+}
+
+// ScheduleFor returns the backup schedule of a container: its own, unless
+// it is "off", or else the global one.
+func (b BackupConfig) ScheduleFor(container ContainerConfig) string {
+	switch container.BackupSchedule {
+	case "":
+		return b.Schedule
+	case BackupScheduleOff:
+		return ""
+	default:
+		return container.BackupSchedule
+	}
+}
+
+// BackupScheduleOff excludes a container from the global backup schedule.
+const BackupScheduleOff = "off"
+
 type MonitoringConfig struct {
 	Interval        time.Duration `yaml:"interval"`
 	Timeout         time.Duration `yaml:"timeout"`
 	FailureThreshold int          `yaml:"failure_threshold"`
+	// FailureWindow, when set, makes FailureThreshold count failures within
+	// this window instead of consecutive failures
+	FailureWindow   time.Duration `yaml:"failure_window"`
+	HealthyThreshold int          `yaml:"healthy_threshold"`
+	HistorySize     int           `yaml:"history_size"`
 	Containers      []ContainerConfig `yaml:"containers"`
+	// Defaults are inherited by every container for the settings it
+	// leaves unset
+	Defaults ContainerConfig `yaml:"defaults"`
+	// Selectors are the Containers entries with a Match, which Load moves
+	// out of Containers
+	Selectors []ContainerConfig `yaml:"-"`
+	// containerPaths and selectorPaths locate the entries of Containers and
+	// Selectors in the configuration files
+	containerPaths, selectorPaths []string
+
+	// SpreadChecks staggers the first run of each check across its interval
+	// so checks do not all fire at once
+	SpreadChecks bool `yaml:"spread_checks"`
+	// Jitter adds a random delay of up to this duration to every check run
+	Jitter time.Duration `yaml:"jitter"`
+	// Proxy is the default proxy for HTTP(S) health checks
+	Proxy string `yaml:"proxy,omitempty"`
+
+	Events   EventsConfig         `yaml:"events"`
+	Nodes    NodeMonitoringConfig `yaml:"nodes"`
+	Flapping FlappingConfig       `yaml:"flapping"`
+	Discover DiscoveryConfig      `yaml:"discover"`
+
+	Witnesses WitnessesConfig `yaml:"witnesses"`
+
+	AdaptiveInterval AdaptiveIntervalConfig `yaml:"adaptive_interval"`
+}
+
+// splitSelectors moves the Containers entries with a Match to Selectors.
+// paths locates every entry of Containers.
+func (m *MonitoringConfig) splitSelectors(paths []string) {
+	var containers []ContainerConfig
+	m.containerPaths, m.selectorPaths = nil, nil
+	for i, container := range m.Containers {
+		if container.Match != nil {
+			m.Selectors = append(m.Selectors, container)
+			m.selectorPaths = append(m.selectorPaths, paths[i])
+			continue
+		}
+		containers = append(containers, container)
+		m.containerPaths = append(m.containerPaths, paths[i])
+	}
+	m.Containers = containers
+}
+
+// Discovers reports whether containers are enrolled by tag or pool, through
+// Discover or Selectors.
+func (m MonitoringConfig) Discovers() bool {
+	return m.Discover.Enabled() || len(m.Selectors) > 0
+}
+
+// ContainerPath returns the YAML path of the ith entry of Containers,
+// prefixed with its file if it was included.
+func (m MonitoringConfig) ContainerPath(i int) string {
+	if i < len(m.containerPaths) {
+		return m.containerPaths[i]
+	}
+	return containerPath("", i)
+}
+
+// SelectorPath returns the YAML path of the ith entry of Selectors,
+// prefixed with its file if it was included.
+func (m MonitoringConfig) SelectorPath(i int) string {
+	if i < len(m.selectorPaths) {
+		return m.selectorPaths[i]
+	}
+	return containerPath("", i)
+}
+
+// requireApproval reports whether any container requires approval of its
+// failovers by itself.
+func (m MonitoringConfig) requireApproval() bool {
+	entries := append([]ContainerConfig{m.Defaults}, m.Containers...)
+	for _, container := range append(entries, m.Selectors...) {
+		if container.RequireApproval != nil && *container.RequireApproval {
+			return true
+		}
+	}
+	return false
+}
+
+func containerPath(file string, i int) string {
+	path := fmt.Sprintf("monitoring.containers[%d]", i)
+	if file != "" {
+		path = file + ": " + path
+	}
+	return path
+}
+
+// AdaptiveIntervalConfig backs a container's checks off to Interval once it
+// has been healthy for HealthyFor, and restores their normal interval on the
+// first failure. Checks that are already slower keep their own interval.
+type AdaptiveIntervalConfig struct {
+	HealthyFor time.Duration `yaml:"healthy_for"`
+	Interval   time.Duration `yaml:"interval"`
+}
+
+// Enabled reports whether adaptive intervals are configured.
+func (a AdaptiveIntervalConfig) Enabled() bool {
+	return a.HealthyFor > 0 && a.Interval > 0
+}
+
+// WitnessCheckProxmox is a witness check type that succeeds when the
+// Proxmox API answers.
+const WitnessCheckProxmox = "proxmox"
+
+// WitnessesConfig lists targets the proxwarden host must be able to reach
+// before it trusts its own view of container health. While fewer than
+// MinReachable respond, failures are not recorded and no failover starts.
+type WitnessesConfig struct {
+	Checks       []HealthCheck `yaml:"checks,omitempty"`
+	MinReachable int           `yaml:"min_reachable"`
+	Interval     time.Duration `yaml:"interval"`
+}
+
+// DiscoveryConfig enrolls containers carrying any of Tags, or belonging to
+// Pool, without listing them in Containers. Discovered containers get the
+// default health checks, failover nodes and storage configured here; checks
+// without a target use the container's name as hostname.
+type DiscoveryConfig struct {
+	Tags     []string      `yaml:"tags,omitempty"`
+	Pool     string        `yaml:"pool,omitempty"`
+	Interval time.Duration `yaml:"interval"`
+
+	HealthChecks  []HealthCheck `yaml:"health_checks,omitempty"`
+	FailoverNodes []string      `yaml:"failover_nodes,omitempty"`
+	Storage       string        `yaml:"storage,omitempty"`
+	Priority      int           `yaml:"priority,omitempty"`
+}
+
+// Enabled reports whether any discovery selector is configured.
+func (d DiscoveryConfig) Enabled() bool {
+	return len(d.Tags) > 0 || d.Pool != ""
+}
+
+// FlappingConfig controls flap detection. A container that changes between
+// healthy and unhealthy more than Threshold times within Window is flapping:
+// automatic failover is suppressed until it stays stable for a full window.
+type FlappingConfig struct {
+	Enabled   bool          `yaml:"enabled"`
+	Threshold int           `yaml:"threshold"`
+	Window    time.Duration `yaml:"window"`
+}
+
+// NodeMonitoringConfig controls detection of whole Proxmox nodes going
+// offline, which fails over every monitored container on the node at once.
+type NodeMonitoringConfig struct {
+	Enabled          bool          `yaml:"enabled"`
+	Interval         time.Duration `yaml:"interval"`
+	FailureThreshold int           `yaml:"failure_threshold"`
+}
+
+// EventsConfig controls watching the Proxmox cluster task list so container
+// stops, migrations and failed tasks trigger immediate health checks.
+type EventsConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	PollInterval time.Duration `yaml:"poll_interval"`
 }
 
 type ContainerConfig struct {
 	ID           int      `yaml:"id"`
+	// Match makes the entry apply to every container carrying a Proxmox tag
+	// or in a pool, instead of the container with ID. Containers configured
+	// by ID take precedence; otherwise the first matching entry applies.
+	Match         *ContainerMatch `yaml:"match,omitempty"`
 	Name         string   `yaml:"name"`
 	HealthChecks []HealthCheck `yaml:"health_checks"`
 	Priority     int      `yaml:"priority"`
 	FailoverNodes []string `yaml:"failover_nodes"`
 	Storage      string   `yaml:"storage"`
 	BackupStorage string  `yaml:"backup_storage,omitempty"`
+	// BackupSchedule overrides Backup.Schedule when set; "off" takes no
+	// scheduled backups of the container
+	BackupSchedule string `yaml:"backup_schedule,omitempty"`
+	// BackupMode, BackupCompress and BackupBwLimit override the vzdump
+	// settings of Backup when set
+	BackupMode     string `yaml:"backup_mode,omitempty"`
+	BackupCompress string `yaml:"backup_compress,omitempty"`
+	BackupBwLimit  int    `yaml:"backup_bwlimit,omitempty"`
+	// PreBackupHooks and PostBackupHooks run around backups of the
+	// container, after those of Backup
+	PreBackupHooks  []Hook `yaml:"pre_backup_hooks,omitempty"`
+	PostBackupHooks []Hook `yaml:"post_backup_hooks,omitempty"`
+
+	// HealthyThreshold overrides Monitoring.HealthyThreshold when set
+	HealthyThreshold int `yaml:"healthy_threshold,omitempty"`
+	// FailureThreshold overrides Monitoring.FailureThreshold when set
+	FailureThreshold int `yaml:"failure_threshold,omitempty"`
+	// FailureWindow overrides Monitoring.FailureWindow when set
+	FailureWindow time.Duration `yaml:"failure_window,omitempty"`
+	// Interval overrides Monitoring.Interval for checks without their own
+	Interval time.Duration `yaml:"interval,omitempty"`
+	// DependsOn lists container IDs this container needs. While any of them
+	// is down, this container's checks are skipped instead of failed.
+	DependsOn []int `yaml:"depends_on,omitempty"`
+	// BlackoutWindows are recurring periods, such as backup runs, during
+	// which failures are logged but not counted
+	BlackoutWindows []BlackoutWindow `yaml:"blackout_windows,omitempty"`
+	// AutoFailover overrides Failover.AutoFailover when set, so a container
+	// can be monitored and alerted on without ever failing over by itself
+	AutoFailover *bool `yaml:"auto_failover,omitempty"`
+	// RequireApproval overrides Failover.Approval.Enabled when set
+	RequireApproval *bool `yaml:"require_approval,omitempty"`
+	// Strategy overrides Failover.Strategy when set
+	Strategy string `yaml:"strategy,omitempty"`
+	// StandbyID is a stopped copy of this container on another node that
+	// the "standby" strategy starts in its place
+	StandbyID int `yaml:"standby_id,omitempty"`
+	// StandbySync creates the standby and keeps it refreshed by restoring
+	// the container's latest backup into it
+	StandbySync bool `yaml:"standby_sync,omitempty"`
+	// Placement overrides Failover.Placement when set
+	Placement string `yaml:"placement,omitempty"`
+	// DNS lists records pointed at the container's addresses after it
+	// failed over
+	DNS []DNSRecord `yaml:"dns,omitempty"`
+	// FloatingIP is a shared service address moved to the container after
+	// it failed over
+	FloatingIP *FloatingIPConfig `yaml:"floating_ip,omitempty"`
+	// NodeOverrides adjusts the container's configuration when it is
+	// restored on the named node
+	NodeOverrides map[string]NodeOverride `yaml:"node_overrides,omitempty"`
+	// AntiAffinity lists containers, such as other replicas of the same
+	// service, this container should not share a failure domain with
+	AntiAffinity []int `yaml:"anti_affinity,omitempty"`
+}
+
+// ContainerMatch selects containers by tag or pool. When both are set a
+// container must match both.
+type ContainerMatch struct {
+	Tag  string `yaml:"tag,omitempty"`
+	Pool string `yaml:"pool,omitempty"`
+}
+
+// Matches reports whether a container with tags in pool is selected.
+func (m ContainerMatch) Matches(tags []string, pool string) bool {
+	if m.Pool != "" && m.Pool != pool {
+		return false
+	}
+	if m.Tag == "" {
+		return m.Pool != ""
+	}
+	for _, tag := range tags {
+		if tag == m.Tag {
+			return true
+		}
+	}
+	return false
+}
+
+// label names the entry in validation errors.
+func (c ContainerConfig) label() string {
+	if c.Match == nil {
+		return fmt.Sprintf("container %d", c.ID)
+	}
+	var selectors []string
+	if c.Match.Tag != "" {
+		selectors = append(selectors, fmt.Sprintf("tag %q", c.Match.Tag))
+	}
+	if c.Match.Pool != "" {
+		selectors = append(selectors, fmt.Sprintf("pool %q", c.Match.Pool))
+	}
+	return "containers matching " + strings.Join(selectors, " and ")
+}
+
+// NodeOverride fits a restored container to a node whose bridges, storage
+// or capacity differ from its original node's. Zero fields keep the values
+// of the backup.
+type NodeOverride struct {
+	// Bridge replaces the bridge of every network interface
+	Bridge string `yaml:"bridge,omitempty"`
+	// Storage is restored to instead of the container's storage
+	Storage string `yaml:"storage,omitempty"`
+	Cores   int    `yaml:"cores,omitempty"`
+	// Memory is in MiB
+	Memory int `yaml:"memory,omitempty"`
+}
+
+// NodeOverride returns the overrides for restoring the container on node.
+func (c ContainerConfig) NodeOverride(node string) NodeOverride {
+	if override, ok := c.NodeOverrides[node]; ok {
+		return override
+	}
+	// Map keys are lowercased when the config file is read
+	return c.NodeOverrides[strings.ToLower(node)]
+}
+
+// Floating IP methods.
+const (
+	FloatingIPCommand    = "command"
+	FloatingIPKeepalived = "keepalived"
+	FloatingIPUcarp      = "ucarp"
+)
+
+// FloatingIPConfig moves a service address to a container once it runs on
+// its new node and announces the move with gratuitous ARP.
+type FloatingIPConfig struct {
+	Address string `yaml:"address"`
+	// Method is "command" (default), running Release and Acquire, or
+	// "keepalived" or "ucarp", controlling that service inside the old and
+	// new container over SSH to their nodes
+	Method  string `yaml:"method,omitempty"`
+	Release Hook   `yaml:"release,omitempty"`
+	Acquire Hook   `yaml:"acquire,omitempty"`
+	// Service is the systemd unit of the keepalived and ucarp methods,
+	// named after the method by default
+	Service string `yaml:"service,omitempty"`
+	// Interface is the container interface holding the address, "eth0" by
+	// default; gratuitous ARP announces its MAC address
+	Interface string `yaml:"interface,omitempty"`
+	// ARPInterface is the interface of this host, on the service's network,
+	// gratuitous ARP is sent from; empty sends none
+	ARPInterface string `yaml:"arp_interface,omitempty"`
+	// ARPCount is the number of gratuitous ARP packets sent, 3 by default
+	ARPCount int `yaml:"arp_count,omitempty"`
+}
+
+// BlackoutWindow starts at every activation of Schedule, a standard
+// five-field cron expression, and lasts for Duration. Besides blackouts it
+// describes other recurring periods, such as failback windows.
+type BlackoutWindow struct {
+	Schedule string        `yaml:"schedule"`
+	Duration time.Duration `yaml:"duration"`
+}
+
+// Active reports whether t falls within the window, that is whether the
+// schedule fired within Duration before t.
+func (w BlackoutWindow) Active(t time.Time) bool {
+	schedule, err := cron.ParseStandard(w.Schedule)
+	if err != nil {
+		return false
+	}
+	return !schedule.Next(t.Add(-w.Duration)).After(t)
+}
+
+// Health check severities. Only critical checks count toward the failover
+// threshold; warning checks raise alerts.
+const (
+	SeverityCritical = "critical"
+	SeverityWarning  = "warning"
+)
+
+// Address families for health checks. AddressFamilyDual checks both stacks
+// and fails only if both fail.
+const (
+	AddressFamilyAny  = "any"
+	AddressFamilyIPv4 = "ipv4"
+	AddressFamilyIPv6 = "ipv6"
+	AddressFamilyDual = "dual"
+)
+
+// MaxSustain is the longest sustain period of metric checks, bounded by the
+// hour of statistics Proxmox keeps at one-minute resolution.
+const MaxSustain = time.Hour
+
+// IsMetricCheck reports whether checks of the type read container resource
+// usage from Proxmox rather than probing a target.
+func IsMetricCheck(checkType string) bool {
+	switch checkType {
+	case "disk", "memory", "swap", "cpu":
+		return true
+	}
+	return false
 }
 
 type HealthCheck struct {
@@ -57,6 +572,38 @@ type HealthCheck struct {
 	Path     string        `yaml:"path,omitempty"`
 	Timeout  time.Duration `yaml:"timeout"`
 	Interval time.Duration `yaml:"interval"`
+	// Retries is the number of immediate re-attempts before the check fails
+	Retries int `yaml:"retries,omitempty"`
+	// MaxLatency fails an otherwise successful check that responds slower
+	MaxLatency time.Duration `yaml:"max_latency,omitempty"`
+	// Severity is "critical" (default) or "warning"
+	Severity string `yaml:"severity,omitempty"`
+	// Proxy overrides Monitoring.Proxy for HTTP(S) checks
+	Proxy string `yaml:"proxy,omitempty"`
+	// AddressFamily is "any" (default), "ipv4", "ipv6" or "dual"
+	AddressFamily string `yaml:"address_family,omitempty"`
+	// UsageThreshold is the usage percentage at which metric checks such as
+	// "disk" fail
+	UsageThreshold float64 `yaml:"usage_threshold,omitempty"`
+	// Sustain makes a metric check fail only when usage stayed at the
+	// threshold for this long, read from the Proxmox RRD statistics
+	Sustain time.Duration `yaml:"sustain,omitempty"`
+	// DependsOn lists container IDs this check needs; it is skipped while
+	// any of them is down
+	DependsOn []int `yaml:"depends_on,omitempty"`
+
+	// Credentials and query used by the database protocol checks
+	// (postgres, mysql, redis). All are optional.
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	Database string `yaml:"database,omitempty"`
+	Query    string `yaml:"query,omitempty"`
+
+	// Command, Args and Options configure "plugin" checks, which run an
+	// external executable speaking the JSON plugin protocol.
+	Command string            `yaml:"command,omitempty"`
+	Args    []string          `yaml:"args,omitempty"`
+	Options map[string]string `yaml:"options,omitempty"`
 }
 
 type FailoverConfig struct {
@@ -64,9 +611,227 @@ type FailoverConfig struct {
 	MaxRetries       int           `yaml:"max_retries"`
 	RetryDelay       time.Duration `yaml:"retry_delay"`
 	BackupBeforeFailover bool       `yaml:"backup_before_failover"`
+	// SnapshotBeforeFailover takes a pre-failover snapshot of containers
+	// that keep their volumes through the failover, so they can be rolled
+	// back quickly
+	SnapshotBeforeFailover bool `yaml:"snapshot_before_failover"`
 	RestoreTimeout   time.Duration `yaml:"restore_timeout"`
-	PreFailoverHooks []string      `yaml:"pre_failover_hooks"`
-	PostFailoverHooks []string     `yaml:"post_failover_hooks"`
+	PreFailoverHooks []Hook        `yaml:"pre_failover_hooks"`
+	PostFailoverHooks []Hook       `yaml:"post_failover_hooks"`
+
+	// Cooldown is the minimum time after a failover of a container before
+	// it is failed over automatically again
+	Cooldown time.Duration `yaml:"cooldown"`
+	// MaxFailoversPerHour opens a per-container circuit breaker that stops
+	// automatic failover after this many failovers within an hour; 0
+	// disables the breaker
+	MaxFailoversPerHour int `yaml:"max_failovers_per_hour"`
+	// MaxConcurrent limits how many failovers run at once; the rest wait
+	// in container priority order. 0 means no limit
+	MaxConcurrent int `yaml:"max_concurrent"`
+	// TierConcurrency limits how many failovers of the same priority run
+	// at once when several containers fail over together; lower priority
+	// values go first and the next tier waits for the previous one. 0
+	// means no limit besides MaxConcurrent
+	TierConcurrency int `yaml:"tier_concurrency"`
+	// PriorityConcurrency overrides TierConcurrency for single priorities
+	PriorityConcurrency map[int]int `yaml:"priority_concurrency,omitempty"`
+	// Strategy is how containers are moved: "restore" (default),
+	// "migrate", "replica", "standby" or "auto"
+	Strategy string `yaml:"strategy"`
+	// Placement is how target nodes are picked among the failover nodes:
+	// "priority" (default), "least-loaded", "round-robin" or "plugin"
+	Placement string `yaml:"placement"`
+	// PlacementPlugin scores the failover nodes with the "plugin" placement
+	PlacementPlugin Plugin `yaml:"placement_plugin,omitempty"`
+	// RespectHAGroups limits failover targets to the nodes Proxmox HA
+	// prefers for a container: the highest-priority online members of its
+	// HA group or node affinity rule
+	RespectHAGroups bool `yaml:"respect_ha_groups"`
+	// FailureDomains labels nodes with the zone and rack they are in, so
+	// failover targets are preferred outside the failed node's
+	FailureDomains map[string]FailureDomain `yaml:"failure_domains,omitempty"`
+	// ClusterConfigDir is where the Proxmox cluster filesystem is mounted;
+	// the "replica" strategy moves guest configs within it
+	ClusterConfigDir string `yaml:"cluster_config_dir"`
+	// GracePeriod delays automatic failovers after announcing them, so an
+	// operator can cancel them before anything is changed; 0 starts them
+	// right away
+	GracePeriod time.Duration `yaml:"grace_period"`
+	// StandbySyncInterval is how often standbys of containers with
+	// standby_sync are refreshed from newer backups
+	StandbySyncInterval time.Duration `yaml:"standby_sync_interval"`
+	// SourceDownTimeout bounds the wait for a stopped container to report
+	// itself stopped before a replacement is started elsewhere
+	SourceDownTimeout time.Duration `yaml:"source_down_timeout"`
+	// AllowUnconfirmedSource lets failovers start a replacement although
+	// the container could be neither confirmed stopped nor fenced on its
+	// source node, risking two running instances. Manual failovers can do
+	// so with --force instead.
+	AllowUnconfirmedSource bool `yaml:"allow_unconfirmed_source"`
+	// ResumeInterrupted resumes failovers a crash or restart of the daemon
+	// left unfinished when it starts again; without it they wait for an
+	// operator to resume or discard them
+	ResumeInterrupted bool `yaml:"resume_interrupted"`
+
+	Failback FailbackConfig `yaml:"failback"`
+	Fencing  FencingConfig  `yaml:"fencing"`
+	Approval ApprovalConfig `yaml:"approval"`
+	Drill    DrillConfig    `yaml:"drill"`
+}
+
+// DrillConfig runs failovers of designated test containers, so broken
+// backups or strategies show up before a real outage does.
+type DrillConfig struct {
+	// Containers are the monitored test containers drills fail over
+	Containers []int `yaml:"containers,omitempty"`
+	// Schedule is a standard cron expression for drills of all Containers;
+	// without it drills only run on demand
+	Schedule string `yaml:"schedule,omitempty"`
+	// MoveBack rolls a container back to its node after a successful drill
+	MoveBack bool `yaml:"move_back"`
+}
+
+// Includes reports whether the container is designated for drills.
+func (d DrillConfig) Includes(containerID int) bool {
+	for _, id := range d.Containers {
+		if id == containerID {
+			return true
+		}
+	}
+	return false
+}
+
+// ConcurrencyFor returns how many failovers of containers with priority
+// run at once within a mass failover; 0 means no limit.
+func (f FailoverConfig) ConcurrencyFor(priority int) int {
+	if limit, ok := f.PriorityConcurrency[priority]; ok {
+		return limit
+	}
+	return f.TierConcurrency
+}
+
+// FailureDomain is where a node is located. Nodes sharing a rack, or a zone,
+// are likely to fail together.
+type FailureDomain struct {
+	Zone string `yaml:"zone,omitempty"`
+	Rack string `yaml:"rack,omitempty"`
+}
+
+// FailureDomain returns the failure domain of node; it is empty for nodes
+// without labels.
+func (f FailoverConfig) FailureDomain(node string) FailureDomain {
+	if domain, ok := f.FailureDomains[node]; ok {
+		return domain
+	}
+	// Map keys are lowercased when the config file is read
+	return f.FailureDomains[strings.ToLower(node)]
+}
+
+// ApprovalConfig holds automatic failovers until an operator approves them.
+type ApprovalConfig struct {
+	// Enabled applies to containers that do not set require_approval
+	Enabled bool `yaml:"enabled"`
+	// Timeout is how long a failover waits for approval before it is
+	// dropped
+	Timeout time.Duration `yaml:"timeout"`
+	// AutoApproveAfter approves failovers nobody decided on within this
+	// time, for unattended clusters; 0 never approves automatically
+	AutoApproveAfter time.Duration `yaml:"auto_approve_after"`
+}
+
+// Hook is a shell command run before or after a failover. In the config file
+// it is either the command alone or a mapping with these fields.
+type Hook struct {
+	Command string        `yaml:"command"`
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// OnFailure is "abort" or "continue". Failing pre-failover hooks abort
+	// the failover by default; failing post-failover hooks continue with
+	// the remaining hooks by default.
+	OnFailure string `yaml:"on_failure,omitempty"`
+}
+
+// DefaultHookTimeout bounds hooks that do not set their own timeout.
+const DefaultHookTimeout = time.Minute
+
+// Hook failure policies.
+const (
+	HookAbort    = "abort"
+	HookContinue = "continue"
+)
+
+// hookFromString decodes the plain command form of a Hook.
+func hookFromString(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String || to != reflect.TypeOf(Hook{}) {
+		return data, nil
+	}
+	return Hook{Command: data.(string)}, nil
+}
+
+// FencingConfig makes sure the source node of a failover can no longer run
+// the container before a copy is started elsewhere. It applies whenever the
+// original container cannot be stopped through the API.
+type FencingConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Cluster waits until corosync has dropped the node from the quorate
+	// partition and Proxmox HA, if it manages the node, has fenced it
+	Cluster bool `yaml:"cluster"`
+	// Hooks are shell commands that fence the node, run with NODE set; all
+	// of them must succeed
+	Hooks []string `yaml:"hooks,omitempty"`
+	// Plugins fence the node after the hooks; all of them must succeed
+	Plugins []Plugin `yaml:"plugins,omitempty"`
+	// Timeout bounds the whole fencing step
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// FailbackConfig moves automatically failed-over containers back to their
+// original node once it has been online for StableFor.
+type FailbackConfig struct {
+	Enabled   bool          `yaml:"enabled"`
+	StableFor time.Duration `yaml:"stable_for"`
+	// Strategy is how containers are moved back; see FailoverConfig.Strategy
+	Strategy string `yaml:"strategy"`
+	// Windows restrict failback to these recurring periods; without windows
+	// containers fail back as soon as their node is stable
+	Windows []BlackoutWindow `yaml:"windows,omitempty"`
+}
+
+// Failover strategies. StrategyAuto picks the first possible of migrate,
+// replica and restore.
+const (
+	StrategyRestore = "restore"
+	StrategyMigrate = "migrate"
+	StrategyReplica = "replica"
+	StrategyStandby = "standby"
+	StrategyAuto    = "auto"
+)
+
+// ValidStrategy reports whether strategy names a failover strategy.
+func ValidStrategy(strategy string) bool {
+	switch strategy {
+	case StrategyRestore, StrategyMigrate, StrategyReplica, StrategyStandby, StrategyAuto:
+		return true
+	}
+	return false
+}
+
+// Placement policies for choosing a failover target node.
+const (
+	PlacementPriority    = "priority"
+	PlacementLeastLoaded = "least-loaded"
+	PlacementRoundRobin  = "round-robin"
+	// PlacementPlugin lets FailoverConfig.PlacementPlugin score the nodes
+	PlacementPlugin = "plugin"
+)
+
+// ValidPlacement reports whether placement names a placement policy.
+func ValidPlacement(placement string) bool {
+	switch placement {
+	case PlacementPriority, PlacementLeastLoaded, PlacementRoundRobin, PlacementPlugin:
+		return true
+	}
+	return false
 }
 
 type LoggingConfig struct {
@@ -75,6 +840,191 @@ type LoggingConfig struct {
 	File   string `yaml:"file,omitempty"`
 }
 
+// ServerConfig controls the daemon's local HTTP API used by CLI commands.
+type ServerConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Listen  string `yaml:"listen"`
+	// MetricsListen also serves /metrics, /healthz and /readyz, and nothing
+	// else, on another address such as one Prometheus can reach
+	MetricsListen string             `yaml:"metrics_listen,omitempty"`
+	TLS           ServerTLSConfig    `yaml:"tls"`
+	Auth          ServerAuthConfig   `yaml:"auth"`
+	Client        ServerClientConfig `yaml:"client"`
+}
+
+// MaintenanceConfig sets the default length of maintenance windows enabled at
+// runtime and lists containers and nodes that are always in maintenance.
+type MaintenanceConfig struct {
+	DefaultDuration time.Duration `yaml:"default_duration"`
+	Containers      []int         `yaml:"containers,omitempty"`
+	Nodes           []string      `yaml:"nodes,omitempty"`
+}
+
+type NotificationsConfig struct {
+	Providers []NotificationProvider `yaml:"providers"`
+	// Routes limit the providers they name to the events they match;
+	// providers no route names receive every event
+	Routes []NotificationRoute `yaml:"routes,omitempty"`
+	// DedupWindow drops events identical to one sent within it; 0 disables
+	// deduplication
+	DedupWindow time.Duration        `yaml:"dedup_window,omitempty"`
+	Throttle    NotificationThrottle `yaml:"throttle,omitempty"`
+	// Template customizes the title and message of every provider's
+	// notifications
+	Template NotificationTemplate `yaml:"template,omitempty"`
+	// Escalations notify further providers of failures nobody acknowledges
+	Escalations []NotificationEscalation `yaml:"escalations,omitempty"`
+}
+
+// NotificationEscalation sends the events matching all of its non-empty
+// criteria through its steps in turn until someone acknowledges them or
+// they are resolved.
+type NotificationEscalation struct {
+	Name       string           `yaml:"name"`
+	Events     []string         `yaml:"events,omitempty"`
+	Severities []string         `yaml:"severities,omitempty"`
+	Containers []int            `yaml:"containers,omitempty"`
+	Steps      []EscalationStep `yaml:"steps"`
+}
+
+// EscalationStep notifies its providers once an escalation has gone
+// unacknowledged for After.
+type EscalationStep struct {
+	After     time.Duration `yaml:"after,omitempty"`
+	Providers []string      `yaml:"providers"`
+}
+
+// NotificationTemplate holds Go templates rendered with each event, such as
+// "{{.ContainerName}} failed over to {{.Details.target_node}}". Empty ones
+// keep the default.
+type NotificationTemplate struct {
+	Title   string `yaml:"title,omitempty"`
+	Message string `yaml:"message,omitempty"`
+}
+
+// NotificationRoute sends the events matching all of its non-empty
+// criteria to its providers.
+type NotificationRoute struct {
+	Providers  []string `yaml:"providers"`
+	Events     []string `yaml:"events,omitempty"`
+	Severities []string `yaml:"severities,omitempty"`
+	Containers []int    `yaml:"containers,omitempty"`
+}
+
+// NotificationThrottle limits how often an event of one type is sent for
+// the same container or node.
+type NotificationThrottle struct {
+	// Interval is the least time between such events; 0 disables throttling
+	Interval time.Duration `yaml:"interval,omitempty"`
+	// Events are the throttled event types; empty throttles all of them
+	Events []string `yaml:"events,omitempty"`
+}
+
+// NotificationProvider configures a single notification destination.
+type NotificationProvider struct {
+	Name    string            `yaml:"name"`
+	Type    string            `yaml:"type"`
+	URL     string            `yaml:"url,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// Command and Args run a plugin receiving each event
+	Command string   `yaml:"command,omitempty"`
+	Args    []string `yaml:"args,omitempty"`
+	// RoutingKey is the integration key of a PagerDuty service
+	RoutingKey string `yaml:"routing_key,omitempty"`
+	// APIKey is the key of an Opsgenie API integration
+	APIKey string `yaml:"api_key,omitempty"`
+	// HistoryURL links incidents and Teams cards of a container to its
+	// failover history, with {container_id} replaced
+	HistoryURL string `yaml:"history_url,omitempty"`
+	// BotToken and ChatID address the chat a Telegram bot posts to
+	BotToken string `yaml:"bot_token,omitempty"`
+	ChatID   string `yaml:"chat_id,omitempty"`
+	// Mention is added to chat messages of critical events, such as
+	// "@here" on Discord or "@oncall" on Telegram
+	Mention string `yaml:"mention,omitempty"`
+	// Topic is the ntfy topic events are published to
+	Topic string `yaml:"topic,omitempty"`
+	// Token is the access token of ntfy or the application token of Gotify
+	Token string `yaml:"token,omitempty"`
+	// ActionURL is opened by the buttons of Teams cards, with
+	// {container_id} and {action} (view, approve or reject) replaced
+	ActionURL string `yaml:"action_url,omitempty"`
+	// Template overrides notifications.template for this provider
+	Template NotificationTemplate `yaml:"template,omitempty"`
+}
+
+// DNS provider types.
+const (
+	DNSCloudflare = "cloudflare"
+	DNSRoute53    = "route53"
+	DNSPowerDNS   = "powerdns"
+	DNSRFC2136    = "rfc2136"
+)
+
+// DNSConfig configures the providers that keep containers' DNS records
+// pointing at them after a failover.
+type DNSConfig struct {
+	Providers []DNSProvider `yaml:"providers"`
+	// TTL of written records, unless a record sets its own
+	TTL int `yaml:"ttl"`
+	// AddressTimeout bounds the wait for a failed-over container to report
+	// its addresses
+	AddressTimeout time.Duration `yaml:"address_timeout"`
+}
+
+// DNSProvider configures a single DNS provider. Which fields are used
+// depends on the type.
+type DNSProvider struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"`
+	// Zone is the zone name, such as "example.com"
+	Zone string `yaml:"zone,omitempty"`
+	// ZoneID is the Cloudflare zone ID, looked up from Zone when empty, or
+	// the Route 53 hosted zone ID
+	ZoneID string `yaml:"zone_id,omitempty"`
+	// URL is the PowerDNS API URL, or overrides the Cloudflare and Route 53
+	// API endpoints
+	URL string `yaml:"url,omitempty"`
+
+	// Cloudflare API token with DNS edit permission
+	APIToken string `yaml:"api_token,omitempty"`
+
+	// PowerDNS API key and server ID, "localhost" by default
+	APIKey   string `yaml:"api_key,omitempty"`
+	ServerID string `yaml:"server_id,omitempty"`
+
+	// Route 53 credentials, taken from the AWS_* environment variables when
+	// empty
+	AccessKeyID     string `yaml:"access_key_id,omitempty"`
+	SecretAccessKey string `yaml:"secret_access_key,omitempty"`
+	Region          string `yaml:"region,omitempty"`
+
+	// RFC 2136 server as host:port and optional TSIG key. The secret is
+	// base64 encoded; the algorithm is hmac-sha256 by default.
+	Server        string `yaml:"server,omitempty"`
+	TSIGKey       string `yaml:"tsig_key,omitempty"`
+	TSIGSecret    string `yaml:"tsig_secret,omitempty"`
+	TSIGAlgorithm string `yaml:"tsig_algorithm,omitempty"`
+}
+
+// DNSRecord is a hostname whose A and AAAA records follow a container.
+type DNSRecord struct {
+	Hostname string `yaml:"hostname"`
+	// Provider names the DNS provider serving the hostname
+	Provider string `yaml:"provider"`
+	// TTL overrides DNS.TTL when set
+	TTL int `yaml:"ttl,omitempty"`
+	// Interface limits the addresses to those of a container interface,
+	// such as "eth0"
+	Interface string `yaml:"interface,omitempty"`
+}
+
+// IsWarning reports whether the check only raises alerts instead of
+// counting toward the failover threshold.
+func (h HealthCheck) IsWarning() bool {
+	return h.Severity == SeverityWarning
+}
+
 func Load() (*Config, error) {
 	config := &Config{
 		Proxmox: ProxmoxConfig{
@@ -86,11 +1036,39 @@ func Load() (*Config, error) {
 			RetentionDays: 7,
 			PreBackup:     true,
 			BackupTimeout: 10 * time.Minute,
+			MaxPerNode:    1,
+			Replication: ReplicationConfig{
+				Interval: 15 * time.Minute,
+			},
 		},
 		Monitoring: MonitoringConfig{
 			Interval:        30 * time.Second,
 			Timeout:         10 * time.Second,
 			FailureThreshold: 3,
+			HealthyThreshold: 1,
+			HistorySize:      100,
+			SpreadChecks:     true,
+			Events: EventsConfig{
+				Enabled:      true,
+				PollInterval: 5 * time.Second,
+			},
+			Nodes: NodeMonitoringConfig{
+				Enabled:          true,
+				Interval:         10 * time.Second,
+				FailureThreshold: 3,
+			},
+			Flapping: FlappingConfig{
+				Enabled:   true,
+				Threshold: 5,
+				Window:    10 * time.Minute,
+			},
+			Discover: DiscoveryConfig{
+				Interval: 5 * time.Minute,
+			},
+			Witnesses: WitnessesConfig{
+				MinReachable: 1,
+				Interval:     10 * time.Second,
+			},
 		},
 		Failover: FailoverConfig{
 			AutoFailover:         true,
@@ -98,24 +1076,107 @@ func Load() (*Config, error) {
 			RetryDelay:           5 * time.Second,
 			BackupBeforeFailover: true,
 			RestoreTimeout:       15 * time.Minute,
+			Cooldown:             10 * time.Minute,
+			MaxFailoversPerHour:  3,
+			MaxConcurrent:        2,
+			Strategy:             StrategyRestore,
+			Placement:            PlacementPriority,
+			ClusterConfigDir:     "/etc/pve",
+			SourceDownTimeout:    time.Minute,
+			StandbySyncInterval:  6 * time.Hour,
+			Failback: FailbackConfig{
+				StableFor: 30 * time.Minute,
+				Strategy:  StrategyMigrate,
+			},
+			Fencing: FencingConfig{
+				Timeout: 3 * time.Minute,
+			},
+			Approval: ApprovalConfig{
+				Timeout: 15 * time.Minute,
+			},
 		},
 		Logging: LoggingConfig{
 			Level:  "info",
 			Format: "json",
 		},
+		Server: ServerConfig{
+			Enabled: true,
+			Listen:  "127.0.0.1:8470",
+		},
+		Maintenance: MaintenanceConfig{
+			DefaultDuration: time.Hour,
+		},
+		DNS: DNSConfig{
+			TTL:            60,
+			AddressTimeout: time.Minute,
+		},
+		DataDir: "/var/lib/proxwarden",
+		LeaderElection: LeaderElectionConfig{
+			TTL: 15 * time.Second,
+		},
+		Audit: AuditConfig{
+			Enabled: true,
+		},
+		Store: StoreConfig{
+			Backend: StoreFile,
+		},
+	}
+
+	remote, remoteState, err := mergeRemote()
+	if err != nil {
+		return nil, err
 	}
 
-	if err := viper.Unmarshal(config); err != nil {
+	var metadata mapstructure.Metadata
+	if err := viper.Unmarshal(config, decoderOptions, withMetadata(&metadata)); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	config.Remote = remote
+	config.RemoteState = remoteState
+	// Keys the file does not set, like bound flags, are not settings
+	notInFile := func(path string) bool {
+		return !viper.InConfig(settingElements(path)[0])
+	}
+	if err := unknownSettings(metadata.Unused, "", notInFile); err != nil {
+		return nil, err
+	}
+
+	paths, err := includeContainers(config)
+	if err != nil {
+		return nil, err
+	}
+	config.Monitoring.splitSelectors(paths)
+	config.Monitoring.applyDefaults()
+
+	if err := readSecretFiles(&config.Proxmox); err != nil {
+		return nil, err
+	}
+	if err := decryptSecrets(&config.Proxmox); err != nil {
+		return nil, err
+	}
 
 	if err := validate(config); err != nil {
-		return nil, fmt.Errorf("config validation failed: %w", err)
+		return nil, remoteUnavailable(fmt.Errorf("config validation failed: %w", err), remoteState)
 	}
 
 	return config, nil
 }
 
+// decoderOptions decodes settings by their YAML names, expanding
+// environment variables and accepting plain string hooks. Decoding by field
+// name would silently ignore settings such as failure_threshold.
+func decoderOptions(dc *mapstructure.DecoderConfig) {
+	dc.TagName = "yaml"
+	dc.DecodeHook = mapstructure.ComposeDecodeHookFunc(expandEnvHook, dc.DecodeHook, hookFromString)
+}
+
+// withMetadata records the keys a decoding used and left unused.
+func withMetadata(metadata *mapstructure.Metadata) viper.DecoderConfigOption {
+	return func(dc *mapstructure.DecoderConfig) {
+		dc.Metadata = metadata
+	}
+}
+
 func validate(config *Config) error {
 	if config.Proxmox.Endpoint == "" {
 		return fmt.Errorf("proxmox endpoint is required")
@@ -129,25 +1190,770 @@ func validate(config *Config) error {
 		return fmt.Errorf("either password or token authentication must be configured")
 	}
 
-	if len(config.Monitoring.Containers) == 0 {
+	if _, err := httpproxy.ProxyFunc(config.Proxmox.Proxy); err != nil {
+		return fmt.Errorf("proxmox proxy: %w", err)
+	}
+
+	if _, err := httpproxy.ProxyFunc(config.Monitoring.Proxy); err != nil {
+		return fmt.Errorf("monitoring proxy: %w", err)
+	}
+
+	discover := config.Monitoring.Discover
+	if len(config.Monitoring.Containers) == 0 && len(config.Monitoring.Selectors) == 0 && !discover.Enabled() {
 		return fmt.Errorf("at least one container must be configured for monitoring")
 	}
 
-	for _, container := range config.Monitoring.Containers {
+	if err := validateDefaults(config.Monitoring.Defaults); err != nil {
+		return err
+	}
+
+	defaults := config.Monitoring.Defaults
+	if discover.Enabled() {
+		if discover.Interval <= 0 {
+			return fmt.Errorf("monitoring discover interval must be positive")
+		}
+		if len(discover.HealthChecks) == 0 && len(defaults.HealthChecks) == 0 {
+			return fmt.Errorf("monitoring discover must have at least one health check")
+		}
+		if err := validateHealthChecks("discovered containers", discover.HealthChecks); err != nil {
+			return err
+		}
+		if len(discover.FailoverNodes) == 0 && len(defaults.FailoverNodes) == 0 {
+			return fmt.Errorf("monitoring discover must have at least one failover node")
+		}
+	}
+
+	if config.Server.Enabled && config.Server.Listen == "" {
+		return fmt.Errorf("server listen address is required when the server is enabled")
+	}
+	if err := validateServerAuth(&config.Server); err != nil {
+		return err
+	}
+	if err := validateLeaderElection(&config.LeaderElection); err != nil {
+		return err
+	}
+	if err := validateStore(&config.Store); err != nil {
+		return err
+	}
+
+	if config.Maintenance.DefaultDuration < 0 {
+		return fmt.Errorf("maintenance default_duration must not be negative")
+	}
+
+	if config.Monitoring.Events.Enabled && config.Monitoring.Events.PollInterval <= 0 {
+		return fmt.Errorf("monitoring events poll_interval must be positive")
+	}
+
+	if config.Monitoring.Nodes.Enabled {
+		if config.Monitoring.Nodes.Interval <= 0 {
+			return fmt.Errorf("monitoring nodes interval must be positive")
+		}
+		if config.Monitoring.Nodes.FailureThreshold < 1 {
+			return fmt.Errorf("monitoring nodes failure_threshold must be at least 1")
+		}
+	}
+
+	if config.Monitoring.Flapping.Enabled {
+		if config.Monitoring.Flapping.Threshold < 1 {
+			return fmt.Errorf("monitoring flapping threshold must be at least 1")
+		}
+		if config.Monitoring.Flapping.Window <= 0 {
+			return fmt.Errorf("monitoring flapping window must be positive")
+		}
+	}
+
+	if witnesses := config.Monitoring.Witnesses; len(witnesses.Checks) > 0 {
+		if witnesses.Interval <= 0 {
+			return fmt.Errorf("monitoring witnesses interval must be positive")
+		}
+		if witnesses.MinReachable < 1 || witnesses.MinReachable > len(witnesses.Checks) {
+			return fmt.Errorf("monitoring witnesses min_reachable must be between 1 and the number of checks")
+		}
+		for _, check := range witnesses.Checks {
+			if IsMetricCheck(check.Type) {
+				return fmt.Errorf("monitoring witnesses: %s checks need a container and cannot be witnesses", check.Type)
+			}
+			if check.Type != WitnessCheckProxmox && check.Type != "plugin" && check.Target == "" {
+				return fmt.Errorf("monitoring witnesses: %s check requires a target", check.Type)
+			}
+		}
+		if err := validateHealthChecks("monitoring witnesses", witnesses.Checks); err != nil {
+			return err
+		}
+	}
+
+	if adaptive := config.Monitoring.AdaptiveInterval; adaptive.HealthyFor < 0 || adaptive.Interval < 0 {
+		return fmt.Errorf("monitoring adaptive_interval durations must not be negative")
+	} else if (adaptive.HealthyFor > 0) != (adaptive.Interval > 0) {
+		return fmt.Errorf("monitoring adaptive_interval requires both healthy_for and interval")
+	}
+
+	if config.Failover.Cooldown < 0 {
+		return fmt.Errorf("failover cooldown must not be negative")
+	}
+
+	if config.Failover.MaxFailoversPerHour < 0 {
+		return fmt.Errorf("failover max_failovers_per_hour must not be negative")
+	}
+
+	if config.Failover.Placement != "" && !ValidPlacement(config.Failover.Placement) {
+		return fmt.Errorf("invalid failover placement %q", config.Failover.Placement)
+	}
+	if config.usesPlacementPlugin() {
+		if err := validatePlugin("failover placement_plugin", config.Failover.PlacementPlugin); err != nil {
+			return err
+		}
+	}
+
+	if config.Failover.MaxConcurrent < 0 {
+		return fmt.Errorf("failover max_concurrent must not be negative")
+	}
+
+	if config.Backup.RetentionDays < 0 || config.Backup.KeepLast < 0 || config.Backup.PruneInterval < 0 {
+		return fmt.Errorf("backup retention_days, keep_last and prune_interval must not be negative")
+	}
+
+	if replication := config.Backup.Replication; replication.Storage != "" {
+		if replication.Command.Command != "" && replication.Interval <= 0 {
+			return fmt.Errorf("backup replication interval must be positive")
+		}
+		if replication.Storage == config.Backup.Storage {
+			return fmt.Errorf("backup replication storage must differ from backup storage")
+		}
+	}
+
+	if config.Backup.MaxConcurrent < 0 || config.Backup.MaxPerNode < 0 {
+		return fmt.Errorf("backup max_concurrent and max_per_node must not be negative")
+	}
+
+	if config.Backup.BackupTimeout < 0 {
+		return fmt.Errorf("backup backup_timeout must not be negative")
+	}
+
+	if config.Failover.RestoreTimeout < 0 {
+		return fmt.Errorf("failover restore_timeout must not be negative")
+	}
+
+	if config.Failover.TierConcurrency < 0 {
+		return fmt.Errorf("failover tier_concurrency must not be negative")
+	}
+	for priority, limit := range config.Failover.PriorityConcurrency {
+		if limit < 0 {
+			return fmt.Errorf("failover priority_concurrency %d must not be negative", priority)
+		}
+	}
+
+	if config.Failover.GracePeriod < 0 {
+		return fmt.Errorf("failover grace_period must not be negative")
+	}
+
+	if config.Failover.SourceDownTimeout < 0 {
+		return fmt.Errorf("failover source_down_timeout must not be negative")
+	}
+
+	if config.Failover.Strategy != "" && !ValidStrategy(config.Failover.Strategy) {
+		return fmt.Errorf("invalid failover strategy %q", config.Failover.Strategy)
+	}
+
+	if failback := config.Failover.Failback; failback.Enabled {
+		if failback.StableFor < 0 {
+			return fmt.Errorf("failover failback stable_for must not be negative")
+		}
+		if failback.Strategy != "" && !ValidStrategy(failback.Strategy) {
+			return fmt.Errorf("invalid failover failback strategy %q", failback.Strategy)
+		}
+		if err := validateWindows("failover failback", failback.Windows); err != nil {
+			return err
+		}
+	}
+
+	if err := validateHooks("failover pre_failover_hooks", config.Failover.PreFailoverHooks); err != nil {
+		return err
+	}
+	if err := validateHooks("failover post_failover_hooks", config.Failover.PostFailoverHooks); err != nil {
+		return err
+	}
+	if err := validateHooks("backup pre_hooks", config.Backup.PreHooks); err != nil {
+		return err
+	}
+	if err := validateHooks("backup post_hooks", config.Backup.PostHooks); err != nil {
+		return err
+	}
+
+	if fencing := config.Failover.Fencing; fencing.Enabled {
+		if !fencing.Cluster && len(fencing.Hooks) == 0 && len(fencing.Plugins) == 0 {
+			return fmt.Errorf("failover fencing requires cluster, hooks or plugins")
+		}
+		for i, plugin := range fencing.Plugins {
+			if err := validatePlugin(fmt.Sprintf("failover fencing plugin %d", i+1), plugin); err != nil {
+				return err
+			}
+		}
+		if fencing.Timeout <= 0 {
+			return fmt.Errorf("failover fencing timeout must be positive")
+		}
+	}
+
+	if approval := config.Failover.Approval; approval.Enabled || config.Monitoring.requireApproval() {
+		if approval.Timeout <= 0 {
+			return fmt.Errorf("failover approval timeout must be positive")
+		}
+		if approval.AutoApproveAfter < 0 || approval.AutoApproveAfter >= approval.Timeout {
+			return fmt.Errorf("failover approval auto_approve_after must be between 0 and the timeout")
+		}
+	}
+
+	if config.Monitoring.Jitter < 0 {
+		return fmt.Errorf("monitoring jitter must not be negative")
+	}
+
+	if config.Monitoring.FailureWindow < 0 {
+		return fmt.Errorf("monitoring failure_window must not be negative")
+	}
+
+	if config.Monitoring.HealthyThreshold < 0 {
+		return fmt.Errorf("monitoring healthy_threshold must not be negative")
+	}
+
+	configured := make(map[int]int)
+	for i, container := range config.Monitoring.Containers {
 		if container.ID <= 0 {
 			return fmt.Errorf("container ID must be positive")
 		}
-		if len(container.HealthChecks) == 0 {
-			return fmt.Errorf("container %d must have at least one health check", container.ID)
+		if previous, exists := configured[container.ID]; exists {
+			return fmt.Errorf("container %d is configured twice, at %s and %s", container.ID,
+				config.Monitoring.ContainerPath(previous), config.Monitoring.ContainerPath(i))
+		}
+		configured[container.ID] = i
+		if err := validateContainer(config, container); err != nil {
+			return err
+		}
+	}
+	for _, selector := range config.Monitoring.Selectors {
+		if err := validateSelector(config, selector); err != nil {
+			return err
+		}
+	}
+
+	if err := validateDependencies(config.Monitoring.Containers); err != nil {
+		return err
+	}
+
+	if err := validateDrill(config); err != nil {
+		return err
+	}
+
+	if config.Backup.Schedule != "" {
+		if _, err := cron.ParseStandard(config.Backup.Schedule); err != nil {
+			return fmt.Errorf("backup: invalid schedule %q: %w", config.Backup.Schedule, err)
+		}
+	}
+
+	if err := validateVzdump("backup", config.Backup.Mode, config.Backup.Compress, config.Backup.BwLimit); err != nil {
+		return err
+	}
+
+	for _, provider := range config.Notifications.Providers {
+		if provider.Name == "" {
+			return fmt.Errorf("notification provider name is required")
+		}
+		if err := validateTemplate("notification provider "+provider.Name, provider.Template); err != nil {
+			return err
+		}
+		if provider.Type == "webhook" && provider.URL == "" {
+			return fmt.Errorf("notification provider %s: url is required", provider.Name)
+		}
+		if provider.Type == "plugin" && provider.Command == "" {
+			return fmt.Errorf("notification provider %s: command is required", provider.Name)
+		}
+		if provider.Type == "pagerduty" && provider.RoutingKey == "" {
+			return fmt.Errorf("notification provider %s: routing_key is required", provider.Name)
+		}
+		if provider.Type == "opsgenie" && provider.APIKey == "" {
+			return fmt.Errorf("notification provider %s: api_key is required", provider.Name)
+		}
+		if provider.Type == "telegram" && (provider.BotToken == "" || provider.ChatID == "") {
+			return fmt.Errorf("notification provider %s: bot_token and chat_id are required", provider.Name)
+		}
+		if (provider.Type == "discord" || provider.Type == "teams") && provider.URL == "" {
+			return fmt.Errorf("notification provider %s: url is required", provider.Name)
+		}
+		if provider.Type == "ntfy" && provider.Topic == "" {
+			return fmt.Errorf("notification provider %s: topic is required", provider.Name)
+		}
+		if provider.Type == "gotify" && (provider.URL == "" || provider.Token == "") {
+			return fmt.Errorf("notification provider %s: url and token are required", provider.Name)
+		}
+	}
+	if err := validateTemplate("notifications", config.Notifications.Template); err != nil {
+		return err
+	}
+	if err := validateNotificationRoutes(&config.Notifications); err != nil {
+		return err
+	}
+
+	if err := validateDNS(config); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateContainer validates the settings of a containers entry.
+func validateContainer(config *Config, container ContainerConfig) error {
+	label := container.label()
+
+	if container.HealthyThreshold < 0 {
+		return fmt.Errorf("%s healthy_threshold must not be negative", label)
+	}
+	if container.FailureThreshold < 0 {
+		return fmt.Errorf("%s failure_threshold must not be negative", label)
+	}
+	if container.Interval < 0 {
+		return fmt.Errorf("%s interval must not be negative", label)
+	}
+	if container.FailureWindow < 0 {
+		return fmt.Errorf("%s failure_window must not be negative", label)
+	}
+	if container.Strategy != "" && !ValidStrategy(container.Strategy) {
+		return fmt.Errorf("%s: invalid failover strategy %q", label, container.Strategy)
+	}
+	if container.StandbyID < 0 || (container.StandbyID != 0 && container.StandbyID == container.ID) {
+		return fmt.Errorf("%s: standby_id must be another container", label)
+	}
+	if container.StandbyID == 0 && container.Strategy == StrategyStandby {
+		return fmt.Errorf("%s: standby strategy requires standby_id", label)
+	}
+	if container.StandbySync && container.StandbyID == 0 {
+		return fmt.Errorf("%s: standby_sync requires standby_id", label)
+	}
+	if container.StandbySync && config.Failover.StandbySyncInterval <= 0 {
+		return fmt.Errorf("failover standby_sync_interval must be positive")
+	}
+	if container.Placement != "" && !ValidPlacement(container.Placement) {
+		return fmt.Errorf("%s: invalid placement %q", label, container.Placement)
+	}
+	if err := validateWindows(fmt.Sprintf("%s: blackout", label), container.BlackoutWindows); err != nil {
+		return err
+	}
+	if err := validateFloatingIP(container); err != nil {
+		return err
+	}
+	if err := validateNodeOverrides(container); err != nil {
+		return err
+	}
+	for _, peer := range container.AntiAffinity {
+		if peer <= 0 || peer == container.ID {
+			return fmt.Errorf("%s: anti_affinity must list other containers", label)
+		}
+	}
+	if len(container.HealthChecks) == 0 {
+		return fmt.Errorf("%s must have at least one health check", label)
+	}
+	if err := validateHealthChecks(label, container.HealthChecks); err != nil {
+		return err
+	}
+	if len(container.FailoverNodes) == 0 {
+		return fmt.Errorf("%s must have at least one failover node", label)
+	}
+	if schedule := config.Backup.ScheduleFor(container); schedule != "" {
+		if _, err := cron.ParseStandard(schedule); err != nil {
+			return fmt.Errorf("%s: invalid backup_schedule %q: %w", label, schedule, err)
+		}
+	}
+	if err := validateVzdump(fmt.Sprintf("%s: backup", label), container.BackupMode, container.BackupCompress, container.BackupBwLimit); err != nil {
+		return err
+	}
+	if err := validateHooks(fmt.Sprintf("%s: pre_backup_hooks", label), container.PreBackupHooks); err != nil {
+		return err
+	}
+	if err := validateHooks(fmt.Sprintf("%s: post_backup_hooks", label), container.PostBackupHooks); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateSelector validates a containers entry that selects containers by
+// tag or pool.
+func validateSelector(config *Config, selector ContainerConfig) error {
+	label := selector.label()
+	switch {
+	case selector.Match.Tag == "" && selector.Match.Pool == "":
+		return fmt.Errorf("monitoring containers: match requires a tag or pool")
+	case selector.ID != 0:
+		return fmt.Errorf("%s: id and match are mutually exclusive", label)
+	case selector.StandbyID != 0 || selector.StandbySync:
+		return fmt.Errorf("%s: standby containers can only be configured by id", label)
+	case len(selector.DNS) > 0 || selector.FloatingIP != nil:
+		return fmt.Errorf("%s: dns and floating_ip can only be configured by id", label)
+	}
+	return validateContainer(config, selector)
+}
+
+func validateDrill(config *Config) error {
+	drill := config.Failover.Drill
+	for _, id := range drill.Containers {
+		monitored := false
+		for _, container := range config.Monitoring.Containers {
+			if container.ID == id {
+				monitored = true
+				break
+			}
 		}
-		if len(container.FailoverNodes) == 0 {
-			return fmt.Errorf("container %d must have at least one failover node", container.ID)
+		if !monitored {
+			return fmt.Errorf("failover drill: container %d is not monitored", id)
 		}
 	}
+	if drill.Schedule != "" {
+		if len(drill.Containers) == 0 {
+			return fmt.Errorf("failover drill: schedule requires containers")
+		}
+		if _, err := cron.ParseStandard(drill.Schedule); err != nil {
+			return fmt.Errorf("failover drill: invalid schedule %q: %w", drill.Schedule, err)
+		}
+	}
+	return nil
+}
+
+func validateNodeOverrides(container ContainerConfig) error {
+	for node, override := range container.NodeOverrides {
+		if override.Cores < 0 || override.Memory < 0 {
+			return fmt.Errorf("%s: node_overrides %s: cores and memory must not be negative", container.label(), node)
+		}
+	}
+	return nil
+}
+
+func validateFloatingIP(container ContainerConfig) error {
+	floating := container.FloatingIP
+	if floating == nil {
+		return nil
+	}
+
+	ip := net.ParseIP(floating.Address)
+	if ip == nil {
+		return fmt.Errorf("container %d: floating_ip: invalid address %q", container.ID, floating.Address)
+	}
+	switch floating.Method {
+	case "", FloatingIPCommand:
+		if floating.Acquire.Command == "" {
+			return fmt.Errorf("container %d: floating_ip: acquire is required", container.ID)
+		}
+	case FloatingIPKeepalived, FloatingIPUcarp:
+	default:
+		return fmt.Errorf("container %d: floating_ip: invalid method %q", container.ID, floating.Method)
+	}
+	if floating.Release.Timeout < 0 || floating.Acquire.Timeout < 0 {
+		return fmt.Errorf("container %d: floating_ip: timeout must not be negative", container.ID)
+	}
+	if floating.ARPCount < 0 {
+		return fmt.Errorf("container %d: floating_ip: arp_count must not be negative", container.ID)
+	}
+	if floating.ARPInterface != "" && ip.To4() == nil {
+		return fmt.Errorf("container %d: floating_ip: gratuitous ARP needs an IPv4 address", container.ID)
+	}
+	return nil
+}
 
+func validateTemplate(label string, t NotificationTemplate) error {
+	if _, err := template.New("title").Parse(t.Title); err != nil {
+		return fmt.Errorf("%s: invalid title template: %w", label, err)
+	}
+	if _, err := template.New("message").Parse(t.Message); err != nil {
+		return fmt.Errorf("%s: invalid message template: %w", label, err)
+	}
+	return nil
+}
+
+func validateNotificationRoutes(notifications *NotificationsConfig) error {
+	providers := make(map[string]bool)
+	for _, provider := range notifications.Providers {
+		providers[provider.Name] = true
+	}
+	for i, route := range notifications.Routes {
+		if len(route.Providers) == 0 {
+			return fmt.Errorf("notification route %d: providers are required", i+1)
+		}
+		for _, name := range route.Providers {
+			if !providers[name] {
+				return fmt.Errorf("notification route %d: unknown provider %s", i+1, name)
+			}
+		}
+		for _, severity := range route.Severities {
+			switch severity {
+			case "info", "warning", "critical":
+			default:
+				return fmt.Errorf("notification route %d: invalid severity %q", i+1, severity)
+			}
+		}
+	}
+	names := make(map[string]bool)
+	for _, escalation := range notifications.Escalations {
+		if escalation.Name == "" {
+			return fmt.Errorf("notification escalation name is required")
+		}
+		if names[escalation.Name] {
+			return fmt.Errorf("notification escalation %s: duplicate name", escalation.Name)
+		}
+		names[escalation.Name] = true
+		if len(escalation.Steps) == 0 {
+			return fmt.Errorf("notification escalation %s: steps are required", escalation.Name)
+		}
+		for _, severity := range escalation.Severities {
+			switch severity {
+			case "info", "warning", "critical":
+			default:
+				return fmt.Errorf("notification escalation %s: invalid severity %q", escalation.Name, severity)
+			}
+		}
+		for i, step := range escalation.Steps {
+			if len(step.Providers) == 0 {
+				return fmt.Errorf("notification escalation %s step %d: providers are required", escalation.Name, i+1)
+			}
+			for _, name := range step.Providers {
+				if !providers[name] {
+					return fmt.Errorf("notification escalation %s step %d: unknown provider %s", escalation.Name, i+1, name)
+				}
+			}
+			if step.After < 0 {
+				return fmt.Errorf("notification escalation %s step %d: after must not be negative", escalation.Name, i+1)
+			}
+			if i > 0 && step.After < escalation.Steps[i-1].After {
+				return fmt.Errorf("notification escalation %s step %d: after must not be earlier than the step before", escalation.Name, i+1)
+			}
+		}
+	}
+	if notifications.DedupWindow < 0 {
+		return fmt.Errorf("notifications dedup_window must not be negative")
+	}
+	if notifications.Throttle.Interval < 0 {
+		return fmt.Errorf("notifications throttle interval must not be negative")
+	}
+	return nil
+}
+
+func validateDNS(config *Config) error {
+	dns := config.DNS
+	if len(dns.Providers) > 0 {
+		if dns.TTL <= 0 {
+			return fmt.Errorf("dns ttl must be positive")
+		}
+		if dns.AddressTimeout <= 0 {
+			return fmt.Errorf("dns address_timeout must be positive")
+		}
+	}
+
+	providers := make(map[string]bool)
+	for _, provider := range dns.Providers {
+		if provider.Name == "" {
+			return fmt.Errorf("dns provider name is required")
+		}
+		if providers[provider.Name] {
+			return fmt.Errorf("dns provider %s: duplicate name", provider.Name)
+		}
+		providers[provider.Name] = true
+
+		var missing string
+		switch provider.Type {
+		case DNSCloudflare:
+			switch {
+			case provider.APIToken == "":
+				missing = "api_token"
+			case provider.Zone == "" && provider.ZoneID == "":
+				missing = "zone or zone_id"
+			}
+		case DNSRoute53:
+			if provider.ZoneID == "" {
+				missing = "zone_id"
+			}
+		case DNSPowerDNS:
+			switch {
+			case provider.URL == "":
+				missing = "url"
+			case provider.APIKey == "":
+				missing = "api_key"
+			case provider.Zone == "":
+				missing = "zone"
+			}
+		case DNSRFC2136:
+			switch {
+			case provider.Server == "":
+				missing = "server"
+			case provider.Zone == "":
+				missing = "zone"
+			case provider.TSIGKey != "" && provider.TSIGSecret == "":
+				missing = "tsig_secret"
+			}
+			switch provider.TSIGAlgorithm {
+			case "", "hmac-sha1", "hmac-sha256", "hmac-sha512":
+			default:
+				return fmt.Errorf("dns provider %s: invalid tsig_algorithm %q", provider.Name, provider.TSIGAlgorithm)
+			}
+		default:
+			return fmt.Errorf("dns provider %s: invalid type %q", provider.Name, provider.Type)
+		}
+		if missing != "" {
+			return fmt.Errorf("dns provider %s: %s is required", provider.Name, missing)
+		}
+	}
+
+	for _, container := range config.Monitoring.Containers {
+		for _, record := range container.DNS {
+			if record.Hostname == "" {
+				return fmt.Errorf("container %d: dns hostname is required", container.ID)
+			}
+			if !providers[record.Provider] {
+				return fmt.Errorf("container %d: dns record %s: unknown provider %q", container.ID, record.Hostname, record.Provider)
+			}
+			if record.TTL < 0 {
+				return fmt.Errorf("container %d: dns record %s: ttl must not be negative", container.ID, record.Hostname)
+			}
+		}
+	}
+	return nil
+}
+
+// validateHealthChecks checks the settings of a list of health checks;
+// label names their owner in error messages.
+func validateHooks(label string, hooks []Hook) error {
+	for i, hook := range hooks {
+		if hook.Command == "" {
+			return fmt.Errorf("%s[%d]: command is required", label, i)
+		}
+		if hook.Timeout < 0 {
+			return fmt.Errorf("%s[%d]: timeout must not be negative", label, i)
+		}
+		switch hook.OnFailure {
+		case "", HookAbort, HookContinue:
+		default:
+			return fmt.Errorf("%s[%d]: invalid on_failure %q", label, i, hook.OnFailure)
+		}
+	}
+	return nil
+}
+
+func validateWindows(label string, windows []BlackoutWindow) error {
+	for _, window := range windows {
+		if _, err := cron.ParseStandard(window.Schedule); err != nil {
+			return fmt.Errorf("%s: invalid window schedule %q: %w", label, window.Schedule, err)
+		}
+		if window.Duration <= 0 {
+			return fmt.Errorf("%s: window duration must be positive", label)
+		}
+	}
+	return nil
+}
+
+func validateHealthChecks(label string, checks []HealthCheck) error {
+	for _, check := range checks {
+		if check.Interval < 0 {
+			return fmt.Errorf("%s: health check interval must not be negative", label)
+		}
+		if check.Retries < 0 {
+			return fmt.Errorf("%s: health check retries must not be negative", label)
+		}
+		if check.MaxLatency < 0 {
+			return fmt.Errorf("%s: health check max_latency must not be negative", label)
+		}
+		if _, err := httpproxy.ProxyFunc(check.Proxy); err != nil {
+			return fmt.Errorf("%s: health check proxy: %w", label, err)
+		}
+		switch check.AddressFamily {
+		case "", AddressFamilyAny, AddressFamilyIPv4, AddressFamilyIPv6, AddressFamilyDual:
+		default:
+			return fmt.Errorf("%s: invalid health check address_family %q", label, check.AddressFamily)
+		}
+		if check.UsageThreshold < 0 || check.UsageThreshold > 100 {
+			return fmt.Errorf("%s: health check usage_threshold must be between 0 and 100", label)
+		}
+		if check.Sustain < 0 || check.Sustain > MaxSustain {
+			return fmt.Errorf("%s: health check sustain must be between 0 and %s", label, MaxSustain)
+		}
+		if check.Sustain > 0 && (!IsMetricCheck(check.Type) || check.Type == "swap") {
+			return fmt.Errorf("%s: sustain is not supported by %s checks", label, check.Type)
+		}
+		if check.Type == "plugin" && check.Command == "" {
+			return fmt.Errorf("%s: plugin health check requires a command", label)
+		}
+		if check.Severity != "" && check.Severity != SeverityCritical && check.Severity != SeverityWarning {
+			return fmt.Errorf("%s: invalid health check severity %q", label, check.Severity)
+		}
+	}
+	return nil
+}
+
+// validateDependencies checks that depends_on only references monitored
+// containers and that container dependencies do not form a cycle.
+func validateDependencies(containers []ContainerConfig) error {
+	dependsOn := make(map[int][]int, len(containers))
+	for _, container := range containers {
+		dependsOn[container.ID] = container.DependsOn
+	}
+
+	for _, container := range containers {
+		deps := append([]int(nil), container.DependsOn...)
+		for _, check := range container.HealthChecks {
+			deps = append(deps, check.DependsOn...)
+		}
+		for _, dep := range deps {
+			if dep == container.ID {
+				return fmt.Errorf("container %d cannot depend on itself", container.ID)
+			}
+			if _, exists := dependsOn[dep]; !exists {
+				return fmt.Errorf("container %d depends on unmonitored container %d", container.ID, dep)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[int]int, len(containers))
+	var visit func(id int) error
+	visit = func(id int) error {
+		switch state[id] {
+		case visiting:
+			return fmt.Errorf("container dependency cycle involving container %d", id)
+		case visited:
+			return nil
+		}
+		state[id] = visiting
+		for _, dep := range dependsOn[id] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[id] = visited
+		return nil
+	}
+
+	for _, container := range containers {
+		if err := visit(container.ID); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 func (c *Config) ToYAML() ([]byte, error) {
 	return yaml.Marshal(c)
-}
\ No newline at end of file
+}
+// validateVzdump checks vzdump settings, any of which may be unset.
+func validateVzdump(prefix, mode, compress string, bwlimit int) error {
+	switch mode {
+	case "", BackupModeSnapshot, BackupModeSuspend, BackupModeStop:
+	default:
+		return fmt.Errorf("%s mode must be snapshot, suspend or stop, got %q", prefix, mode)
+	}
+	switch compress {
+	case "", BackupCompressZstd, BackupCompressGzip, BackupCompressLzo, BackupCompressNone:
+	default:
+		return fmt.Errorf("%s compress must be zstd, gzip, lzo or none, got %q", prefix, compress)
+	}
+	if bwlimit < 0 {
+		return fmt.Errorf("%s bwlimit must not be negative", prefix)
+	}
+	return nil
+}
@@ -1,7 +1,12 @@
 package config
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -23,6 +28,18 @@ backup:
   retention_days: 7
   pre_backup: true
   backup_timeout: 10m
+  keep_last: 3
+  prune_interval: 6h
+  mode: "suspend"
+  compress: "gzip"
+  bwlimit: 51200
+  max_concurrent: 4
+  post_hooks:
+    - command: "/usr/local/bin/thaw.sh"
+      timeout: 30s
+  replication:
+    storage: "offsite"
+    command: "/usr/local/bin/copy-backup.sh"
 
 monitoring:
   interval: 30s
@@ -40,13 +57,39 @@ monitoring:
           port: 80
           timeout: 5s
           interval: 30s
+      floating_ip:
+        address: "192.168.1.50"
+        acquire: "/usr/local/bin/vip.sh up"
+        arp_interface: "vmbr0"
+      node_overrides:
+        PVE2:
+          bridge: "vmbr1"
+          storage: "zfs-b"
+      anti_affinity: [101]
+      backup_mode: "stop"
+      pre_backup_hooks:
+        - "/usr/local/bin/flush-db.sh"
 
 failover:
   auto_failover: true
+  tier_concurrency: 2
+  respect_ha_groups: true
+  priority_concurrency:
+    1: 4
+  failure_domains:
+    node2:
+      zone: "dc1"
+      rack: "r1"
   max_retries: 3
   retry_delay: 5s
   backup_before_failover: true
   restore_timeout: 15m
+  pre_failover_hooks:
+    - "/usr/local/bin/notify.sh"
+  post_failover_hooks:
+    - command: "/usr/local/bin/update-dns.sh"
+      timeout: 30s
+      on_failure: "abort"
 
 logging:
   level: "info"
@@ -90,6 +133,30 @@ logging:
 		t.Error("Expected insecure to be true")
 	}
 
+	// Test backup config
+	if config.Backup.KeepLast != 3 || config.Backup.PruneInterval != 6*time.Hour {
+		t.Errorf("Expected keep_last 3 and prune_interval 6h, got %d and %v", config.Backup.KeepLast, config.Backup.PruneInterval)
+	}
+
+	if config.Backup.MaxConcurrent != 4 || config.Backup.MaxPerNode != 1 {
+		t.Errorf("Expected max_concurrent 4 and default max_per_node 1, got %d and %d", config.Backup.MaxConcurrent, config.Backup.MaxPerNode)
+	}
+
+	vzdump := VzdumpOptions{Mode: BackupModeStop, Compress: BackupCompressGzip, BwLimit: 51200}
+	if got := config.Backup.VzdumpFor(config.Monitoring.Containers[0]); got != vzdump {
+		t.Errorf("Expected vzdump options %+v, got %+v", vzdump, got)
+	}
+
+	pre, post := config.Backup.HooksFor(config.Monitoring.Containers[0])
+	if !reflect.DeepEqual(pre, []Hook{{Command: "/usr/local/bin/flush-db.sh"}}) || !reflect.DeepEqual(post, []Hook{{Command: "/usr/local/bin/thaw.sh", Timeout: 30 * time.Second}}) {
+		t.Errorf("Expected the container's pre-backup hook and the global post-backup hook, got %+v and %+v", pre, post)
+	}
+
+	replication := ReplicationConfig{Storage: "offsite", Command: Hook{Command: "/usr/local/bin/copy-backup.sh"}, Interval: 15 * time.Minute}
+	if config.Backup.Replication != replication {
+		t.Errorf("Expected replication %+v, got %+v", replication, config.Backup.Replication)
+	}
+
 	// Test monitoring config
 	if config.Monitoring.Interval != 30*time.Second {
 		t.Errorf("Expected interval 30s, got %v", config.Monitoring.Interval)
@@ -108,14 +175,116 @@ logging:
 	if len(container.HealthChecks) != 1 {
 		t.Errorf("Expected 1 health check, got %d", len(container.HealthChecks))
 	}
+	floating := &FloatingIPConfig{Address: "192.168.1.50", Acquire: Hook{Command: "/usr/local/bin/vip.sh up"}, ARPInterface: "vmbr0"}
+	if !reflect.DeepEqual(container.FloatingIP, floating) {
+		t.Errorf("Expected floating_ip %+v, got %+v", floating, container.FloatingIP)
+	}
+	override := NodeOverride{Bridge: "vmbr1", Storage: "zfs-b"}
+	if got := container.NodeOverride("PVE2"); got != override {
+		t.Errorf("Expected node override %+v, got %+v", override, got)
+	}
+
+	if !reflect.DeepEqual(container.AntiAffinity, []int{101}) {
+		t.Errorf("Expected anti_affinity [101], got %v", container.AntiAffinity)
+	}
 
 	// Test failover config
+	if !config.Failover.RespectHAGroups {
+		t.Error("Expected respect_ha_groups to be true")
+	}
+	if got := config.Failover.ConcurrencyFor(1); got != 4 {
+		t.Errorf("Expected concurrency 4 for priority 1, got %d", got)
+	}
+	if got := config.Failover.ConcurrencyFor(2); got != 2 {
+		t.Errorf("Expected concurrency 2 for priority 2, got %d", got)
+	}
+	domain := FailureDomain{Zone: "dc1", Rack: "r1"}
+	if got := config.Failover.FailureDomain("node2"); got != domain {
+		t.Errorf("Expected failure domain %+v, got %+v", domain, got)
+	}
+	if got := config.Failover.FailureDomain("node3"); got != (FailureDomain{}) {
+		t.Errorf("Expected no failure domain for node3, got %+v", got)
+	}
 	if !config.Failover.AutoFailover {
 		t.Error("Expected auto_failover to be true")
 	}
 	if config.Failover.MaxRetries != 3 {
 		t.Errorf("Expected max_retries 3, got %d", config.Failover.MaxRetries)
 	}
+
+	// Hooks are either a plain command or a mapping
+	preHooks := []Hook{{Command: "/usr/local/bin/notify.sh"}}
+	if !reflect.DeepEqual(config.Failover.PreFailoverHooks, preHooks) {
+		t.Errorf("Expected pre_failover_hooks %+v, got %+v", preHooks, config.Failover.PreFailoverHooks)
+	}
+	postHooks := []Hook{{Command: "/usr/local/bin/update-dns.sh", Timeout: 30 * time.Second, OnFailure: HookAbort}}
+	if !reflect.DeepEqual(config.Failover.PostFailoverHooks, postHooks) {
+		t.Errorf("Expected post_failover_hooks %+v, got %+v", postHooks, config.Failover.PostFailoverHooks)
+	}
+}
+
+func TestLoad_SnakeCaseKeys(t *testing.T) {
+	// Settings named in more than one word, which only decode by their YAML
+	// names; every value differs from its default
+	configFile := t.TempDir() + "/proxwarden.yaml"
+	content := `
+proxmox:
+  endpoint: "https://pve:8006"
+  username: "root@pam"
+  token_id: "proxwarden"
+  secret: "secret"
+backup:
+  backup_dir: "dumps"
+  retention_days: 14
+  pre_backup: false
+  backup_timeout: 20m
+monitoring:
+  failure_threshold: 5
+  containers:
+    - id: 100
+      failover_nodes: ["node2"]
+      backup_storage: "nfs"
+      health_checks:
+        - type: ping
+          target: 10.0.0.1
+failover:
+  auto_failover: false
+  max_retries: 7
+  retry_delay: 9s
+  backup_before_failover: false
+  restore_timeout: 30m
+`
+	if err := os.WriteFile(configFile, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	viper.Reset()
+	viper.SetConfigFile(configFile)
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatalf("Failed to read config file: %v", err)
+	}
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if config.Proxmox.TokenID != "proxwarden" {
+		t.Errorf("Expected token_id proxwarden, got %q", config.Proxmox.TokenID)
+	}
+	backup := config.Backup
+	if backup.BackupDir != "dumps" || backup.RetentionDays != 14 || backup.PreBackup || backup.BackupTimeout != 20*time.Minute {
+		t.Errorf("Expected the backup settings of the file, got %+v", backup)
+	}
+	if config.Monitoring.FailureThreshold != 5 {
+		t.Errorf("Expected failure_threshold 5, got %d", config.Monitoring.FailureThreshold)
+	}
+	container := config.Monitoring.Containers[0]
+	if !reflect.DeepEqual(container.FailoverNodes, []string{"node2"}) || container.BackupStorage != "nfs" || len(container.HealthChecks) != 1 {
+		t.Errorf("Expected the container settings of the file, got %+v", container)
+	}
+	failover := config.Failover
+	if failover.AutoFailover || failover.MaxRetries != 7 || failover.RetryDelay != 9*time.Second || failover.BackupBeforeFailover || failover.RestoreTimeout != 30*time.Minute {
+		t.Errorf("Expected the failover settings of the file, got %+v", failover)
+	}
 }
 
 func TestValidation(t *testing.T) {
@@ -168,7 +337,7 @@ func TestValidation(t *testing.T) {
 			expectError: true,
 		},
 		{
-			name: "no containers",
+			name: "discovery only",
 			config: &Config{
 				Proxmox: ProxmoxConfig{
 					Endpoint: "https://test:8006",
@@ -176,22 +345,1451 @@ func TestValidation(t *testing.T) {
 					Password: "pass",
 				},
 				Monitoring: MonitoringConfig{
-					Containers: []ContainerConfig{},
+					Discover: DiscoveryConfig{
+						Tags:          []string{"proxwarden"},
+						Interval:      5 * time.Minute,
+						HealthChecks:  []HealthCheck{{Type: "ping"}},
+						FailoverNodes: []string{"node2"},
+					},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "discovery without health checks",
+			config: &Config{
+				Proxmox: ProxmoxConfig{
+					Endpoint: "https://test:8006",
+					Username: "root@pam",
+					Password: "pass",
+				},
+				Monitoring: MonitoringConfig{
+					Discover: DiscoveryConfig{
+						Pool:          "critical",
+						Interval:      5 * time.Minute,
+						FailoverNodes: []string{"node2"},
+					},
 				},
 			},
 			expectError: true,
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			err := validate(tt.config)
-			if tt.expectError && err == nil {
-				t.Error("Expected error but got none")
-			}
-			if !tt.expectError && err != nil {
-				t.Errorf("Expected no error but got: %v", err)
-			}
-		})
-	}
-}
\ No newline at end of file
+		{
+			name: "sustain on a network check",
+			config: &Config{
+				Proxmox: ProxmoxConfig{
+					Endpoint: "https://test:8006",
+					Username: "root@pam",
+					Password: "pass",
+				},
+				Monitoring: MonitoringConfig{
+					Containers: []ContainerConfig{
+						{
+							ID: 100,
+							HealthChecks: []HealthCheck{
+								{Type: "memory", Sustain: 10 * time.Minute},
+								{Type: "tcp", Target: "1.1.1.1", Port: 80, Sustain: 10 * time.Minute},
+							},
+							FailoverNodes: []string{"node2"},
+						},
+					},
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid failback window",
+			config: &Config{
+				Proxmox: ProxmoxConfig{
+					Endpoint: "https://test:8006",
+					Username: "root@pam",
+					Password: "pass",
+				},
+				Monitoring: MonitoringConfig{
+					Containers: []ContainerConfig{
+						{ID: 100, FailoverNodes: []string{"node2"}},
+					},
+				},
+				Failover: FailoverConfig{
+					Failback: FailbackConfig{
+						Enabled:   true,
+						StableFor: 30 * time.Minute,
+						Windows:   []BlackoutWindow{{Schedule: "every night", Duration: time.Hour}},
+					},
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "fencing without cluster or hooks",
+			config: &Config{
+				Proxmox: ProxmoxConfig{
+					Endpoint: "https://test:8006",
+					Username: "root@pam",
+					Password: "pass",
+				},
+				Monitoring: MonitoringConfig{
+					Containers: []ContainerConfig{
+						{ID: 100, FailoverNodes: []string{"node2"}},
+					},
+				},
+				Failover: FailoverConfig{
+					Fencing: FencingConfig{Enabled: true, Timeout: time.Minute},
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "anti-affinity with itself",
+			config: &Config{
+				Proxmox: ProxmoxConfig{
+					Endpoint: "https://test:8006",
+					Username: "root@pam",
+					Password: "pass",
+				},
+				Monitoring: MonitoringConfig{
+					Containers: []ContainerConfig{
+						{ID: 100, FailoverNodes: []string{"node2"}, AntiAffinity: []int{100}},
+					},
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "standby sync without standby",
+			config: &Config{
+				Proxmox: ProxmoxConfig{
+					Endpoint: "https://test:8006",
+					Username: "root@pam",
+					Password: "pass",
+				},
+				Monitoring: MonitoringConfig{
+					Containers: []ContainerConfig{
+						{ID: 100, FailoverNodes: []string{"node2"}, StandbySync: true},
+					},
+				},
+				Failover: FailoverConfig{
+					StandbySyncInterval: time.Hour,
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "drill of unmonitored container",
+			config: &Config{
+				Proxmox: ProxmoxConfig{
+					Endpoint: "https://test:8006",
+					Username: "root@pam",
+					Password: "pass",
+				},
+				Monitoring: MonitoringConfig{
+					Containers: []ContainerConfig{
+						{ID: 100, FailoverNodes: []string{"node2"}, HealthChecks: []HealthCheck{{Type: "tcp", Target: "10.0.0.1", Port: 80}}},
+					},
+				},
+				Failover: FailoverConfig{
+					Drill: DrillConfig{Containers: []int{9000}, Schedule: "0 3 * * 0"},
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "negative grace period",
+			config: &Config{
+				Proxmox: ProxmoxConfig{
+					Endpoint: "https://test:8006",
+					Username: "root@pam",
+					Password: "pass",
+				},
+				Monitoring: MonitoringConfig{
+					Containers: []ContainerConfig{
+						{ID: 100, FailoverNodes: []string{"node2"}},
+					},
+				},
+				Failover: FailoverConfig{
+					GracePeriod: -time.Minute,
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "negative keep last",
+			config: &Config{
+				Proxmox: ProxmoxConfig{
+					Endpoint: "https://test:8006",
+					Username: "root@pam",
+					Password: "pass",
+				},
+				Monitoring: MonitoringConfig{
+					Containers: []ContainerConfig{
+						{ID: 100, FailoverNodes: []string{"node2"}},
+					},
+				},
+				Backup: BackupConfig{KeepLast: -1},
+			},
+			expectError: true,
+		},
+		{
+			name: "negative backup concurrency",
+			config: &Config{
+				Proxmox: ProxmoxConfig{
+					Endpoint: "https://test:8006",
+					Username: "root@pam",
+					Password: "pass",
+				},
+				Monitoring: MonitoringConfig{
+					Containers: []ContainerConfig{
+						{ID: 100, FailoverNodes: []string{"node2"}},
+					},
+				},
+				Backup: BackupConfig{MaxPerNode: -1},
+			},
+			expectError: true,
+		},
+		{
+			name: "backup hook without command",
+			config: &Config{
+				Proxmox: ProxmoxConfig{
+					Endpoint: "https://test:8006",
+					Username: "root@pam",
+					Password: "pass",
+				},
+				Monitoring: MonitoringConfig{
+					Containers: []ContainerConfig{
+						{ID: 100, FailoverNodes: []string{"node2"}, PostBackupHooks: []Hook{{Timeout: time.Minute}}},
+					},
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "unknown backup mode",
+			config: &Config{
+				Proxmox: ProxmoxConfig{
+					Endpoint: "https://test:8006",
+					Username: "root@pam",
+					Password: "pass",
+				},
+				Monitoring: MonitoringConfig{
+					Containers: []ContainerConfig{
+						{ID: 100, FailoverNodes: []string{"node2"}, BackupMode: "live"},
+					},
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "replication to the backup storage",
+			config: &Config{
+				Proxmox: ProxmoxConfig{
+					Endpoint: "https://test:8006",
+					Username: "root@pam",
+					Password: "pass",
+				},
+				Monitoring: MonitoringConfig{
+					Containers: []ContainerConfig{
+						{ID: 100, FailoverNodes: []string{"node2"}},
+					},
+				},
+				Backup: BackupConfig{Storage: "local", Replication: ReplicationConfig{Storage: "local", Interval: time.Hour}},
+			},
+			expectError: true,
+		},
+		{
+			name: "negative source down timeout",
+			config: &Config{
+				Proxmox: ProxmoxConfig{
+					Endpoint: "https://test:8006",
+					Username: "root@pam",
+					Password: "pass",
+				},
+				Monitoring: MonitoringConfig{
+					Containers: []ContainerConfig{
+						{ID: 100, FailoverNodes: []string{"node2"}},
+					},
+				},
+				Failover: FailoverConfig{
+					SourceDownTimeout: -time.Minute,
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "negative restore timeout",
+			config: &Config{
+				Proxmox: ProxmoxConfig{
+					Endpoint: "https://test:8006",
+					Username: "root@pam",
+					Password: "pass",
+				},
+				Monitoring: MonitoringConfig{
+					Containers: []ContainerConfig{
+						{ID: 100, FailoverNodes: []string{"node2"}},
+					},
+				},
+				Failover: FailoverConfig{
+					RestoreTimeout: -time.Minute,
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "auto approval after the approval timeout",
+			config: &Config{
+				Proxmox: ProxmoxConfig{
+					Endpoint: "https://test:8006",
+					Username: "root@pam",
+					Password: "pass",
+				},
+				Monitoring: MonitoringConfig{
+					Containers: []ContainerConfig{
+						{ID: 100, FailoverNodes: []string{"node2"}},
+					},
+				},
+				Failover: FailoverConfig{
+					Approval: ApprovalConfig{Enabled: true, Timeout: 10 * time.Minute, AutoApproveAfter: 15 * time.Minute},
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid placement",
+			config: &Config{
+				Proxmox: ProxmoxConfig{
+					Endpoint: "https://test:8006",
+					Username: "root@pam",
+					Password: "pass",
+				},
+				Monitoring: MonitoringConfig{
+					Containers: []ContainerConfig{
+						{
+							ID:            100,
+							HealthChecks:  []HealthCheck{{Type: "tcp", Target: "1.1.1.1", Port: 80}},
+							FailoverNodes: []string{"node2", "node3"},
+							Placement:     "emptiest",
+						},
+					},
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "dns record with unknown provider",
+			config: &Config{
+				Proxmox: ProxmoxConfig{
+					Endpoint: "https://test:8006",
+					Username: "root@pam",
+					Password: "pass",
+				},
+				Monitoring: MonitoringConfig{
+					Containers: []ContainerConfig{
+						{
+							ID:            100,
+							HealthChecks:  []HealthCheck{{Type: "tcp", Target: "1.1.1.1", Port: 80}},
+							FailoverNodes: []string{"node2"},
+							DNS:           []DNSRecord{{Hostname: "app.example.com", Provider: "cf"}},
+						},
+					},
+				},
+				DNS: DNSConfig{
+					TTL:            60,
+					AddressTimeout: time.Minute,
+					Providers: []DNSProvider{
+						{Name: "pdns", Type: DNSPowerDNS, URL: "http://pdns:8081", APIKey: "key", Zone: "example.com"},
+					},
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "no containers",
+			config: &Config{
+				Proxmox: ProxmoxConfig{
+					Endpoint: "https://test:8006",
+					Username: "root@pam",
+					Password: "pass",
+				},
+				Monitoring: MonitoringConfig{
+					Containers: []ContainerConfig{},
+				},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate(tt.config)
+			if tt.expectError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
+func TestValidateDependencies(t *testing.T) {
+	container := func(id int, dependsOn ...int) ContainerConfig {
+		return ContainerConfig{
+			ID:           id,
+			HealthChecks: []HealthCheck{{Type: "tcp", Target: "1.1.1.1", Port: 80}},
+			DependsOn:    dependsOn,
+		}
+	}
+
+	tests := []struct {
+		name        string
+		containers  []ContainerConfig
+		expectError bool
+	}{
+		{"no dependencies", []ContainerConfig{container(100), container(101)}, false},
+		{"chain", []ContainerConfig{container(100), container(101, 100), container(102, 101)}, false},
+		{"self dependency", []ContainerConfig{container(100, 100)}, true},
+		{"unmonitored dependency", []ContainerConfig{container(100, 200)}, true},
+		{"cycle", []ContainerConfig{container(100, 102), container(101, 100), container(102, 101)}, true},
+		{
+			"check dependency",
+			[]ContainerConfig{
+				container(100),
+				{ID: 101, HealthChecks: []HealthCheck{{Type: "http", Target: "app", DependsOn: []int{100}}}},
+			},
+			false,
+		},
+		{
+			"check dependency on unmonitored container",
+			[]ContainerConfig{{ID: 101, HealthChecks: []HealthCheck{{Type: "http", Target: "app", DependsOn: []int{300}}}}},
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDependencies(tt.containers)
+			if tt.expectError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestBlackoutWindow_Active(t *testing.T) {
+	window := BlackoutWindow{Schedule: "0 2 * * *", Duration: 30 * time.Minute}
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		expected bool
+	}{
+		{"before window", day.Add(time.Hour + 59*time.Minute), false},
+		{"window start", day.Add(2 * time.Hour), true},
+		{"inside window", day.Add(2*time.Hour + 29*time.Minute), true},
+		{"window end", day.Add(2*time.Hour + 30*time.Minute), false},
+		{"next day", day.Add(26*time.Hour + 10*time.Minute), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := window.Active(tt.at); got != tt.expected {
+				t.Errorf("Expected active=%v at %v, got %v", tt.expected, tt.at, got)
+			}
+		})
+	}
+
+	if (BlackoutWindow{Schedule: "not a schedule", Duration: time.Hour}).Active(day) {
+		t.Error("Expected invalid schedule never to be active")
+	}
+}
+
+func TestConfig_RestartRequired(t *testing.T) {
+	base := Config{
+		Proxmox:    ProxmoxConfig{Endpoint: "https://pve:8006"},
+		Monitoring: MonitoringConfig{Interval: 30 * time.Second, Containers: []ContainerConfig{{ID: 100}}},
+		Failover:   FailoverConfig{Cooldown: 10 * time.Minute, MaxConcurrent: 2},
+		Logging:    LoggingConfig{Level: "info", Format: "json"},
+	}
+
+	tests := []struct {
+		name     string
+		change   func(c *Config)
+		expected []string
+	}{
+		{"unchanged", func(c *Config) {}, nil},
+		{"live tunables", func(c *Config) {
+			c.Monitoring.Interval = time.Minute
+			c.Monitoring.Containers = []ContainerConfig{{ID: 100, BackupSchedule: "0 4 * * *"}, {ID: 101}}
+			c.Failover.Cooldown = time.Hour
+			c.Failover.Drill = DrillConfig{Schedule: "0 3 * * 0", Containers: []int{100}}
+			c.Failover.StandbySyncInterval = time.Hour
+			c.Backup.RetentionDays = 30
+			c.Backup.KeepLast = 5
+			c.Logging.Level = "debug"
+		}, nil},
+		{"restart required", func(c *Config) {
+			c.Proxmox.Endpoint = "https://pve2:8006"
+			c.Failover.MaxConcurrent = 4
+			c.Logging.Format = "text"
+			c.Backup.Replication.Storage = "offsite"
+		}, []string{"proxmox", "backup", "logging.format", "failover.max_concurrent"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := base
+			tt.change(&changed)
+			if got := changed.RestartRequired(&base); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("PROXWARDEN_TEST_PASSWORD", "s3cret")
+	t.Setenv("PROXWARDEN_TEST_EMPTY", "")
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"${PROXWARDEN_TEST_PASSWORD}", "s3cret"},
+		{"pre-${PROXWARDEN_TEST_PASSWORD}-post", "pre-s3cret-post"},
+		{"[${PROXWARDEN_TEST_EMPTY}]", "[]"},
+		{"echo ${PROXWARDEN_TEST_UNSET}", "echo ${PROXWARDEN_TEST_UNSET}"},
+		{"$${PROXWARDEN_TEST_PASSWORD}", "${PROXWARDEN_TEST_PASSWORD}"},
+		{"echo $PROXWARDEN_TEST_PASSWORD $$", "echo $PROXWARDEN_TEST_PASSWORD $$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := expandEnv(tt.input); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLoad_Secrets(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := dir + "/secret"
+	if err := os.WriteFile(secretFile, []byte("token-secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PROXWARDEN_TEST_ENDPOINT", "https://pve:8006")
+	t.Setenv("PROXWARDEN_TEST_SECRETS", dir)
+	t.Setenv("PROXWARDEN_TEST_INTERVAL", "45s")
+
+	configFile := dir + "/proxwarden.yaml"
+	content := `
+proxmox:
+  endpoint: "${PROXWARDEN_TEST_ENDPOINT}"
+  username: "root@pam"
+  token_id: "proxwarden"
+  secret_file: "${PROXWARDEN_TEST_SECRETS}/secret"
+monitoring:
+  interval: ${PROXWARDEN_TEST_INTERVAL}
+  containers:
+    - id: 100
+      health_checks:
+        - type: tcp
+          target: "10.0.0.1"
+          port: 22
+      failover_nodes: ["node2"]
+`
+	if err := os.WriteFile(configFile, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	viper.Reset()
+	viper.SetConfigFile(configFile)
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatalf("Failed to read config file: %v", err)
+	}
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if config.Proxmox.Endpoint != "https://pve:8006" || config.Proxmox.Secret != "token-secret" {
+		t.Errorf("Expected endpoint and secret from the environment and file, got %q and %q", config.Proxmox.Endpoint, config.Proxmox.Secret)
+	}
+	if config.Monitoring.Interval != 45*time.Second {
+		t.Errorf("Expected interval 45s, got %v", config.Monitoring.Interval)
+	}
+
+	viper.Set("proxmox.secret", "inline")
+	if _, err := Load(); err == nil {
+		t.Error("Expected secret and secret_file together to fail")
+	}
+	viper.Set("proxmox.secret", "")
+	viper.Set("proxmox.secret_file", dir+"/missing")
+	if _, err := Load(); err == nil {
+		t.Error("Expected a missing secret_file to fail")
+	}
+}
+
+func TestLoad_Selectors(t *testing.T) {
+	configFile := t.TempDir() + "/proxwarden.yaml"
+	content := `
+proxmox:
+  endpoint: "https://pve:8006"
+  username: "root@pam"
+  password: "secret"
+monitoring:
+  containers:
+    - match:
+        tag: critical
+      health_checks:
+        - type: tcp
+          port: 22
+      failover_nodes: ["node2"]
+    - id: 100
+      health_checks:
+        - type: tcp
+          target: "10.0.0.1"
+          port: 22
+      failover_nodes: ["node2"]
+`
+	if err := os.WriteFile(configFile, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	viper.Reset()
+	viper.SetConfigFile(configFile)
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatalf("Failed to read config file: %v", err)
+	}
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if len(config.Monitoring.Containers) != 1 || config.Monitoring.Containers[0].ID != 100 {
+		t.Fatalf("Expected container 100 to be configured, got %+v", config.Monitoring.Containers)
+	}
+	if len(config.Monitoring.Selectors) != 1 || config.Monitoring.Selectors[0].Match.Tag != "critical" {
+		t.Fatalf("Expected the tag entry to be a selector, got %+v", config.Monitoring.Selectors)
+	}
+	if got := config.Monitoring.ContainerPath(0); got != "monitoring.containers[1]" {
+		t.Errorf("Expected the path of container 100 to be monitoring.containers[1], got %s", got)
+	}
+	if got := config.Monitoring.SelectorPath(0); got != "monitoring.containers[0]" {
+		t.Errorf("Expected the path of the selector to be monitoring.containers[0], got %s", got)
+	}
+
+	tests := []struct {
+		name     string
+		key      string
+		value    interface{}
+		expected string
+	}{
+		{"empty match", "monitoring.containers.0.match", map[string]interface{}{}, "match requires a tag or pool"},
+		{"match with id", "monitoring.containers.0.id", 101, "id and match are mutually exclusive"},
+		{"match with standby", "monitoring.containers.0.standby_id", 102, "standby containers can only be configured by id"},
+		{"match without failover nodes", "monitoring.containers.0.failover_nodes", []string{}, `containers matching tag "critical" must have at least one failover node`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			viper.SetConfigFile(configFile)
+			if err := viper.ReadInConfig(); err != nil {
+				t.Fatalf("Failed to read config file: %v", err)
+			}
+			containers := viper.Get("monitoring.containers").([]interface{})
+			entry := containers[0].(map[string]interface{})
+			key := tt.key[len("monitoring.containers.0."):]
+			entry[key] = tt.value
+			viper.Set("monitoring.containers", containers)
+			if _, err := Load(); err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected error %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestContainerConfig_Inherit(t *testing.T) {
+	defaults := ContainerConfig{
+		HealthChecks:     []HealthCheck{{Type: "tcp", Port: 22}, {Type: "ping", Target: "10.0.0.1"}},
+		FailoverNodes:    []string{"node2", "node3"},
+		Storage:          "local-lvm",
+		Strategy:         StrategyMigrate,
+		FailureThreshold: 5,
+		NodeOverrides:    map[string]NodeOverride{"node3": {Bridge: "vmbr1"}},
+	}
+
+	tests := []struct {
+		name      string
+		container ContainerConfig
+		expected  ContainerConfig
+	}{
+		{
+			name:      "inherits everything",
+			container: ContainerConfig{ID: 100, Name: "web"},
+			expected: ContainerConfig{
+				ID: 100, Name: "web",
+				HealthChecks:     []HealthCheck{{Type: "tcp", Target: "web", Port: 22}, {Type: "ping", Target: "10.0.0.1"}},
+				FailoverNodes:    []string{"node2", "node3"},
+				Storage:          "local-lvm",
+				Strategy:         StrategyMigrate,
+				FailureThreshold: 5,
+				NodeOverrides:    map[string]NodeOverride{"node3": {Bridge: "vmbr1"}},
+			},
+		},
+		{
+			name: "overrides replace defaults",
+			container: ContainerConfig{
+				ID:            101,
+				HealthChecks:  []HealthCheck{{Type: "http", Target: "10.0.0.2"}},
+				FailoverNodes: []string{"node4"},
+				Strategy:      StrategyRestore,
+				DependsOn:     []int{100},
+			},
+			expected: ContainerConfig{
+				ID:               101,
+				HealthChecks:     []HealthCheck{{Type: "http", Target: "10.0.0.2"}},
+				FailoverNodes:    []string{"node4"},
+				Storage:          "local-lvm",
+				Strategy:         StrategyRestore,
+				FailureThreshold: 5,
+				DependsOn:        []int{100},
+				NodeOverrides:    map[string]NodeOverride{"node3": {Bridge: "vmbr1"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.container.Inherit(defaults); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+	if defaults.HealthChecks[0].Target != "" {
+		t.Error("Expected the defaults not to be modified")
+	}
+
+	if err := validateDefaults(ContainerConfig{Storage: "local-lvm", StandbyID: 200}); err == nil {
+		t.Error("Expected standby_id in defaults to fail")
+	}
+	if err := validateDefaults(defaults); err != nil {
+		t.Errorf("Expected defaults to be valid, got %v", err)
+	}
+}
+
+func TestLoad_Include(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(dir+"/conf.d", 0o750); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"proxwarden.yaml": `
+proxmox:
+  endpoint: "https://pve:8006"
+  username: "root@pam"
+  password: "secret"
+include: "conf.d/*.yaml"
+monitoring:
+  defaults:
+    failover_nodes: ["node2"]
+    health_checks:
+      - type: ping
+  containers:
+    - id: 100
+      name: web
+`,
+		"conf.d/b-db.yaml": `
+monitoring:
+  containers:
+    - id: 102
+      name: db
+      failure_threshold: 5
+`,
+		"conf.d/a-cache.yaml": `
+monitoring:
+  containers:
+    - id: 101
+      name: cache
+    - match:
+        tag: cache
+`,
+		"conf.d/notes.txt": "not included",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(dir+"/"+name, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	load := func() (*Config, error) {
+		viper.Reset()
+		viper.SetConfigFile(dir + "/proxwarden.yaml")
+		if err := viper.ReadInConfig(); err != nil {
+			t.Fatalf("Failed to read config file: %v", err)
+		}
+		return Load()
+	}
+
+	config, err := load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	var ids []int
+	for _, container := range config.Monitoring.Containers {
+		ids = append(ids, container.ID)
+	}
+	if !reflect.DeepEqual(ids, []int{100, 101, 102}) {
+		t.Fatalf("Expected containers 100, 101 and 102 in file order, got %v", ids)
+	}
+	if db := config.Monitoring.Containers[2]; db.FailureThreshold != 5 || len(db.FailoverNodes) != 1 {
+		t.Errorf("Expected included container to keep its settings and inherit defaults, got %+v", db)
+	}
+	if len(config.Monitoring.Selectors) != 1 {
+		t.Errorf("Expected the included match entry to be a selector, got %+v", config.Monitoring.Selectors)
+	}
+	if got, expected := config.Monitoring.ContainerPath(2), dir+"/conf.d/b-db.yaml: monitoring.containers[0]"; got != expected {
+		t.Errorf("Expected path %s, got %s", expected, got)
+	}
+
+	if err := os.WriteFile(dir+"/conf.d/c-dup.yaml", []byte("monitoring:\n  containers:\n    - id: 100\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := load(); err == nil || !strings.Contains(err.Error(), "configured twice") {
+		t.Errorf("Expected a container configured twice to fail, got %v", err)
+	}
+
+	if err := os.WriteFile(dir+"/conf.d/c-dup.yaml", []byte("failover:\n  auto_failover: false\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := load(); err == nil || !strings.Contains(err.Error(), "only monitoring.containers") {
+		t.Errorf("Expected other settings in an included file to fail, got %v", err)
+	}
+
+	if err := os.WriteFile(dir+"/conf.d/c-dup.yaml", []byte("monitoring:\n  containers:\n    - id: 103\n      standy_id: 104\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := load(); err == nil || !strings.Contains(err.Error(), "c-dup.yaml: unknown setting monitoring.containers[0].standy_id (did you mean standby_id?)") {
+		t.Errorf("Expected an unknown setting in an included file to fail, got %v", err)
+	}
+}
+
+func TestLoad_UnknownSettings(t *testing.T) {
+	base := `
+proxmox:
+  endpoint: "https://pve:8006"
+  username: "root@pam"
+  password: "secret"
+`
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:    "known",
+			content: "monitoring:\n  containers:\n    - id: 100\n      failover_nodes: [node2]\n      health_checks:\n        - type: ping\n          target: 10.0.0.1\n",
+		},
+		{
+			name:     "misspelled container setting",
+			content:  "monitoring:\n  containers:\n    - id: 100\n      failover_node: [node2]\n",
+			expected: "unknown setting monitoring.containers[0].failover_node (did you mean failover_nodes?)",
+		},
+		{
+			name:     "several",
+			content:  "failover:\n  auto_failovr: false\nnotifcations:\n  enabled: true\n",
+			expected: "unknown settings failover.auto_failovr (did you mean auto_failover?), notifcations (did you mean notifications?)",
+		},
+		{
+			name:     "nothing close",
+			content:  "monitoring:\n  colour: blue\n",
+			expected: "unknown setting monitoring.colour",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configFile := t.TempDir() + "/proxwarden.yaml"
+			if err := os.WriteFile(configFile, []byte(base+tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			viper.Reset()
+			viper.SetConfigFile(configFile)
+			if err := viper.ReadInConfig(); err != nil {
+				t.Fatalf("Failed to read config file: %v", err)
+			}
+			// Bound flags are not settings of the file
+			viper.Set("log-level", "debug")
+
+			_, err := Load()
+			if tt.expected == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("Expected error %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestLoad_FailoverToggles(t *testing.T) {
+	configFile := t.TempDir() + "/proxwarden.yaml"
+	content := `
+proxmox:
+  endpoint: "https://pve:8006"
+  username: "root@pam"
+  password: "secret"
+monitoring:
+  defaults:
+    auto_failover: false
+    failover_nodes: ["node2"]
+    health_checks:
+      - type: ping
+  containers:
+    - id: 100
+      name: db
+      auto_failover: true
+      require_approval: true
+    - id: 101
+      name: experimental
+`
+	if err := os.WriteFile(configFile, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	viper.Reset()
+	viper.SetConfigFile(configFile)
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatalf("Failed to read config file: %v", err)
+	}
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	db, experimental := config.Monitoring.Containers[0], config.Monitoring.Containers[1]
+	if db.AutoFailover == nil || !*db.AutoFailover || db.RequireApproval == nil || !*db.RequireApproval {
+		t.Errorf("Expected container 100 to fail over with approval, got %v and %v", db.AutoFailover, db.RequireApproval)
+	}
+	if experimental.AutoFailover == nil || *experimental.AutoFailover || experimental.RequireApproval != nil {
+		t.Errorf("Expected container 101 to inherit auto_failover false only, got %v and %v", experimental.AutoFailover, experimental.RequireApproval)
+	}
+
+	// Approval settings apply although approval is not enabled globally
+	viper.Set("failover.approval.timeout", "0s")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "approval timeout") {
+		t.Errorf("Expected a zero approval timeout to fail, got %v", err)
+	}
+}
+
+func TestEncryptFile(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := dir + "/proxwarden.key"
+	encoded, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := StoreKey(encoded, keyFile, ""); err != nil {
+		t.Fatalf("StoreKey failed: %v", err)
+	}
+	if err := StoreKey(encoded, keyFile, ""); err == nil {
+		t.Error("Expected storing over an existing key file to fail")
+	}
+	key, err := ReadKey(keyFile, "")
+	if err != nil {
+		t.Fatalf("ReadKey failed: %v", err)
+	}
+
+	configFile := dir + "/proxwarden.yaml"
+	content := `# Cluster access
+proxmox:
+  endpoint: "https://pve:8006"
+  username: "root@pam"
+  token_id: "proxwarden"
+  password: "${PROXWARDEN_TEST_UNSET}"
+  secret: "token-secret" # rotated yearly
+monitoring:
+  containers:
+    - id: 100
+      failover_nodes: ["node2"]
+      health_checks:
+        - type: ping
+          target: "10.0.0.1"
+`
+	if err := os.WriteFile(configFile, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	encrypted, err := EncryptFile(configFile, key, keyFile, "")
+	if err != nil {
+		t.Fatalf("EncryptFile failed: %v", err)
+	}
+	if !reflect.DeepEqual(encrypted, []string{"proxmox.secret"}) {
+		t.Errorf("Expected only the secret to be encrypted, got %v", encrypted)
+	}
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := string(data)
+	if strings.Contains(written, "token-secret") || !strings.Contains(written, "# rotated yearly") || !strings.Contains(written, "key_file: \""+keyFile+"\"") {
+		t.Errorf("Expected the secret encrypted, comments kept and the key file recorded, got:\n%s", written)
+	}
+	if encrypted, err := EncryptFile(configFile, key, keyFile, ""); err != nil || len(encrypted) != 0 {
+		t.Errorf("Expected nothing left to encrypt, got %v (%v)", encrypted, err)
+	}
+
+	viper.Reset()
+	viper.SetConfigFile(configFile)
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatalf("Failed to read config file: %v", err)
+	}
+	viper.Set("proxmox.password", "")
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if config.Proxmox.Secret != "token-secret" {
+		t.Errorf("Expected the secret to be decrypted, got %q", config.Proxmox.Secret)
+	}
+
+	other, _ := GenerateKey()
+	if err := os.WriteFile(keyFile, []byte(other), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "wrong key") {
+		t.Errorf("Expected decrypting with another key to fail, got %v", err)
+	}
+}
+
+func TestLoad_Remote(t *testing.T) {
+	remote := `
+monitoring:
+  containers:
+    - id: 100
+      failover_nodes: ["node2"]
+      health_checks:
+        - type: ping
+          target: "10.0.0.1"
+`
+	var index uint64 = 7
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/kv/proxwarden/cluster-a" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode([]map[string]interface{}{{"Value": []byte(remote), "ModifyIndex": index}})
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	configFile := dir + "/proxwarden.yaml"
+	content := `
+proxmox:
+  endpoint: "https://pve:8006"
+  username: "root@pam"
+  password: "secret"
+data_dir: "` + dir + `"
+remote:
+  backend: consul
+  endpoint: "` + server.URL + `"
+  key: proxwarden/cluster-a
+`
+	if err := os.WriteFile(configFile, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	load := func() (*Config, error) {
+		viper.Reset()
+		viper.SetConfigFile(configFile)
+		if err := viper.ReadInConfig(); err != nil {
+			t.Fatalf("Failed to read config file: %v", err)
+		}
+		return Load()
+	}
+
+	config, err := load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if len(config.Monitoring.Containers) != 1 || config.Proxmox.Endpoint != "https://pve:8006" {
+		t.Errorf("Expected the remote containers over the file's settings, got %+v", config)
+	}
+	if config.RemoteState.Source != RemoteConsul || config.RemoteState.Version != 7 || config.RemoteState.Error != nil {
+		t.Errorf("Expected version 7 read from consul, got %+v", config.RemoteState)
+	}
+	if config.Remote.CacheFile != dir+"/remote-config.yaml" {
+		t.Errorf("Expected the cache file in the data directory, got %s", config.Remote.CacheFile)
+	}
+
+	remote = "remote:\n  backend: etcd\n"
+	if _, err := load(); err == nil || !strings.Contains(err.Error(), "only the configuration file can set remote") {
+		t.Errorf("Expected remote settings setting remote to fail, got %v", err)
+	}
+
+	// The backend is down: the last settings read from it still apply
+	server.Close()
+	config, err = load()
+	if err != nil {
+		t.Fatalf("Failed to load config from the cache: %v", err)
+	}
+	if config.RemoteState.Source != "cache" || config.RemoteState.Error == nil || len(config.Monitoring.Containers) != 1 {
+		t.Errorf("Expected the cached containers and the backend error, got %+v", config.RemoteState)
+	}
+
+	if err := os.Remove(config.Remote.CacheFile); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := load(); err == nil || !strings.Contains(err.Error(), "remote config unavailable") {
+		t.Errorf("Expected an incomplete file without remote settings to fail, got %v", err)
+	}
+}
+
+func TestValidateServerAuth(t *testing.T) {
+	tls := ServerTLSConfig{CertFile: "server.pem", KeyFile: "server-key.pem", ClientCAFile: "ca.pem"}
+	tests := []struct {
+		name        string
+		server      ServerConfig
+		expectError bool
+	}{
+		{
+			name: "keys and certificates",
+			server: ServerConfig{
+				TLS: tls,
+				Auth: ServerAuthConfig{
+					APIKeys: []APIKeyConfig{
+						{Name: "grafana", Key: "read-key", Role: RoleReadOnly},
+						{Name: "ops", Key: "ops-key", Role: RoleOperator},
+					},
+					ClientCerts: []ClientCertConfig{{CommonName: "admin.example.com", Role: RoleAdmin}},
+				},
+			},
+		},
+		{
+			name:        "invalid role",
+			server:      ServerConfig{Auth: ServerAuthConfig{APIKeys: []APIKeyConfig{{Name: "ops", Key: "ops-key", Role: "root"}}}},
+			expectError: true,
+		},
+		{
+			name: "duplicate key",
+			server: ServerConfig{Auth: ServerAuthConfig{APIKeys: []APIKeyConfig{
+				{Name: "a", Key: "same", Role: RoleReadOnly},
+				{Name: "b", Key: "same", Role: RoleAdmin},
+			}}},
+			expectError: true,
+		},
+		{
+			name:        "missing key",
+			server:      ServerConfig{Auth: ServerAuthConfig{APIKeys: []APIKeyConfig{{Name: "ops", Role: RoleOperator}}}},
+			expectError: true,
+		},
+		{
+			name: "client certificates without client CA",
+			server: ServerConfig{
+				TLS:  ServerTLSConfig{CertFile: "server.pem", KeyFile: "server-key.pem"},
+				Auth: ServerAuthConfig{ClientCerts: []ClientCertConfig{{CommonName: "ops", Role: RoleOperator}}},
+			},
+			expectError: true,
+		},
+		{
+			name:        "certificate without key",
+			server:      ServerConfig{TLS: ServerTLSConfig{CertFile: "server.pem"}},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateServerAuth(&tt.server)
+			if tt.expectError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+
+	if !RoleAllows(RoleAdmin, RoleOperator) || RoleAllows(RoleReadOnly, RoleOperator) || RoleAllows("", RoleReadOnly) {
+		t.Error("Expected roles to allow what lesser roles are allowed and nothing more")
+	}
+}
+
+func TestValidateLeaderElection(t *testing.T) {
+	tests := []struct {
+		name        string
+		election    LeaderElectionConfig
+		expectError bool
+	}{
+		{name: "disabled", election: LeaderElectionConfig{}},
+		{
+			name:     "etcd",
+			election: LeaderElectionConfig{Enabled: true, Backend: RemoteEtcd, Endpoint: "http://127.0.0.1:2379", Key: "/proxwarden/leader", TTL: 5 * time.Second},
+		},
+		{
+			name:        "consul ttl below minimum",
+			election:    LeaderElectionConfig{Enabled: true, Backend: RemoteConsul, Endpoint: "http://127.0.0.1:8500", Key: "proxwarden/leader", TTL: 5 * time.Second},
+			expectError: true,
+		},
+		{
+			name:        "missing key",
+			election:    LeaderElectionConfig{Enabled: true, Backend: RemoteConsul, Endpoint: "http://127.0.0.1:8500", TTL: 15 * time.Second},
+			expectError: true,
+		},
+		{
+			name:        "unknown backend",
+			election:    LeaderElectionConfig{Enabled: true, Backend: "zookeeper", Endpoint: "127.0.0.1:2181", Key: "leader", TTL: 15 * time.Second},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateLeaderElection(&tt.election)
+			if tt.expectError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateStore(t *testing.T) {
+	tests := []struct {
+		name        string
+		store       StoreConfig
+		expectError bool
+	}{
+		{name: "default", store: StoreConfig{}},
+		{name: "file", store: StoreConfig{Backend: StoreFile}},
+		{name: "bolt", store: StoreConfig{Backend: StoreBolt, File: "/var/lib/proxwarden/state.db"}},
+		{name: "unknown backend", store: StoreConfig{Backend: "sqlite"}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateStore(&tt.store)
+			if tt.expectError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidatePlugins(t *testing.T) {
+	base := func() *Config {
+		return &Config{
+			Proxmox: ProxmoxConfig{
+				Endpoint: "https://test:8006",
+				Username: "root@pam",
+				Password: "pass",
+			},
+			Monitoring: MonitoringConfig{
+				Containers: []ContainerConfig{
+					{ID: 100, FailoverNodes: []string{"node2", "node3"}, HealthChecks: []HealthCheck{{Type: "tcp", Target: "10.0.0.1", Port: 80}}},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		modify      func(c *Config)
+		expectError bool
+	}{
+		{
+			name: "placement plugin",
+			modify: func(c *Config) {
+				c.Failover.Placement = PlacementPlugin
+				c.Failover.PlacementPlugin = Plugin{Command: "/usr/local/bin/place"}
+			},
+		},
+		{
+			name:        "placement plugin without command",
+			modify:      func(c *Config) { c.Failover.Placement = PlacementPlugin },
+			expectError: true,
+		},
+		{
+			name:        "container placement plugin without command",
+			modify:      func(c *Config) { c.Monitoring.Containers[0].Placement = PlacementPlugin },
+			expectError: true,
+		},
+		{
+			name: "fencing plugins only",
+			modify: func(c *Config) {
+				c.Failover.Fencing = FencingConfig{Enabled: true, Timeout: time.Minute, Plugins: []Plugin{{Command: "/usr/local/bin/fence"}}}
+			},
+		},
+		{
+			name: "fencing plugin with negative timeout",
+			modify: func(c *Config) {
+				c.Failover.Fencing = FencingConfig{Enabled: true, Timeout: time.Minute, Plugins: []Plugin{{Command: "/usr/local/bin/fence", Timeout: -time.Second}}}
+			},
+			expectError: true,
+		},
+		{
+			name: "notification plugin",
+			modify: func(c *Config) {
+				c.Notifications.Providers = []NotificationProvider{{Name: "pager", Type: "plugin", Command: "/usr/local/bin/page"}}
+			},
+		},
+		{
+			name: "notification plugin without command",
+			modify: func(c *Config) {
+				c.Notifications.Providers = []NotificationProvider{{Name: "pager", Type: "plugin"}}
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := base()
+			tt.modify(config)
+			err := validate(config)
+			if tt.expectError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateNotificationProviders(t *testing.T) {
+	tests := []struct {
+		name        string
+		provider    NotificationProvider
+		expectError bool
+	}{
+		{name: "webhook", provider: NotificationProvider{Name: "ops", Type: "webhook", URL: "https://hooks.example.com"}},
+		{name: "webhook without url", provider: NotificationProvider{Name: "ops", Type: "webhook"}, expectError: true},
+		{name: "pagerduty", provider: NotificationProvider{Name: "oncall", Type: "pagerduty", RoutingKey: "key"}},
+		{name: "pagerduty without routing key", provider: NotificationProvider{Name: "oncall", Type: "pagerduty"}, expectError: true},
+		{name: "opsgenie", provider: NotificationProvider{Name: "ops", Type: "opsgenie", APIKey: "key"}},
+		{name: "opsgenie without api key", provider: NotificationProvider{Name: "ops", Type: "opsgenie"}, expectError: true},
+		{name: "telegram", provider: NotificationProvider{Name: "chat", Type: "telegram", BotToken: "token", ChatID: "-100"}},
+		{name: "telegram without chat", provider: NotificationProvider{Name: "chat", Type: "telegram", BotToken: "token"}, expectError: true},
+		{name: "discord without url", provider: NotificationProvider{Name: "chat", Type: "discord"}, expectError: true},
+		{name: "teams without url", provider: NotificationProvider{Name: "chat", Type: "teams"}, expectError: true},
+		{name: "ntfy", provider: NotificationProvider{Name: "push", Type: "ntfy", Topic: "proxwarden"}},
+		{name: "ntfy without topic", provider: NotificationProvider{Name: "push", Type: "ntfy"}, expectError: true},
+		{name: "gotify without token", provider: NotificationProvider{Name: "push", Type: "gotify", URL: "https://gotify.example.com"}, expectError: true},
+		{name: "template", provider: NotificationProvider{Name: "ops", Type: "webhook", URL: "https://hooks.example.com", Template: NotificationTemplate{Message: "{{.ContainerName}} on {{.Node}}"}}},
+		{name: "invalid template", provider: NotificationProvider{Name: "ops", Type: "webhook", URL: "https://hooks.example.com", Template: NotificationTemplate{Title: "{{if .Node}}"}}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				Proxmox: ProxmoxConfig{
+					Endpoint: "https://test:8006",
+					Username: "root@pam",
+					Password: "pass",
+				},
+				Monitoring: MonitoringConfig{
+					Containers: []ContainerConfig{
+						{ID: 100, FailoverNodes: []string{"node2"}, HealthChecks: []HealthCheck{{Type: "tcp", Target: "10.0.0.1", Port: 80}}},
+					},
+				},
+				Notifications: NotificationsConfig{Providers: []NotificationProvider{tt.provider}},
+			}
+			err := validate(config)
+			if tt.expectError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateNotificationRoutes(t *testing.T) {
+	providers := []NotificationProvider{{Name: "oncall", Type: "webhook", URL: "https://hooks.example.com"}}
+	tests := []struct {
+		name          string
+		notifications NotificationsConfig
+		expectError   bool
+	}{
+		{name: "no routes", notifications: NotificationsConfig{Providers: providers}},
+		{
+			name: "route",
+			notifications: NotificationsConfig{
+				Providers:   providers,
+				Routes:      []NotificationRoute{{Providers: []string{"oncall"}, Severities: []string{"critical"}, Containers: []int{100}}},
+				DedupWindow: 5 * time.Minute,
+				Throttle:    NotificationThrottle{Interval: 30 * time.Minute},
+			},
+		},
+		{
+			name:          "route without providers",
+			notifications: NotificationsConfig{Providers: providers, Routes: []NotificationRoute{{Events: []string{"node_failed"}}}},
+			expectError:   true,
+		},
+		{
+			name:          "route to unknown provider",
+			notifications: NotificationsConfig{Providers: providers, Routes: []NotificationRoute{{Providers: []string{"pager"}}}},
+			expectError:   true,
+		},
+		{
+			name:          "invalid severity",
+			notifications: NotificationsConfig{Providers: providers, Routes: []NotificationRoute{{Providers: []string{"oncall"}, Severities: []string{"fatal"}}}},
+			expectError:   true,
+		},
+		{
+			name:          "negative throttle",
+			notifications: NotificationsConfig{Providers: providers, Throttle: NotificationThrottle{Interval: -time.Minute}},
+			expectError:   true,
+		},
+		{
+			name: "escalation",
+			notifications: NotificationsConfig{Providers: providers, Escalations: []NotificationEscalation{{
+				Name:       "failures",
+				Severities: []string{"critical"},
+				Steps:      []EscalationStep{{Providers: []string{"oncall"}}, {After: 15 * time.Minute, Providers: []string{"oncall"}}},
+			}}},
+		},
+		{
+			name:          "escalation without steps",
+			notifications: NotificationsConfig{Providers: providers, Escalations: []NotificationEscalation{{Name: "failures"}}},
+			expectError:   true,
+		},
+		{
+			name: "escalation to unknown provider",
+			notifications: NotificationsConfig{Providers: providers, Escalations: []NotificationEscalation{{
+				Name:  "failures",
+				Steps: []EscalationStep{{Providers: []string{"pager"}}},
+			}}},
+			expectError: true,
+		},
+		{
+			name: "escalation steps out of order",
+			notifications: NotificationsConfig{Providers: providers, Escalations: []NotificationEscalation{{
+				Name:  "failures",
+				Steps: []EscalationStep{{After: 30 * time.Minute, Providers: []string{"oncall"}}, {After: 15 * time.Minute, Providers: []string{"oncall"}}},
+			}}},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateNotificationRoutes(&tt.notifications)
+			if tt.expectError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// containerOnly are the container settings that only make sense for a
+// single container, so monitoring defaults cannot set them.
+var containerOnly = map[string]bool{
+	"id":            true,
+	"name":          true,
+	"match":         true,
+	"standby_id":    true,
+	"standby_sync":  true,
+	"dns":           true,
+	"floating_ip":   true,
+	"depends_on":    true,
+	"anti_affinity": true,
+}
+
+// Inherit returns the container with the settings it leaves unset taken
+// from defaults. A setting the container sets, a list included, replaces
+// the default entirely. Inherited health checks without a target check the
+// container's name.
+func (c ContainerConfig) Inherit(defaults ContainerConfig) ContainerConfig {
+	inherited := c
+	value := reflect.ValueOf(&inherited).Elem()
+	fallback := reflect.ValueOf(defaults)
+	for i := 0; i < value.NumField(); i++ {
+		if containerOnly[yamlName(value.Type().Field(i))] || !unset(value.Field(i)) {
+			continue
+		}
+		value.Field(i).Set(fallback.Field(i))
+	}
+
+	if len(c.HealthChecks) == 0 && len(defaults.HealthChecks) > 0 {
+		inherited.HealthChecks = make([]HealthCheck, len(defaults.HealthChecks))
+		copy(inherited.HealthChecks, defaults.HealthChecks)
+		for i := range inherited.HealthChecks {
+			if inherited.HealthChecks[i].Target == "" {
+				inherited.HealthChecks[i].Target = c.Name
+			}
+		}
+	}
+	return inherited
+}
+
+// applyDefaults lets the configured containers and selectors inherit the
+// monitoring defaults.
+func (m *MonitoringConfig) applyDefaults() {
+	for i := range m.Containers {
+		m.Containers[i] = m.Containers[i].Inherit(m.Defaults)
+	}
+	for i := range m.Selectors {
+		m.Selectors[i] = m.Selectors[i].Inherit(m.Defaults)
+	}
+}
+
+func validateDefaults(defaults ContainerConfig) error {
+	value := reflect.ValueOf(defaults)
+	for i := 0; i < value.NumField(); i++ {
+		name := yamlName(value.Type().Field(i))
+		if containerOnly[name] && !unset(value.Field(i)) {
+			return fmt.Errorf("monitoring defaults: %s can only be set per container", name)
+		}
+	}
+	return nil
+}
+
+// unset reports whether a setting was left out, taking empty lists and
+// maps as left out.
+func unset(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	}
+	return value.IsZero()
+}
+
+func yamlName(field reflect.StructField) string {
+	return strings.Split(field.Tag.Get("yaml"), ",")[0]
+}
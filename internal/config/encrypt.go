@@ -0,0 +1,253 @@
+package config
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// encryptedPrefix marks an encrypted setting value.
+const encryptedPrefix = "enc:"
+
+// keySize is the size of the AES-256 key encrypting settings.
+const keySize = 32
+
+// IsEncrypted reports whether a setting value is encrypted.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// GenerateKey returns a new random key, encoded as key files and keyrings
+// hold it.
+func GenerateKey() (string, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// ReadKey reads the key encrypting settings from keyFile or, when keyring
+// is set instead, from the user keyring of the kernel.
+func ReadKey(keyFile, keyring string) ([]byte, error) {
+	var encoded string
+	switch {
+	case keyFile != "" && keyring != "":
+		return nil, fmt.Errorf("proxmox key_file and keyring are mutually exclusive")
+	case keyFile != "":
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key_file: %w", err)
+		}
+		encoded = string(data)
+	case keyring != "":
+		payload, err := readKeyring(keyring)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key %q from the keyring: %w", keyring, err)
+		}
+		encoded = payload
+	default:
+		return nil, fmt.Errorf("neither proxmox key_file nor keyring is set")
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("key is not %d base64-encoded bytes", keySize)
+	}
+	return key, nil
+}
+
+// StoreKey writes a generated key to keyFile, which must not exist yet, or
+// adds it to the user keyring of the kernel.
+func StoreKey(encoded, keyFile, keyring string) error {
+	if keyring != "" {
+		return addKeyring(keyring, encoded)
+	}
+	file, err := os.OpenFile(keyFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create key_file: %w", err)
+	}
+	if _, err := file.WriteString(encoded + "\n"); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write key_file: %w", err)
+	}
+	return file.Close()
+}
+
+// EncryptSetting encrypts value with AES-256-GCM.
+func EncryptSetting(key []byte, value string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func decryptSetting(key []byte, value string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", errors.New("malformed encrypted value")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("wrong key or corrupted value")
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// decryptSecrets decrypts the Proxmox password and token secret when they
+// are encrypted, reading the key only then.
+func decryptSecrets(proxmox *ProxmoxConfig) error {
+	secrets := []struct {
+		name  string
+		value *string
+	}{
+		{"password", &proxmox.Password},
+		{"secret", &proxmox.Secret},
+	}
+
+	var key []byte
+	for _, secret := range secrets {
+		if !IsEncrypted(*secret.value) {
+			continue
+		}
+		if key == nil {
+			var err error
+			if key, err = ReadKey(proxmox.KeyFile, proxmox.Keyring); err != nil {
+				return fmt.Errorf("proxmox %s is encrypted: %w", secret.name, err)
+			}
+		}
+		value, err := decryptSetting(key, *secret.value)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt proxmox %s: %w", secret.name, err)
+		}
+		*secret.value = value
+	}
+	return nil
+}
+
+// EncryptFile encrypts the plaintext Proxmox password and token secret of
+// a configuration file in place, recording where the key is kept, and
+// returns the paths of the settings it encrypted. Values referring to
+// environment variables are left as they are, as are comments, though the
+// file is reindented.
+func EncryptFile(path string, key []byte, keyFile, keyring string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config file has no settings")
+	}
+	proxmox := mappingValue(document.Content[0], "proxmox")
+	if proxmox == nil || proxmox.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config file has no proxmox settings")
+	}
+
+	var encrypted []string
+	for _, name := range []string{"password", "secret"} {
+		node := mappingValue(proxmox, name)
+		if node == nil || node.Kind != yaml.ScalarNode || node.Value == "" || IsEncrypted(node.Value) || envReference.MatchString(node.Value) {
+			continue
+		}
+		value, err := EncryptSetting(key, node.Value)
+		if err != nil {
+			return nil, err
+		}
+		node.Value = value
+		node.Style = yaml.DoubleQuotedStyle
+		encrypted = append(encrypted, "proxmox."+name)
+	}
+	if len(encrypted) == 0 {
+		return nil, nil
+	}
+	name, value, other := "key_file", keyFile, "keyring"
+	if keyring != "" {
+		name, value, other = "keyring", keyring, "key_file"
+	}
+	if mappingValue(proxmox, other) != nil {
+		return nil, fmt.Errorf("config file sets proxmox %s, encrypt with that key instead", other)
+	}
+	if node := mappingValue(proxmox, name); node != nil {
+		node.Value = value
+	} else {
+		proxmox.Content = append(proxmox.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value, Style: yaml.DoubleQuotedStyle})
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return nil, fmt.Errorf("failed to encode config file: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode config file: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to write config file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, fmt.Errorf("failed to write config file: %w", err)
+	}
+	return encrypted, nil
+}
+
+// mappingValue returns the value of key in a YAML mapping, or nil.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+// includeContainers appends the containers of the files matching the
+// include patterns to Containers, in file name order, and returns the path
+// of every entry of Containers.
+func includeContainers(config *Config) ([]string, error) {
+	paths := make([]string, 0, len(config.Monitoring.Containers))
+	for i := range config.Monitoring.Containers {
+		paths = append(paths, containerPath("", i))
+	}
+
+	for _, pattern := range config.IncludePatterns() {
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include pattern %q: %w", pattern, err)
+		}
+		for _, file := range files {
+			containers, err := readInclude(file)
+			if err != nil {
+				return nil, err
+			}
+			for i, container := range containers {
+				config.Monitoring.Containers = append(config.Monitoring.Containers, container)
+				paths = append(paths, containerPath(file, i))
+			}
+		}
+	}
+	return paths, nil
+}
+
+// IncludePatterns returns the include patterns, with relative ones made
+// relative to the directory of the configuration file.
+func (c *Config) IncludePatterns() []string {
+	dir := filepath.Dir(viper.ConfigFileUsed())
+	patterns := make([]string, 0, len(c.Include))
+	for _, pattern := range c.Include {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns
+}
+
+// readInclude reads the containers of an included file, which must not set
+// anything else.
+func readInclude(file string) ([]ContainerConfig, error) {
+	v := viper.New()
+	v.SetConfigFile(file)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read included %s: %w", file, err)
+	}
+	for _, key := range v.AllKeys() {
+		if key != "monitoring.containers" {
+			return nil, fmt.Errorf("included %s: only monitoring.containers can be set, not %s", file, key)
+		}
+	}
+
+	var containers []ContainerConfig
+	var metadata mapstructure.Metadata
+	if err := v.UnmarshalKey("monitoring.containers", &containers, decoderOptions, withMetadata(&metadata)); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal included %s: %w", file, err)
+	}
+	if err := unknownSettings(metadata.Unused, "monitoring.containers", nil); err != nil {
+		return nil, fmt.Errorf("included %s: %w", file, err)
+	}
+	return containers, nil
+}
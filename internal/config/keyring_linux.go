@@ -0,0 +1,42 @@
+//go:build linux
+
+package config
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// readKeyring reads a "user" key of the user keyring, such as one added
+// with keyctl add user <name> <key> @u.
+func readKeyring(name string) (string, error) {
+	id, err := unix.KeyctlSearch(unix.KEY_SPEC_USER_KEYRING, "user", name, 0)
+	if err != nil {
+		return "", err
+	}
+	size, err := unix.KeyctlBuffer(unix.KEYCTL_READ, id, nil, 0)
+	if err != nil {
+		return "", err
+	}
+	payload := make([]byte, size)
+	if _, err := unix.KeyctlBuffer(unix.KEYCTL_READ, id, payload, 0); err != nil {
+		return "", err
+	}
+	return string(payload), nil
+}
+
+// addKeyring adds a "user" key to the user keyring, readable by every
+// process of the user: services such as the daemon do not possess the user
+// keyring.
+func addKeyring(name, payload string) error {
+	id, err := unix.AddKey("user", name, []byte(payload), unix.KEY_SPEC_USER_KEYRING)
+	if err != nil {
+		return fmt.Errorf("failed to add key %q to the keyring: %w", name, err)
+	}
+	const possessorAll, userRead = 0x3f000000, 0x000b0000
+	if err := unix.KeyctlSetperm(id, possessorAll|userRead); err != nil {
+		return fmt.Errorf("failed to set permissions of key %q: %w", name, err)
+	}
+	return nil
+}
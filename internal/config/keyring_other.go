@@ -0,0 +1,13 @@
+//go:build !linux
+
+package config
+
+import "errors"
+
+func readKeyring(name string) (string, error) {
+	return "", errors.New("the kernel keyring is only supported on Linux")
+}
+
+func addKeyring(name, payload string) error {
+	return errors.New("the kernel keyring is only supported on Linux")
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/kv"
+)
+
+// minConsulTTL is the shortest session TTL Consul accepts.
+const minConsulTTL = 10 * time.Second
+
+// LeaderElectionConfig lets several daemons watch the same cluster, only
+// the one holding a lock in Consul or etcd acting on it.
+type LeaderElectionConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Backend  string `yaml:"backend"`
+	Endpoint string `yaml:"endpoint"`
+	Key      string `yaml:"key"`
+	// Token is a Consul ACL token; Username and Password authenticate to
+	// etcd
+	Token    string `yaml:"token,omitempty"`
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	// TTL is how long the lock outlives a leader that stopped renewing it
+	TTL time.Duration `yaml:"ttl"`
+	// Identity names this daemon in the lock; empty uses the hostname
+	Identity string `yaml:"identity,omitempty"`
+}
+
+// Lock returns the lock of the election, held as identity.
+func (l LeaderElectionConfig) Lock(identity string) (kv.Lock, error) {
+	switch l.Backend {
+	case RemoteConsul:
+		return kv.NewConsul(l.Endpoint, l.Token).Lock(l.Key, identity, l.TTL), nil
+	case RemoteEtcd:
+		return kv.NewEtcd(l.Endpoint, l.Username, l.Password).Lock(l.Key, identity, l.TTL), nil
+	default:
+		return nil, fmt.Errorf("unknown leader election backend %q, expected %s or %s", l.Backend, RemoteConsul, RemoteEtcd)
+	}
+}
+
+func validateLeaderElection(election *LeaderElectionConfig) error {
+	if !election.Enabled {
+		return nil
+	}
+	if election.Backend != RemoteConsul && election.Backend != RemoteEtcd {
+		return fmt.Errorf("leader_election backend must be %s or %s", RemoteConsul, RemoteEtcd)
+	}
+	if election.Endpoint == "" || election.Key == "" {
+		return fmt.Errorf("leader_election endpoint and key are required")
+	}
+	if election.TTL < 3*time.Second {
+		return fmt.Errorf("leader_election ttl must be at least 3s")
+	}
+	if election.Backend == RemoteConsul && election.TTL < minConsulTTL {
+		return fmt.Errorf("leader_election ttl must be at least %s with consul", minConsulTTL)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Plugin is an external executable extending ProxWarden through the plugin
+// protocol: it reads a JSON request on stdin and writes a JSON response to
+// stdout.
+type Plugin struct {
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args,omitempty"`
+	// Timeout bounds each run, after which the plugin is killed; 0 uses
+	// 10s
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+func validatePlugin(label string, plugin Plugin) error {
+	if plugin.Command == "" {
+		return fmt.Errorf("%s: command is required", label)
+	}
+	if plugin.Timeout < 0 {
+		return fmt.Errorf("%s: timeout must not be negative", label)
+	}
+	return nil
+}
+
+// usesPlacementPlugin reports whether the failover placement or that of a
+// container is PlacementPlugin.
+func (c *Config) usesPlacementPlugin() bool {
+	if c.Failover.Placement == PlacementPlugin || c.Monitoring.Defaults.Placement == PlacementPlugin {
+		return true
+	}
+	for _, container := range c.Monitoring.Containers {
+		if container.Placement == PlacementPlugin {
+			return true
+		}
+	}
+	for _, selector := range c.Monitoring.Selectors {
+		if selector.Placement == PlacementPlugin {
+			return true
+		}
+	}
+	return false
+}
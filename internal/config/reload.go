@@ -0,0 +1,62 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/spf13/viper"
+)
+
+// Reload reads the configuration file again and loads it.
+func Reload() (*Config, error) {
+	if err := viper.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) {
+			return nil, fmt.Errorf("failed to read config: %w", err)
+		}
+	}
+	return Load()
+}
+
+// RestartRequired lists the settings that differ from previous but only
+// take effect when the daemon restarts.
+func (c *Config) RestartRequired(previous *Config) []string {
+	// Pruning reads its retention again every time it runs
+	backup := c.Backup
+	backup.RetentionDays, backup.KeepLast = previous.Backup.RetentionDays, previous.Backup.KeepLast
+
+	sections := []struct {
+		path       string
+		now, prior interface{}
+	}{
+		{"proxmox", c.Proxmox, previous.Proxmox},
+		{"backup", backup, previous.Backup},
+		{"server", c.Server, previous.Server},
+		{"notifications", c.Notifications, previous.Notifications},
+		{"maintenance", c.Maintenance, previous.Maintenance},
+		{"dns", c.DNS, previous.DNS},
+		{"data_dir", c.DataDir, previous.DataDir},
+		{"store", c.Store, previous.Store},
+		{"logging.format", c.Logging.Format, previous.Logging.Format},
+		{"monitoring.events", c.Monitoring.Events, previous.Monitoring.Events},
+		{"monitoring.nodes", c.Monitoring.Nodes, previous.Monitoring.Nodes},
+		{"monitoring.discover", c.Monitoring.Discover, previous.Monitoring.Discover},
+		{"monitoring.containers[].match", c.Monitoring.Discovers(), previous.Monitoring.Discovers()},
+		{"monitoring.witnesses", c.Monitoring.Witnesses, previous.Monitoring.Witnesses},
+		{"monitoring.proxy", c.Monitoring.Proxy, previous.Monitoring.Proxy},
+		{"failover.max_concurrent", c.Failover.MaxConcurrent, previous.Failover.MaxConcurrent},
+		{"failover.failback.enabled", c.Failover.Failback.Enabled, previous.Failover.Failback.Enabled},
+		{"watch_config", c.WatchConfig, previous.WatchConfig},
+		{"remote", c.Remote, previous.Remote},
+		{"leader_election", c.LeaderElection, previous.LeaderElection},
+	}
+
+	var changed []string
+	for _, section := range sections {
+		if !reflect.DeepEqual(section.now, section.prior) {
+			changed = append(changed, section.path)
+		}
+	}
+	return changed
+}
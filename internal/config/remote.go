@@ -0,0 +1,168 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/kv"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// Remote configuration backends.
+const (
+	RemoteConsul = "consul"
+	RemoteEtcd   = "etcd"
+)
+
+// RemoteConfig reads settings from a key of Consul or etcd, holding a YAML
+// document like the configuration file, over those of the configuration
+// file. Only the configuration file can set it.
+type RemoteConfig struct {
+	Backend  string `yaml:"backend"`
+	Endpoint string `yaml:"endpoint"`
+	Key      string `yaml:"key"`
+	// Token is a Consul ACL token; Username and Password authenticate to
+	// etcd
+	Token    string        `yaml:"token,omitempty"`
+	Username string        `yaml:"username,omitempty"`
+	Password string        `yaml:"password,omitempty"`
+	Timeout  time.Duration `yaml:"timeout"`
+	// PollInterval is how often the daemon checks the key for changes; 0
+	// only reads it on start and reload
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// CacheFile keeps the last settings read from the backend, which are
+	// used while it is unavailable
+	CacheFile string `yaml:"cache_file"`
+}
+
+// RemoteState is where the remote settings of a loaded configuration came
+// from.
+type RemoteState struct {
+	// Source is the backend, "cache" for the cache file, or empty when
+	// neither could be read and only the configuration file applies
+	Source string
+	// Version is the version of the key read from the backend
+	Version uint64
+	// Error is why the backend could not be read
+	Error error
+}
+
+// Store returns the key-value store of the backend.
+func (r RemoteConfig) Store() (kv.Store, error) {
+	switch r.Backend {
+	case RemoteConsul:
+		return kv.NewConsul(r.Endpoint, r.Token), nil
+	case RemoteEtcd:
+		return kv.NewEtcd(r.Endpoint, r.Username, r.Password), nil
+	default:
+		return nil, fmt.Errorf("unknown remote backend %q, expected %s or %s", r.Backend, RemoteConsul, RemoteEtcd)
+	}
+}
+
+// Fetch reads the key of the remote settings.
+func (r RemoteConfig) Fetch(ctx context.Context) (kv.Value, error) {
+	store, err := r.Store()
+	if err != nil {
+		return kv.Value{}, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	value, err := store.Get(ctx, r.Key)
+	if err != nil {
+		return kv.Value{}, fmt.Errorf("failed to read %s key %s: %w", r.Backend, r.Key, err)
+	}
+	return value, nil
+}
+
+// mergeRemote reads the remote settings, if the configuration file sets a
+// backend, and merges them over those of the file. When the backend cannot
+// be read the settings of the cache file are merged instead, if there are
+// any; either way the state says what happened.
+func mergeRemote() (RemoteConfig, RemoteState, error) {
+	remote := RemoteConfig{
+		Timeout:      10 * time.Second,
+		PollInterval: 30 * time.Second,
+	}
+	if !viper.IsSet("remote") {
+		return remote, RemoteState{}, nil
+	}
+	if err := viper.UnmarshalKey("remote", &remote, decoderOptions); err != nil {
+		return remote, RemoteState{}, fmt.Errorf("failed to unmarshal remote config: %w", err)
+	}
+	if remote.Backend == "" {
+		return remote, RemoteState{}, nil
+	}
+	if remote.Endpoint == "" || remote.Key == "" {
+		return remote, RemoteState{}, fmt.Errorf("remote endpoint and key are required")
+	}
+	if remote.Timeout <= 0 {
+		return remote, RemoteState{}, fmt.Errorf("remote timeout must be positive")
+	}
+	if remote.CacheFile == "" {
+		dataDir := viper.GetString("data_dir")
+		if dataDir == "" {
+			dataDir = "/var/lib/proxwarden"
+		}
+		remote.CacheFile = filepath.Join(dataDir, "remote-config.yaml")
+	}
+
+	state := RemoteState{Source: remote.Backend}
+	value, err := remote.Fetch(context.Background())
+	data := value.Data
+	if err == nil {
+		state.Version = value.Version
+	} else {
+		state = RemoteState{Error: err}
+		if cached, cacheErr := os.ReadFile(remote.CacheFile); cacheErr == nil {
+			state.Source = "cache"
+			data = cached
+		}
+	}
+	if state.Source == "" {
+		return remote, state, nil
+	}
+
+	var settings map[string]interface{}
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return remote, state, fmt.Errorf("failed to parse remote config from %s: %w", state.Source, err)
+	}
+	if _, ok := settings["remote"]; ok {
+		return remote, state, fmt.Errorf("remote config from %s: only the configuration file can set remote", state.Source)
+	}
+	if err := viper.MergeConfigMap(settings); err != nil {
+		return remote, state, fmt.Errorf("failed to merge remote config: %w", err)
+	}
+	if state.Error == nil {
+		// Commands run by other users cannot write the daemon's cache,
+		// which only matters to the daemon
+		writeRemoteCache(remote.CacheFile, data)
+	}
+	return remote, state, nil
+}
+
+func writeRemoteCache(path string, data []byte) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+	}
+}
+
+// remoteUnavailable explains an invalid configuration by the remote
+// settings that could not be read.
+func remoteUnavailable(err error, state RemoteState) error {
+	if state.Error == nil || state.Source != "" {
+		return err
+	}
+	return fmt.Errorf("%w (remote config unavailable: %v)", err, state.Error)
+}
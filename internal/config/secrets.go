@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// envReference matches ${NAME}, and $${NAME} which stands for a literal
+// ${NAME}.
+var envReference = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces references to set environment variables in s.
+// References to unset variables are kept, so commands can still refer to
+// variables set when they run.
+func expandEnv(s string) string {
+	return envReference.ReplaceAllStringFunc(s, func(reference string) string {
+		if strings.HasPrefix(reference, "$$") {
+			return reference[1:]
+		}
+		if value, ok := os.LookupEnv(reference[2 : len(reference)-1]); ok {
+			return value
+		}
+		return reference
+	})
+}
+
+// expandEnvHook expands environment variable references in every string
+// setting before it is decoded.
+func expandEnvHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String {
+		return data, nil
+	}
+	return expandEnv(data.(string)), nil
+}
+
+// readSecretFiles sets the Proxmox password and token secret from the files
+// naming them, as written by systemd credentials, Docker secrets or a Vault
+// agent.
+func readSecretFiles(proxmox *ProxmoxConfig) error {
+	secrets := []struct {
+		name  string
+		file  string
+		value *string
+	}{
+		{"password", proxmox.PasswordFile, &proxmox.Password},
+		{"secret", proxmox.SecretFile, &proxmox.Secret},
+	}
+
+	for _, secret := range secrets {
+		if secret.file == "" {
+			continue
+		}
+		if *secret.value != "" {
+			return fmt.Errorf("proxmox %s and %s_file are mutually exclusive", secret.name, secret.name)
+		}
+		data, err := os.ReadFile(secret.file)
+		if err != nil {
+			return fmt.Errorf("failed to read proxmox %s_file: %w", secret.name, err)
+		}
+		value := strings.TrimRight(string(data), "\r\n")
+		if value == "" {
+			return fmt.Errorf("proxmox %s_file %s is empty", secret.name, secret.file)
+		}
+		*secret.value = value
+	}
+	return nil
+}
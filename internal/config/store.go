@@ -0,0 +1,28 @@
+package config
+
+import "fmt"
+
+// Store backends.
+const (
+	StoreFile = "file"
+	StoreBolt = "bolt"
+)
+
+// StoreConfig is where the failover history, backup catalog, failover
+// journal, audit log and last monitor state are kept.
+type StoreConfig struct {
+	// Backend is StoreFile, or empty, for a file of each in DataDir, or
+	// StoreBolt for a single embedded database
+	Backend string `yaml:"backend"`
+	// File is the bolt database; empty puts proxwarden.db in DataDir
+	File string `yaml:"file,omitempty"`
+}
+
+func validateStore(store *StoreConfig) error {
+	switch store.Backend {
+	case "", StoreFile, StoreBolt:
+		return nil
+	default:
+		return fmt.Errorf("store backend must be %s or %s", StoreFile, StoreBolt)
+	}
+}
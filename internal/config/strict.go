@@ -0,0 +1,141 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// unknownSettings returns an error naming the settings a decoding left
+// unused, each with the closest valid name if there is one, or nil if all
+// of them are known. Paths are relative to Config once prefixed with
+// prefix; those skip reports to be ignored, like flags bound to viper keys.
+func unknownSettings(unused []string, prefix string, skip func(path string) bool) error {
+	var settings []string
+	for _, path := range unused {
+		path = prefix + path
+		if skip != nil && skip(path) {
+			continue
+		}
+		setting := path
+		if suggestion := closestSetting(path); suggestion != "" {
+			setting += fmt.Sprintf(" (did you mean %s?)", suggestion)
+		}
+		settings = append(settings, setting)
+	}
+
+	switch len(settings) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("unknown setting %s", settings[0])
+	default:
+		return fmt.Errorf("unknown settings %s", strings.Join(settings, ", "))
+	}
+}
+
+// closestSetting returns the valid name closest to the last element of
+// path, a setting of Config such as monitoring.containers[0].failover_node,
+// or "" if none is close enough to be a likely typo.
+func closestSetting(path string) string {
+	t := reflect.TypeOf(Config{})
+	elements := settingElements(path)
+	for i, element := range elements {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if strings.HasPrefix(element, "[") {
+			if t.Kind() != reflect.Slice && t.Kind() != reflect.Array && t.Kind() != reflect.Map {
+				return ""
+			}
+			t = t.Elem()
+			continue
+		}
+		if t.Kind() != reflect.Struct {
+			return ""
+		}
+		if i == len(elements)-1 {
+			return closestName(element, settingNames(t))
+		}
+		field, ok := settingField(t, element)
+		if !ok {
+			return ""
+		}
+		t = field.Type
+	}
+	return ""
+}
+
+// settingElements splits a path like a.b[0].c into a, b, [0] and c.
+func settingElements(path string) []string {
+	var elements []string
+	for _, part := range strings.Split(path, ".") {
+		for part != "" {
+			end := strings.Index(part[1:], "[") + 1
+			if part[0] == '[' {
+				end = strings.Index(part, "]") + 1
+			}
+			if end <= 0 {
+				end = len(part)
+			}
+			elements = append(elements, part[:end])
+			part = part[end:]
+		}
+	}
+	return elements
+}
+
+func settingNames(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		if name := yamlName(t.Field(i)); name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func settingField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		if field := t.Field(i); strings.EqualFold(yamlName(field), name) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// closestName returns the name with the smallest edit distance to name,
+// if that is small enough for name to be a misspelling of it.
+func closestName(name string, names []string) string {
+	best, bestDistance := "", len(name)/3
+	if bestDistance < 2 {
+		bestDistance = 2
+	}
+	for _, candidate := range names {
+		if distance := editDistance(name, candidate); distance <= bestDistance && (best == "" || distance < editDistance(name, best)) {
+			best = candidate
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,233 @@
+// Package configcheck checks a configuration against the Proxmox cluster it
+// names: that the API answers and that the nodes, storages and containers
+// the configuration refers to exist.
+package configcheck
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+// defaultRestoreStorage is where failovers restore containers without a
+// configured storage.
+const defaultRestoreStorage = "local-lvm"
+
+// Problem is a setting that does not match the cluster. Path is the YAML
+// path of the setting, such as "monitoring.containers[0].failover_nodes[1]".
+type Problem struct {
+	Path    string
+	Message string
+}
+
+func (p Problem) String() string {
+	return p.Path + ": " + p.Message
+}
+
+// Client is the part of the Proxmox API the checks need.
+type Client interface {
+	GetNodes(ctx context.Context) ([]*api.NodeInfo, error)
+	GetStorages(ctx context.Context) ([]api.StorageInfo, error)
+	GetClusterResources(ctx context.Context) ([]api.ClusterResource, error)
+}
+
+// Cluster is what the checks found in the cluster.
+type Cluster struct {
+	Nodes      map[string]*api.NodeInfo
+	Storages   map[string]api.StorageInfo
+	Containers map[int]api.ClusterResource
+}
+
+// Check compares cfg with the cluster. It fails only when the cluster
+// cannot be read; everything else is a problem.
+func Check(ctx context.Context, cfg *config.Config, client Client) (*Cluster, []Problem, error) {
+	cluster := &Cluster{
+		Nodes:      make(map[string]*api.NodeInfo),
+		Storages:   make(map[string]api.StorageInfo),
+		Containers: make(map[int]api.ClusterResource),
+	}
+
+	nodes, err := client.GetNodes(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Proxmox API at %s not reachable: %w", cfg.Proxmox.Endpoint, err)
+	}
+	for _, node := range nodes {
+		cluster.Nodes[node.Name] = node
+	}
+
+	storages, err := client.GetStorages(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, storage := range storages {
+		cluster.Storages[storage.Name] = storage
+	}
+
+	resources, err := client.GetClusterResources(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, resource := range resources {
+		cluster.Containers[resource.ID] = resource
+	}
+
+	c := &checker{cluster: cluster}
+	c.check(cfg)
+	return cluster, c.problems, nil
+}
+
+type checker struct {
+	cluster  *Cluster
+	problems []Problem
+}
+
+// problem records a problem once, though settings shared by several nodes
+// are checked for each.
+func (c *checker) problem(path, format string, args ...interface{}) {
+	problem := Problem{Path: path, Message: fmt.Sprintf(format, args...)}
+	for _, known := range c.problems {
+		if known == problem {
+			return
+		}
+	}
+	c.problems = append(c.problems, problem)
+}
+
+func (c *checker) check(cfg *config.Config) {
+	c.backupStorage("backup.storage", cfg.Backup.Storage)
+	if replica := cfg.Backup.Replication.Storage; replica != "" {
+		c.backupStorage("backup.replication.storage", replica)
+	}
+
+	for i, container := range cfg.Monitoring.Containers {
+		path := cfg.Monitoring.ContainerPath(i)
+		if _, exists := c.cluster.Containers[container.ID]; !exists {
+			c.problem(path+".id", "container %d does not exist", container.ID)
+		}
+		c.container(path, container)
+	}
+
+	for i, selector := range cfg.Monitoring.Selectors {
+		path := cfg.Monitoring.SelectorPath(i)
+		if !c.selects(*selector.Match) {
+			c.problem(path+".match", "no container matches")
+		}
+		c.container(path, selector)
+	}
+
+	discover := cfg.Monitoring.Discover
+	if discover.Enabled() {
+		for j, node := range discover.FailoverNodes {
+			nodePath := fmt.Sprintf("monitoring.discover.failover_nodes[%d]", j)
+			if !c.node(nodePath, node) {
+				continue
+			}
+			if discover.Storage != "" {
+				c.restoreStorage("monitoring.discover.storage", discover.Storage, node)
+			}
+		}
+	}
+
+	var located []string
+	for node := range cfg.Failover.FailureDomains {
+		located = append(located, node)
+	}
+	sort.Strings(located)
+	for _, node := range located {
+		c.node("failover.failure_domains."+node, node)
+	}
+}
+
+// container checks the settings of a containers entry.
+func (c *checker) container(path string, container config.ContainerConfig) {
+	if container.StandbyID != 0 {
+		if _, exists := c.cluster.Containers[container.StandbyID]; !exists {
+			c.problem(path+".standby_id", "container %d does not exist", container.StandbyID)
+		}
+	}
+	if container.BackupStorage != "" {
+		c.backupStorage(path+".backup_storage", container.BackupStorage)
+	}
+
+	for j, node := range container.FailoverNodes {
+		nodePath := fmt.Sprintf("%s.failover_nodes[%d]", path, j)
+		if !c.node(nodePath, node) {
+			continue
+		}
+
+		storagePath := path + ".storage"
+		if container.NodeOverride(node).Storage != "" {
+			storagePath = fmt.Sprintf("%s.node_overrides.%s.storage", path, node)
+		}
+		c.restoreStorage(storagePath, RestoreStorage(container, node), node)
+	}
+
+	var overridden []string
+	for node := range container.NodeOverrides {
+		overridden = append(overridden, node)
+	}
+	sort.Strings(overridden)
+	for _, node := range overridden {
+		c.node(fmt.Sprintf("%s.node_overrides.%s", path, node), node)
+	}
+}
+
+// RestoreStorage returns the storage failovers to node restore the
+// container on.
+func RestoreStorage(container config.ContainerConfig, node string) string {
+	if override := container.NodeOverride(node); override.Storage != "" {
+		return override.Storage
+	}
+	if container.Storage != "" {
+		return container.Storage
+	}
+	return defaultRestoreStorage
+}
+
+// selects reports whether any container of the cluster matches.
+func (c *checker) selects(match config.ContainerMatch) bool {
+	for _, resource := range c.cluster.Containers {
+		if match.Matches(resource.TagList(), resource.Pool) {
+			return true
+		}
+	}
+	return false
+}
+
+// node reports whether node exists.
+func (c *checker) node(path, node string) bool {
+	if _, exists := c.cluster.Nodes[node]; !exists {
+		c.problem(path, "node %q does not exist", node)
+		return false
+	}
+	return true
+}
+
+func (c *checker) backupStorage(path, name string) {
+	storage, exists := c.cluster.Storages[name]
+	switch {
+	case !exists:
+		c.problem(path, "storage %q does not exist", name)
+	case bool(storage.Disable):
+		c.problem(path, "storage %q is disabled", name)
+	case !storage.Holds("backup"):
+		c.problem(path, "storage %q is not configured for backup content", name)
+	}
+}
+
+func (c *checker) restoreStorage(path, name, node string) {
+	storage, exists := c.cluster.Storages[name]
+	switch {
+	case !exists:
+		c.problem(path, "storage %q does not exist", name)
+	case bool(storage.Disable):
+		c.problem(path, "storage %q is disabled", name)
+	case !storage.Holds("rootdir"):
+		c.problem(path, "storage %q is not configured for container volumes", name)
+	case !storage.AvailableOn(node):
+		c.problem(path, "storage %q is not available on node %s", name, node)
+	}
+}
@@ -0,0 +1,108 @@
+package configcheck
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+type fakeClient struct {
+	nodes      []*api.NodeInfo
+	storages   []api.StorageInfo
+	containers []api.ClusterResource
+	err        error
+}
+
+func (f *fakeClient) GetNodes(ctx context.Context) ([]*api.NodeInfo, error) {
+	return f.nodes, f.err
+}
+
+func (f *fakeClient) GetStorages(ctx context.Context) ([]api.StorageInfo, error) {
+	return f.storages, nil
+}
+
+func (f *fakeClient) GetClusterResources(ctx context.Context) ([]api.ClusterResource, error) {
+	return f.containers, nil
+}
+
+func TestCheck(t *testing.T) {
+	client := &fakeClient{
+		nodes: []*api.NodeInfo{{Name: "pve1"}, {Name: "pve2"}, {Name: "pve3"}},
+		storages: []api.StorageInfo{
+			{Name: "local", Content: "iso,vztmpl,backup"},
+			{Name: "local-lvm", Content: "rootdir,images"},
+			{Name: "zfs-b", Content: "rootdir", Nodes: "pve2"},
+			{Name: "old", Content: "backup", Disable: true},
+		},
+		containers: []api.ClusterResource{{ID: 100}, {ID: 101, Tags: "critical"}},
+	}
+
+	tests := []struct {
+		name     string
+		cfg      config.Config
+		expected []Problem
+	}{
+		{
+			name: "matching cluster",
+			cfg: config.Config{
+				Backup: config.BackupConfig{Storage: "local"},
+				Monitoring: config.MonitoringConfig{
+					Containers: []config.ContainerConfig{
+						{ID: 100, FailoverNodes: []string{"pve2", "pve3"}, NodeOverrides: map[string]config.NodeOverride{"pve2": {Storage: "zfs-b"}}},
+					},
+					Selectors: []config.ContainerConfig{
+						{Match: &config.ContainerMatch{Tag: "critical"}, FailoverNodes: []string{"pve1"}},
+					},
+				},
+			},
+		},
+		{
+			name: "missing references",
+			cfg: config.Config{
+				Backup: config.BackupConfig{Storage: "old", Replication: config.ReplicationConfig{Storage: "local-lvm"}},
+				Monitoring: config.MonitoringConfig{
+					Containers: []config.ContainerConfig{
+						{ID: 100, FailoverNodes: []string{"pve2", "pve9"}},
+						{ID: 102, StandbyID: 103, FailoverNodes: []string{"pve1", "pve3"}, Storage: "zfs-b"},
+					},
+					Selectors: []config.ContainerConfig{
+						{Match: &config.ContainerMatch{Tag: "critical", Pool: "prod"}, FailoverNodes: []string{"pve5"}},
+					},
+				},
+				Failover: config.FailoverConfig{FailureDomains: map[string]config.FailureDomain{"pve4": {Zone: "dc2"}}},
+			},
+			expected: []Problem{
+				{Path: "backup.storage", Message: `storage "old" is disabled`},
+				{Path: "backup.replication.storage", Message: `storage "local-lvm" is not configured for backup content`},
+				{Path: "monitoring.containers[0].failover_nodes[1]", Message: `node "pve9" does not exist`},
+				{Path: "monitoring.containers[1].id", Message: "container 102 does not exist"},
+				{Path: "monitoring.containers[1].standby_id", Message: "container 103 does not exist"},
+				{Path: "monitoring.containers[1].storage", Message: `storage "zfs-b" is not available on node pve1`},
+				{Path: "monitoring.containers[1].storage", Message: `storage "zfs-b" is not available on node pve3`},
+				{Path: "monitoring.containers[0].match", Message: "no container matches"},
+				{Path: "monitoring.containers[0].failover_nodes[0]", Message: `node "pve5" does not exist`},
+				{Path: "failover.failure_domains.pve4", Message: `node "pve4" does not exist`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, problems, err := Check(context.Background(), &tt.cfg, client)
+			if err != nil {
+				t.Fatalf("Check failed: %v", err)
+			}
+			if !reflect.DeepEqual(problems, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, problems)
+			}
+		})
+	}
+
+	if _, _, err := Check(context.Background(), &config.Config{}, &fakeClient{err: errors.New("connection refused")}); err == nil {
+		t.Error("Expected an unreachable API to fail the check")
+	}
+}
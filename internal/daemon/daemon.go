@@ -3,11 +3,26 @@ package daemon
 import (
 	"context"
 	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 
 	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/audit"
+	"github.com/jbutlerdev/proxwarden/internal/backup"
+	"github.com/jbutlerdev/proxwarden/internal/catalog"
 	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/dns"
+	"github.com/jbutlerdev/proxwarden/internal/events"
 	"github.com/jbutlerdev/proxwarden/internal/failover"
+	"github.com/jbutlerdev/proxwarden/internal/history"
+	"github.com/jbutlerdev/proxwarden/internal/health"
+	"github.com/jbutlerdev/proxwarden/internal/journal"
+	"github.com/jbutlerdev/proxwarden/internal/maintenance"
 	"github.com/jbutlerdev/proxwarden/internal/monitor"
+	"github.com/jbutlerdev/proxwarden/internal/notify"
+	"github.com/jbutlerdev/proxwarden/internal/server"
+	"github.com/jbutlerdev/proxwarden/internal/store"
 	"github.com/sirupsen/logrus"
 )
 
@@ -16,7 +31,21 @@ type Daemon struct {
 	apiClient     *api.Client
 	monitor       *monitor.Monitor
 	failoverEngine *failover.Engine
+	server        *server.Server
+	events        *events.Watcher
+	backups       *backup.Scheduler
+	pruner        *backup.Pruner
+	replicator    *backup.Replicator
+	notifier      *notify.Dispatcher
 	logger        *logrus.Logger
+	reloads       chan struct{}
+	// leader is set while this daemon holds the leader lock
+	leader atomic.Bool
+	// recovered runs the recovery of interrupted failovers once, when
+	// the daemon first becomes active
+	recovered sync.Once
+	// db is the store database, nil with the file backend
+	db *store.DB
 }
 
 func New(logger *logrus.Logger) (*Daemon, error) {
@@ -38,6 +67,29 @@ func New(logger *logrus.Logger) (*Daemon, error) {
 	} else {
 		logger.SetFormatter(&logrus.TextFormatter{})
 	}
+	logRemoteState(logger, cfg.RemoteState)
+	if err := audit.OpenConfig(cfg, audit.ActorAuto, logger); err != nil {
+		return nil, err
+	}
+
+	// Open the store database, migrating it and importing the files of the
+	// file backend when the bolt backend is new
+	db, err := store.OpenConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store: %w", err)
+	}
+	failoverHistory, err := history.Open(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open failover history: %w", err)
+	}
+	failoverJournal, err := journal.Open(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open failover journal: %w", err)
+	}
+	backupCatalog, err := catalog.Open(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup catalog: %w", err)
+	}
 
 	// Create API client
 	apiClient, err := api.NewClient(&cfg.Proxmox)
@@ -45,11 +97,41 @@ func New(logger *logrus.Logger) (*Daemon, error) {
 		return nil, fmt.Errorf("failed to create Proxmox API client: %w", err)
 	}
 
+	// Create notification dispatcher
+	notifier, err := notify.NewDispatcher(&cfg.Notifications, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notifier: %w", err)
+	}
+	if err := notifier.PersistEscalations(filepath.Join(cfg.DataDir, notify.EscalationsFile)); err != nil {
+		return nil, fmt.Errorf("failed to load escalations: %w", err)
+	}
+
+	dnsUpdater, err := dns.NewUpdater(&cfg.DNS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DNS updater: %w", err)
+	}
+
 	// Create failover engine
 	failoverEngine := failover.NewWithConfig(cfg, apiClient, logger)
+	failoverEngine.SetNotifier(notifier)
+	failoverEngine.SetDNS(dnsUpdater)
+	failoverEngine.SetHistory(failoverHistory)
+	failoverEngine.SetJournal(failoverJournal)
+	failoverEngine.SetCatalog(backupCatalog)
+	backupLimiter := backup.NewLimiter(&cfg.Backup)
+	failoverEngine.SetBackupLimiter(backupLimiter)
 
 	// Create monitor
 	monitorService := monitor.New(cfg, apiClient, logger)
+	failoverEngine.SetContainerLookup(monitorService.ContainerConfig)
+	failoverEngine.SetObserver(monitorService)
+
+	// Planned maintenance suppresses failover for its containers and nodes
+	maint, err := maintenance.NewManager(&cfg.Maintenance, filepath.Join(cfg.DataDir, "maintenance.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load maintenance state: %w", err)
+	}
+	monitorService.SetMaintenance(maint)
 
 	// Setup failover callback
 	monitorService.AddFailureCallback(func(containerID int, state *monitor.ContainerState) {
@@ -58,6 +140,20 @@ func New(logger *logrus.Logger) (*Daemon, error) {
 			"failure_count": state.FailureCount,
 		}).Warn("Container failure detected, initiating failover")
 
+		// With a failure window the failures counted need not be consecutive
+		message := fmt.Sprintf("Container %d failed %d consecutive health checks", containerID, state.FailureCount)
+		if recent := len(state.RecentFailures); recent > 0 {
+			message = fmt.Sprintf("Container %d failed %d health checks within its failure window", containerID, recent)
+		}
+		notifier.Send(notify.Event{
+			Type:          notify.EventContainerFailed,
+			Severity:      notify.SeverityCritical,
+			ContainerID:   containerID,
+			ContainerName: state.Name,
+			Node:          state.Node,
+			Message:       message,
+		})
+
 		if err := failoverEngine.HandleContainerFailure(containerID); err != nil {
 			logger.WithFields(logrus.Fields{
 				"container_id": containerID,
@@ -66,13 +162,123 @@ func New(logger *logrus.Logger) (*Daemon, error) {
 		}
 	})
 
-	return &Daemon{
+	// Fail over everything on a node that went down, in priority order
+	monitorService.AddNodeFailureCallback(func(node string, containerIDs []int) {
+		notifier.Send(notify.Event{
+			Type:     notify.EventNodeFailed,
+			Severity: notify.SeverityCritical,
+			Node:     node,
+			Message:  fmt.Sprintf("Node %s is down, failing over %d containers", node, len(containerIDs)),
+		})
+
+		if err := failoverEngine.HandleNodeFailure(node, containerIDs); err != nil {
+			logger.WithFields(logrus.Fields{
+				"node":  node,
+				"error": err,
+			}).Error("Node failover failed")
+		}
+	})
+
+	// Alert on warning-level checks without triggering failover
+	monitorService.AddWarningCallback(func(containerID int, state *monitor.ContainerState, result *health.CheckResult, active bool) {
+		event := notify.Event{
+			Type:          notify.EventCheckWarning,
+			Severity:      notify.SeverityWarning,
+			ContainerID:   containerID,
+			ContainerName: state.Name,
+			Node:          state.Node,
+			Message:       fmt.Sprintf("Warning check %s %s failing on container %d: %v", result.Type, result.Target, containerID, result.Error),
+			Details:       map[string]string{"check_type": result.Type, "target": result.Target},
+		}
+		if !active {
+			event.Type = notify.EventCheckWarningCleared
+			event.Severity = notify.SeverityInfo
+			event.Message = fmt.Sprintf("Warning check %s %s recovered on container %d", result.Type, result.Target, containerID)
+		}
+		notifier.Send(event)
+	})
+
+	// Failure detection pauses while the daemon cannot reach its witnesses
+	monitorService.AddQuorumCallback(func(lost bool, reachable, total int) {
+		event := notify.Event{
+			Type:     notify.EventQuorumLost,
+			Severity: notify.SeverityCritical,
+			Message:  fmt.Sprintf("Only %d of %d witnesses reachable, failure detection suspended", reachable, total),
+		}
+		if !lost {
+			event.Type = notify.EventQuorumRestored
+			event.Severity = notify.SeverityInfo
+			event.Message = fmt.Sprintf("%d of %d witnesses reachable, failure detection resumed", reachable, total)
+		}
+		notifier.Send(event)
+	})
+
+	// Alert instead of failing over repeatedly when a container flaps
+	monitorService.AddFlappingCallback(func(containerID int, state *monitor.ContainerState, active bool) {
+		event := notify.Event{
+			Type:          notify.EventContainerFlapping,
+			Severity:      notify.SeverityWarning,
+			ContainerID:   containerID,
+			ContainerName: state.Name,
+			Node:          state.Node,
+			Message:       fmt.Sprintf("Container %d is flapping (%d state changes), automatic failover suppressed", containerID, len(state.Transitions)),
+		}
+		if !active {
+			event.Type = notify.EventFlappingCleared
+			event.Severity = notify.SeverityInfo
+			event.Message = fmt.Sprintf("Container %d stopped flapping, automatic failover resumed", containerID)
+		}
+		notifier.Send(event)
+	})
+
+	d := &Daemon{
 		config:         cfg,
 		apiClient:      apiClient,
 		monitor:        monitorService,
 		failoverEngine: failoverEngine,
+		notifier:       notifier,
 		logger:         logger,
-	}, nil
+		reloads:        make(chan struct{}, 1),
+		db:             db,
+	}
+
+	// Take scheduled backups so restores have a recent archive
+	d.backups, err = backup.NewScheduler(cfg, apiClient, notifier, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backup scheduler: %w", err)
+	}
+	d.backups.SetCatalog(backupCatalog)
+	d.backups.SetLimiter(backupLimiter)
+	d.backups.SetContainerLookup(monitorService.ContainerConfig)
+	if err := d.backups.Persist(filepath.Join(cfg.DataDir, backup.ScheduleFile)); err != nil {
+		return nil, fmt.Errorf("failed to load backup schedules: %w", err)
+	}
+	d.pruner = backup.NewPruner(cfg, apiClient, logger)
+	d.pruner.SetCatalog(backupCatalog)
+	d.replicator = backup.NewReplicator(cfg, apiClient, notifier, logger)
+
+	if cfg.Server.Enabled {
+		d.server = server.New(&cfg.Server, monitorService, maint, failoverEngine, logger)
+		d.server.SetCatalog(backupCatalog)
+		d.server.SetProxmox(apiClient)
+		d.server.SetLeadership(d)
+		d.server.SetNotifier(notifier)
+		d.server.SetBackups(d.backups)
+	}
+
+	// Check containers immediately when the cluster reports activity on them
+	if cfg.Monitoring.Events.Enabled {
+		d.events = events.NewWatcher(&cfg.Monitoring.Events, apiClient, func(event events.Event) {
+			switch event.Kind {
+			case events.KindContainer:
+				monitorService.Trigger(event.ContainerID)
+			case events.KindNode:
+				monitorService.TriggerNode(event.Node)
+			}
+		}, logger)
+	}
+
+	return d, nil
 }
 
 func (d *Daemon) Start(ctx context.Context) error {
@@ -83,6 +289,94 @@ func (d *Daemon) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to validate Proxmox connectivity: %w", err)
 	}
 
+	// Start API server
+	if d.server != nil {
+		go func() {
+			if err := d.server.Start(ctx); err != nil {
+				d.logger.WithField("error", err).Error("API server failed")
+			}
+		}()
+	}
+
+	// Apply configuration changes on request
+	go d.runReloads(ctx)
+	if d.config.WatchConfig {
+		go func() {
+			if err := d.watchConfig(ctx); err != nil {
+				d.logger.WithField("error", err).Error("Configuration file watcher failed")
+			}
+		}()
+	}
+	if d.config.Remote.Backend != "" && d.config.Remote.PollInterval > 0 {
+		go d.watchRemote(ctx)
+	}
+
+	if d.config.LeaderElection.Enabled {
+		return d.runElection(ctx)
+	}
+	return d.runActive(ctx)
+}
+
+// runActive runs the parts of the daemon that act on the cluster until ctx
+// is cancelled.
+func (d *Daemon) runActive(ctx context.Context) error {
+	// Failovers the last run of the daemon left unfinished are found
+	// before any new one starts
+	d.recovered.Do(d.failoverEngine.RecoverInterrupted)
+
+	// Start cluster event watcher
+	if d.events != nil {
+		go func() {
+			if err := d.events.Start(ctx); err != nil && ctx.Err() == nil {
+				d.logger.WithField("error", err).Error("Cluster event watcher failed")
+			}
+		}()
+	}
+
+	// Start scheduled backups, also without schedules so they can be added
+	go func() {
+		if err := d.backups.Start(ctx); err != nil && ctx.Err() == nil {
+			d.logger.WithField("error", err).Error("Backup scheduler failed")
+		}
+	}()
+
+	// Copy new backups to the replication storage
+	if d.replicator.Enabled() {
+		go func() {
+			if err := d.replicator.Start(ctx); err != nil && ctx.Err() == nil {
+				d.logger.WithField("error", err).Error("Backup replication failed")
+			}
+		}()
+	}
+
+	// Delete backups outside the retention
+	if d.pruner.Enabled() {
+		go func() {
+			if err := d.pruner.Start(ctx); err != nil && ctx.Err() == nil {
+				d.logger.WithField("error", err).Error("Backup pruning failed")
+			}
+		}()
+	}
+
+	// Start moving failed-over containers back
+	if d.config.Failover.Failback.Enabled {
+		go d.failoverEngine.RunFailback(ctx)
+	}
+
+	// Keep standby copies refreshed from the latest backups
+	go d.failoverEngine.RunStandbySync(ctx)
+
+	// Run scheduled failover drills
+	go d.failoverEngine.RunDrills(ctx)
+
+	// Notify later escalation steps of unacknowledged failures
+	go d.notifier.RunEscalations(ctx)
+
+	// Keep the last monitor state for when the daemon is not running
+	if d.db != nil {
+		go d.saveMonitorStates(ctx)
+	}
+
 	// Start monitoring
 	return d.monitor.Start(ctx)
 }
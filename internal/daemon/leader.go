@@ -0,0 +1,131 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/kv"
+	"github.com/sirupsen/logrus"
+)
+
+// releaseTimeout bounds giving up the lock on shutdown.
+const releaseTimeout = 5 * time.Second
+
+// IsLeader reports whether this daemon acts on the cluster: always without
+// leader election, and while it holds the lock with it.
+func (d *Daemon) IsLeader() bool {
+	return !d.config.LeaderElection.Enabled || d.leader.Load()
+}
+
+// runElection campaigns for the leader lock and runs the parts of the daemon
+// that act on the cluster while it holds it, until ctx is cancelled.
+// Standby daemons keep serving the API.
+func (d *Daemon) runElection(ctx context.Context) error {
+	election := d.config.LeaderElection
+	identity := election.Identity
+	if identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("failed to get hostname for leader election: %w", err)
+		}
+		identity = hostname
+	}
+	lock, err := election.Lock(identity)
+	if err != nil {
+		return err
+	}
+
+	// Renewing three times per TTL leaves time to step down before the
+	// lock expires when the backend stops answering
+	interval := election.TTL / 3
+	logger := d.logger.WithFields(logrus.Fields{
+		"backend":  election.Backend,
+		"key":      election.Key,
+		"identity": identity,
+	})
+	logger.Info("Campaigning for leadership")
+
+	var standbyFor string
+	for {
+		held, err := lock.Acquire(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		switch {
+		case err != nil:
+			logger.WithField("error", err).Warn("Failed to acquire leader lock")
+		case !held:
+			if holder, err := lock.Holder(ctx); err == nil && holder != standbyFor {
+				logger.WithField("leader", holder).Info("Standing by")
+				standbyFor = holder
+			}
+		default:
+			standbyFor = ""
+			logger.Info("Became leader")
+			d.lead(ctx, lock, interval, election.TTL-interval)
+			if ctx.Err() != nil {
+				releaseCtx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+				defer cancel()
+				if err := lock.Release(releaseCtx); err != nil {
+					logger.WithField("error", err).Warn("Failed to release leader lock")
+				}
+				return nil
+			}
+			logger.Warn("Lost leadership, standing by")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// lead runs the active parts of the daemon while the lock is held, renewing
+// it every interval, and stops them once it is lost, the backend has not
+// confirmed it for stepDown, or ctx is cancelled.
+func (d *Daemon) lead(ctx context.Context, lock kv.Lock, interval, stepDown time.Duration) {
+	termCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan error, 1)
+	d.leader.Store(true)
+	defer d.leader.Store(false)
+	go func() { done <- d.runActive(termCtx) }()
+	stop := func() {
+		cancel()
+		<-done
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	renewed := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			stop()
+			return
+		case err := <-done:
+			d.logger.WithField("error", err).Error("Monitoring stopped while leader")
+			return
+		case <-ticker.C:
+		}
+
+		held, err := lock.Acquire(termCtx)
+		switch {
+		case err == nil && held:
+			renewed = time.Now()
+		case err == nil:
+			stop()
+			return
+		case time.Since(renewed) >= stepDown:
+			d.logger.WithField("error", err).Error("Leader lock could not be renewed, stepping down")
+			stop()
+			return
+		default:
+			d.logger.WithField("error", err).Warn("Failed to renew leader lock")
+		}
+	}
+}
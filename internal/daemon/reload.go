@@ -0,0 +1,185 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/jbutlerdev/proxwarden/internal/audit"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// reloadDelay lets editors finish writing the configuration file before it
+// is read.
+const reloadDelay = 500 * time.Millisecond
+
+// RequestReload asks the daemon to reload its configuration. Requests made
+// while one is pending are merged.
+func (d *Daemon) RequestReload() {
+	select {
+	case d.reloads <- struct{}{}:
+	default:
+	}
+}
+
+func (d *Daemon) runReloads(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.reloads:
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reloadDelay):
+		}
+		select {
+		case <-d.reloads:
+		default:
+		}
+
+		d.reload()
+	}
+}
+
+// reload applies a changed configuration to the monitor, failover engine,
+// backup scheduler and pruner. Settings the daemon only reads on start keep
+// their value until it restarts, which is logged.
+func (d *Daemon) reload() {
+	cfg, err := config.Reload()
+	if err != nil {
+		d.logger.WithField("error", err).Error("Configuration reload failed, keeping the current configuration")
+		return
+	}
+
+	logRemoteState(d.logger, cfg.RemoteState)
+	if changed := cfg.RestartRequired(d.config); len(changed) > 0 {
+		d.logger.WithField("settings", changed).Warn("Changed settings take effect after a restart")
+	}
+
+	if level, err := logrus.ParseLevel(cfg.Logging.Level); err == nil {
+		d.logger.SetLevel(level)
+	}
+	d.monitor.Reload(cfg, time.Now())
+	d.failoverEngine.Reload(cfg)
+	d.backups.Reload(cfg)
+	d.pruner.Reload(cfg)
+	if err := audit.OpenConfig(cfg, audit.ActorAuto, d.logger); err != nil {
+		d.logger.WithField("error", err).Error("Failed to reopen audit log")
+	}
+
+	d.logger.Info("Configuration reloaded")
+}
+
+// watchConfig requests a reload whenever the configuration file or a file
+// matching its include patterns changes. Directories are watched, so files
+// replaced by a rename, as many editors save them, are followed.
+func (d *Daemon) watchConfig(ctx context.Context) error {
+	path := viper.ConfigFileUsed()
+	if path == "" {
+		return fmt.Errorf("no configuration file to watch")
+	}
+	patterns := append([]string{filepath.Clean(path)}, d.config.IncludePatterns()...)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	watched := make(map[string]bool)
+	for _, pattern := range patterns {
+		dir := filepath.Dir(pattern)
+		if watched[dir] {
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+		watched[dir] = true
+	}
+	d.logger.WithField("files", patterns).Info("Watching configuration files for changes")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) != 0 && matchesAny(patterns, event.Name) {
+				d.RequestReload()
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			d.logger.WithField("error", err).Warn("Configuration file watcher error")
+		}
+	}
+}
+
+func matchesAny(patterns []string, name string) bool {
+	name = filepath.Clean(name)
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// watchRemote requests a reload whenever the key of the remote settings
+// changes, checking it every poll interval.
+func (d *Daemon) watchRemote(ctx context.Context) {
+	remote := d.config.Remote
+	version := d.config.RemoteState.Version
+	ticker := time.NewTicker(remote.PollInterval)
+	defer ticker.Stop()
+
+	var failing bool
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		value, err := remote.Fetch(ctx)
+		if err != nil {
+			if !failing {
+				d.logger.WithField("error", err).Warn("Remote configuration unavailable, keeping the current configuration")
+			}
+			failing = true
+			continue
+		}
+		if failing {
+			d.logger.Info("Remote configuration available again")
+			failing = false
+		}
+		if value.Version != version {
+			version = value.Version
+			d.RequestReload()
+		}
+	}
+}
+
+// logRemoteState warns when the remote settings of a loaded configuration
+// could not be read from their backend.
+func logRemoteState(logger *logrus.Logger, state config.RemoteState) {
+	if state.Error == nil {
+		return
+	}
+	if state.Source == "" {
+		logger.WithField("error", state.Error).Warn("Remote configuration unavailable and not cached, using the configuration file only")
+		return
+	}
+	logger.WithField("error", state.Error).Warn("Remote configuration unavailable, using the cached copy")
+}
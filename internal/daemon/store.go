@@ -0,0 +1,34 @@
+package daemon
+
+import (
+	"context"
+	"time"
+)
+
+// saveMonitorStates saves the state of every container in the store
+// database each monitoring interval, and once more when ctx is cancelled,
+// for status commands run while the daemon is not.
+func (d *Daemon) saveMonitorStates(ctx context.Context) {
+	interval := d.config.Monitoring.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			d.saveMonitorState()
+			return
+		case <-ticker.C:
+			d.saveMonitorState()
+		}
+	}
+}
+
+func (d *Daemon) saveMonitorState() {
+	if err := d.monitor.SaveStates(d.db, time.Now()); err != nil {
+		d.logger.WithField("error", err).Error("Failed to save monitor state")
+	}
+}
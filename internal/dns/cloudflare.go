@@ -0,0 +1,188 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+const cloudflareAPI = "https://api.cloudflare.com/client/v4"
+
+// Cloudflare manages records through the Cloudflare API.
+type Cloudflare struct {
+	name     string
+	baseURL  string
+	token    string
+	zoneName string
+	client   *http.Client
+
+	// mu guards zoneID, looked up from zoneName on first use
+	mu     sync.Mutex
+	zoneID string
+}
+
+type cloudflareRecord struct {
+	ID      string `json:"id,omitempty"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	TTL     int    `json:"ttl"`
+}
+
+type cloudflareResponse struct {
+	Success bool `json:"success"`
+	Errors  []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+	Result json.RawMessage `json:"result"`
+}
+
+func NewCloudflare(provider config.DNSProvider) *Cloudflare {
+	baseURL := provider.URL
+	if baseURL == "" {
+		baseURL = cloudflareAPI
+	}
+	return &Cloudflare{
+		name:     provider.Name,
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		token:    provider.APIToken,
+		zoneName: provider.Zone,
+		zoneID:   provider.ZoneID,
+		client:   &http.Client{},
+	}
+}
+
+func (c *Cloudflare) Name() string {
+	return c.name
+}
+
+// Replace keeps records that already hold a wanted value, rewrites the
+// others in place and creates or deletes records for the difference.
+func (c *Cloudflare) Replace(ctx context.Context, name, recordType string, values []string, ttl int) error {
+	zoneID, err := c.zone(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{"type": {recordType}, "name": {name}}
+	var existing []cloudflareRecord
+	if err := c.do(ctx, http.MethodGet, "/zones/"+zoneID+"/dns_records?"+query.Encode(), nil, &existing); err != nil {
+		return fmt.Errorf("failed to list records: %w", err)
+	}
+
+	wanted := make(map[string]bool, len(values))
+	for _, value := range values {
+		wanted[value] = true
+	}
+	var stale []cloudflareRecord
+	for _, record := range existing {
+		if wanted[record.Content] && record.TTL == ttl {
+			delete(wanted, record.Content)
+			continue
+		}
+		stale = append(stale, record)
+	}
+
+	for _, value := range values {
+		if !wanted[value] {
+			continue
+		}
+		record := cloudflareRecord{Type: recordType, Name: name, Content: value, TTL: ttl}
+		if len(stale) > 0 {
+			if err := c.do(ctx, http.MethodPut, "/zones/"+zoneID+"/dns_records/"+stale[0].ID, record, nil); err != nil {
+				return fmt.Errorf("failed to update record: %w", err)
+			}
+			stale = stale[1:]
+			continue
+		}
+		if err := c.do(ctx, http.MethodPost, "/zones/"+zoneID+"/dns_records", record, nil); err != nil {
+			return fmt.Errorf("failed to create record: %w", err)
+		}
+	}
+
+	for _, record := range stale {
+		if err := c.do(ctx, http.MethodDelete, "/zones/"+zoneID+"/dns_records/"+record.ID, nil, nil); err != nil {
+			return fmt.Errorf("failed to delete record: %w", err)
+		}
+	}
+	return nil
+}
+
+// zone returns the configured zone ID, looking it up by name if unset.
+func (c *Cloudflare) zone(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.zoneID != "" {
+		return c.zoneID, nil
+	}
+
+	var zones []struct {
+		ID string `json:"id"`
+	}
+	query := url.Values{"name": {strings.TrimSuffix(c.zoneName, ".")}}
+	if err := c.do(ctx, http.MethodGet, "/zones?"+query.Encode(), nil, &zones); err != nil {
+		return "", fmt.Errorf("failed to look up zone %s: %w", c.zoneName, err)
+	}
+	if len(zones) == 0 {
+		return "", fmt.Errorf("zone %s not found", c.zoneName)
+	}
+
+	c.zoneID = zones[0].ID
+	return c.zoneID, nil
+}
+
+func (c *Cloudflare) do(ctx context.Context, method, path string, body, v interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result cloudflareResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("unexpected response (status %d): %w", resp.StatusCode, err)
+	}
+	if !result.Success {
+		var messages []string
+		for _, e := range result.Errors {
+			messages = append(messages, fmt.Sprintf("%d: %s", e.Code, e.Message))
+		}
+		return fmt.Errorf("cloudflare returned %d: %s", resp.StatusCode, strings.Join(messages, "; "))
+	}
+
+	if v == nil {
+		return nil
+	}
+	if err := json.Unmarshal(result.Result, v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+// Package dns points DNS records at containers after they failed over.
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+// Record types written by providers.
+const (
+	TypeA    = "A"
+	TypeAAAA = "AAAA"
+)
+
+// requestTimeout bounds a single request to a DNS provider.
+const requestTimeout = 30 * time.Second
+
+// Provider changes records at a single DNS service.
+type Provider interface {
+	Name() string
+	// Replace makes values the only records of recordType at name.
+	Replace(ctx context.Context, name, recordType string, values []string, ttl int) error
+}
+
+// Updater points containers' DNS records at their addresses. A nil Updater
+// is valid and has no providers.
+type Updater struct {
+	providers map[string]Provider
+	ttl       int
+}
+
+func NewUpdater(cfg *config.DNSConfig) (*Updater, error) {
+	u := &Updater{
+		providers: make(map[string]Provider),
+		ttl:       cfg.TTL,
+	}
+
+	for _, provider := range cfg.Providers {
+		p, err := newProvider(provider)
+		if err != nil {
+			return nil, fmt.Errorf("dns provider %q: %w", provider.Name, err)
+		}
+		u.providers[provider.Name] = p
+	}
+
+	return u, nil
+}
+
+func newProvider(provider config.DNSProvider) (Provider, error) {
+	switch provider.Type {
+	case config.DNSCloudflare:
+		return NewCloudflare(provider), nil
+	case config.DNSRoute53:
+		return NewRoute53(provider), nil
+	case config.DNSPowerDNS:
+		return NewPowerDNS(provider), nil
+	case config.DNSRFC2136:
+		return NewRFC2136(provider)
+	default:
+		return nil, fmt.Errorf("unknown provider type: %s", provider.Type)
+	}
+}
+
+// Update points the A and AAAA records of the record's hostname at
+// addresses. A family without addresses is left alone, so a container that
+// only reports IPv4 does not lose its AAAA records.
+func (u *Updater) Update(ctx context.Context, record config.DNSRecord, addresses []net.IP) error {
+	if u == nil {
+		return fmt.Errorf("dns not configured")
+	}
+	provider, exists := u.providers[record.Provider]
+	if !exists {
+		return fmt.Errorf("unknown dns provider %q", record.Provider)
+	}
+
+	ttl := record.TTL
+	if ttl == 0 {
+		ttl = u.ttl
+	}
+
+	var errs []error
+	for recordType, values := range recordValues(addresses) {
+		ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+		err := provider.Replace(ctx, strings.TrimSuffix(record.Hostname, "."), recordType, values, ttl)
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", recordType, record.Hostname, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// recordValues groups addresses by the record type holding them, without
+// duplicates.
+func recordValues(addresses []net.IP) map[string][]string {
+	values := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, ip := range addresses {
+		value := ip.String()
+		if seen[value] {
+			continue
+		}
+		seen[value] = true
+
+		recordType := TypeAAAA
+		if ip.To4() != nil {
+			recordType = TypeA
+		}
+		values[recordType] = append(values[recordType], value)
+	}
+	return values
+}
+
+// fqdn returns name with a trailing dot.
+func fqdn(name string) string {
+	return strings.TrimSuffix(name, ".") + "."
+}
@@ -0,0 +1,370 @@
+package dns
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+type fakeProvider struct {
+	mu      sync.Mutex
+	changes map[string][]string
+}
+
+func (p *fakeProvider) Name() string { return "fake" }
+
+func (p *fakeProvider) Replace(ctx context.Context, name, recordType string, values []string, ttl int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.changes[name+" "+recordType] = values
+	return nil
+}
+
+func TestUpdater_Update(t *testing.T) {
+	tests := []struct {
+		name      string
+		addresses []string
+		expected  map[string][]string
+	}{
+		{
+			name:      "dual stack",
+			addresses: []string{"10.0.0.5", "2001:db8::5"},
+			expected: map[string][]string{
+				"app.example.com A":    {"10.0.0.5"},
+				"app.example.com AAAA": {"2001:db8::5"},
+			},
+		},
+		{
+			name:      "ipv4 only leaves AAAA alone",
+			addresses: []string{"10.0.0.5", "10.0.0.6", "10.0.0.5"},
+			expected: map[string][]string{
+				"app.example.com A": {"10.0.0.5", "10.0.0.6"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &fakeProvider{changes: make(map[string][]string)}
+			updater := &Updater{providers: map[string]Provider{"fake": provider}, ttl: 60}
+
+			var addresses []net.IP
+			for _, address := range tt.addresses {
+				addresses = append(addresses, net.ParseIP(address))
+			}
+			err := updater.Update(context.Background(), config.DNSRecord{Hostname: "app.example.com.", Provider: "fake"}, addresses)
+			if err != nil {
+				t.Fatalf("Update failed: %v", err)
+			}
+			if !reflect.DeepEqual(provider.changes, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, provider.changes)
+			}
+		})
+	}
+}
+
+func TestUpdater_UnknownProvider(t *testing.T) {
+	updater, err := NewUpdater(&config.DNSConfig{TTL: 60})
+	if err != nil {
+		t.Fatalf("Failed to create updater: %v", err)
+	}
+	err = updater.Update(context.Background(), config.DNSRecord{Hostname: "app.example.com", Provider: "missing"}, []net.IP{net.ParseIP("10.0.0.5")})
+	if err == nil {
+		t.Error("Expected error for unknown provider")
+	}
+}
+
+func TestCloudflare_Replace(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": false})
+			return
+		}
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+
+		var result interface{}
+		switch {
+		case r.URL.Path == "/zones":
+			result = []map[string]string{{"id": "zone1"}}
+		case r.Method == http.MethodGet:
+			result = []cloudflareRecord{
+				{ID: "r1", Type: "A", Name: "app.example.com", Content: "10.0.0.1", TTL: 60},
+				{ID: "r2", Type: "A", Name: "app.example.com", Content: "10.0.0.2", TTL: 60},
+				{ID: "r3", Type: "A", Name: "app.example.com", Content: "10.0.0.3", TTL: 60},
+			}
+		default:
+			result = map[string]string{}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "result": result})
+	}))
+	defer server.Close()
+
+	provider := NewCloudflare(config.DNSProvider{Name: "cf", Zone: "example.com", APIToken: "token", URL: server.URL})
+	err := provider.Replace(context.Background(), "app.example.com", TypeA, []string{"10.0.0.2", "10.0.0.9"}, 60)
+	if err != nil {
+		t.Fatalf("Replace failed: %v", err)
+	}
+
+	expected := []string{
+		"GET /zones",
+		"GET /zones/zone1/dns_records",
+		"PUT /zones/zone1/dns_records/r1",
+		"DELETE /zones/zone1/dns_records/r3",
+	}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("Expected requests %v, got %v", expected, requests)
+	}
+}
+
+func TestPowerDNS_Replace(t *testing.T) {
+	var patch powerDNSPatch
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch || r.URL.Path != "/api/v1/servers/localhost/zones/example.com." {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("X-API-Key") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "unauthorized"})
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&patch)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	provider := NewPowerDNS(config.DNSProvider{Name: "pdns", URL: server.URL, APIKey: "key", Zone: "example.com"})
+	if err := provider.Replace(context.Background(), "app.example.com", TypeAAAA, []string{"2001:db8::5"}, 120); err != nil {
+		t.Fatalf("Replace failed: %v", err)
+	}
+
+	expected := powerDNSPatch{RRSets: []powerDNSRRSet{{
+		Name:       "app.example.com.",
+		Type:       "AAAA",
+		TTL:        120,
+		ChangeType: "REPLACE",
+		Records:    []powerDNSRecord{{Content: "2001:db8::5"}},
+	}}}
+	if !reflect.DeepEqual(patch, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, patch)
+	}
+
+	wrongKey := NewPowerDNS(config.DNSProvider{Name: "pdns", URL: server.URL, APIKey: "wrong", Zone: "example.com"})
+	if err := wrongKey.Replace(context.Background(), "app.example.com", TypeA, []string{"10.0.0.5"}, 60); err == nil {
+		t.Error("Expected error for rejected API key")
+	}
+}
+
+func TestRoute53_Replace(t *testing.T) {
+	var (
+		authorization string
+		body          string
+		path          string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		path = r.URL.Path
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer server.Close()
+
+	provider := NewRoute53(config.DNSProvider{
+		Name:            "r53",
+		ZoneID:          "/hostedzone/Z123",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		URL:             server.URL,
+	})
+	provider.now = func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC) }
+
+	if err := provider.Replace(context.Background(), "app.example.com", TypeA, []string{"10.0.0.5", "10.0.0.6"}, 60); err != nil {
+		t.Fatalf("Replace failed: %v", err)
+	}
+
+	if path != "/2013-04-01/hostedzone/Z123/rrset" {
+		t.Errorf("Unexpected path %s", path)
+	}
+	if !strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=AKID/20240501/us-east-1/route53/aws4_request, SignedHeaders=host;x-amz-date, Signature=") {
+		t.Errorf("Unexpected authorization header %s", authorization)
+	}
+	for _, part := range []string{
+		"<Action>UPSERT</Action>",
+		"<ResourceRecordSet><Name>app.example.com.</Name><Type>A</Type><TTL>60</TTL>",
+		"<ResourceRecord><Value>10.0.0.5</Value></ResourceRecord><ResourceRecord><Value>10.0.0.6</Value></ResourceRecord>",
+	} {
+		if !strings.Contains(body, part) {
+			t.Errorf("Expected request body to contain %s, got %s", part, body)
+		}
+	}
+}
+
+// serveUpdate accepts a single DNS message over TCP, returns it on the
+// channel and answers with rcode.
+func serveUpdate(t *testing.T, rcode byte) (string, <-chan []byte) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var length [2]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return
+		}
+		msg := make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err := io.ReadFull(conn, msg); err != nil {
+			return
+		}
+		received <- msg
+
+		resp := make([]byte, 12)
+		copy(resp, msg[:2])
+		resp[2] = 0x80 | opcodeUpdate<<3
+		resp[3] = rcode
+		conn.Write(binary.BigEndian.AppendUint16(nil, uint16(len(resp))))
+		conn.Write(resp)
+	}()
+
+	return listener.Addr().String(), received
+}
+
+func TestRFC2136_Replace(t *testing.T) {
+	server, received := serveUpdate(t, 0)
+
+	provider, err := NewRFC2136(config.DNSProvider{
+		Name:       "bind",
+		Server:     server,
+		Zone:       "example.com",
+		TSIGKey:    "proxwarden",
+		TSIGSecret: base64.StdEncoding.EncodeToString([]byte("secret")),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	if err := provider.Replace(context.Background(), "app.example.com", TypeA, []string{"10.0.0.5", "10.0.0.6"}, 60); err != nil {
+		t.Fatalf("Replace failed: %v", err)
+	}
+
+	msg := <-received
+	if opcode := msg[2] >> 3 & 0x0f; opcode != opcodeUpdate {
+		t.Errorf("Expected update opcode, got %d", opcode)
+	}
+	counts := []uint16{
+		binary.BigEndian.Uint16(msg[4:]),
+		binary.BigEndian.Uint16(msg[6:]),
+		binary.BigEndian.Uint16(msg[8:]),
+		binary.BigEndian.Uint16(msg[10:]),
+	}
+	// One zone, no prerequisites, a delete and two adds, and the TSIG record
+	if !reflect.DeepEqual(counts, []uint16{1, 0, 3, 1}) {
+		t.Errorf("Unexpected section counts %v", counts)
+	}
+	if !strings.Contains(string(msg), "\x0bhmac-sha256\x00") {
+		t.Error("Expected TSIG record with hmac-sha256")
+	}
+}
+
+func TestRFC2136_Refused(t *testing.T) {
+	server, _ := serveUpdate(t, 5)
+
+	provider, err := NewRFC2136(config.DNSProvider{Name: "bind", Server: server, Zone: "example.com"})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	err = provider.Replace(context.Background(), "app.example.com", TypeA, []string{"10.0.0.5"}, 60)
+	if err == nil || !strings.Contains(err.Error(), "REFUSED") {
+		t.Errorf("Expected REFUSED error, got %v", err)
+	}
+}
+
+func TestRFC2136_Message(t *testing.T) {
+	provider, err := NewRFC2136(config.DNSProvider{Name: "bind", Server: "127.0.0.1:53", Zone: "example.com"})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		recordType string
+		value      string
+		rdata      []byte
+		expectErr  bool
+	}{
+		{"A", TypeA, "10.0.0.5", []byte{10, 0, 0, 5}, false},
+		{"A from IPv4-mapped address", TypeA, "::ffff:10.0.0.5", []byte{10, 0, 0, 5}, false},
+		{"A with IPv6 address", TypeA, "2001:db8::1", nil, true},
+		{"AAAA", TypeAAAA, "2001:db8::1", net.ParseIP("2001:db8::1"), false},
+		{"AAAA with IPv4 address", TypeAAAA, "10.0.0.5", nil, true},
+		{"AAAA with IPv4-mapped address", TypeAAAA, "::ffff:10.0.0.5", nil, true},
+		{"not an address", TypeA, "app.example.com", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, _, err := provider.message("app.example.com", tt.recordType, []string{tt.value}, 60)
+			if tt.expectErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("message failed: %v", err)
+			}
+			// The added record ends the unsigned message, after its length
+			rdata := msg[len(msg)-len(tt.rdata):]
+			length := binary.BigEndian.Uint16(msg[len(msg)-len(tt.rdata)-2:])
+			if int(length) != len(tt.rdata) || !reflect.DeepEqual(rdata, tt.rdata) {
+				t.Errorf("Expected record data %v, got %v of length %d", tt.rdata, rdata, length)
+			}
+		})
+	}
+}
+
+func TestRecordValues(t *testing.T) {
+	values := recordValues([]net.IP{net.ParseIP("2001:db8::1"), net.ParseIP("10.0.0.1"), net.ParseIP("::ffff:10.0.0.2")})
+	var types []string
+	for recordType := range values {
+		types = append(types, recordType)
+	}
+	sort.Strings(types)
+	if !reflect.DeepEqual(types, []string{TypeA, TypeAAAA}) {
+		t.Errorf("Unexpected record types %v", types)
+	}
+	if !reflect.DeepEqual(values[TypeA], []string{"10.0.0.1", "10.0.0.2"}) {
+		t.Errorf("Unexpected A values %v", values[TypeA])
+	}
+}
@@ -0,0 +1,99 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+// PowerDNS manages records through the PowerDNS Authoritative HTTP API.
+type PowerDNS struct {
+	name     string
+	baseURL  string
+	apiKey   string
+	serverID string
+	zone     string
+	client   *http.Client
+}
+
+type powerDNSPatch struct {
+	RRSets []powerDNSRRSet `json:"rrsets"`
+}
+
+type powerDNSRRSet struct {
+	Name       string           `json:"name"`
+	Type       string           `json:"type"`
+	TTL        int              `json:"ttl"`
+	ChangeType string           `json:"changetype"`
+	Records    []powerDNSRecord `json:"records"`
+}
+
+type powerDNSRecord struct {
+	Content  string `json:"content"`
+	Disabled bool   `json:"disabled"`
+}
+
+func NewPowerDNS(provider config.DNSProvider) *PowerDNS {
+	serverID := provider.ServerID
+	if serverID == "" {
+		serverID = "localhost"
+	}
+	return &PowerDNS{
+		name:     provider.Name,
+		baseURL:  strings.TrimSuffix(provider.URL, "/"),
+		apiKey:   provider.APIKey,
+		serverID: serverID,
+		zone:     fqdn(provider.Zone),
+		client:   &http.Client{},
+	}
+}
+
+func (p *PowerDNS) Name() string {
+	return p.name
+}
+
+func (p *PowerDNS) Replace(ctx context.Context, name, recordType string, values []string, ttl int) error {
+	rrset := powerDNSRRSet{
+		Name:       fqdn(name),
+		Type:       recordType,
+		TTL:        ttl,
+		ChangeType: "REPLACE",
+	}
+	for _, value := range values {
+		rrset.Records = append(rrset.Records, powerDNSRecord{Content: value})
+	}
+
+	body, err := json.Marshal(powerDNSPatch{RRSets: []powerDNSRRSet{rrset}})
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("%s/api/v1/servers/%s/zones/%s", p.baseURL, url.PathEscape(p.serverID), url.PathEscape(p.zone))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", p.apiKey)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("powerdns returned %d: %s", resp.StatusCode, apiErr.Error)
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+package dns
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+// DNS wire format constants used by dynamic updates.
+const (
+	opcodeUpdate = 5
+	classIN      = 1
+	classANY     = 255
+	typeSOA      = 6
+	typeTSIG     = 250
+	typeA        = 1
+	typeAAAA     = 28
+
+	// tsigFudge is the clock skew in seconds the server may allow
+	tsigFudge = 300
+)
+
+var rcodeNames = map[int]string{
+	1:  "FORMERR",
+	2:  "SERVFAIL",
+	3:  "NXDOMAIN",
+	4:  "NOTIMP",
+	5:  "REFUSED",
+	6:  "YXDOMAIN",
+	7:  "YXRRSET",
+	8:  "NXRRSET",
+	9:  "NOTAUTH",
+	10: "NOTZONE",
+}
+
+var tsigHashes = map[string]func() hash.Hash{
+	"hmac-sha1":   sha1.New,
+	"hmac-sha256": sha256.New,
+	"hmac-sha512": sha512.New,
+}
+
+// RFC2136 sends dynamic DNS updates over TCP to an authoritative server,
+// such as BIND or Knot, signed with TSIG when a key is configured.
+type RFC2136 struct {
+	name      string
+	server    string
+	zone      string
+	keyName   string
+	secret    []byte
+	algorithm string
+	now       func() time.Time
+}
+
+func NewRFC2136(provider config.DNSProvider) (*RFC2136, error) {
+	r := &RFC2136{
+		name:      provider.Name,
+		server:    provider.Server,
+		zone:      provider.Zone,
+		keyName:   provider.TSIGKey,
+		algorithm: provider.TSIGAlgorithm,
+		now:       time.Now,
+	}
+	if r.algorithm == "" {
+		r.algorithm = "hmac-sha256"
+	}
+	if _, exists := tsigHashes[r.algorithm]; !exists {
+		return nil, fmt.Errorf("unsupported tsig algorithm: %s", r.algorithm)
+	}
+	if _, _, err := net.SplitHostPort(r.server); err != nil {
+		r.server = net.JoinHostPort(r.server, "53")
+	}
+
+	if r.keyName != "" {
+		secret, err := base64.StdEncoding.DecodeString(provider.TSIGSecret)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tsig secret: %w", err)
+		}
+		r.secret = secret
+	}
+	return r, nil
+}
+
+func (r *RFC2136) Name() string {
+	return r.name
+}
+
+// Replace deletes the record set and adds values in a single update, which
+// the server applies atomically.
+func (r *RFC2136) Replace(ctx context.Context, name, recordType string, values []string, ttl int) error {
+	msg, id, err := r.message(name, recordType, values, ttl)
+	if err != nil {
+		return err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", r.server)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", r.server, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// Messages over TCP are prefixed with their length
+	if _, err := conn.Write(binary.BigEndian.AppendUint16(nil, uint16(len(msg)))); err != nil {
+		return fmt.Errorf("failed to send update: %w", err)
+	}
+	if _, err := conn.Write(msg); err != nil {
+		return fmt.Errorf("failed to send update: %w", err)
+	}
+
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if len(resp) < 12 {
+		return fmt.Errorf("short response")
+	}
+	if binary.BigEndian.Uint16(resp) != id {
+		return fmt.Errorf("response ID mismatch")
+	}
+	if rcode := int(resp[3] & 0x0f); rcode != 0 {
+		rcodeName, known := rcodeNames[rcode]
+		if !known {
+			rcodeName = fmt.Sprintf("RCODE%d", rcode)
+		}
+		return fmt.Errorf("server refused update: %s", rcodeName)
+	}
+	return nil
+}
+
+// message builds the update message and returns it with its ID.
+func (r *RFC2136) message(name, recordType string, values []string, ttl int) ([]byte, uint16, error) {
+	rrType := uint16(typeA)
+	if recordType == TypeAAAA {
+		rrType = typeAAAA
+	}
+
+	var idBytes [2]byte
+	if _, err := rand.Read(idBytes[:]); err != nil {
+		return nil, 0, fmt.Errorf("failed to generate message ID: %w", err)
+	}
+	id := binary.BigEndian.Uint16(idBytes[:])
+
+	// Header: zone count 1, no prerequisites, delete plus one add per value
+	msg := binary.BigEndian.AppendUint16(nil, id)
+	msg = binary.BigEndian.AppendUint16(msg, opcodeUpdate<<11)
+	msg = binary.BigEndian.AppendUint16(msg, 1)
+	msg = binary.BigEndian.AppendUint16(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, uint16(1+len(values)))
+	msg = binary.BigEndian.AppendUint16(msg, 0)
+
+	// Zone section
+	msg, err := appendName(msg, r.zone)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid zone: %w", err)
+	}
+	msg = binary.BigEndian.AppendUint16(msg, typeSOA)
+	msg = binary.BigEndian.AppendUint16(msg, classIN)
+
+	// Delete the whole record set of the type
+	if msg, err = appendName(msg, name); err != nil {
+		return nil, 0, fmt.Errorf("invalid name: %w", err)
+	}
+	msg = binary.BigEndian.AppendUint16(msg, rrType)
+	msg = binary.BigEndian.AppendUint16(msg, classANY)
+	msg = binary.BigEndian.AppendUint32(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, 0)
+
+	for _, value := range values {
+		ip := net.ParseIP(value)
+		var rdata net.IP
+		switch {
+		case rrType == typeA:
+			rdata = ip.To4()
+		case ip.To4() == nil:
+			// IPv4 addresses are no AAAA records, even mapped to IPv6
+			rdata = ip.To16()
+		}
+		if ip == nil || rdata == nil {
+			return nil, 0, fmt.Errorf("invalid %s value %q", recordType, value)
+		}
+
+		msg, _ = appendName(msg, name)
+		msg = binary.BigEndian.AppendUint16(msg, rrType)
+		msg = binary.BigEndian.AppendUint16(msg, classIN)
+		msg = binary.BigEndian.AppendUint32(msg, uint32(ttl))
+		msg = binary.BigEndian.AppendUint16(msg, uint16(len(rdata)))
+		msg = append(msg, rdata...)
+	}
+
+	if r.keyName == "" {
+		return msg, id, nil
+	}
+	msg, err = r.sign(msg, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	return msg, id, nil
+}
+
+// sign appends a TSIG record (RFC 8945) to msg.
+func (r *RFC2136) sign(msg []byte, id uint16) ([]byte, error) {
+	keyName, err := appendName(nil, strings.ToLower(r.keyName))
+	if err != nil {
+		return nil, fmt.Errorf("invalid tsig key name: %w", err)
+	}
+	algorithm, _ := appendName(nil, r.algorithm)
+	now := uint64(r.now().Unix())
+
+	// Time signed is 48 bits, followed by the fudge
+	timers := binary.BigEndian.AppendUint16(nil, uint16(now>>32))
+	timers = binary.BigEndian.AppendUint32(timers, uint32(now))
+	timers = binary.BigEndian.AppendUint16(timers, tsigFudge)
+
+	// The MAC covers the unsigned message and the TSIG variables
+	mac := hmac.New(tsigHashes[r.algorithm], r.secret)
+	mac.Write(msg)
+	mac.Write(keyName)
+	mac.Write(binary.BigEndian.AppendUint16(nil, classANY))
+	mac.Write(binary.BigEndian.AppendUint32(nil, 0))
+	mac.Write(algorithm)
+	mac.Write(timers)
+	mac.Write(binary.BigEndian.AppendUint16(nil, 0)) // error
+	mac.Write(binary.BigEndian.AppendUint16(nil, 0)) // other length
+	sum := mac.Sum(nil)
+
+	rdata := append(algorithm, timers...)
+	rdata = binary.BigEndian.AppendUint16(rdata, uint16(len(sum)))
+	rdata = append(rdata, sum...)
+	rdata = binary.BigEndian.AppendUint16(rdata, id)
+	rdata = binary.BigEndian.AppendUint16(rdata, 0) // error
+	rdata = binary.BigEndian.AppendUint16(rdata, 0) // other length
+
+	signed := append([]byte{}, msg...)
+	binary.BigEndian.PutUint16(signed[10:], binary.BigEndian.Uint16(msg[10:])+1)
+	signed = append(signed, keyName...)
+	signed = binary.BigEndian.AppendUint16(signed, typeTSIG)
+	signed = binary.BigEndian.AppendUint16(signed, classANY)
+	signed = binary.BigEndian.AppendUint32(signed, 0)
+	signed = binary.BigEndian.AppendUint16(signed, uint16(len(rdata)))
+	signed = append(signed, rdata...)
+	return signed, nil
+}
+
+// appendName appends name in uncompressed wire format.
+func appendName(b []byte, name string) ([]byte, error) {
+	name = strings.TrimSuffix(name, ".")
+	if len(name) > 253 {
+		return nil, fmt.Errorf("name too long: %s", name)
+	}
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			if len(label) == 0 || len(label) > 63 {
+				return nil, fmt.Errorf("invalid label in %s", name)
+			}
+			b = append(b, byte(len(label)))
+			b = append(b, label...)
+		}
+	}
+	return append(b, 0), nil
+}
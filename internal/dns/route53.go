@@ -0,0 +1,193 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+const (
+	route53API      = "https://route53.amazonaws.com"
+	route53Region   = "us-east-1"
+	route53Service  = "route53"
+	route53Version  = "2013-04-01"
+	route53XMLSpace = "https://route53.amazonaws.com/doc/2013-04-01/"
+)
+
+// Route53 manages records through the AWS Route 53 API, signing requests
+// with AWS Signature Version 4.
+type Route53 struct {
+	name            string
+	baseURL         string
+	zoneID          string
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+	region          string
+	client          *http.Client
+	now             func() time.Time
+}
+
+type route53ChangeRequest struct {
+	XMLName xml.Name        `xml:"ChangeResourceRecordSetsRequest"`
+	Xmlns   string          `xml:"xmlns,attr"`
+	Changes []route53Change `xml:"ChangeBatch>Changes>Change"`
+}
+
+type route53Change struct {
+	Action string         `xml:"Action"`
+	Name   string         `xml:"ResourceRecordSet>Name"`
+	Type   string         `xml:"ResourceRecordSet>Type"`
+	TTL    int            `xml:"ResourceRecordSet>TTL"`
+	Values []route53Value `xml:"ResourceRecordSet>ResourceRecords>ResourceRecord"`
+}
+
+type route53Value struct {
+	Value string `xml:"Value"`
+}
+
+type route53Error struct {
+	Code    string `xml:"Error>Code"`
+	Message string `xml:"Error>Message"`
+}
+
+// NewRoute53 uses the configured credentials, falling back to the
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment
+// variables.
+func NewRoute53(provider config.DNSProvider) *Route53 {
+	r := &Route53{
+		name:            provider.Name,
+		baseURL:         strings.TrimSuffix(provider.URL, "/"),
+		zoneID:          strings.TrimPrefix(provider.ZoneID, "/hostedzone/"),
+		accessKeyID:     provider.AccessKeyID,
+		secretAccessKey: provider.SecretAccessKey,
+		region:          provider.Region,
+		client:          &http.Client{},
+		now:             time.Now,
+	}
+	if r.baseURL == "" {
+		r.baseURL = route53API
+	}
+	if r.region == "" {
+		r.region = route53Region
+	}
+	if r.accessKeyID == "" {
+		r.accessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		r.secretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		r.sessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	return r
+}
+
+func (r *Route53) Name() string {
+	return r.name
+}
+
+func (r *Route53) Replace(ctx context.Context, name, recordType string, values []string, ttl int) error {
+	if r.accessKeyID == "" || r.secretAccessKey == "" {
+		return fmt.Errorf("no AWS credentials configured")
+	}
+
+	records := make([]route53Value, len(values))
+	for i, value := range values {
+		records[i] = route53Value{Value: value}
+	}
+
+	body, err := xml.Marshal(route53ChangeRequest{
+		Xmlns: route53XMLSpace,
+		Changes: []route53Change{{
+			Action: "UPSERT",
+			Name:   fqdn(name),
+			Type:   recordType,
+			TTL:    ttl,
+			Values: records,
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	body = append([]byte(xml.Header), body...)
+
+	endpoint := fmt.Sprintf("%s/%s/hostedzone/%s/rrset", r.baseURL, route53Version, r.zoneID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/xml")
+	r.sign(req, body, r.now())
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr route53Error
+		xml.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("route53 returned %d: %s: %s", resp.StatusCode, apiErr.Code, apiErr.Message)
+	}
+	return nil
+}
+
+// sign adds the Signature Version 4 headers to req.
+func (r *Route53) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	headers := []string{"host:" + req.URL.Host, "x-amz-date:" + amzDate}
+	signedHeaders := "host;x-amz-date"
+	if r.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", r.sessionToken)
+		headers = append(headers, "x-amz-security-token:"+r.sessionToken)
+		signedHeaders += ";x-amz-security-token"
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		strings.Join(headers, "\n") + "\n",
+		signedHeaders,
+		sha256Hex(body),
+	}, "\n")
+
+	scope := strings.Join([]string{date, r.region, route53Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+r.secretAccessKey), date)
+	key = hmacSHA256(key, r.region)
+	key = hmacSHA256(key, route53Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		r.accessKeyID, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,512 @@
+// Package doctor diagnoses the environment ProxWarden runs in: whether the
+// Proxmox API answers, the API user may do what failovers do, the storages
+// failovers need are usable on the failover nodes, the clocks agree and the
+// programs hooks and plugins run exist.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/configcheck"
+)
+
+// Outcomes of a diagnostic.
+const (
+	StatusPass = "pass"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+)
+
+// Clock skew between ProxWarden and a node above which a diagnostic warns
+// and fails.
+const (
+	skewWarning = 2 * time.Second
+	skewFailure = 30 * time.Second
+)
+
+// assignment matches a variable assignment preceding a shell command.
+var assignment = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// minFreeSpace is the share of a restore storage below which free space is
+// warned about.
+const minFreeSpace = 0.1
+
+// Result is the outcome of a single diagnostic.
+type Result struct {
+	Check  string `json:"check"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// Client is the part of the Proxmox API the diagnostics need.
+type Client interface {
+	configcheck.Client
+	GetPermissions(ctx context.Context, path string) (map[string]bool, error)
+	GetStorageStatus(ctx context.Context, node, storage string) (*api.StorageStatus, error)
+	GetNodeTime(ctx context.Context, node string) (time.Time, error)
+}
+
+// Diagnose runs every diagnostic against cfg and the cluster client reaches
+// and returns the results in order. The diagnostics of the cluster are
+// skipped when the API does not answer.
+func Diagnose(ctx context.Context, cfg *config.Config, client Client) []Result {
+	d := &doctor{cfg: cfg, client: client, permissions: make(map[string]map[string]bool)}
+
+	if cluster, ok := d.api(ctx); ok {
+		d.permissionsOf(ctx)
+		d.storages(ctx, cluster)
+		d.clocks(ctx, cluster)
+	}
+	d.programs()
+	return d.results
+}
+
+type doctor struct {
+	cfg         *config.Config
+	client      Client
+	results     []Result
+	permissions map[string]map[string]bool
+}
+
+func (d *doctor) add(check, status, format string, args ...interface{}) {
+	d.results = append(d.results, Result{Check: check, Status: status, Detail: fmt.Sprintf(format, args...)})
+}
+
+// api checks that the API answers and that the nodes, storages and
+// containers of the configuration exist.
+func (d *doctor) api(ctx context.Context) (*configcheck.Cluster, bool) {
+	cluster, problems, err := configcheck.Check(ctx, d.cfg, d.client)
+	if err != nil {
+		d.add("Proxmox API", StatusFail, "%v", err)
+		return nil, false
+	}
+
+	online := 0
+	for _, node := range cluster.Nodes {
+		if node.Online {
+			online++
+		}
+	}
+	d.add("Proxmox API", StatusPass, "%s answers: %d of %d nodes online, %d storages, %d containers",
+		d.cfg.Proxmox.Endpoint, online, len(cluster.Nodes), len(cluster.Storages), len(cluster.Containers))
+
+	if len(problems) == 0 {
+		d.add("Cluster references", StatusPass, "every node, storage and container of the configuration exists")
+	}
+	for _, problem := range problems {
+		d.add("Cluster references", StatusFail, "%s", problem)
+	}
+	return cluster, true
+}
+
+// requirement is a privilege needed on a path; any of privileges will do.
+type requirement struct {
+	path       string
+	privileges []string
+}
+
+// permissionsOf checks that the API user may do what monitoring and
+// failovers do to the containers of the configuration.
+func (d *doctor) permissionsOf(ctx context.Context) {
+	var vms []string
+	for _, container := range d.cfg.Monitoring.Containers {
+		vms = append(vms, fmt.Sprintf("/vms/%d", container.ID))
+	}
+	if d.cfg.Monitoring.Discovers() {
+		vms = append(vms, "/vms")
+	}
+	if len(vms) == 0 {
+		return
+	}
+
+	onVMs := func(privileges ...string) []requirement {
+		var requirements []requirement
+		for _, vm := range vms {
+			requirements = append(requirements, requirement{path: vm, privileges: privileges})
+		}
+		return requirements
+	}
+	onStorages := func(requirements []requirement, storages []string) []requirement {
+		for _, storage := range storages {
+			requirements = append(requirements, requirement{path: "/storage/" + storage, privileges: []string{"Datastore.AllocateSpace"}})
+		}
+		return requirements
+	}
+
+	d.require(ctx, "Permission to monitor", onVMs("VM.Audit"))
+	d.require(ctx, "Permission to stop and start", onVMs("VM.PowerMgmt"))
+	d.require(ctx, "Permission to back up", onStorages(onVMs("VM.Backup"), d.backupStorages()))
+	d.require(ctx, "Permission to restore", onStorages(onVMs("VM.Allocate", "VM.Backup"), d.restoreStorages()))
+	if d.migrates() {
+		d.require(ctx, "Permission to migrate", onVMs("VM.Migrate"))
+	}
+}
+
+// require adds the result of a check that every requirement is met.
+func (d *doctor) require(ctx context.Context, check string, requirements []requirement) {
+	var missing []string
+	for _, req := range requirements {
+		granted, ok := d.permissions[req.path]
+		if !ok {
+			var err error
+			granted, err = d.client.GetPermissions(ctx, req.path)
+			if err != nil {
+				d.add(check, StatusWarn, "permissions could not be read: %v", err)
+				return
+			}
+			d.permissions[req.path] = granted
+		}
+
+		met := false
+		for _, privilege := range req.privileges {
+			met = met || granted[privilege]
+		}
+		if !met {
+			missing = append(missing, fmt.Sprintf("%s on %s", strings.Join(req.privileges, " or "), req.path))
+		}
+	}
+
+	if len(missing) > 0 {
+		d.add(check, StatusFail, "%s is missing %s", d.cfg.Proxmox.Username, strings.Join(missing, ", "))
+		return
+	}
+	d.add(check, StatusPass, "%s has %s", d.cfg.Proxmox.Username, privilegeList(requirements))
+}
+
+// storages checks that the storages failovers restore to, and read
+// backups from, are active on the failover nodes.
+func (d *doctor) storages(ctx context.Context, cluster *configcheck.Cluster) {
+	type use struct {
+		node, storage string
+		// restore is whether containers are restored to the storage,
+		// rather than only backups restored from it
+		restore bool
+	}
+	var uses []*use
+	seen := make(map[string]*use)
+	for _, container := range d.failoverContainers() {
+		for _, node := range container.FailoverNodes {
+			for _, u := range []use{
+				{node: node, storage: configcheck.RestoreStorage(container, node), restore: true},
+				{node: node, storage: d.backupStorage(container)},
+			} {
+				key := u.node + "/" + u.storage
+				switch {
+				case u.storage == "":
+				case seen[key] != nil:
+					seen[key].restore = seen[key].restore || u.restore
+				default:
+					u := u
+					seen[key] = &u
+					uses = append(uses, &u)
+				}
+			}
+		}
+	}
+
+	for _, u := range uses {
+		check := fmt.Sprintf("Storage %s on %s", u.storage, u.node)
+		if node, exists := cluster.Nodes[u.node]; !exists || !node.Online {
+			d.add(check, StatusWarn, "node %s is not online", u.node)
+			continue
+		}
+		storage, exists := cluster.Storages[u.storage]
+		if !exists || (u.restore && !storage.AvailableOn(u.node)) {
+			// Reported as a cluster reference already
+			continue
+		}
+		if !storage.AvailableOn(u.node) {
+			d.add(check, StatusFail, "not available, so failovers to %s cannot restore backups from it", u.node)
+			continue
+		}
+
+		status, err := d.client.GetStorageStatus(ctx, u.node, u.storage)
+		switch {
+		case err != nil:
+			d.add(check, StatusFail, "%v", err)
+		case !bool(status.Enabled) || !bool(status.Active):
+			d.add(check, StatusFail, "not active, so failovers to %s cannot %s", u.node, storageUse(u.restore))
+		case !u.restore:
+			d.add(check, StatusPass, "active, backups can be restored from it")
+		case status.Total > 0 && float64(status.Avail) < minFreeSpace*float64(status.Total):
+			d.add(check, StatusWarn, "active, only %s of %s free", formatBytes(status.Avail), formatBytes(status.Total))
+		default:
+			d.add(check, StatusPass, "active, %s of %s free", formatBytes(status.Avail), formatBytes(status.Total))
+		}
+	}
+}
+
+// clocks checks that the clocks of the online nodes agree with this
+// host's, as timeouts, cooldowns and backup ages compare them.
+func (d *doctor) clocks(ctx context.Context, cluster *configcheck.Cluster) {
+	var nodes []string
+	for name, node := range cluster.Nodes {
+		if node.Online {
+			nodes = append(nodes, name)
+		}
+	}
+	sort.Strings(nodes)
+
+	for _, node := range nodes {
+		check := "Clock of " + node
+		before := time.Now()
+		clock, err := d.client.GetNodeTime(ctx, node)
+		if err != nil {
+			d.add(check, StatusWarn, "%v", err)
+			continue
+		}
+		// Compare with the middle of the request; the node reports whole
+		// seconds
+		local := before.Add(time.Since(before) / 2).Truncate(time.Second)
+		skew := clock.Sub(local)
+		if skew < 0 {
+			skew = -skew
+		}
+
+		switch {
+		case skew > skewFailure:
+			d.add(check, StatusFail, "%s off from this host", skew)
+		case skew > skewWarning:
+			d.add(check, StatusWarn, "%s off from this host", skew)
+		default:
+			d.add(check, StatusPass, "within %s of this host", skewWarning)
+		}
+	}
+}
+
+// programs checks that the programs hooks and plugins run exist.
+func (d *doctor) programs() {
+	if _, err := exec.LookPath("sh"); err != nil {
+		d.add("Shell", StatusFail, "sh, which runs hooks, not found: %v", err)
+	}
+
+	var missing, found []string
+	seen := make(map[string]bool)
+	for _, command := range d.commands() {
+		program := command.command
+		if !command.exec {
+			program = firstProgram(command.command)
+		}
+		if program == "" || seen[program] {
+			continue
+		}
+		seen[program] = true
+		if err := lookPath(program); err != nil {
+			missing = append(missing, fmt.Sprintf("%s (%s)", program, command.use))
+		} else {
+			found = append(found, program)
+		}
+	}
+
+	switch {
+	case len(missing) > 0:
+		d.add("Hook and plugin programs", StatusFail, "not found: %s", strings.Join(missing, ", "))
+	case len(found) > 0:
+		d.add("Hook and plugin programs", StatusPass, "found %s", strings.Join(found, ", "))
+	}
+}
+
+// command is a command of the configuration, with what runs it.
+type command struct {
+	use     string
+	command string
+	// exec means the command is a program run directly rather than by sh
+	exec bool
+}
+
+// commands returns the hook and plugin commands of the configuration.
+func (d *doctor) commands() []command {
+	var commands []command
+	hooks := func(use string, hooks []config.Hook) {
+		for _, hook := range hooks {
+			commands = append(commands, command{use: use, command: hook.Command})
+		}
+	}
+	plugin := func(use, path string) {
+		if path != "" {
+			commands = append(commands, command{use: use, command: path, exec: true})
+		}
+	}
+
+	hooks("pre-failover hook", d.cfg.Failover.PreFailoverHooks)
+	hooks("post-failover hook", d.cfg.Failover.PostFailoverHooks)
+	hooks("pre-backup hook", d.cfg.Backup.PreHooks)
+	hooks("post-backup hook", d.cfg.Backup.PostHooks)
+	hooks("replication command", []config.Hook{d.cfg.Backup.Replication.Command})
+	for _, fence := range d.cfg.Failover.Fencing.Hooks {
+		hooks("fencing hook", []config.Hook{{Command: fence}})
+	}
+	for _, fence := range d.cfg.Failover.Fencing.Plugins {
+		plugin("fence plugin", fence.Command)
+	}
+	plugin("placement plugin", d.cfg.Failover.PlacementPlugin.Command)
+	for _, provider := range d.cfg.Notifications.Providers {
+		plugin("notification plugin", provider.Command)
+	}
+
+	for _, container := range d.failoverContainers() {
+		hooks("pre-backup hook", container.PreBackupHooks)
+		hooks("post-backup hook", container.PostBackupHooks)
+		if floating := container.FloatingIP; floating != nil {
+			hooks("floating IP command", []config.Hook{floating.Release, floating.Acquire})
+		}
+		for _, check := range container.HealthChecks {
+			plugin("check plugin", check.Command)
+		}
+	}
+	return commands
+}
+
+// failoverContainers returns the configured containers and the containers
+// entries of selectors.
+func (d *doctor) failoverContainers() []config.ContainerConfig {
+	containers := append([]config.ContainerConfig{}, d.cfg.Monitoring.Containers...)
+	containers = append(containers, d.cfg.Monitoring.Selectors...)
+	if discover := d.cfg.Monitoring.Discover; discover.Enabled() {
+		containers = append(containers, config.ContainerConfig{
+			FailoverNodes: discover.FailoverNodes,
+			Storage:       discover.Storage,
+			HealthChecks:  discover.HealthChecks,
+		})
+	}
+	return containers
+}
+
+func (d *doctor) backupStorage(container config.ContainerConfig) string {
+	if container.BackupStorage != "" {
+		return container.BackupStorage
+	}
+	return d.cfg.Backup.Storage
+}
+
+func (d *doctor) backupStorages() []string {
+	var storages []string
+	for _, container := range d.failoverContainers() {
+		storages = appendUnique(storages, d.backupStorage(container))
+	}
+	return storages
+}
+
+func (d *doctor) restoreStorages() []string {
+	var storages []string
+	for _, container := range d.failoverContainers() {
+		for _, node := range container.FailoverNodes {
+			storages = appendUnique(storages, configcheck.RestoreStorage(container, node))
+		}
+	}
+	return storages
+}
+
+// migrates reports whether failovers or failbacks may migrate containers.
+func (d *doctor) migrates() bool {
+	uses := func(strategy string) bool {
+		return strategy == config.StrategyMigrate || strategy == config.StrategyAuto
+	}
+	if uses(d.cfg.Failover.Strategy) || uses(d.cfg.Failover.Failback.Strategy) {
+		return true
+	}
+	for _, container := range d.failoverContainers() {
+		if uses(container.Strategy) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstProgram returns the program a shell command runs first, skipping
+// variable assignments, or "" if it starts with shell syntax or a builtin.
+func firstProgram(command string) string {
+	for _, field := range strings.Fields(command) {
+		if assignment.MatchString(field) {
+			continue
+		}
+		field = strings.Trim(field, `'"`)
+		if strings.ContainsAny(field, "$`(){}[];|&<>") {
+			return ""
+		}
+		if isBuiltin(field) {
+			return ""
+		}
+		return field
+	}
+	return ""
+}
+
+// isBuiltin reports whether name is a shell builtin or keyword rather than
+// a program.
+func isBuiltin(name string) bool {
+	switch name {
+	case "!", ".", ":", "cd", "echo", "eval", "exec", "exit", "export", "false", "for", "if",
+		"printf", "read", "set", "test", "true", "unset", "while":
+		return true
+	}
+	return false
+}
+
+// lookPath finds a program as sh would: by path if it has a slash, in
+// PATH otherwise.
+func lookPath(program string) error {
+	if !strings.Contains(program, "/") {
+		_, err := exec.LookPath(program)
+		return err
+	}
+	info, err := os.Stat(filepath.Clean(program))
+	if err != nil {
+		return err
+	}
+	if info.IsDir() || info.Mode()&0o111 == 0 {
+		return fmt.Errorf("%s is not executable", program)
+	}
+	return nil
+}
+
+func storageUse(restore bool) string {
+	if restore {
+		return "restore containers to it"
+	}
+	return "restore backups from it"
+}
+
+func privilegeList(requirements []requirement) string {
+	var privileges []string
+	for _, req := range requirements {
+		privileges = appendUnique(privileges, strings.Join(req.privileges, " or "))
+	}
+	return strings.Join(privileges, ", ")
+}
+
+func appendUnique(values []string, value string) []string {
+	if value == "" {
+		return values
+	}
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// formatBytes formats a size with a binary unit.
+func formatBytes(size uint64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := uint64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
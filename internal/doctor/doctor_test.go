@@ -0,0 +1,151 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+type fakeClient struct {
+	nodes       []*api.NodeInfo
+	storages    []api.StorageInfo
+	containers  []api.ClusterResource
+	permissions map[string]map[string]bool
+	status      map[string]*api.StorageStatus
+	skew        map[string]time.Duration
+	err         error
+}
+
+func (f *fakeClient) GetNodes(ctx context.Context) ([]*api.NodeInfo, error) {
+	return f.nodes, f.err
+}
+
+func (f *fakeClient) GetStorages(ctx context.Context) ([]api.StorageInfo, error) {
+	return f.storages, nil
+}
+
+func (f *fakeClient) GetClusterResources(ctx context.Context) ([]api.ClusterResource, error) {
+	return f.containers, nil
+}
+
+func (f *fakeClient) GetPermissions(ctx context.Context, path string) (map[string]bool, error) {
+	return f.permissions[path], nil
+}
+
+func (f *fakeClient) GetStorageStatus(ctx context.Context, node, storage string) (*api.StorageStatus, error) {
+	status, ok := f.status[node+"/"+storage]
+	if !ok {
+		return nil, errors.New("no such storage")
+	}
+	return status, nil
+}
+
+func (f *fakeClient) GetNodeTime(ctx context.Context, node string) (time.Time, error) {
+	return time.Now().Add(f.skew[node]), nil
+}
+
+func testConfig() *config.Config {
+	return &config.Config{
+		Proxmox: config.ProxmoxConfig{Endpoint: "https://pve1:8006", Username: "proxwarden@pve"},
+		Backup:  config.BackupConfig{Storage: "nfs"},
+		Monitoring: config.MonitoringConfig{Containers: []config.ContainerConfig{
+			{ID: 100, FailoverNodes: []string{"pve2", "pve3"}},
+		}},
+		Failover: config.FailoverConfig{
+			PreFailoverHooks:  []config.Hook{{Command: "DRY=1 /nonexistent/drain.sh --now"}, {Command: "echo starting"}},
+			PostFailoverHooks: []config.Hook{{Command: "sh -c true"}},
+		},
+	}
+}
+
+func testClient() *fakeClient {
+	return &fakeClient{
+		nodes: []*api.NodeInfo{{Name: "pve1", Online: true}, {Name: "pve2", Online: true}, {Name: "pve3"}},
+		storages: []api.StorageInfo{
+			{Name: "nfs", Content: "backup"},
+			{Name: "local-lvm", Content: "rootdir,images"},
+		},
+		containers: []api.ClusterResource{{ID: 100}},
+		permissions: map[string]map[string]bool{
+			"/vms/100":     {"VM.Audit": true, "VM.PowerMgmt": true, "VM.Backup": true},
+			"/storage/nfs": {"Datastore.AllocateSpace": true},
+		},
+		status: map[string]*api.StorageStatus{
+			"pve2/local-lvm": {Active: true, Enabled: true, Avail: 5 << 30, Total: 100 << 30},
+			"pve2/nfs":       {Active: false, Enabled: true},
+		},
+		skew: map[string]time.Duration{"pve2": time.Minute},
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	results := Diagnose(context.Background(), testConfig(), testClient())
+
+	expected := map[string]string{
+		"Proxmox API":                  StatusPass,
+		"Cluster references":           StatusPass,
+		"Permission to monitor":        StatusPass,
+		"Permission to stop and start": StatusPass,
+		"Permission to back up":        StatusPass,
+		// VM.Backup is enough on the container, but local-lvm lacks
+		// Datastore.AllocateSpace
+		"Permission to restore":     StatusFail,
+		"Storage local-lvm on pve2": StatusWarn,
+		"Storage nfs on pve2":       StatusFail,
+		"Storage local-lvm on pve3": StatusWarn,
+		"Storage nfs on pve3":       StatusWarn,
+		"Clock of pve1":             StatusPass,
+		"Clock of pve2":             StatusFail,
+		"Hook and plugin programs":  StatusFail,
+	}
+	got := make(map[string]string)
+	for _, result := range results {
+		got[result.Check] = result.Status
+	}
+	for check, status := range expected {
+		if got[check] != status {
+			t.Errorf("Expected %s to %s, got %q", check, status, got[check])
+		}
+	}
+	if len(got) != len(expected) {
+		t.Errorf("Expected %d checks, got %+v", len(expected), results)
+	}
+	if _, migrate := got["Permission to migrate"]; migrate {
+		t.Error("Expected no migrate permission check without the migrate strategy")
+	}
+}
+
+func TestDiagnose_Unreachable(t *testing.T) {
+	client := testClient()
+	client.err = errors.New("connection refused")
+
+	results := Diagnose(context.Background(), testConfig(), client)
+	if len(results) != 2 || results[0].Status != StatusFail || results[1].Check != "Hook and plugin programs" {
+		t.Errorf("Expected the API failure and the local checks only, got %+v", results)
+	}
+}
+
+func TestFirstProgram(t *testing.T) {
+	tests := []struct {
+		command  string
+		expected string
+	}{
+		{"/usr/local/bin/update-dns.sh --zone lan", "/usr/local/bin/update-dns.sh"},
+		{"ZONE=lan TTL=60 curl -fsS http://dns/update", "curl"},
+		{"'/opt/hooks/notify' now", "/opt/hooks/notify"},
+		{"echo done", ""},
+		{"$HOME/bin/hook", ""},
+		{"(cd /tmp && make)", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := firstProgram(tt.command); got != tt.expected {
+			t.Errorf("firstProgram(%q) = %q, want %q", tt.command, got, tt.expected)
+		}
+	}
+}
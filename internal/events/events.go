@@ -0,0 +1,153 @@
+// Package events watches the Proxmox cluster task list and turns container
+// and node activity into events, so failures are noticed without waiting for
+// the next scheduled health check.
+package events
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+type Kind string
+
+const (
+	// KindContainer is a finished task affecting a single container
+	KindContainer Kind = "container"
+	// KindNode is a fence or a failed node-level task
+	KindNode Kind = "node"
+)
+
+// containerTaskTypes are task types that change whether a container is
+// serving, so it is worth checking right away.
+var containerTaskTypes = map[string]bool{
+	"vzstart":    true,
+	"vzstop":     true,
+	"vzshutdown": true,
+	"vzreboot":   true,
+	"vzsuspend":  true,
+	"vzdestroy":  true,
+	"vzmigrate":  true,
+	"hastart":    true,
+	"hastop":     true,
+	"hamigrate":  true,
+	"harelocate": true,
+}
+
+type Event struct {
+	Kind        Kind
+	ContainerID int
+	Node        string
+	Task        api.TaskInfo
+}
+
+type Handler func(event Event)
+
+// TaskSource lists recent cluster tasks.
+type TaskSource interface {
+	GetClusterTasks(ctx context.Context) ([]api.TaskInfo, error)
+}
+
+// Watcher polls the cluster task list and reports each finished task once.
+type Watcher struct {
+	source   TaskSource
+	interval time.Duration
+	handler  Handler
+	logger   *logrus.Logger
+	seen     map[string]bool
+	primed   bool
+}
+
+func NewWatcher(cfg *config.EventsConfig, source TaskSource, handler Handler, logger *logrus.Logger) *Watcher {
+	return &Watcher{
+		source:   source,
+		interval: cfg.PollInterval,
+		handler:  handler,
+		logger:   logger,
+		seen:     make(map[string]bool),
+	}
+}
+
+// Start polls until ctx is cancelled.
+func (w *Watcher) Start(ctx context.Context) error {
+	w.logger.WithField("interval", w.interval).Info("Watching Proxmox cluster tasks")
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Watcher) poll(ctx context.Context) {
+	tasks, err := w.source.GetClusterTasks(ctx)
+	if err != nil {
+		w.logger.WithError(err).Warn("Failed to poll cluster tasks")
+		return
+	}
+
+	present := make(map[string]bool, len(tasks))
+	for _, task := range tasks {
+		present[task.UPID] = true
+		if !task.Finished() || w.seen[task.UPID] {
+			continue
+		}
+		w.seen[task.UPID] = true
+
+		// Tasks that finished before the watcher started are history
+		if !w.primed {
+			continue
+		}
+
+		if event, ok := classify(task); ok {
+			w.logger.WithFields(logrus.Fields{
+				"kind":         event.Kind,
+				"node":         event.Node,
+				"container_id": event.ContainerID,
+				"task":         task.Type,
+				"status":       task.Status,
+			}).Debug("Cluster event")
+			w.handler(event)
+		}
+	}
+	w.primed = true
+
+	// Forget tasks that dropped out of the list so the set stays bounded
+	for upid := range w.seen {
+		if !present[upid] {
+			delete(w.seen, upid)
+		}
+	}
+}
+
+// classify decides whether a finished task is worth an event.
+func classify(task api.TaskInfo) (Event, bool) {
+	if strings.Contains(task.Type, "fence") {
+		return Event{Kind: KindNode, Node: task.Node, Task: task}, true
+	}
+
+	containerID, err := strconv.Atoi(task.ID)
+	if err != nil || containerID <= 0 {
+		if task.Failed() {
+			return Event{Kind: KindNode, Node: task.Node, Task: task}, true
+		}
+		return Event{}, false
+	}
+
+	if containerTaskTypes[task.Type] || task.Failed() {
+		return Event{Kind: KindContainer, ContainerID: containerID, Node: task.Node, Task: task}, true
+	}
+	return Event{}, false
+}
@@ -0,0 +1,120 @@
+package events
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeTaskSource struct {
+	tasks []api.TaskInfo
+}
+
+func (f *fakeTaskSource) GetClusterTasks(ctx context.Context) ([]api.TaskInfo, error) {
+	return f.tasks, nil
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name     string
+		task     api.TaskInfo
+		expected *Event
+	}{
+		{
+			"container stop",
+			api.TaskInfo{Node: "node1", Type: "vzstop", ID: "100", Status: "OK", EndTime: 1},
+			&Event{Kind: KindContainer, ContainerID: 100, Node: "node1"},
+		},
+		{
+			"failed container task",
+			api.TaskInfo{Node: "node1", Type: "vzdump", ID: "100", Status: "job errors", EndTime: 1},
+			&Event{Kind: KindContainer, ContainerID: 100, Node: "node1"},
+		},
+		{
+			"successful backup is ignored",
+			api.TaskInfo{Node: "node1", Type: "vzdump", ID: "100", Status: "OK", EndTime: 1},
+			nil,
+		},
+		{
+			"fence",
+			api.TaskInfo{Node: "node2", Type: "hafence", Status: "OK", EndTime: 1},
+			&Event{Kind: KindNode, Node: "node2"},
+		},
+		{
+			"failed node task",
+			api.TaskInfo{Node: "node2", Type: "aptupdate", Status: "command failed", EndTime: 1},
+			&Event{Kind: KindNode, Node: "node2"},
+		},
+		{
+			"successful node task is ignored",
+			api.TaskInfo{Node: "node2", Type: "aptupdate", Status: "OK", EndTime: 1},
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, ok := classify(tt.task)
+			if tt.expected == nil {
+				if ok {
+					t.Errorf("Expected no event, got %+v", event)
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("Expected an event")
+			}
+			if event.Kind != tt.expected.Kind || event.ContainerID != tt.expected.ContainerID || event.Node != tt.expected.Node {
+				t.Errorf("Expected %+v, got %+v", tt.expected, event)
+			}
+		})
+	}
+}
+
+func TestWatcher_Poll(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	source := &fakeTaskSource{
+		tasks: []api.TaskInfo{
+			{UPID: "old", Node: "node1", Type: "vzstop", ID: "100", Status: "OK", EndTime: 1},
+			{UPID: "running", Node: "node1", Type: "vzshutdown", ID: "101"},
+		},
+	}
+
+	var got []int
+	watcher := NewWatcher(&config.EventsConfig{PollInterval: time.Second}, source, func(event Event) {
+		got = append(got, event.ContainerID)
+	}, logger)
+
+	// Tasks that finished before the first poll are not replayed
+	watcher.poll(context.Background())
+	if len(got) != 0 {
+		t.Fatalf("Expected no events on first poll, got %v", got)
+	}
+
+	// The running task finishes and a new one appears
+	source.tasks = []api.TaskInfo{
+		{UPID: "old", Node: "node1", Type: "vzstop", ID: "100", Status: "OK", EndTime: 1},
+		{UPID: "running", Node: "node1", Type: "vzshutdown", ID: "101", Status: "OK", EndTime: 2},
+		{UPID: "new", Node: "node1", Type: "vzmigrate", ID: "102", Status: "OK", EndTime: 3},
+	}
+	watcher.poll(context.Background())
+	watcher.poll(context.Background())
+
+	if !reflect.DeepEqual(got, []int{101, 102}) {
+		t.Errorf("Expected events for containers [101 102] once, got %v", got)
+	}
+
+	// Tasks that age out of the list are forgotten
+	source.tasks = nil
+	watcher.poll(context.Background())
+	if len(watcher.seen) != 0 {
+		t.Errorf("Expected seen set to be pruned, got %d entries", len(watcher.seen))
+	}
+}
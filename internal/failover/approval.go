@@ -0,0 +1,201 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/history"
+	"github.com/jbutlerdev/proxwarden/internal/notify"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrNotPending is returned when approving or rejecting a container without
+// a failover awaiting approval.
+var ErrNotPending = errors.New("no failover of the container awaiting approval")
+
+// ErrRejected is the error of a failover an operator rejected.
+var ErrRejected = errors.New("failover rejected")
+
+// PendingFailover describes a failover awaiting approval.
+type PendingFailover struct {
+	ContainerID int
+	SourceNode  string
+	TargetNode  string
+	Trigger     string
+	Created     time.Time
+	ExpiresAt   time.Time
+	// AutoApproveAt is zero unless the failover is approved automatically
+	AutoApproveAt time.Time
+}
+
+type pendingApproval struct {
+	failover PendingFailover
+	// decision receives true on approval and false on rejection
+	decision chan bool
+}
+
+// requiresApproval reports whether failovers with the trigger wait for
+// approval when it is enabled. Operators start all others themselves.
+func requiresApproval(trigger string) bool {
+	return trigger == history.TriggerAutomatic || trigger == history.TriggerNode
+}
+
+// approvalRequired reports whether a failover of the container with the
+// trigger waits for approval.
+func (e *Engine) approvalRequired(containerConfig *config.ContainerConfig, trigger string) bool {
+	if !requiresApproval(trigger) {
+		return false
+	}
+	if containerConfig.RequireApproval != nil {
+		return *containerConfig.RequireApproval
+	}
+	return e.cfg().Failover.Approval.Enabled
+}
+
+// awaitApproval holds an automatic failover until an operator approves it,
+// it is approved automatically, or it expires or is rejected, which fail it.
+func (e *Engine) awaitApproval(ctx context.Context, plan *Plan) error {
+	cfg := e.cfg().Failover.Approval
+	if !e.approvalRequired(plan.Container, plan.Trigger) {
+		return nil
+	}
+
+	containerID := plan.Container.ID
+	now := time.Now()
+	pending := &pendingApproval{
+		failover: PendingFailover{
+			ContainerID: containerID,
+			SourceNode:  plan.SourceNode,
+			TargetNode:  plan.TargetNode,
+			Trigger:     plan.Trigger,
+			Created:     now,
+			ExpiresAt:   now.Add(cfg.Timeout),
+		},
+		decision: make(chan bool, 1),
+	}
+	if cfg.AutoApproveAfter > 0 {
+		pending.failover.AutoApproveAt = now.Add(cfg.AutoApproveAfter)
+	}
+
+	e.approvalMu.Lock()
+	e.pending[containerID] = pending
+	e.approvalMu.Unlock()
+	defer func() {
+		e.approvalMu.Lock()
+		defer e.approvalMu.Unlock()
+		delete(e.pending, containerID)
+	}()
+
+	logger := e.logger.WithFields(logrus.Fields{
+		"container_id": containerID,
+		"source_node":  plan.SourceNode,
+		"target_node":  plan.TargetNode,
+	})
+	logger.Warn("Failover awaiting approval")
+
+	message := fmt.Sprintf("Failover of container %d from %s to %s awaits approval: run 'proxwarden failover approve %d' within %s",
+		containerID, plan.SourceNode, plan.TargetNode, containerID, cfg.Timeout)
+	if cfg.AutoApproveAfter > 0 {
+		message += fmt.Sprintf(", it is approved automatically after %s", cfg.AutoApproveAfter)
+	}
+	e.notifier.Send(notify.Event{
+		Type:          notify.EventFailoverPending,
+		Severity:      notify.SeverityCritical,
+		ContainerID:   containerID,
+		ContainerName: plan.Container.Name,
+		Node:          plan.SourceNode,
+		Message:       message,
+		Details:       map[string]string{"target_node": plan.TargetNode, "expires_at": pending.failover.ExpiresAt.Format(time.RFC3339)},
+	})
+
+	expire := time.NewTimer(cfg.Timeout)
+	defer expire.Stop()
+	var autoApprove <-chan time.Time
+	if cfg.AutoApproveAfter > 0 {
+		timer := time.NewTimer(cfg.AutoApproveAfter)
+		defer timer.Stop()
+		autoApprove = timer.C
+	}
+
+	var err error
+	select {
+	case approved := <-pending.decision:
+		if approved {
+			logger.Info("Failover approved")
+			return nil
+		}
+		err = ErrRejected
+	case <-autoApprove:
+		logger.Warn("Failover approved automatically, nobody decided on it")
+		return nil
+	case <-expire.C:
+		err = fmt.Errorf("failover not approved within %s", cfg.Timeout)
+	case <-ctx.Done():
+		return fmt.Errorf("failover awaiting approval stopped: %w", ctx.Err())
+	}
+
+	logger.WithField("reason", err).Warn("Failover dropped")
+	e.notifier.Send(notify.Event{
+		Type:          notify.EventFailoverRejected,
+		Severity:      notify.SeverityWarning,
+		ContainerID:   containerID,
+		ContainerName: plan.Container.Name,
+		Node:          plan.SourceNode,
+		Message:       fmt.Sprintf("Failover of container %d from %s to %s dropped: %v", containerID, plan.SourceNode, plan.TargetNode, err),
+		Details:       map[string]string{"target_node": plan.TargetNode},
+	})
+	return err
+}
+
+// Approve lets a failover awaiting approval go ahead.
+func (e *Engine) Approve(containerID int) error {
+	return e.decide(containerID, true)
+}
+
+// Reject drops a failover awaiting approval.
+func (e *Engine) Reject(containerID int) error {
+	return e.decide(containerID, false)
+}
+
+func (e *Engine) decide(containerID int, approved bool) error {
+	e.approvalMu.Lock()
+	defer e.approvalMu.Unlock()
+
+	pending, exists := e.pending[containerID]
+	if !exists {
+		return ErrNotPending
+	}
+	select {
+	case pending.decision <- approved:
+	default:
+		// Already decided
+	}
+	return nil
+}
+
+// Pending returns the failovers awaiting approval, oldest first.
+func (e *Engine) Pending() []PendingFailover {
+	e.approvalMu.Lock()
+	defer e.approvalMu.Unlock()
+
+	result := make([]PendingFailover, 0, len(e.pending))
+	for _, pending := range e.pending {
+		result = append(result, pending.failover)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Created.Before(result[j].Created)
+	})
+	return result
+}
+
+// awaitingApproval reports whether the container's failover awaits approval.
+func (e *Engine) awaitingApproval(containerID int) bool {
+	e.approvalMu.Lock()
+	defer e.approvalMu.Unlock()
+	_, exists := e.pending[containerID]
+	return exists
+}
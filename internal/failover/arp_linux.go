@@ -0,0 +1,52 @@
+//go:build linux
+
+package failover
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+)
+
+// sendGratuitousARP sends frame count times from the named interface. It
+// needs CAP_NET_RAW.
+func sendGratuitousARP(ctx context.Context, ifaceName string, frame []byte, count int) (int, error) {
+	iface, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return 0, fmt.Errorf("interface %s: %w", ifaceName, err)
+	}
+
+	protocol := htons(syscall.ETH_P_ARP)
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, int(protocol))
+	if err != nil {
+		return 0, fmt.Errorf("failed to open raw socket: %w", err)
+	}
+	defer syscall.Close(fd)
+
+	addr := &syscall.SockaddrLinklayer{
+		Protocol: protocol,
+		Ifindex:  iface.Index,
+		Halen:    6,
+	}
+	copy(addr.Addr[:], frame[:6])
+
+	for sent := 0; sent < count; sent++ {
+		if sent > 0 {
+			select {
+			case <-ctx.Done():
+				return sent, ctx.Err()
+			case <-time.After(arpInterval):
+			}
+		}
+		if err := syscall.Sendto(fd, frame, 0, addr); err != nil {
+			return sent, fmt.Errorf("failed to send: %w", err)
+		}
+	}
+	return count, nil
+}
+
+func htons(v uint16) uint16 {
+	return v<<8 | v>>8
+}
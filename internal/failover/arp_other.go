@@ -0,0 +1,12 @@
+//go:build !linux
+
+package failover
+
+import (
+	"context"
+	"errors"
+)
+
+func sendGratuitousARP(ctx context.Context, ifaceName string, frame []byte, count int) (int, error) {
+	return 0, errors.New("gratuitous ARP is only supported on Linux")
+}
@@ -0,0 +1,161 @@
+package failover
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/history"
+	"github.com/sirupsen/logrus"
+)
+
+// batch is a set of containers failed over off the same node.
+type batch struct {
+	sourceNode   string
+	containerIDs []int
+	trigger      string
+	// strategy and targetNode, when set, override the configured strategy
+	// and the selected target
+	strategy   string
+	targetNode string
+}
+
+// tier is the containers of a batch sharing a priority, in batch order.
+type tier struct {
+	priority   int
+	containers []*config.ContainerConfig
+}
+
+// tiers groups containers by priority, lowest value first.
+func tiers(containers []*config.ContainerConfig) []tier {
+	var result []tier
+	for _, containerConfig := range containers {
+		i := sort.Search(len(result), func(i int) bool {
+			return result[i].priority >= containerConfig.Priority
+		})
+		if i == len(result) || result[i].priority != containerConfig.Priority {
+			result = append(result, tier{})
+			copy(result[i+1:], result[i:])
+			result[i] = tier{priority: containerConfig.Priority}
+		}
+		result[i].containers = append(result[i].containers, containerConfig)
+	}
+	return result
+}
+
+// failoverBatch fails over the containers of b one priority tier at a time:
+// every failover of a tier has finished before the next tier starts. Within
+// a tier, failovers start in the order given, up to the tier's concurrency
+// at once, and still wait for failover slots. onResult is called once per
+// container, one call at a time, including containers that could not be
+// failed over at all.
+func (e *Engine) failoverBatch(ctx context.Context, b batch, onResult func(*FailoverResult)) {
+	var mu sync.Mutex
+	report := func(result *FailoverResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		onResult(result)
+	}
+	fail := func(containerID int, err error) {
+		now := time.Now()
+		report(&FailoverResult{
+			ContainerID: containerID,
+			SourceNode:  b.sourceNode,
+			Trigger:     b.trigger,
+			Error:       err,
+			StartTime:   now,
+			EndTime:     now,
+		})
+	}
+
+	var containers []*config.ContainerConfig
+	for _, containerID := range b.containerIDs {
+		containerConfig := e.containerConfig(containerID)
+		if containerConfig == nil {
+			fail(containerID, fmt.Errorf("container %d not found in configuration", containerID))
+			continue
+		}
+		containers = append(containers, containerConfig)
+	}
+
+	for _, t := range tiers(containers) {
+		limit := e.cfg().Failover.ConcurrencyFor(t.priority)
+		e.logger.WithFields(logrus.Fields{
+			"source_node": b.sourceNode,
+			"priority":    t.priority,
+			"containers":  len(t.containers),
+			"concurrency": limit,
+		}).Info("Failing over priority tier")
+
+		var slots chan struct{}
+		if limit > 0 {
+			slots = make(chan struct{}, limit)
+		}
+		var wg sync.WaitGroup
+		for _, containerConfig := range t.containers {
+			if slots != nil {
+				slots <- struct{}{}
+			}
+			plan, err := e.planBatchFailover(ctx, b, containerConfig)
+			if err != nil {
+				if slots != nil {
+					<-slots
+				}
+				fail(containerConfig.ID, err)
+				continue
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if slots != nil {
+					defer func() { <-slots }()
+				}
+
+				result := e.performFailover(ctx, plan, b.strategy)
+				e.release(plan.Container)
+				if b.trigger == history.TriggerNode {
+					e.trackFailback(result)
+				}
+				report(result)
+			}()
+		}
+		wg.Wait()
+	}
+}
+
+// planBatchFailover selects the target of a container in b and reserves its
+// failover.
+func (e *Engine) planBatchFailover(ctx context.Context, b batch, containerConfig *config.ContainerConfig) (*Plan, error) {
+	containerID := containerConfig.ID
+	targetNode := b.targetNode
+	if targetNode == "" {
+		var err error
+		targetNode, err = e.selectBestNode(ctx, containerConfig, b.sourceNode)
+		if err != nil {
+			return nil, fmt.Errorf("failed to select target node: %w", err)
+		}
+	}
+
+	if err := e.guard.acquire(containerID, b.trigger); err != nil {
+		e.forgetPlacement(containerID)
+		return nil, err
+	}
+
+	plan := &Plan{
+		Container:  containerConfig,
+		SourceNode: b.sourceNode,
+		TargetNode: targetNode,
+		Trigger:    b.trigger,
+	}
+	// Queue here rather than in the goroutine to keep the given order.
+	// Failovers awaiting approval queue once approved instead, so they do
+	// not hold slots meanwhile.
+	if !e.approvalRequired(containerConfig, b.trigger) {
+		plan.ticket = e.queue.enqueue(containerConfig.Priority)
+	}
+	return plan, nil
+}
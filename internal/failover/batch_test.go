@@ -0,0 +1,202 @@
+package failover
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/history"
+)
+
+func TestTiers(t *testing.T) {
+	tests := []struct {
+		name       string
+		priorities map[int]int
+		ids        []int
+		expected   [][]int
+	}{
+		{
+			name:     "single tier keeps batch order",
+			ids:      []int{103, 101, 102},
+			expected: [][]int{{103, 101, 102}},
+		},
+		{
+			name:       "lowest priority value first",
+			priorities: map[int]int{101: 5, 102: 1, 103: 3},
+			ids:        []int{101, 102, 103},
+			expected:   [][]int{{102}, {103}, {101}},
+		},
+		{
+			name:       "tiers keep batch order",
+			priorities: map[int]int{101: 2, 102: 1, 103: 2, 104: 1, 105: 0},
+			ids:        []int{101, 102, 103, 104, 105},
+			expected:   [][]int{{105}, {102, 104}, {101, 103}},
+		},
+		{
+			name:     "no containers",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var containers []*config.ContainerConfig
+			for _, id := range tt.ids {
+				containers = append(containers, &config.ContainerConfig{ID: id, Priority: tt.priorities[id]})
+			}
+
+			var result [][]int
+			for _, tier := range tiers(containers) {
+				var ids []int
+				for _, containerConfig := range tier.containers {
+					if containerConfig.Priority != tier.priority {
+						t.Errorf("Container %d with priority %d in tier %d", containerConfig.ID, containerConfig.Priority, tier.priority)
+					}
+					ids = append(ids, containerConfig.ID)
+				}
+				result = append(result, ids)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Expected tiers %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestEngine_FailoverBatchTiers(t *testing.T) {
+	priorities := map[int]int{100: 0, 101: 1, 102: 1, 103: 1}
+	cfg := testConfig(priorities)
+	cfg.Failover.TierConcurrency = 2
+
+	client := newFakeAPIClient(onlineNode("source", 0, 0), onlineNode("a", 0, 0))
+	client.entered = make(chan int)
+	client.block = make(chan struct{})
+	for id := range priorities {
+		client.addContainer(id, "source", "running")
+	}
+	engine := newTestEngine(cfg, client)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		engine.failoverBatch(context.Background(), batch{
+			sourceNode:   "source",
+			containerIDs: []int{101, 102, 103, 100},
+			trigger:      history.TriggerEvacuation,
+		}, func(*FailoverResult) {})
+	}()
+
+	// started waits for the next migration to start
+	started := func() int {
+		t.Helper()
+		select {
+		case id := <-client.entered:
+			return id
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for a migration to start")
+			return 0
+		}
+	}
+	// idle checks that no other migration starts for a while
+	idle := func(running string) {
+		t.Helper()
+		select {
+		case id := <-client.entered:
+			t.Fatalf("Expected no migration to start while %s, container %d started", running, id)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	if id := started(); id != 100 {
+		t.Fatalf("Expected the first tier to start with container 100, got %d", id)
+	}
+	idle("the first tier runs")
+	client.block <- struct{}{}
+
+	// Two of the second tier at once, then the third once one finishes
+	first, second := started(), started()
+	if got := []int{first, second}; !reflect.DeepEqual(got, []int{101, 102}) && !reflect.DeepEqual(got, []int{102, 101}) {
+		t.Fatalf("Expected containers 101 and 102 to start, got %v", got)
+	}
+	idle("two failovers of the tier run")
+	client.block <- struct{}{}
+	if id := started(); id != 103 {
+		t.Fatalf("Expected container 103 to start once a slot was free, got %d", id)
+	}
+	close(client.block)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the batch to finish")
+	}
+}
+
+func TestEngine_FailoverBatchResults(t *testing.T) {
+	cfg := testConfig(map[int]int{101: 1, 102: 0})
+	// Container 103 has nowhere to go
+	cfg.Monitoring.Containers = append(cfg.Monitoring.Containers, config.ContainerConfig{ID: 103})
+
+	client := newFakeAPIClient(onlineNode("source", 0, 0), onlineNode("a", 0, 0))
+	client.addContainer(101, "source", "running")
+	client.addContainer(102, "source", "stopped")
+	engine := newTestEngine(cfg, client)
+
+	results := make(map[int]*FailoverResult)
+	var order []int
+	engine.failoverBatch(context.Background(), batch{
+		sourceNode:   "source",
+		containerIDs: []int{101, 999, 103, 102},
+		trigger:      history.TriggerEvacuation,
+	}, func(result *FailoverResult) {
+		if _, seen := results[result.ContainerID]; seen {
+			t.Errorf("Container %d reported twice", result.ContainerID)
+		}
+		results[result.ContainerID] = result
+		order = append(order, result.ContainerID)
+	})
+
+	// Unknown containers are reported up front, then each tier as it runs;
+	// container 103 cannot be planned and is reported before 102 finishes
+	if expected := []int{999, 103, 102, 101}; !reflect.DeepEqual(order, expected) {
+		t.Errorf("Expected results in order %v, got %v", expected, order)
+	}
+
+	tests := []struct {
+		id      int
+		success bool
+		target  string
+	}{
+		{101, true, "a"},
+		{102, true, "a"},
+		{103, false, ""},
+		{999, false, ""},
+	}
+	for _, tt := range tests {
+		result, ok := results[tt.id]
+		if !ok {
+			t.Errorf("Expected a result for container %d", tt.id)
+			continue
+		}
+		if result.Success != tt.success {
+			t.Errorf("Expected container %d success %v, got %v (%v)", tt.id, tt.success, result.Success, result.Error)
+		}
+		if !tt.success && result.Error == nil {
+			t.Errorf("Expected an error for container %d", tt.id)
+		}
+		if result.TargetNode != tt.target {
+			t.Errorf("Expected container %d on %q, got %q", tt.id, tt.target, result.TargetNode)
+		}
+		if result.Trigger != history.TriggerEvacuation || result.SourceNode != "source" {
+			t.Errorf("Expected container %d evacuated from source, got %s from %s", tt.id, result.Trigger, result.SourceNode)
+		}
+	}
+
+	// The stopped container was started after its migration
+	expected := []string{"migrate 102 a", "start 102", "migrate 101 a"}
+	if calls := client.recorded(); !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Expected calls %v, got %v", expected, calls)
+	}
+}
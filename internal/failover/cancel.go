@@ -0,0 +1,97 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrNotInFlight is returned when cancelling a container that is not being
+// failed over.
+var ErrNotInFlight = errors.New("no failover of the container in progress")
+
+// InFlightFailover describes a failover that is queued or running.
+type InFlightFailover struct {
+	ContainerID int
+	SourceNode  string
+	TargetNode  string
+	Trigger     string
+	Queued      bool
+	// AwaitingApproval is set while the failover waits for approval
+	AwaitingApproval bool
+	StartTime        time.Time
+	Cancelled        bool
+	Progress         Progress
+}
+
+type inFlight struct {
+	plan      *Plan
+	startTime time.Time
+	cancel    context.CancelFunc
+	cancelled bool
+	progress  Progress
+	strategy  string
+}
+
+// track registers a failover as in flight and returns the context it runs
+// under, which Cancel cancels, and a function to call once it finished.
+func (e *Engine) track(ctx context.Context, plan *Plan, startTime time.Time) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	e.inFlightMu.Lock()
+	e.inFlight[plan.Container.ID] = &inFlight{plan: plan, startTime: startTime, cancel: cancel}
+	e.inFlightMu.Unlock()
+	e.journalStart(plan, startTime)
+
+	return ctx, func() {
+		cancel()
+		e.inFlightMu.Lock()
+		delete(e.inFlight, plan.Container.ID)
+		e.inFlightMu.Unlock()
+		e.journalFinish(plan.Container.ID)
+	}
+}
+
+// Cancel stops the failover of a container. Steps already sent to Proxmox
+// finish there, but no further steps are started and the failover fails.
+func (e *Engine) Cancel(containerID int) error {
+	e.inFlightMu.Lock()
+	defer e.inFlightMu.Unlock()
+
+	failover, exists := e.inFlight[containerID]
+	if !exists {
+		return ErrNotInFlight
+	}
+	failover.cancelled = true
+	failover.cancel()
+
+	e.logger.WithField("container_id", containerID).Warn("Failover cancelled")
+	return nil
+}
+
+// InFlight returns the failovers that are queued or running, oldest first.
+func (e *Engine) InFlight() []InFlightFailover {
+	e.inFlightMu.Lock()
+	defer e.inFlightMu.Unlock()
+
+	result := make([]InFlightFailover, 0, len(e.inFlight))
+	for containerID, failover := range e.inFlight {
+		result = append(result, InFlightFailover{
+			ContainerID: containerID,
+			SourceNode:  failover.plan.SourceNode,
+			TargetNode:  failover.plan.TargetNode,
+			Trigger:     failover.plan.Trigger,
+			Queued:      failover.plan.ticket != nil && failover.plan.ticket.queued(),
+			StartTime:   failover.startTime,
+			Cancelled:   failover.cancelled,
+			Progress:    failover.progress,
+
+			AwaitingApproval: e.awaitingApproval(containerID),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StartTime.Before(result[j].StartTime)
+	})
+	return result
+}
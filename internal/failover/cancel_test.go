@@ -0,0 +1,141 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+// waitFor polls cond until it holds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// cancelEngine returns an engine running one failover at a time that
+// migrates container 101 from source to a.
+func cancelEngine(client *fakeAPIClient) *Engine {
+	cfg := testConfig(map[int]int{101: 0})
+	cfg.Failover.MaxConcurrent = 1
+	client.addContainer(101, "source", "running")
+	return newTestEngine(cfg, client)
+}
+
+// startFailover fails container 101 over by hand in the background.
+func startFailover(engine *Engine) <-chan error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- engine.TriggerFailover(101, "a", config.StrategyMigrate, true)
+	}()
+	return errc
+}
+
+// failoverOf returns the failover of container 101 in flight, if any.
+func failoverOf(engine *Engine) *InFlightFailover {
+	for _, failover := range engine.InFlight() {
+		if failover.ContainerID == 101 {
+			return &failover
+		}
+	}
+	return nil
+}
+
+func failoverError(t *testing.T, errc <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-errc:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the failover to finish")
+		return nil
+	}
+}
+
+func TestEngine_CancelUnknown(t *testing.T) {
+	engine := cancelEngine(newFakeAPIClient(onlineNode("source", 0, 0), onlineNode("a", 0, 0)))
+
+	if err := engine.Cancel(999); !errors.Is(err, ErrNotInFlight) {
+		t.Errorf("Expected ErrNotInFlight for an unknown container, got %v", err)
+	}
+	if err := engine.Cancel(101); !errors.Is(err, ErrNotInFlight) {
+		t.Errorf("Expected ErrNotInFlight for a container not being failed over, got %v", err)
+	}
+}
+
+func TestEngine_CancelQueued(t *testing.T) {
+	client := newFakeAPIClient(onlineNode("source", 0, 0), onlineNode("a", 0, 0))
+	engine := cancelEngine(client)
+
+	// Another failover holds the only slot
+	engine.queue.enqueue(0)
+
+	errc := startFailover(engine)
+	waitFor(t, "the failover to queue", func() bool {
+		failover := failoverOf(engine)
+		return failover != nil && failover.Queued
+	})
+
+	if err := engine.Cancel(101); err != nil {
+		t.Fatalf("Failed to cancel the queued failover: %v", err)
+	}
+	if err := failoverError(t, errc); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the failover to fail with context.Canceled, got %v", err)
+	}
+
+	if calls := client.recorded(); len(calls) != 0 {
+		t.Errorf("Expected a queued failover to leave the cluster alone, got %v", calls)
+	}
+	if len(engine.queue.waiting) != 0 {
+		t.Errorf("Expected the failover to leave the queue, %d still waiting", len(engine.queue.waiting))
+	}
+	if failoverOf(engine) != nil {
+		t.Error("Expected the failover to be no longer in flight")
+	}
+	if err := engine.Cancel(101); !errors.Is(err, ErrNotInFlight) {
+		t.Errorf("Expected ErrNotInFlight once the failover finished, got %v", err)
+	}
+}
+
+func TestEngine_CancelRunning(t *testing.T) {
+	client := newFakeAPIClient(onlineNode("source", 0, 0), onlineNode("a", 0, 0))
+	client.entered = make(chan int, 1)
+	client.block = make(chan struct{})
+	engine := cancelEngine(client)
+
+	errc := startFailover(engine)
+	select {
+	case <-client.entered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the migration to start")
+	}
+	if failover := failoverOf(engine); failover == nil || failover.Queued {
+		t.Fatalf("Expected a running failover in flight, got %+v", failover)
+	}
+
+	if err := engine.Cancel(101); err != nil {
+		t.Fatalf("Failed to cancel the running failover: %v", err)
+	}
+	if err := failoverError(t, errc); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the failover to fail with context.Canceled, got %v", err)
+	}
+
+	// The migration was stopped before the container was started
+	if calls := client.recorded(); len(calls) != 1 || calls[0] != "migrate 101 a" {
+		t.Errorf("Expected only the migration to be sent, got %v", calls)
+	}
+	if engine.queue.running != 0 {
+		t.Errorf("Expected the failover slot to be freed, %d still running", engine.queue.running)
+	}
+	if failoverOf(engine) != nil {
+		t.Error("Expected the failover to be no longer in flight")
+	}
+}
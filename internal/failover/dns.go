@@ -0,0 +1,106 @@
+package failover
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/notify"
+	"github.com/sirupsen/logrus"
+)
+
+// addressPollInterval is how often a failed-over container is asked for its
+// addresses until it reports some.
+const addressPollInterval = 2 * time.Second
+
+// updateDNS points the container's DNS records at the addresses it has after
+// the failover. The failover already succeeded, so failures are logged and
+// notified but do not fail it.
+func (e *Engine) updateDNS(ctx context.Context, plan *Plan, result *FailoverResult) {
+	records := plan.Container.DNS
+	if len(records) == 0 {
+		return
+	}
+
+	_, containerID := instances(plan, result)
+
+	logger := e.logger.WithFields(logrus.Fields{
+		"container_id": plan.Container.ID,
+		"node":         result.TargetNode,
+	})
+
+	interfaces, err := e.waitForAddresses(ctx, result.TargetNode, containerID)
+	for _, record := range records {
+		recordLogger := logger.WithFields(logrus.Fields{
+			"hostname": record.Hostname,
+			"provider": record.Provider,
+		})
+
+		updateErr := err
+		var addresses []net.IP
+		if updateErr == nil {
+			addresses = interfaceAddresses(interfaces, record.Interface)
+			if len(addresses) == 0 {
+				updateErr = fmt.Errorf("no addresses on interface %s", record.Interface)
+			}
+		}
+		if updateErr == nil {
+			updateErr = e.dns.Update(ctx, record, addresses)
+		}
+
+		if updateErr != nil {
+			recordLogger.WithField("error", updateErr).Error("Failed to update DNS record")
+			e.notifier.Send(notify.Event{
+				Type:          notify.EventDNSUpdateFailed,
+				Severity:      notify.SeverityWarning,
+				ContainerID:   plan.Container.ID,
+				ContainerName: plan.Container.Name,
+				Node:          result.TargetNode,
+				Message:       fmt.Sprintf("DNS record %s of container %d not updated: %v", record.Hostname, plan.Container.ID, updateErr),
+				Details:       map[string]string{"hostname": record.Hostname, "provider": record.Provider},
+			})
+			continue
+		}
+
+		recordLogger.WithField("addresses", addresses).Info("DNS record updated")
+	}
+}
+
+// waitForAddresses returns the container's interfaces once any of them has
+// an address, waiting up to the configured timeout for it to come up.
+func (e *Engine) waitForAddresses(ctx context.Context, node string, containerID int) ([]api.ContainerInterface, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.cfg().DNS.AddressTimeout)
+	defer cancel()
+
+	var lastErr error
+	for {
+		interfaces, err := e.apiClient.GetContainerInterfaces(ctx, node, containerID)
+		if err == nil && len(interfaceAddresses(interfaces, "")) > 0 {
+			return interfaces, nil
+		}
+		lastErr = err
+
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return nil, fmt.Errorf("container reported no addresses: %w", lastErr)
+			}
+			return nil, fmt.Errorf("container reported no addresses within %s", e.cfg().DNS.AddressTimeout)
+		case <-time.After(addressPollInterval):
+		}
+	}
+}
+
+// interfaceAddresses returns the addresses of the named interface, or of all
+// interfaces if name is empty.
+func interfaceAddresses(interfaces []api.ContainerInterface, name string) []net.IP {
+	var addresses []net.IP
+	for _, iface := range interfaces {
+		if name == "" || iface.Name == name {
+			addresses = append(addresses, iface.Addresses()...)
+		}
+	}
+	return addresses
+}
@@ -0,0 +1,148 @@
+package failover
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/history"
+	"github.com/jbutlerdev/proxwarden/internal/notify"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+)
+
+// Drill fails a container over as if it had failed, through the whole
+// failover pipeline, and records the result in the history with trigger
+// "drill". With drill.move_back it rolls the container back afterwards. A
+// failed drill sends a drill_failed notification.
+func (e *Engine) Drill(ctx context.Context, containerID int) (*FailoverResult, error) {
+	containerConfig := e.containerConfig(containerID)
+	if containerConfig == nil {
+		return nil, fmt.Errorf("container %d not found in configuration", containerID)
+	}
+
+	result, err := e.drill(ctx, containerConfig)
+	// Failed failovers have been notified by the pipeline
+	if err != nil && (result == nil || result.Success) {
+		e.notifier.Send(notify.Event{
+			Type:          notify.EventDrillFailed,
+			Severity:      notify.SeverityCritical,
+			ContainerID:   containerID,
+			ContainerName: containerConfig.Name,
+			Message:       fmt.Sprintf("Failover drill of container %d failed: %v", containerID, err),
+		})
+	}
+	return result, err
+}
+
+func (e *Engine) drill(ctx context.Context, containerConfig *config.ContainerConfig) (*FailoverResult, error) {
+	containerID := containerConfig.ID
+
+	containerInfo, err := e.apiClient.GetContainer(ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container info: %w", err)
+	}
+
+	targetNode, err := e.selectBestNode(ctx, containerConfig, containerInfo.Node)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select target node: %w", err)
+	}
+
+	if err := e.guard.acquire(containerID, history.TriggerDrill); err != nil {
+		e.forgetPlacement(containerID)
+		return nil, err
+	}
+
+	e.logger.WithFields(logrus.Fields{
+		"container_id": containerID,
+		"source_node":  containerInfo.Node,
+		"target_node":  targetNode,
+	}).Info("Starting failover drill")
+
+	result := e.performFailover(ctx, &Plan{
+		Container:   containerConfig,
+		SourceNode:  containerInfo.Node,
+		TargetNode:  targetNode,
+		BackupFirst: e.cfg().Failover.BackupBeforeFailover,
+		Trigger:     history.TriggerDrill,
+	}, "")
+	e.release(containerConfig)
+
+	if !result.Success {
+		e.logger.WithFields(logrus.Fields{
+			"container_id": containerID,
+			"error":        result.Error,
+		}).Error("Failover drill failed")
+		return result, result.Error
+	}
+
+	e.logger.WithFields(logrus.Fields{
+		"container_id": containerID,
+		"target_node":  result.TargetNode,
+		"duration":     result.Duration,
+	}).Info("Failover drill completed successfully")
+
+	if e.cfg().Failover.Drill.MoveBack {
+		if _, err := e.Rollback(ctx, containerID, ""); err != nil {
+			return result, fmt.Errorf("moving the container back after the drill: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// drillRecheck is how often RunDrills looks at the drill settings, so that
+// reloaded ones apply within it.
+const drillRecheck = time.Minute
+
+// RunDrills runs drills of the designated containers, one after another, on
+// the drill schedule until ctx is done. The schedule and containers are read
+// again every drillRecheck.
+func (e *Engine) RunDrills(ctx context.Context) {
+	ticker := time.NewTicker(drillRecheck)
+	defer ticker.Stop()
+
+	var spec string
+	var schedule cron.Schedule
+	var next time.Time
+	for {
+		drill := e.cfg().Failover.Drill
+		if drill.Schedule != spec {
+			spec, schedule, next = drill.Schedule, nil, time.Time{}
+			if spec != "" {
+				parsed, err := cron.ParseStandard(spec)
+				if err != nil {
+					e.logger.WithField("error", err).Error("Invalid drill schedule")
+				} else {
+					schedule, next = parsed, parsed.Next(time.Now())
+				}
+			}
+		}
+
+		if schedule != nil && !time.Now().Before(next) {
+			e.runDrills(ctx, drill.Containers)
+			next = schedule.Next(time.Now())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runDrills drills the containers one after another.
+func (e *Engine) runDrills(ctx context.Context, containerIDs []int) {
+	for _, containerID := range containerIDs {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := e.Drill(ctx, containerID); err != nil {
+			e.logger.WithFields(logrus.Fields{
+				"container_id": containerID,
+				"error":        err,
+			}).Error("Scheduled failover drill failed")
+		}
+	}
+}
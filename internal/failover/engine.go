@@ -2,72 +2,241 @@ package failover
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os/exec"
-	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/backup"
 	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/dns"
+	"github.com/jbutlerdev/proxwarden/internal/catalog"
+	"github.com/jbutlerdev/proxwarden/internal/history"
+	"github.com/jbutlerdev/proxwarden/internal/journal"
+	"github.com/jbutlerdev/proxwarden/internal/notify"
 	"github.com/sirupsen/logrus"
 )
 
 type Engine struct {
-	config    *config.Config
-	apiClient *api.Client
+	// current is the configuration, replaced as a whole on reload
+	current   atomic.Pointer[config.Config]
+	apiClient api.ProxmoxClient
 	logger    *logrus.Logger
+	notifier  *notify.Dispatcher
+	lookup    ContainerLookup
+	guard     *guard
+	queue     *queue
+	backups   *backup.Limiter
+	observer  Observer
+	history   *history.Store
+	catalog   *catalog.Store
+	journal   *journal.Store
+	dns       *dns.Updater
+
+	strategies map[string]Strategy
+
+	// failbackMu guards failbacks and onlineSince
+	failbackMu  sync.Mutex
+	failbacks   map[int]failback
+	onlineSince map[string]time.Time
+
+	// placementMu guards placed and roundRobin
+	placementMu sync.Mutex
+	// placed is the node each container was last failed over to
+	placed     map[int]string
+	roundRobin int
+
+	// inFlightMu guards inFlight
+	inFlightMu sync.Mutex
+	inFlight   map[int]*inFlight
+
+	// approvalMu guards pending
+	approvalMu sync.Mutex
+	pending    map[int]*pendingApproval
+
+	// standbyMu guards syncing and synced
+	standbyMu sync.Mutex
+	// syncing holds containers whose standby is being restored
+	syncing map[int]bool
+	// synced is the backup each container's standby was last restored from
+	synced map[int]string
 }
 
+// Observer is told when failovers of a container start and finish, so
+// container state can reflect them.
+type Observer interface {
+	FailoverStarted(containerID int)
+	FailoverFinished(containerID int, success bool)
+}
+
+// ContainerLookup returns the configuration of a monitored container that is
+// not listed in the config file, such as a discovered one.
+type ContainerLookup func(containerID int) (config.ContainerConfig, bool)
+
 type FailoverResult struct {
 	ContainerID   int
 	SourceNode    string
 	TargetNode    string
+	Strategy      string
+	Trigger       string
+	Backup        string
 	Success       bool
+	// RoundTrip is the time from the failover to the end of its failback
+	RoundTrip     time.Duration
+	Hooks         []HookResult
+	FloatingIP    *FloatingIPResult
+	// Warnings are risks the failover went ahead with
+	Warnings      []string
+	// Progress is how far the failover came
+	Progress      Progress
 	Error         error
 	Duration      time.Duration
 	StartTime     time.Time
 	EndTime       time.Time
 }
 
-func New(logger *logrus.Logger) (*Engine, error) {
-	cfg, err := config.Load()
+// New creates an engine for a CLI command with its own Proxmox client,
+// notifier and DNS updater.
+func New(cfg *config.Config, logger *logrus.Logger) (*Engine, error) {
+	apiClient, err := api.NewClient(&cfg.Proxmox)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load config: %w", err)
+		return nil, fmt.Errorf("failed to create API client: %w", err)
 	}
 
-	apiClient, err := api.NewClient(&cfg.Proxmox)
+	notifier, err := notify.NewDispatcher(&cfg.Notifications, logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create API client: %w", err)
+		return nil, fmt.Errorf("failed to create notifier: %w", err)
 	}
 
-	return &Engine{
-		config:    cfg,
-		apiClient: apiClient,
-		logger:    logger,
-	}, nil
+	updater, err := dns.NewUpdater(&cfg.DNS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DNS updater: %w", err)
+	}
+
+	failoverHistory, err := history.Open(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open failover history: %w", err)
+	}
+
+	backupCatalog, err := catalog.Open(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup catalog: %w", err)
+	}
+
+	engine := NewWithConfig(cfg, apiClient, logger)
+	engine.SetNotifier(notifier)
+	engine.SetDNS(updater)
+	engine.SetHistory(failoverHistory)
+	engine.SetCatalog(backupCatalog)
+	return engine, nil
 }
 
-func NewWithConfig(cfg *config.Config, apiClient *api.Client, logger *logrus.Logger) *Engine {
-	return &Engine{
-		config:    cfg,
+func NewWithConfig(cfg *config.Config, apiClient api.ProxmoxClient, logger *logrus.Logger) *Engine {
+	engine := &Engine{
 		apiClient: apiClient,
 		logger:    logger,
+		guard:     newGuard(cfg.Failover),
+		queue:     newQueue(cfg.Failover.MaxConcurrent),
+		backups:   backup.NewLimiter(&cfg.Backup),
+
+		failbacks:   make(map[int]failback),
+		onlineSince: make(map[string]time.Time),
+		placed:      make(map[int]string),
+		inFlight:    make(map[int]*inFlight),
+		pending:     make(map[int]*pendingApproval),
+		syncing:     make(map[int]bool),
+		synced:      make(map[int]string),
 	}
+	engine.current.Store(cfg)
+	engine.registerStrategies()
+	return engine
 }
 
-func (e *Engine) TriggerFailover(containerID int, targetNode string, force bool) error {
-	ctx := context.Background()
-	
-	// Find container config
-	var containerConfig *config.ContainerConfig
-	for _, c := range e.config.Monitoring.Containers {
+// cfg returns the current configuration.
+func (e *Engine) cfg() *config.Config {
+	return e.current.Load()
+}
+
+// Reload switches the engine to a changed configuration. Failovers in
+// progress may see either.
+func (e *Engine) Reload(cfg *config.Config) {
+	e.current.Store(cfg)
+	e.guard.setLimits(cfg.Failover)
+}
+
+// SetNotifier sets the dispatcher used to announce failover progress.
+func (e *Engine) SetNotifier(notifier *notify.Dispatcher) {
+	e.notifier = notifier
+}
+
+// SetDNS sets the updater that points DNS records at failed-over containers.
+func (e *Engine) SetDNS(updater *dns.Updater) {
+	e.dns = updater
+}
+
+// SetHistory sets the store every failover attempt is recorded to.
+func (e *Engine) SetHistory(store *history.Store) {
+	e.history = store
+}
+
+// SetCatalog sets the catalog backups are recorded to and looked up in.
+func (e *Engine) SetCatalog(store *catalog.Store) {
+	e.catalog = store
+}
+
+// SetBackupLimiter sets the limiter pre-failover backups share with other
+// backups.
+func (e *Engine) SetBackupLimiter(limiter *backup.Limiter) {
+	e.backups = limiter
+}
+
+// SetObserver sets the observer told about failover progress.
+func (e *Engine) SetObserver(observer Observer) {
+	e.observer = observer
+}
+
+// SetContainerLookup sets where configurations of containers missing from
+// the config file are looked up.
+func (e *Engine) SetContainerLookup(lookup ContainerLookup) {
+	e.lookup = lookup
+}
+
+// containerConfig finds the configuration of a container, falling back to
+// the container lookup for containers not in the config file.
+func (e *Engine) containerConfig(containerID int) *config.ContainerConfig {
+	for _, c := range e.cfg().Monitoring.Containers {
 		if c.ID == containerID {
-			containerConfig = &c
-			break
+			return &c
+		}
+	}
+	if e.lookup != nil {
+		if c, ok := e.lookup(containerID); ok {
+			return &c
 		}
 	}
+	return nil
+}
+
+// autoFailover reports whether failures of a container, which may be
+// unconfigured, are failed over without an operator starting it.
+func (e *Engine) autoFailover(containerConfig *config.ContainerConfig) bool {
+	if containerConfig != nil && containerConfig.AutoFailover != nil {
+		return *containerConfig.AutoFailover
+	}
+	return e.cfg().Failover.AutoFailover
+}
+
+// TriggerFailover fails a container over by hand. An empty targetNode selects
+// the best failover node and an empty strategy uses the configured one; a
+// given strategy fails rather than fall back when it is not possible.
+func (e *Engine) TriggerFailover(containerID int, targetNode, strategy string, force bool) error {
+	ctx := context.Background()
 	
+	// Find container config
+	containerConfig := e.containerConfig(containerID)
 	if containerConfig == nil {
 		return fmt.Errorf("container %d not found in configuration", containerID)
 	}
@@ -91,6 +260,12 @@ func (e *Engine) TriggerFailover(containerID int, targetNode string, force bool)
 		}
 	}
 
+	if err := e.guard.acquire(containerID, history.TriggerManual); err != nil {
+		e.forgetPlacement(containerID)
+		return err
+	}
+	defer e.release(containerConfig)
+
 	e.logger.WithFields(logrus.Fields{
 		"container_id": containerID,
 		"source_node":  containerInfo.Node,
@@ -98,7 +273,15 @@ func (e *Engine) TriggerFailover(containerID int, targetNode string, force bool)
 		"force":        force,
 	}).Info("Starting manual failover")
 
-	result := e.performFailover(ctx, containerConfig, containerInfo.Node, targetNode)
+	result := e.performFailover(ctx, &Plan{
+		Container:   containerConfig,
+		SourceNode:  containerInfo.Node,
+		TargetNode:  targetNode,
+		BackupFirst: e.cfg().Failover.BackupBeforeFailover,
+		Trigger:     history.TriggerManual,
+		Force:       force,
+		exact:       strategy != "",
+	}, strategy)
 	
 	if result.Success {
 		e.logger.WithFields(logrus.Fields{
@@ -119,25 +302,21 @@ func (e *Engine) TriggerFailover(containerID int, targetNode string, force bool)
 }
 
 func (e *Engine) HandleContainerFailure(containerID int) error {
-	if !e.config.Failover.AutoFailover {
-		e.logger.WithField("container_id", containerID).Info("Auto-failover disabled, skipping")
-		return nil
-	}
-
 	ctx := context.Background()
 	
 	// Find container config
-	var containerConfig *config.ContainerConfig
-	for _, c := range e.config.Monitoring.Containers {
-		if c.ID == containerID {
-			containerConfig = &c
-			break
-		}
+	containerConfig := e.containerConfig(containerID)
+	if !e.autoFailover(containerConfig) {
+		e.logger.WithField("container_id", containerID).Info("Auto-failover disabled, skipping")
+		return nil
 	}
-	
 	if containerConfig == nil {
 		return fmt.Errorf("container %d not found in configuration", containerID)
 	}
+	if e.awaitsOperator(containerID) {
+		e.logger.WithField("container_id", containerID).Warn("Interrupted failover awaits an operator, skipping automatic failover")
+		return nil
+	}
 
 	// Get current container info
 	containerInfo, err := e.apiClient.GetContainer(ctx, containerID)
@@ -151,13 +330,32 @@ func (e *Engine) HandleContainerFailure(containerID int) error {
 		return fmt.Errorf("failed to select target node: %w", err)
 	}
 
+	// Skip containers that were just failed over or keep failing over. Only
+	// failovers that start count toward the cooldown.
+	if err := e.guard.acquire(containerID, history.TriggerAutomatic); err != nil {
+		e.forgetPlacement(containerID)
+		e.logger.WithFields(logrus.Fields{
+			"container_id": containerID,
+			"reason":       err,
+		}).Warn("Automatic failover suppressed")
+		return nil
+	}
+	defer e.release(containerConfig)
+
 	e.logger.WithFields(logrus.Fields{
 		"container_id": containerID,
 		"source_node":  containerInfo.Node,
 		"target_node":  targetNode,
 	}).Info("Starting automatic failover")
 
-	result := e.performFailover(ctx, containerConfig, containerInfo.Node, targetNode)
+	result := e.performFailover(ctx, &Plan{
+		Container:   containerConfig,
+		SourceNode:  containerInfo.Node,
+		TargetNode:  targetNode,
+		BackupFirst: e.cfg().Failover.BackupBeforeFailover,
+		Trigger:     history.TriggerAutomatic,
+	}, "")
+	e.trackFailback(result)
 	
 	if result.Success {
 		e.logger.WithFields(logrus.Fields{
@@ -177,194 +375,512 @@ func (e *Engine) HandleContainerFailure(containerID int) error {
 	return nil
 }
 
-func (e *Engine) selectBestNode(ctx context.Context, containerConfig *config.ContainerConfig, currentNode string) (string, error) {
-	if len(containerConfig.FailoverNodes) == 0 {
-		return "", fmt.Errorf("no failover nodes configured for container %d", containerConfig.ID)
+// HandleNodeFailure fails over the given containers of a down node, one
+// priority tier after another. The node is unreachable, so containers are
+// restored from their latest existing backup.
+func (e *Engine) HandleNodeFailure(node string, containerIDs []int) error {
+	automatic := make([]int, 0, len(containerIDs))
+	for _, containerID := range containerIDs {
+		if !e.autoFailover(e.containerConfig(containerID)) {
+			e.logger.WithFields(logrus.Fields{
+				"container_id": containerID,
+				"node":         node,
+			}).Info("Auto-failover disabled for container, skipping")
+			continue
+		}
+		if e.awaitsOperator(containerID) {
+			e.logger.WithFields(logrus.Fields{
+				"container_id": containerID,
+				"node":         node,
+			}).Warn("Interrupted failover awaits an operator, skipping automatic failover")
+			continue
+		}
+		automatic = append(automatic, containerID)
 	}
-
-	// Get all nodes status
-	nodes, err := e.apiClient.GetNodes(ctx)
-	if err != nil {
-		return "", fmt.Errorf("failed to get nodes: %w", err)
+	if len(automatic) == 0 {
+		e.logger.WithField("node", node).Info("Auto-failover disabled, skipping node failover")
+		return nil
 	}
+	containerIDs = automatic
 
-	nodeStatus := make(map[string]*api.NodeInfo)
-	for _, node := range nodes {
-		nodeStatus[node.Name] = node
-	}
+	e.logger.WithFields(logrus.Fields{
+		"node":       node,
+		"containers": containerIDs,
+	}).Warn("Starting failover of all containers on failed node")
+
+	var errs []error
+	e.failoverBatch(context.Background(), batch{
+		sourceNode:   node,
+		containerIDs: containerIDs,
+		trigger:      history.TriggerNode,
+	}, func(result *FailoverResult) {
+		if !result.Success {
+			e.logger.WithFields(logrus.Fields{
+				"container_id": result.ContainerID,
+				"node":         node,
+				"error":        result.Error,
+			}).Error("Node failover of container failed")
+			errs = append(errs, fmt.Errorf("container %d: %w", result.ContainerID, result.Error))
+			return
+		}
 
-	// Filter available failover nodes
-	type nodeCandidate struct {
-		name     string
-		priority int
-		online   bool
+		e.logger.WithFields(logrus.Fields{
+			"container_id": result.ContainerID,
+			"source_node":  node,
+			"target_node":  result.TargetNode,
+			"duration":     result.Duration,
+		}).Info("Node failover of container completed successfully")
+	})
+
+	return errors.Join(errs...)
+}
+
+func (e *Engine) performFailover(ctx context.Context, plan *Plan, strategyName string) *FailoverResult {
+	containerConfig := plan.Container
+	result := &FailoverResult{
+		ContainerID: containerConfig.ID,
+		SourceNode:  plan.SourceNode,
+		TargetNode:  plan.TargetNode,
+		Trigger:     plan.Trigger,
+		StartTime:   time.Now(),
 	}
 
-	var candidates []nodeCandidate
-	for i, nodeName := range containerConfig.FailoverNodes {
-		if nodeName == currentNode {
-			continue // Skip current node
-		}
+	defer e.recordPlacement(result)
 
-		node, exists := nodeStatus[nodeName]
-		if !exists {
-			e.logger.WithField("node", nodeName).Warn("Configured failover node not found in cluster")
-			continue
-		}
+	// Register before queueing so queued failovers can be cancelled too
+	ctx, finished := e.track(ctx, plan, result.StartTime)
+	defer finished()
+	defer func() {
+		result.Progress = e.progressOf(containerConfig.ID)
+	}()
 
-		candidates = append(candidates, nodeCandidate{
-			name:     nodeName,
-			priority: i, // Lower index = higher priority
-			online:   node.Online,
-		})
+	// Automatic failovers may need an operator's go-ahead first, or give
+	// operators a chance to cancel them
+	err := e.awaitApproval(ctx, plan)
+	if err == nil {
+		err = e.awaitGracePeriod(ctx, plan)
 	}
-
-	if len(candidates) == 0 {
-		return "", fmt.Errorf("no available failover nodes for container %d", containerConfig.ID)
+	if err != nil {
+		result.Error = err
+		result.EndTime = time.Now()
+		result.Duration = result.EndTime.Sub(result.StartTime)
+		return result
 	}
 
-	// Sort by online status first, then by priority
-	sort.Slice(candidates, func(i, j int) bool {
-		if candidates[i].online != candidates[j].online {
-			return candidates[i].online // Online nodes first
-		}
-		return candidates[i].priority < candidates[j].priority // Lower priority index first
-	})
+	// Wait for a failover slot before touching the container
+	if plan.ticket == nil {
+		// InFlight reads the ticket of tracked plans
+		ticket := e.queue.enqueue(containerConfig.Priority)
+		e.inFlightMu.Lock()
+		plan.ticket = ticket
+		e.inFlightMu.Unlock()
+	}
+	if plan.ticket.queued() {
+		e.logger.WithFields(logrus.Fields{
+			"container_id": containerConfig.ID,
+			"priority":     containerConfig.Priority,
+		}).Info("Failover queued, waiting for a free slot")
+	}
+	if err := e.queue.wait(ctx, plan.ticket); err != nil {
+		result.Error = fmt.Errorf("failover queue: %w", err)
+		result.EndTime = time.Now()
+		result.Duration = result.EndTime.Sub(result.StartTime)
+		return result
+	}
+	defer e.queue.done()
 
-	if !candidates[0].online {
-		return "", fmt.Errorf("no online failover nodes available for container %d", containerConfig.ID)
+	strategy, err := e.selectStrategy(ctx, plan, strategyName)
+	if err == nil {
+		result.Strategy = strategy.Name()
+		e.setStrategy(containerConfig.ID, result.Strategy)
 	}
 
-	return candidates[0].name, nil
-}
+	started := notify.Event{
+		Type:          notify.EventFailoverStarted,
+		Severity:      notify.SeverityWarning,
+		ContainerID:   containerConfig.ID,
+		ContainerName: containerConfig.Name,
+		Node:          plan.SourceNode,
+		Message:       fmt.Sprintf("Failing over container %d from %s to %s", containerConfig.ID, plan.SourceNode, plan.TargetNode),
+		Details:       map[string]string{"target_node": plan.TargetNode, "strategy": result.Strategy},
+	}
+	failback := movesBack(plan.Trigger)
+	switch {
+	case failback:
+		started.Type = notify.EventFailbackStarted
+		started.Severity = notify.SeverityInfo
+		started.Message = fmt.Sprintf("Failing container %d back from %s to %s", containerConfig.ID, plan.SourceNode, plan.TargetNode)
+	case plan.Trigger == history.TriggerDrill:
+		started.Type = notify.EventDrillStarted
+		started.Severity = notify.SeverityInfo
+		started.Message = fmt.Sprintf("Failover drill: failing over container %d from %s to %s", containerConfig.ID, plan.SourceNode, plan.TargetNode)
+	}
+	e.notifier.Send(started)
+	defer e.recordHistory(containerConfig, result)
+	defer recordMetrics(result)
+	defer e.notifyResult(containerConfig, result)
+
+	if e.observer != nil {
+		e.observer.FailoverStarted(containerConfig.ID)
+		defer func() {
+			e.observer.FailoverFinished(containerConfig.ID, result.Success)
+		}()
+	}
 
-func (e *Engine) performFailover(ctx context.Context, containerConfig *config.ContainerConfig, sourceNode, targetNode string) *FailoverResult {
-	result := &FailoverResult{
-		ContainerID: containerConfig.ID,
-		SourceNode:  sourceNode,
-		TargetNode:  targetNode,
-		StartTime:   time.Now(),
+	if err != nil {
+		result.Error = err
+		result.EndTime = time.Now()
+		result.Duration = result.EndTime.Sub(result.StartTime)
+		return result
 	}
 
 	// Execute pre-failover hooks
-	if err := e.executeHooks(e.config.Failover.PreFailoverHooks, containerConfig); err != nil {
+	e.setProgress(containerConfig.ID, StepPreHooks, "", -1)
+	hooks, err := e.executeHooks(ctx, hookPhasePre, e.cfg().Failover.PreFailoverHooks, plan, result)
+	result.Hooks = append(result.Hooks, hooks...)
+	if err != nil {
 		result.Error = fmt.Errorf("pre-failover hooks failed: %w", err)
 		result.EndTime = time.Now()
 		result.Duration = result.EndTime.Sub(result.StartTime)
 		return result
 	}
 
+	e.snapshotBeforeFailover(ctx, plan, result.Strategy)
+
+	e.setProgress(containerConfig.ID, result.Strategy, "", -1)
+	err = strategy.Execute(ctx, plan)
+	result.TargetNode = plan.TargetNode
+	result.EndTime = time.Now()
+	result.Duration = result.EndTime.Sub(result.StartTime)
+	result.Backup = plan.Backup
+	result.Warnings = plan.Warnings
+	if failback && !plan.FailedOverAt.IsZero() {
+		result.RoundTrip = result.EndTime.Sub(plan.FailedOverAt)
+	}
+
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	result.Success = true
+
+	e.setProgress(containerConfig.ID, StepNetwork, "", -1)
+	e.moveFloatingIP(ctx, plan, result)
+	e.updateDNS(ctx, plan, result)
+
+	// Execute post-failover hooks
+	e.setProgress(containerConfig.ID, StepPostHooks, "", -1)
+	hooks, err = e.executeHooks(ctx, hookPhasePost, e.cfg().Failover.PostFailoverHooks, plan, result)
+	result.Hooks = append(result.Hooks, hooks...)
+	if err != nil {
+		e.logger.WithFields(logrus.Fields{
+			"container_id": containerConfig.ID,
+			"error":        err,
+		}).Warn("Post-failover hooks failed, but failover was successful")
+	}
+
+	return result
+}
+
+func (e *Engine) nodeOnline(ctx context.Context, name string) (bool, error) {
+	nodes, err := e.apiClient.GetNodes(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get nodes: %w", err)
+	}
+	for _, node := range nodes {
+		if node.Name == name {
+			return node.Online, nil
+		}
+	}
+	return false, nil
+}
+
+// restore fails the container over by restoring a backup on the target
+// node, taking a fresh backup first when the plan asks for one.
+func (e *Engine) restore(ctx context.Context, plan *Plan) (string, error) {
+	containerConfig := plan.Container
 	var err error
 	var backupPath string
 
 	// Step 1: Create backup if required or find latest backup
-	if e.config.Failover.BackupBeforeFailover {
+	if plan.BackupFirst {
+		e.setProgress(containerConfig.ID, StepBackup, "waiting for other backups", -1)
 		e.logger.WithField("container_id", containerConfig.ID).Info("Creating backup before failover")
 		
 		backupStorage := containerConfig.BackupStorage
 		if backupStorage == "" {
-			backupStorage = e.config.Backup.Storage
+			backupStorage = e.cfg().Backup.Storage
 		}
 
-		backupPath, err = e.apiClient.BackupContainer(ctx, containerConfig.ID, backupStorage, e.config.Backup.BackupDir)
+		// Other backups on the source node go first; waiting does not
+		// count towards the backup timeout
+		release, err := e.backups.Acquire(ctx, plan.SourceNode)
+		if err != nil {
+			return "", fmt.Errorf("backup failed: %w", err)
+		}
+		e.setProgress(containerConfig.ID, StepBackup, "", -1)
+
+		started := time.Now()
+		target := backup.Target{Container: *containerConfig, Node: plan.SourceNode, Storage: backupStorage, Origin: catalog.OriginPreFailover}
+		backupPath, err = backup.WithHooks(ctx, &e.cfg().Backup, target, e.logger, func(ctx context.Context) (string, error) {
+			backupCtx, cancel := withTimeout(ctx, e.cfg().Backup.BackupTimeout)
+			defer cancel()
+			return e.apiClient.BackupContainer(backupCtx, containerConfig.ID, backupStorage, e.cfg().Backup.BackupDir, e.cfg().Backup.VzdumpFor(*containerConfig))
+		})
+		release()
 		if err != nil {
-			result.Error = fmt.Errorf("backup failed: %w", err)
-			result.EndTime = time.Now()
-			result.Duration = result.EndTime.Sub(result.StartTime)
-			return result
+			return "", fmt.Errorf("backup failed: %w", err)
 		}
+		e.recordBackup(catalog.Entry{
+			VolID:       backupPath,
+			ContainerID: containerConfig.ID,
+			Storage:     backupStorage,
+			Created:     started,
+			Origin:      catalog.OriginPreFailover,
+		})
 
 		e.logger.WithFields(logrus.Fields{
 			"container_id": containerConfig.ID,
 			"backup_path":  backupPath,
 		}).Info("Backup completed successfully")
-	} else {
-		// Find the latest backup
-		backupPath, err = e.findLatestBackup(ctx, containerConfig.ID)
+	}
+
+	// Find the latest backup, or the pinned one, which is restored even
+	// when a fresh backup was taken
+	if _, pinned := e.pinnedBackup(containerConfig.ID); pinned || !plan.BackupFirst {
+		backupPath, err = e.findBackup(ctx, containerConfig.ID)
 		if err != nil {
-			result.Error = fmt.Errorf("failed to find backup: %w", err)
-			result.EndTime = time.Now()
-			result.Duration = result.EndTime.Sub(result.StartTime)
-			return result
+			return "", fmt.Errorf("failed to find backup: %w", err)
 		}
 	}
 
-	// Perform backup-restore based failover with retries
-	for attempt := 1; attempt <= e.config.Failover.MaxRetries; attempt++ {
+	// Step 2: Make sure the original no longer runs
+	e.setProgress(containerConfig.ID, StepStopSource, "", -1)
+	if err := e.ensureSourceDown(ctx, plan, containerConfig.ID); err != nil {
+		return backupPath, err
+	}
+
+	return backupPath, e.retry(ctx, containerConfig, "backup-restore", func() error {
+		return e.performBackupRestoreFailover(ctx, containerConfig, plan.TargetNode, backupPath)
+	})
+}
+
+// retry runs a failover step up to MaxRetries times, until ctx is done.
+func (e *Engine) retry(ctx context.Context, containerConfig *config.ContainerConfig, method string, step func() error) error {
+	var err error
+	for attempt := 1; attempt <= e.cfg().Failover.MaxRetries; attempt++ {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%s failover stopped: %w", method, ctx.Err())
+		}
+
 		e.logger.WithFields(logrus.Fields{
 			"container_id": containerConfig.ID,
+			"method":       method,
 			"attempt":      attempt,
-			"max_retries":  e.config.Failover.MaxRetries,
-		}).Info("Attempting backup-restore failover")
+			"max_retries":  e.cfg().Failover.MaxRetries,
+		}).Info("Attempting failover")
 
-		err = e.performBackupRestoreFailover(ctx, containerConfig, sourceNode, targetNode, backupPath)
+		err = step()
 		if err == nil {
-			result.Success = true
-			break
+			return nil
 		}
 
 		e.logger.WithFields(logrus.Fields{
 			"container_id": containerConfig.ID,
+			"method":       method,
 			"attempt":      attempt,
 			"error":        err,
-		}).Warn("Backup-restore failover attempt failed")
+		}).Warn("Failover attempt failed")
 
-		if attempt < e.config.Failover.MaxRetries {
-			time.Sleep(e.config.Failover.RetryDelay)
+		if attempt < e.cfg().Failover.MaxRetries {
+			select {
+			case <-ctx.Done():
+			case <-time.After(e.cfg().Failover.RetryDelay):
+			}
 		}
 	}
 
-	result.EndTime = time.Now()
-	result.Duration = result.EndTime.Sub(result.StartTime)
+	return fmt.Errorf("%s failover failed after %d attempts: %w", method, e.cfg().Failover.MaxRetries, err)
+}
 
-	if err != nil {
-		result.Error = fmt.Errorf("backup-restore failover failed after %d attempts: %w", e.config.Failover.MaxRetries, err)
-		return result
+// withTimeout bounds ctx by timeout; a zero timeout leaves it unbounded.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
 	}
+	return context.WithTimeout(ctx, timeout)
+}
 
-	// Execute post-failover hooks
-	if err := e.executeHooks(e.config.Failover.PostFailoverHooks, containerConfig); err != nil {
+// release ends a failover of the container and alerts when it opened the
+// container's circuit breaker.
+func (e *Engine) release(containerConfig *config.ContainerConfig) {
+	if !e.guard.release(containerConfig.ID) {
+		return
+	}
+
+	e.logger.WithFields(logrus.Fields{
+		"container_id": containerConfig.ID,
+		"max_per_hour": e.cfg().Failover.MaxFailoversPerHour,
+	}).Error("Failover circuit breaker opened, automatic failover disabled for the next hour")
+
+	e.notifier.Send(notify.Event{
+		Type:          notify.EventCircuitOpen,
+		Severity:      notify.SeverityCritical,
+		ContainerID:   containerConfig.ID,
+		ContainerName: containerConfig.Name,
+		Message:       fmt.Sprintf("Container %d failed over %d times within an hour, automatic failover suspended", containerConfig.ID, e.cfg().Failover.MaxFailoversPerHour),
+	})
+}
+
+func (e *Engine) recordHistory(containerConfig *config.ContainerConfig, result *FailoverResult) {
+	if e.history == nil {
+		return
+	}
+
+	record := history.Record{
+		ContainerID:   result.ContainerID,
+		ContainerName: containerConfig.Name,
+		Trigger:       result.Trigger,
+		Strategy:      result.Strategy,
+		SourceNode:    result.SourceNode,
+		TargetNode:    result.TargetNode,
+		Backup:        result.Backup,
+		Success:       result.Success,
+		StartTime:     result.StartTime,
+		EndTime:       result.EndTime,
+		Duration:      result.Duration,
+		RoundTrip:     result.RoundTrip,
+	}
+	if result.Error != nil {
+		record.Error = result.Error.Error()
+	}
+	for _, hook := range result.Hooks {
+		hookRecord := history.HookRecord{
+			Phase:    hook.Phase,
+			Command:  hook.Command,
+			Success:  hook.Success,
+			Output:   hook.Output,
+			Duration: hook.Duration,
+		}
+		if hook.Error != nil {
+			hookRecord.Error = hook.Error.Error()
+		}
+		record.Hooks = append(record.Hooks, hookRecord)
+	}
+	record.Warnings = result.Warnings
+	if floating := result.FloatingIP; floating != nil {
+		record.FloatingIP = &history.FloatingIPRecord{
+			Address:  floating.Address,
+			Success:  floating.Success,
+			ARPSent:  floating.ARPSent,
+			Duration: floating.Duration,
+		}
+		if floating.Error != nil {
+			record.FloatingIP.Error = floating.Error.Error()
+		}
+	}
+
+	if err := e.history.Append(record); err != nil {
 		e.logger.WithFields(logrus.Fields{
-			"container_id": containerConfig.ID,
+			"container_id": result.ContainerID,
 			"error":        err,
-		}).Warn("Post-failover hooks failed, but failover was successful")
+		}).Error("Failed to record failover history")
 	}
+}
 
-	return result
+func (e *Engine) notifyResult(containerConfig *config.ContainerConfig, result *FailoverResult) {
+	event := notify.Event{
+		ContainerID:   containerConfig.ID,
+		ContainerName: containerConfig.Name,
+		Node:          result.SourceNode,
+		Details: map[string]string{
+			"target_node": result.TargetNode,
+			"strategy":    result.Strategy,
+			"duration":    result.Duration.String(),
+		},
+	}
+
+	if result.RoundTrip > 0 {
+		event.Details["round_trip"] = result.RoundTrip.String()
+	}
+	if len(result.Warnings) > 0 {
+		event.Details["warnings"] = strings.Join(result.Warnings, "; ")
+	}
+
+	failback := movesBack(result.Trigger)
+	drill := result.Trigger == history.TriggerDrill
+	switch {
+	case drill && result.Success:
+		event.Type = notify.EventDrillSucceeded
+		event.Severity = notify.SeverityInfo
+		event.Message = fmt.Sprintf("Failover drill of container %d from %s to %s succeeded", containerConfig.ID, result.SourceNode, result.TargetNode)
+	case drill:
+		event.Type = notify.EventDrillFailed
+		event.Severity = notify.SeverityCritical
+		event.Message = fmt.Sprintf("Failover drill of container %d from %s to %s failed, a real failover may fail too: %v", containerConfig.ID, result.SourceNode, result.TargetNode, result.Error)
+	case failback && result.Success:
+		event.Type = notify.EventFailbackSucceeded
+		event.Severity = notify.SeverityInfo
+		event.Message = fmt.Sprintf("Container %d failed back from %s to %s", containerConfig.ID, result.SourceNode, result.TargetNode)
+	case failback:
+		event.Type = notify.EventFailbackFailed
+		event.Severity = notify.SeverityWarning
+		event.Message = fmt.Sprintf("Failback of container %d from %s to %s failed: %v", containerConfig.ID, result.SourceNode, result.TargetNode, result.Error)
+	case result.Success:
+		event.Type = notify.EventFailoverSucceeded
+		event.Severity = notify.SeverityInfo
+		event.Message = fmt.Sprintf("Container %d failed over from %s to %s", containerConfig.ID, result.SourceNode, result.TargetNode)
+	default:
+		event.Type = notify.EventFailoverFailed
+		event.Severity = notify.SeverityCritical
+		event.Message = fmt.Sprintf("Failover of container %d from %s to %s failed: %v", containerConfig.ID, result.SourceNode, result.TargetNode, result.Error)
+	}
+
+	e.notifier.Send(event)
 }
 
-func (e *Engine) performBackupRestoreFailover(ctx context.Context, containerConfig *config.ContainerConfig, sourceNode, targetNode, backupPath string) error {
+// performBackupRestoreFailover restores the backup on the target node and
+// starts it. The original must already be confirmed down.
+func (e *Engine) performBackupRestoreFailover(ctx context.Context, containerConfig *config.ContainerConfig, targetNode, backupPath string) error {
 	containerID := containerConfig.ID
 
-	// Step 1: Stop original container if reachable
-	e.logger.WithField("container_id", containerID).Info("Attempting to stop original container")
-	if err := e.apiClient.StopContainer(ctx, containerID); err != nil {
-		e.logger.WithFields(logrus.Fields{
-			"container_id": containerID,
-			"error":        err,
-		}).Warn("Failed to stop original container, continuing with restore")
-	} else {
-		e.logger.WithField("container_id", containerID).Info("Original container stopped successfully")
+	// Step 1: Restore from backup on target node, adjusted to fit it
+	override := containerConfig.NodeOverride(targetNode)
+	options := api.RestoreOptions{
+		Storage: override.Storage,
+		Force:   true,
+		Cores:   override.Cores,
+		Memory:  override.Memory,
+		Bridge:  override.Bridge,
+		Progress: func(progress api.RestoreProgress) {
+			e.setProgress(containerID, StepRestore, progress.Phase, progress.Percent)
+		},
+	}
+	if options.Storage == "" {
+		options.Storage = containerConfig.Storage
+	}
+	if options.Storage == "" {
+		options.Storage = "local-lvm" // Default storage
 	}
 
-	// Step 2: Restore from backup on target node
 	e.logger.WithFields(logrus.Fields{
 		"container_id": containerID,
 		"target_node":  targetNode,
 		"backup_path":  backupPath,
+		"storage":      options.Storage,
+		"override":     override,
 	}).Info("Restoring container from backup")
 
-	storage := containerConfig.Storage
-	if storage == "" {
-		storage = "local-lvm" // Default storage
-	}
-
-	err := e.apiClient.RestoreContainerFromBackup(ctx, containerID, targetNode, storage, backupPath, true)
+	restoreCtx, cancel := withTimeout(ctx, e.cfg().Failover.RestoreTimeout)
+	err := e.apiClient.RestoreContainerFromBackup(restoreCtx, containerID, targetNode, backupPath, options)
+	cancel()
 	if err != nil {
 		return fmt.Errorf("failed to restore container: %w", err)
 	}
+	e.backupVerified(backupPath)
 
-	// Step 3: Start the restored container
+	// Step 2: Start the restored container
+	e.setProgress(containerID, StepStart, "", -1)
 	e.logger.WithField("container_id", containerID).Info("Starting restored container")
 	err = e.apiClient.StartContainer(ctx, containerID)
 	if err != nil {
@@ -379,54 +895,160 @@ func (e *Engine) performBackupRestoreFailover(ctx context.Context, containerConf
 	return nil
 }
 
-func (e *Engine) findLatestBackup(ctx context.Context, containerID int) (string, error) {
-	backups, err := e.apiClient.GetBackups(ctx, e.config.Backup.Storage)
+// performMigrationFailover moves the container to the target node with a
+// Proxmox migration, keeping its disks and configuration. A running
+// container is restarted on the target.
+func (e *Engine) performMigrationFailover(ctx context.Context, containerConfig *config.ContainerConfig, targetNode string) error {
+	containerID := containerConfig.ID
+
+	e.logger.WithFields(logrus.Fields{
+		"container_id": containerID,
+		"target_node":  targetNode,
+	}).Info("Migrating container")
+
+	if err := e.apiClient.MigrateContainer(ctx, containerID, targetNode); err != nil {
+		return fmt.Errorf("failed to migrate container: %w", err)
+	}
+
+	// A stopped container stays stopped after migration
+	containerInfo, err := e.apiClient.GetContainer(ctx, containerID)
 	if err != nil {
-		return "", fmt.Errorf("failed to get backups: %w", err)
+		return fmt.Errorf("failed to get migrated container: %w", err)
+	}
+	if containerInfo.Status != "running" {
+		e.logger.WithField("container_id", containerID).Info("Starting migrated container")
+		if err := e.apiClient.StartContainer(ctx, containerID); err != nil {
+			return fmt.Errorf("failed to start migrated container: %w", err)
+		}
 	}
 
-	var latestBackup *api.BackupInfo
-	containerBackupPrefix := fmt.Sprintf("vzdump-lxc-%d-", containerID)
+	e.logger.WithFields(logrus.Fields{
+		"container_id": containerID,
+		"target_node":  targetNode,
+	}).Info("Container successfully migrated and running on target node")
+
+	return nil
+}
 
-	for _, backup := range backups {
-		if !strings.HasPrefix(backup.Filename, containerBackupPrefix) {
+// findBackup returns the backup a container is pinned to, or else its
+// newest backup on its backup storage or the replication storage. Storages
+// that cannot be listed are looked up in the backup catalog instead.
+func (e *Engine) findBackup(ctx context.Context, containerID int) (string, error) {
+	// The replication storage still has copies when the backup storage
+	// went down with a node
+	storages := []string{e.cfg().Backup.Storage}
+	if cfg := e.containerConfig(containerID); cfg != nil && cfg.BackupStorage != "" {
+		storages[0] = cfg.BackupStorage
+	}
+	if replica := e.cfg().Backup.Replication.Storage; replica != "" && replica != storages[0] {
+		storages = append(storages, replica)
+	}
+	// A backup on another storage may be pinned
+	pinned, isPinned := e.pinnedBackup(containerID)
+	if isPinned {
+		listed := false
+		for _, storage := range storages {
+			listed = listed || storage == pinned.Storage
+		}
+		if !listed {
+			storages = append(storages, pinned.Storage)
+		}
+	}
+
+	var (
+		entries []catalog.Entry
+		errs    []error
+	)
+	for _, storage := range storages {
+		backups, err := e.apiClient.GetBackups(ctx, storage)
+		if err != nil {
+			err = fmt.Errorf("storage %s: %w", storage, err)
+			known, catalogErr := e.catalog.List(catalog.Filter{ContainerID: containerID, Storage: storage})
+			if catalogErr != nil || len(known) == 0 {
+				errs = append(errs, err)
+				continue
+			}
+			e.logger.WithFields(logrus.Fields{
+				"container_id": containerID,
+				"storage":      storage,
+				"error":        err,
+			}).Warn("Failed to list backups, using the backup catalog")
+			entries = append(entries, known...)
 			continue
 		}
 
-		if latestBackup == nil || backup.Filename > latestBackup.Filename {
-			backupCopy := backup
-			latestBackup = &backupCopy
+		listed, err := e.catalog.Sync(storage, backups)
+		if err != nil {
+			e.logger.WithField("error", err).Warn("Failed to update backup catalog")
+		}
+		entries = append(entries, listed...)
+	}
+
+	if isPinned {
+		for _, entry := range entries {
+			if entry.VolID == pinned.VolID {
+				e.logger.WithFields(logrus.Fields{
+					"container_id": containerID,
+					"backup":       pinned.VolID,
+				}).Info("Using pinned backup")
+				return pinned.VolID, nil
+			}
+		}
+		return "", fmt.Errorf("pinned backup %s of container %d no longer exists", pinned.VolID, containerID)
+	}
+
+	var latest *catalog.Entry
+	for i, entry := range entries {
+		if entry.ContainerID != containerID {
+			continue
+		}
+		if latest == nil || entry.Created.After(latest.Created) {
+			latest = &entries[i]
 		}
 	}
 
-	if latestBackup == nil {
+	if latest == nil {
+		if len(errs) > 0 {
+			return "", fmt.Errorf("failed to get backups: %w", errors.Join(errs...))
+		}
 		return "", fmt.Errorf("no backup found for container %d", containerID)
 	}
 
-	return fmt.Sprintf("%s:%s", latestBackup.Storage, latestBackup.Filename), nil
+	if latest.VolID != "" {
+		return latest.VolID, nil
+	}
+	return fmt.Sprintf("%s:%s", latest.Storage, latest.Filename), nil
 }
 
-func (e *Engine) executeHooks(hooks []string, containerConfig *config.ContainerConfig) error {
-	if len(hooks) == 0 {
-		return nil
+// pinnedBackup returns the backup the failovers of a container are pinned
+// to, if any.
+func (e *Engine) pinnedBackup(containerID int) (catalog.Entry, bool) {
+	entry, pinned, err := e.catalog.Pinned(containerID)
+	if err != nil {
+		e.logger.WithFields(logrus.Fields{
+			"container_id": containerID,
+			"error":        err,
+		}).Warn("Failed to look up pinned backup")
 	}
+	return entry, pinned
+}
 
-	for _, hook := range hooks {
+// recordBackup adds a backup the engine took to the backup catalog.
+func (e *Engine) recordBackup(entry catalog.Entry) {
+	if err := e.catalog.Record(entry); err != nil {
 		e.logger.WithFields(logrus.Fields{
-			"container_id": containerConfig.ID,
-			"hook":         hook,
-		}).Info("Executing hook")
-
-		cmd := exec.Command("sh", "-c", hook)
-		cmd.Env = append(cmd.Env,
-			fmt.Sprintf("CONTAINER_ID=%d", containerConfig.ID),
-			fmt.Sprintf("CONTAINER_NAME=%s", containerConfig.Name),
-		)
-
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("hook '%s' failed: %w", hook, err)
-		}
+			"backup": entry.VolID,
+			"error":  err,
+		}).Warn("Failed to record backup in catalog")
 	}
+}
 
-	return nil
-}
\ No newline at end of file
+// backupVerified marks a backup restored successfully in the backup catalog.
+func (e *Engine) backupVerified(backupPath string) {
+	if err := e.catalog.MarkVerified(backupPath, time.Now()); err != nil {
+		e.logger.WithFields(logrus.Fields{
+			"backup": backupPath,
+			"error":  err,
+		}).Warn("Failed to record verified backup in catalog")
+	}
+}
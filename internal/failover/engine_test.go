@@ -0,0 +1,242 @@
+package failover
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeAPIClient is a Proxmox cluster in memory. Migrations and restores
+// move containers; calls are recorded in order.
+type fakeAPIClient struct {
+	mu          sync.Mutex
+	containers  map[int]*api.ContainerInfo
+	nodes       []*api.NodeInfo
+	replication []api.ReplicationJob
+	cluster     *api.ClusterStatus
+	migrateErr  error
+	restoreErr  error
+	// startErr fails starting the containers it lists
+	startErr map[int]error
+	calls    []string
+	// entered, when set, is sent the container of every migration started
+	entered chan int
+	// block, when set, holds each migration until it receives a value or
+	// is closed
+	block chan struct{}
+}
+
+func newFakeAPIClient(nodes ...*api.NodeInfo) *fakeAPIClient {
+	return &fakeAPIClient{containers: make(map[int]*api.ContainerInfo), nodes: nodes}
+}
+
+// onlineNode returns an online node with the given memory and CPU usage.
+func onlineNode(name string, memoryUsage, cpu float64) *api.NodeInfo {
+	return &api.NodeInfo{Name: name, Status: "online", Online: true, CPU: cpu, MaxCPU: 8, Mem: uint64(memoryUsage * 1000), MaxMem: 1000}
+}
+
+func (f *fakeAPIClient) record(format string, args ...interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeAPIClient) recorded() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+func (f *fakeAPIClient) addContainer(id int, node, status string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.containers[id] = &api.ContainerInfo{ID: id, Node: node, Status: status}
+}
+
+func (f *fakeAPIClient) GetContainer(ctx context.Context, containerID int) (*api.ContainerInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	container, ok := f.containers[containerID]
+	if !ok {
+		return nil, api.ErrContainerNotFound
+	}
+	info := *container
+	return &info, nil
+}
+
+func (f *fakeAPIClient) GetContainerMetrics(ctx context.Context, node string, containerID int) (*api.ContainerMetrics, error) {
+	return &api.ContainerMetrics{}, nil
+}
+
+func (f *fakeAPIClient) GetContainerRRD(ctx context.Context, node string, containerID int) ([]api.RRDPoint, error) {
+	return nil, nil
+}
+
+func (f *fakeAPIClient) GetContainerInterfaces(ctx context.Context, node string, containerID int) ([]api.ContainerInterface, error) {
+	return nil, nil
+}
+
+func (f *fakeAPIClient) GetContainersByNode(ctx context.Context, nodeName string) ([]*api.ContainerInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var result []*api.ContainerInfo
+	for _, container := range f.containers {
+		if container.Node == nodeName {
+			info := *container
+			result = append(result, &info)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeAPIClient) GetNodes(ctx context.Context) ([]*api.NodeInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.nodes, nil
+}
+
+func (f *fakeAPIClient) GetClusterTasks(ctx context.Context) ([]api.TaskInfo, error) {
+	return nil, nil
+}
+
+func (f *fakeAPIClient) GetClusterResources(ctx context.Context) ([]api.ClusterResource, error) {
+	return nil, nil
+}
+
+func (f *fakeAPIClient) GetReplicationJobs(ctx context.Context) ([]api.ReplicationJob, error) {
+	return f.replication, nil
+}
+
+func (f *fakeAPIClient) GetClusterStatus(ctx context.Context) (*api.ClusterStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.cluster != nil {
+		return f.cluster, nil
+	}
+	status := &api.ClusterStatus{Quorate: true, Nodes: make(map[string]bool)}
+	for _, node := range f.nodes {
+		status.Nodes[node.Name] = node.Online
+	}
+	return status, nil
+}
+
+func (f *fakeAPIClient) GetHANodeStatus(ctx context.Context) (map[string]string, error) {
+	return nil, nil
+}
+
+func (f *fakeAPIClient) GetHAPlacement(ctx context.Context, containerID int) (*api.HAPlacement, error) {
+	return nil, nil
+}
+
+func (f *fakeAPIClient) MigrateContainer(ctx context.Context, containerID int, targetNode string) error {
+	f.record("migrate %d %s", containerID, targetNode)
+	if f.entered != nil {
+		f.entered <- containerID
+	}
+	if f.block != nil {
+		select {
+		case <-f.block:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if f.migrateErr != nil {
+		return f.migrateErr
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if container, ok := f.containers[containerID]; ok {
+		container.Node = targetNode
+	}
+	return nil
+}
+
+func (f *fakeAPIClient) StopContainer(ctx context.Context, containerID int) error {
+	f.record("stop %d", containerID)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if container, ok := f.containers[containerID]; ok {
+		container.Status = "stopped"
+	}
+	return nil
+}
+
+func (f *fakeAPIClient) StartContainer(ctx context.Context, containerID int) error {
+	f.record("start %d", containerID)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.startErr[containerID]; err != nil {
+		return err
+	}
+	if container, ok := f.containers[containerID]; ok {
+		container.Status = "running"
+	}
+	return nil
+}
+
+func (f *fakeAPIClient) BackupContainer(ctx context.Context, containerID int, storage, backupDir string, options config.VzdumpOptions) (string, error) {
+	f.record("backup %d", containerID)
+	return fmt.Sprintf("%s:backup/vzdump-lxc-%d.tar.zst", storage, containerID), nil
+}
+
+func (f *fakeAPIClient) RestoreContainerFromBackup(ctx context.Context, containerID int, targetNode, backupPath string, options api.RestoreOptions) error {
+	f.record("restore %d %s", containerID, targetNode)
+	if f.restoreErr != nil {
+		return f.restoreErr
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.containers[containerID] = &api.ContainerInfo{ID: containerID, Node: targetNode, Status: "stopped"}
+	return nil
+}
+
+func (f *fakeAPIClient) GetBackups(ctx context.Context, storage string) ([]api.BackupInfo, error) {
+	return nil, nil
+}
+
+func (f *fakeAPIClient) DeleteBackup(ctx context.Context, nodeName, storage, backupPath string) error {
+	return nil
+}
+
+func (f *fakeAPIClient) GetSnapshots(ctx context.Context, containerID int) ([]api.Snapshot, error) {
+	return nil, nil
+}
+
+func (f *fakeAPIClient) CreateSnapshot(ctx context.Context, containerID int, name, description string) error {
+	return nil
+}
+
+func (f *fakeAPIClient) DeleteSnapshot(ctx context.Context, containerID int, name string) error {
+	return nil
+}
+
+// testConfig monitors containers with the given priorities, failing over
+// from source to a.
+func testConfig(priorities map[int]int) *config.Config {
+	cfg := &config.Config{}
+	cfg.Failover.MaxRetries = 1
+	cfg.Failover.Strategy = config.StrategyMigrate
+	for id, priority := range priorities {
+		cfg.Monitoring.Containers = append(cfg.Monitoring.Containers, config.ContainerConfig{
+			ID:            id,
+			Priority:      priority,
+			FailoverNodes: []string{"a"},
+		})
+	}
+	sort.Slice(cfg.Monitoring.Containers, func(i, j int) bool {
+		return cfg.Monitoring.Containers[i].ID < cfg.Monitoring.Containers[j].ID
+	})
+	return cfg
+}
+
+// newTestEngine returns an engine on the fake cluster that logs nothing.
+func newTestEngine(cfg *config.Config, client *fakeAPIClient) *Engine {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	return NewWithConfig(cfg, client, logger)
+}
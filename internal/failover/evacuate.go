@@ -0,0 +1,90 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/history"
+	"github.com/sirupsen/logrus"
+)
+
+// EvacuationProgress reports a container of a node evacuation that finished.
+type EvacuationProgress struct {
+	Done   int
+	Total  int
+	Result *FailoverResult
+}
+
+// EvacuateNode moves every monitored container off node for planned
+// maintenance, in priority order. Containers are migrated where possible,
+// falling back to backup-restore. A non-empty targetHint moves all of them
+// to that node instead of selecting one per container. progress, if set, is
+// called as each container finishes.
+func (e *Engine) EvacuateNode(ctx context.Context, node, targetHint string, progress func(EvacuationProgress)) error {
+	if targetHint == node {
+		return fmt.Errorf("target node %s is the node being evacuated", node)
+	}
+
+	containers, err := e.apiClient.GetContainersByNode(ctx, node)
+	if err != nil {
+		return fmt.Errorf("failed to list containers on node %s: %w", node, err)
+	}
+
+	var monitored []*config.ContainerConfig
+	for _, container := range containers {
+		if containerConfig := e.containerConfig(container.ID); containerConfig != nil {
+			monitored = append(monitored, containerConfig)
+		}
+	}
+	sort.SliceStable(monitored, func(i, j int) bool {
+		if monitored[i].Priority != monitored[j].Priority {
+			return monitored[i].Priority < monitored[j].Priority
+		}
+		return monitored[i].ID < monitored[j].ID
+	})
+
+	containerIDs := make([]int, len(monitored))
+	for i, containerConfig := range monitored {
+		containerIDs[i] = containerConfig.ID
+	}
+
+	e.logger.WithFields(logrus.Fields{
+		"node":        node,
+		"containers":  containerIDs,
+		"target_node": targetHint,
+	}).Info("Starting evacuation of node")
+
+	var (
+		errs []error
+		done int
+	)
+	e.failoverBatch(ctx, batch{
+		sourceNode:   node,
+		containerIDs: containerIDs,
+		trigger:      history.TriggerEvacuation,
+		strategy:     config.StrategyMigrate,
+		targetNode:   targetHint,
+	}, func(result *FailoverResult) {
+		done++
+		if !result.Success {
+			errs = append(errs, fmt.Errorf("container %d: %w", result.ContainerID, result.Error))
+		}
+		if progress != nil {
+			progress(EvacuationProgress{Done: done, Total: len(containerIDs), Result: result})
+		}
+	})
+
+	if err := errors.Join(errs...); err != nil {
+		e.logger.WithFields(logrus.Fields{
+			"node":   node,
+			"failed": len(errs),
+		}).Error("Evacuation of node incomplete")
+		return err
+	}
+
+	e.logger.WithField("node", node).Info("Evacuation of node completed successfully")
+	return nil
+}
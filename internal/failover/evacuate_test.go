@@ -0,0 +1,111 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEngine_EvacuateNode(t *testing.T) {
+	tests := []struct {
+		name       string
+		targetHint string
+		migrateErr error
+		expected   []string
+		target     string
+	}{
+		{
+			name:     "lowest priority value first",
+			expected: []string{"migrate 102 a", "migrate 103 a", "migrate 101 a"},
+			target:   "a",
+		},
+		{
+			name:       "target override",
+			targetHint: "b",
+			expected:   []string{"migrate 102 b", "migrate 103 b", "migrate 101 b"},
+			target:     "b",
+		},
+		{
+			name:       "failed migrations",
+			migrateErr: errors.New("migration refused"),
+			expected:   []string{"migrate 102 a", "migrate 103 a", "migrate 101 a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(map[int]int{101: 2, 102: 1, 103: 1, 104: 0})
+			cfg.Failover.TierConcurrency = 1
+
+			client := newFakeAPIClient(onlineNode("source", 0, 0), onlineNode("a", 0, 0), onlineNode("b", 0, 0))
+			client.migrateErr = tt.migrateErr
+			client.addContainer(101, "source", "running")
+			client.addContainer(102, "source", "running")
+			client.addContainer(103, "source", "running")
+			// Not on the node, and not monitored
+			client.addContainer(104, "a", "running")
+			client.addContainer(200, "source", "running")
+			engine := newTestEngine(cfg, client)
+
+			var progress []EvacuationProgress
+			err := engine.EvacuateNode(context.Background(), "source", tt.targetHint, func(p EvacuationProgress) {
+				progress = append(progress, p)
+			})
+
+			var migrations []string
+			for _, call := range client.recorded() {
+				if strings.HasPrefix(call, "migrate ") {
+					migrations = append(migrations, call)
+				}
+			}
+			if !reflect.DeepEqual(migrations, tt.expected) {
+				t.Errorf("Expected migrations %v, got %v", tt.expected, migrations)
+			}
+
+			if len(progress) != 3 {
+				t.Fatalf("Expected progress for 3 containers, got %d", len(progress))
+			}
+			for i, p := range progress {
+				if p.Done != i+1 || p.Total != 3 {
+					t.Errorf("Expected progress %d/3, got %d/%d", i+1, p.Done, p.Total)
+				}
+				if p.Result.Success != (tt.migrateErr == nil) {
+					t.Errorf("Expected container %d success %v, got %v", p.Result.ContainerID, tt.migrateErr == nil, p.Result.Success)
+				}
+				if p.Result.Success && p.Result.TargetNode != tt.target {
+					t.Errorf("Expected container %d on %s, got %s", p.Result.ContainerID, tt.target, p.Result.TargetNode)
+				}
+			}
+
+			if tt.migrateErr == nil {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Expected error but got none")
+			}
+			for _, id := range []string{"container 101", "container 102", "container 103"} {
+				if !strings.Contains(err.Error(), id) {
+					t.Errorf("Expected error to report %s, got %v", id, err)
+				}
+			}
+		})
+	}
+}
+
+func TestEngine_EvacuateNodeOntoItself(t *testing.T) {
+	client := newFakeAPIClient(onlineNode("source", 0, 0))
+	client.addContainer(101, "source", "running")
+	engine := newTestEngine(testConfig(map[int]int{101: 0}), client)
+
+	if err := engine.EvacuateNode(context.Background(), "source", "source", nil); err == nil {
+		t.Error("Expected error evacuating a node onto itself")
+	}
+	if calls := client.recorded(); len(calls) != 0 {
+		t.Errorf("Expected no calls, got %v", calls)
+	}
+}
@@ -0,0 +1,201 @@
+package failover
+
+import (
+	"context"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/history"
+	"github.com/sirupsen/logrus"
+)
+
+// failbackInterval is how often pending failbacks are checked.
+const failbackInterval = time.Minute
+
+// failback is an automatic failover waiting to be moved back.
+type failback struct {
+	originalNode string
+	failoverNode string
+	failedOverAt time.Time
+}
+
+// trackFailback remembers a successful automatic failover so the container
+// can be moved back once its original node is stable. Standby failovers run
+// a different container and are not moved back.
+func (e *Engine) trackFailback(result *FailoverResult) {
+	if !e.cfg().Failover.Failback.Enabled || !result.Success || result.Strategy == config.StrategyStandby {
+		return
+	}
+
+	e.failbackMu.Lock()
+	defer e.failbackMu.Unlock()
+
+	// A container failing over again keeps its first original node
+	originalNode := result.SourceNode
+	if pending, exists := e.failbacks[result.ContainerID]; exists {
+		originalNode = pending.originalNode
+	}
+	if originalNode == result.TargetNode {
+		delete(e.failbacks, result.ContainerID)
+		return
+	}
+
+	e.failbacks[result.ContainerID] = failback{
+		originalNode: originalNode,
+		failoverNode: result.TargetNode,
+		failedOverAt: result.EndTime,
+	}
+}
+
+// RunFailback moves failed-over containers back to their original node once
+// that node has been online for the configured period, until ctx is done.
+func (e *Engine) RunFailback(ctx context.Context) {
+	ticker := time.NewTicker(failbackInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			e.checkFailbacks(ctx, now)
+		}
+	}
+}
+
+func (e *Engine) checkFailbacks(ctx context.Context, now time.Time) {
+	e.failbackMu.Lock()
+	pending := make(map[int]failback, len(e.failbacks))
+	for id, fb := range e.failbacks {
+		pending[id] = fb
+	}
+	e.failbackMu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	nodes, err := e.apiClient.GetNodes(ctx)
+	if err != nil {
+		e.logger.WithField("error", err).Warn("Failed to get nodes for failback")
+		return
+	}
+
+	e.failbackMu.Lock()
+	online := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		if !node.Online {
+			continue
+		}
+		online[node.Name] = true
+		if _, exists := e.onlineSince[node.Name]; !exists {
+			e.onlineSince[node.Name] = now
+		}
+	}
+	for name := range e.onlineSince {
+		if !online[name] {
+			delete(e.onlineSince, name)
+		}
+	}
+	onlineSince := make(map[string]time.Time, len(e.onlineSince))
+	for name, since := range e.onlineSince {
+		onlineSince[name] = since
+	}
+	e.failbackMu.Unlock()
+
+	cfg := e.cfg().Failover.Failback
+	if !windowActive(cfg.Windows, now) {
+		return
+	}
+
+	for containerID, fb := range pending {
+		since, exists := onlineSince[fb.originalNode]
+		if !exists {
+			continue
+		}
+		// The node may have stayed online while the container failed
+		if since.Before(fb.failedOverAt) {
+			since = fb.failedOverAt
+		}
+		if now.Sub(since) < cfg.StableFor {
+			continue
+		}
+
+		e.failBack(ctx, containerID, fb)
+	}
+}
+
+// windowActive reports whether any of the windows is active at t. No windows
+// means always.
+func windowActive(windows []config.BlackoutWindow, t time.Time) bool {
+	if len(windows) == 0 {
+		return true
+	}
+	for _, window := range windows {
+		if window.Active(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// failBack moves a single container back to its original node. The entry is
+// dropped whatever the outcome, so a failing failback is not retried every
+// interval; operators are notified instead.
+func (e *Engine) failBack(ctx context.Context, containerID int, fb failback) {
+	logger := e.logger.WithFields(logrus.Fields{
+		"container_id":  containerID,
+		"original_node": fb.originalNode,
+	})
+
+	containerConfig := e.containerConfig(containerID)
+	if containerConfig == nil {
+		logger.Info("Container no longer monitored, dropping failback")
+		e.forgetFailback(containerID)
+		return
+	}
+
+	containerInfo, err := e.apiClient.GetContainer(ctx, containerID)
+	if err != nil {
+		logger.WithField("error", err).Warn("Failed to get container info for failback")
+		return
+	}
+	if containerInfo.Node != fb.failoverNode {
+		logger.WithField("node", containerInfo.Node).Info("Container moved since failover, dropping failback")
+		e.forgetFailback(containerID)
+		return
+	}
+
+	if err := e.guard.acquire(containerID, history.TriggerFailback); err != nil {
+		logger.WithField("reason", err).Debug("Failback postponed")
+		return
+	}
+	defer e.release(containerConfig)
+	e.forgetFailback(containerID)
+
+	logger.WithField("source_node", containerInfo.Node).Info("Starting failback")
+
+	result := e.performFailover(ctx, &Plan{
+		Container:    containerConfig,
+		SourceNode:   containerInfo.Node,
+		TargetNode:   fb.originalNode,
+		BackupFirst:  true,
+		Trigger:      history.TriggerFailback,
+		FailedOverAt: fb.failedOverAt,
+	}, e.cfg().Failover.Failback.Strategy)
+
+	if !result.Success {
+		logger.WithField("error", result.Error).Error("Failback failed, container stays on failover node")
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"duration":   result.Duration,
+		"round_trip": result.RoundTrip,
+	}).Info("Failback completed successfully")
+}
+
+func (e *Engine) forgetFailback(containerID int) {
+	e.failbackMu.Lock()
+	defer e.failbackMu.Unlock()
+	delete(e.failbacks, containerID)
+}
@@ -0,0 +1,115 @@
+package failover
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// fencePollInterval is how often the cluster is asked whether a node has
+// been fenced.
+const fencePollInterval = 10 * time.Second
+
+// fence makes sure node can no longer run the container before a copy is
+// started elsewhere. Without fencing configured it does nothing.
+func (e *Engine) fence(ctx context.Context, containerConfig *config.ContainerConfig, node string) error {
+	cfg := e.cfg().Failover.Fencing
+	if !cfg.Enabled {
+		return nil
+	}
+
+	logger := e.logger.WithFields(logrus.Fields{
+		"container_id": containerConfig.ID,
+		"node":         node,
+	})
+	logger.Warn("Fencing source node before starting container elsewhere")
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	if cfg.Cluster {
+		if err := e.waitClusterFenced(ctx, node); err != nil {
+			return fmt.Errorf("node %s not fenced by cluster: %w", node, err)
+		}
+	}
+
+	for _, hook := range cfg.Hooks {
+		logger.WithField("hook", hook).Info("Executing fence hook")
+
+		cmd := exec.CommandContext(ctx, "sh", "-c", hook)
+		cmd.Env = append(os.Environ(),
+			fmt.Sprintf("NODE=%s", node),
+			fmt.Sprintf("CONTAINER_ID=%d", containerConfig.ID),
+			fmt.Sprintf("CONTAINER_NAME=%s", containerConfig.Name),
+		)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("fence hook '%s' failed: %w: %s", hook, err, output)
+		}
+	}
+
+	for _, p := range cfg.Plugins {
+		logger.WithField("plugin", p.Command).Info("Executing fence plugin")
+		if err := runFencePlugin(ctx, p, containerConfig, node); err != nil {
+			return fmt.Errorf("fence plugin %s failed: %w", p.Command, err)
+		}
+	}
+
+	logger.Info("Source node fenced")
+	return nil
+}
+
+// waitClusterFenced waits until the quorate cluster no longer counts node as
+// a member and Proxmox HA, when it manages the node, has finished fencing it.
+func (e *Engine) waitClusterFenced(ctx context.Context, node string) error {
+	ticker := time.NewTicker(fencePollInterval)
+	defer ticker.Stop()
+
+	for {
+		err := e.clusterFenced(ctx, node)
+		if err == nil {
+			return nil
+		}
+
+		e.logger.WithFields(logrus.Fields{
+			"node":   node,
+			"reason": err,
+		}).Info("Waiting for node to be fenced")
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Engine) clusterFenced(ctx context.Context, node string) error {
+	status, err := e.apiClient.GetClusterStatus(ctx)
+	if err != nil {
+		return err
+	}
+	// Without quorum this partition may be the one that is cut off
+	if !status.Quorate {
+		return fmt.Errorf("cluster is not quorate")
+	}
+	if status.Nodes[node] {
+		return fmt.Errorf("node is still a cluster member")
+	}
+
+	haStatus, err := e.apiClient.GetHANodeStatus(ctx)
+	if err != nil {
+		return err
+	}
+	switch state := haStatus[node]; state {
+	case "online", "maintenance":
+		return fmt.Errorf("HA manager reports node %s", state)
+	case "fence":
+		return fmt.Errorf("HA fencing still pending")
+	}
+	return nil
+}
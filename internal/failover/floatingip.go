@@ -0,0 +1,197 @@
+package failover
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/notify"
+	"github.com/sirupsen/logrus"
+)
+
+// Phases of the hooks that move a floating IP.
+const (
+	hookPhaseRelease = "floating-ip-release"
+	hookPhaseAcquire = "floating-ip-acquire"
+)
+
+// arpInterval spaces gratuitous ARP packets, so a switch dropping one still
+// learns from the next.
+const arpInterval = 500 * time.Millisecond
+
+// FloatingIPResult is the outcome of moving a container's floating IP.
+type FloatingIPResult struct {
+	Address  string
+	Success  bool
+	Error    error
+	ARPSent  int
+	Duration time.Duration
+}
+
+// instances returns the container the failover moved away from and the one
+// now running. They differ when a standby took over, or was replaced by the
+// original again on rollback.
+func instances(plan *Plan, result *FailoverResult) (previous, current int) {
+	if result.Strategy != config.StrategyStandby {
+		return plan.Container.ID, plan.Container.ID
+	}
+	if movesBack(plan.Trigger) {
+		return plan.Container.StandbyID, plan.Container.ID
+	}
+	return plan.Container.ID, plan.Container.StandbyID
+}
+
+// moveFloatingIP moves the container's floating IP to where it now runs and
+// announces it with gratuitous ARP. The failover already succeeded, so
+// failures are recorded in the result and notified but do not fail it.
+func (e *Engine) moveFloatingIP(ctx context.Context, plan *Plan, result *FailoverResult) {
+	floating := plan.Container.FloatingIP
+	if floating == nil {
+		return
+	}
+
+	start := time.Now()
+	step := &FloatingIPResult{Address: floating.Address}
+	result.FloatingIP = step
+
+	logger := e.logger.WithFields(logrus.Fields{
+		"container_id": plan.Container.ID,
+		"floating_ip":  floating.Address,
+		"node":         result.TargetNode,
+	})
+	logger.Info("Moving floating IP")
+
+	err := e.acquireFloatingIP(ctx, plan, result, floating)
+	if err == nil && floating.ARPInterface != "" {
+		step.ARPSent, err = e.announceFloatingIP(ctx, plan, result, floating)
+	}
+	step.Duration = time.Since(start)
+
+	if err != nil {
+		step.Error = err
+		logger.WithField("error", err).Error("Failed to move floating IP")
+		e.notifier.Send(notify.Event{
+			Type:          notify.EventFloatingIPFailed,
+			Severity:      notify.SeverityCritical,
+			ContainerID:   plan.Container.ID,
+			ContainerName: plan.Container.Name,
+			Node:          result.TargetNode,
+			Message:       fmt.Sprintf("Floating IP %s of container %d not moved: %v", floating.Address, plan.Container.ID, err),
+			Details:       map[string]string{"address": floating.Address},
+		})
+		return
+	}
+
+	step.Success = true
+	logger.WithFields(logrus.Fields{
+		"arp_sent": step.ARPSent,
+		"duration": step.Duration,
+	}).Info("Floating IP moved")
+}
+
+// acquireFloatingIP releases the address from the previous instance, which
+// is usually gone with its node so failing to is only logged, and acquires
+// it for the running one.
+func (e *Engine) acquireFloatingIP(ctx context.Context, plan *Plan, result *FailoverResult, floating *config.FloatingIPConfig) error {
+	release, acquire := floating.Release, floating.Acquire
+	if floating.Method == config.FloatingIPKeepalived || floating.Method == config.FloatingIPUcarp {
+		service := floating.Service
+		if service == "" {
+			service = floating.Method
+		}
+		previous, current := instances(plan, result)
+
+		// keepalived gives up the address when stopped; ucarp demotes
+		// itself to backup on SIGUSR2
+		releaseCommand := "systemctl stop " + service
+		if floating.Method == config.FloatingIPUcarp {
+			releaseCommand = "systemctl kill --signal=SIGUSR2 " + service
+		}
+		release = config.Hook{Command: containerCommand(plan.SourceNode, previous, releaseCommand), Timeout: floating.Release.Timeout}
+		acquire = config.Hook{Command: containerCommand(result.TargetNode, current, "systemctl restart "+service), Timeout: floating.Acquire.Timeout}
+	}
+
+	env := fmt.Sprintf("FLOATING_IP=%s", floating.Address)
+	if release.Command != "" {
+		run := e.runHook(ctx, hookPhaseRelease, release, plan, result, env)
+		result.Hooks = append(result.Hooks, run)
+		if !run.Success {
+			e.logger.WithFields(logrus.Fields{
+				"container_id": plan.Container.ID,
+				"floating_ip":  floating.Address,
+				"error":        run.Error,
+			}).Warn("Failed to release floating IP from previous instance, continuing")
+		}
+	}
+
+	run := e.runHook(ctx, hookPhaseAcquire, acquire, plan, result, env)
+	result.Hooks = append(result.Hooks, run)
+	return run.Error
+}
+
+// containerCommand returns a shell command running command inside a
+// container through SSH to its node, relying on the root SSH trust between
+// Proxmox cluster nodes.
+func containerCommand(node string, containerID int, command string) string {
+	return fmt.Sprintf("ssh -o BatchMode=yes -o ConnectTimeout=10 root@%s pct exec %d -- %s", node, containerID, command)
+}
+
+// announceFloatingIP sends gratuitous ARP for the floating IP with the MAC
+// address of the running container's interface, so switches and neighbours
+// stop sending traffic to the previous instance.
+func (e *Engine) announceFloatingIP(ctx context.Context, plan *Plan, result *FailoverResult, floating *config.FloatingIPConfig) (int, error) {
+	name := floating.Interface
+	if name == "" {
+		name = "eth0"
+	}
+	count := floating.ARPCount
+	if count == 0 {
+		count = 3
+	}
+
+	_, current := instances(plan, result)
+	interfaces, err := e.apiClient.GetContainerInterfaces(ctx, result.TargetNode, current)
+	if err != nil {
+		return 0, err
+	}
+
+	var mac net.HardwareAddr
+	for _, iface := range interfaces {
+		if iface.Name == name {
+			mac, err = net.ParseMAC(iface.HWAddr)
+			if err != nil {
+				return 0, fmt.Errorf("invalid MAC address of interface %s: %w", name, err)
+			}
+		}
+	}
+	if mac == nil {
+		return 0, fmt.Errorf("container has no interface %s", name)
+	}
+
+	sent, err := sendGratuitousARP(ctx, floating.ARPInterface, arpFrame(mac, net.ParseIP(floating.Address).To4()), count)
+	if err != nil {
+		return sent, fmt.Errorf("gratuitous ARP failed: %w", err)
+	}
+	return sent, nil
+}
+
+// arpFrame builds a broadcast gratuitous ARP request announcing that ip is
+// at mac.
+func arpFrame(mac net.HardwareAddr, ip net.IP) []byte {
+	frame := make([]byte, 0, 42)
+	frame = append(frame, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff)
+	frame = append(frame, mac...)
+	frame = binary.BigEndian.AppendUint16(frame, 0x0806) // ARP
+
+	frame = binary.BigEndian.AppendUint16(frame, 1)      // Ethernet
+	frame = binary.BigEndian.AppendUint16(frame, 0x0800) // IPv4
+	frame = append(frame, 6, 4)
+	frame = binary.BigEndian.AppendUint16(frame, 1) // request
+	frame = append(frame, mac...)
+	frame = append(frame, ip...)
+	frame = append(frame, 0, 0, 0, 0, 0, 0)
+	return append(frame, ip...)
+}
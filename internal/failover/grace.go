@@ -0,0 +1,52 @@
+package failover
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/notify"
+	"github.com/sirupsen/logrus"
+)
+
+// awaitGracePeriod announces an automatic failover and holds it for the
+// configured grace period, during which cancelling it leaves the container
+// untouched. Failovers awaiting approval had their chance to be stopped
+// already and skip it.
+func (e *Engine) awaitGracePeriod(ctx context.Context, plan *Plan) error {
+	grace := e.cfg().Failover.GracePeriod
+	if grace <= 0 || !requiresApproval(plan.Trigger) || e.approvalRequired(plan.Container, plan.Trigger) {
+		return nil
+	}
+
+	containerID := plan.Container.ID
+	startsAt := time.Now().Add(grace)
+	logger := e.logger.WithFields(logrus.Fields{
+		"container_id": containerID,
+		"source_node":  plan.SourceNode,
+		"target_node":  plan.TargetNode,
+		"grace_period": grace,
+	})
+	logger.Warn("Failover starts after grace period")
+
+	e.notifier.Send(notify.Event{
+		Type:          notify.EventFailoverImminent,
+		Severity:      notify.SeverityCritical,
+		ContainerID:   containerID,
+		ContainerName: plan.Container.Name,
+		Node:          plan.SourceNode,
+		Message: fmt.Sprintf("Failover of container %d from %s to %s starts in %s: run 'proxwarden failover cancel %d' to stop it",
+			containerID, plan.SourceNode, plan.TargetNode, grace, containerID),
+		Details: map[string]string{"target_node": plan.TargetNode, "starts_at": startsAt.Format(time.RFC3339)},
+	})
+
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		logger.Warn("Failover cancelled during grace period")
+		return fmt.Errorf("failover cancelled during grace period: %w", ctx.Err())
+	}
+}
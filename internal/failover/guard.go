@@ -0,0 +1,111 @@
+package failover
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/history"
+)
+
+// breakerWindow is the period over which failovers count toward the circuit
+// breaker limit.
+const breakerWindow = time.Hour
+
+// guard prevents overlapping failovers of a container and, for automatic
+// failovers, enforces the cooldown and the circuit breaker.
+type guard struct {
+	mu         sync.Mutex
+	cooldown   time.Duration
+	maxPerHour int
+	inProgress map[int]bool
+	completed  map[int][]time.Time
+	now        func() time.Time
+}
+
+func newGuard(cfg config.FailoverConfig) *guard {
+	return &guard{
+		cooldown:   cfg.Cooldown,
+		maxPerHour: cfg.MaxFailoversPerHour,
+		inProgress: make(map[int]bool),
+		completed:  make(map[int][]time.Time),
+		now:        time.Now,
+	}
+}
+
+// setLimits applies changed cooldown and circuit breaker settings.
+func (g *guard) setLimits(cfg config.FailoverConfig) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.cooldown = cfg.Cooldown
+	g.maxPerHour = cfg.MaxFailoversPerHour
+}
+
+// limited reports whether failovers with trigger are subject to the
+// cooldown and circuit breaker: those nobody started by hand, so a flapping
+// container or node cannot move containers back and forth.
+func limited(trigger string) bool {
+	switch trigger {
+	case history.TriggerAutomatic, history.TriggerNode, history.TriggerFailback:
+		return true
+	}
+	return false
+}
+
+// acquire marks a failover of the container as in progress. For automatic
+// triggers it also refuses while the container is in cooldown or its
+// circuit breaker is open.
+func (g *guard) acquire(containerID int, trigger string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.inProgress[containerID] {
+		return fmt.Errorf("failover of container %d already in progress", containerID)
+	}
+
+	if limited(trigger) {
+		now := g.now()
+		completed := g.prune(containerID, now)
+
+		if g.cooldown > 0 && len(completed) > 0 {
+			if remaining := g.cooldown - now.Sub(completed[len(completed)-1]); remaining > 0 {
+				return fmt.Errorf("container %d is in failover cooldown for another %s", containerID, remaining.Round(time.Second))
+			}
+		}
+		if g.maxPerHour > 0 && len(completed) >= g.maxPerHour {
+			return fmt.Errorf("circuit breaker open for container %d: %d failovers in the last hour", containerID, len(completed))
+		}
+	}
+
+	g.inProgress[containerID] = true
+	return nil
+}
+
+// release records the end of a failover and reports whether it opened the
+// container's circuit breaker.
+func (g *guard) release(containerID int) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.inProgress, containerID)
+
+	now := g.now()
+	completed := append(g.prune(containerID, now), now)
+	g.completed[containerID] = completed
+
+	return g.maxPerHour > 0 && len(completed) == g.maxPerHour
+}
+
+// prune drops failovers older than the breaker window. Callers must hold mu.
+func (g *guard) prune(containerID int, now time.Time) []time.Time {
+	var kept []time.Time
+	for _, at := range g.completed[containerID] {
+		if now.Sub(at) < breakerWindow {
+			kept = append(kept, at)
+		}
+	}
+	g.completed[containerID] = kept
+	return kept
+}
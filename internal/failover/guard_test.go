@@ -0,0 +1,172 @@
+package failover
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/history"
+)
+
+// testGuard returns a guard whose clock is advanced by the returned function.
+func testGuard(cfg config.FailoverConfig) (*guard, func(time.Duration)) {
+	g := newGuard(cfg)
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	g.now = func() time.Time { return now }
+	return g, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestGuard_InProgress(t *testing.T) {
+	g, _ := testGuard(config.FailoverConfig{})
+
+	if err := g.acquire(100, history.TriggerManual); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+	if err := g.acquire(100, history.TriggerManual); err == nil {
+		t.Error("Expected a second failover of the same container to be refused")
+	}
+	if err := g.acquire(101, history.TriggerManual); err != nil {
+		t.Errorf("Expected a failover of another container to be allowed, got %v", err)
+	}
+	g.release(100)
+	if err := g.acquire(100, history.TriggerManual); err != nil {
+		t.Errorf("Expected a failover after release to be allowed, got %v", err)
+	}
+}
+
+func TestGuard_Cooldown(t *testing.T) {
+	tests := []struct {
+		name    string
+		trigger string
+		after   time.Duration
+		allowed bool
+	}{
+		{"automatic within cooldown", history.TriggerAutomatic, 5 * time.Minute, false},
+		{"node within cooldown", history.TriggerNode, 5 * time.Minute, false},
+		{"failback within cooldown", history.TriggerFailback, 5 * time.Minute, false},
+		{"automatic after cooldown", history.TriggerAutomatic, 10 * time.Minute, true},
+		{"manual within cooldown", history.TriggerManual, time.Minute, true},
+		{"evacuation within cooldown", history.TriggerEvacuation, time.Minute, true},
+		{"drill within cooldown", history.TriggerDrill, time.Minute, true},
+		{"rollback within cooldown", history.TriggerRollback, time.Minute, true},
+		{"resume within cooldown", history.TriggerResume, time.Minute, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, advance := testGuard(config.FailoverConfig{Cooldown: 10 * time.Minute})
+			if err := g.acquire(100, history.TriggerAutomatic); err != nil {
+				t.Fatalf("acquire failed: %v", err)
+			}
+			g.release(100)
+
+			advance(tt.after)
+			err := g.acquire(100, tt.trigger)
+			if tt.allowed && err != nil {
+				t.Errorf("Expected the failover to be allowed, got %v", err)
+			}
+			if !tt.allowed && err == nil {
+				t.Error("Expected the failover to be refused during the cooldown")
+			}
+		})
+	}
+}
+
+func TestGuard_CircuitBreaker(t *testing.T) {
+	g, advance := testGuard(config.FailoverConfig{MaxFailoversPerHour: 3})
+
+	var opened []int
+	for i := 1; i <= 3; i++ {
+		if err := g.acquire(100, history.TriggerNode); err != nil {
+			t.Fatalf("Failover %d refused: %v", i, err)
+		}
+		if g.release(100) {
+			opened = append(opened, i)
+		}
+		advance(10 * time.Minute)
+	}
+	if len(opened) != 1 || opened[0] != 3 {
+		t.Errorf("Expected the breaker to open with the third failover only, got %v", opened)
+	}
+
+	if err := g.acquire(100, history.TriggerAutomatic); err == nil {
+		t.Error("Expected automatic failover to be refused with the breaker open")
+	}
+
+	// Manual failovers bypass the breaker and do not report it opening again
+	if err := g.acquire(100, history.TriggerManual); err != nil {
+		t.Fatalf("Expected a manual failover to bypass the breaker, got %v", err)
+	}
+	if g.release(100) {
+		t.Error("Expected the breaker opening to be reported once")
+	}
+
+	// The breaker closes once failovers fall out of the hour
+	advance(breakerWindow)
+	if err := g.acquire(100, history.TriggerAutomatic); err != nil {
+		t.Errorf("Expected automatic failover once the breaker closed, got %v", err)
+	}
+}
+
+func TestGuard_SetLimits(t *testing.T) {
+	g, advance := testGuard(config.FailoverConfig{Cooldown: time.Hour})
+	if err := g.acquire(100, history.TriggerAutomatic); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+	g.release(100)
+	advance(time.Minute)
+
+	g.setLimits(config.FailoverConfig{Cooldown: 30 * time.Second})
+	if err := g.acquire(100, history.TriggerAutomatic); err != nil {
+		t.Errorf("Expected the shorter cooldown to apply, got %v", err)
+	}
+}
+
+func TestEngine_HandleContainerFailureNotStarted(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(client *fakeAPIClient)
+		fix   func(client *fakeAPIClient)
+	}{
+		{
+			name:  "container info unavailable",
+			setup: func(client *fakeAPIClient) {},
+			fix:   func(client *fakeAPIClient) { client.addContainer(101, "source", "running") },
+		},
+		{
+			name: "no target node online",
+			setup: func(client *fakeAPIClient) {
+				client.addContainer(101, "source", "running")
+				client.nodes[1] = &api.NodeInfo{Name: "a", Status: "offline"}
+			},
+			fix: func(client *fakeAPIClient) { client.nodes[1] = onlineNode("a", 0, 0) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(map[int]int{101: 0})
+			cfg.Failover.AutoFailover = true
+			cfg.Failover.Cooldown = time.Hour
+			cfg.Failover.MaxFailoversPerHour = 1
+			client := newFakeAPIClient(onlineNode("source", 0, 0), onlineNode("a", 0, 0))
+			tt.setup(client)
+			engine := newTestEngine(cfg, client)
+
+			if err := engine.HandleContainerFailure(101); err == nil {
+				t.Fatal("Expected the failover not to start")
+			}
+
+			// Neither the cooldown nor the breaker hold back the next failure
+			tt.fix(client)
+			if err := engine.HandleContainerFailure(101); err != nil {
+				t.Fatalf("Expected the next failure to fail over, got %v", err)
+			}
+			if calls := client.recorded(); !reflect.DeepEqual(calls, []string{"migrate 101 a"}) {
+				t.Errorf("Expected the container to be migrated to a, got %v", calls)
+			}
+		})
+	}
+}
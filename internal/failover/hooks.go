@@ -0,0 +1,90 @@
+package failover
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/hook"
+	"github.com/sirupsen/logrus"
+)
+
+// Phases of a failover in which hooks run.
+const (
+	hookPhasePre  = "pre"
+	hookPhasePost = "post"
+)
+
+// HookResult is the outcome of a single hook run.
+type HookResult struct {
+	Phase    string
+	Command  string
+	Success  bool
+	Error    error
+	Output   string
+	Duration time.Duration
+}
+
+// executeHooks runs hooks in order with the failover described in the
+// environment. A failing hook stops the remaining ones when its failure
+// policy, by default abort before and continue after the failover, is
+// abort; the error of the first failing hook is returned either way.
+func (e *Engine) executeHooks(ctx context.Context, phase string, hooks []config.Hook, plan *Plan, result *FailoverResult) ([]HookResult, error) {
+	onFailure := config.HookContinue
+	if phase == hookPhasePre {
+		onFailure = config.HookAbort
+	}
+	runs, err := hook.RunAll(ctx, hooks, onFailure, hookEnv(phase, plan, result), e.hookLogger(phase, plan))
+
+	results := make([]HookResult, 0, len(runs))
+	for _, run := range runs {
+		results = append(results, hookResult(phase, run))
+	}
+	return results, err
+}
+
+// runHook runs a single hook with the failover, and env, in its
+// environment.
+func (e *Engine) runHook(ctx context.Context, phase string, h config.Hook, plan *Plan, result *FailoverResult, env ...string) HookResult {
+	run := hook.Run(ctx, h, append(hookEnv(phase, plan, result), env...), e.hookLogger(phase, plan))
+	return hookResult(phase, run)
+}
+
+func (e *Engine) hookLogger(phase string, plan *Plan) *logrus.Entry {
+	return e.logger.WithFields(logrus.Fields{
+		"container_id": plan.Container.ID,
+		"phase":        phase,
+	})
+}
+
+// hookEnv describes the failover to hooks.
+func hookEnv(phase string, plan *Plan, result *FailoverResult) []string {
+	return []string{
+		fmt.Sprintf("CONTAINER_ID=%d", plan.Container.ID),
+		fmt.Sprintf("CONTAINER_NAME=%s", plan.Container.Name),
+		fmt.Sprintf("SOURCE_NODE=%s", plan.SourceNode),
+		fmt.Sprintf("TARGET_NODE=%s", plan.TargetNode),
+		fmt.Sprintf("PHASE=%s", phase),
+		fmt.Sprintf("STRATEGY=%s", result.Strategy),
+		fmt.Sprintf("TRIGGER=%s", plan.Trigger),
+	}
+}
+
+// SimulatedHookEnv describes to hooks a failover of plan by strategy that
+// does not happen, as proxwarden hooks test runs them with. HOOK_TEST=true
+// lets hooks tell.
+func SimulatedHookEnv(phase string, plan *Plan, strategy string) []string {
+	return append(hookEnv(phase, plan, &FailoverResult{Strategy: strategy}), "HOOK_TEST=true")
+}
+
+func hookResult(phase string, run hook.Result) HookResult {
+	return HookResult{
+		Phase:    phase,
+		Command:  run.Command,
+		Success:  run.Success,
+		Error:    run.Error,
+		Output:   run.Output,
+		Duration: run.Duration,
+	}
+}
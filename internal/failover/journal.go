@@ -0,0 +1,236 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/history"
+	"github.com/jbutlerdev/proxwarden/internal/journal"
+	"github.com/jbutlerdev/proxwarden/internal/notify"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrNotInterrupted is returned when resuming or discarding a container
+// without an interrupted failover.
+var ErrNotInterrupted = errors.New("no interrupted failover of the container")
+
+// SetJournal sets the journal failovers are recorded in while they run.
+func (e *Engine) SetJournal(store *journal.Store) {
+	e.journal = store
+}
+
+// journalStart records a failover that was registered as in flight.
+func (e *Engine) journalStart(plan *Plan, startTime time.Time) {
+	err := e.journal.Put(journal.Entry{
+		ContainerID:   plan.Container.ID,
+		ContainerName: plan.Container.Name,
+		Trigger:       plan.Trigger,
+		SourceNode:    plan.SourceNode,
+		TargetNode:    plan.TargetNode,
+		StartTime:     startTime,
+		Updated:       startTime,
+	})
+	e.logJournalError(plan.Container.ID, err)
+}
+
+// journalStep records the step a failover started.
+func (e *Engine) journalStep(containerID int, step, strategy string) {
+	e.logJournalError(containerID, e.journal.SetStep(containerID, step, strategy))
+}
+
+// journalFinish forgets a failover that finished, successfully or not.
+func (e *Engine) journalFinish(containerID int) {
+	e.logJournalError(containerID, e.journal.Remove(containerID))
+}
+
+func (e *Engine) logJournalError(containerID int, err error) {
+	if err != nil {
+		e.logger.WithFields(logrus.Fields{
+			"container_id": containerID,
+			"error":        err,
+		}).Error("Failed to update failover journal")
+	}
+}
+
+// RecoverInterrupted looks for failovers the daemon was running when it
+// last stopped, and must run before any new failover starts. Each is
+// recorded in the history as failed and notified. Those that had not
+// changed anything yet are dropped, leaving it to the monitor to detect the
+// failure again. The others are resumed with failover.resume_interrupted,
+// and otherwise kept until an operator resumes or discards them.
+func (e *Engine) RecoverInterrupted() {
+	entries, err := e.journal.Interrupt()
+	if err != nil {
+		e.logger.WithField("error", err).Error("Failed to read failover journal")
+		return
+	}
+
+	for _, entry := range entries {
+		logger := e.logger.WithFields(logrus.Fields{
+			"container_id": entry.ContainerID,
+			"source_node":  entry.SourceNode,
+			"target_node":  entry.TargetNode,
+			"step":         entry.Step,
+		})
+
+		e.recordInterrupted(entry)
+
+		switch {
+		case entry.Step == "":
+			logger.Warn("Failover was interrupted before it started, dropping it")
+			e.journalFinish(entry.ContainerID)
+		case e.cfg().Failover.ResumeInterrupted && entry.Trigger != history.TriggerDrill:
+			logger.Warn("Resuming interrupted failover")
+			if err := e.Resume(entry.ContainerID); err != nil {
+				logger.WithField("error", err).Error("Failed to resume interrupted failover")
+			}
+		default:
+			logger.Warn("Failover was interrupted, resume or discard it")
+		}
+	}
+}
+
+// recordInterrupted records an interrupted failover as failed and notifies
+// it.
+func (e *Engine) recordInterrupted(entry journal.Entry) {
+	step := entry.Step
+	if step == "" {
+		step = "waiting to start"
+	}
+	message := fmt.Sprintf("interrupted by a daemon restart during step %s", step)
+
+	if e.history != nil {
+		err := e.history.Append(history.Record{
+			ContainerID:   entry.ContainerID,
+			ContainerName: entry.ContainerName,
+			Trigger:       entry.Trigger,
+			Strategy:      entry.Strategy,
+			SourceNode:    entry.SourceNode,
+			TargetNode:    entry.TargetNode,
+			Error:         message,
+			StartTime:     entry.StartTime,
+			EndTime:       entry.Updated,
+			Duration:      entry.Updated.Sub(entry.StartTime),
+		})
+		if err != nil {
+			e.logger.WithFields(logrus.Fields{
+				"container_id": entry.ContainerID,
+				"error":        err,
+			}).Error("Failed to record failover history")
+		}
+	}
+
+	e.notifier.Send(notify.Event{
+		Type:          notify.EventFailoverInterrupted,
+		Severity:      notify.SeverityCritical,
+		ContainerID:   entry.ContainerID,
+		ContainerName: entry.ContainerName,
+		Node:          entry.SourceNode,
+		Message:       fmt.Sprintf("Failover of container %d from %s to %s was %s", entry.ContainerID, entry.SourceNode, entry.TargetNode, message),
+		Details: map[string]string{
+			"target_node": entry.TargetNode,
+			"strategy":    entry.Strategy,
+			"step":        step,
+		},
+	})
+}
+
+// Interrupted returns the interrupted failovers awaiting an operator, oldest
+// first.
+func (e *Engine) Interrupted() ([]journal.Entry, error) {
+	entries, err := e.journal.List()
+	if err != nil {
+		return nil, err
+	}
+	var result []journal.Entry
+	for _, entry := range entries {
+		if entry.Interrupted {
+			result = append(result, entry)
+		}
+	}
+	return result, nil
+}
+
+// interrupted returns the interrupted failover of a container.
+func (e *Engine) interrupted(containerID int) (journal.Entry, error) {
+	entry, exists, err := e.journal.Get(containerID)
+	if err != nil {
+		return journal.Entry{}, err
+	}
+	if !exists || !entry.Interrupted {
+		return journal.Entry{}, ErrNotInterrupted
+	}
+	return entry, nil
+}
+
+// awaitsOperator reports whether the container has an interrupted failover,
+// which automatic failovers leave alone until an operator decided on it.
+func (e *Engine) awaitsOperator(containerID int) bool {
+	_, err := e.interrupted(containerID)
+	return err == nil
+}
+
+// Resume runs an interrupted failover again in the background, from its
+// first step, with the source, target and strategy it had. A container
+// already running on the target node is taken as failed over. The result
+// is recorded and notified like that of any failover, with trigger
+// "resume".
+func (e *Engine) Resume(containerID int) error {
+	entry, err := e.interrupted(containerID)
+	if err != nil {
+		return err
+	}
+	containerConfig := e.containerConfig(containerID)
+	if containerConfig == nil {
+		return fmt.Errorf("container %d not found in configuration", containerID)
+	}
+	if err := e.guard.acquire(containerID, history.TriggerResume); err != nil {
+		return err
+	}
+
+	go func() {
+		defer e.release(containerConfig)
+		ctx := context.Background()
+		logger := e.logger.WithFields(logrus.Fields{
+			"container_id": containerID,
+			"source_node":  entry.SourceNode,
+			"target_node":  entry.TargetNode,
+		})
+
+		info, err := e.apiClient.GetContainer(ctx, containerID)
+		if err == nil && info.Node == entry.TargetNode && info.Status == "running" {
+			logger.Info("Container already runs on the target node, interrupted failover completed")
+			e.journalFinish(containerID)
+			return
+		}
+
+		logger.Info("Resuming interrupted failover")
+		result := e.performFailover(ctx, &Plan{
+			Container:  containerConfig,
+			SourceNode: entry.SourceNode,
+			TargetNode: entry.TargetNode,
+			Trigger:    history.TriggerResume,
+		}, entry.Strategy)
+		if result.Success {
+			logger.WithField("duration", result.Duration).Info("Resumed failover completed successfully")
+			return
+		}
+		logger.WithField("error", result.Error).Error("Resumed failover failed")
+	}()
+	return nil
+}
+
+// Discard forgets an interrupted failover, once an operator has cleaned up
+// after it.
+func (e *Engine) Discard(containerID int) error {
+	if _, err := e.interrupted(containerID); err != nil {
+		return err
+	}
+	if err := e.journal.Remove(containerID); err != nil {
+		return err
+	}
+	e.logger.WithField("container_id", containerID).Warn("Interrupted failover discarded")
+	return nil
+}
@@ -0,0 +1,21 @@
+package failover
+
+import "github.com/jbutlerdev/proxwarden/internal/metrics"
+
+var (
+	failoverResults = metrics.NewCounter("proxwarden_failovers_total",
+		"Failovers by trigger and outcome, success or failure.",
+		"trigger", "outcome")
+	failoverDuration = metrics.NewHistogram("proxwarden_failover_duration_seconds",
+		"Duration of failovers by trigger and outcome, including time spent awaiting approval and queued.",
+		[]float64{10, 30, 60, 120, 300, 600, 900, 1800, 3600}, "trigger", "outcome")
+)
+
+func recordMetrics(result *FailoverResult) {
+	outcome := "success"
+	if !result.Success {
+		outcome = "failure"
+	}
+	failoverResults.Inc(result.Trigger, outcome)
+	failoverDuration.Observe(result.Duration.Seconds(), result.Trigger, outcome)
+}
@@ -0,0 +1,302 @@
+package failover
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// placedWeight is how much each container already failed over to a node
+// adds to its load, on the scale of a fully used memory or CPU.
+const placedWeight = 0.1
+
+// How much of a failure domain two nodes share, from nothing to everything.
+const (
+	domainApart = iota
+	domainSameZone
+	domainSameRack
+	domainSameNode
+)
+
+// selectBestNode picks the failover target of a container among its online
+// failover nodes, using the container's placement policy. The container
+// counts as placed on the chosen node from now on, unless the failover does
+// not go ahead or fails.
+func (e *Engine) selectBestNode(ctx context.Context, containerConfig *config.ContainerConfig, currentNode string) (string, error) {
+	if len(containerConfig.FailoverNodes) == 0 {
+		return "", fmt.Errorf("no failover nodes configured for container %d", containerConfig.ID)
+	}
+
+	// Get all nodes status
+	nodes, err := e.apiClient.GetNodes(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get nodes: %w", err)
+	}
+
+	nodeStatus := make(map[string]*api.NodeInfo)
+	for _, node := range nodes {
+		nodeStatus[node.Name] = node
+	}
+
+	// Online failover nodes in configured order
+	var candidates []*api.NodeInfo
+	for _, nodeName := range containerConfig.FailoverNodes {
+		if nodeName == currentNode {
+			continue // Skip current node
+		}
+
+		node, exists := nodeStatus[nodeName]
+		if !exists {
+			e.logger.WithField("node", nodeName).Warn("Configured failover node not found in cluster")
+			continue
+		}
+		if node.Online {
+			candidates = append(candidates, node)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no online failover nodes available for container %d", containerConfig.ID)
+	}
+
+	if e.cfg().Failover.RespectHAGroups {
+		candidates, err = e.haCandidates(ctx, containerConfig, candidates)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	placement := containerConfig.Placement
+	if placement == "" {
+		placement = e.cfg().Failover.Placement
+	}
+
+	peers := e.antiAffinityPeers(ctx, containerConfig)
+
+	e.placementMu.Lock()
+	defer e.placementMu.Unlock()
+
+	candidates = e.furthestNodes(containerConfig, currentNode, candidates, peers)
+
+	var target *api.NodeInfo
+	switch placement {
+	case config.PlacementLeastLoaded:
+		placed := e.placedCounts()
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return load(candidates[i], placed) < load(candidates[j], placed)
+		})
+		target = candidates[0]
+	case config.PlacementRoundRobin:
+		target = candidates[e.roundRobin%len(candidates)]
+		e.roundRobin++
+	case config.PlacementPlugin:
+		target, err = e.pluginTarget(ctx, containerConfig, currentNode, candidates)
+		if err != nil {
+			return "", err
+		}
+	default:
+		target = candidates[0]
+	}
+
+	e.logger.WithFields(logrus.Fields{
+		"container_id": containerConfig.ID,
+		"placement":    placement,
+		"target_node":  target.Name,
+	}).Debug("Selected failover target node")
+
+	e.placed[containerConfig.ID] = target.Name
+	return target.Name, nil
+}
+
+// haCandidates narrows the candidates to the highest-priority members of the
+// container's Proxmox HA group or node affinity rule, the nodes Proxmox HA
+// would pick. Without an online member, unrestricted containers keep all
+// candidates and restricted ones have none.
+func (e *Engine) haCandidates(ctx context.Context, containerConfig *config.ContainerConfig, candidates []*api.NodeInfo) ([]*api.NodeInfo, error) {
+	logger := e.logger.WithField("container_id", containerConfig.ID)
+
+	placement, err := e.apiClient.GetHAPlacement(ctx, containerConfig.ID)
+	if err != nil {
+		logger.WithField("error", err).Warn("Could not look up Proxmox HA placement, ignoring it")
+		return candidates, nil
+	}
+	if placement == nil {
+		return candidates, nil
+	}
+
+	var members []*api.NodeInfo
+	best := 0
+	for _, candidate := range candidates {
+		priority, ok := placement.Nodes[candidate.Name]
+		if !ok {
+			continue
+		}
+		if len(members) == 0 || priority > best {
+			best = priority
+			members = members[:0]
+		}
+		if priority == best {
+			members = append(members, candidate)
+		}
+	}
+
+	logger = logger.WithField("ha_placement", placement.Source)
+	switch {
+	case len(members) > 0:
+		logger.WithField("priority", best).Debug("Limited failover nodes to Proxmox HA members")
+		return members, nil
+	case placement.Restricted:
+		return nil, fmt.Errorf("no online failover node of container %d is a member of its Proxmox HA %s", containerConfig.ID, placement.Source)
+	default:
+		logger.Warn("No online failover node is a Proxmox HA member, using the others")
+		return candidates, nil
+	}
+}
+
+// antiAffinityPeers returns the nodes the container's anti-affinity peers
+// run on, keyed by container ID. Peers are the containers it lists and those
+// listing it.
+func (e *Engine) antiAffinityPeers(ctx context.Context, containerConfig *config.ContainerConfig) map[int]string {
+	ids := append([]int(nil), containerConfig.AntiAffinity...)
+	for _, other := range e.cfg().Monitoring.Containers {
+		for _, peer := range other.AntiAffinity {
+			if peer == containerConfig.ID {
+				ids = append(ids, other.ID)
+			}
+		}
+	}
+
+	peers := make(map[int]string, len(ids))
+	for _, id := range ids {
+		if _, seen := peers[id]; seen {
+			continue
+		}
+		info, err := e.apiClient.GetContainer(ctx, id)
+		if err != nil {
+			e.logger.WithFields(logrus.Fields{
+				"container_id": containerConfig.ID,
+				"peer_id":      id,
+				"error":        err,
+			}).Debug("Could not locate anti-affinity peer")
+			continue
+		}
+		peers[id] = info.Node
+	}
+	return peers
+}
+
+// furthestNodes narrows the candidates to those sharing the least of a
+// failure domain with the failed node and, after that, with the container's
+// anti-affinity peers. Callers must hold placementMu.
+func (e *Engine) furthestNodes(containerConfig *config.ContainerConfig, currentNode string, candidates []*api.NodeInfo, peers map[int]string) []*api.NodeInfo {
+	if len(e.cfg().Failover.FailureDomains) == 0 && len(peers) == 0 {
+		return candidates
+	}
+
+	// Peers already failed over elsewhere, possibly in the same batch,
+	// are on their new nodes
+	for id := range peers {
+		if node, ok := e.placed[id]; ok {
+			peers[id] = node
+		}
+	}
+
+	type score struct{ source, peers int }
+	scores := make([]score, len(candidates))
+	best := score{source: domainSameNode + 1}
+	for i, candidate := range candidates {
+		scores[i].source = e.proximity(currentNode, candidate.Name)
+		for _, node := range peers {
+			// Peers on the failed node are moving as well
+			if node != currentNode {
+				scores[i].peers += e.proximity(node, candidate.Name)
+			}
+		}
+		if scores[i].source < best.source || scores[i].source == best.source && scores[i].peers < best.peers {
+			best = scores[i]
+		}
+	}
+
+	var furthest []*api.NodeInfo
+	for i, candidate := range candidates {
+		if scores[i] == best {
+			furthest = append(furthest, candidate)
+		}
+	}
+
+	if best.source > domainApart || best.peers > domainApart {
+		e.logger.WithFields(logrus.Fields{
+			"container_id": containerConfig.ID,
+			"failed_node":  currentNode,
+		}).Warn("No failover node outside the failure domain of the failed node or anti-affinity peers, using the closest")
+	}
+	return furthest
+}
+
+// proximity reports how much of a failure domain nodes a and b share.
+// Nodes without labels share nothing with other nodes.
+func (e *Engine) proximity(a, b string) int {
+	if a == b {
+		return domainSameNode
+	}
+	domainA := e.cfg().Failover.FailureDomain(a)
+	domainB := e.cfg().Failover.FailureDomain(b)
+	switch {
+	case domainA.Zone != "" && domainB.Zone != "" && domainA.Zone != domainB.Zone:
+		return domainApart
+	case domainA.Rack != "" && domainA.Rack == domainB.Rack:
+		return domainSameRack
+	case domainA.Zone != "" && domainA.Zone == domainB.Zone:
+		return domainSameZone
+	default:
+		return domainApart
+	}
+}
+
+// load scores how busy a node is; lower is better. Memory and CPU usage
+// are fractions, and every container already failed over to the node adds
+// placedWeight.
+func load(node *api.NodeInfo, placed map[string]int) float64 {
+	return node.MemoryUsage() + node.CPU + placedWeight*float64(placed[node.Name])
+}
+
+// placedCounts returns how many containers were failed over to each node.
+// Callers must hold placementMu.
+func (e *Engine) placedCounts() map[string]int {
+	counts := make(map[string]int)
+	for _, node := range e.placed {
+		counts[node]++
+	}
+	return counts
+}
+
+// recordPlacement updates where failed-over containers ended up once a
+// failover finished.
+func (e *Engine) recordPlacement(result *FailoverResult) {
+	e.placementMu.Lock()
+	defer e.placementMu.Unlock()
+
+	switch {
+	case movesBack(result.Trigger):
+		// Back home; a failed failback leaves the container where it was
+		if result.Success {
+			delete(e.placed, result.ContainerID)
+		}
+	case result.Success:
+		e.placed[result.ContainerID] = result.TargetNode
+	default:
+		delete(e.placed, result.ContainerID)
+	}
+}
+
+// forgetPlacement drops the placement of a container whose failover does not
+// go ahead.
+func (e *Engine) forgetPlacement(containerID int) {
+	e.placementMu.Lock()
+	defer e.placementMu.Unlock()
+	delete(e.placed, containerID)
+}
@@ -0,0 +1,185 @@
+package failover
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/history"
+)
+
+func placementConfig(placement string, ids ...int) *config.Config {
+	cfg := &config.Config{}
+	cfg.Failover.Placement = placement
+	for _, id := range ids {
+		cfg.Monitoring.Containers = append(cfg.Monitoring.Containers, config.ContainerConfig{
+			ID:            id,
+			FailoverNodes: []string{"source", "a", "b", "c"},
+		})
+	}
+	return cfg
+}
+
+// selectAll picks the target of each container in turn.
+func selectAll(t *testing.T, engine *Engine, ids ...int) []string {
+	t.Helper()
+	var targets []string
+	for _, id := range ids {
+		target, err := engine.selectBestNode(context.Background(), engine.containerConfig(id), "source")
+		if err != nil {
+			t.Fatalf("Failed to select a node for container %d: %v", id, err)
+		}
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+func TestEngine_SelectBestNode(t *testing.T) {
+	tests := []struct {
+		name      string
+		placement string
+		nodes     []*api.NodeInfo
+		expected  []string
+	}{
+		{
+			name:      "priority takes the first online node",
+			placement: config.PlacementPriority,
+			nodes:     []*api.NodeInfo{onlineNode("source", 0, 0), onlineNode("a", 0.9, 0.9), onlineNode("b", 0, 0)},
+			expected:  []string{"a", "a", "a"},
+		},
+		{
+			name:      "offline nodes are skipped",
+			placement: config.PlacementPriority,
+			nodes:     []*api.NodeInfo{onlineNode("source", 0, 0), {Name: "a", Status: "offline"}, onlineNode("b", 0, 0)},
+			expected:  []string{"b", "b", "b"},
+		},
+		{
+			name:      "least loaded by memory and CPU",
+			placement: config.PlacementLeastLoaded,
+			nodes:     []*api.NodeInfo{onlineNode("source", 0, 0), onlineNode("a", 0.5, 0.3), onlineNode("b", 0.4, 0.1), onlineNode("c", 0.2, 0.45)},
+			expected:  []string{"b", "b", "c"},
+		},
+		{
+			name:      "least loaded spreads placed containers",
+			placement: config.PlacementLeastLoaded,
+			nodes:     []*api.NodeInfo{onlineNode("source", 0, 0), onlineNode("a", 0.2, 0.1), onlineNode("b", 0.25, 0.1), onlineNode("c", 0.5, 0.5)},
+			expected:  []string{"a", "b", "a"},
+		},
+		{
+			name:      "round robin",
+			placement: config.PlacementRoundRobin,
+			nodes:     []*api.NodeInfo{onlineNode("source", 0, 0), onlineNode("a", 0, 0), onlineNode("b", 0, 0), onlineNode("c", 0, 0)},
+			expected:  []string{"a", "b", "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := newTestEngine(placementConfig(tt.placement, 101, 102, 103), newFakeAPIClient(tt.nodes...))
+			if targets := selectAll(t, engine, 101, 102, 103); !reflect.DeepEqual(targets, tt.expected) {
+				t.Errorf("Expected targets %v, got %v", tt.expected, targets)
+			}
+		})
+	}
+}
+
+func TestEngine_SelectBestNodeNoCandidates(t *testing.T) {
+	client := newFakeAPIClient(onlineNode("source", 0, 0), &api.NodeInfo{Name: "a", Status: "offline"})
+	engine := newTestEngine(placementConfig(config.PlacementLeastLoaded, 101), client)
+
+	if _, err := engine.selectBestNode(context.Background(), engine.containerConfig(101), "source"); err == nil {
+		t.Fatal("Expected an error without an online failover node")
+	}
+	if len(engine.placed) != 0 {
+		t.Errorf("Expected no placement without a target, got %v", engine.placed)
+	}
+}
+
+func TestEngine_PlacementRollback(t *testing.T) {
+	tests := []struct {
+		name     string
+		rollback func(e *Engine)
+		// expected is the target of a fourth container once the first
+		// placement is rolled back or kept
+		expected string
+		placed   map[int]string
+	}{
+		{
+			name: "failed failover",
+			rollback: func(e *Engine) {
+				e.recordPlacement(&FailoverResult{ContainerID: 101, TargetNode: "a", Trigger: history.TriggerAutomatic})
+			},
+			expected: "a",
+			placed:   map[int]string{102: "b", 103: "a", 104: "a"},
+		},
+		{
+			name:     "failover that does not go ahead",
+			rollback: func(e *Engine) { e.forgetPlacement(101) },
+			expected: "a",
+			placed:   map[int]string{102: "b", 103: "a", 104: "a"},
+		},
+		{
+			name: "successful failover",
+			rollback: func(e *Engine) {
+				e.recordPlacement(&FailoverResult{ContainerID: 101, TargetNode: "a", Trigger: history.TriggerAutomatic, Success: true})
+			},
+			expected: "b",
+			placed:   map[int]string{101: "a", 102: "b", 103: "a", 104: "b"},
+		},
+		{
+			name: "failed failback keeps the container where it is",
+			rollback: func(e *Engine) {
+				e.recordPlacement(&FailoverResult{ContainerID: 101, TargetNode: "source", Trigger: history.TriggerFailback})
+			},
+			expected: "b",
+			placed:   map[int]string{101: "a", 102: "b", 103: "a", 104: "b"},
+		},
+		{
+			name: "successful failback",
+			rollback: func(e *Engine) {
+				e.recordPlacement(&FailoverResult{ContainerID: 101, TargetNode: "source", Trigger: history.TriggerFailback, Success: true})
+			},
+			expected: "a",
+			placed:   map[int]string{102: "b", 103: "a", 104: "a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeAPIClient(onlineNode("source", 0, 0), onlineNode("a", 0.2, 0.1), onlineNode("b", 0.25, 0.1))
+			engine := newTestEngine(placementConfig(config.PlacementLeastLoaded, 101, 102, 103, 104), client)
+
+			// a: 0.3, then 0.4; b: 0.35, then 0.45
+			selectAll(t, engine, 101, 102, 103)
+			tt.rollback(engine)
+			if targets := selectAll(t, engine, 104); targets[0] != tt.expected {
+				t.Errorf("Expected container 104 on %s, got %s", tt.expected, targets[0])
+			}
+			if !reflect.DeepEqual(engine.placed, tt.placed) {
+				t.Errorf("Expected placements %v, got %v", tt.placed, engine.placed)
+			}
+		})
+	}
+}
+
+func TestEngine_TriggerFailoverForgetsPlacement(t *testing.T) {
+	client := newFakeAPIClient(onlineNode("source", 0, 0), onlineNode("a", 0, 0))
+	client.addContainer(101, "source", "stopped")
+	engine := newTestEngine(placementConfig(config.PlacementLeastLoaded, 101), client)
+
+	// A failover of the container already in progress turns this one away
+	if err := engine.guard.acquire(101, history.TriggerManual); err != nil {
+		t.Fatalf("Failed to acquire the guard: %v", err)
+	}
+	if err := engine.TriggerFailover(101, "", "", false); err == nil {
+		t.Fatal("Expected the failover to be refused while another is in progress")
+	}
+	if len(engine.placed) != 0 {
+		t.Errorf("Expected the placement to be dropped, got %v", engine.placed)
+	}
+	if calls := client.recorded(); len(calls) != 0 {
+		t.Errorf("Expected no changes to the cluster, got %v", calls)
+	}
+}
@@ -0,0 +1,114 @@
+package failover
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/plugin"
+	"github.com/sirupsen/logrus"
+)
+
+// PlacementRequest is written as JSON to the stdin of placement plugins.
+type PlacementRequest struct {
+	Version       int    `json:"version"`
+	Kind          string `json:"kind"`
+	ContainerID   int    `json:"container_id"`
+	ContainerName string `json:"container_name"`
+	SourceNode    string `json:"source_node"`
+	// Candidates are the eligible failover nodes in configured order
+	Candidates []PlacementCandidate `json:"candidates"`
+}
+
+// PlacementCandidate is a node a container can fail over to.
+type PlacementCandidate struct {
+	Node string `json:"node"`
+	// CPU and MemoryUsage are fractions of the node's capacity
+	CPU         float64 `json:"cpu"`
+	MemoryUsage float64 `json:"memory_usage"`
+	MaxCPU      int     `json:"max_cpu"`
+	MaxMemory   uint64  `json:"max_memory"`
+	// Placed counts the containers already failed over to the node
+	Placed int `json:"placed"`
+}
+
+// PlacementResponse is read as JSON from the stdout of placement plugins.
+type PlacementResponse struct {
+	// Scores rank the candidates, highest first; candidates without a
+	// score must not be picked
+	Scores map[string]float64 `json:"scores"`
+}
+
+// FenceRequest is written as JSON to the stdin of fencing plugins, which
+// respond with whether they fenced the node.
+type FenceRequest struct {
+	Version       int    `json:"version"`
+	Kind          string `json:"kind"`
+	Node          string `json:"node"`
+	ContainerID   int    `json:"container_id"`
+	ContainerName string `json:"container_name"`
+}
+
+// pluginTarget picks the candidate the placement plugin scores highest, the
+// first in configured order among equal scores. A plugin that fails leaves
+// the configured order to decide, as the priority placement does. Callers
+// must hold placementMu.
+func (e *Engine) pluginTarget(ctx context.Context, containerConfig *config.ContainerConfig, currentNode string, candidates []*api.NodeInfo) (*api.NodeInfo, error) {
+	placed := e.placedCounts()
+	request := PlacementRequest{
+		Version:       plugin.ProtocolVersion,
+		Kind:          plugin.KindPlacement,
+		ContainerID:   containerConfig.ID,
+		ContainerName: containerConfig.Name,
+		SourceNode:    currentNode,
+	}
+	for _, candidate := range candidates {
+		request.Candidates = append(request.Candidates, PlacementCandidate{
+			Node:        candidate.Name,
+			CPU:         candidate.CPU,
+			MemoryUsage: candidate.MemoryUsage(),
+			MaxCPU:      candidate.MaxCPU,
+			MaxMemory:   candidate.MaxMem,
+			Placed:      placed[candidate.Name],
+		})
+	}
+
+	var response PlacementResponse
+	if err := plugin.Run(ctx, e.cfg().Failover.PlacementPlugin, request, &response); err != nil {
+		e.logger.WithFields(logrus.Fields{
+			"container_id": containerConfig.ID,
+			"error":        err,
+		}).Warn("Placement plugin failed, using the configured order of failover nodes")
+		return candidates[0], nil
+	}
+
+	var target *api.NodeInfo
+	for _, candidate := range candidates {
+		score, ok := response.Scores[candidate.Name]
+		if ok && (target == nil || score > response.Scores[target.Name]) {
+			target = candidate
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("placement plugin scored none of the failover nodes of container %d", containerConfig.ID)
+	}
+	return target, nil
+}
+
+// runFencePlugin runs a fencing plugin, which must report that it fenced
+// node.
+func runFencePlugin(ctx context.Context, p config.Plugin, containerConfig *config.ContainerConfig, node string) error {
+	var result plugin.Result
+	err := plugin.Run(ctx, p, FenceRequest{
+		Version:       plugin.ProtocolVersion,
+		Kind:          plugin.KindFence,
+		Node:          node,
+		ContainerID:   containerConfig.ID,
+		ContainerName: containerConfig.Name,
+	}, &result)
+	if err != nil {
+		return err
+	}
+	return result.Err()
+}
@@ -0,0 +1,61 @@
+package failover
+
+// Steps of a failover reported as its progress. Strategies other than
+// restore report their name as the step.
+const (
+	StepPreHooks   = "pre_hooks"
+	StepSnapshot   = "snapshot"
+	StepBackup     = "backup"
+	StepStopSource = "stop_source"
+	StepRestore    = "restore"
+	StepStart      = "start"
+	StepNetwork    = "network"
+	StepPostHooks  = "post_hooks"
+)
+
+// Progress is how far a failover has come.
+type Progress struct {
+	Step string
+	// Detail is what the step is doing, as far as Proxmox tells
+	Detail string
+	// Percent is how far the step is, or -1 when unknown
+	Percent int
+}
+
+// setProgress records how far the failover of a container has come, and
+// journals each step it starts.
+func (e *Engine) setProgress(containerID int, step, detail string, percent int) {
+	e.inFlightMu.Lock()
+	failover, exists := e.inFlight[containerID]
+	var started bool
+	var strategy string
+	if exists {
+		started = failover.progress.Step != step
+		strategy = failover.strategy
+		failover.progress = Progress{Step: step, Detail: detail, Percent: percent}
+	}
+	e.inFlightMu.Unlock()
+
+	if started {
+		e.journalStep(containerID, step, strategy)
+	}
+}
+
+// setStrategy records the strategy the failover of a container runs with.
+func (e *Engine) setStrategy(containerID int, strategy string) {
+	e.inFlightMu.Lock()
+	defer e.inFlightMu.Unlock()
+	if failover, exists := e.inFlight[containerID]; exists {
+		failover.strategy = strategy
+	}
+}
+
+// progressOf returns how far the failover of a container has come.
+func (e *Engine) progressOf(containerID int) Progress {
+	e.inFlightMu.Lock()
+	defer e.inFlightMu.Unlock()
+	if failover, exists := e.inFlight[containerID]; exists {
+		return failover.progress
+	}
+	return Progress{}
+}
@@ -0,0 +1,104 @@
+package failover
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// queue limits how many failovers run at once. Failovers waiting for a slot
+// are started by priority (lowest value first), then in the order they
+// were queued.
+type queue struct {
+	mu          sync.Mutex
+	parallelism int
+	running     int
+	waiting     []*ticket
+	seq         uint64
+}
+
+// ticket is a place in the queue. ready is closed once the failover may run.
+type ticket struct {
+	priority int
+	seq      uint64
+	ready    chan struct{}
+}
+
+// newQueue returns a queue running up to parallelism failovers at once, or
+// any number when parallelism is not positive.
+func newQueue(parallelism int) *queue {
+	return &queue{parallelism: parallelism}
+}
+
+// enqueue takes a place in the queue, which is granted right away when a
+// slot is free.
+func (q *queue) enqueue(priority int) *ticket {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.seq++
+	t := &ticket{priority: priority, seq: q.seq, ready: make(chan struct{})}
+	if q.parallelism <= 0 || q.running < q.parallelism {
+		q.running++
+		close(t.ready)
+		return t
+	}
+
+	q.waiting = append(q.waiting, t)
+	sort.SliceStable(q.waiting, func(i, j int) bool {
+		if q.waiting[i].priority != q.waiting[j].priority {
+			return q.waiting[i].priority < q.waiting[j].priority
+		}
+		return q.waiting[i].seq < q.waiting[j].seq
+	})
+	return t
+}
+
+// queued reports whether the ticket still waits for a slot.
+func (t *ticket) queued() bool {
+	select {
+	case <-t.ready:
+		return false
+	default:
+		return true
+	}
+}
+
+// wait blocks until the ticket is granted a slot. When ctx ends first the
+// ticket leaves the queue and ctx's error is returned.
+func (q *queue) wait(ctx context.Context, t *ticket) error {
+	select {
+	case <-t.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, waiting := range q.waiting {
+		if waiting == t {
+			q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+			return ctx.Err()
+		}
+	}
+	// Granted meanwhile; hand the slot on
+	q.releaseLocked()
+	return ctx.Err()
+}
+
+// done frees the slot of a finished failover.
+func (q *queue) done() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.releaseLocked()
+}
+
+func (q *queue) releaseLocked() {
+	if len(q.waiting) == 0 {
+		q.running--
+		return
+	}
+	next := q.waiting[0]
+	q.waiting = q.waiting[1:]
+	close(next.ready)
+}
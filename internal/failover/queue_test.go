@@ -0,0 +1,161 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// granted returns the names of the tickets that may run.
+func granted(names []string, tickets []*ticket) []string {
+	var result []string
+	for i, t := range tickets {
+		if !t.queued() {
+			result = append(result, names[i])
+		}
+	}
+	return result
+}
+
+func TestQueue_Order(t *testing.T) {
+	tests := []struct {
+		name        string
+		parallelism int
+		priorities  []int
+		// order is the order in which the queued tickets are granted as
+		// running failovers finish
+		order []int
+	}{
+		{
+			name:        "lowest priority value first",
+			parallelism: 1,
+			priorities:  []int{5, 3, 1, 2},
+			order:       []int{0, 2, 3, 1},
+		},
+		{
+			name:        "same priority in queue order",
+			parallelism: 1,
+			priorities:  []int{1, 2, 2, 2},
+			order:       []int{0, 1, 2, 3},
+		},
+		{
+			name:        "two at once",
+			parallelism: 2,
+			priorities:  []int{9, 9, 4, 1, 4},
+			order:       []int{0, 1, 3, 2, 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newQueue(tt.parallelism)
+			tickets := make([]*ticket, len(tt.priorities))
+			for i, priority := range tt.priorities {
+				tickets[i] = q.enqueue(priority)
+			}
+
+			// Failovers finish in the order they were granted
+			var order []int
+			seen := make(map[int]bool)
+			for finished := 0; finished < len(tickets); finished++ {
+				for i, ticket := range tickets {
+					if !ticket.queued() && !seen[i] {
+						seen[i] = true
+						order = append(order, i)
+					}
+				}
+				if running := len(order) - finished; running > tt.parallelism {
+					t.Fatalf("Expected at most %d running failovers, got %d", tt.parallelism, running)
+				}
+				q.done()
+			}
+			if !reflect.DeepEqual(order, tt.order) {
+				t.Errorf("Expected tickets granted in order %v, got %v", tt.order, order)
+			}
+		})
+	}
+}
+
+func TestQueue_Parallelism(t *testing.T) {
+	tests := []struct {
+		name        string
+		parallelism int
+		running     int
+	}{
+		{"limited", 2, 2},
+		{"single", 1, 1},
+		{"unlimited", 0, 5},
+		{"negative is unlimited", -1, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newQueue(tt.parallelism)
+			names := []string{"a", "b", "c", "d", "e"}
+			tickets := make([]*ticket, len(names))
+			for i := range names {
+				tickets[i] = q.enqueue(0)
+			}
+			if running := granted(names, tickets); len(running) != tt.running {
+				t.Errorf("Expected %d running failovers, got %v", tt.running, running)
+			}
+			if err := q.wait(context.Background(), tickets[0]); err != nil {
+				t.Errorf("Expected the first ticket to be granted, got %v", err)
+			}
+		})
+	}
+}
+
+func TestQueue_WaitCancelled(t *testing.T) {
+	q := newQueue(1)
+	names := []string{"running", "cancelled", "next"}
+	running := q.enqueue(0)
+	cancelled := q.enqueue(0)
+	next := q.enqueue(0)
+	tickets := []*ticket{running, cancelled, next}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := q.wait(ctx, cancelled); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected a cancelled wait to fail with context.Canceled, got %v", err)
+	}
+	if len(q.waiting) != 1 {
+		t.Errorf("Expected the cancelled ticket to leave the queue, %d still waiting", len(q.waiting))
+	}
+
+	// The slot goes to the ticket behind the cancelled one
+	q.done()
+	if got := granted(names, tickets); !reflect.DeepEqual(got, []string{"running", "next"}) {
+		t.Errorf("Expected the next ticket to be granted, got %v", got)
+	}
+}
+
+func TestQueue_WaitCancelledAfterGrant(t *testing.T) {
+	// A ticket granted as its failover is cancelled either runs, and frees
+	// its slot when done, or hands the slot on right away; which one is up
+	// to the scheduler, so both must keep the queue consistent.
+	for i := 0; i < 50; i++ {
+		q := newQueue(1)
+		q.enqueue(0)
+		second := q.enqueue(0)
+		third := q.enqueue(0)
+
+		q.done()
+		if second.queued() {
+			t.Fatal("Expected the second ticket to be granted")
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := q.wait(ctx, second); err == nil {
+			q.done()
+		}
+
+		if third.queued() {
+			t.Fatal("Expected the slot to be handed on to the third ticket")
+		}
+		if q.running != 1 || len(q.waiting) != 0 {
+			t.Fatalf("Expected only the third ticket running, got %d running and %d waiting", q.running, len(q.waiting))
+		}
+	}
+}
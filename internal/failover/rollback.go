@@ -0,0 +1,196 @@
+package failover
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/history"
+	"github.com/sirupsen/logrus"
+)
+
+// movesBack reports whether a failover with the trigger returns a container
+// to the node it failed over from.
+func movesBack(trigger string) bool {
+	return trigger == history.TriggerFailback || trigger == history.TriggerRollback
+}
+
+// Rollback returns a container to the node it was on before its last
+// successful failover, as recorded in the history. The container must still
+// be where that failover left it, and the original node must be online and
+// part of a quorate cluster. An empty strategy migrates where possible and
+// restores a fresh backup otherwise. A standby failover is rolled back by
+// stopping the standby and starting the original again.
+func (e *Engine) Rollback(ctx context.Context, containerID int, strategy string) (*FailoverResult, error) {
+	containerConfig := e.containerConfig(containerID)
+	if containerConfig == nil {
+		return nil, fmt.Errorf("container %d not found in configuration", containerID)
+	}
+
+	record, err := e.lastFailover(containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	containerInfo, err := e.apiClient.GetContainer(ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container info: %w", err)
+	}
+
+	plan := &Plan{
+		Container:    containerConfig,
+		SourceNode:   containerInfo.Node,
+		TargetNode:   record.SourceNode,
+		BackupFirst:  true,
+		Trigger:      history.TriggerRollback,
+		FailedOverAt: record.EndTime,
+	}
+	if record.Strategy == config.StrategyStandby {
+		// The original never left its node, the standby took over instead
+		if containerInfo.Node != record.SourceNode {
+			return nil, fmt.Errorf("container %d is on %s, not on %s where its standby took over from", containerID, containerInfo.Node, record.SourceNode)
+		}
+		plan.SourceNode = record.TargetNode
+		plan.strategy = &standbyRollbackStrategy{e}
+	} else {
+		if strategy == "" {
+			strategy = config.StrategyMigrate
+		}
+		if containerInfo.Node != record.TargetNode {
+			return nil, fmt.Errorf("container %d is on %s, not on %s where it failed over to", containerID, containerInfo.Node, record.TargetNode)
+		}
+	}
+
+	if err := e.checkRollbackNode(ctx, record.SourceNode); err != nil {
+		return nil, fmt.Errorf("original node %s not ready: %w", record.SourceNode, err)
+	}
+
+	if err := e.guard.acquire(containerID, history.TriggerRollback); err != nil {
+		return nil, err
+	}
+	defer e.release(containerConfig)
+
+	e.logger.WithFields(logrus.Fields{
+		"container_id":   containerID,
+		"source_node":    plan.SourceNode,
+		"target_node":    plan.TargetNode,
+		"failed_over_at": record.EndTime,
+	}).Info("Starting rollback")
+
+	result := e.performFailover(ctx, plan, strategy)
+	if !result.Success {
+		e.logger.WithFields(logrus.Fields{
+			"container_id": containerID,
+			"error":        result.Error,
+		}).Error("Rollback failed")
+		return result, result.Error
+	}
+
+	e.logger.WithFields(logrus.Fields{
+		"container_id": containerID,
+		"target_node":  result.TargetNode,
+		"duration":     result.Duration,
+	}).Info("Rollback completed successfully")
+	return result, nil
+}
+
+// lastFailover returns the latest successful failover of a container, unless
+// the container was moved back since.
+func (e *Engine) lastFailover(containerID int) (*history.Record, error) {
+	if e.history == nil {
+		return nil, fmt.Errorf("failover history not available")
+	}
+
+	records, err := e.history.List(history.Filter{ContainerID: containerID})
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		if !record.Success {
+			continue
+		}
+		if movesBack(record.Trigger) {
+			return nil, fmt.Errorf("container %d was already moved back to %s at %s", containerID, record.TargetNode, record.EndTime.Format(time.RFC3339))
+		}
+		return &record, nil
+	}
+	return nil, fmt.Errorf("no successful failover of container %d recorded", containerID)
+}
+
+// checkRollbackNode returns why a container should not be moved back to
+// node, or nil.
+func (e *Engine) checkRollbackNode(ctx context.Context, node string) error {
+	online, err := e.nodeOnline(ctx, node)
+	if err != nil {
+		return err
+	}
+	if !online {
+		return fmt.Errorf("node is offline")
+	}
+
+	status, err := e.apiClient.GetClusterStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get cluster status: %w", err)
+	}
+	if !status.Quorate {
+		return fmt.Errorf("cluster is not quorate")
+	}
+	if len(status.Nodes) > 0 && !status.Nodes[node] {
+		return fmt.Errorf("node is not an online cluster member")
+	}
+	return nil
+}
+
+// standbyRollbackStrategy undoes a standby failover: the standby is stopped
+// and the original container, still on its node, is started again.
+type standbyRollbackStrategy struct{ e *Engine }
+
+func (s *standbyRollbackStrategy) Name() string { return config.StrategyStandby }
+
+func (s *standbyRollbackStrategy) Check(ctx context.Context, plan *Plan) error {
+	if plan.Container.StandbyID == 0 {
+		return fmt.Errorf("no standby_id configured")
+	}
+	return nil
+}
+
+func (s *standbyRollbackStrategy) Execute(ctx context.Context, plan *Plan) error {
+	e := s.e
+	containerID := plan.Container.ID
+	standbyID := plan.Container.StandbyID
+
+	// Both share addresses, so the standby has to stop first
+	e.logger.WithFields(logrus.Fields{
+		"container_id": containerID,
+		"standby_id":   standbyID,
+	}).Info("Stopping standby container")
+	if err := e.retry(ctx, plan.Container, config.StrategyStandby, func() error {
+		if err := e.apiClient.StopContainer(ctx, standbyID); err != nil {
+			return fmt.Errorf("failed to stop standby container: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	err := e.retry(ctx, plan.Container, config.StrategyStandby, func() error {
+		if err := e.apiClient.StartContainer(ctx, containerID); err != nil {
+			return fmt.Errorf("failed to start original container: %w", err)
+		}
+		return nil
+	})
+	if err == nil {
+		return nil
+	}
+
+	// Bring the standby back rather than leaving neither running
+	if startErr := e.apiClient.StartContainer(context.WithoutCancel(ctx), standbyID); startErr != nil {
+		e.logger.WithFields(logrus.Fields{
+			"container_id": containerID,
+			"standby_id":   standbyID,
+			"error":        startErr,
+		}).Error("Failed to restart standby container after failed rollback")
+	}
+	return err
+}
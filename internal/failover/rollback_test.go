@@ -0,0 +1,191 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/history"
+)
+
+// rollbackEngine returns an engine whose history has container 101 failed
+// over from a to b with strategy.
+func rollbackEngine(t *testing.T, client *fakeAPIClient, strategy string) (*Engine, *history.Store) {
+	t.Helper()
+	cfg := testConfig(map[int]int{101: 0})
+	cfg.Monitoring.Containers[0].StandbyID = 201
+
+	store := history.NewStore(filepath.Join(t.TempDir(), history.FileName))
+	end := time.Now().Add(-time.Hour)
+	if err := store.Append(history.Record{
+		ContainerID: 101,
+		Trigger:     history.TriggerAutomatic,
+		Strategy:    strategy,
+		SourceNode:  "a",
+		TargetNode:  "b",
+		Success:     true,
+		StartTime:   end.Add(-time.Minute),
+		EndTime:     end,
+	}); err != nil {
+		t.Fatalf("Failed to record failover: %v", err)
+	}
+
+	engine := newTestEngine(cfg, client)
+	engine.SetHistory(store)
+	return engine, store
+}
+
+// latest returns the newest history record.
+func latest(t *testing.T, store *history.Store) history.Record {
+	t.Helper()
+	records, err := store.List(history.Filter{})
+	if err != nil || len(records) == 0 {
+		t.Fatalf("Failed to list history: %v", err)
+	}
+	return records[0]
+}
+
+func TestEngine_Rollback(t *testing.T) {
+	client := newFakeAPIClient(onlineNode("a", 0, 0), onlineNode("b", 0, 0))
+	client.addContainer(101, "b", "running")
+	engine, store := rollbackEngine(t, client, config.StrategyMigrate)
+
+	result, err := engine.Rollback(context.Background(), 101, "")
+	if err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if !result.Success || result.SourceNode != "b" || result.TargetNode != "a" {
+		t.Errorf("Expected a successful rollback from b to a, got %+v", result)
+	}
+	if result.Strategy != config.StrategyMigrate {
+		t.Errorf("Expected the rollback to migrate, got %s", result.Strategy)
+	}
+	if result.RoundTrip <= time.Hour {
+		t.Errorf("Expected the round trip to count from the failover, got %s", result.RoundTrip)
+	}
+	if calls := client.recorded(); !reflect.DeepEqual(calls, []string{"migrate 101 a"}) {
+		t.Errorf("Expected the container to be migrated back, got %v", calls)
+	}
+
+	record := latest(t, store)
+	if record.Trigger != history.TriggerRollback || !record.Success || record.TargetNode != "a" {
+		t.Errorf("Expected a successful rollback to a recorded, got %+v", record)
+	}
+	if engine.guard.inProgress[101] {
+		t.Error("Expected the guard to be released")
+	}
+
+	// Nothing is left to roll back
+	_, err = engine.Rollback(context.Background(), 101, "")
+	if err == nil || !strings.Contains(err.Error(), "already moved back") {
+		t.Errorf("Expected a second rollback to be refused, got %v", err)
+	}
+}
+
+func TestEngine_RollbackFailed(t *testing.T) {
+	client := newFakeAPIClient(onlineNode("a", 0, 0), onlineNode("b", 0, 0))
+	client.addContainer(101, "b", "running")
+	client.migrateErr = errors.New("migration aborted")
+	engine, store := rollbackEngine(t, client, config.StrategyMigrate)
+
+	result, err := engine.Rollback(context.Background(), 101, "")
+	if err == nil || !errors.Is(err, client.migrateErr) {
+		t.Fatalf("Expected the rollback to fail with the migration error, got %v", err)
+	}
+	if result == nil || result.Success {
+		t.Fatalf("Expected a failed result, got %+v", result)
+	}
+
+	record := latest(t, store)
+	if record.Trigger != history.TriggerRollback || record.Success || record.Error == "" {
+		t.Errorf("Expected a failed rollback recorded, got %+v", record)
+	}
+	if info, _ := client.GetContainer(context.Background(), 101); info.Node != "b" {
+		t.Errorf("Expected the container to stay on b, got %s", info.Node)
+	}
+	if engine.guard.inProgress[101] {
+		t.Error("Expected the guard to be released")
+	}
+	if len(engine.placed) != 0 {
+		t.Errorf("Expected no placement kept, got %v", engine.placed)
+	}
+
+	// A failed rollback can be tried again
+	client.migrateErr = nil
+	if _, err := engine.Rollback(context.Background(), 101, ""); err != nil {
+		t.Errorf("Expected a retried rollback to succeed, got %v", err)
+	}
+}
+
+func TestEngine_RollbackStandbyFailed(t *testing.T) {
+	client := newFakeAPIClient(onlineNode("a", 0, 0), onlineNode("b", 0, 0))
+	client.addContainer(101, "a", "stopped")
+	client.addContainer(201, "b", "running")
+	client.startErr = map[int]error{101: errors.New("start failed")}
+	engine, _ := rollbackEngine(t, client, config.StrategyStandby)
+
+	result, err := engine.Rollback(context.Background(), 101, "")
+	if err == nil || result.Success {
+		t.Fatalf("Expected the rollback to fail, got %v", err)
+	}
+
+	// The standby is brought back rather than leaving neither running
+	expected := []string{"stop 201", "start 101", "start 201"}
+	if calls := client.recorded(); !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Expected calls %v, got %v", expected, calls)
+	}
+	if info, _ := client.GetContainer(context.Background(), 201); info.Status != "running" {
+		t.Errorf("Expected the standby to run again, got %s", info.Status)
+	}
+}
+
+func TestEngine_RollbackRefused(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(client *fakeAPIClient)
+		message string
+	}{
+		{
+			name:    "container moved since",
+			setup:   func(client *fakeAPIClient) { client.addContainer(101, "c", "running") },
+			message: "not on b",
+		},
+		{
+			name: "original node offline",
+			setup: func(client *fakeAPIClient) {
+				client.nodes[0] = &api.NodeInfo{Name: "a", Status: "offline"}
+			},
+			message: "node is offline",
+		},
+		{
+			name: "cluster not quorate",
+			setup: func(client *fakeAPIClient) {
+				client.cluster = &api.ClusterStatus{Nodes: map[string]bool{"a": true, "b": true}}
+			},
+			message: "not quorate",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeAPIClient(onlineNode("a", 0, 0), onlineNode("b", 0, 0))
+			client.addContainer(101, "b", "running")
+			tt.setup(client)
+			engine, _ := rollbackEngine(t, client, config.StrategyMigrate)
+
+			_, err := engine.Rollback(context.Background(), 101, "")
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Expected an error containing %q, got %v", tt.message, err)
+			}
+			if calls := client.recorded(); len(calls) != 0 {
+				t.Errorf("Expected no changes to the cluster, got %v", calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package failover
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// snapshotTimeout bounds a pre-failover snapshot, so a source node that
+// hangs does not hold up the failover.
+const snapshotTimeout = 5 * time.Minute
+
+// snapshotBeforeFailover takes a pre-failover snapshot of the container, so
+// it can be rolled back to how it was before the failover, and deletes its
+// older pre-failover snapshots. Restores replace the container and its
+// snapshots with the backup, so they get none. A snapshot that cannot be
+// taken, as on a node that is down, does not stop the failover.
+func (e *Engine) snapshotBeforeFailover(ctx context.Context, plan *Plan, strategy string) {
+	if !e.cfg().Failover.SnapshotBeforeFailover || strategy == config.StrategyRestore {
+		return
+	}
+	containerID := plan.Container.ID
+	logger := e.logger.WithFields(logrus.Fields{
+		"container_id": containerID,
+		"source_node":  plan.SourceNode,
+	})
+	e.setProgress(containerID, StepSnapshot, "", -1)
+
+	snapshotCtx, cancel := context.WithTimeout(ctx, snapshotTimeout)
+	defer cancel()
+
+	name := api.PreFailoverSnapshotName(time.Now())
+	description := fmt.Sprintf("Taken by ProxWarden before a failover from %s to %s", plan.SourceNode, plan.TargetNode)
+	if err := e.apiClient.CreateSnapshot(snapshotCtx, containerID, name, description); err != nil {
+		logger.WithField("error", err).Warn("Failed to take pre-failover snapshot")
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("no pre-failover snapshot taken: %v", err))
+		return
+	}
+	logger.WithField("snapshot", name).Info("Took pre-failover snapshot")
+
+	// Only the newest is kept, so snapshots do not pile up on storage
+	snapshots, err := e.apiClient.GetSnapshots(snapshotCtx, containerID)
+	if err != nil {
+		logger.WithField("error", err).Warn("Failed to list snapshots to delete older pre-failover snapshots")
+		return
+	}
+	for _, snapshot := range snapshots {
+		if !snapshot.PreFailover() || snapshot.Name == name {
+			continue
+		}
+		if err := e.apiClient.DeleteSnapshot(snapshotCtx, containerID, snapshot.Name); err != nil {
+			logger.WithFields(logrus.Fields{
+				"snapshot": snapshot.Name,
+				"error":    err,
+			}).Warn("Failed to delete older pre-failover snapshot")
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrSourceNotDown is returned when a container could not be confirmed down
+// on its source node, so starting a replacement could leave two instances
+// running.
+var ErrSourceNotDown = errors.New("container not confirmed down on source node")
+
+// sourceDownPollInterval is how often a stopped container is asked for its
+// status until it reports itself stopped.
+const sourceDownPollInterval = 2 * time.Second
+
+// ensureSourceDown stops containerID and makes sure it no longer runs on the
+// plan's source node before a replacement is started. The container is down
+// once it reports itself stopped or its node has been fenced. Otherwise the
+// failover only goes ahead when forced or when unconfirmed sources are
+// allowed, and then carries a split-brain warning into its history.
+func (e *Engine) ensureSourceDown(ctx context.Context, plan *Plan, containerID int) error {
+	logger := e.logger.WithFields(logrus.Fields{
+		"container_id": containerID,
+		"source_node":  plan.SourceNode,
+	})
+
+	logger.Info("Stopping original container")
+	err := e.apiClient.StopContainer(ctx, containerID)
+	if err == nil {
+		err = e.waitStopped(ctx, containerID)
+	}
+	if err == nil {
+		logger.Info("Original container confirmed stopped")
+		return nil
+	}
+	logger.WithField("error", err).Warn("Could not confirm original container stopped")
+
+	// The original may still run on a partitioned node
+	if e.cfg().Failover.Fencing.Enabled {
+		fenceErr := e.fence(ctx, plan.Container, plan.SourceNode)
+		if fenceErr == nil {
+			return nil
+		}
+		err = fmt.Errorf("fencing failed: %w", fenceErr)
+	}
+	if ctx.Err() != nil {
+		return err
+	}
+
+	if !plan.Force && !e.cfg().Failover.AllowUnconfirmedSource {
+		return fmt.Errorf("%w: %v; enable fencing or force the failover", ErrSourceNotDown, err)
+	}
+
+	warning := fmt.Sprintf("possible split brain: container %d not confirmed down on %s: %v", containerID, plan.SourceNode, err)
+	plan.Warnings = append(plan.Warnings, warning)
+	logger.WithField("error", err).Error("Possible split brain: starting replacement although the original container may still be running")
+	return nil
+}
+
+// waitStopped waits for the container to report itself stopped, up to the
+// configured source-down timeout.
+func (e *Engine) waitStopped(ctx context.Context, containerID int) error {
+	timeout := e.cfg().Failover.SourceDownTimeout
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		info, err := e.apiClient.GetContainer(ctx, containerID)
+		if err == nil {
+			if info.Status == "stopped" {
+				return nil
+			}
+			err = fmt.Errorf("container is %s on %s", info.Status, info.Node)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("not stopped within %s: %w", timeout, err)
+		case <-time.After(sourceDownPollInterval):
+		}
+	}
+}
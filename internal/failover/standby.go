@@ -0,0 +1,179 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/notify"
+	"github.com/sirupsen/logrus"
+)
+
+// RunStandbySync keeps the standbys of containers with standby_sync restored
+// from their latest backup, checking for newer backups at the configured
+// interval until ctx is done. Containers and the interval are read again
+// before every sync.
+func (e *Engine) RunStandbySync(ctx context.Context) {
+	interval := e.cfg().Failover.StandbySyncInterval
+	if interval <= 0 {
+		// Validation requires an interval once a container syncs its standby
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// Reloaded containers and interval apply from the next sync
+		cfg := e.cfg()
+		if reloaded := cfg.Failover.StandbySyncInterval; reloaded != interval && reloaded > 0 {
+			interval = reloaded
+			ticker.Reset(interval)
+		}
+
+		for i := range cfg.Monitoring.Containers {
+			containerConfig := cfg.Monitoring.Containers[i]
+			if !containerConfig.StandbySync {
+				continue
+			}
+			if err := e.syncStandby(ctx, &containerConfig); err != nil && ctx.Err() == nil {
+				e.logger.WithFields(logrus.Fields{
+					"container_id": containerConfig.ID,
+					"standby_id":   containerConfig.StandbyID,
+					"error":        err,
+				}).Error("Standby sync failed")
+				e.notifier.Send(notify.Event{
+					Type:          notify.EventStandbySyncFailed,
+					Severity:      notify.SeverityWarning,
+					ContainerID:   containerConfig.ID,
+					ContainerName: containerConfig.Name,
+					Message:       fmt.Sprintf("Standby %d of container %d could not be refreshed: %v", containerConfig.StandbyID, containerConfig.ID, err),
+				})
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncStandby restores the container's latest backup into its standby,
+// unless the standby already holds it. A missing standby is created on the
+// first failover node the container does not run on. A running standby may
+// have taken over from the container and is left alone.
+func (e *Engine) syncStandby(ctx context.Context, containerConfig *config.ContainerConfig) error {
+	containerID := containerConfig.ID
+	standbyID := containerConfig.StandbyID
+	logger := e.logger.WithFields(logrus.Fields{
+		"container_id": containerID,
+		"standby_id":   standbyID,
+	})
+
+	// Failovers starting from now on do not use the standby
+	e.standbyMu.Lock()
+	e.syncing[containerID] = true
+	e.standbyMu.Unlock()
+	defer func() {
+		e.standbyMu.Lock()
+		defer e.standbyMu.Unlock()
+		delete(e.syncing, containerID)
+	}()
+
+	e.inFlightMu.Lock()
+	_, failingOver := e.inFlight[containerID]
+	e.inFlightMu.Unlock()
+	if failingOver {
+		logger.Debug("Container is failing over, skipping standby sync")
+		return nil
+	}
+
+	backup, err := e.findBackup(ctx, containerID)
+	if err != nil {
+		return err
+	}
+	e.standbyMu.Lock()
+	current := e.synced[containerID] == backup
+	e.standbyMu.Unlock()
+	if current {
+		return nil
+	}
+
+	container, err := e.apiClient.GetContainer(ctx, containerID)
+	if err != nil {
+		return fmt.Errorf("failed to get container: %w", err)
+	}
+
+	var node string
+	standby, err := e.apiClient.GetContainer(ctx, standbyID)
+	switch {
+	case err == nil:
+		if standby.Status != "stopped" {
+			return fmt.Errorf("standby container is %s on %s, not overwriting it", standby.Status, standby.Node)
+		}
+		node = standby.Node
+	case errors.Is(err, api.ErrContainerNotFound):
+		for _, candidate := range containerConfig.FailoverNodes {
+			if candidate != container.Node {
+				node = candidate
+				break
+			}
+		}
+		if node == "" {
+			return fmt.Errorf("no failover node other than %s to create the standby on", container.Node)
+		}
+	default:
+		return fmt.Errorf("failed to get standby container: %w", err)
+	}
+	if node == container.Node {
+		return fmt.Errorf("standby container is on %s with the container", node)
+	}
+
+	override := containerConfig.NodeOverride(node)
+	options := api.RestoreOptions{
+		Storage:       override.Storage,
+		Force:         true,
+		Cores:         override.Cores,
+		Memory:        override.Memory,
+		Bridge:        override.Bridge,
+		DisableOnBoot: true,
+	}
+	if options.Storage == "" {
+		options.Storage = containerConfig.Storage
+	}
+	if options.Storage == "" {
+		options.Storage = "local-lvm" // Default storage
+	}
+
+	logger = logger.WithFields(logrus.Fields{
+		"node":        node,
+		"backup_path": backup,
+	})
+	logger.Info("Refreshing standby from latest backup")
+
+	started := time.Now()
+	restoreCtx, cancel := withTimeout(ctx, e.cfg().Failover.RestoreTimeout)
+	defer cancel()
+	if err := e.apiClient.RestoreContainerFromBackup(restoreCtx, standbyID, node, backup, options); err != nil {
+		return fmt.Errorf("failed to restore standby on %s: %w", node, err)
+	}
+	e.backupVerified(backup)
+
+	e.standbyMu.Lock()
+	e.synced[containerID] = backup
+	e.standbyMu.Unlock()
+	logger.WithField("duration", time.Since(started)).Info("Standby refreshed")
+	return nil
+}
+
+// standbySyncing reports whether the container's standby is being refreshed
+// and must not be started.
+func (e *Engine) standbySyncing(containerID int) bool {
+	e.standbyMu.Lock()
+	defer e.standbyMu.Unlock()
+	return e.syncing[containerID]
+}
@@ -0,0 +1,313 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// Strategy moves a container to another node.
+type Strategy interface {
+	Name() string
+	// Check returns why the strategy cannot carry out the plan, or nil
+	Check(ctx context.Context, plan *Plan) error
+	// Execute carries out the plan. It may change plan.TargetNode when the
+	// strategy decides where the container ends up.
+	Execute(ctx context.Context, plan *Plan) error
+}
+
+// Plan describes a single failover for a strategy to carry out.
+type Plan struct {
+	Container   *config.ContainerConfig
+	SourceNode  string
+	TargetNode  string
+	BackupFirst bool
+	// Trigger is what started the failover, one of the history triggers
+	Trigger string
+	// FailedOverAt is when a failback's container left its original node
+	FailedOverAt time.Time
+	// Backup is set by strategies to the backup they restored
+	Backup string
+	// Force starts the replacement even if the container cannot be
+	// confirmed down on the source node
+	Force bool
+	// Warnings are added by strategies for risks they went ahead with
+	Warnings []string
+
+	// ticket is the plan's place in the failover queue, taken up front by
+	// callers queuing several failovers in order
+	ticket *ticket
+	// strategy, if set, carries out the plan instead of a configured one
+	strategy Strategy
+	// exact fails the plan when its strategy is not possible instead of
+	// falling back, for strategies an operator asked for
+	exact bool
+}
+
+// strategyChains lists, per configured strategy, the strategies tried in
+// order until one is possible. Restoring a backup needs nothing but the
+// backup, so every chain ends with it.
+var strategyChains = map[string][]string{
+	config.StrategyRestore: {config.StrategyRestore},
+	config.StrategyMigrate: {config.StrategyMigrate, config.StrategyRestore},
+	config.StrategyReplica: {config.StrategyReplica, config.StrategyRestore},
+	config.StrategyStandby: {config.StrategyStandby, config.StrategyRestore},
+	config.StrategyAuto:    {config.StrategyMigrate, config.StrategyReplica, config.StrategyRestore},
+}
+
+func (e *Engine) registerStrategies() {
+	e.strategies = make(map[string]Strategy)
+	for _, strategy := range []Strategy{
+		&restoreStrategy{e},
+		&migrateStrategy{e},
+		&replicaStrategy{e},
+		&standbyStrategy{e},
+	} {
+		e.strategies[strategy.Name()] = strategy
+	}
+}
+
+// selectStrategy returns the plan's own strategy, if set, or else the first
+// possible strategy of the chain of the requested strategy, falling back to
+// the container's and then the global one. An exact plan only tries the
+// requested strategy, or for auto its whole chain.
+func (e *Engine) selectStrategy(ctx context.Context, plan *Plan, requested string) (Strategy, error) {
+	if plan.strategy != nil {
+		if err := plan.strategy.Check(ctx, plan); err != nil {
+			return nil, fmt.Errorf("%s: %w", plan.strategy.Name(), err)
+		}
+		return plan.strategy, nil
+	}
+
+	name := requested
+	if name == "" {
+		name = plan.Container.Strategy
+	}
+	if name == "" {
+		name = e.cfg().Failover.Strategy
+	}
+	if name == "" {
+		name = config.StrategyRestore
+	}
+
+	chain, exists := strategyChains[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown failover strategy %q", name)
+	}
+	if plan.exact && name != config.StrategyAuto {
+		chain = chain[:1]
+	}
+
+	var errs []error
+	for _, candidate := range chain {
+		strategy := e.strategies[candidate]
+		err := strategy.Check(ctx, plan)
+		if err == nil {
+			return strategy, nil
+		}
+
+		e.logger.WithFields(logrus.Fields{
+			"container_id": plan.Container.ID,
+			"strategy":     candidate,
+			"reason":       err,
+		}).Info("Failover strategy not possible, trying next")
+		errs = append(errs, fmt.Errorf("%s: %w", candidate, err))
+	}
+	return nil, fmt.Errorf("no failover strategy possible: %w", errors.Join(errs...))
+}
+
+// restoreStrategy restores a backup of the container on the target node.
+type restoreStrategy struct{ e *Engine }
+
+func (s *restoreStrategy) Name() string { return config.StrategyRestore }
+
+func (s *restoreStrategy) Check(ctx context.Context, plan *Plan) error {
+	return nil
+}
+
+func (s *restoreStrategy) Execute(ctx context.Context, plan *Plan) error {
+	backup, err := s.e.restore(ctx, plan)
+	plan.Backup = backup
+	return err
+}
+
+// migrateStrategy moves the container with a Proxmox migration, which needs
+// the source node to be online.
+type migrateStrategy struct{ e *Engine }
+
+func (s *migrateStrategy) Name() string { return config.StrategyMigrate }
+
+func (s *migrateStrategy) Check(ctx context.Context, plan *Plan) error {
+	online, err := s.e.nodeOnline(ctx, plan.SourceNode)
+	if err != nil {
+		return err
+	}
+	if !online {
+		return fmt.Errorf("source node %s is offline", plan.SourceNode)
+	}
+	return nil
+}
+
+func (s *migrateStrategy) Execute(ctx context.Context, plan *Plan) error {
+	return s.e.retry(ctx, plan.Container, config.StrategyMigrate, func() error {
+		return s.e.performMigrationFailover(ctx, plan.Container, plan.TargetNode)
+	})
+}
+
+// replicaStrategy promotes the ZFS replica kept by Proxmox storage
+// replication: the container's config is moved to the replication target
+// within the cluster filesystem, the same way Proxmox HA recovers guests,
+// and the container is started there. Changes since the last replication
+// run are lost. Promoting a replica while the source still runs would
+// split the container in two, so the source node must be offline.
+type replicaStrategy struct{ e *Engine }
+
+func (s *replicaStrategy) Name() string { return config.StrategyReplica }
+
+func (s *replicaStrategy) Check(ctx context.Context, plan *Plan) error {
+	online, err := s.e.nodeOnline(ctx, plan.SourceNode)
+	if err != nil {
+		return err
+	}
+	if online {
+		return fmt.Errorf("source node %s is online", plan.SourceNode)
+	}
+
+	if _, err := os.Stat(s.configPath(plan.SourceNode, plan.Container.ID)); err != nil {
+		return fmt.Errorf("container config not accessible: %w", err)
+	}
+
+	_, err = s.target(ctx, plan)
+	return err
+}
+
+func (s *replicaStrategy) Execute(ctx context.Context, plan *Plan) error {
+	target, err := s.target(ctx, plan)
+	if err != nil {
+		return err
+	}
+	plan.TargetNode = target
+
+	e := s.e
+	containerID := plan.Container.ID
+	e.logger.WithFields(logrus.Fields{
+		"container_id": containerID,
+		"source_node":  plan.SourceNode,
+		"target_node":  target,
+	}).Info("Promoting replica of container")
+
+	// Offline to the API does not mean the container stopped
+	if err := e.ensureSourceDown(ctx, plan, containerID); err != nil {
+		return err
+	}
+
+	if err := os.Rename(s.configPath(plan.SourceNode, containerID), s.configPath(target, containerID)); err != nil {
+		return fmt.Errorf("failed to move container config: %w", err)
+	}
+
+	return e.retry(ctx, plan.Container, config.StrategyReplica, func() error {
+		if err := e.apiClient.StartContainer(ctx, containerID); err != nil {
+			return fmt.Errorf("failed to start promoted container: %w", err)
+		}
+		return nil
+	})
+}
+
+// target returns the online replication target for the container,
+// preferring the planned target node.
+func (s *replicaStrategy) target(ctx context.Context, plan *Plan) (string, error) {
+	jobs, err := s.e.apiClient.GetReplicationJobs(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var targets []string
+	for _, job := range jobs {
+		if job.Guest != plan.Container.ID || bool(job.Disable) {
+			continue
+		}
+		if job.Target == plan.TargetNode {
+			targets = append([]string{job.Target}, targets...)
+		} else {
+			targets = append(targets, job.Target)
+		}
+	}
+	if len(targets) == 0 {
+		return "", fmt.Errorf("no replication job for container %d", plan.Container.ID)
+	}
+
+	for _, target := range targets {
+		if online, _ := s.e.nodeOnline(ctx, target); online {
+			return target, nil
+		}
+	}
+	return "", fmt.Errorf("no replication target of container %d is online", plan.Container.ID)
+}
+
+func (s *replicaStrategy) configPath(node string, containerID int) string {
+	return filepath.Join(s.e.cfg().Failover.ClusterConfigDir, "nodes", node, "lxc", fmt.Sprintf("%d.conf", containerID))
+}
+
+// standbyStrategy stops the failed container where possible and starts its
+// pre-provisioned standby copy instead.
+type standbyStrategy struct{ e *Engine }
+
+func (s *standbyStrategy) Name() string { return config.StrategyStandby }
+
+func (s *standbyStrategy) Check(ctx context.Context, plan *Plan) error {
+	if plan.Container.StandbyID == 0 {
+		return fmt.Errorf("no standby_id configured")
+	}
+
+	standby, err := s.e.apiClient.GetContainer(ctx, plan.Container.StandbyID)
+	if err != nil {
+		return fmt.Errorf("standby container %d: %w", plan.Container.StandbyID, err)
+	}
+	if standby.Node == plan.SourceNode {
+		return fmt.Errorf("standby container %d is on the failed node", standby.ID)
+	}
+	if s.e.standbySyncing(plan.Container.ID) {
+		return fmt.Errorf("standby container %d is being refreshed", standby.ID)
+	}
+	return nil
+}
+
+func (s *standbyStrategy) Execute(ctx context.Context, plan *Plan) error {
+	e := s.e
+	standbyID := plan.Container.StandbyID
+
+	standby, err := e.apiClient.GetContainer(ctx, standbyID)
+	if err != nil {
+		return fmt.Errorf("failed to get standby container: %w", err)
+	}
+	plan.TargetNode = standby.Node
+
+	// The standby takes over the container's addresses, so the original
+	// must not come back while it runs
+	if err := e.ensureSourceDown(ctx, plan, plan.Container.ID); err != nil {
+		return err
+	}
+
+	if standby.Status == "running" {
+		return nil
+	}
+
+	e.logger.WithFields(logrus.Fields{
+		"container_id": plan.Container.ID,
+		"standby_id":   standbyID,
+		"target_node":  standby.Node,
+	}).Info("Starting standby container")
+
+	return e.retry(ctx, plan.Container, config.StrategyStandby, func() error {
+		if err := e.apiClient.StartContainer(ctx, standbyID); err != nil {
+			return fmt.Errorf("failed to start standby container: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,300 @@
+package failover
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+// strategyEngine returns an engine keeping the cluster filesystem, with a
+// directory per node of the client, in a temporary directory. Container 101
+// is on source.
+func strategyEngine(t *testing.T, client *fakeAPIClient) (*Engine, *replicaStrategy) {
+	t.Helper()
+	cfg := testConfig(map[int]int{101: 0})
+	cfg.Failover.ClusterConfigDir = t.TempDir()
+	cfg.Failover.SourceDownTimeout = time.Second
+	for _, node := range client.nodes {
+		if err := os.MkdirAll(filepath.Join(cfg.Failover.ClusterConfigDir, "nodes", node.Name, "lxc"), 0o755); err != nil {
+			t.Fatalf("Failed to create node directory: %v", err)
+		}
+	}
+	client.addContainer(101, "source", "running")
+	engine := newTestEngine(cfg, client)
+	return engine, engine.strategies[config.StrategyReplica].(*replicaStrategy)
+}
+
+// writeContainerConfig puts the config of container 101 on node.
+func writeContainerConfig(t *testing.T, s *replicaStrategy, node string) {
+	t.Helper()
+	if err := os.WriteFile(s.configPath(node, 101), []byte("hostname: web\n"), 0o640); err != nil {
+		t.Fatalf("Failed to write container config: %v", err)
+	}
+}
+
+func TestEngine_SelectStrategy(t *testing.T) {
+	tests := []struct {
+		name          string
+		requested     string
+		sourceOnline  bool
+		replicated    bool
+		configOnDisk  bool
+		expected      string
+		expectedError bool
+	}{
+		{"auto migrates off an online node", config.StrategyAuto, true, true, true, config.StrategyMigrate, false},
+		{"auto promotes a replica off an offline node", config.StrategyAuto, false, true, true, config.StrategyReplica, false},
+		{"auto restores without a replication job", config.StrategyAuto, false, false, true, config.StrategyRestore, false},
+		{"auto restores without the container config", config.StrategyAuto, false, true, false, config.StrategyRestore, false},
+		{"migrate", config.StrategyMigrate, true, false, true, config.StrategyMigrate, false},
+		{"migrate falls back to restore", config.StrategyMigrate, false, true, true, config.StrategyRestore, false},
+		{"replica", config.StrategyReplica, false, true, true, config.StrategyReplica, false},
+		{"replica never splits a running source", config.StrategyReplica, true, true, true, config.StrategyRestore, false},
+		{"standby without standby_id falls back to restore", config.StrategyStandby, true, false, true, config.StrategyRestore, false},
+		{"restore", config.StrategyRestore, true, true, true, config.StrategyRestore, false},
+		{"configured strategy when none requested", "", true, false, true, config.StrategyMigrate, false},
+		{"unknown", "teleport", true, true, true, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := onlineNode("source", 0, 0)
+			if !tt.sourceOnline {
+				source = &api.NodeInfo{Name: "source", Status: "offline"}
+			}
+			client := newFakeAPIClient(source, onlineNode("a", 0, 0))
+			if tt.replicated {
+				client.replication = []api.ReplicationJob{{ID: "101-0", Guest: 101, Target: "a"}}
+			}
+			engine, replica := strategyEngine(t, client)
+			if tt.configOnDisk {
+				writeContainerConfig(t, replica, "source")
+			}
+
+			plan := &Plan{Container: engine.containerConfig(101), SourceNode: "source", TargetNode: "a"}
+			strategy, err := engine.selectStrategy(context.Background(), plan, tt.requested)
+			if tt.expectedError {
+				if err == nil {
+					t.Errorf("Expected an error, got strategy %s", strategy.Name())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to select a strategy: %v", err)
+			}
+			if strategy.Name() != tt.expected {
+				t.Errorf("Expected strategy %s, got %s", tt.expected, strategy.Name())
+			}
+		})
+	}
+}
+
+func TestEngine_SelectStrategyExact(t *testing.T) {
+	tests := []struct {
+		name          string
+		requested     string
+		sourceOnline  bool
+		expected      string
+		expectedError bool
+	}{
+		{"migrate off an online node", config.StrategyMigrate, true, config.StrategyMigrate, false},
+		{"migrate off an offline node fails", config.StrategyMigrate, false, "", true},
+		{"replica of a running source fails", config.StrategyReplica, true, "", true},
+		{"auto still falls back to restore", config.StrategyAuto, false, config.StrategyRestore, false},
+		{"unknown", "teleport", true, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := onlineNode("source", 0, 0)
+			if !tt.sourceOnline {
+				source = &api.NodeInfo{Name: "source", Status: "offline"}
+			}
+			engine, _ := strategyEngine(t, newFakeAPIClient(source, onlineNode("a", 0, 0)))
+
+			plan := &Plan{Container: engine.containerConfig(101), SourceNode: "source", TargetNode: "a", exact: true}
+			strategy, err := engine.selectStrategy(context.Background(), plan, tt.requested)
+			if tt.expectedError {
+				if err == nil {
+					t.Errorf("Expected an error, got strategy %s", strategy.Name())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to select a strategy: %v", err)
+			}
+			if strategy.Name() != tt.expected {
+				t.Errorf("Expected strategy %s, got %s", tt.expected, strategy.Name())
+			}
+		})
+	}
+}
+
+func TestEngine_TriggerFailoverMigrate(t *testing.T) {
+	tests := []struct {
+		name         string
+		sourceOnline bool
+		strategy     string
+		expectError  bool
+		calls        []string
+	}{
+		{"migrates off an online node", true, config.StrategyMigrate, false, []string{"migrate 101 a"}},
+		{"refuses an offline node", false, config.StrategyMigrate, true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := onlineNode("source", 0, 0)
+			if !tt.sourceOnline {
+				source = &api.NodeInfo{Name: "source", Status: "offline"}
+			}
+			client := newFakeAPIClient(source, onlineNode("a", 0, 0))
+			engine, _ := strategyEngine(t, client)
+
+			err := engine.TriggerFailover(101, "a", tt.strategy, true)
+			if tt.expectError && err == nil {
+				t.Error("Expected the failover to fail")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected the failover to succeed, got %v", err)
+			}
+			if calls := client.recorded(); !reflect.DeepEqual(calls, tt.calls) {
+				t.Errorf("Expected calls %v, got %v", tt.calls, calls)
+			}
+		})
+	}
+}
+
+func TestStrategyChains(t *testing.T) {
+	for name, chain := range strategyChains {
+		if !config.ValidStrategy(name) {
+			t.Errorf("Chain of unknown strategy %s", name)
+		}
+		if chain[len(chain)-1] != config.StrategyRestore {
+			t.Errorf("Expected the chain of %s to end with restore, got %v", name, chain)
+		}
+	}
+	expected := []string{config.StrategyMigrate, config.StrategyReplica, config.StrategyRestore}
+	if !reflect.DeepEqual(strategyChains[config.StrategyAuto], expected) {
+		t.Errorf("Expected auto to try %v, got %v", expected, strategyChains[config.StrategyAuto])
+	}
+}
+
+func TestReplicaStrategy_Execute(t *testing.T) {
+	tests := []struct {
+		name     string
+		planned  string
+		jobs     []api.ReplicationJob
+		expected string
+	}{
+		{
+			name:    "planned target",
+			planned: "b",
+			jobs: []api.ReplicationJob{
+				{ID: "101-0", Guest: 101, Target: "a"},
+				{ID: "101-1", Guest: 101, Target: "b"},
+			},
+			expected: "b",
+		},
+		{
+			name:    "first online target otherwise",
+			planned: "c",
+			jobs: []api.ReplicationJob{
+				{ID: "101-0", Guest: 101, Target: "offline"},
+				{ID: "101-1", Guest: 101, Target: "b"},
+				{ID: "101-2", Guest: 101, Target: "a"},
+			},
+			expected: "b",
+		},
+		{
+			name:    "disabled jobs and other guests are skipped",
+			planned: "a",
+			jobs: []api.ReplicationJob{
+				{ID: "101-0", Guest: 101, Target: "a", Disable: true},
+				{ID: "102-0", Guest: 102, Target: "a"},
+				{ID: "101-1", Guest: 101, Target: "b"},
+			},
+			expected: "b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeAPIClient(
+				&api.NodeInfo{Name: "source", Status: "offline"},
+				&api.NodeInfo{Name: "offline", Status: "offline"},
+				onlineNode("a", 0, 0), onlineNode("b", 0, 0), onlineNode("c", 0, 0),
+			)
+			client.replication = tt.jobs
+			engine, replica := strategyEngine(t, client)
+			writeContainerConfig(t, replica, "source")
+
+			plan := &Plan{Container: engine.containerConfig(101), SourceNode: "source", TargetNode: tt.planned}
+			if err := replica.Check(context.Background(), plan); err != nil {
+				t.Fatalf("Expected the replica to be promotable: %v", err)
+			}
+			if err := replica.Execute(context.Background(), plan); err != nil {
+				t.Fatalf("Failed to promote replica: %v", err)
+			}
+
+			if plan.TargetNode != tt.expected {
+				t.Errorf("Expected the replica on %s promoted, got %s", tt.expected, plan.TargetNode)
+			}
+			if _, err := os.Stat(replica.configPath("source", 101)); !os.IsNotExist(err) {
+				t.Errorf("Expected the config to leave the source node, got %v", err)
+			}
+			data, err := os.ReadFile(replica.configPath(tt.expected, 101))
+			if err != nil || string(data) != "hostname: web\n" {
+				t.Errorf("Expected the config moved to %s, got %q (%v)", tt.expected, data, err)
+			}
+			if calls := client.recorded(); !reflect.DeepEqual(calls, []string{"stop 101", "start 101"}) {
+				t.Errorf("Expected the source stopped and the replica started, got %v", calls)
+			}
+		})
+	}
+}
+
+func TestReplicaStrategy_Check(t *testing.T) {
+	tests := []struct {
+		name   string
+		online []string
+		jobs   []api.ReplicationJob
+		config bool
+	}{
+		{"source online", []string{"source", "a"}, []api.ReplicationJob{{Guest: 101, Target: "a"}}, true},
+		{"config not accessible", []string{"a"}, []api.ReplicationJob{{Guest: 101, Target: "a"}}, false},
+		{"no replication job", []string{"a"}, nil, true},
+		{"replication target offline", nil, []api.ReplicationJob{{Guest: 101, Target: "a"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeAPIClient()
+			for _, name := range []string{"source", "a"} {
+				node := &api.NodeInfo{Name: name, Status: "offline"}
+				for _, online := range tt.online {
+					if online == name {
+						node = onlineNode(name, 0, 0)
+					}
+				}
+				client.nodes = append(client.nodes, node)
+			}
+			client.replication = tt.jobs
+			engine, replica := strategyEngine(t, client)
+			if tt.config {
+				writeContainerConfig(t, replica, "source")
+			}
+
+			plan := &Plan{Container: engine.containerConfig(101), SourceNode: "source", TargetNode: "a"}
+			if err := replica.Check(context.Background(), plan); err == nil {
+				t.Error("Expected the replica not to be promotable")
+			}
+		})
+	}
+}
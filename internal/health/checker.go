@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/httpproxy"
 	"github.com/sirupsen/logrus"
 )
 
@@ -18,10 +20,25 @@ type CheckResult struct {
 	Error     error
 	Duration  time.Duration
 	Timestamp time.Time
+	Attempts  int
+	// Degraded is set when the check succeeded but exceeded its max_latency
+	Degraded bool
+	Severity string
+	// Skipped is set when the check did not run because a dependency is down
+	Skipped bool
 }
 
 type Checker struct {
-	logger *logrus.Logger
+	logger  *logrus.Logger
+	proxy   string
+	metrics MetricsSource
+}
+
+// Container identifies the container a check belongs to. Metric checks use
+// it to read resource usage from Proxmox instead of probing a network target.
+type Container struct {
+	ID   int
+	Node string
 }
 
 func NewChecker(logger *logrus.Logger) *Checker {
@@ -30,35 +47,73 @@ func NewChecker(logger *logrus.Logger) *Checker {
 	}
 }
 
+// SetProxy sets the proxy used by HTTP(S) checks that do not configure their
+// own. See httpproxy.ProxyFunc for accepted values.
+func (c *Checker) SetProxy(proxy string) {
+	c.proxy = proxy
+}
+
+// SetMetricsSource sets where metric checks such as "disk" or "memory" read
+// container resource usage from.
+func (c *Checker) SetMetricsSource(source MetricsSource) {
+	c.metrics = source
+}
+
+// RunHealthCheck runs a check that does not need to know its container.
 func (c *Checker) RunHealthCheck(ctx context.Context, check config.HealthCheck) *CheckResult {
-	start := time.Now()
+	return c.RunContainerCheck(ctx, Container{}, check)
+}
+
+// RunContainerCheck runs a health check of the given container.
+func (c *Checker) RunContainerCheck(ctx context.Context, container Container, check config.HealthCheck) *CheckResult {
 	result := &CheckResult{
 		Type:      check.Type,
 		Target:    check.Target,
-		Timestamp: start,
+		Timestamp: time.Now(),
+		Severity:  check.Severity,
+	}
+	if result.Severity == "" {
+		result.Severity = config.SeverityCritical
 	}
 
-	checkCtx, cancel := context.WithTimeout(ctx, check.Timeout)
-	defer cancel()
-
-	switch check.Type {
-	case "ping", "icmp":
-		result.Success, result.Error = c.pingCheck(checkCtx, check.Target)
-	case "tcp":
-		result.Success, result.Error = c.tcpCheck(checkCtx, check.Target, check.Port)
-	case "http", "https":
-		result.Success, result.Error = c.httpCheck(checkCtx, check.Type, check.Target, check.Port, check.Path)
-	default:
-		result.Error = fmt.Errorf("unknown health check type: %s", check.Type)
+	// Retries happen immediately so a single lost packet does not cost the
+	// container one of its failure-threshold slots.
+	var start time.Time
+	for attempt := 1; attempt <= check.Retries+1; attempt++ {
+		start = time.Now()
+		result.Attempts = attempt
+		result.Success, result.Error = c.runCheck(ctx, container, check)
+		result.Degraded = false
+
+		if elapsed := time.Since(start); result.Success && check.MaxLatency > 0 && elapsed > check.MaxLatency {
+			result.Success = false
+			result.Degraded = true
+			result.Error = fmt.Errorf("latency %s exceeds max_latency %s", elapsed.Round(time.Millisecond), check.MaxLatency)
+		}
+
+		if result.Success || ctx.Err() != nil {
+			break
+		}
+
+		if attempt <= check.Retries {
+			c.logger.WithFields(logrus.Fields{
+				"type":    check.Type,
+				"target":  check.Target,
+				"attempt": attempt,
+				"error":   result.Error,
+			}).Debug("Health check attempt failed, retrying")
+		}
 	}
 
 	result.Duration = time.Since(start)
+	recordCheck(result)
 	
 	if result.Error != nil {
 		c.logger.WithFields(logrus.Fields{
 			"type":     check.Type,
 			"target":   check.Target,
 			"duration": result.Duration,
+			"attempts": result.Attempts,
 			"error":    result.Error,
 		}).Debug("Health check failed")
 	} else {
@@ -73,9 +128,40 @@ func (c *Checker) RunHealthCheck(ctx context.Context, check config.HealthCheck)
 	return result
 }
 
-func (c *Checker) pingCheck(ctx context.Context, target string) (bool, error) {
+// runCheck performs a single attempt of a health check bounded by its timeout.
+func (c *Checker) runCheck(ctx context.Context, container Container, check config.HealthCheck) (bool, error) {
+	if check.AddressFamily == config.AddressFamilyDual {
+		return c.dualStackCheck(ctx, container, check)
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, check.Timeout)
+	defer cancel()
+
+	switch check.Type {
+	case "ping", "icmp":
+		return c.pingCheck(checkCtx, check.Target, check.AddressFamily)
+	case "tcp":
+		return c.tcpCheck(checkCtx, check.Target, check.Port, check.AddressFamily)
+	case "http", "https":
+		return c.httpCheck(checkCtx, check.Type, check.Target, check.Port, check.Path, check.Proxy, check.AddressFamily)
+	case "postgres", "postgresql":
+		return c.postgresCheck(checkCtx, check)
+	case "mysql", "mariadb":
+		return c.mysqlCheck(checkCtx, check)
+	case "redis":
+		return c.redisCheck(checkCtx, check)
+	case "plugin":
+		return c.pluginCheck(checkCtx, check)
+	case "disk", "memory", "swap", "cpu":
+		return c.usageCheck(checkCtx, container, check)
+	default:
+		return false, fmt.Errorf("unknown health check type: %s", check.Type)
+	}
+}
+
+func (c *Checker) pingCheck(ctx context.Context, target, family string) (bool, error) {
 	dialer := &net.Dialer{}
-	conn, err := dialer.DialContext(ctx, "ip4:icmp", target)
+	conn, err := dialer.DialContext(ctx, icmpNetwork(target, family), target)
 	if err != nil {
 		return false, fmt.Errorf("ping failed: %w", err)
 	}
@@ -83,10 +169,10 @@ func (c *Checker) pingCheck(ctx context.Context, target string) (bool, error) {
 	return true, nil
 }
 
-func (c *Checker) tcpCheck(ctx context.Context, target string, port int) (bool, error) {
+func (c *Checker) tcpCheck(ctx context.Context, target string, port int, family string) (bool, error) {
 	dialer := &net.Dialer{}
-	address := fmt.Sprintf("%s:%d", target, port)
-	conn, err := dialer.DialContext(ctx, "tcp", address)
+	address := net.JoinHostPort(target, strconv.Itoa(port))
+	conn, err := dialer.DialContext(ctx, tcpNetwork(family), address)
 	if err != nil {
 		return false, fmt.Errorf("tcp check failed: %w", err)
 	}
@@ -94,24 +180,53 @@ func (c *Checker) tcpCheck(ctx context.Context, target string, port int) (bool,
 	return true, nil
 }
 
-func (c *Checker) httpCheck(ctx context.Context, scheme, target string, port int, path string) (bool, error) {
+// dialDatabase opens a TCP connection for the protocol-level checks, falling
+// back to the engine's default port, and bounds all I/O by the context deadline.
+func dialDatabase(ctx context.Context, check config.HealthCheck, defaultPort int) (net.Conn, error) {
+	port := check.Port
+	if port == 0 {
+		port = defaultPort
+	}
+
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, tcpNetwork(check.AddressFamily), net.JoinHostPort(check.Target, strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	return conn, nil
+}
+
+func (c *Checker) httpCheck(ctx context.Context, scheme, target string, port int, path, proxy, family string) (bool, error) {
 	if path == "" {
 		path = "/"
 	}
 	
-	var url string
-	if port > 0 {
-		url = fmt.Sprintf("%s://%s:%d%s", scheme, target, port, path)
-	} else {
-		url = fmt.Sprintf("%s://%s%s", scheme, target, path)
-	}
+	url := fmt.Sprintf("%s://%s%s", scheme, urlHost(target, port), path)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return false, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	client := &http.Client{}
+	if proxy == "" {
+		proxy = c.proxy
+	}
+	transport, err := httpproxy.Transport(proxy)
+	if err != nil {
+		return false, err
+	}
+	// Each check gets its own transport, so don't leave idle connections behind
+	transport.DisableKeepAlives = true
+	dialer := &net.Dialer{}
+	transport.DialContext = func(ctx context.Context, _, address string) (net.Conn, error) {
+		return dialer.DialContext(ctx, tcpNetwork(family), address)
+	}
+
+	client := &http.Client{Transport: transport}
 	resp, err := client.Do(req)
 	if err != nil {
 		return false, fmt.Errorf("http check failed: %w", err)
@@ -132,7 +132,7 @@ func TestChecker_HTTPCheck(t *testing.T) {
 			// Extract host and port from server URL
 			host := "127.0.0.1"
 			port := extractPortFromURL(server.URL)
-			success, err := checker.httpCheck(ctx, "http", host, port, tt.path)
+			success, err := checker.httpCheck(ctx, "http", host, port, tt.path, "", "")
 
 			if success != tt.expected {
 				t.Errorf("Expected %v, got %v, error: %v", tt.expected, success, err)
@@ -141,6 +141,139 @@ func TestChecker_HTTPCheck(t *testing.T) {
 	}
 }
 
+func TestChecker_HTTPProxy(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	// A forward proxy receives the absolute target URL
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+
+	tests := []struct {
+		name         string
+		defaultProxy string
+		checkProxy   string
+	}{
+		{"default proxy", proxy.URL, ""},
+		{"per-check proxy", "direct", proxy.URL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxied = ""
+			checker := NewChecker(logger)
+			checker.SetProxy(tt.defaultProxy)
+
+			success, err := checker.httpCheck(context.Background(), "http", "app.invalid", 8080, "/health", tt.checkProxy, "")
+			if !success {
+				t.Fatalf("Expected success through proxy, got error: %v", err)
+			}
+			if proxied != "http://app.invalid:8080/health" {
+				t.Errorf("Expected proxied request for target URL, got %q", proxied)
+			}
+		})
+	}
+}
+
+func TestChecker_Retries(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	checker := NewChecker(logger)
+
+	tests := []struct {
+		name             string
+		failFirst        int
+		retries          int
+		expectSuccess    bool
+		expectedAttempts int
+	}{
+		{"no retries needed", 0, 2, true, 1},
+		{"recovers on retry", 1, 2, true, 2},
+		{"retries exhausted", 3, 2, false, 3},
+		{"retries disabled", 1, 0, false, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if requests <= tt.failFirst {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			result := checker.RunHealthCheck(context.Background(), config.HealthCheck{
+				Type:    "http",
+				Target:  "127.0.0.1",
+				Port:    extractPortFromURL(server.URL),
+				Timeout: 2 * time.Second,
+				Retries: tt.retries,
+			})
+
+			if result.Success != tt.expectSuccess {
+				t.Errorf("Expected success=%v, got %v, error: %v", tt.expectSuccess, result.Success, result.Error)
+			}
+			if result.Attempts != tt.expectedAttempts {
+				t.Errorf("Expected %d attempts, got %d", tt.expectedAttempts, result.Attempts)
+			}
+		})
+	}
+}
+
+func TestChecker_MaxLatency(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	checker := NewChecker(logger)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(50 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name           string
+		path           string
+		maxLatency     time.Duration
+		expectSuccess  bool
+		expectDegraded bool
+	}{
+		{"no budget", "/slow", 0, true, false},
+		{"within budget", "/fast", time.Second, true, false},
+		{"over budget", "/slow", 10 * time.Millisecond, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := checker.RunHealthCheck(context.Background(), config.HealthCheck{
+				Type:       "http",
+				Target:     "127.0.0.1",
+				Port:       extractPortFromURL(server.URL),
+				Path:       tt.path,
+				Timeout:    2 * time.Second,
+				MaxLatency: tt.maxLatency,
+			})
+
+			if result.Success != tt.expectSuccess {
+				t.Errorf("Expected success=%v, got %v, error: %v", tt.expectSuccess, result.Success, result.Error)
+			}
+			if result.Degraded != tt.expectDegraded {
+				t.Errorf("Expected degraded=%v, got %v", tt.expectDegraded, result.Degraded)
+			}
+		})
+	}
+}
+
 func TestChecker_ContextTimeout(t *testing.T) {
 	logger := logrus.New()
 	logger.SetLevel(logrus.FatalLevel)
@@ -0,0 +1,24 @@
+package health
+
+import "github.com/jbutlerdev/proxwarden/internal/metrics"
+
+var (
+	checkRuns = metrics.NewCounter("proxwarden_health_checks_total",
+		"Health checks run by type and result: success, failure, or degraded when slower than max_latency.",
+		"type", "result")
+	checkDuration = metrics.NewHistogram("proxwarden_health_check_duration_seconds",
+		"Duration of the last attempt of health checks by type.",
+		metrics.DurationBuckets, "type")
+)
+
+func recordCheck(result *CheckResult) {
+	outcome := "success"
+	switch {
+	case result.Degraded:
+		outcome = "degraded"
+	case !result.Success:
+		outcome = "failure"
+	}
+	checkRuns.Inc(result.Type, outcome)
+	checkDuration.Observe(result.Duration.Seconds(), result.Type)
+}
@@ -0,0 +1,292 @@
+package health
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// startFakeServer accepts a single connection and hands it to handler.
+func startFakeServer(t *testing.T, handler func(conn net.Conn)) int {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handler(conn)
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestChecker_RedisCheck(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	checker := NewChecker(logger)
+
+	tests := []struct {
+		name     string
+		password string
+		replies  map[string]string
+		expected bool
+	}{
+		{"pong", "", map[string]string{"PING": "+PONG"}, true},
+		{"loading", "", map[string]string{"PING": "-LOADING Redis is loading the dataset in memory"}, false},
+		{"auth then pong", "secret", map[string]string{"AUTH": "+OK", "PING": "+PONG"}, true},
+		{"auth rejected", "wrong", map[string]string{"AUTH": "-WRONGPASS invalid password"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port := startFakeServer(t, func(conn net.Conn) {
+				reader := bufio.NewReader(conn)
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					count, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+
+					var args []string
+					for i := 0; i < count; i++ {
+						reader.ReadString('\n') // $<len>
+						arg, _ := reader.ReadString('\n')
+						args = append(args, strings.TrimSpace(arg))
+					}
+					if reply, ok := tt.replies[args[0]]; ok {
+						conn.Write([]byte(reply + "\r\n"))
+					}
+				}
+			})
+
+			result := checker.RunHealthCheck(context.Background(), config.HealthCheck{
+				Type:     "redis",
+				Target:   "127.0.0.1",
+				Port:     port,
+				Password: tt.password,
+				Timeout:  2 * time.Second,
+			})
+
+			if result.Success != tt.expected {
+				t.Errorf("Expected %v, got %v, error: %v", tt.expected, result.Success, result.Error)
+			}
+		})
+	}
+}
+
+func TestChecker_PostgresCheck(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	checker := NewChecker(logger)
+
+	pgMessage := func(msgType byte, body []byte) []byte {
+		msg := []byte{msgType, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(msg[1:], uint32(4+len(body)))
+		return append(msg, body...)
+	}
+	authMessage := func(code uint32, extra []byte) []byte {
+		body := make([]byte, 4)
+		binary.BigEndian.PutUint32(body, code)
+		return pgMessage('R', append(body, extra...))
+	}
+	readStartup := func(conn net.Conn) {
+		header := make([]byte, 4)
+		io.ReadFull(conn, header)
+		io.ReadFull(conn, make([]byte, binary.BigEndian.Uint32(header)-4))
+	}
+	readMessage := func(conn net.Conn) byte {
+		header := make([]byte, 5)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return 0
+		}
+		io.ReadFull(conn, make([]byte, binary.BigEndian.Uint32(header[1:])-4))
+		return header[0]
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		server   func(conn net.Conn)
+		expected bool
+	}{
+		{
+			name: "auth request without credentials",
+			server: func(conn net.Conn) {
+				readStartup(conn)
+				conn.Write(authMessage(pgAuthMD5, []byte{1, 2, 3, 4}))
+			},
+			expected: true,
+		},
+		{
+			name: "startup error",
+			server: func(conn net.Conn) {
+				readStartup(conn)
+				conn.Write(pgMessage('E', []byte("SFATAL\x00C57P03\x00Mthe database system is starting up\x00\x00")))
+			},
+			expected: false,
+		},
+		{
+			name:     "md5 login and query",
+			password: "secret",
+			server: func(conn net.Conn) {
+				readStartup(conn)
+				conn.Write(authMessage(pgAuthMD5, []byte{1, 2, 3, 4}))
+				if readMessage(conn) != 'p' {
+					return
+				}
+				conn.Write(authMessage(pgAuthOK, nil))
+				conn.Write(pgMessage('Z', []byte{'I'}))
+				if readMessage(conn) != 'Q' {
+					return
+				}
+				conn.Write(pgMessage('C', []byte("SELECT 1\x00")))
+				conn.Write(pgMessage('Z', []byte{'I'}))
+				readMessage(conn)
+			},
+			expected: true,
+		},
+		{
+			name:     "query error",
+			password: "secret",
+			server: func(conn net.Conn) {
+				readStartup(conn)
+				conn.Write(authMessage(pgAuthOK, nil))
+				conn.Write(pgMessage('Z', []byte{'I'}))
+				readMessage(conn)
+				conn.Write(pgMessage('E', []byte("SERROR\x00Mcanceling statement due to lock timeout\x00\x00")))
+				conn.Write(pgMessage('Z', []byte{'I'}))
+			},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port := startFakeServer(t, tt.server)
+
+			result := checker.RunHealthCheck(context.Background(), config.HealthCheck{
+				Type:     "postgres",
+				Target:   "127.0.0.1",
+				Port:     port,
+				Username: "monitor",
+				Password: tt.password,
+				Timeout:  2 * time.Second,
+			})
+
+			if result.Success != tt.expected {
+				t.Errorf("Expected %v, got %v, error: %v", tt.expected, result.Success, result.Error)
+			}
+		})
+	}
+}
+
+func TestChecker_MySQLCheck(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	checker := NewChecker(logger)
+
+	packet := func(seq byte, payload []byte) []byte {
+		return append([]byte{byte(len(payload)), byte(len(payload) >> 8), byte(len(payload) >> 16), seq}, payload...)
+	}
+
+	greeting := []byte{10}
+	greeting = append(greeting, "8.0.36\x00"...)
+	greeting = append(greeting, 1, 0, 0, 0)            // connection id
+	greeting = append(greeting, "abcdefgh"...)         // salt part 1
+	greeting = append(greeting, 0)                     // filler
+	greeting = append(greeting, 0xff, 0xff, 33, 2, 0)  // caps low, charset, status
+	greeting = append(greeting, 0xff, 0xff, 21)        // caps high, salt length
+	greeting = append(greeting, make([]byte, 10)...)   // reserved
+	greeting = append(greeting, "ijklmnopqrst\x00"...) // salt part 2
+	greeting = append(greeting, "mysql_native_password\x00"...)
+
+	readPacket := func(conn net.Conn) {
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		io.ReadFull(conn, make([]byte, int(header[0])|int(header[1])<<8|int(header[2])<<16))
+	}
+
+	tests := []struct {
+		name     string
+		username string
+		server   func(conn net.Conn)
+		expected bool
+	}{
+		{
+			name: "greeting without credentials",
+			server: func(conn net.Conn) {
+				conn.Write(packet(0, greeting))
+			},
+			expected: true,
+		},
+		{
+			name: "too many connections",
+			server: func(conn net.Conn) {
+				conn.Write(packet(0, append([]byte{0xff, 0x10, 0x04}, "Too many connections"...)))
+			},
+			expected: false,
+		},
+		{
+			name:     "login and ping",
+			username: "monitor",
+			server: func(conn net.Conn) {
+				conn.Write(packet(0, greeting))
+				readPacket(conn)
+				conn.Write(packet(2, []byte{0x00, 0, 0, 2, 0, 0, 0}))
+				readPacket(conn)
+				conn.Write(packet(1, []byte{0x00, 0, 0, 2, 0, 0, 0}))
+				readPacket(conn)
+			},
+			expected: true,
+		},
+		{
+			name:     "access denied",
+			username: "monitor",
+			server: func(conn net.Conn) {
+				conn.Write(packet(0, greeting))
+				readPacket(conn)
+				conn.Write(packet(2, append([]byte{0xff, 0x15, 0x04}, "#28000Access denied"...)))
+			},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port := startFakeServer(t, tt.server)
+
+			result := checker.RunHealthCheck(context.Background(), config.HealthCheck{
+				Type:     "mysql",
+				Target:   "127.0.0.1",
+				Port:     port,
+				Username: tt.username,
+				Password: "secret",
+				Timeout:  2 * time.Second,
+			})
+
+			if result.Success != tt.expected {
+				t.Errorf("Expected %v, got %v, error: %v", tt.expected, result.Success, result.Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package health
+
+import (
+	"sort"
+	"time"
+)
+
+// DefaultHistorySize is the number of results kept per check when no size is configured.
+const DefaultHistorySize = 100
+
+// History is a bounded ring buffer of results for a single health check.
+// It is not safe for concurrent use; callers provide their own locking.
+type History struct {
+	results []*CheckResult
+	next    int
+	full    bool
+
+	lastFailure       time.Time
+	lastFailureReason string
+}
+
+// HistoryStats summarises the results currently held in a History.
+type HistoryStats struct {
+	Type              string
+	Target            string
+	Samples           int
+	SuccessRate       float64
+	P95Latency        time.Duration
+	LastResult        *CheckResult
+	LastFailure       time.Time
+	LastFailureReason string
+}
+
+func NewHistory(size int) *History {
+	if size <= 0 {
+		size = DefaultHistorySize
+	}
+	return &History{
+		results: make([]*CheckResult, size),
+	}
+}
+
+func (h *History) Add(result *CheckResult) {
+	h.results[h.next] = result
+	h.next = (h.next + 1) % len(h.results)
+	if h.next == 0 {
+		h.full = true
+	}
+
+	// The last failure is tracked separately so it survives being rotated out
+	if !result.Success {
+		h.lastFailure = result.Timestamp
+		if result.Error != nil {
+			h.lastFailureReason = result.Error.Error()
+		}
+	}
+}
+
+// Results returns the buffered results from oldest to newest.
+func (h *History) Results() []*CheckResult {
+	if !h.full {
+		return append([]*CheckResult(nil), h.results[:h.next]...)
+	}
+	return append(append([]*CheckResult(nil), h.results[h.next:]...), h.results[:h.next]...)
+}
+
+func (h *History) Stats() HistoryStats {
+	results := h.Results()
+	stats := HistoryStats{
+		Samples:           len(results),
+		LastFailure:       h.lastFailure,
+		LastFailureReason: h.lastFailureReason,
+	}
+	if len(results) == 0 {
+		return stats
+	}
+
+	last := results[len(results)-1]
+	stats.Type = last.Type
+	stats.Target = last.Target
+	stats.LastResult = last
+
+	successes := 0
+	latencies := make([]time.Duration, 0, len(results))
+	for _, result := range results {
+		if result.Success {
+			successes++
+		}
+		latencies = append(latencies, result.Duration)
+	}
+	stats.SuccessRate = float64(successes) / float64(len(results))
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	// Nearest-rank percentile
+	rank := (95*len(latencies) + 99) / 100
+	stats.P95Latency = latencies[rank-1]
+
+	return stats
+}
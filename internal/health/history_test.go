@@ -0,0 +1,59 @@
+package health
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHistory_Stats(t *testing.T) {
+	history := NewHistory(4)
+
+	stats := history.Stats()
+	if stats.Samples != 0 {
+		t.Errorf("Expected 0 samples, got %d", stats.Samples)
+	}
+
+	base := time.Now()
+	results := []*CheckResult{
+		{Type: "tcp", Target: "10.0.0.1", Success: false, Error: errors.New("connection refused"), Duration: 5 * time.Millisecond, Timestamp: base},
+		{Type: "tcp", Target: "10.0.0.1", Success: true, Duration: 10 * time.Millisecond, Timestamp: base.Add(time.Second)},
+		{Type: "tcp", Target: "10.0.0.1", Success: true, Duration: 20 * time.Millisecond, Timestamp: base.Add(2 * time.Second)},
+		{Type: "tcp", Target: "10.0.0.1", Success: true, Duration: 40 * time.Millisecond, Timestamp: base.Add(3 * time.Second)},
+		{Type: "tcp", Target: "10.0.0.1", Success: true, Duration: 30 * time.Millisecond, Timestamp: base.Add(4 * time.Second)},
+	}
+	for _, result := range results {
+		history.Add(result)
+	}
+
+	stats = history.Stats()
+
+	// The oldest (failed) result has been rotated out of the buffer
+	if stats.Samples != 4 {
+		t.Errorf("Expected 4 samples, got %d", stats.Samples)
+	}
+	if stats.SuccessRate != 1.0 {
+		t.Errorf("Expected success rate 1.0, got %f", stats.SuccessRate)
+	}
+	if stats.P95Latency != 40*time.Millisecond {
+		t.Errorf("Expected p95 40ms, got %v", stats.P95Latency)
+	}
+	if stats.LastResult != results[4] {
+		t.Error("Expected last result to be the newest result")
+	}
+
+	// The last failure survives rotation
+	if !stats.LastFailure.Equal(base) {
+		t.Errorf("Expected last failure at %v, got %v", base, stats.LastFailure)
+	}
+	if stats.LastFailureReason != "connection refused" {
+		t.Errorf("Expected last failure reason 'connection refused', got '%s'", stats.LastFailureReason)
+	}
+
+	ordered := history.Results()
+	for i := 1; i < len(ordered); i++ {
+		if ordered[i].Timestamp.Before(ordered[i-1].Timestamp) {
+			t.Fatal("Expected results ordered oldest to newest")
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+// DefaultUsageThreshold is the usage percentage at which metric checks fail
+// when the check does not set usage_threshold.
+const DefaultUsageThreshold = 90.0
+
+// rrdResolution is the spacing of the RRD samples used by sustained checks.
+const rrdResolution = time.Minute
+
+// MetricsSource provides container resource usage for metric checks.
+type MetricsSource interface {
+	GetContainerMetrics(ctx context.Context, node string, containerID int) (*api.ContainerMetrics, error)
+	GetContainerRRD(ctx context.Context, node string, containerID int) ([]api.RRDPoint, error)
+}
+
+func (c *Checker) metricsSource(container Container) (MetricsSource, error) {
+	if c.metrics == nil {
+		return nil, fmt.Errorf("no metrics source configured")
+	}
+	if container.ID == 0 || container.Node == "" {
+		return nil, fmt.Errorf("metric checks require a container")
+	}
+	return c.metrics, nil
+}
+
+// usageCheck fails when the container's usage of the check's resource
+// (disk, memory, swap or cpu) reaches the check's usage threshold.
+func (c *Checker) usageCheck(ctx context.Context, container Container, check config.HealthCheck) (bool, error) {
+	source, err := c.metricsSource(container)
+	if err != nil {
+		return false, fmt.Errorf("%s check failed: %w", check.Type, err)
+	}
+
+	threshold := check.UsageThreshold
+	if threshold == 0 {
+		threshold = DefaultUsageThreshold
+	}
+
+	if check.Sustain > 0 {
+		return sustainedUsageCheck(ctx, source, container, check, threshold)
+	}
+
+	metrics, err := source.GetContainerMetrics(ctx, container.Node, container.ID)
+	if err != nil {
+		return false, fmt.Errorf("%s check failed: %w", check.Type, err)
+	}
+	usage, err := currentUsage(check.Type, metrics)
+	if err != nil {
+		return false, fmt.Errorf("%s check failed: %w", check.Type, err)
+	}
+
+	if usage >= threshold {
+		return false, fmt.Errorf("%s usage %.1f%% exceeds threshold %.1f%%", check.Type, usage, threshold)
+	}
+	return true, nil
+}
+
+// sustainedUsageCheck fails when every RRD sample of the last check.Sustain
+// reached the threshold. Until samples cover the whole period the pressure
+// is not considered sustained and the check passes.
+func sustainedUsageCheck(ctx context.Context, source MetricsSource, container Container, check config.HealthCheck, threshold float64) (bool, error) {
+	points, err := source.GetContainerRRD(ctx, container.Node, container.ID)
+	if err != nil {
+		return false, fmt.Errorf("%s check failed: %w", check.Type, err)
+	}
+
+	start := time.Now().Add(-check.Sustain)
+	var oldest time.Time
+	lowest := math.Inf(1)
+	for _, point := range points {
+		if point.Timestamp().Before(start) {
+			continue
+		}
+		usage, ok := sampleUsage(check.Type, point)
+		if !ok {
+			continue
+		}
+		if usage < threshold {
+			return true, nil
+		}
+		if oldest.IsZero() {
+			oldest = point.Timestamp()
+		}
+		lowest = math.Min(lowest, usage)
+	}
+
+	if oldest.IsZero() || oldest.After(start.Add(rrdResolution)) {
+		return true, nil
+	}
+	return false, fmt.Errorf("%s usage stayed at or above %.1f%% for %s, threshold %.1f%%", check.Type, lowest, check.Sustain, threshold)
+}
+
+func currentUsage(checkType string, metrics *api.ContainerMetrics) (float64, error) {
+	switch checkType {
+	case "disk":
+		if metrics.MaxDisk == 0 {
+			return 0, fmt.Errorf("container reports no disk size")
+		}
+		return metrics.DiskUsagePercent(), nil
+	case "memory":
+		if metrics.MaxMem == 0 {
+			return 0, fmt.Errorf("container reports no memory limit")
+		}
+		return metrics.MemoryUsagePercent(), nil
+	case "swap":
+		// A container without swap cannot exhaust it
+		return metrics.SwapUsagePercent(), nil
+	case "cpu":
+		return metrics.CPUUsagePercent(), nil
+	default:
+		return 0, fmt.Errorf("not a metric check: %s", checkType)
+	}
+}
+
+// sampleUsage returns the usage percentage of an RRD sample and whether the
+// sample holds data for the check's resource.
+func sampleUsage(checkType string, point api.RRDPoint) (float64, bool) {
+	switch checkType {
+	case "disk":
+		if point.MaxDisk == 0 {
+			return 0, false
+		}
+		return point.Disk / point.MaxDisk * 100, true
+	case "memory":
+		if point.MaxMem == 0 {
+			return 0, false
+		}
+		return point.Mem / point.MaxMem * 100, true
+	case "cpu":
+		if point.MaxCPU == 0 {
+			return 0, false
+		}
+		return point.CPU * 100, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,156 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeMetricsSource struct {
+	metrics *api.ContainerMetrics
+	rrd     []api.RRDPoint
+	err     error
+}
+
+func (f *fakeMetricsSource) GetContainerMetrics(ctx context.Context, node string, containerID int) (*api.ContainerMetrics, error) {
+	return f.metrics, f.err
+}
+
+func (f *fakeMetricsSource) GetContainerRRD(ctx context.Context, node string, containerID int) ([]api.RRDPoint, error) {
+	return f.rrd, f.err
+}
+
+func TestChecker_DiskCheck(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	const gb = 1 << 30
+
+	tests := []struct {
+		name      string
+		source    MetricsSource
+		container Container
+		threshold float64
+		expected  bool
+	}{
+		{"below default threshold", &fakeMetricsSource{metrics: &api.ContainerMetrics{Disk: 5 * gb, MaxDisk: 10 * gb}}, Container{ID: 100, Node: "node1"}, 0, true},
+		{"above default threshold", &fakeMetricsSource{metrics: &api.ContainerMetrics{Disk: 95 * gb, MaxDisk: 100 * gb}}, Container{ID: 100, Node: "node1"}, 0, false},
+		{"above custom threshold", &fakeMetricsSource{metrics: &api.ContainerMetrics{Disk: 8 * gb, MaxDisk: 10 * gb}}, Container{ID: 100, Node: "node1"}, 75, false},
+		{"unknown disk size", &fakeMetricsSource{metrics: &api.ContainerMetrics{Disk: gb}}, Container{ID: 100, Node: "node1"}, 0, false},
+		{"api error", &fakeMetricsSource{err: errors.New("connection refused")}, Container{ID: 100, Node: "node1"}, 0, false},
+		{"no metrics source", nil, Container{ID: 100, Node: "node1"}, 0, false},
+		{"no container", &fakeMetricsSource{metrics: &api.ContainerMetrics{Disk: gb, MaxDisk: 10 * gb}}, Container{}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewChecker(logger)
+			if tt.source != nil {
+				checker.SetMetricsSource(tt.source)
+			}
+
+			result := checker.RunContainerCheck(context.Background(), tt.container, config.HealthCheck{
+				Type:           "disk",
+				Timeout:        time.Second,
+				UsageThreshold: tt.threshold,
+			})
+			if result.Success != tt.expected {
+				t.Errorf("Expected success=%v, got %v, error=%v", tt.expected, result.Success, result.Error)
+			}
+		})
+	}
+}
+
+func TestChecker_ResourceChecks(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	const mb = 1 << 20
+
+	tests := []struct {
+		name      string
+		checkType string
+		metrics   *api.ContainerMetrics
+		threshold float64
+		expected  bool
+	}{
+		{"memory below threshold", "memory", &api.ContainerMetrics{Mem: 512 * mb, MaxMem: 1024 * mb}, 0, true},
+		{"memory exhausted", "memory", &api.ContainerMetrics{Mem: 1000 * mb, MaxMem: 1024 * mb}, 0, false},
+		{"memory without limit", "memory", &api.ContainerMetrics{Mem: 512 * mb}, 0, false},
+		{"swap below threshold", "swap", &api.ContainerMetrics{Swap: 10 * mb, MaxSwap: 512 * mb}, 0, true},
+		{"swap exhausted", "swap", &api.ContainerMetrics{Swap: 500 * mb, MaxSwap: 512 * mb}, 0, false},
+		{"no swap configured", "swap", &api.ContainerMetrics{}, 0, true},
+		{"cpu below threshold", "cpu", &api.ContainerMetrics{CPU: 0.5, MaxCPU: 2}, 0, true},
+		{"cpu above custom threshold", "cpu", &api.ContainerMetrics{CPU: 0.85, MaxCPU: 2}, 80, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewChecker(logger)
+			checker.SetMetricsSource(&fakeMetricsSource{metrics: tt.metrics})
+
+			result := checker.RunContainerCheck(context.Background(), Container{ID: 100, Node: "node1"}, config.HealthCheck{
+				Type:           tt.checkType,
+				Timeout:        time.Second,
+				UsageThreshold: tt.threshold,
+			})
+			if result.Success != tt.expected {
+				t.Errorf("Expected success=%v, got %v, error=%v", tt.expected, result.Success, result.Error)
+			}
+		})
+	}
+}
+
+func TestChecker_SustainedCheck(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	// samples returns one memory sample per minute for the last n minutes,
+	// oldest first
+	samples := func(usage ...float64) []api.RRDPoint {
+		now := time.Now().Truncate(time.Minute)
+		points := make([]api.RRDPoint, len(usage))
+		for i, u := range usage {
+			points[i] = api.RRDPoint{
+				Time:   now.Add(-time.Duration(len(usage)-1-i) * time.Minute).Unix(),
+				Mem:    u,
+				MaxMem: 100,
+			}
+		}
+		return points
+	}
+
+	tests := []struct {
+		name     string
+		rrd      []api.RRDPoint
+		expected bool
+	}{
+		{"sustained pressure", samples(50, 95, 95, 96, 97, 98, 99), false},
+		{"pressure eased within period", samples(95, 95, 95, 80, 95, 95, 95), true},
+		{"pressure shorter than period", samples(50, 50, 50, 50, 95, 95), true},
+		{"not enough history", samples(95, 95), true},
+		{"no samples", nil, true},
+		{"samples without data are ignored", append(samples(95, 95, 95, 95, 95, 95), api.RRDPoint{Time: time.Now().Unix()}), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewChecker(logger)
+			checker.SetMetricsSource(&fakeMetricsSource{rrd: tt.rrd})
+
+			result := checker.RunContainerCheck(context.Background(), Container{ID: 100, Node: "node1"}, config.HealthCheck{
+				Type:    "memory",
+				Timeout: time.Second,
+				Sustain: 5 * time.Minute,
+			})
+			if result.Success != tt.expected {
+				t.Errorf("Expected success=%v, got %v, error=%v", tt.expected, result.Success, result.Error)
+			}
+		})
+	}
+}
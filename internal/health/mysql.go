@@ -0,0 +1,291 @@
+package health
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+const defaultMySQLPort = 3306
+
+// Capability flags used in the handshake response
+const (
+	mysqlClientLongPassword     = 0x00000001
+	mysqlClientConnectWithDB    = 0x00000008
+	mysqlClientProtocol41       = 0x00000200
+	mysqlClientSecureConnection = 0x00008000
+	mysqlClientPluginAuth       = 0x00080000
+)
+
+// Command bytes
+const (
+	mysqlComQuit  = 0x01
+	mysqlComQuery = 0x03
+	mysqlComPing  = 0x0e
+)
+
+// mysqlCheck reads the server greeting. Without credentials a valid greeting
+// is considered healthy; with credentials the check logs in and issues either
+// the configured query or COM_PING.
+func (c *Checker) mysqlCheck(ctx context.Context, check config.HealthCheck) (bool, error) {
+	conn, err := dialDatabase(ctx, check, defaultMySQLPort)
+	if err != nil {
+		return false, fmt.Errorf("mysql check failed: %w", err)
+	}
+	defer conn.Close()
+
+	my := &mysqlConn{w: conn, r: bufio.NewReader(conn)}
+
+	greeting, err := my.readPacket()
+	if err != nil {
+		return false, fmt.Errorf("mysql handshake failed: %w", err)
+	}
+	if len(greeting) > 0 && greeting[0] == 0xff {
+		return false, fmt.Errorf("mysql error: %s", mysqlErrorMessage(greeting))
+	}
+
+	salt, plugin, err := parseMySQLGreeting(greeting)
+	if err != nil {
+		return false, fmt.Errorf("mysql handshake failed: %w", err)
+	}
+
+	if check.Username == "" {
+		return true, nil
+	}
+
+	if err := my.writePacket(mysqlHandshakeResponse(check, salt, plugin)); err != nil {
+		return false, fmt.Errorf("mysql handshake failed: %w", err)
+	}
+
+	if err := my.finishAuth(check.Password, salt, plugin); err != nil {
+		return false, fmt.Errorf("mysql authentication failed: %w", err)
+	}
+
+	command := []byte{mysqlComPing}
+	if check.Query != "" {
+		command = append([]byte{mysqlComQuery}, check.Query...)
+	}
+
+	my.seq = 0
+	if err := my.writePacket(command); err != nil {
+		return false, fmt.Errorf("mysql command failed: %w", err)
+	}
+
+	reply, err := my.readPacket()
+	if err != nil {
+		return false, fmt.Errorf("mysql command failed: %w", err)
+	}
+	if len(reply) > 0 && reply[0] == 0xff {
+		return false, fmt.Errorf("mysql error: %s", mysqlErrorMessage(reply))
+	}
+
+	my.seq = 0
+	my.writePacket([]byte{mysqlComQuit})
+	return true, nil
+}
+
+type mysqlConn struct {
+	w   io.Writer
+	r   *bufio.Reader
+	seq byte
+}
+
+func (m *mysqlConn) readPacket() ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(m.r, header); err != nil {
+		return nil, err
+	}
+
+	length := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	m.seq = header[3] + 1
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(m.r, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+func (m *mysqlConn) writePacket(payload []byte) error {
+	header := []byte{byte(len(payload)), byte(len(payload) >> 8), byte(len(payload) >> 16), m.seq}
+	m.seq++
+
+	_, err := m.w.Write(append(header, payload...))
+	return err
+}
+
+// finishAuth handles the server's reply to the handshake response, including
+// auth switch requests and the caching_sha2_password fast-auth path.
+func (m *mysqlConn) finishAuth(password string, salt []byte, plugin string) error {
+	for {
+		packet, err := m.readPacket()
+		if err != nil {
+			return err
+		}
+		if len(packet) == 0 {
+			return fmt.Errorf("empty packet")
+		}
+
+		switch packet[0] {
+		case 0x00:
+			return nil
+		case 0xff:
+			return fmt.Errorf("%s", mysqlErrorMessage(packet))
+		case 0xfe:
+			// Auth switch request: plugin name, NUL, new salt
+			rest := packet[1:]
+			idx := bytes.IndexByte(rest, 0)
+			if idx < 0 {
+				return fmt.Errorf("malformed auth switch request")
+			}
+			plugin = string(rest[:idx])
+			salt = bytes.TrimRight(rest[idx+1:], "\x00")
+
+			auth, err := mysqlScramble(plugin, password, salt)
+			if err != nil {
+				return err
+			}
+			if err := m.writePacket(auth); err != nil {
+				return err
+			}
+		case 0x01:
+			// caching_sha2_password: 0x03 fast auth succeeded, 0x04 full auth required
+			if len(packet) > 1 && packet[1] == 0x04 {
+				return fmt.Errorf("caching_sha2_password full authentication requires TLS")
+			}
+		default:
+			return fmt.Errorf("unexpected packet 0x%02x", packet[0])
+		}
+	}
+}
+
+// parseMySQLGreeting extracts the auth salt and plugin from a v10 handshake.
+func parseMySQLGreeting(packet []byte) ([]byte, string, error) {
+	if len(packet) < 1 || packet[0] != 10 {
+		return nil, "", fmt.Errorf("unsupported protocol version")
+	}
+
+	pos := 1
+	end := bytes.IndexByte(packet[pos:], 0)
+	if end < 0 {
+		return nil, "", fmt.Errorf("malformed server version")
+	}
+	pos += end + 1
+
+	// connection id (4), salt part 1 (8), filler (1)
+	if len(packet) < pos+13 {
+		return nil, "", fmt.Errorf("greeting too short")
+	}
+	salt := append([]byte{}, packet[pos+4:pos+12]...)
+	pos += 13
+
+	// capabilities low (2), charset (1), status (2), capabilities high (2), salt length (1), reserved (10)
+	if len(packet) < pos+18 {
+		return salt, "mysql_native_password", nil
+	}
+	saltLen := int(packet[pos+7])
+	pos += 18
+
+	part2Len := saltLen - 8
+	if part2Len < 13 {
+		part2Len = 13
+	}
+	if len(packet) >= pos+part2Len {
+		salt = append(salt, bytes.TrimRight(packet[pos:pos+part2Len], "\x00")...)
+		pos += part2Len
+	}
+
+	plugin := "mysql_native_password"
+	if pos < len(packet) {
+		if name := string(bytes.TrimRight(packet[pos:], "\x00")); name != "" {
+			plugin = name
+		}
+	}
+
+	return salt, plugin, nil
+}
+
+func mysqlHandshakeResponse(check config.HealthCheck, salt []byte, plugin string) []byte {
+	capabilities := uint32(mysqlClientLongPassword | mysqlClientProtocol41 |
+		mysqlClientSecureConnection | mysqlClientPluginAuth)
+	if check.Database != "" {
+		capabilities |= mysqlClientConnectWithDB
+	}
+
+	auth, err := mysqlScramble(plugin, check.Password, salt)
+	if err != nil {
+		// Unknown plugin: answer with native password and let the server switch
+		plugin = "mysql_native_password"
+		auth, _ = mysqlScramble(plugin, check.Password, salt)
+	}
+
+	buf := make([]byte, 32)
+	binary.LittleEndian.PutUint32(buf[0:4], capabilities)
+	binary.LittleEndian.PutUint32(buf[4:8], 1<<24)
+	buf[8] = 33 // utf8_general_ci
+
+	buf = append(buf, check.Username...)
+	buf = append(buf, 0)
+	buf = append(buf, byte(len(auth)))
+	buf = append(buf, auth...)
+	if check.Database != "" {
+		buf = append(buf, check.Database...)
+		buf = append(buf, 0)
+	}
+	buf = append(buf, plugin...)
+	return append(buf, 0)
+}
+
+func mysqlScramble(plugin, password string, salt []byte) ([]byte, error) {
+	if password == "" {
+		return []byte{}, nil
+	}
+
+	switch plugin {
+	case "mysql_native_password":
+		// SHA1(password) XOR SHA1(salt + SHA1(SHA1(password)))
+		stage1 := sha1.Sum([]byte(password))
+		stage2 := sha1.Sum(stage1[:])
+		h := sha1.New()
+		h.Write(salt)
+		h.Write(stage2[:])
+		scramble := h.Sum(nil)
+		for i := range scramble {
+			scramble[i] ^= stage1[i]
+		}
+		return scramble, nil
+	case "caching_sha2_password":
+		// SHA256(password) XOR SHA256(SHA256(SHA256(password)) + salt)
+		stage1 := sha256.Sum256([]byte(password))
+		stage2 := sha256.Sum256(stage1[:])
+		h := sha256.New()
+		h.Write(stage2[:])
+		h.Write(salt)
+		scramble := h.Sum(nil)
+		for i := range scramble {
+			scramble[i] ^= stage1[i]
+		}
+		return scramble, nil
+	default:
+		return nil, fmt.Errorf("unsupported auth plugin %q", plugin)
+	}
+}
+
+func mysqlErrorMessage(packet []byte) string {
+	// 0xff, error code (2), optional '#' + SQL state (5), message
+	if len(packet) < 3 {
+		return "unknown error"
+	}
+	msg := packet[3:]
+	if len(msg) > 0 && msg[0] == '#' && len(msg) >= 6 {
+		msg = msg[6:]
+	}
+	return string(msg)
+}
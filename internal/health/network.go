@@ -0,0 +1,96 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// tcpNetwork maps an address family to the network name used for dialing.
+func tcpNetwork(family string) string {
+	switch family {
+	case config.AddressFamilyIPv4:
+		return "tcp4"
+	case config.AddressFamilyIPv6:
+		return "tcp6"
+	default:
+		return "tcp"
+	}
+}
+
+// icmpNetwork picks the ICMP network for a ping. Without an explicit family
+// IPv6 literals are pinged over ICMPv6 and everything else over ICMPv4.
+func icmpNetwork(target, family string) string {
+	switch family {
+	case config.AddressFamilyIPv4:
+		return "ip4:icmp"
+	case config.AddressFamilyIPv6:
+		return "ip6:ipv6-icmp"
+	}
+
+	if ip := net.ParseIP(target); ip != nil && ip.To4() == nil {
+		return "ip6:ipv6-icmp"
+	}
+	return "ip4:icmp"
+}
+
+// urlHost formats the host part of a URL, bracketing IPv6 literals.
+func urlHost(target string, port int) string {
+	if port > 0 {
+		return net.JoinHostPort(target, strconv.Itoa(port))
+	}
+	if strings.Contains(target, ":") {
+		return "[" + target + "]"
+	}
+	return target
+}
+
+// dualStackCheck runs the check over IPv4 and IPv6 concurrently and only
+// fails if both address families fail.
+func (c *Checker) dualStackCheck(ctx context.Context, container Container, check config.HealthCheck) (bool, error) {
+	families := []string{config.AddressFamilyIPv4, config.AddressFamilyIPv6}
+
+	type outcome struct {
+		success bool
+		err     error
+	}
+	outcomes := make([]chan outcome, len(families))
+
+	for i, family := range families {
+		outcomes[i] = make(chan outcome, 1)
+		single := check
+		single.AddressFamily = family
+		go func(result chan<- outcome) {
+			success, err := c.runCheck(ctx, container, single)
+			result <- outcome{success, err}
+		}(outcomes[i])
+	}
+
+	var errs []error
+	for i, family := range families {
+		result := <-outcomes[i]
+		if result.success {
+			continue
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", family, result.err))
+	}
+
+	if len(errs) == len(families) {
+		return false, errors.Join(errs...)
+	}
+
+	if len(errs) > 0 {
+		c.logger.WithFields(logrus.Fields{
+			"type":   check.Type,
+			"target": check.Target,
+			"error":  errs[0],
+		}).Warn("Health check failed on one address family")
+	}
+	return true, nil
+}
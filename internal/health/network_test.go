@@ -0,0 +1,113 @@
+package health
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+func TestURLHost(t *testing.T) {
+	tests := []struct {
+		target   string
+		port     int
+		expected string
+	}{
+		{"192.168.1.10", 0, "192.168.1.10"},
+		{"192.168.1.10", 8080, "192.168.1.10:8080"},
+		{"app.example.com", 443, "app.example.com:443"},
+		{"2001:db8::10", 0, "[2001:db8::10]"},
+		{"2001:db8::10", 8080, "[2001:db8::10]:8080"},
+	}
+
+	for _, tt := range tests {
+		if got := urlHost(tt.target, tt.port); got != tt.expected {
+			t.Errorf("urlHost(%q, %d) = %q, expected %q", tt.target, tt.port, got, tt.expected)
+		}
+	}
+}
+
+func TestICMPNetwork(t *testing.T) {
+	tests := []struct {
+		target   string
+		family   string
+		expected string
+	}{
+		{"192.168.1.10", "", "ip4:icmp"},
+		{"2001:db8::10", "", "ip6:ipv6-icmp"},
+		{"app.example.com", config.AddressFamilyAny, "ip4:icmp"},
+		{"app.example.com", config.AddressFamilyIPv6, "ip6:ipv6-icmp"},
+		{"app.example.com", config.AddressFamilyIPv4, "ip4:icmp"},
+	}
+
+	for _, tt := range tests {
+		if got := icmpNetwork(tt.target, tt.family); got != tt.expected {
+			t.Errorf("icmpNetwork(%q, %q) = %q, expected %q", tt.target, tt.family, got, tt.expected)
+		}
+	}
+}
+
+func TestChecker_AddressFamily(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	checker := NewChecker(logger)
+
+	// Only listen on IPv4 so the IPv6 half of a dual-stack check fails
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	tests := []struct {
+		name     string
+		family   string
+		expected bool
+	}{
+		{"default", "", true},
+		{"ipv4", config.AddressFamilyIPv4, true},
+		{"ipv6 only", config.AddressFamilyIPv6, false},
+		{"dual stack with one family down", config.AddressFamilyDual, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := checker.RunHealthCheck(context.Background(), config.HealthCheck{
+				Type:          "tcp",
+				Target:        "localhost",
+				Port:          port,
+				Timeout:       2 * time.Second,
+				AddressFamily: tt.family,
+			})
+			if result.Success != tt.expected {
+				t.Errorf("Expected success=%v, got %v, error=%v", tt.expected, result.Success, result.Error)
+			}
+		})
+	}
+
+	// Both families down fails the dual-stack check
+	listener.Close()
+	result := checker.RunHealthCheck(context.Background(), config.HealthCheck{
+		Type:          "tcp",
+		Target:        "localhost",
+		Port:          port,
+		Timeout:       2 * time.Second,
+		AddressFamily: config.AddressFamilyDual,
+	})
+	if result.Success {
+		t.Error("Expected dual-stack check to fail when both families are down")
+	}
+}
@@ -0,0 +1,66 @@
+package health
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/plugin"
+)
+
+// PluginProtocolVersion is sent to plugins so they can reject requests they
+// do not understand.
+const PluginProtocolVersion = plugin.ProtocolVersion
+
+// PluginRequest is written as JSON to a plugin's stdin.
+type PluginRequest struct {
+	Version   int               `json:"version"`
+	Target    string            `json:"target"`
+	Port      int               `json:"port,omitempty"`
+	Path      string            `json:"path,omitempty"`
+	TimeoutMS int64             `json:"timeout_ms"`
+	Options   map[string]string `json:"options,omitempty"`
+	// AddressFamily is "ipv4" or "ipv6" when the check is pinned to one
+	// stack, including each half of a dual-stack check
+	AddressFamily string `json:"address_family,omitempty"`
+}
+
+// PluginResponse is read as JSON from a plugin's stdout.
+type PluginResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+}
+
+// pluginCheck runs an external executable implementing the plugin protocol.
+// The plugin is killed when the check timeout expires.
+func (c *Checker) pluginCheck(ctx context.Context, check config.HealthCheck) (bool, error) {
+	if check.Command == "" {
+		return false, fmt.Errorf("plugin check requires a command")
+	}
+
+	request := PluginRequest{
+		Version:   PluginProtocolVersion,
+		Target:    check.Target,
+		Port:      check.Port,
+		Path:      check.Path,
+		TimeoutMS: check.Timeout.Milliseconds(),
+		Options:   check.Options,
+
+		AddressFamily: check.AddressFamily,
+	}
+
+	var response PluginResponse
+	if err := plugin.Exec(ctx, check.Command, check.Args, request, &response); err != nil {
+		return false, err
+	}
+
+	if !response.Success {
+		message := response.Message
+		if message == "" {
+			message = "plugin reported failure"
+		}
+		return false, fmt.Errorf("plugin check failed: %s", message)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,81 @@
+package health
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+func writePlugin(t *testing.T, script string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "plugin.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatalf("Failed to write plugin: %v", err)
+	}
+	return path
+}
+
+func TestChecker_PluginCheck(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	checker := NewChecker(logger)
+
+	tests := []struct {
+		name          string
+		script        string
+		timeout       time.Duration
+		expectSuccess bool
+	}{
+		{
+			name:          "reports success",
+			script:        `cat > /dev/null; echo '{"success": true, "message": "ok"}'`,
+			timeout:       2 * time.Second,
+			expectSuccess: true,
+		},
+		{
+			name:          "reports failure",
+			script:        `cat > /dev/null; echo '{"success": false, "message": "replication lag 300s"}'`,
+			timeout:       2 * time.Second,
+			expectSuccess: false,
+		},
+		{
+			name:          "receives request on stdin",
+			script:        `grep -q '"target":"10.0.0.5"' && echo '{"success": true}' || echo '{"success": false}'`,
+			timeout:       2 * time.Second,
+			expectSuccess: true,
+		},
+		{
+			name:          "invalid output",
+			script:        `echo "not json"; exit 2`,
+			timeout:       2 * time.Second,
+			expectSuccess: false,
+		},
+		{
+			name:          "timeout",
+			script:        `sleep 5; echo '{"success": true}'`,
+			timeout:       100 * time.Millisecond,
+			expectSuccess: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := checker.RunHealthCheck(context.Background(), config.HealthCheck{
+				Type:    "plugin",
+				Target:  "10.0.0.5",
+				Command: writePlugin(t, tt.script),
+				Timeout: tt.timeout,
+			})
+
+			if result.Success != tt.expectSuccess {
+				t.Errorf("Expected success=%v, got %v, error: %v", tt.expectSuccess, result.Success, result.Error)
+			}
+		})
+	}
+}
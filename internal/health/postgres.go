@@ -0,0 +1,321 @@
+package health
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+const (
+	defaultPostgresPort  = 5432
+	defaultPostgresQuery = "SELECT 1"
+	postgresProtocol     = 196608 // protocol version 3.0
+)
+
+// Authentication request codes sent in 'R' messages
+const (
+	pgAuthOK           = 0
+	pgAuthCleartext    = 3
+	pgAuthMD5          = 5
+	pgAuthSASL         = 10
+	pgAuthSASLContinue = 11
+	pgAuthSASLFinal    = 12
+)
+
+// postgresCheck performs a PostgreSQL startup handshake. Without a password a
+// server that answers with an authentication request is considered healthy;
+// with a password the check authenticates and runs the configured query.
+func (c *Checker) postgresCheck(ctx context.Context, check config.HealthCheck) (bool, error) {
+	conn, err := dialDatabase(ctx, check, defaultPostgresPort)
+	if err != nil {
+		return false, fmt.Errorf("postgres check failed: %w", err)
+	}
+	defer conn.Close()
+
+	pg := &pgConn{w: conn, r: bufio.NewReader(conn)}
+
+	user := check.Username
+	if user == "" {
+		user = "postgres"
+	}
+	database := check.Database
+	if database == "" {
+		database = user
+	}
+
+	if err := pg.startup(user, database); err != nil {
+		return false, fmt.Errorf("postgres startup failed: %w", err)
+	}
+
+	authenticated := false
+	var scram *scramClient
+
+	for !authenticated {
+		msgType, body, err := pg.receive()
+		if err != nil {
+			return false, fmt.Errorf("postgres handshake failed: %w", err)
+		}
+
+		switch msgType {
+		case 'E':
+			return false, fmt.Errorf("postgres error: %s", pgErrorMessage(body))
+		case 'R':
+			if len(body) < 4 {
+				return false, fmt.Errorf("postgres handshake failed: short authentication message")
+			}
+			code := binary.BigEndian.Uint32(body)
+
+			if code != pgAuthOK && check.Password == "" {
+				// The engine is alive and asking for credentials
+				return true, nil
+			}
+
+			switch code {
+			case pgAuthOK:
+				authenticated = true
+			case pgAuthCleartext:
+				err = pg.send('p', append([]byte(check.Password), 0))
+			case pgAuthMD5:
+				if len(body) < 8 {
+					return false, fmt.Errorf("postgres handshake failed: short md5 salt")
+				}
+				err = pg.send('p', append([]byte(pgMD5Password(user, check.Password, body[4:8])), 0))
+			case pgAuthSASL:
+				if !strings.Contains(string(body[4:]), "SCRAM-SHA-256") {
+					return false, fmt.Errorf("postgres server offers no supported SASL mechanism")
+				}
+				scram, err = newScramClient(check.Password)
+				if err == nil {
+					err = pg.send('p', scram.initialResponse())
+				}
+			case pgAuthSASLContinue:
+				if scram == nil {
+					return false, fmt.Errorf("postgres sent SASL continue without SASL start")
+				}
+				var final string
+				final, err = scram.finalMessage(string(body[4:]))
+				if err == nil {
+					err = pg.send('p', []byte(final))
+				}
+			case pgAuthSASLFinal:
+				// Server signature; authentication completes with AuthenticationOK
+			default:
+				return false, fmt.Errorf("postgres authentication method %d not supported", code)
+			}
+
+			if err != nil {
+				return false, fmt.Errorf("postgres authentication failed: %w", err)
+			}
+		}
+	}
+
+	if err := pg.waitReady(); err != nil {
+		return false, fmt.Errorf("postgres check failed: %w", err)
+	}
+
+	query := check.Query
+	if query == "" {
+		query = defaultPostgresQuery
+	}
+
+	if err := pg.send('Q', append([]byte(query), 0)); err != nil {
+		return false, fmt.Errorf("postgres query failed: %w", err)
+	}
+	if err := pg.waitReady(); err != nil {
+		return false, fmt.Errorf("postgres query failed: %w", err)
+	}
+
+	pg.send('X', nil)
+	return true, nil
+}
+
+type pgConn struct {
+	w io.Writer
+	r *bufio.Reader
+}
+
+func (p *pgConn) startup(user, database string) error {
+	var params []byte
+	params = append(params, "user\x00"+user+"\x00"...)
+	params = append(params, "database\x00"+database+"\x00"...)
+	params = append(params, "application_name\x00proxwarden\x00"...)
+	params = append(params, 0)
+
+	msg := make([]byte, 8, 8+len(params))
+	binary.BigEndian.PutUint32(msg[0:4], uint32(8+len(params)))
+	binary.BigEndian.PutUint32(msg[4:8], postgresProtocol)
+	msg = append(msg, params...)
+
+	_, err := p.w.Write(msg)
+	return err
+}
+
+func (p *pgConn) send(msgType byte, body []byte) error {
+	msg := make([]byte, 5, 5+len(body))
+	msg[0] = msgType
+	binary.BigEndian.PutUint32(msg[1:5], uint32(4+len(body)))
+	msg = append(msg, body...)
+
+	_, err := p.w.Write(msg)
+	return err
+}
+
+func (p *pgConn) receive() (byte, []byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(p.r, header); err != nil {
+		return 0, nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[1:5])
+	if length < 4 || length > 1<<20 {
+		return 0, nil, fmt.Errorf("invalid message length %d", length)
+	}
+
+	body := make([]byte, length-4)
+	if _, err := io.ReadFull(p.r, body); err != nil {
+		return 0, nil, err
+	}
+
+	return header[0], body, nil
+}
+
+// waitReady consumes messages until ReadyForQuery, surfacing any ErrorResponse.
+func (p *pgConn) waitReady() error {
+	var queryErr error
+	for {
+		msgType, body, err := p.receive()
+		if err != nil {
+			return err
+		}
+
+		switch msgType {
+		case 'E':
+			queryErr = fmt.Errorf("postgres error: %s", pgErrorMessage(body))
+		case 'Z':
+			return queryErr
+		}
+	}
+}
+
+// pgErrorMessage extracts the human readable message field from an ErrorResponse.
+func pgErrorMessage(body []byte) string {
+	for _, field := range strings.Split(string(body), "\x00") {
+		if len(field) > 1 && field[0] == 'M' {
+			return field[1:]
+		}
+	}
+	return "unknown error"
+}
+
+func pgMD5Password(user, password string, salt []byte) string {
+	inner := md5.Sum([]byte(password + user))
+	outer := md5.Sum(append([]byte(hex.EncodeToString(inner[:])), salt...))
+	return "md5" + hex.EncodeToString(outer[:])
+}
+
+// scramClient implements the client side of SCRAM-SHA-256 (RFC 7677).
+type scramClient struct {
+	password        string
+	clientNonce     string
+	clientFirstBare string
+}
+
+func newScramClient(password string) (*scramClient, error) {
+	nonce := make([]byte, 18)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	s := &scramClient{
+		password:    password,
+		clientNonce: base64.RawStdEncoding.EncodeToString(nonce),
+	}
+	s.clientFirstBare = "n=,r=" + s.clientNonce
+	return s, nil
+}
+
+func (s *scramClient) initialResponse() []byte {
+	data := "n,," + s.clientFirstBare
+
+	msg := append([]byte("SCRAM-SHA-256"), 0)
+	length := make([]byte, 4)
+	binary.BigEndian.PutUint32(length, uint32(len(data)))
+	msg = append(msg, length...)
+	return append(msg, data...)
+}
+
+func (s *scramClient) finalMessage(serverFirst string) (string, error) {
+	var nonce, salt string
+	iterations := 0
+
+	for _, attr := range strings.Split(serverFirst, ",") {
+		if len(attr) < 2 || attr[1] != '=' {
+			continue
+		}
+		switch attr[0] {
+		case 'r':
+			nonce = attr[2:]
+		case 's':
+			salt = attr[2:]
+		case 'i':
+			iterations, _ = strconv.Atoi(attr[2:])
+		}
+	}
+
+	if !strings.HasPrefix(nonce, s.clientNonce) || salt == "" || iterations <= 0 {
+		return "", fmt.Errorf("invalid SCRAM server-first message")
+	}
+
+	saltBytes, err := base64.StdEncoding.DecodeString(salt)
+	if err != nil {
+		return "", fmt.Errorf("invalid SCRAM salt: %w", err)
+	}
+
+	saltedPassword := pbkdf2SHA256([]byte(s.password), saltBytes, iterations)
+	clientKey := hmacSHA256(saltedPassword, []byte("Client Key"))
+	storedKey := sha256.Sum256(clientKey)
+
+	clientFinalWithoutProof := "c=biws,r=" + nonce
+	authMessage := s.clientFirstBare + "," + serverFirst + "," + clientFinalWithoutProof
+	clientSignature := hmacSHA256(storedKey[:], []byte(authMessage))
+
+	proof := make([]byte, len(clientKey))
+	for i := range clientKey {
+		proof[i] = clientKey[i] ^ clientSignature[i]
+	}
+
+	return clientFinalWithoutProof + ",p=" + base64.StdEncoding.EncodeToString(proof), nil
+}
+
+func hmacSHA256(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// pbkdf2SHA256 derives a single 32-byte block, which is all SCRAM-SHA-256 needs.
+func pbkdf2SHA256(password, salt []byte, iterations int) []byte {
+	block := append(append([]byte{}, salt...), 0, 0, 0, 1)
+	u := hmacSHA256(password, block)
+	result := append([]byte{}, u...)
+
+	for i := 1; i < iterations; i++ {
+		u = hmacSHA256(password, u)
+		for j := range result {
+			result[j] ^= u[j]
+		}
+	}
+	return result
+}
@@ -0,0 +1,83 @@
+package health
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+const defaultRedisPort = 6379
+
+// redisCheck authenticates (when credentials are configured), selects the
+// configured database and expects a PONG reply to PING.
+func (c *Checker) redisCheck(ctx context.Context, check config.HealthCheck) (bool, error) {
+	conn, err := dialDatabase(ctx, check, defaultRedisPort)
+	if err != nil {
+		return false, fmt.Errorf("redis check failed: %w", err)
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+
+	if check.Password != "" {
+		args := []string{"AUTH", check.Password}
+		if check.Username != "" {
+			args = []string{"AUTH", check.Username, check.Password}
+		}
+		if _, err := redisCommand(conn, reader, args...); err != nil {
+			return false, fmt.Errorf("redis auth failed: %w", err)
+		}
+	}
+
+	if check.Database != "" {
+		if _, err := redisCommand(conn, reader, "SELECT", check.Database); err != nil {
+			return false, fmt.Errorf("redis select failed: %w", err)
+		}
+	}
+
+	reply, err := redisCommand(conn, reader, "PING")
+	if err != nil {
+		return false, fmt.Errorf("redis ping failed: %w", err)
+	}
+	if reply != "PONG" {
+		return false, fmt.Errorf("redis ping returned unexpected reply: %q", reply)
+	}
+
+	return true, nil
+}
+
+// redisCommand sends a RESP array command and returns the simple-string reply.
+func redisCommand(w io.Writer, reader *bufio.Reader, args ...string) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return "", err
+	}
+
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+
+	if line == "" {
+		return "", fmt.Errorf("empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return "", fmt.Errorf("%s", line[1:])
+	default:
+		return "", fmt.Errorf("unexpected reply: %q", line)
+	}
+}
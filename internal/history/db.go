@@ -0,0 +1,48 @@
+package history
+
+import (
+	"encoding/json"
+	"path/filepath"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/store"
+)
+
+// NewDBStore returns a Store recording to db.
+func NewDBStore(db *store.DB) *Store {
+	return &Store{db: db}
+}
+
+// Open returns the history of the store backend cfg sets.
+func Open(cfg *config.Config) (*Store, error) {
+	db, err := store.OpenConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if db != nil {
+		return NewDBStore(db), nil
+	}
+	return NewStore(filepath.Join(cfg.DataDir, FileName)), nil
+}
+
+func (s *Store) appendDB(record Record) error {
+	return s.db.Update(func(tx *store.Tx) error {
+		return tx.Append(store.BucketHistory, record)
+	})
+}
+
+// listDB returns the records matching filter, skipping those that cannot be
+// decoded as the file skips torn lines.
+func (s *Store) listDB(filter Filter) ([]Record, error) {
+	var records []Record
+	err := s.db.View(func(tx *store.Tx) error {
+		return tx.ForEach(store.BucketHistory, func(data []byte) error {
+			var record Record
+			if err := json.Unmarshal(data, &record); err == nil && filter.match(record) {
+				records = append(records, record)
+			}
+			return nil
+		})
+	})
+	return records, err
+}
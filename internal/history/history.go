@@ -0,0 +1,186 @@
+// Package history keeps a persistent record of every failover attempt.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/store"
+)
+
+// FileName is the name of the history file within the data directory.
+const FileName = "failover-history.jsonl"
+
+// Triggers of a failover.
+const (
+	TriggerManual     = "manual"
+	TriggerAutomatic  = "automatic"
+	TriggerNode       = "node"
+	TriggerFailback   = "failback"
+	TriggerEvacuation = "evacuation"
+	TriggerRollback   = "rollback"
+	TriggerDrill      = "drill"
+	TriggerResume     = "resume"
+)
+
+// Record is a single failover attempt.
+type Record struct {
+	ContainerID   int           `json:"container_id"`
+	ContainerName string        `json:"container_name,omitempty"`
+	Trigger       string        `json:"trigger"`
+	Strategy      string        `json:"strategy,omitempty"`
+	SourceNode    string        `json:"source_node"`
+	TargetNode    string        `json:"target_node"`
+	Backup        string        `json:"backup,omitempty"`
+	Success       bool          `json:"success"`
+	Error         string        `json:"error,omitempty"`
+	StartTime     time.Time     `json:"start_time"`
+	EndTime       time.Time     `json:"end_time"`
+	Duration      time.Duration `json:"duration"`
+	// RoundTrip is set on failbacks: the time since the container failed over
+	RoundTrip time.Duration `json:"round_trip,omitempty"`
+	Hooks     []HookRecord  `json:"hooks,omitempty"`
+	// FloatingIP is set when the failover moved a floating IP
+	FloatingIP *FloatingIPRecord `json:"floating_ip,omitempty"`
+	// Warnings are risks the failover went ahead with, such as the
+	// container possibly still running on the source node
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// FloatingIPRecord is the floating IP step of a failover.
+type FloatingIPRecord struct {
+	Address  string        `json:"address"`
+	Success  bool          `json:"success"`
+	Error    string        `json:"error,omitempty"`
+	ARPSent  int           `json:"arp_sent,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// HookRecord is a hook run during a failover, with its captured output.
+type HookRecord struct {
+	Phase    string        `json:"phase"`
+	Command  string        `json:"command"`
+	Success  bool          `json:"success"`
+	Error    string        `json:"error,omitempty"`
+	Output   string        `json:"output,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Filter selects records. Zero fields match everything.
+type Filter struct {
+	ContainerID int
+	Since       time.Time
+	Limit       int
+}
+
+func (f Filter) match(record Record) bool {
+	if f.ContainerID != 0 && record.ContainerID != f.ContainerID {
+		return false
+	}
+	if !f.Since.IsZero() && record.StartTime.Before(f.Since) {
+		return false
+	}
+	return true
+}
+
+// Store appends records to a file as JSON lines, or to the store database.
+// Each record is written with a single append, so the daemon and CLI
+// commands can record to the same file.
+type Store struct {
+	mu   sync.Mutex
+	path string
+	db   *store.DB
+}
+
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Append records a failover attempt.
+func (s *Store) Append(record Record) error {
+	if s.db != nil {
+		return s.appendDB(record)
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode failover record: %w", err)
+	}
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o750); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to open failover history: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write failover history: %w", err)
+	}
+	return f.Close()
+}
+
+// List returns the records matching filter, newest first. Lines that cannot
+// be decoded, such as one torn by a crash, are skipped.
+func (s *Store) List(filter Filter) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.db != nil {
+		records, err := s.listDB(filter)
+		if err != nil {
+			return nil, err
+		}
+		return newestFirst(records, filter.Limit), nil
+	}
+
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open failover history: %w", err)
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		if filter.match(record) {
+			records = append(records, record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read failover history: %w", err)
+	}
+	return newestFirst(records, filter.Limit), nil
+}
+
+// newestFirst sorts records by start time, newest first, and keeps at most
+// limit of them, unless limit is 0.
+func newestFirst(records []Record, limit int) []Record {
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].StartTime.After(records[j].StartTime)
+	})
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+	return records
+}
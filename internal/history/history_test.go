@@ -0,0 +1,111 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/store"
+)
+
+func TestStore_AppendList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history", "failovers.jsonl")
+	store := NewStore(path)
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	records := []Record{
+		{ContainerID: 100, Trigger: TriggerAutomatic, SourceNode: "node1", TargetNode: "node2", Success: true, StartTime: start},
+		{ContainerID: 101, Trigger: TriggerManual, SourceNode: "node1", TargetNode: "node3", Error: "restore failed", StartTime: start.Add(time.Minute)},
+		{ContainerID: 100, Trigger: TriggerFailback, SourceNode: "node2", TargetNode: "node1", Success: true, StartTime: start.Add(time.Hour), RoundTrip: time.Hour},
+	}
+	for _, record := range records {
+		if err := store.Append(record); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		triggers []string
+	}{
+		{name: "all, newest first", filter: Filter{}, triggers: []string{TriggerFailback, TriggerManual, TriggerAutomatic}},
+		{name: "by container", filter: Filter{ContainerID: 100}, triggers: []string{TriggerFailback, TriggerAutomatic}},
+		{name: "since", filter: Filter{Since: start.Add(time.Minute)}, triggers: []string{TriggerFailback, TriggerManual}},
+		{name: "limit", filter: Filter{Limit: 1}, triggers: []string{TriggerFailback}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.List(tt.filter)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if len(got) != len(tt.triggers) {
+				t.Fatalf("List() returned %d records, want %d", len(got), len(tt.triggers))
+			}
+			for i, record := range got {
+				if record.Trigger != tt.triggers[i] {
+					t.Errorf("record %d trigger = %q, want %q", i, record.Trigger, tt.triggers[i])
+				}
+			}
+		})
+	}
+}
+
+func TestStore_List(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("missing file", func(t *testing.T) {
+		got, err := NewStore(filepath.Join(dir, "missing.jsonl")).List(Filter{})
+		if err != nil || len(got) != 0 {
+			t.Errorf("List() = %v, %v, want no records", got, err)
+		}
+	})
+
+	t.Run("torn line", func(t *testing.T) {
+		path := filepath.Join(dir, "torn.jsonl")
+		data := `{"container_id":100,"trigger":"manual","success":true}` + "\n" + `{"container_id":10`
+		if err := os.WriteFile(path, []byte(data), 0o640); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := NewStore(path).List(Filter{})
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		if len(got) != 1 || got[0].ContainerID != 100 {
+			t.Errorf("List() = %+v, want the complete record only", got)
+		}
+	})
+}
+
+func TestDBStore(t *testing.T) {
+	db, err := store.Open(filepath.Join(t.TempDir(), store.FileName), "")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	s := NewDBStore(db)
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	records := []Record{
+		{ContainerID: 100, Trigger: TriggerAutomatic, StartTime: start},
+		{ContainerID: 101, Trigger: TriggerManual, StartTime: start.Add(time.Hour)},
+		{ContainerID: 100, Trigger: TriggerFailback, StartTime: start.Add(time.Minute)},
+	}
+	for _, record := range records {
+		if err := s.Append(record); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	// A CLI command reads what the daemon recorded
+	got, err := NewDBStore(db).List(Filter{ContainerID: 100})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(got) != 2 || got[0].Trigger != TriggerFailback || got[1].Trigger != TriggerAutomatic {
+		t.Errorf("List() = %+v, want the failback then the automatic failover", got)
+	}
+}
@@ -0,0 +1,148 @@
+// Package hook runs the shell commands users configure around ProxWarden's
+// work, such as failovers and backups.
+package hook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/audit"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// maxOutput bounds how much of a hook's output is kept.
+const maxOutput = 4096
+
+// waitDelay is how long a timed out hook's output pipes may stay open, for
+// example held by a background child, before they are closed.
+const waitDelay = 5 * time.Second
+
+// Result is the outcome of a single hook run.
+type Result struct {
+	Command string
+	Success bool
+	// ExitCode is the exit status of the command, or -1 if it did not exit
+	// on its own, for example because it timed out
+	ExitCode int
+	Error    error
+	Output   string
+	Duration time.Duration
+}
+
+// RunAll runs hooks in order with env added to their environment. A
+// failing hook stops the remaining ones when its failure policy, by default
+// onFailure, is abort; the error of the first failing hook is returned
+// either way.
+func RunAll(ctx context.Context, hooks []config.Hook, onFailure string, env []string, logger *logrus.Entry) ([]Result, error) {
+	var (
+		results  []Result
+		firstErr error
+	)
+	for _, hook := range hooks {
+		run := Run(ctx, hook, env, logger)
+		results = append(results, run)
+		if run.Success {
+			continue
+		}
+
+		if firstErr == nil {
+			firstErr = run.Error
+		}
+		policy := hook.OnFailure
+		if policy == "" {
+			policy = onFailure
+		}
+		if policy == config.HookAbort {
+			break
+		}
+	}
+	return results, firstErr
+}
+
+// Run runs a single hook with env added to its environment, capturing its
+// output.
+func Run(ctx context.Context, hook config.Hook, env []string, logger *logrus.Entry) Result {
+	logger = logger.WithField("hook", hook.Command)
+	logger.Info("Executing hook")
+
+	timeout := hook.Timeout
+	if timeout == 0 {
+		timeout = config.DefaultHookTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", hook.Command)
+	cmd.WaitDelay = waitDelay
+	cmd.Env = append(os.Environ(), env...)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	entry := auditEntry(ctx, hook.Command, env)
+	start := time.Now()
+	err := cmd.Run()
+	run := Result{
+		Command:  hook.Command,
+		Success:  err == nil,
+		Output:   truncate(output.String()),
+		Duration: time.Since(start),
+	}
+
+	logger = logger.WithFields(logrus.Fields{
+		"duration": run.Duration,
+		"output":   run.Output,
+	})
+	if err != nil {
+		run.ExitCode = -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			run.ExitCode = exitErr.ExitCode()
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %s", timeout)
+		}
+		run.Error = fmt.Errorf("hook '%s' failed: %w", hook.Command, err)
+		logger.WithField("error", err).Warn("Hook failed")
+		entry.End(run.Error)
+		return run
+	}
+
+	logger.Info("Hook completed")
+	entry.End(nil)
+	return run
+}
+
+// auditEntry starts the audit entry of a hook run, with the environment
+// describing what it runs for as its parameters.
+func auditEntry(ctx context.Context, command string, env []string) *audit.Entry {
+	entry := audit.Begin(ctx, audit.ActionHook, 0).Param("command", command)
+	for _, pair := range env {
+		name, value, _ := strings.Cut(pair, "=")
+		switch name {
+		case "CONTAINER_ID":
+			entry.ContainerID, _ = strconv.Atoi(value)
+		case "NODE":
+			entry.Node = value
+		default:
+			entry.Param(strings.ToLower(name), value)
+		}
+	}
+	return entry
+}
+
+// truncate keeps the end of long output, where errors usually are.
+func truncate(output string) string {
+	if len(output) <= maxOutput {
+		return output
+	}
+	return "..." + output[len(output)-maxOutput:]
+}
@@ -0,0 +1,70 @@
+package hook
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+func testLogger() *logrus.Entry {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logrus.NewEntry(logger)
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		hook     config.Hook
+		success  bool
+		exitCode int
+		output   string
+		err      string
+	}{
+		{name: "success", hook: config.Hook{Command: `echo "$GREETING"`}, success: true, output: "hello\n"},
+		{name: "failure", hook: config.Hook{Command: "echo broken >&2; exit 3"}, exitCode: 3, output: "broken\n", err: "exit status 3"},
+		{name: "timeout", hook: config.Hook{Command: "exec sleep 5", Timeout: 50 * time.Millisecond}, exitCode: -1, err: "timed out after 50ms"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := Run(context.Background(), tt.hook, []string{"GREETING=hello"}, testLogger())
+			if run.Success != tt.success || run.Output != tt.output {
+				t.Errorf("Expected success %v with output %q, got %v with %q", tt.success, tt.output, run.Success, run.Output)
+			}
+			if run.ExitCode != tt.exitCode {
+				t.Errorf("Expected exit code %d, got %d", tt.exitCode, run.ExitCode)
+			}
+			if tt.err != "" && (run.Error == nil || !strings.Contains(run.Error.Error(), tt.err)) {
+				t.Errorf("Expected error containing %q, got %v", tt.err, run.Error)
+			}
+		})
+	}
+}
+
+func TestRunAll(t *testing.T) {
+	hooks := []config.Hook{
+		{Command: "exit 1", OnFailure: config.HookContinue},
+		{Command: "exit 2"},
+		{Command: "true"},
+	}
+
+	results, err := RunAll(context.Background(), hooks, config.HookAbort, nil, testLogger())
+	if len(results) != 2 || err == nil || !strings.Contains(err.Error(), "exit status 1") {
+		t.Errorf("Expected the default abort to stop after the second hook with the first error, got %d runs and %v", len(results), err)
+	}
+
+	results, _ = RunAll(context.Background(), hooks, config.HookContinue, nil, testLogger())
+	if len(results) != 3 || !results[2].Success {
+		t.Errorf("Expected all hooks to run, got %+v", results)
+	}
+
+	if len(truncate(strings.Repeat("x", 2*maxOutput))) != maxOutput+3 {
+		t.Error("Expected long output to be truncated")
+	}
+}
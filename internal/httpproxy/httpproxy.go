@@ -0,0 +1,48 @@
+// Package httpproxy resolves proxy settings shared by the Proxmox API client
+// and HTTP health checks.
+package httpproxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Direct disables proxying, including proxies from the environment.
+const Direct = "direct"
+
+// ProxyFunc returns the proxy selector for a setting. An empty setting uses
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY from the environment, "direct" never
+// proxies, and anything else must be a proxy URL such as
+// "http://proxy.example.com:3128".
+func ProxyFunc(setting string) (func(*http.Request) (*url.URL, error), error) {
+	switch setting {
+	case "":
+		return http.ProxyFromEnvironment, nil
+	case Direct:
+		return nil, nil
+	}
+
+	proxyURL, err := url.Parse(setting)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %q: %w", setting, err)
+	}
+	if proxyURL.Scheme == "" || proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q: scheme and host are required", setting)
+	}
+
+	return http.ProxyURL(proxyURL), nil
+}
+
+// Transport returns a copy of the default transport using the given proxy
+// setting.
+func Transport(setting string) (*http.Transport, error) {
+	proxy, err := ProxyFunc(setting)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+	return transport, nil
+}
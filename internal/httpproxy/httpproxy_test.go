@@ -0,0 +1,62 @@
+package httpproxy
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestProxyFunc(t *testing.T) {
+	t.Setenv("HTTP_PROXY", "http://env-proxy:3128")
+	t.Setenv("NO_PROXY", "internal.example.com")
+
+	tests := []struct {
+		name      string
+		setting   string
+		target    string
+		expected  string
+		expectErr bool
+	}{
+		{"environment", "", "http://app.example.com/health", "http://env-proxy:3128", false},
+		{"environment no_proxy", "", "http://internal.example.com/health", "", false},
+		{"direct", Direct, "http://app.example.com/health", "", false},
+		{"explicit", "http://proxy:8080", "http://internal.example.com/health", "http://proxy:8080", false},
+		{"missing scheme", "proxy:8080", "", "", true},
+		{"invalid", "http://[::1", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy, err := ProxyFunc(tt.setting)
+			if tt.expectErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if proxy == nil {
+				if tt.expected != "" {
+					t.Errorf("Expected proxy %s, got none", tt.expected)
+				}
+				return
+			}
+
+			req, _ := http.NewRequest(http.MethodGet, tt.target, nil)
+			proxyURL, err := proxy(req)
+			if err != nil {
+				t.Fatalf("Proxy selection failed: %v", err)
+			}
+
+			got := ""
+			if proxyURL != nil {
+				got = proxyURL.String()
+			}
+			if got != tt.expected {
+				t.Errorf("Expected proxy %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
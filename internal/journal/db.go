@@ -0,0 +1,72 @@
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/store"
+)
+
+// NewDBStore returns a Store keeping the journal in db.
+func NewDBStore(db *store.DB) *Store {
+	return &Store{db: db}
+}
+
+// Open returns the journal of the store backend cfg sets.
+func Open(cfg *config.Config) (*Store, error) {
+	db, err := store.OpenConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if db != nil {
+		return NewDBStore(db), nil
+	}
+	return NewStore(filepath.Join(cfg.DataDir, FileName)), nil
+}
+
+func (s *Store) loadDB() (map[int]Entry, error) {
+	var entries map[int]Entry
+	err := s.db.View(func(tx *store.Tx) error {
+		var err error
+		entries, err = loadTx(tx)
+		return err
+	})
+	return entries, err
+}
+
+// updateDB changes the journal in a single transaction, so that it cannot be
+// read half written.
+func (s *Store) updateDB(change func(entries map[int]Entry)) error {
+	return s.db.Update(func(tx *store.Tx) error {
+		entries, err := loadTx(tx)
+		if err != nil {
+			return err
+		}
+		change(entries)
+		if err := tx.Clear(store.BucketJournal); err != nil {
+			return err
+		}
+		for id, entry := range entries {
+			if err := tx.Put(store.BucketJournal, strconv.Itoa(id), entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func loadTx(tx *store.Tx) (map[int]Entry, error) {
+	entries := make(map[int]Entry)
+	err := tx.ForEach(store.BucketJournal, func(data []byte) error {
+		var entry Entry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return fmt.Errorf("failed to decode failover journal: %w", err)
+		}
+		entries[entry.ContainerID] = entry
+		return nil
+	})
+	return entries, err
+}
@@ -0,0 +1,210 @@
+// Package journal records failovers while they run, so that failovers a
+// crash of the daemon left unfinished are found when it starts again.
+package journal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/store"
+)
+
+// FileName is the name of the journal file within the data directory.
+const FileName = "failover-journal.json"
+
+// Entry is a failover that is running or, if Interrupted, was running when
+// the daemon stopped.
+type Entry struct {
+	ContainerID   int    `json:"container_id"`
+	ContainerName string `json:"container_name,omitempty"`
+	Trigger       string `json:"trigger"`
+	Strategy      string `json:"strategy,omitempty"`
+	SourceNode    string `json:"source_node"`
+	TargetNode    string `json:"target_node"`
+	// Step is the last step the failover started, empty while it waits
+	// for approval, its grace period or a free slot
+	Step      string    `json:"step,omitempty"`
+	StartTime time.Time `json:"start_time"`
+	Updated   time.Time `json:"updated"`
+	// Interrupted is set on entries found when the daemon started
+	Interrupted bool `json:"interrupted,omitempty"`
+}
+
+// Store keeps the journal in a JSON file, rewritten on every change, or in
+// the store database. A nil Store records nothing.
+type Store struct {
+	mu   sync.Mutex
+	path string
+	db   *store.DB
+}
+
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Put adds the entry of a container or replaces it.
+func (s *Store) Put(entry Entry) error {
+	if s == nil {
+		return nil
+	}
+	return s.update(func(entries map[int]Entry) {
+		entries[entry.ContainerID] = entry
+	})
+}
+
+// SetStep records the step the failover of a container started.
+func (s *Store) SetStep(containerID int, step, strategy string) error {
+	if s == nil {
+		return nil
+	}
+	return s.update(func(entries map[int]Entry) {
+		entry, exists := entries[containerID]
+		if !exists {
+			return
+		}
+		entry.Step = step
+		if strategy != "" {
+			entry.Strategy = strategy
+		}
+		entry.Updated = time.Now()
+		entries[containerID] = entry
+	})
+}
+
+// Remove deletes the entry of a container.
+func (s *Store) Remove(containerID int) error {
+	if s == nil {
+		return nil
+	}
+	return s.update(func(entries map[int]Entry) {
+		delete(entries, containerID)
+	})
+}
+
+// Get returns the entry of a container.
+func (s *Store) Get(containerID int) (Entry, bool, error) {
+	if s == nil {
+		return Entry{}, false, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return Entry{}, false, err
+	}
+	entry, exists := entries[containerID]
+	return entry, exists, nil
+}
+
+// List returns every entry, oldest first.
+func (s *Store) List() ([]Entry, error) {
+	if s == nil {
+		return nil, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return sorted(entries), nil
+}
+
+// Interrupt marks every entry as interrupted and returns them, oldest
+// first. It is called once when the daemon starts, before it runs
+// failovers, so every entry belongs to a failover that did not finish.
+func (s *Store) Interrupt() ([]Entry, error) {
+	if s == nil {
+		return nil, nil
+	}
+	var result []Entry
+	err := s.update(func(entries map[int]Entry) {
+		for id, entry := range entries {
+			entry.Interrupted = true
+			entries[id] = entry
+		}
+		result = sorted(entries)
+	})
+	return result, err
+}
+
+func (s *Store) update(change func(entries map[int]Entry)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.db != nil {
+		return s.updateDB(change)
+	}
+	entries, err := s.load()
+	if err != nil {
+		return err
+	}
+	change(entries)
+	return s.save(entries)
+}
+
+func (s *Store) load() (map[int]Entry, error) {
+	if s.db != nil {
+		return s.loadDB()
+	}
+	entries := make(map[int]Entry)
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read failover journal: %w", err)
+	}
+
+	var list []Entry
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to decode failover journal: %w", err)
+	}
+	for _, entry := range list {
+		entries[entry.ContainerID] = entry
+	}
+	return entries, nil
+}
+
+func (s *Store) save(entries map[int]Entry) error {
+	data, err := json.MarshalIndent(sorted(entries), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode failover journal: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o750); err != nil {
+		return fmt.Errorf("failed to create journal directory: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o640); err != nil {
+		return fmt.Errorf("failed to write failover journal: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write failover journal: %w", err)
+	}
+	return nil
+}
+
+func sorted(entries map[int]Entry) []Entry {
+	list := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		list = append(list, entry)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].StartTime.Equal(list[j].StartTime) {
+			return list[i].StartTime.Before(list[j].StartTime)
+		}
+		return list[i].ContainerID < list[j].ContainerID
+	})
+	return list
+}
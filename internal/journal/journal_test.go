@@ -0,0 +1,114 @@
+package journal
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/store"
+)
+
+func TestStore_Lifecycle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", FileName)
+	store := NewStore(path)
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{ContainerID: 101, Trigger: "manual", SourceNode: "node1", TargetNode: "node3", StartTime: start.Add(time.Minute)},
+		{ContainerID: 100, Trigger: "automatic", SourceNode: "node1", TargetNode: "node2", StartTime: start},
+		{ContainerID: 102, Trigger: "node", SourceNode: "node1", TargetNode: "node2", StartTime: start.Add(time.Hour)},
+	}
+	for _, entry := range entries {
+		if err := store.Put(entry); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+	if err := store.SetStep(100, "restore", "restore"); err != nil {
+		t.Fatalf("SetStep() error = %v", err)
+	}
+	if err := store.SetStep(100, "start", ""); err != nil {
+		t.Fatalf("SetStep() error = %v", err)
+	}
+	// Steps of failovers without an entry are ignored
+	if err := store.SetStep(200, "restore", "restore"); err != nil {
+		t.Fatalf("SetStep() error = %v", err)
+	}
+	if err := store.Remove(102); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+
+	// A restarted daemon reads the journal from the same file
+	interrupted, err := NewStore(path).Interrupt()
+	if err != nil {
+		t.Fatalf("Interrupt() error = %v", err)
+	}
+	if len(interrupted) != 2 {
+		t.Fatalf("Interrupt() returned %d entries, want 2", len(interrupted))
+	}
+	first := interrupted[0]
+	if first.ContainerID != 100 || first.Step != "start" || first.Strategy != "restore" || !first.Interrupted {
+		t.Errorf("Interrupt()[0] = %+v, want container 100 interrupted at step start with strategy restore", first)
+	}
+	if interrupted[1].ContainerID != 101 || interrupted[1].Step != "" {
+		t.Errorf("Interrupt()[1] = %+v, want container 101 without a step", interrupted[1])
+	}
+
+	entry, exists, err := store.Get(101)
+	if err != nil || !exists || !entry.Interrupted {
+		t.Errorf("Get(101) = %+v, %v, %v, want an interrupted entry", entry, exists, err)
+	}
+	if _, exists, _ := store.Get(102); exists {
+		t.Error("Get(102) found a removed entry")
+	}
+}
+
+func TestStore_Nil(t *testing.T) {
+	var store *Store
+	if err := store.Put(Entry{ContainerID: 100}); err != nil {
+		t.Errorf("Put() error = %v", err)
+	}
+	if entries, err := store.Interrupt(); err != nil || len(entries) != 0 {
+		t.Errorf("Interrupt() = %v, %v, want nothing", entries, err)
+	}
+}
+
+func TestStore_Missing(t *testing.T) {
+	entries, err := NewStore(filepath.Join(t.TempDir(), FileName)).List()
+	if err != nil || len(entries) != 0 {
+		t.Errorf("List() = %v, %v, want nothing", entries, err)
+	}
+}
+
+func TestDBStore(t *testing.T) {
+	db, err := store.Open(filepath.Join(t.TempDir(), store.FileName), "")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	s := NewDBStore(db)
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := s.Put(Entry{ContainerID: 100, Trigger: "automatic", StartTime: start}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := s.Put(Entry{ContainerID: 101, Trigger: "manual", StartTime: start.Add(time.Minute)}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := s.SetStep(100, "restore", "restore"); err != nil {
+		t.Fatalf("SetStep() error = %v", err)
+	}
+	if err := s.Remove(101); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+
+	// A restarted daemon reads the journal from the same database
+	interrupted, err := NewDBStore(db).Interrupt()
+	if err != nil {
+		t.Fatalf("Interrupt() error = %v", err)
+	}
+	if len(interrupted) != 1 || interrupted[0].Step != "restore" || !interrupted[0].Interrupted {
+		t.Fatalf("Interrupt() = %+v, want container 100 interrupted at step restore", interrupted)
+	}
+	if entry, exists, err := s.Get(100); err != nil || !exists || !entry.Interrupted {
+		t.Errorf("Get(100) = %+v, %v, %v, want an interrupted entry", entry, exists, err)
+	}
+}
@@ -0,0 +1,171 @@
+// Package kv reads keys of the key-value stores the configuration can be
+// kept in, through their HTTP APIs.
+package kv
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrNotFound is returned for keys that do not exist.
+var ErrNotFound = errors.New("key not found")
+
+// Value is the value of a key and its version, which changes whenever the
+// key is written.
+type Value struct {
+	Data    []byte
+	Version uint64
+}
+
+// Store reads keys.
+type Store interface {
+	Get(ctx context.Context, key string) (Value, error)
+}
+
+// Consul reads keys of the Consul KV store.
+type Consul struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// NewConsul returns a Consul store at endpoint, such as
+// http://127.0.0.1:8500, authenticating with an ACL token if one is given.
+func NewConsul(endpoint, token string) *Consul {
+	return &Consul{
+		baseURL: strings.TrimSuffix(endpoint, "/"),
+		token:   token,
+		client:  &http.Client{},
+	}
+}
+
+func (c *Consul) Get(ctx context.Context, key string) (Value, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/v1/kv/"+strings.TrimPrefix(key, "/"), nil)
+	if err != nil {
+		return Value{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	if c.token != "" {
+		req.Header.Set("X-Consul-Token", c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Value{}, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return Value{}, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return Value{}, fmt.Errorf("consul returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var entries []struct {
+		Value       []byte `json:"Value"`
+		ModifyIndex uint64 `json:"ModifyIndex"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return Value{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(entries) == 0 {
+		return Value{}, ErrNotFound
+	}
+	return Value{Data: entries[0].Value, Version: entries[0].ModifyIndex}, nil
+}
+
+// Etcd reads keys of etcd through its v3 JSON gateway.
+type Etcd struct {
+	baseURL  string
+	username string
+	password string
+	client   *http.Client
+}
+
+// NewEtcd returns an etcd store at endpoint, such as
+// http://127.0.0.1:2379, authenticating as username if one is given.
+func NewEtcd(endpoint, username, password string) *Etcd {
+	return &Etcd{
+		baseURL:  strings.TrimSuffix(endpoint, "/"),
+		username: username,
+		password: password,
+		client:   &http.Client{},
+	}
+}
+
+func (e *Etcd) Get(ctx context.Context, key string) (Value, error) {
+	token, err := e.authenticate(ctx)
+	if err != nil {
+		return Value{}, err
+	}
+
+	var result struct {
+		KVs []struct {
+			Value       []byte `json:"value"`
+			ModRevision uint64 `json:"mod_revision,string"`
+		} `json:"kvs"`
+	}
+	request := map[string]string{"key": base64.StdEncoding.EncodeToString([]byte(key))}
+	if err := e.post(ctx, "/v3/kv/range", token, request, &result); err != nil {
+		return Value{}, err
+	}
+	if len(result.KVs) == 0 {
+		return Value{}, ErrNotFound
+	}
+	return Value{Data: result.KVs[0].Value, Version: result.KVs[0].ModRevision}, nil
+}
+
+// authenticate returns a token for the user, or "" when no user is set.
+func (e *Etcd) authenticate(ctx context.Context) (string, error) {
+	if e.username == "" {
+		return "", nil
+	}
+	var auth struct {
+		Token string `json:"token"`
+	}
+	if err := e.post(ctx, "/v3/auth/authenticate", "", map[string]string{"name": e.username, "password": e.password}, &auth); err != nil {
+		return "", fmt.Errorf("authentication failed: %w", err)
+	}
+	return auth.Token, nil
+}
+
+func (e *Etcd) post(ctx context.Context, path, token string, request, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("etcd returned %d: %s", resp.StatusCode, apiErr.Message)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
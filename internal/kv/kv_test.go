@@ -0,0 +1,92 @@
+package kv
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConsul_Get(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Consul-Token") != "acl-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/v1/kv/proxwarden/cluster-a" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"Key": "proxwarden/cluster-a", "Value": base64.StdEncoding.EncodeToString([]byte("data_dir: /tmp\n")), "ModifyIndex": 42},
+		})
+	}))
+	defer server.Close()
+
+	store := NewConsul(server.URL+"/", "acl-token")
+	value, err := store.Get(context.Background(), "proxwarden/cluster-a")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if string(value.Data) != "data_dir: /tmp\n" || value.Version != 42 {
+		t.Errorf("Expected the value at version 42, got %q at %d", value.Data, value.Version)
+	}
+
+	if _, err := store.Get(context.Background(), "proxwarden/missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if _, err := NewConsul(server.URL, "").Get(context.Background(), "proxwarden/cluster-a"); err == nil {
+		t.Error("Expected a request without the token to fail")
+	}
+}
+
+func TestEtcd_Get(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]string
+		json.NewDecoder(r.Body).Decode(&request)
+		switch r.URL.Path {
+		case "/v3/auth/authenticate":
+			if request["name"] != "proxwarden" || request["password"] != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(map[string]string{"message": "authentication failed, invalid user ID or password"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"token": "etcd-token"})
+		case "/v3/kv/range":
+			if r.Header.Get("Authorization") != "etcd-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			key, _ := base64.StdEncoding.DecodeString(request["key"])
+			if string(key) != "/proxwarden/cluster-a" {
+				json.NewEncoder(w).Encode(map[string]interface{}{"header": map[string]string{"revision": "7"}})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"kvs": []map[string]string{{"key": request["key"], "value": base64.StdEncoding.EncodeToString([]byte("data_dir: /tmp\n")), "mod_revision": "7"}},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	store := NewEtcd(server.URL, "proxwarden", "secret")
+	value, err := store.Get(context.Background(), "/proxwarden/cluster-a")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if string(value.Data) != "data_dir: /tmp\n" || value.Version != 7 {
+		t.Errorf("Expected the value at revision 7, got %q at %d", value.Data, value.Version)
+	}
+
+	if _, err := store.Get(context.Background(), "/proxwarden/missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if _, err := NewEtcd(server.URL, "proxwarden", "wrong").Get(context.Background(), "/proxwarden/cluster-a"); err == nil {
+		t.Error("Expected a wrong password to fail")
+	}
+}
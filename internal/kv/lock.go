@@ -0,0 +1,258 @@
+package kv
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Lock is a key held by one holder at a time, for as long as the holder
+// keeps acquiring it within its TTL.
+type Lock interface {
+	// Acquire takes the lock if it is free, or extends it if this holder
+	// has it, and reports whether this holder has it.
+	Acquire(ctx context.Context) (bool, error)
+	// Release gives the lock up if this holder has it.
+	Release(ctx context.Context) error
+	// Holder returns the holder of the lock, or "" if it is free.
+	Holder(ctx context.Context) (string, error)
+}
+
+// consulLock holds a key through a Consul session, which deletes the key
+// when it expires.
+type consulLock struct {
+	consul  *Consul
+	key     string
+	holder  string
+	ttl     time.Duration
+	session string
+}
+
+// Lock returns a lock on key held as holder; Consul expires it ttl, at least
+// 10s, after it was last acquired.
+func (c *Consul) Lock(key, holder string, ttl time.Duration) Lock {
+	return &consulLock{consul: c, key: strings.TrimPrefix(key, "/"), holder: holder, ttl: ttl}
+}
+
+func (l *consulLock) Acquire(ctx context.Context) (bool, error) {
+	if l.session != "" {
+		err := l.consul.put(ctx, "/v1/session/renew/"+l.session, nil, nil)
+		if errors.Is(err, ErrNotFound) {
+			// The session expired, and the key went with it
+			l.session = ""
+		} else if err != nil {
+			return false, fmt.Errorf("failed to renew session: %w", err)
+		}
+	}
+	if l.session == "" {
+		var session struct {
+			ID string `json:"ID"`
+		}
+		request := map[string]string{
+			"Name":      "proxwarden " + l.holder,
+			"TTL":       l.ttl.String(),
+			"Behavior":  "delete",
+			"LockDelay": "0s",
+		}
+		if err := l.consul.put(ctx, "/v1/session/create", request, &session); err != nil {
+			return false, fmt.Errorf("failed to create session: %w", err)
+		}
+		l.session = session.ID
+	}
+
+	var acquired bool
+	if err := l.consul.put(ctx, "/v1/kv/"+l.key+"?acquire="+l.session, l.holder, &acquired); err != nil {
+		return false, fmt.Errorf("failed to acquire %s: %w", l.key, err)
+	}
+	return acquired, nil
+}
+
+func (l *consulLock) Release(ctx context.Context) error {
+	if l.session == "" {
+		return nil
+	}
+	session := l.session
+	l.session = ""
+	// Destroying the session deletes the key it holds
+	if err := l.consul.put(ctx, "/v1/session/destroy/"+session, nil, nil); err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("failed to destroy session: %w", err)
+	}
+	return nil
+}
+
+func (l *consulLock) Holder(ctx context.Context) (string, error) {
+	value, err := l.consul.Get(ctx, l.key)
+	if errors.Is(err, ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(value.Data), nil
+}
+
+// put sends a PUT request with body, sent as is if it is a string and as
+// JSON otherwise, and decodes the response into response if it is set.
+func (c *Consul) put(ctx context.Context, path string, body, response interface{}) error {
+	var reader io.Reader
+	switch body := body.(type) {
+	case nil:
+	case string:
+		reader = strings.NewReader(body)
+	default:
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if c.token != "" {
+		req.Header.Set("X-Consul-Token", c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("consul returned %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	if response == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// etcdLock holds a key attached to an etcd lease, which deletes the key
+// when it expires.
+type etcdLock struct {
+	etcd   *Etcd
+	key    string
+	holder string
+	ttl    time.Duration
+	lease  string
+}
+
+// Lock returns a lock on key held as holder; etcd expires it ttl after it
+// was last acquired.
+func (e *Etcd) Lock(key, holder string, ttl time.Duration) Lock {
+	return &etcdLock{etcd: e, key: key, holder: holder, ttl: ttl}
+}
+
+func (l *etcdLock) Acquire(ctx context.Context) (bool, error) {
+	token, err := l.etcd.authenticate(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if l.lease != "" {
+		var keepalive struct {
+			Result struct {
+				TTL string `json:"TTL"`
+			} `json:"result"`
+		}
+		if err := l.etcd.post(ctx, "/v3/lease/keepalive", token, map[string]string{"ID": l.lease}, &keepalive); err != nil {
+			return false, fmt.Errorf("failed to renew lease: %w", err)
+		}
+		if ttl, _ := strconv.ParseInt(keepalive.Result.TTL, 10, 64); ttl <= 0 {
+			// The lease expired, and the key went with it
+			l.lease = ""
+		}
+	}
+	if l.lease == "" {
+		var grant struct {
+			ID string `json:"ID"`
+		}
+		request := map[string]string{"TTL": strconv.FormatInt(int64(l.ttl.Seconds()), 10)}
+		if err := l.etcd.post(ctx, "/v3/lease/grant", token, request, &grant); err != nil {
+			return false, fmt.Errorf("failed to grant lease: %w", err)
+		}
+		l.lease = grant.ID
+	}
+
+	// Create the key unless it exists, reading it otherwise
+	key := base64.StdEncoding.EncodeToString([]byte(l.key))
+	request := map[string]interface{}{
+		"compare": []map[string]string{{"key": key, "target": "CREATE", "result": "EQUAL", "create_revision": "0"}},
+		"success": []map[string]interface{}{{"request_put": map[string]string{
+			"key":   key,
+			"value": base64.StdEncoding.EncodeToString([]byte(l.holder)),
+			"lease": l.lease,
+		}}},
+		"failure": []map[string]interface{}{{"request_range": map[string]string{"key": key}}},
+	}
+	var result struct {
+		Succeeded bool `json:"succeeded"`
+		Responses []struct {
+			ResponseRange struct {
+				KVs []struct {
+					Lease string `json:"lease"`
+				} `json:"kvs"`
+			} `json:"response_range"`
+		} `json:"responses"`
+	}
+	if err := l.etcd.post(ctx, "/v3/kv/txn", token, request, &result); err != nil {
+		return false, fmt.Errorf("failed to acquire %s: %w", l.key, err)
+	}
+	if result.Succeeded {
+		return true, nil
+	}
+	for _, response := range result.Responses {
+		for _, kv := range response.ResponseRange.KVs {
+			if kv.Lease == l.lease {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func (l *etcdLock) Release(ctx context.Context) error {
+	if l.lease == "" {
+		return nil
+	}
+	token, err := l.etcd.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+	lease := l.lease
+	l.lease = ""
+	// Revoking the lease deletes the key attached to it
+	if err := l.etcd.post(ctx, "/v3/lease/revoke", token, map[string]string{"ID": lease}, &struct{}{}); err != nil {
+		return fmt.Errorf("failed to revoke lease: %w", err)
+	}
+	return nil
+}
+
+func (l *etcdLock) Holder(ctx context.Context) (string, error) {
+	value, err := l.etcd.Get(ctx, l.key)
+	if errors.Is(err, ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(value.Data), nil
+}
@@ -0,0 +1,219 @@
+package kv
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeConsul implements the session and lock endpoints of Consul for one
+// key, without expiry.
+type fakeConsul struct {
+	mu       sync.Mutex
+	sessions map[string]bool
+	holder   string
+	value    string
+	next     int
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.URL.Path == "/v1/session/create":
+		f.next++
+		id := fmt.Sprintf("session-%d", f.next)
+		f.sessions[id] = true
+		json.NewEncoder(w).Encode(map[string]string{"ID": id})
+	case strings.HasPrefix(r.URL.Path, "/v1/session/renew/"):
+		if !f.sessions[strings.TrimPrefix(r.URL.Path, "/v1/session/renew/")] {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("[]"))
+	case strings.HasPrefix(r.URL.Path, "/v1/session/destroy/"):
+		id := strings.TrimPrefix(r.URL.Path, "/v1/session/destroy/")
+		delete(f.sessions, id)
+		if f.holder == id {
+			f.holder, f.value = "", ""
+		}
+		w.Write([]byte("true"))
+	case r.Method == http.MethodPut:
+		session := r.URL.Query().Get("acquire")
+		body, _ := io.ReadAll(r.Body)
+		acquired := f.sessions[session] && (f.holder == "" || f.holder == session)
+		if acquired {
+			f.holder, f.value = session, string(body)
+		}
+		json.NewEncoder(w).Encode(acquired)
+	default:
+		if f.holder == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode([]map[string]interface{}{{"Value": base64.StdEncoding.EncodeToString([]byte(f.value)), "ModifyIndex": 1}})
+	}
+}
+
+func TestConsul_Lock(t *testing.T) {
+	fake := &fakeConsul{sessions: make(map[string]bool)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	ctx := context.Background()
+
+	first := NewConsul(server.URL, "").Lock("proxwarden/leader", "pve1", 15*time.Second)
+	second := NewConsul(server.URL, "").Lock("proxwarden/leader", "pve2", 15*time.Second)
+
+	if held, err := first.Acquire(ctx); err != nil || !held {
+		t.Fatalf("Expected the first daemon to take the lock, got %v, %v", held, err)
+	}
+	if held, err := second.Acquire(ctx); err != nil || held {
+		t.Fatalf("Expected the second daemon to be refused, got %v, %v", held, err)
+	}
+	if held, err := first.Acquire(ctx); err != nil || !held {
+		t.Fatalf("Expected the first daemon to keep the lock, got %v, %v", held, err)
+	}
+	if holder, err := second.Holder(ctx); err != nil || holder != "pve1" {
+		t.Errorf("Expected pve1 to hold the lock, got %q, %v", holder, err)
+	}
+
+	if err := first.Release(ctx); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if held, err := second.Acquire(ctx); err != nil || !held {
+		t.Fatalf("Expected the second daemon to take the released lock, got %v, %v", held, err)
+	}
+
+	// An expired session is replaced
+	fake.mu.Lock()
+	fake.sessions = make(map[string]bool)
+	fake.holder = ""
+	fake.mu.Unlock()
+	if held, err := second.Acquire(ctx); err != nil || !held {
+		t.Fatalf("Expected the lock to be taken again after the session expired, got %v, %v", held, err)
+	}
+}
+
+// fakeEtcd implements the lease and transaction endpoints of the etcd JSON
+// gateway for one key, without expiry.
+type fakeEtcd struct {
+	mu     sync.Mutex
+	leases map[string]bool
+	value  string
+	lease  string
+	next   int
+}
+
+func (f *fakeEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var request map[string]json.RawMessage
+	json.NewDecoder(r.Body).Decode(&request)
+	var id string
+	json.Unmarshal(request["ID"], &id)
+
+	switch r.URL.Path {
+	case "/v3/lease/grant":
+		f.next++
+		id := fmt.Sprint(f.next)
+		f.leases[id] = true
+		json.NewEncoder(w).Encode(map[string]string{"ID": id, "TTL": "15"})
+	case "/v3/lease/keepalive":
+		ttl := "0"
+		if f.leases[id] {
+			ttl = "15"
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": map[string]string{"ID": id, "TTL": ttl}})
+	case "/v3/lease/revoke":
+		delete(f.leases, id)
+		if f.lease == id {
+			f.value, f.lease = "", ""
+		}
+		w.Write([]byte("{}"))
+	case "/v3/kv/txn":
+		var txn struct {
+			Success []struct {
+				RequestPut struct {
+					Value string `json:"value"`
+					Lease string `json:"lease"`
+				} `json:"request_put"`
+			} `json:"success"`
+		}
+		body, _ := json.Marshal(request)
+		json.Unmarshal(body, &txn)
+		if f.lease == "" {
+			put := txn.Success[0].RequestPut
+			value, _ := base64.StdEncoding.DecodeString(put.Value)
+			f.value, f.lease = string(value), put.Lease
+			json.NewEncoder(w).Encode(map[string]interface{}{"succeeded": true})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"responses": []map[string]interface{}{{"response_range": map[string]interface{}{
+				"kvs": []map[string]string{{"value": base64.StdEncoding.EncodeToString([]byte(f.value)), "lease": f.lease}},
+			}}},
+		})
+	case "/v3/kv/range":
+		if f.lease == "" {
+			w.Write([]byte("{}"))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"kvs": []map[string]string{{"value": base64.StdEncoding.EncodeToString([]byte(f.value)), "mod_revision": "1"}},
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestEtcd_Lock(t *testing.T) {
+	fake := &fakeEtcd{leases: make(map[string]bool)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	ctx := context.Background()
+
+	first := NewEtcd(server.URL, "", "").Lock("/proxwarden/leader", "pve1", 15*time.Second)
+	second := NewEtcd(server.URL, "", "").Lock("/proxwarden/leader", "pve2", 15*time.Second)
+
+	if held, err := first.Acquire(ctx); err != nil || !held {
+		t.Fatalf("Expected the first daemon to take the lock, got %v, %v", held, err)
+	}
+	if held, err := second.Acquire(ctx); err != nil || held {
+		t.Fatalf("Expected the second daemon to be refused, got %v, %v", held, err)
+	}
+	if held, err := first.Acquire(ctx); err != nil || !held {
+		t.Fatalf("Expected the first daemon to keep the lock, got %v, %v", held, err)
+	}
+	if holder, err := second.Holder(ctx); err != nil || holder != "pve1" {
+		t.Errorf("Expected pve1 to hold the lock, got %q, %v", holder, err)
+	}
+
+	// The lease of the first daemon expires
+	fake.mu.Lock()
+	delete(fake.leases, fake.lease)
+	fake.value, fake.lease = "", ""
+	fake.mu.Unlock()
+	if held, err := second.Acquire(ctx); err != nil || !held {
+		t.Fatalf("Expected the second daemon to take the expired lock, got %v, %v", held, err)
+	}
+	if held, err := first.Acquire(ctx); err != nil || held {
+		t.Fatalf("Expected the first daemon to have lost the lock, got %v, %v", held, err)
+	}
+
+	if err := second.Release(ctx); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if holder, err := first.Holder(ctx); err != nil || holder != "" {
+		t.Errorf("Expected the released lock to be free, got %q, %v", holder, err)
+	}
+}
@@ -0,0 +1,240 @@
+// Package maintenance tracks containers and nodes under planned maintenance,
+// during which health failures must not trigger failover.
+package maintenance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+type Kind string
+
+const (
+	KindContainer Kind = "container"
+	KindNode      Kind = "node"
+)
+
+// Window is a maintenance period for a single container or node. A zero
+// Expires means the window lasts until it is disabled.
+type Window struct {
+	Kind    Kind      `json:"kind"`
+	Target  string    `json:"target"`
+	Reason  string    `json:"reason,omitempty"`
+	Start   time.Time `json:"start"`
+	Expires time.Time `json:"expires,omitempty"`
+	// Configured windows come from the config file and are not persisted
+	Configured bool `json:"configured,omitempty"`
+}
+
+func (w Window) expired(now time.Time) bool {
+	return !w.Expires.IsZero() && !now.Before(w.Expires)
+}
+
+// Manager holds active maintenance windows. Windows enabled at runtime are
+// persisted to path, when set, so they survive daemon restarts.
+type Manager struct {
+	mu              sync.Mutex
+	windows         map[string]Window
+	defaultDuration time.Duration
+	path            string
+	now             func() time.Time
+}
+
+func NewManager(cfg *config.MaintenanceConfig, path string) (*Manager, error) {
+	m := &Manager{
+		windows:         make(map[string]Window),
+		defaultDuration: cfg.DefaultDuration,
+		path:            path,
+		now:             time.Now,
+	}
+
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+
+	// Configured windows never expire
+	for _, id := range cfg.Containers {
+		w := Window{Kind: KindContainer, Target: strconv.Itoa(id), Start: m.now(), Configured: true}
+		m.windows[key(w.Kind, w.Target)] = w
+	}
+	for _, node := range cfg.Nodes {
+		w := Window{Kind: KindNode, Target: node, Start: m.now(), Configured: true}
+		m.windows[key(w.Kind, w.Target)] = w
+	}
+
+	return m, nil
+}
+
+func key(kind Kind, target string) string {
+	return string(kind) + "/" + target
+}
+
+// Enable starts maintenance for a container or node. A zero duration uses
+// the configured default; a negative one never expires.
+func (m *Manager) Enable(kind Kind, target string, duration time.Duration, reason string) (Window, error) {
+	if kind != KindContainer && kind != KindNode {
+		return Window{}, fmt.Errorf("invalid maintenance kind: %s", kind)
+	}
+	if target == "" {
+		return Window{}, fmt.Errorf("maintenance target is required")
+	}
+
+	if duration == 0 {
+		duration = m.defaultDuration
+	}
+
+	now := m.now()
+	w := Window{Kind: kind, Target: target, Reason: reason, Start: now}
+	if duration > 0 {
+		w.Expires = now.Add(duration)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.windows[key(kind, target)] = w
+	return w, m.save()
+}
+
+// Disable ends maintenance and reports whether it was active.
+func (m *Manager) Disable(kind Kind, target string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := key(kind, target)
+	w, exists := m.windows[k]
+	if !exists || w.expired(m.now()) {
+		return false, nil
+	}
+
+	delete(m.windows, k)
+	return true, m.save()
+}
+
+// Active returns all unexpired windows sorted by kind and target.
+func (m *Manager) Active() []Window {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	var result []Window
+	for k, w := range m.windows {
+		if w.expired(now) {
+			delete(m.windows, k)
+			continue
+		}
+		result = append(result, w)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Kind != result[j].Kind {
+			return result[i].Kind < result[j].Kind
+		}
+		return result[i].Target < result[j].Target
+	})
+	return result
+}
+
+// Lookup returns the active window for a container or node.
+func (m *Manager) Lookup(kind Kind, target string) (Window, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w, exists := m.windows[key(kind, target)]
+	if !exists || w.expired(m.now()) {
+		return Window{}, false
+	}
+	return w, true
+}
+
+// Container returns the window covering a container, either directly or
+// through maintenance of the node it runs on.
+func (m *Manager) Container(containerID int, node string) (Window, bool) {
+	if m == nil {
+		return Window{}, false
+	}
+	if w, ok := m.Lookup(KindContainer, strconv.Itoa(containerID)); ok {
+		return w, true
+	}
+	if node == "" {
+		return Window{}, false
+	}
+	return m.Lookup(KindNode, node)
+}
+
+// Node returns the window covering a node.
+func (m *Manager) Node(node string) (Window, bool) {
+	if m == nil {
+		return Window{}, false
+	}
+	return m.Lookup(KindNode, node)
+}
+
+// save writes runtime windows to disk. Callers must hold mu.
+func (m *Manager) save() error {
+	if m.path == "" {
+		return nil
+	}
+
+	var windows []Window
+	for _, w := range m.windows {
+		if w.Configured {
+			continue
+		}
+		windows = append(windows, w)
+	}
+
+	data, err := json.MarshalIndent(windows, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode maintenance windows: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.path), 0o750); err != nil {
+		return fmt.Errorf("failed to create maintenance directory: %w", err)
+	}
+
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o640); err != nil {
+		return fmt.Errorf("failed to write maintenance windows: %w", err)
+	}
+	if err := os.Rename(tmp, m.path); err != nil {
+		return fmt.Errorf("failed to write maintenance windows: %w", err)
+	}
+	return nil
+}
+
+func (m *Manager) load() error {
+	if m.path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(m.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read maintenance windows: %w", err)
+	}
+
+	var windows []Window
+	if err := json.Unmarshal(data, &windows); err != nil {
+		return fmt.Errorf("failed to decode maintenance windows: %w", err)
+	}
+
+	now := m.now()
+	for _, w := range windows {
+		if !w.expired(now) {
+			m.windows[key(w.Kind, w.Target)] = w
+		}
+	}
+	return nil
+}
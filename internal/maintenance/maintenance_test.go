@@ -0,0 +1,135 @@
+package maintenance
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+func newTestManager(t *testing.T, cfg *config.MaintenanceConfig, path string, now *time.Time) *Manager {
+	t.Helper()
+	m, err := NewManager(cfg, path)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	m.now = func() time.Time { return *now }
+	return m
+}
+
+func TestManager_EnableExpiry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	m := newTestManager(t, &config.MaintenanceConfig{DefaultDuration: time.Hour}, "", &now)
+
+	tests := []struct {
+		name     string
+		target   string
+		duration time.Duration
+		expires  time.Time
+	}{
+		{name: "default duration", target: "100", duration: 0, expires: now.Add(time.Hour)},
+		{name: "explicit duration", target: "101", duration: 10 * time.Minute, expires: now.Add(10 * time.Minute)},
+		{name: "no expiry", target: "102", duration: -1, expires: time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := m.Enable(KindContainer, tt.target, tt.duration, "upgrade")
+			if err != nil {
+				t.Fatalf("Enable() error = %v", err)
+			}
+			if !w.Expires.Equal(tt.expires) {
+				t.Errorf("Expected expiry %v, got %v", tt.expires, w.Expires)
+			}
+		})
+	}
+
+	if got := len(m.Active()); got != 3 {
+		t.Fatalf("Expected 3 active windows, got %d", got)
+	}
+
+	now = now.Add(30 * time.Minute)
+	if _, ok := m.Container(101, ""); ok {
+		t.Error("Expected container 101 maintenance to have expired")
+	}
+	if _, ok := m.Container(100, ""); !ok {
+		t.Error("Expected container 100 to still be in maintenance")
+	}
+
+	now = now.Add(24 * time.Hour)
+	active := m.Active()
+	if len(active) != 1 || active[0].Target != "102" {
+		t.Errorf("Expected only the unbounded window to remain, got %v", active)
+	}
+}
+
+func TestManager_Enable_Invalid(t *testing.T) {
+	now := time.Now()
+	m := newTestManager(t, &config.MaintenanceConfig{}, "", &now)
+
+	if _, err := m.Enable("vm", "100", 0, ""); err == nil {
+		t.Error("Expected error for invalid kind")
+	}
+	if _, err := m.Enable(KindNode, "", 0, ""); err == nil {
+		t.Error("Expected error for empty target")
+	}
+}
+
+func TestManager_NodeCoversContainers(t *testing.T) {
+	now := time.Now()
+	m := newTestManager(t, &config.MaintenanceConfig{DefaultDuration: time.Hour}, "", &now)
+
+	if _, err := m.Enable(KindNode, "node1", 0, "kernel update"); err != nil {
+		t.Fatalf("Enable() error = %v", err)
+	}
+
+	if w, ok := m.Container(100, "node1"); !ok || w.Kind != KindNode {
+		t.Errorf("Expected container on node1 to be covered by node maintenance, got %v %v", w, ok)
+	}
+	if _, ok := m.Container(100, "node2"); ok {
+		t.Error("Expected container on node2 not to be in maintenance")
+	}
+
+	disabled, err := m.Disable(KindNode, "node1")
+	if err != nil || !disabled {
+		t.Fatalf("Disable() = %v, %v", disabled, err)
+	}
+	if _, ok := m.Node("node1"); ok {
+		t.Error("Expected node1 maintenance to be disabled")
+	}
+
+	disabled, err = m.Disable(KindNode, "node1")
+	if err != nil || disabled {
+		t.Errorf("Expected second Disable() to report nothing disabled, got %v, %v", disabled, err)
+	}
+}
+
+func TestManager_Persistence(t *testing.T) {
+	now := time.Now()
+	path := filepath.Join(t.TempDir(), "state", "maintenance.json")
+	cfg := &config.MaintenanceConfig{DefaultDuration: time.Hour, Nodes: []string{"node3"}}
+
+	m := newTestManager(t, cfg, path, &now)
+	if _, err := m.Enable(KindContainer, "100", 0, "upgrade"); err != nil {
+		t.Fatalf("Enable() error = %v", err)
+	}
+	if _, err := m.Enable(KindNode, "node1", -1, ""); err != nil {
+		t.Fatalf("Enable() error = %v", err)
+	}
+
+	// Configured windows are restored from config, not from the state file
+	reloaded := newTestManager(t, &config.MaintenanceConfig{}, path, &now)
+	active := reloaded.Active()
+	if len(active) != 2 {
+		t.Fatalf("Expected 2 persisted windows, got %v", active)
+	}
+	if w, ok := reloaded.Lookup(KindContainer, "100"); !ok || w.Reason != "upgrade" {
+		t.Errorf("Expected container 100 window with reason, got %v %v", w, ok)
+	}
+
+	configured := newTestManager(t, cfg, path, &now)
+	if w, ok := configured.Node("node3"); !ok || !w.Configured || !w.Expires.IsZero() {
+		t.Errorf("Expected configured node3 window without expiry, got %v %v", w, ok)
+	}
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	counter := NewCounter("test_requests_total", "Requests by method.", "method")
+	counter.Inc("GET")
+	counter.Add(2, "GET")
+	counter.Inc("POST")
+
+	histogram := NewHistogram("test_duration_seconds", "Durations.", []float64{0.1, 1}, "type")
+	histogram.Observe(0.05, "http")
+	histogram.Observe(0.5, "http")
+	histogram.Observe(5, "http")
+
+	var buf bytes.Buffer
+	Write(&buf)
+	WriteGauge(&buf, "test_up", "Whether targets are up.", []Sample{
+		{Labels: []string{"target", `a"b`}, Value: 1},
+	})
+
+	expected := []string{
+		"# HELP test_requests_total Requests by method.",
+		"# TYPE test_requests_total counter",
+		`test_requests_total{method="GET"} 3`,
+		`test_requests_total{method="POST"} 1`,
+		"# TYPE test_duration_seconds histogram",
+		`test_duration_seconds_bucket{type="http",le="0.1"} 1`,
+		`test_duration_seconds_bucket{type="http",le="1"} 2`,
+		`test_duration_seconds_bucket{type="http",le="+Inf"} 3`,
+		`test_duration_seconds_sum{type="http"} 5.55`,
+		`test_duration_seconds_count{type="http"} 3`,
+		"# TYPE test_up gauge",
+		`test_up{target="a\"b"} 1`,
+	}
+	output := buf.String()
+	for _, line := range expected {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("Expected output to contain %q, got:\n%s", line, output)
+		}
+	}
+
+	if got := counter.Value("GET"); got != 3 {
+		t.Errorf("Expected a GET count of 3, got %v", got)
+	}
+	if got := counter.Value("DELETE"); got != 0 {
+		t.Errorf("Expected a DELETE count of 0, got %v", got)
+	}
+}
@@ -0,0 +1,243 @@
+package monitor
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/health"
+	"github.com/sirupsen/logrus"
+)
+
+// containers returns the configured containers followed by the discovered
+// ones.
+func (m *Monitor) containers() []config.ContainerConfig {
+	m.discoveredMu.RLock()
+	defer m.discoveredMu.RUnlock()
+
+	containers := make([]config.ContainerConfig, 0, len(m.cfg().Monitoring.Containers)+len(m.discovered))
+	containers = append(containers, m.cfg().Monitoring.Containers...)
+	return append(containers, m.discovered...)
+}
+
+// ContainerConfig returns the configuration of a monitored container,
+// whether configured or discovered.
+func (m *Monitor) ContainerConfig(containerID int) (config.ContainerConfig, bool) {
+	for _, container := range m.containers() {
+		if container.ID == containerID {
+			return container, true
+		}
+	}
+	return config.ContainerConfig{}, false
+}
+
+// discoverContainers refreshes the discovered containers periodically.
+func (m *Monitor) discoverContainers(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg().Monitoring.Discover.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.discover(ctx)
+		}
+	}
+}
+
+// discover enrolls containers matching a containers entry by tag or pool,
+// or the discovery tags or pool, and drops ones that no longer match. On API
+// errors the current set is kept.
+func (m *Monitor) discover(ctx context.Context) {
+	monitoring := m.cfg().Monitoring
+	cfg := monitoring.Discover
+
+	resources, err := m.apiClient.GetClusterResources(ctx)
+	if err != nil {
+		m.logger.WithField("error", err).Error("Failed to discover containers")
+		return
+	}
+
+	configured := make(map[int]bool)
+	for _, container := range monitoring.Containers {
+		configured[container.ID] = true
+	}
+
+	var found []config.ContainerConfig
+	for _, resource := range resources {
+		if configured[resource.ID] {
+			continue
+		}
+		if selector, ok := matchingSelector(monitoring.Selectors, resource); ok {
+			found = append(found, selectedContainer(selector, resource))
+		} else if cfg.Enabled() && matchesDiscovery(cfg, resource) {
+			found = append(found, discoveredContainer(cfg, resource).Inherit(monitoring.Defaults))
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].ID < found[j].ID
+	})
+
+	m.setDiscovered(found, time.Now())
+}
+
+// How containers come to be monitored, as Enrollment reports.
+const (
+	EnrolledConfigured = "configured"
+	EnrolledSelector   = "selector"
+	EnrolledDiscovery  = "discovery"
+)
+
+// Enrollment returns how a guest is monitored under monitoring: listed in
+// the containers, selected by a containers entry matching tags or pool, or
+// found by discovery. It returns "" for guests that are not monitored,
+// which includes every virtual machine.
+func Enrollment(monitoring config.MonitoringConfig, resource api.ClusterResource) string {
+	_, enrollment := Enrolled(monitoring, resource)
+	return enrollment
+}
+
+// Enrolled returns the configuration a guest is monitored with under
+// monitoring, as the daemon would, along with its Enrollment.
+func Enrolled(monitoring config.MonitoringConfig, resource api.ClusterResource) (config.ContainerConfig, string) {
+	if resource.Type != "lxc" {
+		return config.ContainerConfig{}, ""
+	}
+	for _, container := range monitoring.Containers {
+		if container.ID == resource.ID {
+			return container, EnrolledConfigured
+		}
+	}
+	if selector, ok := matchingSelector(monitoring.Selectors, resource); ok {
+		return selectedContainer(selector, resource), EnrolledSelector
+	}
+	if monitoring.Discover.Enabled() && matchesDiscovery(monitoring.Discover, resource) {
+		return discoveredContainer(monitoring.Discover, resource).Inherit(monitoring.Defaults), EnrolledDiscovery
+	}
+	return config.ContainerConfig{}, ""
+}
+
+// matchesDiscovery reports whether a resource carries any discovery tag or
+// belongs to the discovery pool.
+func matchesDiscovery(cfg config.DiscoveryConfig, resource api.ClusterResource) bool {
+	if cfg.Pool != "" && resource.Pool == cfg.Pool {
+		return true
+	}
+	for _, tag := range resource.TagList() {
+		for _, wanted := range cfg.Tags {
+			if tag == wanted {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchingSelector returns the first containers entry selecting a resource
+// by tag or pool.
+func matchingSelector(selectors []config.ContainerConfig, resource api.ClusterResource) (config.ContainerConfig, bool) {
+	tags := resource.TagList()
+	for _, selector := range selectors {
+		if selector.Match.Matches(tags, resource.Pool) {
+			return selector, true
+		}
+	}
+	return config.ContainerConfig{}, false
+}
+
+// selectedContainer builds a container config from the containers entry
+// that selected it.
+func selectedContainer(selector config.ContainerConfig, resource api.ClusterResource) config.ContainerConfig {
+	container := selector
+	container.ID = resource.ID
+	container.Name = resource.Name
+	container.Match = nil
+	container.HealthChecks = checksFor(selector.HealthChecks, resource.Name)
+	return container
+}
+
+// discoveredContainer builds a container config from the discovery defaults.
+func discoveredContainer(cfg config.DiscoveryConfig, resource api.ClusterResource) config.ContainerConfig {
+	return config.ContainerConfig{
+		ID:            resource.ID,
+		Name:          resource.Name,
+		HealthChecks:  checksFor(cfg.HealthChecks, resource.Name),
+		Priority:      cfg.Priority,
+		FailoverNodes: cfg.FailoverNodes,
+		Storage:       cfg.Storage,
+	}
+}
+
+// checksFor copies default health checks for a container, using its name
+// as the target of checks without one.
+func checksFor(defaults []config.HealthCheck, name string) []config.HealthCheck {
+	checks := make([]config.HealthCheck, len(defaults))
+	copy(checks, defaults)
+	for i := range checks {
+		if checks[i].Target == "" {
+			checks[i].Target = name
+		}
+	}
+	return checks
+}
+
+// setDiscovered replaces the discovered containers, starting monitoring of
+// new ones and forgetting removed ones.
+func (m *Monitor) setDiscovered(containers []config.ContainerConfig, now time.Time) {
+	m.discoveredMu.Lock()
+	previous := make(map[int]bool)
+	for _, container := range m.discovered {
+		previous[container.ID] = true
+	}
+	m.discovered = containers
+	m.discoveredMu.Unlock()
+
+	var added []config.ContainerConfig
+	for _, container := range containers {
+		if previous[container.ID] {
+			delete(previous, container.ID)
+			continue
+		}
+		added = append(added, container)
+		m.initContainer(container, now)
+		m.logger.WithFields(logrus.Fields{
+			"container_id": container.ID,
+			"name":         container.Name,
+		}).Info("Discovered container, starting monitoring")
+	}
+	m.scheduler.schedule(added, now)
+
+	for id := range previous {
+		m.statesMu.Lock()
+		delete(m.states, id)
+		delete(m.histories, id)
+		m.statesMu.Unlock()
+		m.scheduler.remove(id)
+		m.logger.WithField("container_id", id).Info("Container no longer matches discovery, stopping monitoring")
+	}
+}
+
+// initContainer creates the state and check histories of a newly monitored
+// container.
+func (m *Monitor) initContainer(container config.ContainerConfig, now time.Time) {
+	m.statesMu.Lock()
+	defer m.statesMu.Unlock()
+
+	m.states[container.ID] = &ContainerState{
+		ID:            container.ID,
+		Name:          container.Name,
+		LastSeen:      now,
+		Status:        "unknown",
+		State:         StateUnknown,
+		HealthResults: make([]*health.CheckResult, 0),
+	}
+
+	histories := make([]*health.History, len(container.HealthChecks))
+	for i := range histories {
+		histories[i] = health.NewHistory(m.cfg().Monitoring.HistorySize)
+	}
+	m.histories[container.ID] = histories
+}
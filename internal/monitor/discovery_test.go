@@ -0,0 +1,216 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+func TestMatchesDiscovery(t *testing.T) {
+	cfg := config.DiscoveryConfig{Tags: []string{"proxwarden"}, Pool: "critical"}
+
+	tests := []struct {
+		name     string
+		resource api.ClusterResource
+		expected bool
+	}{
+		{name: "tagged", resource: api.ClusterResource{Tags: "web;proxwarden"}, expected: true},
+		{name: "in pool", resource: api.ClusterResource{Pool: "critical"}, expected: true},
+		{name: "other tag", resource: api.ClusterResource{Tags: "web"}, expected: false},
+		{name: "other pool", resource: api.ClusterResource{Pool: "batch"}, expected: false},
+		{name: "tag prefix only", resource: api.ClusterResource{Tags: "proxwarden-test"}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesDiscovery(cfg, tt.resource); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestEnrollment(t *testing.T) {
+	monitoring := config.MonitoringConfig{
+		Containers: []config.ContainerConfig{{ID: 100}},
+		Selectors:  []config.ContainerConfig{{Match: &config.ContainerMatch{Tag: "critical"}}},
+		Discover:   config.DiscoveryConfig{Pool: "lab", FailoverNodes: []string{"node4"}},
+	}
+
+	tests := []struct {
+		name     string
+		resource api.ClusterResource
+		expected string
+	}{
+		{name: "configured", resource: api.ClusterResource{ID: 100, Type: "lxc"}, expected: EnrolledConfigured},
+		{name: "selected", resource: api.ClusterResource{ID: 101, Type: "lxc", Tags: "critical", Pool: "lab"}, expected: EnrolledSelector},
+		{name: "discovered", resource: api.ClusterResource{ID: 102, Type: "lxc", Pool: "lab"}, expected: EnrolledDiscovery},
+		{name: "not monitored", resource: api.ClusterResource{ID: 103, Type: "lxc"}},
+		{name: "virtual machine", resource: api.ClusterResource{ID: 100, Type: "qemu", Pool: "lab"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Enrollment(monitoring, tt.resource); got != tt.expected {
+				t.Errorf("Enrollment() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	container, _ := Enrolled(monitoring, api.ClusterResource{ID: 102, Name: "scratch", Type: "lxc", Pool: "lab"})
+	if container.ID != 102 || len(container.FailoverNodes) != 1 || container.FailoverNodes[0] != "node4" {
+		t.Errorf("Enrolled() = %+v, want the discovery settings", container)
+	}
+}
+
+func TestMonitor_Discover(t *testing.T) {
+	cfg := &config.Config{
+		Monitoring: config.MonitoringConfig{
+			Containers: []config.ContainerConfig{
+				{ID: 100, Name: "static"},
+			},
+			Discover: config.DiscoveryConfig{
+				Tags: []string{"proxwarden"},
+				HealthChecks: []config.HealthCheck{
+					{Type: "tcp", Port: 80},
+					{Type: "ping", Target: "10.0.0.1"},
+				},
+				FailoverNodes: []string{"node2"},
+				Priority:      5,
+			},
+		},
+	}
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	client := &mockAPIClient{
+		resources: []api.ClusterResource{
+			{ID: 100, Name: "static", Tags: "proxwarden"},
+			{ID: 102, Name: "worker", Tags: "proxwarden"},
+			{ID: 101, Name: "web", Tags: "proxwarden;web"},
+			{ID: 103, Name: "untagged"},
+		},
+	}
+
+	monitor := New(cfg, client, logger)
+	ctx := context.Background()
+	monitor.discover(ctx)
+
+	containers := monitor.containers()
+	if len(containers) != 3 {
+		t.Fatalf("Expected static plus 2 discovered containers, got %v", containers)
+	}
+	if containers[1].ID != 101 || containers[2].ID != 102 {
+		t.Errorf("Expected discovered containers 101 and 102 after static ones, got %d and %d", containers[1].ID, containers[2].ID)
+	}
+
+	web, ok := monitor.ContainerConfig(101)
+	if !ok {
+		t.Fatal("Expected discovered container 101 to be looked up")
+	}
+	if web.HealthChecks[0].Target != "web" {
+		t.Errorf("Expected empty target to default to container name, got %q", web.HealthChecks[0].Target)
+	}
+	if web.HealthChecks[1].Target != "10.0.0.1" {
+		t.Errorf("Expected explicit target to be kept, got %q", web.HealthChecks[1].Target)
+	}
+	if web.Priority != 5 || len(web.FailoverNodes) != 1 {
+		t.Errorf("Expected discovery defaults, got %+v", web)
+	}
+	if cfg.Monitoring.Discover.HealthChecks[0].Target != "" {
+		t.Error("Expected discovery defaults not to be modified")
+	}
+	if _, exists := monitor.GetContainerState(101); !exists {
+		t.Error("Expected state for discovered container")
+	}
+
+	// A failed refresh keeps the current set
+	client.getError = errors.New("api down")
+	monitor.discover(ctx)
+	if got := len(monitor.containers()); got != 3 {
+		t.Errorf("Expected discovered containers to be kept on error, got %d", got)
+	}
+
+	// Removing the tag stops monitoring
+	client.getError = nil
+	client.resources = client.resources[:3]
+	client.resources[1].Tags = ""
+	monitor.discover(ctx)
+	if _, ok := monitor.ContainerConfig(102); ok {
+		t.Error("Expected untagged container 102 to be dropped")
+	}
+	if _, exists := monitor.GetContainerState(102); exists {
+		t.Error("Expected state of dropped container to be removed")
+	}
+	if _, ok := monitor.ContainerConfig(101); !ok {
+		t.Error("Expected container 101 to stay discovered")
+	}
+}
+
+func TestMonitor_DiscoverSelectors(t *testing.T) {
+	cfg := &config.Config{
+		Monitoring: config.MonitoringConfig{
+			Selectors: []config.ContainerConfig{
+				{
+					Match:         &config.ContainerMatch{Tag: "critical", Pool: "prod"},
+					HealthChecks:  []config.HealthCheck{{Type: "http", Port: 443}},
+					FailoverNodes: []string{"node3"},
+					Priority:      1,
+					Strategy:      config.StrategyMigrate,
+				},
+				{
+					Match:         &config.ContainerMatch{Tag: "critical"},
+					HealthChecks:  []config.HealthCheck{{Type: "tcp", Port: 22}},
+					FailoverNodes: []string{"node2"},
+				},
+			},
+			Discover: config.DiscoveryConfig{
+				Pool:          "lab",
+				HealthChecks:  []config.HealthCheck{{Type: "ping"}},
+				FailoverNodes: []string{"node4"},
+			},
+		},
+	}
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	client := &mockAPIClient{
+		resources: []api.ClusterResource{
+			{ID: 101, Name: "db", Tags: "critical", Pool: "prod"},
+			{ID: 102, Name: "cache", Tags: "critical", Pool: "lab"},
+			{ID: 103, Name: "scratch", Pool: "lab"},
+			{ID: 104, Name: "other", Pool: "prod"},
+		},
+	}
+
+	monitor := New(cfg, client, logger)
+	monitor.discover(context.Background())
+
+	tests := []struct {
+		id       int
+		target   string
+		node     string
+		strategy string
+	}{
+		{id: 101, target: "db", node: "node3", strategy: config.StrategyMigrate},
+		{id: 102, target: "cache", node: "node2"},
+		{id: 103, target: "scratch", node: "node4"},
+	}
+	for _, tt := range tests {
+		container, ok := monitor.ContainerConfig(tt.id)
+		if !ok {
+			t.Errorf("Expected container %d to be selected", tt.id)
+			continue
+		}
+		if container.HealthChecks[0].Target != tt.target || container.FailoverNodes[0] != tt.node || container.Strategy != tt.strategy || container.Match != nil {
+			t.Errorf("Container %d: expected target %s, node %s and strategy %q, got %+v", tt.id, tt.target, tt.node, tt.strategy, container)
+		}
+	}
+	if _, ok := monitor.ContainerConfig(104); ok {
+		t.Error("Expected container 104 without the tag not to be selected")
+	}
+}
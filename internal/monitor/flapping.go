@@ -0,0 +1,59 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// FlappingCallback is invoked when a container starts flapping (active is
+// true) or settles down again (active is false).
+type FlappingCallback func(containerID int, state *ContainerState, active bool)
+
+func (m *Monitor) AddFlappingCallback(callback FlappingCallback) {
+	m.flappingCallbacks = append(m.flappingCallbacks, callback)
+}
+
+// updateFlapping records a healthy/unhealthy transition, if there was one,
+// and re-evaluates whether the container is flapping. Flapping starts when
+// more than the threshold of transitions fall within the window and ends
+// once a full window passes without any. Callers must hold statesMu.
+func (m *Monitor) updateFlapping(state *ContainerState, transition bool, now time.Time) {
+	cfg := m.cfg().Monitoring.Flapping
+	if !cfg.Enabled {
+		return
+	}
+
+	if transition {
+		state.Transitions = append(state.Transitions, now)
+	}
+
+	cutoff := now.Add(-cfg.Window)
+	var kept []time.Time
+	for _, at := range state.Transitions {
+		if at.After(cutoff) {
+			kept = append(kept, at)
+		}
+	}
+	state.Transitions = kept
+
+	switch {
+	case !state.Flapping && len(state.Transitions) > cfg.Threshold:
+		state.Flapping = true
+		m.logger.WithFields(logrus.Fields{
+			"container_id": state.ID,
+			"transitions":  len(state.Transitions),
+			"window":       cfg.Window,
+		}).Warn("Container is flapping, suppressing automatic failover")
+	case state.Flapping && len(state.Transitions) == 0:
+		state.Flapping = false
+		m.logger.WithField("container_id", state.ID).Info("Container stopped flapping")
+	default:
+		return
+	}
+
+	stateCopy := *state
+	for _, callback := range m.flappingCallbacks {
+		go callback(state.ID, &stateCopy, stateCopy.Flapping)
+	}
+}
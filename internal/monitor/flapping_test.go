@@ -0,0 +1,103 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+func TestMonitor_UpdateFlapping(t *testing.T) {
+	cfg := &config.Config{
+		Monitoring: config.MonitoringConfig{
+			Flapping: config.FlappingConfig{Enabled: true, Threshold: 2, Window: time.Minute},
+		},
+	}
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	monitor := New(cfg, nil, logger)
+	changes := make(chan bool, 4)
+	monitor.AddFlappingCallback(func(containerID int, state *ContainerState, active bool) {
+		changes <- active
+	})
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	state := &ContainerState{ID: 100}
+
+	tests := []struct {
+		name       string
+		transition bool
+		at         time.Duration
+		flapping   bool
+	}{
+		{name: "first transition", transition: true, at: 0, flapping: false},
+		{name: "at threshold", transition: true, at: 10 * time.Second, flapping: false},
+		{name: "above threshold", transition: true, at: 20 * time.Second, flapping: true},
+		{name: "old transitions pruned", transition: false, at: 65 * time.Second, flapping: true},
+		{name: "window without transitions", transition: false, at: 81 * time.Second, flapping: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monitor.updateFlapping(state, tt.transition, start.Add(tt.at))
+			if state.Flapping != tt.flapping {
+				t.Errorf("Expected flapping %v, got %v (transitions %d)", tt.flapping, state.Flapping, len(state.Transitions))
+			}
+		})
+	}
+
+	for _, expected := range []bool{true, false} {
+		select {
+		case active := <-changes:
+			if active != expected {
+				t.Errorf("Expected flapping callback with active=%v, got %v", expected, active)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected flapping callback with active=%v", expected)
+		}
+	}
+}
+
+func TestMonitor_FlappingSuppressesFailover(t *testing.T) {
+	cfg := &config.Config{
+		Monitoring: config.MonitoringConfig{
+			FailureThreshold: 1,
+			HealthyThreshold: 1,
+			Flapping:         config.FlappingConfig{Enabled: true, Threshold: 3, Window: time.Hour},
+		},
+	}
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	monitor := New(cfg, nil, logger)
+	failures := make(chan int, 8)
+	monitor.AddFailureCallback(func(containerID int, state *ContainerState) {
+		failures <- containerID
+	})
+
+	state := &ContainerState{ID: 100}
+
+	// fail, recover, fail, recover: four transitions, one more than allowed
+	monitor.recordFailure(state)
+	monitor.recordSuccess(state)
+	monitor.recordFailure(state)
+	monitor.recordSuccess(state)
+	if !state.Flapping {
+		t.Fatalf("Expected container to be flapping after %d transitions", len(state.Transitions))
+	}
+
+	// Drop callbacks from the failures before flapping was detected
+	time.Sleep(20 * time.Millisecond)
+	for len(failures) > 0 {
+		<-failures
+	}
+
+	monitor.recordFailure(state)
+	select {
+	case id := <-failures:
+		t.Errorf("Unexpected failover callback for flapping container %d", id)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
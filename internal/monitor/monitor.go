@@ -2,12 +2,15 @@ package monitor
 
 import (
 	"context"
+	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jbutlerdev/proxwarden/internal/api"
 	"github.com/jbutlerdev/proxwarden/internal/config"
 	"github.com/jbutlerdev/proxwarden/internal/health"
+	"github.com/jbutlerdev/proxwarden/internal/maintenance"
 	"github.com/sirupsen/logrus"
 )
 
@@ -21,86 +24,253 @@ type ContainerState struct {
 	Status          string
 	LastHealthCheck time.Time
 	HealthResults   []*health.CheckResult
+
+	// State is the container's lifecycle state as seen by the monitor.
+	// Status above is the status reported by Proxmox, such as "running".
+	State State
+	// ConsecutiveSuccesses counts healthy checks since the last failure
+	ConsecutiveSuccesses int
+	// ActiveWarnings counts warning-severity checks failing in the last run
+	ActiveWarnings int
+	// CheckStats holds per-check statistics over the recent result history,
+	// in the same order as the container's configured health checks
+	CheckStats []health.HistoryStats
+	// SkippedReason is set while health checks are skipped because a
+	// container this one depends on is down
+	SkippedReason string
+	// Transitions holds the times of recent changes between healthy and
+	// unhealthy, within the flap detection window
+	Transitions []time.Time
+	// Flapping is set while the container changes state too often for
+	// automatic failover to be trusted
+	Flapping bool
+	// RecentFailures holds the times of failures within the failure window,
+	// when one is configured
+	RecentFailures []time.Time
+	// HealthySince is when the container last became healthy; zero while
+	// it is in any other state
+	HealthySince time.Time
+	// BackedOff is set while the container's checks run on the adaptive
+	// backed-off interval
+	BackedOff bool
 }
 
 type Monitor struct {
-	config     *config.Config
-	apiClient  *api.Client
-	checker    *health.Checker
-	logger     *logrus.Logger
-	states     map[int]*ContainerState
-	statesMu   sync.RWMutex
-	callbacks  []FailureCallback
+	// current is the configuration, replaced as a whole on reload
+	current          atomic.Pointer[config.Config]
+	apiClient        api.ProxmoxClient
+	checker          *health.Checker
+	logger           *logrus.Logger
+	states           map[int]*ContainerState
+	statesMu         sync.RWMutex
+	callbacks        []FailureCallback
+	warningCallbacks []WarningCallback
+	histories        map[int][]*health.History
+	scheduler        *scheduler
+	triggers         chan int
+	nodes            map[string]*NodeState
+	nodeCallbacks    []NodeFailureCallback
+	maintenance      *maintenance.Manager
+
+	flappingCallbacks []FlappingCallback
+
+	// discovered holds containers enrolled through tag or pool discovery
+	discovered   []config.ContainerConfig
+	discoveredMu sync.RWMutex
+
+	// quorumLost is set while too few witnesses are reachable; guarded by
+	// statesMu
+	quorumLost      bool
+	quorumCallbacks []QuorumCallback
+
+	transitionCallbacks []TransitionCallback
+
+	// pause is guarded by statesMu
+	pause PauseState
+
+	subscribers subscribers
+
+	// running is set while the monitoring loop runs
+	running atomic.Bool
 }
 
+// triggerQueueSize bounds pending out-of-schedule checks; further triggers
+// are dropped and left to the regular schedule.
+const triggerQueueSize = 64
+
 type FailureCallback func(containerID int, state *ContainerState)
 
-func New(cfg *config.Config, apiClient *api.Client, logger *logrus.Logger) *Monitor {
-	return &Monitor{
-		config:    cfg,
+// WarningCallback is invoked when a warning-severity check starts failing
+// (active is true) or recovers (active is false).
+type WarningCallback func(containerID int, state *ContainerState, result *health.CheckResult, active bool)
+
+func New(cfg *config.Config, apiClient api.ProxmoxClient, logger *logrus.Logger) *Monitor {
+	checker := health.NewChecker(logger)
+	checker.SetProxy(cfg.Monitoring.Proxy)
+	if apiClient != nil {
+		checker.SetMetricsSource(apiClient)
+	}
+
+	m := &Monitor{
 		apiClient: apiClient,
-		checker:   health.NewChecker(logger),
+		checker:   checker,
 		logger:    logger,
 		states:    make(map[int]*ContainerState),
 		callbacks: make([]FailureCallback, 0),
+		histories: make(map[int][]*health.History),
+		scheduler: newScheduler(cfg.Monitoring),
+		triggers:  make(chan int, triggerQueueSize),
+		nodes:     make(map[string]*NodeState),
 	}
+	m.current.Store(cfg)
+	return m
+}
+
+// cfg returns the current configuration.
+func (m *Monitor) cfg() *config.Config {
+	return m.current.Load()
+}
+
+// SetMaintenance sets the maintenance windows that suppress failover.
+func (m *Monitor) SetMaintenance(manager *maintenance.Manager) {
+	m.maintenance = manager
 }
 
 func (m *Monitor) AddFailureCallback(callback FailureCallback) {
 	m.callbacks = append(m.callbacks, callback)
 }
 
+func (m *Monitor) AddWarningCallback(callback WarningCallback) {
+	m.warningCallbacks = append(m.warningCallbacks, callback)
+}
+
 func (m *Monitor) Start(ctx context.Context) error {
 	m.logger.Info("Starting container monitoring")
 
 	// Initialize container states
-	for _, container := range m.config.Monitoring.Containers {
-		m.statesMu.Lock()
-		m.states[container.ID] = &ContainerState{
-			ID:              container.ID,
-			Name:            container.Name,
-			LastSeen:        time.Now(),
-			FailureCount:    0,
-			HealthyCount:    0,
-			Status:          "unknown",
-			LastHealthCheck: time.Time{},
-			HealthResults:   make([]*health.CheckResult, 0),
-		}
-		m.statesMu.Unlock()
+	for _, container := range m.cfg().Monitoring.Containers {
+		m.initContainer(container, time.Now())
+	}
+	m.scheduler.schedule(m.cfg().Monitoring.Containers, time.Now())
+
+	if m.cfg().Monitoring.Discovers() {
+		m.discover(ctx)
+		go m.discoverContainers(ctx)
+	}
+
+	if m.cfg().Monitoring.Nodes.Enabled {
+		go m.monitorNodes(ctx)
+	}
+
+	if m.witnessesEnabled() {
+		m.checkWitnesses(ctx)
+		go m.monitorWitnesses(ctx)
 	}
 
-	ticker := time.NewTicker(m.config.Monitoring.Interval)
-	defer ticker.Stop()
+	m.running.Store(true)
+	defer m.running.Store(false)
 
 	for {
+		wait := m.cfg().Monitoring.Interval
+		if next := m.scheduler.nextDue(); !next.IsZero() {
+			wait = time.Until(next)
+		}
+
+		timer := time.NewTimer(wait)
 		select {
 		case <-ctx.Done():
+			timer.Stop()
 			m.logger.Info("Stopping container monitoring")
 			return ctx.Err()
-		case <-ticker.C:
-			m.checkAllContainers(ctx)
+		case <-timer.C:
+			m.checkDueContainers(ctx, time.Now())
+		case containerID := <-m.triggers:
+			timer.Stop()
+			m.checkTriggered(ctx, containerID)
+		}
+	}
+}
+
+// Running reports whether the monitoring loop is running.
+func (m *Monitor) Running() bool {
+	return m.running.Load()
+}
+
+// Trigger requests an immediate run of all health checks of a monitored
+// container, outside its regular schedule.
+func (m *Monitor) Trigger(containerID int) {
+	select {
+	case m.triggers <- containerID:
+	default:
+		m.logger.WithField("container_id", containerID).Debug("Trigger queue full, leaving check to schedule")
+	}
+}
+
+// TriggerNode triggers checks of every monitored container last seen on node.
+func (m *Monitor) TriggerNode(node string) {
+	m.statesMu.RLock()
+	var containerIDs []int
+	for id, state := range m.states {
+		if state.Node == node {
+			containerIDs = append(containerIDs, id)
+		}
+	}
+	m.statesMu.RUnlock()
+
+	for _, id := range containerIDs {
+		m.Trigger(id)
+	}
+}
+
+func (m *Monitor) checkTriggered(ctx context.Context, containerID int) {
+	for _, container := range m.containers() {
+		if container.ID != containerID {
+			continue
+		}
+
+		due := make([]int, len(container.HealthChecks))
+		for i := range due {
+			due[i] = i
 		}
+
+		m.logger.WithField("container_id", containerID).Debug("Running triggered health checks")
+		m.checkContainer(ctx, container, due)
+		return
 	}
 }
 
-func (m *Monitor) checkAllContainers(ctx context.Context) {
+// checkDueContainers evaluates every container that has at least one health
+// check due at now.
+func (m *Monitor) checkDueContainers(ctx context.Context, now time.Time) {
 	var wg sync.WaitGroup
 
-	for _, container := range m.config.Monitoring.Containers {
+	for _, container := range m.containers() {
+		due, run := m.scheduler.due(container, now)
+		if !run {
+			continue
+		}
+
 		wg.Add(1)
-		go func(container config.ContainerConfig) {
+		go func(container config.ContainerConfig, due []int) {
 			defer wg.Done()
-			m.checkContainer(ctx, container)
-		}(container)
+			m.checkContainer(ctx, container, due)
+		}(container, due)
 	}
 
 	wg.Wait()
 }
 
-func (m *Monitor) checkContainer(ctx context.Context, container config.ContainerConfig) {
+// checkContainer refreshes the container's Proxmox status and runs the health
+// checks at the given indexes. Checks that are not due keep their last result.
+func (m *Monitor) checkContainer(ctx context.Context, container config.ContainerConfig, due []int) {
 	m.statesMu.Lock()
 	state := m.states[container.ID]
 	m.statesMu.Unlock()
+	// A reload removed the container since its check was scheduled
+	if state == nil {
+		return
+	}
+	defer m.adaptInterval(container, state)
 
 	// Update container info from Proxmox
 	containerInfo, err := m.apiClient.GetContainer(ctx, container.ID)
@@ -109,7 +279,9 @@ func (m *Monitor) checkContainer(ctx context.Context, container config.Container
 			"container_id": container.ID,
 			"error":        err,
 		}).Error("Failed to get container info from Proxmox")
-		m.recordFailure(state)
+		if m.monitored(state) {
+			m.recordFailure(state)
+		}
 		return
 	}
 
@@ -128,86 +300,434 @@ func (m *Monitor) checkContainer(ctx context.Context, container config.Container
 		return
 	}
 
-	// Run health checks
-	allHealthy := true
-	var results []*health.CheckResult
-
-	for _, healthCheck := range container.HealthChecks {
-		result := m.checker.RunHealthCheck(ctx, healthCheck)
-		results = append(results, result)
+	// A down dependency makes this container's health unknown; failing it
+	// could fail over the wrong container.
+	if dep, down := m.dependencyDown(container.DependsOn); down {
+		reason := fmt.Sprintf("dependency container %d is down", dep)
+		m.statesMu.Lock()
+		changed := state.SkippedReason != reason
+		state.SkippedReason = reason
+		m.statesMu.Unlock()
 
-		if !result.Success {
-			allHealthy = false
+		if changed {
 			m.logger.WithFields(logrus.Fields{
 				"container_id": container.ID,
-				"check_type":   result.Type,
-				"target":       result.Target,
-				"error":        result.Error,
-			}).Warn("Health check failed")
+				"dependency":   dep,
+			}).Warn("Dependency down, skipping health checks")
+		}
+		return
+	}
+
+	m.statesMu.Lock()
+	state.SkippedReason = ""
+	previous := state.HealthResults
+	m.statesMu.Unlock()
+
+	// Run due health checks
+	freshFailure := false
+	fresh := make([]*health.CheckResult, len(container.HealthChecks))
+	for _, i := range due {
+		healthCheck := container.HealthChecks[i]
+		if dep, down := m.dependencyDown(healthCheck.DependsOn); down {
+			severity := config.SeverityCritical
+			if healthCheck.IsWarning() {
+				severity = config.SeverityWarning
+			}
+			fresh[i] = &health.CheckResult{
+				Type:      healthCheck.Type,
+				Target:    healthCheck.Target,
+				Timestamp: time.Now(),
+				Severity:  severity,
+				Skipped:   true,
+				Error:     fmt.Errorf("skipped: dependency container %d is down", dep),
+			}
+			continue
+		}
+
+		result := m.checker.RunContainerCheck(ctx, health.Container{ID: container.ID, Node: containerInfo.Node}, healthCheck)
+		fresh[i] = result
+		m.publish(Event{
+			Type:        EventCheckResult,
+			ContainerID: container.ID,
+			Node:        containerInfo.Node,
+			Timestamp:   result.Timestamp,
+			Result:      result,
+		})
+
+		if result.Success {
+			continue
+		}
+
+		fields := logrus.Fields{
+			"container_id": container.ID,
+			"check_type":   result.Type,
+			"target":       result.Target,
+			"duration":     result.Duration,
+			"severity":     result.Severity,
+			"error":        result.Error,
+		}
+
+		// Warning checks alert but never count toward the failover threshold
+		if healthCheck.IsWarning() {
+			m.logger.WithFields(fields).Warn("Warning-level health check failed")
+			continue
+		}
+
+		freshFailure = true
+		message := "Health check failed"
+		if result.Degraded {
+			message = "Health check exceeded latency budget"
+		}
+		m.logger.WithFields(fields).Warn(message)
+	}
+
+	// Merge fresh results with the last result of checks that were not due
+	allHealthy := true
+	skipped := false
+	activeWarnings := 0
+	results := make([]*health.CheckResult, len(container.HealthChecks))
+	for i, healthCheck := range container.HealthChecks {
+		results[i] = fresh[i]
+		if results[i] == nil && i < len(previous) {
+			results[i] = previous[i]
+		}
+		if results[i] == nil || results[i].Success {
+			continue
+		}
+		if results[i].Skipped {
+			skipped = skipped || !healthCheck.IsWarning()
+			continue
+		}
+		if healthCheck.IsWarning() {
+			activeWarnings++
+		} else {
+			allHealthy = false
 		}
 	}
 
 	m.statesMu.Lock()
 	state.LastHealthCheck = time.Now()
 	state.HealthResults = results
+	state.ActiveWarnings = activeWarnings
+	state.CheckStats = m.recordHistory(container.ID, fresh)
+	m.evaluateState(state)
 	m.statesMu.Unlock()
 
-	if allHealthy {
+	m.notifyWarnings(state, previous, fresh)
+
+	// Only fresh failures count toward the threshold, and a stale failure of a
+	// slower check holds off recovery until that check passes again. Skipped
+	// critical checks leave the container's health unknown.
+	switch {
+	case freshFailure && m.monitored(state):
+		m.recordFailure(state)
+	case allHealthy && !skipped:
 		m.recordSuccess(state)
+	}
+}
+
+// monitored reports whether state is still that of a monitored container,
+// rather than one a reload removed while its check ran.
+func (m *Monitor) monitored(state *ContainerState) bool {
+	if state == nil {
+		return false
+	}
+	m.statesMu.Lock()
+	defer m.statesMu.Unlock()
+	return m.states[state.ID] == state
+}
+
+// adaptInterval backs the container's checks off once it has been healthy
+// for the configured time, and restores the normal interval as soon as it
+// is not.
+func (m *Monitor) adaptInterval(container config.ContainerConfig, state *ContainerState) {
+	adaptive := m.cfg().Monitoring.AdaptiveInterval
+	if !adaptive.Enabled() {
+		return
+	}
+
+	now := time.Now()
+	// A container removed by a reload is no longer scheduled
+	if !m.monitored(state) {
+		return
+	}
+	m.statesMu.Lock()
+	backOff := state.State == StateHealthy && state.Status == "running" && state.SkippedReason == "" &&
+		!state.HealthySince.IsZero() && now.Sub(state.HealthySince) >= adaptive.HealthyFor
+	state.BackedOff = backOff
+	m.statesMu.Unlock()
+
+	if !m.scheduler.setBackedOff(container, backOff, now) {
+		return
+	}
+
+	if backOff {
+		m.logger.WithFields(logrus.Fields{
+			"container_id": container.ID,
+			"interval":     adaptive.Interval,
+		}).Info("Container stable, backing off check interval")
 	} else {
-		m.recordFailure(state)
+		m.logger.WithField("container_id", container.ID).Info("Restoring normal check interval")
+	}
+}
+
+// dependencyDown returns the first of the given containers that is down: not
+// running, or failing its own health checks.
+func (m *Monitor) dependencyDown(containerIDs []int) (int, bool) {
+	m.statesMu.RLock()
+	defer m.statesMu.RUnlock()
+
+	for _, id := range containerIDs {
+		state, exists := m.states[id]
+		if !exists {
+			continue
+		}
+		stopped := state.Status != "" && state.Status != "unknown" && state.Status != "running"
+		if stopped || state.FailureCount > 0 || state.SkippedReason != "" {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// notifyWarnings fires warning callbacks only when a warning check changes
+// between passing and failing, so a persistent warning alerts once.
+func (m *Monitor) notifyWarnings(state *ContainerState, previous, results []*health.CheckResult) {
+	for i, result := range results {
+		if result == nil || result.Skipped || result.Severity != config.SeverityWarning {
+			continue
+		}
+
+		wasFailing := i < len(previous) && previous[i] != nil && !previous[i].Success && !previous[i].Skipped
+		if result.Success == !wasFailing {
+			continue
+		}
+
+		for _, callback := range m.warningCallbacks {
+			go callback(state.ID, state, result, !result.Success)
+		}
 	}
 }
 
 func (m *Monitor) recordSuccess(state *ContainerState) {
 	m.statesMu.Lock()
 	defer m.statesMu.Unlock()
+	defer m.evaluateState(state)
+
+	// A success right after a failure is a transition back to healthy
+	m.updateFlapping(state, state.FailureCount > 0 && state.ConsecutiveSuccesses == 0, time.Now())
 
 	state.HealthyCount++
-	if state.FailureCount > 0 {
+	state.ConsecutiveSuccesses++
+	if state.FailureCount == 0 {
+		return
+	}
+
+	threshold := m.healthyThreshold(state.ID)
+	if state.ConsecutiveSuccesses < threshold {
 		m.logger.WithFields(logrus.Fields{
-			"container_id":   state.ID,
-			"failure_count":  state.FailureCount,
-			"healthy_count":  state.HealthyCount,
-		}).Info("Container health recovered")
-		state.FailureCount = 0
+			"container_id":          state.ID,
+			"failure_count":         state.FailureCount,
+			"consecutive_successes": state.ConsecutiveSuccesses,
+			"healthy_threshold":     threshold,
+		}).Debug("Container healthy, waiting for recovery threshold")
+		return
 	}
+
+	m.logger.WithFields(logrus.Fields{
+		"container_id":  state.ID,
+		"failure_count": state.FailureCount,
+		"healthy_count": state.HealthyCount,
+	}).Info("Container health recovered")
+	state.FailureCount = 0
 }
 
 func (m *Monitor) recordFailure(state *ContainerState) {
+	if state == nil {
+		return
+	}
 	m.statesMu.Lock()
+	// Without quorum the failure is more likely ours than the container's
+	if m.quorumLost {
+		m.statesMu.Unlock()
+		m.logger.WithField("container_id", state.ID).Warn("Health check failed while witnesses are unreachable, not counting failure")
+		return
+	}
+	if m.inBlackout(state.ID, time.Now()) {
+		m.statesMu.Unlock()
+		m.logger.WithField("container_id", state.ID).Info("Health check failed during blackout window, not counting failure")
+		return
+	}
+	m.updateFlapping(state, state.FailureCount == 0 || state.ConsecutiveSuccesses > 0, time.Now())
 	state.FailureCount++
+	state.ConsecutiveSuccesses = 0
 	failureCount := state.FailureCount
+	if window := m.failureWindow(state.ID); window > 0 {
+		failureCount = recordRecentFailure(state, window, time.Now())
+	}
+	m.evaluateState(state)
+	onDownNode := m.nodeDown(state.Node)
+	flapping := state.Flapping
+	paused := m.pause.Paused
+	node := state.Node
+	// Callbacks get a copy, as the state keeps changing under statesMu
+	stateCopy := *state
+	stateCopy.RecentFailures = append([]time.Time(nil), state.RecentFailures...)
 	m.statesMu.Unlock()
 
+	threshold := m.failureThreshold(state.ID)
 	m.logger.WithFields(logrus.Fields{
 		"container_id":  state.ID,
 		"failure_count": failureCount,
-		"threshold":     m.config.Monitoring.FailureThreshold,
+		"threshold":     threshold,
 	}).Warn("Container health check failed")
 
-	if failureCount >= m.config.Monitoring.FailureThreshold {
+	// Containers on a down node are failed over together by the node handler
+	if onDownNode {
+		return
+	}
+
+	if failureCount >= threshold {
+		if window, ok := m.maintenance.Container(state.ID, node); ok {
+			m.logger.WithFields(logrus.Fields{
+				"container_id":  state.ID,
+				"failure_count": failureCount,
+				"maintenance":   window.Kind,
+				"expires":       window.Expires,
+			}).Warn("Container failure threshold reached during maintenance, not failing over")
+			return
+		}
+
+		if flapping {
+			m.logger.WithFields(logrus.Fields{
+				"container_id":  state.ID,
+				"failure_count": failureCount,
+			}).Warn("Container failure threshold reached while flapping, not failing over")
+			return
+		}
+
+		if paused {
+			m.logger.WithFields(logrus.Fields{
+				"container_id":  state.ID,
+				"failure_count": failureCount,
+			}).Warn("Container failure threshold reached while failover is paused, not failing over")
+			return
+		}
+
+		// Confirm our own connectivity right before acting on the failure
+		if m.witnessesEnabled() && m.checkWitnesses(context.Background()) {
+			return
+		}
+
 		m.logger.WithFields(logrus.Fields{
 			"container_id":  state.ID,
 			"failure_count": failureCount,
 		}).Error("Container failure threshold reached")
 
+		m.publish(Event{Type: EventFailoverTriggered, ContainerID: state.ID, Node: node})
+
 		// Trigger callbacks
 		for _, callback := range m.callbacks {
-			go callback(state.ID, state)
+			go callback(state.ID, &stateCopy)
+		}
+	}
+}
+
+// recordHistory appends results to the per-check histories and returns fresh
+// statistics. Nil and skipped results belong to checks that did not run. Callers must
+// hold statesMu.
+func (m *Monitor) recordHistory(containerID int, results []*health.CheckResult) []health.HistoryStats {
+	histories := m.histories[containerID]
+	if len(histories) != len(results) {
+		histories = make([]*health.History, len(results))
+		for i := range histories {
+			histories[i] = health.NewHistory(m.cfg().Monitoring.HistorySize)
+		}
+		m.histories[containerID] = histories
+	}
+
+	stats := make([]health.HistoryStats, len(results))
+	for i, result := range results {
+		if result != nil && !result.Skipped {
+			histories[i].Add(result)
+		}
+		stats[i] = histories[i].Stats()
+	}
+	return stats
+}
+
+// failureThreshold returns the number of consecutive failed checks that
+// trigger failover of the container.
+func (m *Monitor) failureThreshold(containerID int) int {
+	if container, ok := m.ContainerConfig(containerID); ok && container.FailureThreshold > 0 {
+		return container.FailureThreshold
+	}
+	return m.cfg().Monitoring.FailureThreshold
+}
+
+// inBlackout reports whether one of the container's blackout windows is
+// active at now.
+func (m *Monitor) inBlackout(containerID int, now time.Time) bool {
+	container, ok := m.ContainerConfig(containerID)
+	if !ok {
+		return false
+	}
+	for _, window := range container.BlackoutWindows {
+		if window.Active(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// failureWindow returns the window failures are counted in, or zero when
+// consecutive failures count.
+func (m *Monitor) failureWindow(containerID int) time.Duration {
+	if container, ok := m.ContainerConfig(containerID); ok && container.FailureWindow > 0 {
+		return container.FailureWindow
+	}
+	return m.cfg().Monitoring.FailureWindow
+}
+
+// recordRecentFailure adds a failure at now, drops failures older than the
+// window and returns how many remain. Callers must hold statesMu.
+func recordRecentFailure(state *ContainerState, window time.Duration, now time.Time) int {
+	var recent []time.Time
+	for _, at := range state.RecentFailures {
+		if now.Sub(at) < window {
+			recent = append(recent, at)
+		}
+	}
+	state.RecentFailures = append(recent, now)
+	return len(state.RecentFailures)
+}
+
+// healthyThreshold returns the number of consecutive successful checks required
+// before a failing container counts as recovered.
+func (m *Monitor) healthyThreshold(containerID int) int {
+	threshold := m.cfg().Monitoring.HealthyThreshold
+	for _, container := range m.containers() {
+		if container.ID == containerID && container.HealthyThreshold > 0 {
+			threshold = container.HealthyThreshold
+			break
 		}
 	}
+
+	if threshold < 1 {
+		return 1
+	}
+	return threshold
 }
 
 func (m *Monitor) GetContainerState(containerID int) (*ContainerState, bool) {
 	m.statesMu.RLock()
 	defer m.statesMu.RUnlock()
-	
+
 	state, exists := m.states[containerID]
 	if !exists {
 		return nil, false
 	}
-	
+
 	// Return a copy to avoid race conditions
 	stateCopy := *state
 	return &stateCopy, true
@@ -216,12 +736,12 @@ func (m *Monitor) GetContainerState(containerID int) (*ContainerState, bool) {
 func (m *Monitor) GetAllStates() map[int]*ContainerState {
 	m.statesMu.RLock()
 	defer m.statesMu.RUnlock()
-	
+
 	result := make(map[int]*ContainerState)
 	for id, state := range m.states {
 		stateCopy := *state
 		result[id] = &stateCopy
 	}
-	
+
 	return result
-}
\ No newline at end of file
+}
@@ -7,13 +7,17 @@ import (
 
 	"github.com/jbutlerdev/proxwarden/internal/api"
 	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/health"
+	"github.com/jbutlerdev/proxwarden/internal/maintenance"
 	"github.com/sirupsen/logrus"
 )
 
 // Mock API client for testing
 type mockAPIClient struct {
 	containers map[int]*api.ContainerInfo
+	metrics    map[int]*api.ContainerMetrics
 	nodes      []*api.NodeInfo
+	resources  []api.ClusterResource
 	getError   error
 }
 
@@ -27,6 +31,21 @@ func (m *mockAPIClient) GetContainer(ctx context.Context, containerID int) (*api
 	return nil, api.ErrContainerNotFound
 }
 
+func (m *mockAPIClient) GetContainerMetrics(ctx context.Context, node string, containerID int) (*api.ContainerMetrics, error) {
+	if metrics, exists := m.metrics[containerID]; exists {
+		return metrics, nil
+	}
+	return nil, api.ErrContainerNotFound
+}
+
+func (m *mockAPIClient) GetContainerRRD(ctx context.Context, node string, containerID int) ([]api.RRDPoint, error) {
+	return nil, nil
+}
+
+func (m *mockAPIClient) GetContainerInterfaces(ctx context.Context, node string, containerID int) ([]api.ContainerInterface, error) {
+	return nil, nil
+}
+
 func (m *mockAPIClient) GetContainersByNode(ctx context.Context, nodeName string) ([]*api.ContainerInfo, error) {
 	var result []*api.ContainerInfo
 	for _, container := range m.containers {
@@ -44,6 +63,33 @@ func (m *mockAPIClient) GetNodes(ctx context.Context) ([]*api.NodeInfo, error) {
 	return m.nodes, nil
 }
 
+func (m *mockAPIClient) GetClusterTasks(ctx context.Context) ([]api.TaskInfo, error) {
+	return nil, nil
+}
+
+func (m *mockAPIClient) GetClusterResources(ctx context.Context) ([]api.ClusterResource, error) {
+	if m.getError != nil {
+		return nil, m.getError
+	}
+	return m.resources, nil
+}
+
+func (m *mockAPIClient) GetReplicationJobs(ctx context.Context) ([]api.ReplicationJob, error) {
+	return nil, nil
+}
+
+func (m *mockAPIClient) GetClusterStatus(ctx context.Context) (*api.ClusterStatus, error) {
+	return &api.ClusterStatus{Quorate: true}, nil
+}
+
+func (m *mockAPIClient) GetHANodeStatus(ctx context.Context) (map[string]string, error) {
+	return nil, nil
+}
+
+func (m *mockAPIClient) GetHAPlacement(ctx context.Context, containerID int) (*api.HAPlacement, error) {
+	return nil, nil
+}
+
 func (m *mockAPIClient) MigrateContainer(ctx context.Context, containerID int, targetNode string) error {
 	if container, exists := m.containers[containerID]; exists {
 		container.Node = targetNode
@@ -68,11 +114,11 @@ func (m *mockAPIClient) StartContainer(ctx context.Context, containerID int) err
 	return api.ErrContainerNotFound
 }
 
-func (m *mockAPIClient) BackupContainer(ctx context.Context, containerID int, storage, backupDir string) (string, error) {
+func (m *mockAPIClient) BackupContainer(ctx context.Context, containerID int, storage, backupDir string, options config.VzdumpOptions) (string, error) {
 	return "mock:backup/file.tar.zst", nil
 }
 
-func (m *mockAPIClient) RestoreContainerFromBackup(ctx context.Context, containerID int, targetNode, storage, backupPath string, force bool) error {
+func (m *mockAPIClient) RestoreContainerFromBackup(ctx context.Context, containerID int, targetNode, backupPath string, options api.RestoreOptions) error {
 	return nil
 }
 
@@ -84,16 +130,30 @@ func (m *mockAPIClient) DeleteBackup(ctx context.Context, nodeName, storage, bac
 	return nil
 }
 
+func (m *mockAPIClient) GetSnapshots(ctx context.Context, containerID int) ([]api.Snapshot, error) {
+	return []api.Snapshot{}, nil
+}
+
+func (m *mockAPIClient) CreateSnapshot(ctx context.Context, containerID int, name, description string) error {
+	return nil
+}
+
+func (m *mockAPIClient) DeleteSnapshot(ctx context.Context, containerID int, name string) error {
+	return nil
+}
+
 // Create interface that matches what Monitor expects
 type APIClient interface {
 	GetContainer(ctx context.Context, containerID int) (*api.ContainerInfo, error)
+	GetContainerMetrics(ctx context.Context, node string, containerID int) (*api.ContainerMetrics, error)
 	GetContainersByNode(ctx context.Context, nodeName string) ([]*api.ContainerInfo, error)
 	GetNodes(ctx context.Context) ([]*api.NodeInfo, error)
+	GetClusterTasks(ctx context.Context) ([]api.TaskInfo, error)
 	MigrateContainer(ctx context.Context, containerID int, targetNode string) error
 	StopContainer(ctx context.Context, containerID int) error
 	StartContainer(ctx context.Context, containerID int) error
-	BackupContainer(ctx context.Context, containerID int, storage, backupDir string) (string, error)
-	RestoreContainerFromBackup(ctx context.Context, containerID int, targetNode, storage, backupPath string, force bool) error
+	BackupContainer(ctx context.Context, containerID int, storage, backupDir string, options config.VzdumpOptions) (string, error)
+	RestoreContainerFromBackup(ctx context.Context, containerID int, targetNode, backupPath string, options api.RestoreOptions) error
 	GetBackups(ctx context.Context, storage string) ([]api.BackupInfo, error)
 	DeleteBackup(ctx context.Context, nodeName, storage, backupPath string) error
 }
@@ -264,6 +324,422 @@ func TestMonitor_RecordSuccess(t *testing.T) {
 	}
 }
 
+func TestMonitor_FailureThreshold(t *testing.T) {
+	cfg := &config.Config{
+		Monitoring: config.MonitoringConfig{
+			FailureThreshold: 3,
+			Containers: []config.ContainerConfig{
+				{ID: 100, Name: "global-threshold"},
+				{ID: 101, Name: "override", FailureThreshold: 1},
+			},
+		},
+	}
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	monitor := New(cfg, nil, logger)
+	failures := make(chan int, 4)
+	monitor.AddFailureCallback(func(containerID int, state *ContainerState) {
+		failures <- containerID
+	})
+
+	tests := []struct {
+		name      string
+		id        int
+		failures  int
+		triggered bool
+	}{
+		{"below global threshold", 100, 2, false},
+		{"reaches global threshold", 100, 3, true},
+		{"reaches container override", 101, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := &ContainerState{ID: tt.id}
+			for i := 0; i < tt.failures; i++ {
+				monitor.recordFailure(state)
+			}
+
+			select {
+			case id := <-failures:
+				if !tt.triggered {
+					t.Errorf("Unexpected failure callback for container %d", id)
+				}
+			case <-time.After(20 * time.Millisecond):
+				if tt.triggered {
+					t.Error("Expected failure callback")
+				}
+			}
+		})
+	}
+}
+
+func TestRecordRecentFailure(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	state := &ContainerState{ID: 100}
+	window := 5 * time.Minute
+
+	tests := []struct {
+		name     string
+		at       time.Duration
+		expected int
+	}{
+		{"first failure", 0, 1},
+		{"sporadic failure", 4 * time.Minute, 2},
+		{"first failure expired", 6 * time.Minute, 2},
+		{"burst", 6*time.Minute + 30*time.Second, 3},
+		{"after long pause", 2 * time.Hour, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := recordRecentFailure(state, window, start.Add(tt.at)); got != tt.expected {
+				t.Errorf("Expected %d failures in window, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestMonitor_FailureWindow(t *testing.T) {
+	cfg := &config.Config{
+		Monitoring: config.MonitoringConfig{
+			FailureThreshold: 2,
+			FailureWindow:    time.Hour,
+			HealthyThreshold: 1,
+		},
+	}
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	monitor := New(cfg, nil, logger)
+	failures := make(chan *ContainerState, 2)
+	monitor.AddFailureCallback(func(containerID int, state *ContainerState) {
+		failures <- state
+	})
+
+	// Failures within the window count even when separated by a recovery
+	state := &ContainerState{ID: 100}
+	monitor.recordFailure(state)
+	monitor.recordSuccess(state)
+	monitor.recordFailure(state)
+
+	var failed *ContainerState
+	select {
+	case failed = <-failures:
+		if failed.ID != 100 {
+			t.Errorf("Expected callback for container 100, got %d", failed.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected failure callback for two failures within the window")
+	}
+
+	// Callbacks get a copy the monitor does not change afterwards
+	monitor.recordFailure(state)
+	<-failures
+	if failed == state || len(failed.RecentFailures) != 2 {
+		t.Errorf("Expected a copy with 2 recent failures, got %d", len(failed.RecentFailures))
+	}
+}
+
+func TestMonitor_BlackoutWindow(t *testing.T) {
+	cfg := &config.Config{
+		Monitoring: config.MonitoringConfig{
+			FailureThreshold: 1,
+			Containers: []config.ContainerConfig{
+				{ID: 100, BlackoutWindows: []config.BlackoutWindow{{Schedule: "* * * * *", Duration: 2 * time.Minute}}},
+				{ID: 101},
+			},
+		},
+	}
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	monitor := New(cfg, nil, logger)
+
+	blackedOut := &ContainerState{ID: 100}
+	monitor.recordFailure(blackedOut)
+	if blackedOut.FailureCount != 0 {
+		t.Errorf("Expected failure during blackout not to count, got %d", blackedOut.FailureCount)
+	}
+
+	regular := &ContainerState{ID: 101}
+	monitor.recordFailure(regular)
+	if regular.FailureCount != 1 {
+		t.Errorf("Expected failure outside blackout to count, got %d", regular.FailureCount)
+	}
+}
+
+func TestMonitor_HealthyThreshold(t *testing.T) {
+	cfg := &config.Config{
+		Monitoring: config.MonitoringConfig{
+			FailureThreshold: 5,
+			HealthyThreshold: 3,
+			Containers: []config.ContainerConfig{
+				{ID: 100, Name: "global-threshold"},
+				{ID: 101, Name: "override", HealthyThreshold: 2},
+			},
+		},
+	}
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	monitor := New(cfg, nil, logger)
+
+	tests := []struct {
+		name      string
+		id        int
+		successes int
+		expected  int
+	}{
+		{"below global threshold", 100, 2, 2},
+		{"reaches global threshold", 100, 3, 0},
+		{"reaches container override", 101, 2, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := &ContainerState{ID: tt.id}
+			monitor.recordFailure(state)
+			monitor.recordFailure(state)
+
+			for i := 0; i < tt.successes; i++ {
+				monitor.recordSuccess(state)
+			}
+
+			if state.FailureCount != tt.expected {
+				t.Errorf("Expected failure count %d, got %d", tt.expected, state.FailureCount)
+			}
+		})
+	}
+
+	// A failure between successes restarts the recovery count
+	state := &ContainerState{ID: 100}
+	monitor.recordFailure(state)
+	monitor.recordSuccess(state)
+	monitor.recordSuccess(state)
+	monitor.recordFailure(state)
+	monitor.recordSuccess(state)
+	if state.ConsecutiveSuccesses != 1 {
+		t.Errorf("Expected consecutive successes 1, got %d", state.ConsecutiveSuccesses)
+	}
+	if state.FailureCount != 2 {
+		t.Errorf("Expected failure count 2, got %d", state.FailureCount)
+	}
+}
+
+func TestMonitor_WarningTransitions(t *testing.T) {
+	cfg := &config.Config{}
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	monitor := New(cfg, nil, logger)
+
+	events := make(chan bool, 10)
+	monitor.AddWarningCallback(func(containerID int, state *ContainerState, result *health.CheckResult, active bool) {
+		events <- active
+	})
+
+	warning := func(success bool) []*health.CheckResult {
+		return []*health.CheckResult{
+			{Type: "tcp", Success: true, Severity: config.SeverityCritical},
+			{Type: "http", Success: success, Severity: config.SeverityWarning},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		previous []*health.CheckResult
+		results  []*health.CheckResult
+		expected []bool
+	}{
+		{"first failure alerts", nil, warning(false), []bool{true}},
+		{"persistent failure is quiet", warning(false), warning(false), nil},
+		{"recovery clears", warning(false), warning(true), []bool{false}},
+		{"persistent success is quiet", warning(true), warning(true), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := &ContainerState{ID: 100}
+			monitor.notifyWarnings(state, tt.previous, tt.results)
+
+			for _, want := range tt.expected {
+				select {
+				case got := <-events:
+					if got != want {
+						t.Errorf("Expected active=%v, got %v", want, got)
+					}
+				case <-time.After(time.Second):
+					t.Fatal("Timed out waiting for warning callback")
+				}
+			}
+
+			select {
+			case got := <-events:
+				t.Errorf("Unexpected warning callback with active=%v", got)
+			case <-time.After(50 * time.Millisecond):
+			}
+		})
+	}
+}
+
+func TestMonitor_DiskCheck(t *testing.T) {
+	container := config.ContainerConfig{
+		ID:   100,
+		Name: "web",
+		HealthChecks: []config.HealthCheck{
+			{Type: "disk", Timeout: time.Second, UsageThreshold: 80},
+		},
+	}
+	cfg := &config.Config{
+		Monitoring: config.MonitoringConfig{
+			FailureThreshold: 3,
+			Containers:       []config.ContainerConfig{container},
+		},
+	}
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	client := &mockAPIClient{
+		containers: map[int]*api.ContainerInfo{
+			100: {ID: 100, Name: "web", Node: "node1", Status: "running"},
+		},
+		metrics: map[int]*api.ContainerMetrics{
+			100: {Disk: 90, MaxDisk: 100},
+		},
+	}
+
+	monitor := New(cfg, client, logger)
+	monitor.states[100] = &ContainerState{ID: 100}
+
+	monitor.checkContainer(context.Background(), container, []int{0})
+
+	state, _ := monitor.GetContainerState(100)
+	if state.FailureCount != 1 {
+		t.Errorf("Expected failure count 1 for full disk, got %d", state.FailureCount)
+	}
+
+	client.metrics[100].Disk = 50
+	monitor.checkContainer(context.Background(), container, []int{0})
+
+	state, _ = monitor.GetContainerState(100)
+	if state.FailureCount != 0 {
+		t.Errorf("Expected failure count 0 after disk freed, got %d", state.FailureCount)
+	}
+}
+
+func TestMonitor_Dependencies(t *testing.T) {
+	db := config.ContainerConfig{
+		ID:           100,
+		HealthChecks: []config.HealthCheck{{Type: "disk", Timeout: time.Second}},
+	}
+	app := config.ContainerConfig{
+		ID:           101,
+		DependsOn:    []int{100},
+		HealthChecks: []config.HealthCheck{{Type: "disk", Timeout: time.Second}},
+	}
+	web := config.ContainerConfig{
+		ID: 102,
+		HealthChecks: []config.HealthCheck{
+			{Type: "disk", Timeout: time.Second},
+			{Type: "disk", Timeout: time.Second, DependsOn: []int{100}},
+		},
+	}
+	cfg := &config.Config{
+		Monitoring: config.MonitoringConfig{
+			FailureThreshold: 3,
+			Containers:       []config.ContainerConfig{db, app, web},
+		},
+	}
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	client := &mockAPIClient{
+		containers: map[int]*api.ContainerInfo{
+			100: {ID: 100, Node: "node1", Status: "running"},
+			101: {ID: 101, Node: "node1", Status: "running"},
+			102: {ID: 102, Node: "node1", Status: "running"},
+		},
+		metrics: map[int]*api.ContainerMetrics{
+			100: {Disk: 99, MaxDisk: 100},
+			101: {Disk: 99, MaxDisk: 100},
+			102: {Disk: 10, MaxDisk: 100},
+		},
+	}
+
+	monitor := New(cfg, client, logger)
+	for _, id := range []int{100, 101, 102} {
+		monitor.states[id] = &ContainerState{ID: id}
+	}
+
+	// The database fails first, so its dependents are skipped
+	monitor.checkContainer(context.Background(), db, []int{0})
+	monitor.checkContainer(context.Background(), app, []int{0})
+	monitor.checkContainer(context.Background(), web, []int{0, 1})
+
+	state, _ := monitor.GetContainerState(101)
+	if state.FailureCount != 0 {
+		t.Errorf("Expected dependent container not to fail, got failure count %d", state.FailureCount)
+	}
+	if state.SkippedReason == "" {
+		t.Error("Expected dependent container to be marked skipped")
+	}
+
+	state, _ = monitor.GetContainerState(102)
+	if !state.HealthResults[1].Skipped {
+		t.Error("Expected dependent check to be skipped")
+	}
+	if state.HealthResults[0].Skipped || !state.HealthResults[0].Success {
+		t.Error("Expected independent check to run and pass")
+	}
+	if state.HealthyCount != 0 {
+		t.Error("Expected skipped critical check to leave health unknown")
+	}
+
+	// Once the database recovers, dependents are checked again
+	client.metrics[100].Disk = 10
+	monitor.checkContainer(context.Background(), db, []int{0})
+	monitor.checkContainer(context.Background(), app, []int{0})
+
+	state, _ = monitor.GetContainerState(101)
+	if state.SkippedReason != "" {
+		t.Errorf("Expected skip to clear, got %q", state.SkippedReason)
+	}
+	if state.FailureCount != 1 {
+		t.Errorf("Expected dependent container's own failure to count, got %d", state.FailureCount)
+	}
+}
+
+func TestMonitor_TriggerNode(t *testing.T) {
+	cfg := &config.Config{}
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	monitor := New(cfg, nil, logger)
+	monitor.states[100] = &ContainerState{ID: 100, Node: "node1"}
+	monitor.states[101] = &ContainerState{ID: 101, Node: "node2"}
+	monitor.states[102] = &ContainerState{ID: 102, Node: "node1"}
+
+	monitor.TriggerNode("node1")
+
+	triggered := map[int]bool{}
+	for len(monitor.triggers) > 0 {
+		triggered[<-monitor.triggers] = true
+	}
+	if len(triggered) != 2 || !triggered[100] || !triggered[102] {
+		t.Errorf("Expected containers 100 and 102 to be triggered, got %v", triggered)
+	}
+
+	// A full queue drops triggers instead of blocking
+	for i := 0; i < triggerQueueSize+1; i++ {
+		monitor.Trigger(100)
+	}
+	if len(monitor.triggers) != triggerQueueSize {
+		t.Errorf("Expected %d queued triggers, got %d", triggerQueueSize, len(monitor.triggers))
+	}
+}
+
 func TestMonitor_GetAllStates(t *testing.T) {
 	cfg := &config.Config{}
 	logger := logrus.New()
@@ -289,4 +765,102 @@ func TestMonitor_GetAllStates(t *testing.T) {
 	if states[101].Name != "test2" {
 		t.Errorf("Expected name 'test2', got '%s'", states[101].Name)
 	}
-}
\ No newline at end of file
+}
+func TestMonitor_Maintenance(t *testing.T) {
+	cfg := &config.Config{
+		Monitoring: config.MonitoringConfig{
+			FailureThreshold: 1,
+		},
+	}
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	manager, err := maintenance.NewManager(&config.MaintenanceConfig{DefaultDuration: time.Hour}, "")
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	monitor := New(cfg, nil, logger)
+	monitor.SetMaintenance(manager)
+
+	failures := make(chan int, 2)
+	monitor.AddFailureCallback(func(containerID int, state *ContainerState) {
+		failures <- containerID
+	})
+
+	if _, err := manager.Enable(maintenance.KindNode, "node1", 0, "upgrade"); err != nil {
+		t.Fatalf("Enable() error = %v", err)
+	}
+
+	state := &ContainerState{ID: 100, Node: "node1"}
+	monitor.recordFailure(state)
+	select {
+	case id := <-failures:
+		t.Fatalf("Unexpected failover callback for container %d during maintenance", id)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if _, err := manager.Disable(maintenance.KindNode, "node1"); err != nil {
+		t.Fatalf("Disable() error = %v", err)
+	}
+
+	monitor.recordFailure(state)
+	select {
+	case id := <-failures:
+		if id != 100 {
+			t.Errorf("Expected callback for container 100, got %d", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected failure callback after maintenance ended")
+	}
+}
+
+func TestMonitor_AdaptiveInterval(t *testing.T) {
+	cfg := &config.Config{
+		Monitoring: config.MonitoringConfig{
+			Interval:         30 * time.Second,
+			FailureThreshold: 3,
+			HealthyThreshold: 1,
+			AdaptiveInterval: config.AdaptiveIntervalConfig{HealthyFor: time.Hour, Interval: 5 * time.Minute},
+		},
+	}
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	monitor := New(cfg, nil, logger)
+	container := config.ContainerConfig{ID: 100, HealthChecks: []config.HealthCheck{{Type: "tcp"}}}
+	monitor.initContainer(container, time.Now())
+	state := monitor.states[100]
+	state.Status = "running"
+	state.LastHealthCheck = time.Now()
+
+	monitor.recordSuccess(state)
+	if state.HealthySince.IsZero() {
+		t.Fatal("Expected HealthySince to be set once healthy")
+	}
+	monitor.adaptInterval(container, state)
+	if state.BackedOff {
+		t.Error("Expected normal interval before healthy_for elapsed")
+	}
+
+	state.HealthySince = time.Now().Add(-2 * time.Hour)
+	monitor.adaptInterval(container, state)
+	if !state.BackedOff {
+		t.Fatal("Expected backed-off interval after healthy_for elapsed")
+	}
+	if got := monitor.scheduler.intervals(container); got[0] != 5*time.Minute {
+		t.Errorf("Expected backed-off interval 5m, got %v", got[0])
+	}
+
+	monitor.recordFailure(state)
+	if !state.HealthySince.IsZero() {
+		t.Error("Expected HealthySince to be cleared by a failure")
+	}
+	monitor.adaptInterval(container, state)
+	if state.BackedOff {
+		t.Error("Expected normal interval after the first failure")
+	}
+	if got := monitor.scheduler.intervals(container); got[0] != 30*time.Second {
+		t.Errorf("Expected normal interval 30s, got %v", got[0])
+	}
+}
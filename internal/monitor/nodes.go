@@ -0,0 +1,148 @@
+package monitor
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// NodeState tracks the availability of a Proxmox node.
+type NodeState struct {
+	Name                string
+	Online              bool
+	ConsecutiveFailures int
+	// Down is set once the node has been offline for the node failure
+	// threshold and stays set until it is seen online again
+	Down     bool
+	LastSeen time.Time
+}
+
+// NodeFailureCallback is invoked once when a node is declared down, with the
+// monitored containers last seen on it in failover priority order.
+type NodeFailureCallback func(node string, containerIDs []int)
+
+func (m *Monitor) AddNodeFailureCallback(callback NodeFailureCallback) {
+	m.nodeCallbacks = append(m.nodeCallbacks, callback)
+}
+
+// monitorNodes polls node status until ctx is cancelled.
+func (m *Monitor) monitorNodes(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg().Monitoring.Nodes.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.checkNodes(ctx)
+		}
+	}
+}
+
+func (m *Monitor) checkNodes(ctx context.Context) {
+	nodes, err := m.apiClient.GetNodes(ctx)
+	if err != nil {
+		// Losing the API is not evidence that any particular node is down
+		m.logger.WithError(err).Warn("Failed to get node status from Proxmox")
+		return
+	}
+
+	var failed []string
+	m.statesMu.Lock()
+	for _, node := range nodes {
+		state, exists := m.nodes[node.Name]
+		if !exists {
+			state = &NodeState{Name: node.Name}
+			m.nodes[node.Name] = state
+		}
+		state.Online = node.Online
+
+		if node.Online {
+			if state.Down {
+				m.logger.WithField("node", node.Name).Info("Node back online")
+			}
+			state.ConsecutiveFailures = 0
+			state.Down = false
+			state.LastSeen = time.Now()
+			continue
+		}
+
+		// Nodes under maintenance are expected to go offline
+		if _, ok := m.maintenance.Node(node.Name); ok {
+			state.ConsecutiveFailures = 0
+			continue
+		}
+
+		state.ConsecutiveFailures++
+		// While paused the node is not declared down, so it fails over on
+		// the first poll after resuming if it is still offline
+		if m.pause.Paused {
+			continue
+		}
+		if !state.Down && state.ConsecutiveFailures >= m.cfg().Monitoring.Nodes.FailureThreshold {
+			state.Down = true
+			failed = append(failed, node.Name)
+		}
+	}
+	m.statesMu.Unlock()
+
+	for _, node := range failed {
+		containerIDs := m.containersOnNode(node)
+		m.logger.WithFields(logrus.Fields{
+			"node":       node,
+			"containers": containerIDs,
+		}).Error("Node down, failing over its containers")
+
+		m.publish(Event{Type: EventNodeFailed, Node: node, ContainerIDs: containerIDs})
+		for _, callback := range m.nodeCallbacks {
+			go callback(node, containerIDs)
+		}
+	}
+}
+
+// containersOnNode returns the monitored containers last seen on node,
+// ordered by configured priority (lowest value first).
+func (m *Monitor) containersOnNode(node string) []int {
+	m.statesMu.RLock()
+	defer m.statesMu.RUnlock()
+
+	var containers []config.ContainerConfig
+	for _, container := range m.containers() {
+		if state, exists := m.states[container.ID]; exists && state.Node == node {
+			containers = append(containers, container)
+		}
+	}
+
+	sort.SliceStable(containers, func(i, j int) bool {
+		return containers[i].Priority < containers[j].Priority
+	})
+
+	ids := make([]int, len(containers))
+	for i, container := range containers {
+		ids[i] = container.ID
+	}
+	return ids
+}
+
+// nodeDown reports whether node has been declared down. Callers must hold
+// statesMu.
+func (m *Monitor) nodeDown(node string) bool {
+	state, exists := m.nodes[node]
+	return exists && state.Down
+}
+
+func (m *Monitor) GetNodeStates() map[string]*NodeState {
+	m.statesMu.RLock()
+	defer m.statesMu.RUnlock()
+
+	result := make(map[string]*NodeState, len(m.nodes))
+	for name, state := range m.nodes {
+		stateCopy := *state
+		result[name] = &stateCopy
+	}
+	return result
+}
@@ -0,0 +1,101 @@
+package monitor
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+func TestMonitor_NodeFailure(t *testing.T) {
+	cfg := &config.Config{
+		Monitoring: config.MonitoringConfig{
+			FailureThreshold: 1,
+			Nodes:            config.NodeMonitoringConfig{Enabled: true, FailureThreshold: 2},
+			Containers: []config.ContainerConfig{
+				{ID: 100, Priority: 3},
+				{ID: 101, Priority: 1},
+				{ID: 102, Priority: 2},
+				{ID: 103, Priority: 1},
+			},
+		},
+	}
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	client := &mockAPIClient{
+		nodes: []*api.NodeInfo{
+			{Name: "node1", Online: true},
+			{Name: "node2", Online: true},
+		},
+	}
+
+	monitor := New(cfg, client, logger)
+	monitor.states[100] = &ContainerState{ID: 100, Node: "node1"}
+	monitor.states[101] = &ContainerState{ID: 101, Node: "node1"}
+	monitor.states[102] = &ContainerState{ID: 102, Node: "node1"}
+	monitor.states[103] = &ContainerState{ID: 103, Node: "node2"}
+
+	failures := make(chan []int, 2)
+	monitor.AddNodeFailureCallback(func(node string, containerIDs []int) {
+		if node != "node1" {
+			t.Errorf("Unexpected node failure for %s", node)
+		}
+		failures <- containerIDs
+	})
+	containerFailures := make(chan int, 4)
+	monitor.AddFailureCallback(func(containerID int, state *ContainerState) {
+		containerFailures <- containerID
+	})
+
+	ctx := context.Background()
+	client.nodes[0].Online = false
+
+	// One offline poll is below the threshold
+	monitor.checkNodes(ctx)
+	select {
+	case ids := <-failures:
+		t.Fatalf("Unexpected node failure below threshold: %v", ids)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	monitor.checkNodes(ctx)
+	select {
+	case ids := <-failures:
+		if !reflect.DeepEqual(ids, []int{101, 102, 100}) {
+			t.Errorf("Expected containers in priority order [101 102 100], got %v", ids)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected node failure callback")
+	}
+
+	// A down node is reported only once
+	monitor.checkNodes(ctx)
+	select {
+	case ids := <-failures:
+		t.Errorf("Unexpected repeated node failure: %v", ids)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// Containers on the down node are left to the node failover
+	monitor.recordFailure(monitor.states[100])
+	monitor.recordFailure(monitor.states[103])
+	select {
+	case id := <-containerFailures:
+		if id != 103 {
+			t.Errorf("Expected only container 103 to fail over individually, got %d", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected container failure callback for container 103")
+	}
+
+	client.nodes[0].Online = true
+	monitor.checkNodes(ctx)
+	if states := monitor.GetNodeStates(); states["node1"].Down {
+		t.Error("Expected node to recover once online")
+	}
+}
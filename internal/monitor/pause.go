@@ -0,0 +1,43 @@
+package monitor
+
+import "time"
+
+// PauseState describes whether automatic failover is paused cluster-wide.
+type PauseState struct {
+	Paused bool
+	Since  time.Time
+	Reason string
+}
+
+// Pause stops the monitor from triggering container and node failovers.
+// Health checks keep running, so state stays current while paused.
+func (m *Monitor) Pause(reason string) {
+	m.statesMu.Lock()
+	defer m.statesMu.Unlock()
+
+	if m.pause.Paused {
+		m.pause.Reason = reason
+		return
+	}
+	m.pause = PauseState{Paused: true, Since: time.Now(), Reason: reason}
+	m.logger.WithField("reason", reason).Warn("Automatic failover paused")
+}
+
+// Resume re-enables automatic failover after Pause.
+func (m *Monitor) Resume() {
+	m.statesMu.Lock()
+	defer m.statesMu.Unlock()
+
+	if !m.pause.Paused {
+		return
+	}
+	m.logger.WithField("paused_for", time.Since(m.pause.Since).Round(time.Second)).Info("Automatic failover resumed")
+	m.pause = PauseState{}
+}
+
+// GetPauseState returns the current pause state.
+func (m *Monitor) GetPauseState() PauseState {
+	m.statesMu.RLock()
+	defer m.statesMu.RUnlock()
+	return m.pause
+}
@@ -0,0 +1,106 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+func TestMonitor_Pause(t *testing.T) {
+	cfg := &config.Config{
+		Monitoring: config.MonitoringConfig{
+			FailureThreshold: 2,
+		},
+	}
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	monitor := New(cfg, nil, logger)
+
+	failures := make(chan int, 2)
+	monitor.AddFailureCallback(func(containerID int, state *ContainerState) {
+		failures <- containerID
+	})
+
+	monitor.Pause("cluster upgrade")
+	pause := monitor.GetPauseState()
+	if !pause.Paused || pause.Reason != "cluster upgrade" || pause.Since.IsZero() {
+		t.Fatalf("Unexpected pause state %+v", pause)
+	}
+
+	state := &ContainerState{ID: 100}
+	monitor.recordFailure(state)
+	monitor.recordFailure(state)
+	select {
+	case id := <-failures:
+		t.Fatalf("Unexpected failover callback for container %d while paused", id)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// Failures keep counting while paused
+	if state.FailureCount != 2 {
+		t.Errorf("Expected failure count 2, got %d", state.FailureCount)
+	}
+
+	monitor.Resume()
+	if monitor.GetPauseState().Paused {
+		t.Fatal("Expected monitor to be resumed")
+	}
+
+	monitor.recordFailure(state)
+	select {
+	case id := <-failures:
+		if id != 100 {
+			t.Errorf("Expected callback for container 100, got %d", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected failure callback after resume")
+	}
+}
+
+func TestMonitor_PauseNodeFailure(t *testing.T) {
+	cfg := &config.Config{
+		Monitoring: config.MonitoringConfig{
+			Nodes: config.NodeMonitoringConfig{Enabled: true, FailureThreshold: 1},
+		},
+	}
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	client := &mockAPIClient{
+		nodes: []*api.NodeInfo{{Name: "node1", Online: false}},
+	}
+
+	monitor := New(cfg, client, logger)
+	monitor.states[100] = &ContainerState{ID: 100, Node: "node1"}
+
+	failures := make(chan string, 2)
+	monitor.AddNodeFailureCallback(func(node string, containerIDs []int) {
+		failures <- node
+	})
+
+	ctx := context.Background()
+	monitor.Pause("")
+	monitor.checkNodes(ctx)
+	select {
+	case node := <-failures:
+		t.Fatalf("Unexpected node failure for %s while paused", node)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// A node still offline after resuming fails over on the next poll
+	monitor.Resume()
+	monitor.checkNodes(ctx)
+	select {
+	case node := <-failures:
+		if node != "node1" {
+			t.Errorf("Expected node1 failure, got %s", node)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected node failure after resume")
+	}
+}
@@ -0,0 +1,123 @@
+package monitor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// QuorumCallback is invoked when the proxwarden host loses (lost is true) or
+// regains quorum with its witnesses.
+type QuorumCallback func(lost bool, reachable, total int)
+
+func (m *Monitor) AddQuorumCallback(callback QuorumCallback) {
+	m.quorumCallbacks = append(m.quorumCallbacks, callback)
+}
+
+// QuorumLost reports whether too few witnesses were reachable at the last
+// witness check.
+func (m *Monitor) QuorumLost() bool {
+	m.statesMu.RLock()
+	defer m.statesMu.RUnlock()
+	return m.quorumLost
+}
+
+func (m *Monitor) witnessesEnabled() bool {
+	return len(m.cfg().Monitoring.Witnesses.Checks) > 0
+}
+
+// monitorWitnesses checks the witnesses until ctx is cancelled.
+func (m *Monitor) monitorWitnesses(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg().Monitoring.Witnesses.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.checkWitnesses(ctx)
+		}
+	}
+}
+
+// checkWitnesses runs all witness checks concurrently, updates the quorum
+// state and reports whether quorum is lost.
+func (m *Monitor) checkWitnesses(ctx context.Context) bool {
+	checks := m.cfg().Monitoring.Witnesses.Checks
+
+	reachable := make([]bool, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check config.HealthCheck) {
+			defer wg.Done()
+			reachable[i] = m.witnessReachable(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	count := 0
+	for _, ok := range reachable {
+		if ok {
+			count++
+		}
+	}
+
+	lost := count < m.cfg().Monitoring.Witnesses.MinReachable
+	m.setQuorumLost(lost, count, len(checks))
+	return lost
+}
+
+func (m *Monitor) witnessReachable(ctx context.Context, check config.HealthCheck) bool {
+	if check.Timeout <= 0 {
+		check.Timeout = m.cfg().Monitoring.Timeout
+	}
+
+	var err error
+	if check.Type == config.WitnessCheckProxmox {
+		checkCtx, cancel := context.WithTimeout(ctx, check.Timeout)
+		_, err = m.apiClient.GetNodes(checkCtx)
+		cancel()
+	} else if result := m.checker.RunHealthCheck(ctx, check); !result.Success {
+		err = result.Error
+	}
+
+	if err != nil {
+		m.logger.WithFields(logrus.Fields{
+			"check_type": check.Type,
+			"target":     check.Target,
+			"error":      err,
+		}).Debug("Witness unreachable")
+		return false
+	}
+	return true
+}
+
+func (m *Monitor) setQuorumLost(lost bool, reachable, total int) {
+	m.statesMu.Lock()
+	changed := m.quorumLost != lost
+	m.quorumLost = lost
+	m.statesMu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	fields := logrus.Fields{
+		"reachable": reachable,
+		"witnesses": total,
+	}
+	if lost {
+		m.logger.WithFields(fields).Error("Witnesses unreachable, suspending failure detection")
+	} else {
+		m.logger.WithFields(fields).Info("Witnesses reachable again, resuming failure detection")
+	}
+
+	for _, callback := range m.quorumCallbacks {
+		go callback(lost, reachable, total)
+	}
+}
@@ -0,0 +1,133 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+func TestMonitor_Witnesses(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	cfg := &config.Config{
+		Monitoring: config.MonitoringConfig{
+			FailureThreshold: 1,
+			Timeout:          time.Second,
+			Witnesses: config.WitnessesConfig{
+				MinReachable: 2,
+				Checks: []config.HealthCheck{
+					{Type: "tcp", Target: "127.0.0.1", Port: port},
+					{Type: config.WitnessCheckProxmox},
+				},
+			},
+		},
+	}
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	client := &mockAPIClient{}
+	monitor := New(cfg, client, logger)
+
+	changes := make(chan bool, 4)
+	monitor.AddQuorumCallback(func(lost bool, reachable, total int) {
+		changes <- lost
+	})
+	failures := make(chan int, 4)
+	monitor.AddFailureCallback(func(containerID int, state *ContainerState) {
+		failures <- containerID
+	})
+
+	ctx := context.Background()
+	if monitor.checkWitnesses(ctx) {
+		t.Fatal("Expected quorum with all witnesses reachable")
+	}
+
+	// Losing the Proxmox API leaves one of two required witnesses
+	client.getError = errors.New("connection refused")
+	if !monitor.checkWitnesses(ctx) {
+		t.Fatal("Expected quorum to be lost")
+	}
+	select {
+	case lost := <-changes:
+		if !lost {
+			t.Error("Expected quorum lost callback")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected quorum callback")
+	}
+
+	state := &ContainerState{ID: 100}
+	monitor.recordFailure(state)
+	if state.FailureCount != 0 {
+		t.Errorf("Expected failure not to be counted without quorum, got %d", state.FailureCount)
+	}
+
+	client.getError = nil
+	if monitor.checkWitnesses(ctx) {
+		t.Fatal("Expected quorum to be restored")
+	}
+
+	monitor.recordFailure(state)
+	select {
+	case id := <-failures:
+		if id != 100 {
+			t.Errorf("Expected failure callback for container 100, got %d", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected failure callback with quorum restored")
+	}
+}
+
+func TestMonitor_WitnessesConfirmBeforeFailover(t *testing.T) {
+	cfg := &config.Config{
+		Monitoring: config.MonitoringConfig{
+			FailureThreshold: 1,
+			Timeout:          time.Second,
+			Witnesses: config.WitnessesConfig{
+				MinReachable: 1,
+				Checks:       []config.HealthCheck{{Type: "tcp", Target: "127.0.0.1", Port: closedPort(t)}},
+			},
+		},
+	}
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	monitor := New(cfg, &mockAPIClient{}, logger)
+	failures := make(chan int, 1)
+	monitor.AddFailureCallback(func(containerID int, state *ContainerState) {
+		failures <- containerID
+	})
+
+	// Quorum is only checked when the threshold is reached
+	monitor.recordFailure(&ContainerState{ID: 100})
+	select {
+	case id := <-failures:
+		t.Errorf("Unexpected failover callback for container %d without quorum", id)
+	case <-time.After(20 * time.Millisecond):
+	}
+	if !monitor.QuorumLost() {
+		t.Error("Expected quorum to be lost")
+	}
+}
+
+// closedPort returns a local port with nothing listening on it.
+func closedPort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	return port
+}
@@ -0,0 +1,100 @@
+package monitor
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/health"
+)
+
+// Reload switches the monitor to a changed configuration. Monitoring of
+// added containers starts and that of removed ones stops; containers whose
+// settings changed are rescheduled, and their check results and histories
+// start afresh when their health checks changed. Everything else about
+// unchanged containers, such as their failure counts and state, is kept.
+func (m *Monitor) Reload(cfg *config.Config, now time.Time) {
+	previous := make(map[int]config.ContainerConfig)
+	for _, container := range m.cfg().Monitoring.Containers {
+		previous[container.ID] = container
+	}
+
+	// Containers now configured are no longer discovered
+	configured := make(map[int]bool)
+	for _, container := range cfg.Monitoring.Containers {
+		configured[container.ID] = true
+	}
+	m.discoveredMu.Lock()
+	discovered := make(map[int]bool)
+	var kept []config.ContainerConfig
+	for _, container := range m.discovered {
+		if configured[container.ID] {
+			discovered[container.ID] = true
+			continue
+		}
+		kept = append(kept, container)
+	}
+	m.discovered = kept
+	m.current.Store(cfg)
+	m.discoveredMu.Unlock()
+
+	rescheduleAll := m.scheduler.reconfigure(cfg.Monitoring)
+
+	var reschedule []config.ContainerConfig
+	for _, container := range cfg.Monitoring.Containers {
+		logger := m.logger.WithField("container_id", container.ID)
+		known, exists := previous[container.ID]
+		switch {
+		case !exists && !discovered[container.ID]:
+			m.initContainer(container, now)
+			reschedule = append(reschedule, container)
+			logger.Info("Container added to configuration, starting monitoring")
+		case !exists:
+			// Discovered before, so its state is kept
+			m.updateContainer(container, true)
+			reschedule = append(reschedule, container)
+		case !reflect.DeepEqual(known, container):
+			m.updateContainer(container, !reflect.DeepEqual(known.HealthChecks, container.HealthChecks))
+			reschedule = append(reschedule, container)
+			logger.Info("Container configuration changed")
+		case rescheduleAll:
+			reschedule = append(reschedule, container)
+		}
+		delete(previous, container.ID)
+	}
+	if rescheduleAll {
+		reschedule = append(reschedule, kept...)
+	}
+	m.scheduler.schedule(reschedule, now)
+
+	for id := range previous {
+		m.statesMu.Lock()
+		delete(m.states, id)
+		delete(m.histories, id)
+		m.statesMu.Unlock()
+		m.scheduler.remove(id)
+		m.logger.WithField("container_id", id).Info("Container removed from configuration, stopping monitoring")
+	}
+
+	m.logger.WithField("containers", len(cfg.Monitoring.Containers)).Info("Monitoring configuration reloaded")
+}
+
+// updateContainer applies the changed settings of a monitored container to
+// its state. With checksChanged its check results and histories start
+// afresh.
+func (m *Monitor) updateContainer(container config.ContainerConfig, checksChanged bool) {
+	m.statesMu.Lock()
+	defer m.statesMu.Unlock()
+
+	state, exists := m.states[container.ID]
+	if !exists {
+		return
+	}
+	state.Name = container.Name
+	if checksChanged {
+		state.HealthResults = make([]*health.CheckResult, 0)
+		state.CheckStats = nil
+		state.ActiveWarnings = 0
+		delete(m.histories, container.ID)
+	}
+}
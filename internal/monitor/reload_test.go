@@ -0,0 +1,159 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/health"
+	"github.com/sirupsen/logrus"
+)
+
+func TestMonitor_Reload(t *testing.T) {
+	checks := []config.HealthCheck{{Type: "tcp", Target: "10.0.0.1", Port: 80}}
+	cfg := &config.Config{
+		Monitoring: config.MonitoringConfig{
+			Interval:    30 * time.Second,
+			HistorySize: 10,
+			Containers: []config.ContainerConfig{
+				{ID: 100, Name: "web", HealthChecks: checks},
+				{ID: 101, Name: "db", HealthChecks: checks},
+				{ID: 102, Name: "cache", HealthChecks: checks},
+			},
+		},
+	}
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	m := New(cfg, &mockAPIClient{}, logger)
+	now := time.Now()
+	for _, container := range cfg.Monitoring.Containers {
+		m.initContainer(container, now)
+		m.states[container.ID].FailureCount = 2
+		m.states[container.ID].HealthResults = []*health.CheckResult{{Success: false}}
+	}
+	m.scheduler.schedule(cfg.Monitoring.Containers, now)
+	m.setDiscovered([]config.ContainerConfig{{ID: 200, Name: "worker"}}, now)
+
+	reloaded := &config.Config{
+		Monitoring: config.MonitoringConfig{
+			Interval:    30 * time.Second,
+			HistorySize: 10,
+			Containers: []config.ContainerConfig{
+				{ID: 100, Name: "web", HealthChecks: checks},
+				{ID: 101, Name: "database", HealthChecks: checks[:0]},
+				{ID: 103, Name: "queue", HealthChecks: checks},
+				{ID: 200, Name: "worker", HealthChecks: checks},
+			},
+		},
+	}
+	m.Reload(reloaded, now)
+
+	if m.cfg() != reloaded {
+		t.Error("Expected the reloaded configuration to be current")
+	}
+	if state := m.states[100]; state == nil || state.FailureCount != 2 || len(state.HealthResults) != 1 {
+		t.Errorf("Expected unchanged container 100 to keep its state, got %+v", state)
+	}
+	if state := m.states[101]; state == nil || state.Name != "database" || state.FailureCount != 2 || len(state.HealthResults) != 0 {
+		t.Errorf("Expected container 101 to keep its failure count and start its checks afresh, got %+v", state)
+	}
+	if _, exists := m.states[102]; exists {
+		t.Error("Expected removed container 102 to be forgotten")
+	}
+	if _, exists := m.scheduler.slots[102]; exists {
+		t.Error("Expected removed container 102 to be unscheduled")
+	}
+	if state := m.states[103]; state == nil || state.State != StateUnknown {
+		t.Errorf("Expected added container 103 to be monitored, got %+v", state)
+	}
+
+	// A discovered container now configured is monitored once
+	count := 0
+	for _, container := range m.containers() {
+		if container.ID == 200 {
+			count++
+			if len(container.HealthChecks) != 1 {
+				t.Errorf("Expected the configured settings of container 200, got %+v", container)
+			}
+		}
+	}
+	if count != 1 {
+		t.Errorf("Expected container 200 once, got %d times", count)
+	}
+}
+
+// blockingAPIClient holds GetContainer until released, so a reload can run
+// while a check is in flight.
+type blockingAPIClient struct {
+	mockAPIClient
+	once    sync.Once
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingAPIClient) GetContainer(ctx context.Context, containerID int) (*api.ContainerInfo, error) {
+	b.once.Do(func() { close(b.entered) })
+	<-b.release
+	return nil, errors.New("connection refused")
+}
+
+func TestMonitor_ReloadDuringCheck(t *testing.T) {
+	checks := []config.HealthCheck{{Type: "tcp", Target: "10.0.0.1", Port: 80}}
+	container := config.ContainerConfig{ID: 100, Name: "web", HealthChecks: checks}
+	cfg := &config.Config{
+		Monitoring: config.MonitoringConfig{
+			Interval:         30 * time.Second,
+			FailureThreshold: 1,
+			HistorySize:      10,
+			AdaptiveInterval: config.AdaptiveIntervalConfig{HealthyFor: time.Minute, Interval: 5 * time.Minute},
+			Containers:       []config.ContainerConfig{container},
+		},
+	}
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	client := &blockingAPIClient{entered: make(chan struct{}), release: make(chan struct{})}
+	m := New(cfg, client, logger)
+	now := time.Now()
+	m.initContainer(container, now)
+	m.scheduler.schedule(cfg.Monitoring.Containers, now)
+
+	var failed []int
+	m.AddFailureCallback(func(containerID int, state *ContainerState) {
+		failed = append(failed, containerID)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.checkContainer(context.Background(), container, []int{0})
+	}()
+	<-client.entered
+
+	reloaded := *cfg
+	reloaded.Monitoring.Containers = nil
+	m.Reload(&reloaded, now)
+	close(client.release)
+	<-done
+
+	if len(failed) != 0 {
+		t.Errorf("Expected no failover of a removed container, got %v", failed)
+	}
+	if _, exists := m.states[100]; exists {
+		t.Error("Expected the removed container not to be monitored again")
+	}
+	if _, exists := m.scheduler.slots[100]; exists {
+		t.Error("Expected the removed container not to be scheduled again")
+	}
+
+	// A check scheduled before the reload finds no state at all
+	m.checkContainer(context.Background(), container, []int{0})
+	if len(failed) != 0 {
+		t.Errorf("Expected no failover of a removed container, got %v", failed)
+	}
+}
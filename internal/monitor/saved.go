@@ -0,0 +1,71 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/store"
+)
+
+// SavedState is the state of a container as the daemon last saved it in
+// the store database, for when it is not running.
+type SavedState struct {
+	ID              int       `json:"id"`
+	Name            string    `json:"name"`
+	Node            string    `json:"node"`
+	Status          string    `json:"status"`
+	State           State     `json:"state"`
+	FailureCount    int       `json:"failure_count"`
+	SkippedReason   string    `json:"skipped_reason,omitempty"`
+	Flapping        bool      `json:"flapping,omitempty"`
+	LastSeen        time.Time `json:"last_seen"`
+	LastHealthCheck time.Time `json:"last_health_check,omitempty"`
+	Saved           time.Time `json:"saved"`
+}
+
+// SaveStates replaces the states saved in db with the current ones.
+func (m *Monitor) SaveStates(db *store.DB, now time.Time) error {
+	states := m.GetAllStates()
+	return db.Update(func(tx *store.Tx) error {
+		if err := tx.Clear(store.BucketMonitor); err != nil {
+			return err
+		}
+		for id, state := range states {
+			saved := SavedState{
+				ID:              state.ID,
+				Name:            state.Name,
+				Node:            state.Node,
+				Status:          state.Status,
+				State:           state.State,
+				FailureCount:    state.FailureCount,
+				SkippedReason:   state.SkippedReason,
+				Flapping:        state.Flapping,
+				LastSeen:        state.LastSeen,
+				LastHealthCheck: state.LastHealthCheck,
+				Saved:           now,
+			}
+			if err := tx.Put(store.BucketMonitor, strconv.Itoa(id), saved); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// LoadStates returns the states saved in db by container ID.
+func LoadStates(db *store.DB) (map[int]SavedState, error) {
+	states := make(map[int]SavedState)
+	err := db.View(func(tx *store.Tx) error {
+		return tx.ForEach(store.BucketMonitor, func(data []byte) error {
+			var state SavedState
+			if err := json.Unmarshal(data, &state); err != nil {
+				return fmt.Errorf("failed to decode saved monitor state: %w", err)
+			}
+			states[state.ID] = state
+			return nil
+		})
+	})
+	return states, err
+}
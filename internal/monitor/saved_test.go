@@ -0,0 +1,44 @@
+package monitor
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/store"
+	"github.com/sirupsen/logrus"
+)
+
+func TestMonitor_SaveStates(t *testing.T) {
+	db, err := store.Open(filepath.Join(t.TempDir(), store.FileName), "")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	monitor := New(&config.Config{}, nil, logrus.New())
+	now := time.Now().Truncate(time.Second)
+
+	monitor.states[100] = &ContainerState{ID: 100, Name: "web", Node: "node1", State: StateFailing, FailureCount: 2}
+	monitor.states[101] = &ContainerState{ID: 101, Name: "db", Node: "node2", State: StateHealthy}
+	if err := monitor.SaveStates(db, now.Add(-time.Minute)); err != nil {
+		t.Fatalf("SaveStates failed: %v", err)
+	}
+
+	// Containers no longer monitored are dropped
+	delete(monitor.states, 101)
+	if err := monitor.SaveStates(db, now); err != nil {
+		t.Fatalf("SaveStates failed: %v", err)
+	}
+
+	saved, err := LoadStates(db)
+	if err != nil {
+		t.Fatalf("LoadStates failed: %v", err)
+	}
+	if len(saved) != 1 {
+		t.Fatalf("Expected 1 saved state, got %+v", saved)
+	}
+	state := saved[100]
+	if state.State != StateFailing || state.FailureCount != 2 || state.Node != "node1" || !state.Saved.Equal(now) {
+		t.Errorf("Unexpected saved state %+v", state)
+	}
+}
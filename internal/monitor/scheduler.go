@@ -0,0 +1,228 @@
+package monitor
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+// scheduler tracks when each health check of each container is next due, so
+// checks run on their own interval rather than one global tick.
+type scheduler struct {
+	mu       sync.Mutex
+	interval time.Duration
+	spread   bool
+	jitter   time.Duration
+	random   func(n int64) int64
+	slots    map[int][]slot
+
+	// backoff is the interval of containers in backedOff
+	backoff   time.Duration
+	backedOff map[int]bool
+}
+
+// slot is the schedule of a single check. base advances by exactly one
+// interval per run so jitter never accumulates into drift.
+type slot struct {
+	base time.Time
+	next time.Time
+}
+
+func newScheduler(cfg config.MonitoringConfig) *scheduler {
+	return &scheduler{
+		interval: cfg.Interval,
+		spread:   cfg.SpreadChecks,
+		jitter:   cfg.Jitter,
+		random:   rand.Int63n,
+		slots:    make(map[int][]slot),
+
+		backoff:   cfg.AdaptiveInterval.Interval,
+		backedOff: make(map[int]bool),
+	}
+}
+
+// reconfigure applies changed monitoring settings and reports whether they
+// change the schedule of every container.
+func (s *scheduler) reconfigure(cfg config.MonitoringConfig) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed := s.interval != cfg.Interval || s.spread != cfg.SpreadChecks ||
+		s.jitter != cfg.Jitter || s.backoff != cfg.AdaptiveInterval.Interval
+	s.interval = cfg.Interval
+	s.spread = cfg.SpreadChecks
+	s.jitter = cfg.Jitter
+	s.backoff = cfg.AdaptiveInterval.Interval
+	return changed
+}
+
+// containerInterval returns the container's interval override, falling back
+// to the global monitoring interval.
+func (s *scheduler) containerInterval(container config.ContainerConfig) time.Duration {
+	if container.Interval > 0 {
+		return container.Interval
+	}
+	return s.interval
+}
+
+// checkInterval returns how often a check runs, falling back to the
+// container's interval when the check does not set its own.
+func (s *scheduler) checkInterval(container config.ContainerConfig, check config.HealthCheck) time.Duration {
+	if check.Interval > 0 {
+		return check.Interval
+	}
+	return s.containerInterval(container)
+}
+
+// intervals returns one interval per scheduling slot. A container without
+// health checks still gets a single slot so its Proxmox status is refreshed.
+// Callers must hold mu.
+func (s *scheduler) intervals(container config.ContainerConfig) []time.Duration {
+	var intervals []time.Duration
+	if len(container.HealthChecks) == 0 {
+		intervals = []time.Duration{s.containerInterval(container)}
+	} else {
+		intervals = make([]time.Duration, len(container.HealthChecks))
+		for i, check := range container.HealthChecks {
+			intervals[i] = s.checkInterval(container, check)
+		}
+	}
+
+	if s.backedOff[container.ID] {
+		for i := range intervals {
+			if intervals[i] < s.backoff {
+				intervals[i] = s.backoff
+			}
+		}
+	}
+	return intervals
+}
+
+// setBackedOff switches a container between its normal and backed-off
+// intervals and reports whether that changed anything. Returning to normal
+// pulls in checks planned on the longer interval, so they run again within
+// their normal interval.
+func (s *scheduler) setBackedOff(container config.ContainerConfig, backedOff bool, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.backedOff[container.ID] == backedOff {
+		return false
+	}
+	if backedOff {
+		s.backedOff[container.ID] = true
+		return true
+	}
+
+	delete(s.backedOff, container.ID)
+	intervals := s.intervals(container)
+	slots := s.slots[container.ID]
+	for i := range slots {
+		if i >= len(intervals) {
+			break
+		}
+		if base := now.Add(intervals[i]); slots[i].base.After(base) {
+			slots[i].base = base
+			slots[i].next = base.Add(s.randomJitter())
+		}
+	}
+	return true
+}
+
+// schedule plans the first run of every check. Without spreading each check
+// first runs one interval after start; with spreading the checks are
+// staggered evenly across their intervals.
+func (s *scheduler) schedule(containers []config.ContainerConfig, start time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total := 0
+	for _, container := range containers {
+		total += len(s.intervals(container))
+	}
+
+	position := 0
+	for _, container := range containers {
+		intervals := s.intervals(container)
+		slots := make([]slot, len(intervals))
+		for i, interval := range intervals {
+			position++
+			offset := interval
+			if s.spread {
+				offset = interval * time.Duration(position) / time.Duration(total)
+			}
+			slots[i].base = start.Add(offset)
+			slots[i].next = slots[i].base.Add(s.randomJitter())
+		}
+		s.slots[container.ID] = slots
+	}
+}
+
+// due returns the indexes of the container's checks that are due at now and
+// advances their next run time. The boolean reports whether the container
+// needs to be evaluated at all.
+func (s *scheduler) due(container config.ContainerConfig, now time.Time) ([]int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	intervals := s.intervals(container)
+	slots, exists := s.slots[container.ID]
+	if !exists || len(slots) != len(intervals) {
+		slots = make([]slot, len(intervals))
+		s.slots[container.ID] = slots
+	}
+
+	var due []int
+	for i, interval := range intervals {
+		if slots[i].next.After(now) {
+			continue
+		}
+		due = append(due, i)
+
+		// Skip missed runs rather than firing them back to back
+		slots[i].base = slots[i].base.Add(interval)
+		if !slots[i].base.After(now) {
+			slots[i].base = now.Add(interval)
+		}
+		slots[i].next = slots[i].base.Add(s.randomJitter())
+	}
+
+	if len(container.HealthChecks) == 0 {
+		return nil, len(due) > 0
+	}
+	return due, len(due) > 0
+}
+
+// remove forgets the schedule of a container that is no longer monitored.
+func (s *scheduler) remove(containerID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.slots, containerID)
+	delete(s.backedOff, containerID)
+}
+
+// nextDue returns the earliest time any check is due.
+func (s *scheduler) nextDue() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var earliest time.Time
+	for _, slots := range s.slots {
+		for _, slot := range slots {
+			if earliest.IsZero() || slot.next.Before(earliest) {
+				earliest = slot.next
+			}
+		}
+	}
+	return earliest
+}
+
+func (s *scheduler) randomJitter() time.Duration {
+	if s.jitter <= 0 {
+		return 0
+	}
+	return time.Duration(s.random(int64(s.jitter)))
+}
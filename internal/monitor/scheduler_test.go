@@ -0,0 +1,215 @@
+package monitor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+func TestScheduler_Due(t *testing.T) {
+	container := config.ContainerConfig{
+		ID: 100,
+		HealthChecks: []config.HealthCheck{
+			{Type: "tcp", Interval: 10 * time.Second},
+			{Type: "postgres", Interval: 5 * time.Minute},
+			{Type: "ping"},
+		},
+	}
+
+	s := newScheduler(config.MonitoringConfig{Interval: 30 * time.Second})
+	start := time.Unix(0, 0)
+	s.schedule([]config.ContainerConfig{container}, start)
+
+	tests := []struct {
+		name     string
+		offset   time.Duration
+		expected []int
+	}{
+		{"nothing due before first interval", 5 * time.Second, nil},
+		{"fast check due", 10 * time.Second, []int{0}},
+		{"fast check not due twice", 15 * time.Second, nil},
+		{"fast and default interval", 30 * time.Second, []int{0, 2}},
+		{"all checks due", 5 * time.Minute, []int{0, 1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			due, run := s.due(container, start.Add(tt.offset))
+			if !reflect.DeepEqual(due, tt.expected) {
+				t.Errorf("Expected due checks %v, got %v", tt.expected, due)
+			}
+			if run != (len(tt.expected) > 0) {
+				t.Errorf("Expected run=%v, got %v", len(tt.expected) > 0, run)
+			}
+		})
+	}
+}
+
+func TestScheduler_ContainerInterval(t *testing.T) {
+	s := newScheduler(config.MonitoringConfig{Interval: 30 * time.Second})
+
+	tests := []struct {
+		name      string
+		container config.ContainerConfig
+		expected  []time.Duration
+	}{
+		{
+			name:      "global interval",
+			container: config.ContainerConfig{HealthChecks: []config.HealthCheck{{Type: "tcp"}}},
+			expected:  []time.Duration{30 * time.Second},
+		},
+		{
+			name: "container override",
+			container: config.ContainerConfig{
+				Interval:     5 * time.Second,
+				HealthChecks: []config.HealthCheck{{Type: "tcp"}, {Type: "http", Interval: time.Minute}},
+			},
+			expected: []time.Duration{5 * time.Second, time.Minute},
+		},
+		{
+			name:      "container override without checks",
+			container: config.ContainerConfig{Interval: 2 * time.Minute},
+			expected:  []time.Duration{2 * time.Minute},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.intervals(tt.container); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected intervals %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestScheduler_NextDue(t *testing.T) {
+	s := newScheduler(config.MonitoringConfig{Interval: 30 * time.Second})
+	if !s.nextDue().IsZero() {
+		t.Error("Expected zero next due time without containers")
+	}
+
+	start := time.Unix(0, 0)
+	s.schedule([]config.ContainerConfig{
+		{ID: 100, HealthChecks: []config.HealthCheck{{Interval: time.Minute}, {Interval: 10 * time.Second}}},
+		{ID: 101},
+	}, start)
+
+	if next := s.nextDue(); !next.Equal(start.Add(10 * time.Second)) {
+		t.Errorf("Expected next due at +10s, got %v", next.Sub(start))
+	}
+
+	// Missed runs are skipped rather than replayed
+	s.due(config.ContainerConfig{
+		ID:           100,
+		HealthChecks: []config.HealthCheck{{Interval: time.Minute}, {Interval: 10 * time.Second}},
+	}, start.Add(95*time.Second))
+	if next := s.nextDue(); !next.Equal(start.Add(30 * time.Second)) {
+		t.Errorf("Expected next due at +30s, got %v", next.Sub(start))
+	}
+}
+
+func TestScheduler_Spread(t *testing.T) {
+	containers := []config.ContainerConfig{
+		{ID: 100, HealthChecks: []config.HealthCheck{{Type: "tcp"}, {Type: "http"}}},
+		{ID: 101, HealthChecks: []config.HealthCheck{{Type: "tcp"}, {Type: "ping"}}},
+	}
+
+	s := newScheduler(config.MonitoringConfig{Interval: 40 * time.Second, SpreadChecks: true})
+	start := time.Unix(0, 0)
+	s.schedule(containers, start)
+
+	expected := map[int][]time.Duration{
+		100: {10 * time.Second, 20 * time.Second},
+		101: {30 * time.Second, 40 * time.Second},
+	}
+	for id, offsets := range expected {
+		for i, offset := range offsets {
+			if got := s.slots[id][i].next.Sub(start); got != offset {
+				t.Errorf("Container %d check %d: expected first run at +%v, got +%v", id, i, offset, got)
+			}
+		}
+	}
+
+	// Spread checks keep their interval after the first run
+	due, _ := s.due(containers[0], start.Add(10*time.Second))
+	if len(due) != 1 || due[0] != 0 {
+		t.Errorf("Expected only check 0 due, got %v", due)
+	}
+	if got := s.slots[100][0].next.Sub(start); got != 50*time.Second {
+		t.Errorf("Expected next run at +50s, got +%v", got)
+	}
+}
+
+func TestScheduler_Jitter(t *testing.T) {
+	container := config.ContainerConfig{ID: 100, HealthChecks: []config.HealthCheck{{Type: "tcp"}}}
+
+	s := newScheduler(config.MonitoringConfig{Interval: 30 * time.Second, Jitter: 5 * time.Second})
+	s.random = func(n int64) int64 { return n - 1 }
+	start := time.Unix(0, 0)
+	s.schedule([]config.ContainerConfig{container}, start)
+
+	maxJitter := 5*time.Second - 1
+	if got := s.nextDue().Sub(start); got != 30*time.Second+maxJitter {
+		t.Errorf("Expected jittered first run, got +%v", got)
+	}
+
+	// Jitter delays a run but does not accumulate across runs
+	for run := 1; run <= 3; run++ {
+		now := s.nextDue()
+		if due, _ := s.due(container, now); len(due) != 1 {
+			t.Fatalf("Expected check to be due on run %d", run)
+		}
+		expected := time.Duration(run+1)*30*time.Second + maxJitter
+		if got := s.nextDue().Sub(start); got != expected {
+			t.Errorf("Run %d: expected next run at +%v, got +%v", run, expected, got)
+		}
+	}
+}
+
+func TestScheduler_BackedOff(t *testing.T) {
+	container := config.ContainerConfig{
+		ID: 100,
+		HealthChecks: []config.HealthCheck{
+			{Type: "tcp"},
+			{Type: "postgres", Interval: 10 * time.Minute},
+		},
+	}
+
+	s := newScheduler(config.MonitoringConfig{
+		Interval:         30 * time.Second,
+		AdaptiveInterval: config.AdaptiveIntervalConfig{HealthyFor: time.Hour, Interval: 5 * time.Minute},
+	})
+	start := time.Unix(0, 0)
+	s.schedule([]config.ContainerConfig{container}, start)
+
+	if !s.setBackedOff(container, true, start) {
+		t.Fatal("Expected backing off to change the schedule")
+	}
+	if s.setBackedOff(container, true, start) {
+		t.Error("Expected backing off twice to be a no-op")
+	}
+
+	// Slower checks keep their own interval
+	if got, expected := s.intervals(container), []time.Duration{5 * time.Minute, 10 * time.Minute}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected backed-off intervals %v, got %v", expected, got)
+	}
+
+	// The first run was planned on the normal interval; the next on the backed-off one
+	if due, _ := s.due(container, start.Add(30*time.Second)); !reflect.DeepEqual(due, []int{0}) {
+		t.Fatalf("Expected check 0 due at 30s, got %v", due)
+	}
+	if due, _ := s.due(container, start.Add(time.Minute)); due != nil {
+		t.Errorf("Expected no checks due at 1m while backed off, got %v", due)
+	}
+
+	// Snapping back reschedules within the normal interval
+	now := start.Add(2 * time.Minute)
+	if !s.setBackedOff(container, false, now) {
+		t.Fatal("Expected restoring the normal interval to change the schedule")
+	}
+	if due, _ := s.due(container, now.Add(30*time.Second)); !reflect.DeepEqual(due, []int{0}) {
+		t.Errorf("Expected check 0 due 30s after snapping back, got %v", due)
+	}
+}
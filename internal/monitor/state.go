@@ -0,0 +1,122 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// State is the lifecycle state of a monitored container.
+type State string
+
+const (
+	// StateUnknown means no health check has completed yet
+	StateUnknown State = "unknown"
+	StateHealthy State = "healthy"
+	// StateDegraded means only warning-severity checks are failing
+	StateDegraded State = "degraded"
+	// StateFailing means critical checks are failing; failover starts once
+	// the failure threshold is reached
+	StateFailing            State = "failing"
+	StateFailoverInProgress State = "failover_in_progress"
+	// StateFailedOver means the container was failed over and has not
+	// passed a health check since
+	StateFailedOver  State = "failed_over"
+	StateMaintenance State = "maintenance"
+)
+
+// TransitionCallback is invoked whenever a container changes state.
+type TransitionCallback func(containerID int, from, to State)
+
+func (m *Monitor) AddTransitionCallback(callback TransitionCallback) {
+	m.transitionCallbacks = append(m.transitionCallbacks, callback)
+}
+
+// FailoverStarted marks a failover of the container as in progress. The
+// state is held until FailoverFinished.
+func (m *Monitor) FailoverStarted(containerID int) {
+	m.statesMu.Lock()
+	defer m.statesMu.Unlock()
+
+	if state, exists := m.states[containerID]; exists {
+		m.transition(state, StateFailoverInProgress)
+	}
+}
+
+// FailoverFinished ends a failover. After a successful failover the failure
+// history is cleared, since the container now runs from a fresh restore.
+func (m *Monitor) FailoverFinished(containerID int, success bool) {
+	m.statesMu.Lock()
+	defer m.statesMu.Unlock()
+
+	state, exists := m.states[containerID]
+	if !exists {
+		return
+	}
+
+	if success {
+		state.FailureCount = 0
+		state.ConsecutiveSuccesses = 0
+		state.RecentFailures = nil
+		m.transition(state, StateFailedOver)
+		return
+	}
+
+	m.transition(state, StateFailing)
+}
+
+// evaluateState derives the container's state from its health counters.
+// Callers must hold statesMu.
+func (m *Monitor) evaluateState(state *ContainerState) {
+	var next State
+	switch {
+	case state.State == StateFailoverInProgress:
+		// Only FailoverFinished ends a failover
+		return
+	case m.underMaintenance(state):
+		next = StateMaintenance
+	case state.FailureCount > 0:
+		next = StateFailing
+	case state.State == StateFailedOver && state.ConsecutiveSuccesses == 0:
+		next = StateFailedOver
+	case state.LastHealthCheck.IsZero():
+		next = StateUnknown
+	case state.ActiveWarnings > 0:
+		next = StateDegraded
+	default:
+		next = StateHealthy
+	}
+
+	if next != StateHealthy {
+		state.HealthySince = time.Time{}
+	} else if state.HealthySince.IsZero() {
+		state.HealthySince = time.Now()
+	}
+	m.transition(state, next)
+}
+
+func (m *Monitor) underMaintenance(state *ContainerState) bool {
+	_, ok := m.maintenance.Container(state.ID, state.Node)
+	return ok
+}
+
+// transition moves the container to a new state and notifies callbacks.
+// Callers must hold statesMu.
+func (m *Monitor) transition(state *ContainerState, to State) {
+	from := state.State
+	if from == to {
+		return
+	}
+	state.State = to
+
+	m.logger.WithFields(logrus.Fields{
+		"container_id": state.ID,
+		"from":         from,
+		"to":           to,
+	}).Debug("Container state changed")
+
+	m.publish(Event{Type: EventStateChanged, ContainerID: state.ID, Node: state.Node, From: from, To: to})
+	for _, callback := range m.transitionCallbacks {
+		go callback(state.ID, from, to)
+	}
+}
@@ -0,0 +1,128 @@
+package monitor
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/maintenance"
+	"github.com/sirupsen/logrus"
+)
+
+func TestMonitor_StateTransitions(t *testing.T) {
+	cfg := &config.Config{
+		Monitoring: config.MonitoringConfig{
+			FailureThreshold: 2,
+			HealthyThreshold: 1,
+		},
+	}
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	manager, err := maintenance.NewManager(&config.MaintenanceConfig{DefaultDuration: time.Hour}, "")
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	monitor := New(cfg, nil, logger)
+	monitor.SetMaintenance(manager)
+
+	var mu sync.Mutex
+	var transitions [][2]State
+	monitor.AddTransitionCallback(func(containerID int, from, to State) {
+		mu.Lock()
+		defer mu.Unlock()
+		transitions = append(transitions, [2]State{from, to})
+	})
+
+	monitor.initContainer(config.ContainerConfig{ID: 100}, time.Now())
+	state := monitor.states[100]
+
+	expectState := func(expected State) {
+		t.Helper()
+		if state.State != expected {
+			t.Errorf("Expected state %s, got %s", expected, state.State)
+		}
+	}
+
+	expectState(StateUnknown)
+
+	state.LastHealthCheck = time.Now()
+	monitor.recordSuccess(state)
+	expectState(StateHealthy)
+
+	state.ActiveWarnings = 1
+	monitor.recordSuccess(state)
+	expectState(StateDegraded)
+
+	state.ActiveWarnings = 0
+	monitor.recordFailure(state)
+	expectState(StateFailing)
+
+	monitor.FailoverStarted(100)
+	expectState(StateFailoverInProgress)
+
+	// Checks during the failover do not change the state
+	monitor.recordFailure(state)
+	expectState(StateFailoverInProgress)
+
+	monitor.FailoverFinished(100, true)
+	expectState(StateFailedOver)
+	if state.FailureCount != 0 {
+		t.Errorf("Expected failure count reset after failover, got %d", state.FailureCount)
+	}
+
+	monitor.recordSuccess(state)
+	expectState(StateHealthy)
+
+	if _, err := manager.Enable(maintenance.KindContainer, "100", 0, ""); err != nil {
+		t.Fatalf("Enable() error = %v", err)
+	}
+	monitor.recordSuccess(state)
+	expectState(StateMaintenance)
+
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	expected := [][2]State{
+		{StateUnknown, StateHealthy},
+		{StateHealthy, StateDegraded},
+		{StateDegraded, StateFailing},
+		{StateFailing, StateFailoverInProgress},
+		{StateFailoverInProgress, StateFailedOver},
+		{StateFailedOver, StateHealthy},
+		{StateHealthy, StateMaintenance},
+	}
+	if len(transitions) != len(expected) {
+		t.Fatalf("Expected transitions %v, got %v", expected, transitions)
+	}
+	// Callbacks run in goroutines, so only the set of transitions is stable
+	for _, want := range expected {
+		found := false
+		for _, got := range transitions {
+			if reflect.DeepEqual(got, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Expected transition %s -> %s, got %v", want[0], want[1], transitions)
+		}
+	}
+}
+
+func TestMonitor_FailoverFailed(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	monitor := New(&config.Config{}, nil, logger)
+	monitor.initContainer(config.ContainerConfig{ID: 100}, time.Now())
+
+	monitor.FailoverStarted(100)
+	monitor.FailoverFinished(100, false)
+	if state := monitor.states[100].State; state != StateFailing {
+		t.Errorf("Expected failing after failed failover, got %s", state)
+	}
+}
@@ -0,0 +1,92 @@
+package monitor
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/health"
+)
+
+// EventType identifies what a monitor Event reports.
+type EventType string
+
+const (
+	// EventStateChanged reports a container state transition in From and To
+	EventStateChanged EventType = "state_changed"
+	// EventCheckResult reports the Result of a health check run
+	EventCheckResult EventType = "check_result"
+	// EventFailoverTriggered reports that a container reached its failure
+	// threshold and failure callbacks were invoked
+	EventFailoverTriggered EventType = "failover_triggered"
+	// EventNodeFailed reports a node declared down; ContainerIDs lists the
+	// containers being failed over
+	EventNodeFailed EventType = "node_failed"
+)
+
+// subscriberBuffer is the number of events a subscriber may fall behind
+// before further events are dropped for it.
+const subscriberBuffer = 256
+
+// Event is something the monitor observed, delivered to subscribers.
+type Event struct {
+	Type         EventType
+	ContainerID  int
+	Node         string
+	Timestamp    time.Time
+	From         State
+	To           State
+	Result       *health.CheckResult
+	ContainerIDs []int
+}
+
+// subscribers holds the channels returned by Subscribe.
+type subscribers struct {
+	mu       sync.Mutex
+	channels map[<-chan Event]chan Event
+}
+
+// Subscribe returns a channel receiving every event the monitor emits from
+// now on. Delivery never blocks monitoring: a subscriber that falls more
+// than subscriberBuffer events behind misses events until it catches up.
+// Call Unsubscribe when done.
+func (m *Monitor) Subscribe() <-chan Event {
+	m.subscribers.mu.Lock()
+	defer m.subscribers.mu.Unlock()
+
+	if m.subscribers.channels == nil {
+		m.subscribers.channels = make(map[<-chan Event]chan Event)
+	}
+	ch := make(chan Event, subscriberBuffer)
+	m.subscribers.channels[ch] = ch
+	return ch
+}
+
+// Unsubscribe stops delivery to a channel returned by Subscribe and closes it.
+func (m *Monitor) Unsubscribe(events <-chan Event) {
+	m.subscribers.mu.Lock()
+	defer m.subscribers.mu.Unlock()
+
+	if ch, exists := m.subscribers.channels[events]; exists {
+		delete(m.subscribers.channels, events)
+		close(ch)
+	}
+}
+
+// publish delivers an event to all subscribers. It is safe to call while
+// holding statesMu.
+func (m *Monitor) publish(event Event) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	m.subscribers.mu.Lock()
+	defer m.subscribers.mu.Unlock()
+
+	for _, ch := range m.subscribers.channels {
+		select {
+		case ch <- event:
+		default:
+			m.logger.WithField("event", event.Type).Debug("Subscriber not keeping up, dropping monitor event")
+		}
+	}
+}
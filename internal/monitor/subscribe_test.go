@@ -0,0 +1,79 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+func TestMonitor_Subscribe(t *testing.T) {
+	cfg := &config.Config{
+		Monitoring: config.MonitoringConfig{
+			FailureThreshold: 1,
+		},
+	}
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	monitor := New(cfg, nil, logger)
+	events := monitor.Subscribe()
+
+	monitor.initContainer(config.ContainerConfig{ID: 100}, time.Now())
+	state := monitor.states[100]
+	state.Node = "node1"
+	monitor.recordFailure(state)
+
+	expected := []Event{
+		{Type: EventStateChanged, ContainerID: 100, Node: "node1", From: StateUnknown, To: StateFailing},
+		{Type: EventFailoverTriggered, ContainerID: 100, Node: "node1"},
+	}
+	for _, want := range expected {
+		select {
+		case got := <-events:
+			if got.Type != want.Type || got.ContainerID != want.ContainerID || got.Node != want.Node || got.From != want.From || got.To != want.To {
+				t.Errorf("Expected event %+v, got %+v", want, got)
+			}
+			if got.Timestamp.IsZero() {
+				t.Error("Expected event timestamp to be set")
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected %s event", want.Type)
+		}
+	}
+
+	monitor.Unsubscribe(events)
+	if _, ok := <-events; ok {
+		t.Error("Expected channel to be closed after Unsubscribe")
+	}
+
+	// Publishing without subscribers must not block
+	monitor.recordFailure(state)
+}
+
+func TestMonitor_SubscribeSlowConsumer(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	monitor := New(&config.Config{}, nil, logger)
+	events := monitor.Subscribe()
+	defer monitor.Unsubscribe(events)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < subscriberBuffer*2; i++ {
+			monitor.publish(Event{Type: EventCheckResult, ContainerID: i})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected publish not to block on a full subscriber")
+	}
+	if len(events) != subscriberBuffer {
+		t.Errorf("Expected %d buffered events, got %d", subscriberBuffer, len(events))
+	}
+}
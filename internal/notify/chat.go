@@ -0,0 +1,151 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+const telegramURL = "https://api.telegram.org"
+
+// severityIcons prefix chat messages so their severity shows at a glance.
+var severityIcons = map[Severity]string{
+	SeverityCritical: "🔴",
+	SeverityWarning:  "🟠",
+	SeverityInfo:     "🟢",
+}
+
+// chatSubject returns the container and node an event is about, such as
+// "container 100 (web) on node1".
+func chatSubject(event Event) string {
+	var parts []string
+	if event.ContainerID != 0 {
+		container := "container " + strconv.Itoa(event.ContainerID)
+		if event.ContainerName != "" {
+			container += " (" + event.ContainerName + ")"
+		}
+		parts = append(parts, container)
+	}
+	if event.Node != "" {
+		parts = append(parts, "on "+event.Node)
+	}
+	return strings.Join(parts, " ")
+}
+
+// chatHeading returns the heading of chat messages of an event, shown in
+// bold, and what the event is about. A title set by a template replaces
+// both.
+func chatHeading(event Event) (heading, subject string) {
+	if event.Title != "" {
+		return event.Title, ""
+	}
+	return string(event.Type), chatSubject(event)
+}
+
+// Telegram sends events as messages of a Telegram bot to a chat.
+type Telegram struct {
+	name     string
+	url      string
+	botToken string
+	chatID   string
+	mention  string
+	client   *http.Client
+}
+
+func NewTelegram(provider config.NotificationProvider) *Telegram {
+	base := provider.URL
+	if base == "" {
+		base = telegramURL
+	}
+	return &Telegram{
+		name:     provider.Name,
+		url:      strings.TrimSuffix(base, "/"),
+		botToken: provider.BotToken,
+		chatID:   provider.ChatID,
+		mention:  provider.Mention,
+		client:   &http.Client{},
+	}
+}
+
+func (t *Telegram) Name() string {
+	return t.name
+}
+
+type telegramMessage struct {
+	ChatID    string `json:"chat_id"`
+	Text      string `json:"text"`
+	ParseMode string `json:"parse_mode"`
+}
+
+func (t *Telegram) Notify(ctx context.Context, event Event) error {
+	heading, subject := chatHeading(event)
+	text := fmt.Sprintf("%s <b>%s</b>", severityIcons[event.Severity], html.EscapeString(heading))
+	if subject != "" {
+		text += " · " + html.EscapeString(subject)
+	}
+	text += "\n" + html.EscapeString(event.Message)
+	if event.Severity == SeverityCritical && t.mention != "" {
+		text += "\n" + html.EscapeString(t.mention)
+	}
+
+	body, err := json.Marshal(telegramMessage{ChatID: t.chatID, Text: text, ParseMode: "HTML"})
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	return postJSON(ctx, t.client, t.url+"/bot"+t.botToken+"/sendMessage", nil, body)
+}
+
+// Discord posts events to a Discord channel through a webhook.
+type Discord struct {
+	name    string
+	url     string
+	mention string
+	client  *http.Client
+}
+
+func NewDiscord(provider config.NotificationProvider) *Discord {
+	return &Discord{
+		name:    provider.Name,
+		url:     provider.URL,
+		mention: provider.Mention,
+		client:  &http.Client{},
+	}
+}
+
+func (d *Discord) Name() string {
+	return d.name
+}
+
+type discordMessage struct {
+	Content string `json:"content"`
+}
+
+// discordLimit is the longest message content Discord accepts.
+const discordLimit = 2000
+
+func (d *Discord) Notify(ctx context.Context, event Event) error {
+	heading, subject := chatHeading(event)
+	content := fmt.Sprintf("%s **%s**", severityIcons[event.Severity], heading)
+	if subject != "" {
+		content += " · " + subject
+	}
+	content += "\n" + event.Message
+	if event.Severity == SeverityCritical && d.mention != "" {
+		content = d.mention + " " + content
+	}
+	if runes := []rune(content); len(runes) > discordLimit {
+		content = string(runes[:discordLimit-3]) + "..."
+	}
+
+	body, err := json.Marshal(discordMessage{Content: content})
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	return postJSON(ctx, d.client, d.url, nil, body)
+}
@@ -0,0 +1,95 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+func TestTelegram(t *testing.T) {
+	received := make(chan telegramMessage, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bottoken/sendMessage" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var message telegramMessage
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- message
+	}))
+	defer server.Close()
+
+	telegram := NewTelegram(config.NotificationProvider{
+		Name: "tg", Type: "telegram", URL: server.URL, BotToken: "token", ChatID: "-100", Mention: "@oncall",
+	})
+	err := telegram.Notify(context.Background(), Event{
+		Type:          EventFailoverFailed,
+		Severity:      SeverityCritical,
+		ContainerID:   100,
+		ContainerName: "web",
+		Node:          "node1",
+		Message:       "Failover of container 100 failed: <timeout>",
+	})
+	if err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	message := <-received
+	if message.ChatID != "-100" || message.ParseMode != "HTML" {
+		t.Errorf("Unexpected message: %+v", message)
+	}
+	for _, want := range []string{"<b>failover_failed</b>", "container 100 (web) on node1", "&lt;timeout&gt;", "@oncall"} {
+		if !strings.Contains(message.Text, want) {
+			t.Errorf("Message %q does not contain %q", message.Text, want)
+		}
+	}
+}
+
+func TestDiscord(t *testing.T) {
+	received := make(chan discordMessage, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message discordMessage
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- message
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	discord := NewDiscord(config.NotificationProvider{Name: "dc", Type: "discord", URL: server.URL, Mention: "@here"})
+
+	tests := []struct {
+		name        string
+		severity    Severity
+		wantMention bool
+	}{
+		{"critical pings", SeverityCritical, true},
+		{"info does not ping", SeverityInfo, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := discord.Notify(context.Background(), Event{Type: EventNodeFailed, Severity: tt.severity, Node: "node1", Message: "Node node1 is down"})
+			if err != nil {
+				t.Fatalf("Notify failed: %v", err)
+			}
+			message := <-received
+			if strings.HasPrefix(message.Content, "@here ") != tt.wantMention {
+				t.Errorf("Content %q, want mention %v", message.Content, tt.wantMention)
+			}
+			if !strings.Contains(message.Content, "**node_failed** · on node1\nNode node1 is down") {
+				t.Errorf("Unexpected content %q", message.Content)
+			}
+		})
+	}
+}
@@ -0,0 +1,485 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// EscalationsFile is the name of the escalation state within the data
+// directory.
+const EscalationsFile = "escalations.json"
+
+// escalationInterval is how often escalations are checked for steps that
+// are due.
+const escalationInterval = 30 * time.Second
+
+// escalationRetention is how long escalations are kept once acknowledged or
+// through all of their steps, so that a failure repeating meanwhile joins
+// them rather than paging from the start again.
+const escalationRetention = 24 * time.Hour
+
+// ErrEscalationNotFound is returned when acknowledging an escalation that
+// is not open.
+var ErrEscalationNotFound = errors.New("escalation not found")
+
+// Escalation is a failure going through the steps of an escalation policy.
+type Escalation struct {
+	ID     int    `json:"id"`
+	Policy string `json:"policy"`
+	// Key identifies the failure, so that its later events join the
+	// escalation and resolving events close it
+	Key     string    `json:"key"`
+	Event   Event     `json:"event"`
+	Started time.Time `json:"started"`
+	// Step is the number of steps notified so far
+	Step           int       `json:"step"`
+	Steps          int       `json:"steps"`
+	AcknowledgedBy string    `json:"acknowledged_by,omitempty"`
+	AcknowledgedAt time.Time `json:"acknowledged_at,omitempty"`
+}
+
+// Acknowledged reports whether someone took the failure on, which stops
+// the escalation.
+func (e Escalation) Acknowledged() bool {
+	return !e.AcknowledgedAt.IsZero()
+}
+
+// escalationState is what the escalation state file holds.
+type escalationState struct {
+	NextID      int          `json:"next_id"`
+	Escalations []Escalation `json:"escalations"`
+}
+
+// escalator tracks failures through the configured escalation policies. A
+// nil escalator has no policies.
+type escalator struct {
+	mu       sync.Mutex
+	policies []config.NotificationEscalation
+	path     string
+	nextID   int
+	open     []*Escalation
+}
+
+func newEscalator(cfg *config.NotificationsConfig) (*escalator, error) {
+	if len(cfg.Escalations) == 0 {
+		return nil, nil
+	}
+	for _, policy := range cfg.Escalations {
+		if err := validateEventTypes("escalation "+policy.Name, policy.Events); err != nil {
+			return nil, err
+		}
+	}
+	return &escalator{policies: cfg.Escalations, nextID: 1}, nil
+}
+
+func (e *escalator) policy(name string) (config.NotificationEscalation, bool) {
+	for _, policy := range e.policies {
+		if policy.Name == name {
+			return policy, true
+		}
+	}
+	return config.NotificationEscalation{}, false
+}
+
+func policyMatches(policy config.NotificationEscalation, event Event) bool {
+	return routeMatches(config.NotificationRoute{
+		Events:     policy.Events,
+		Severities: policy.Severities,
+		Containers: policy.Containers,
+	}, event)
+}
+
+// escalationKey returns the failure an event is about and whether the
+// event resolves it.
+func escalationKey(event Event) (string, bool) {
+	if action, key, ok := incident(event); ok {
+		return key, action == incidentResolve
+	}
+	return fmt.Sprintf("proxwarden:%s:%d:%s", event.Type, event.ContainerID, event.Node), false
+}
+
+// reached adds the providers of the first step steps of policy to
+// providers.
+func reached(policy config.NotificationEscalation, steps int, providers map[string]bool) {
+	for i := 0; i < steps && i < len(policy.Steps); i++ {
+		for _, name := range policy.Steps[i].Providers {
+			providers[name] = true
+		}
+	}
+}
+
+// dueSteps returns how many steps of policy are due once an escalation has
+// run for elapsed.
+func dueSteps(policy config.NotificationEscalation, elapsed time.Duration) int {
+	steps := 0
+	for _, step := range policy.Steps {
+		if step.After > elapsed {
+			break
+		}
+		steps++
+	}
+	return steps
+}
+
+// handle starts, joins or closes the escalations of an event at now. It
+// returns the event with the escalation it belongs to in its details, the
+// providers the escalations send it to, and whether the state changed.
+func (e *escalator) handle(event Event, now time.Time) (Event, map[string]bool, bool) {
+	providers := make(map[string]bool)
+	if e == nil {
+		return event, providers, false
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	key, resolves := escalationKey(event)
+	changed := false
+	if resolves {
+		// Everyone told about the failure is told it is over
+		open := e.open[:0]
+		for _, escalation := range e.open {
+			if escalation.Key != key {
+				open = append(open, escalation)
+				continue
+			}
+			if policy, ok := e.policy(escalation.Policy); ok {
+				reached(policy, escalation.Step, providers)
+			}
+			changed = true
+		}
+		e.open = open
+		return event, providers, changed
+	}
+
+	var joined *Escalation
+	for _, policy := range e.policies {
+		if !policyMatches(policy, event) {
+			continue
+		}
+		escalation := e.find(policy.Name, key)
+		if escalation == nil {
+			escalation = &Escalation{
+				ID:      e.nextID,
+				Policy:  policy.Name,
+				Key:     key,
+				Event:   event,
+				Started: now,
+				Step:    dueSteps(policy, 0),
+				Steps:   len(policy.Steps),
+			}
+			e.nextID++
+			e.open = append(e.open, escalation)
+			changed = true
+		}
+		reached(policy, escalation.Step, providers)
+		if joined == nil {
+			joined = escalation
+		}
+	}
+	if joined != nil {
+		event = withEscalation(event, joined)
+	}
+	return event, providers, changed
+}
+
+// immediate returns the providers the escalations an event would start
+// send it to right away.
+func (e *escalator) immediate(event Event) map[string]bool {
+	providers := make(map[string]bool)
+	if e == nil {
+		return providers
+	}
+	if _, resolves := escalationKey(event); resolves {
+		return providers
+	}
+	for _, policy := range e.policies {
+		if policyMatches(policy, event) {
+			reached(policy, dueSteps(policy, 0), providers)
+		}
+	}
+	return providers
+}
+
+func (e *escalator) find(policy, key string) *Escalation {
+	for _, escalation := range e.open {
+		if escalation.Policy == policy && escalation.Key == key {
+			return escalation
+		}
+	}
+	return nil
+}
+
+// withEscalation returns event with the escalation it belongs to in its
+// details.
+func withEscalation(event Event, escalation *Escalation) Event {
+	details := make(map[string]string, len(event.Details)+3)
+	for key, value := range event.Details {
+		details[key] = value
+	}
+	details["escalation"] = escalation.Policy
+	details["escalation_id"] = strconv.Itoa(escalation.ID)
+	details["escalation_step"] = strconv.Itoa(escalation.Step)
+	event.Details = details
+	return event
+}
+
+// escalatedEvent is an event due for the providers of a later step.
+type escalatedEvent struct {
+	event     Event
+	providers map[string]bool
+}
+
+// due advances the unacknowledged escalations whose next steps are due at
+// now and returns what to send for them. It also forgets escalations past
+// their retention, and reports whether the state changed.
+func (e *escalator) due(now time.Time) ([]escalatedEvent, bool) {
+	if e == nil {
+		return nil, false
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var events []escalatedEvent
+	changed := false
+	open := e.open[:0]
+	for _, escalation := range e.open {
+		policy, ok := e.policy(escalation.Policy)
+		if !ok || e.expired(escalation, policy, now) {
+			changed = true
+			continue
+		}
+		open = append(open, escalation)
+		if escalation.Acknowledged() {
+			continue
+		}
+
+		elapsed := now.Sub(escalation.Started)
+		steps := dueSteps(policy, elapsed)
+		if steps <= escalation.Step {
+			continue
+		}
+		providers := make(map[string]bool)
+		for i := escalation.Step; i < steps; i++ {
+			for _, name := range policy.Steps[i].Providers {
+				providers[name] = true
+			}
+		}
+		escalation.Step = steps
+		changed = true
+
+		event := withEscalation(escalation.Event, escalation)
+		event.Message = fmt.Sprintf("%s (unacknowledged for %s)", event.Message, elapsed.Round(time.Second))
+		event.Timestamp = now
+		events = append(events, escalatedEvent{event: event, providers: providers})
+	}
+	e.open = open
+	return events, changed
+}
+
+func (e *escalator) expired(escalation *Escalation, policy config.NotificationEscalation, now time.Time) bool {
+	if escalation.Acknowledged() {
+		return now.Sub(escalation.AcknowledgedAt) >= escalationRetention
+	}
+	if escalation.Step < len(policy.Steps) {
+		return false
+	}
+	return now.Sub(escalation.Started) >= policy.Steps[len(policy.Steps)-1].After+escalationRetention
+}
+
+// acknowledge stops the escalation with the given ID.
+func (e *escalator) acknowledge(id int, actor string, now time.Time) (Escalation, error) {
+	if e == nil {
+		return Escalation{}, ErrEscalationNotFound
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, escalation := range e.open {
+		if escalation.ID != id {
+			continue
+		}
+		if !escalation.Acknowledged() {
+			escalation.AcknowledgedBy = actor
+			escalation.AcknowledgedAt = now
+		}
+		return *escalation, nil
+	}
+	return Escalation{}, ErrEscalationNotFound
+}
+
+func (e *escalator) list() []Escalation {
+	if e == nil {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	escalations := make([]Escalation, 0, len(e.open))
+	for _, escalation := range e.open {
+		escalations = append(escalations, *escalation)
+	}
+	return escalations
+}
+
+func (e *escalator) save() error {
+	if e == nil || e.path == "" {
+		return nil
+	}
+	e.mu.Lock()
+	state := escalationState{NextID: e.nextID}
+	for _, escalation := range e.open {
+		state.Escalations = append(state.Escalations, *escalation)
+	}
+	e.mu.Unlock()
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode escalations: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(e.path), 0o750); err != nil {
+		return fmt.Errorf("failed to create escalation directory: %w", err)
+	}
+
+	tmp := e.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o640); err != nil {
+		return fmt.Errorf("failed to write escalations: %w", err)
+	}
+	if err := os.Rename(tmp, e.path); err != nil {
+		return fmt.Errorf("failed to write escalations: %w", err)
+	}
+	return nil
+}
+
+// load restores the escalations a previous run left in the file at path,
+// dropping those of policies no longer configured, and keeps saving them
+// there.
+func (e *escalator) load(path string) error {
+	if e == nil {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.path = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read escalations: %w", err)
+	}
+	var state escalationState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to decode escalations: %w", err)
+	}
+
+	if state.NextID > e.nextID {
+		e.nextID = state.NextID
+	}
+	e.open = nil
+	for i := range state.Escalations {
+		escalation := state.Escalations[i]
+		policy, ok := e.policy(escalation.Policy)
+		if !ok {
+			continue
+		}
+		escalation.Steps = len(policy.Steps)
+		if escalation.Step > escalation.Steps {
+			escalation.Step = escalation.Steps
+		}
+		e.open = append(e.open, &escalation)
+	}
+	return nil
+}
+
+// PersistEscalations keeps the state of escalations in the file at path,
+// restoring the escalations a previous run left open, so acknowledgments
+// and pending steps survive restarts.
+func (d *Dispatcher) PersistEscalations(path string) error {
+	if d == nil {
+		return nil
+	}
+	return d.escalator.load(path)
+}
+
+// Escalations returns the open escalations, oldest first.
+func (d *Dispatcher) Escalations() []Escalation {
+	if d == nil {
+		return nil
+	}
+	return d.escalator.list()
+}
+
+// Acknowledge stops the escalation with the given ID, as acknowledged by
+// actor. Its later steps are no longer notified.
+func (d *Dispatcher) Acknowledge(id int, actor string) (Escalation, error) {
+	if d == nil {
+		return Escalation{}, ErrEscalationNotFound
+	}
+	escalation, err := d.escalator.acknowledge(id, actor, time.Now())
+	if err != nil {
+		return Escalation{}, err
+	}
+	d.logger.WithFields(logrus.Fields{
+		"escalation":   escalation.ID,
+		"policy":       escalation.Policy,
+		"container_id": escalation.Event.ContainerID,
+		"actor":        actor,
+	}).Info("Escalation acknowledged")
+	d.saveEscalations()
+	return escalation, nil
+}
+
+// RunEscalations notifies the later steps of unacknowledged escalations as
+// they become due, until ctx is cancelled.
+func (d *Dispatcher) RunEscalations(ctx context.Context) {
+	if d == nil || d.escalator == nil {
+		return
+	}
+
+	ticker := time.NewTicker(escalationInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.escalate(ctx, time.Now())
+		}
+	}
+}
+
+// escalate sends the escalation steps due at now.
+func (d *Dispatcher) escalate(ctx context.Context, now time.Time) {
+	events, changed := d.escalator.due(now)
+	if changed {
+		d.saveEscalations()
+	}
+	for _, escalated := range events {
+		d.logger.WithFields(logrus.Fields{
+			"escalation":   escalated.event.Details["escalation_id"],
+			"policy":       escalated.event.Details["escalation"],
+			"step":         escalated.event.Details["escalation_step"],
+			"container_id": escalated.event.ContainerID,
+		}).Warn("Escalating unacknowledged notification")
+		providers := escalated.providers
+		d.deliver(ctx, escalated.event, func(name string) bool { return providers[name] })
+	}
+}
+
+func (d *Dispatcher) saveEscalations() {
+	if err := d.escalator.save(); err != nil {
+		d.logger.WithField("error", err).Error("Failed to save escalations")
+	}
+}
@@ -0,0 +1,138 @@
+package notify
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+func testEscalator(t *testing.T) *escalator {
+	t.Helper()
+	e, err := newEscalator(&config.NotificationsConfig{Escalations: []config.NotificationEscalation{{
+		Name:       "failures",
+		Severities: []string{"critical"},
+		Steps: []config.EscalationStep{
+			{Providers: []string{"chat"}},
+			{After: 15 * time.Minute, Providers: []string{"email"}},
+			{After: 30 * time.Minute, Providers: []string{"pager"}},
+		},
+	}}})
+	if err != nil {
+		t.Fatalf("newEscalator() error = %v", err)
+	}
+	return e
+}
+
+func providerNames(providers map[string]bool) []string {
+	var names []string
+	for _, name := range []string{"chat", "email", "pager"} {
+		if providers[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func TestEscalator(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	failed := Event{Type: EventContainerFailed, Severity: SeverityCritical, ContainerID: 100}
+
+	t.Run("escalates until acknowledged", func(t *testing.T) {
+		e := testEscalator(t)
+		event, providers, changed := e.handle(failed, start)
+		if !changed || event.Details["escalation_id"] != "1" {
+			t.Fatalf("handle() = %v, %v, want a new escalation", event.Details, changed)
+		}
+		if got := providerNames(providers); len(got) != 1 || got[0] != "chat" {
+			t.Errorf("handle() providers = %v, want [chat]", got)
+		}
+
+		if events, _ := e.due(start.Add(10 * time.Minute)); len(events) != 0 {
+			t.Errorf("due() before the second step = %d events", len(events))
+		}
+		events, changed := e.due(start.Add(16 * time.Minute))
+		if !changed || len(events) != 1 {
+			t.Fatalf("due() = %d events, want 1", len(events))
+		}
+		if got := providerNames(events[0].providers); len(got) != 1 || got[0] != "email" {
+			t.Errorf("due() providers = %v, want [email]", got)
+		}
+		if events[0].event.Details["escalation_step"] != "2" {
+			t.Errorf("due() step = %q", events[0].event.Details["escalation_step"])
+		}
+
+		// A later event of the same failure reaches every step so far
+		_, providers, changed = e.handle(Event{Type: EventFailoverFailed, Severity: SeverityCritical, ContainerID: 100}, start.Add(20*time.Minute))
+		if changed || len(providerNames(providers)) != 2 {
+			t.Errorf("handle() of a later event = %v, %v", providerNames(providers), changed)
+		}
+
+		if _, err := e.acknowledge(1, "api:alice", start.Add(25*time.Minute)); err != nil {
+			t.Fatalf("acknowledge() error = %v", err)
+		}
+		if events, _ := e.due(start.Add(time.Hour)); len(events) != 0 {
+			t.Errorf("due() after acknowledgment = %d events", len(events))
+		}
+		if _, err := e.acknowledge(2, "api:alice", start); err != ErrEscalationNotFound {
+			t.Errorf("acknowledge() of unknown escalation error = %v", err)
+		}
+		if escalations, _ := e.due(start.Add(25*time.Minute + escalationRetention)); len(escalations) != 0 || len(e.list()) != 0 {
+			t.Errorf("list() after retention = %d escalations", len(e.list()))
+		}
+	})
+
+	t.Run("resolved", func(t *testing.T) {
+		e := testEscalator(t)
+		e.handle(failed, start)
+		e.due(start.Add(40 * time.Minute))
+
+		_, providers, changed := e.handle(Event{Type: EventFailoverSucceeded, Severity: SeverityInfo, ContainerID: 100}, start.Add(45*time.Minute))
+		if !changed || len(providerNames(providers)) != 3 {
+			t.Errorf("handle() of resolving event = %v, %v, want all providers", providerNames(providers), changed)
+		}
+		if len(e.list()) != 0 {
+			t.Errorf("list() after resolving = %d escalations", len(e.list()))
+		}
+	})
+
+	t.Run("not matching", func(t *testing.T) {
+		e := testEscalator(t)
+		_, providers, changed := e.handle(Event{Type: EventCheckWarning, Severity: SeverityWarning, ContainerID: 100}, start)
+		if changed || len(providers) != 0 {
+			t.Errorf("handle() = %v, %v, want no escalation", providers, changed)
+		}
+	})
+}
+
+func TestEscalator_Persist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), EscalationsFile)
+	start := time.Now()
+
+	e := testEscalator(t)
+	if err := e.load(path); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	e.handle(Event{Type: EventContainerFailed, Severity: SeverityCritical, ContainerID: 100}, start)
+	e.handle(Event{Type: EventContainerFailed, Severity: SeverityCritical, ContainerID: 101}, start)
+	if _, err := e.acknowledge(2, "user:bob", start); err != nil {
+		t.Fatalf("acknowledge() error = %v", err)
+	}
+	if err := e.save(); err != nil {
+		t.Fatalf("save() error = %v", err)
+	}
+
+	restored := testEscalator(t)
+	if err := restored.load(path); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	escalations := restored.list()
+	if len(escalations) != 2 || escalations[1].AcknowledgedBy != "user:bob" || escalations[0].Acknowledged() {
+		t.Fatalf("list() after load = %+v", escalations)
+	}
+	event, _, _ := restored.handle(Event{Type: EventContainerFailed, Severity: SeverityCritical, ContainerID: 102}, start)
+	if event.Details["escalation_id"] != "3" {
+		t.Errorf("escalation ID after load = %q, want 3", event.Details["escalation_id"])
+	}
+}
@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Actions of incident-management providers on an incident.
+const (
+	incidentTrigger = "trigger"
+	incidentResolve = "resolve"
+)
+
+// incident returns the incident an event opens or resolves, keyed so that
+// the events of one problem land on the same incident. Events that are not
+// about a problem ProxWarden tracks to its end are not incidents.
+func incident(event Event) (action, key string, ok bool) {
+	action, key, ok = incidentKey(event)
+	// Test events never touch the incidents of real failures
+	if ok && event.Details["test"] == "true" {
+		key += ":test"
+	}
+	return action, key, ok
+}
+
+func incidentKey(event Event) (action, key string, ok bool) {
+	container := "proxwarden:container:" + strconv.Itoa(event.ContainerID)
+	switch event.Type {
+	case EventContainerFailed, EventFailoverStarted, EventFailoverFailed,
+		EventFailoverInterrupted, EventCircuitOpen:
+		return incidentTrigger, container, true
+	case EventFailoverSucceeded:
+		return incidentResolve, container, true
+	case EventContainerFlapping:
+		return incidentTrigger, container + ":flapping", true
+	case EventFlappingCleared:
+		return incidentResolve, container + ":flapping", true
+	case EventCheckWarning:
+		return incidentTrigger, container + ":check:" + event.Details["check_type"] + ":" + event.Details["target"], true
+	case EventCheckWarningCleared:
+		return incidentResolve, container + ":check:" + event.Details["check_type"] + ":" + event.Details["target"], true
+	case EventQuorumLost:
+		return incidentTrigger, "proxwarden:quorum", true
+	case EventQuorumRestored:
+		return incidentResolve, "proxwarden:quorum", true
+	case EventDrillFailed:
+		return incidentTrigger, container + ":drill", true
+	case EventDrillSucceeded:
+		return incidentResolve, container + ":drill", true
+	}
+	return "", "", false
+}
+
+// incidentDetails returns the container, node and details of an event as
+// the custom details of its incident.
+func incidentDetails(event Event) map[string]string {
+	details := map[string]string{"event": string(event.Type)}
+	if event.ContainerID != 0 {
+		details["container_id"] = strconv.Itoa(event.ContainerID)
+	}
+	if event.ContainerName != "" {
+		details["container_name"] = event.ContainerName
+	}
+	if event.Node != "" {
+		details["node"] = event.Node
+	}
+	for key, value := range event.Details {
+		details[key] = value
+	}
+	return details
+}
+
+// historyLink returns the failover history page of the event's container,
+// from a URL with {container_id} in it, or "" without one.
+func historyLink(historyURL string, event Event) string {
+	if historyURL == "" || event.ContainerID == 0 {
+		return ""
+	}
+	return strings.ReplaceAll(historyURL, "{container_id}", strconv.Itoa(event.ContainerID))
+}
+
+// incidentSummary returns the event title or message cut to limit
+// characters, as incident titles are limited in length.
+func incidentSummary(event Event, limit int) string {
+	summary := event.Title
+	if summary == "" {
+		summary = event.Message
+	}
+	if summary == "" {
+		summary = fmt.Sprintf("ProxWarden %s", event.Type)
+	}
+	if runes := []rune(summary); len(runes) > limit {
+		summary = string(runes[:limit-3]) + "..."
+	}
+	return summary
+}
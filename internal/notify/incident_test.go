@@ -0,0 +1,149 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+func TestIncident(t *testing.T) {
+	tests := []struct {
+		name       string
+		event      Event
+		wantAction string
+		wantKey    string
+		wantOK     bool
+	}{
+		{"failover started", Event{Type: EventFailoverStarted, ContainerID: 100}, incidentTrigger, "proxwarden:container:100", true},
+		{"failover failed", Event{Type: EventFailoverFailed, ContainerID: 100}, incidentTrigger, "proxwarden:container:100", true},
+		{"failover succeeded", Event{Type: EventFailoverSucceeded, ContainerID: 100}, incidentResolve, "proxwarden:container:100", true},
+		{"warning", Event{Type: EventCheckWarning, ContainerID: 100, Details: map[string]string{"check_type": "disk", "target": "/"}}, incidentTrigger, "proxwarden:container:100:check:disk:/", true},
+		{"warning cleared", Event{Type: EventCheckWarningCleared, ContainerID: 100, Details: map[string]string{"check_type": "disk", "target": "/"}}, incidentResolve, "proxwarden:container:100:check:disk:/", true},
+		{"quorum restored", Event{Type: EventQuorumRestored}, incidentResolve, "proxwarden:quorum", true},
+		{"test", Event{Type: EventFailoverFailed, ContainerID: 100, Details: map[string]string{"test": "true"}}, incidentTrigger, "proxwarden:container:100:test", true},
+		{"backup failed", Event{Type: EventBackupFailed, ContainerID: 100}, "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, key, ok := incident(tt.event)
+			if action != tt.wantAction || key != tt.wantKey || ok != tt.wantOK {
+				t.Errorf("incident() = %q, %q, %v, want %q, %q, %v", action, key, ok, tt.wantAction, tt.wantKey, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestPagerDuty(t *testing.T) {
+	received := make(chan pagerDutyEvent, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event pagerDutyEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- event
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	pd := NewPagerDuty(config.NotificationProvider{
+		Name:       "pd",
+		Type:       "pagerduty",
+		URL:        server.URL,
+		RoutingKey: "key",
+		HistoryURL: "https://grafana.example.com/history?container={container_id}",
+	})
+
+	err := pd.Notify(context.Background(), Event{
+		Type:        EventFailoverFailed,
+		Severity:    SeverityCritical,
+		ContainerID: 100,
+		Node:        "node1",
+		Message:     "Failover of container 100 failed",
+		Details:     map[string]string{"target_node": "node2"},
+	})
+	if err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	trigger := <-received
+	if trigger.RoutingKey != "key" || trigger.EventAction != incidentTrigger || trigger.DedupKey != "proxwarden:container:100" {
+		t.Errorf("Unexpected trigger: %+v", trigger)
+	}
+	if trigger.Payload == nil || trigger.Payload.Severity != "critical" || trigger.Payload.Source != "node1" ||
+		trigger.Payload.CustomDetails["target_node"] != "node2" || trigger.Payload.CustomDetails["container_id"] != "100" {
+		t.Errorf("Unexpected payload: %+v", trigger.Payload)
+	}
+	if len(trigger.Links) != 1 || trigger.Links[0].Href != "https://grafana.example.com/history?container=100" {
+		t.Errorf("Unexpected links: %+v", trigger.Links)
+	}
+
+	if err := pd.Notify(context.Background(), Event{Type: EventFailoverSucceeded, ContainerID: 100}); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	resolve := <-received
+	if resolve.EventAction != incidentResolve || resolve.DedupKey != "proxwarden:container:100" || resolve.Payload != nil {
+		t.Errorf("Unexpected resolve: %+v", resolve)
+	}
+
+	// Events that are not incidents are not sent
+	if err := pd.Notify(context.Background(), Event{Type: EventBackupFailed, ContainerID: 100}); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if len(received) != 0 {
+		t.Errorf("Unexpected event sent: %+v", <-received)
+	}
+}
+
+func TestOpsgenie(t *testing.T) {
+	type request struct {
+		path  string
+		alert opsgenieAlert
+	}
+	received := make(chan request, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "GenieKey secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var alert opsgenieAlert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- request{path: r.URL.EscapedPath() + "?" + r.URL.RawQuery, alert: alert}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	og := NewOpsgenie(config.NotificationProvider{Name: "og", Type: "opsgenie", URL: server.URL, APIKey: "secret"})
+
+	err := og.Notify(context.Background(), Event{
+		Type:          EventFailoverStarted,
+		Severity:      SeverityWarning,
+		ContainerID:   100,
+		ContainerName: "web",
+		Message:       "Failing over container 100 from node1 to node2",
+	})
+	if err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	create := <-received
+	if create.path != "/v2/alerts?" {
+		t.Errorf("Create path = %s", create.path)
+	}
+	if create.alert.Alias != "proxwarden:container:100" || create.alert.Priority != "P3" || create.alert.Details["container_name"] != "web" {
+		t.Errorf("Unexpected alert: %+v", create.alert)
+	}
+
+	if err := og.Notify(context.Background(), Event{Type: EventFailoverSucceeded, ContainerID: 100}); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if close := <-received; close.path != "/v2/alerts/proxwarden:container:100/close?identifierType=alias" {
+		t.Errorf("Close path = %s", close.path)
+	}
+}
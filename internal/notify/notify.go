@@ -0,0 +1,339 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+type EventType string
+
+const (
+	EventCheckWarning        EventType = "check_warning"
+	EventCheckWarningCleared EventType = "check_warning_cleared"
+	EventContainerFailed     EventType = "container_failed"
+	EventContainerFlapping   EventType = "container_flapping"
+	EventFlappingCleared     EventType = "container_flapping_cleared"
+	EventNodeFailed          EventType = "node_failed"
+	EventQuorumLost          EventType = "quorum_lost"
+	EventQuorumRestored      EventType = "quorum_restored"
+	EventFailoverStarted     EventType = "failover_started"
+	EventFailoverSucceeded   EventType = "failover_succeeded"
+	EventFailoverFailed      EventType = "failover_failed"
+	EventCircuitOpen         EventType = "failover_circuit_open"
+	EventFailbackStarted     EventType = "failback_started"
+	EventFailbackSucceeded   EventType = "failback_succeeded"
+	EventFailbackFailed      EventType = "failback_failed"
+	EventDNSUpdateFailed     EventType = "dns_update_failed"
+	EventFloatingIPFailed    EventType = "floating_ip_failed"
+	EventStandbySyncFailed   EventType = "standby_sync_failed"
+	EventBackupFailed        EventType = "backup_failed"
+	EventReplicationFailed   EventType = "backup_replication_failed"
+	EventDrillStarted        EventType = "drill_started"
+	EventDrillSucceeded      EventType = "drill_succeeded"
+	EventDrillFailed         EventType = "drill_failed"
+	EventFailoverPending     EventType = "failover_pending_approval"
+	EventFailoverImminent    EventType = "failover_imminent"
+	EventFailoverRejected    EventType = "failover_rejected"
+	EventFailoverInterrupted EventType = "failover_interrupted"
+)
+
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// Event describes something operators should be told about. Title is only
+// set by title templates; providers use their default title without one.
+type Event struct {
+	Type          EventType         `json:"type"`
+	Severity      Severity          `json:"severity"`
+	ContainerID   int               `json:"container_id,omitempty"`
+	ContainerName string            `json:"container_name,omitempty"`
+	Node          string            `json:"node,omitempty"`
+	Title         string            `json:"title,omitempty"`
+	Message       string            `json:"message"`
+	Timestamp     time.Time         `json:"timestamp"`
+	Details       map[string]string `json:"details,omitempty"`
+}
+
+// ParseEventType returns the event type named name.
+func ParseEventType(name string) (EventType, error) {
+	if !eventTypes[EventType(name)] {
+		return "", fmt.Errorf("unknown event %q", name)
+	}
+	return EventType(name), nil
+}
+
+// SampleEvent returns an event of type eventType about a made-up failover
+// of a container, for testing notifications and previewing templates.
+func SampleEvent(eventType EventType, containerID int, containerName string) Event {
+	severity := SeverityInfo
+	switch {
+	case strings.HasSuffix(string(eventType), "_failed"), strings.HasSuffix(string(eventType), "_lost"),
+		eventType == EventCircuitOpen, eventType == EventFailoverInterrupted:
+		severity = SeverityCritical
+	case strings.HasSuffix(string(eventType), "_started"), strings.HasSuffix(string(eventType), "_warning"),
+		eventType == EventContainerFlapping, eventType == EventFailoverPending,
+		eventType == EventFailoverImminent, eventType == EventFailoverRejected:
+		severity = SeverityWarning
+	}
+	return Event{
+		Type:          eventType,
+		Severity:      severity,
+		ContainerID:   containerID,
+		ContainerName: containerName,
+		Node:          "node1",
+		Message:       fmt.Sprintf("Test %s notification from ProxWarden for container %d", eventType, containerID),
+		Timestamp:     time.Now(),
+		Details: map[string]string{
+			"target_node": "node2",
+			"strategy":    "restore",
+			"duration":    "1m30s",
+			"check_type":  "tcp",
+			"target":      "192.168.1.100",
+			"test":        "true",
+		},
+	}
+}
+
+// Notifier delivers events to a single destination.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, event Event) error
+}
+
+// Dispatcher fans events out to all configured notifiers. A nil Dispatcher
+// is valid and discards events.
+type Dispatcher struct {
+	notifiers  []Notifier
+	router     router
+	suppressor *suppressor
+	escalator  *escalator
+	timeout    time.Duration
+	logger     *logrus.Logger
+	wg         sync.WaitGroup
+}
+
+func NewDispatcher(cfg *config.NotificationsConfig, logger *logrus.Logger) (*Dispatcher, error) {
+	d := &Dispatcher{
+		timeout: defaultTimeout,
+		logger:  logger,
+	}
+
+	for _, provider := range cfg.Providers {
+		notifier, err := newNotifier(provider)
+		if err != nil {
+			return nil, fmt.Errorf("notification provider %q: %w", provider.Name, err)
+		}
+		templates, err := parseTemplates(cfg.Template, provider.Template)
+		if err != nil {
+			return nil, fmt.Errorf("notification provider %q: %w", provider.Name, err)
+		}
+		if templates != nil {
+			notifier = &templated{Notifier: notifier, templates: templates, logger: logger}
+		}
+		d.notifiers = append(d.notifiers, notifier)
+	}
+
+	var err error
+	if d.router, err = newRouter(cfg); err != nil {
+		return nil, fmt.Errorf("notifications: %w", err)
+	}
+	if d.suppressor, err = newSuppressor(cfg); err != nil {
+		return nil, fmt.Errorf("notifications: %w", err)
+	}
+	if d.escalator, err = newEscalator(cfg); err != nil {
+		return nil, fmt.Errorf("notifications: %w", err)
+	}
+
+	return d, nil
+}
+
+func newNotifier(provider config.NotificationProvider) (Notifier, error) {
+	switch provider.Type {
+	case "webhook":
+		return NewWebhook(provider), nil
+	case "plugin":
+		return NewPlugin(provider), nil
+	case "pagerduty":
+		return NewPagerDuty(provider), nil
+	case "opsgenie":
+		return NewOpsgenie(provider), nil
+	case "telegram":
+		return NewTelegram(provider), nil
+	case "discord":
+		return NewDiscord(provider), nil
+	case "teams":
+		return NewTeams(provider), nil
+	case "ntfy":
+		return NewNtfy(provider), nil
+	case "gotify":
+		return NewGotify(provider), nil
+	default:
+		return nil, fmt.Errorf("unknown provider type: %s", provider.Type)
+	}
+}
+
+// Notify delivers the event to every notifier routed to it, or its
+// escalations reached, and returns the first error. Events repeating one
+// sent recently are dropped.
+func (d *Dispatcher) Notify(ctx context.Context, event Event) error {
+	if d == nil {
+		return nil
+	}
+
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	if ok, reason := d.suppressor.allow(event, time.Now()); !ok {
+		d.logger.WithFields(logrus.Fields{
+			"event":        event.Type,
+			"container_id": event.ContainerID,
+			"reason":       reason,
+		}).Debug("Notification suppressed")
+		return nil
+	}
+
+	event, escalated, changed := d.escalator.handle(event, time.Now())
+	if changed {
+		d.saveEscalations()
+	}
+	return d.deliver(ctx, event, func(name string) bool {
+		return escalated[name] || d.router.wants(name, event)
+	})
+}
+
+// deliver sends the event to the notifiers wants accepts and returns the
+// first error.
+func (d *Dispatcher) deliver(ctx context.Context, event Event, wants func(name string) bool) error {
+	var firstErr error
+	for _, notifier := range d.notifiers {
+		if !wants(notifier.Name()) {
+			continue
+		}
+		notifyCtx, cancel := context.WithTimeout(ctx, d.timeout)
+		err := notifier.Notify(notifyCtx, event)
+		cancel()
+
+		if err != nil {
+			d.logger.WithFields(logrus.Fields{
+				"notifier": notifier.Name(),
+				"event":    event.Type,
+				"error":    err,
+			}).Error("Failed to send notification")
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return firstErr
+}
+
+// Rendering is an event as a provider would send it.
+type Rendering struct {
+	Provider string
+	Event    Event
+	// Routed tells whether the routes, or the first steps of escalations,
+	// send the event to the provider
+	Routed bool
+	Err    error
+}
+
+// Render returns the event as every provider would send it, with its
+// templates applied, without sending it.
+func (d *Dispatcher) Render(event Event) []Rendering {
+	if d == nil {
+		return nil
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	immediate := d.escalator.immediate(event)
+	var renderings []Rendering
+	for _, notifier := range d.notifiers {
+		routed := immediate[notifier.Name()] || d.router.wants(notifier.Name(), event)
+		rendering := Rendering{Provider: notifier.Name(), Event: event, Routed: routed}
+		if t, ok := notifier.(*templated); ok {
+			rendering.Event, rendering.Err = t.templates.apply(event)
+		}
+		renderings = append(renderings, rendering)
+	}
+	return renderings
+}
+
+// Delivery is the outcome of sending an event to a provider.
+type Delivery struct {
+	Provider string
+	// Routed tells whether the routes, or the first steps of escalations,
+	// send such events to the provider
+	Routed   bool
+	Duration time.Duration
+	Err      error
+}
+
+// SendTest sends the event to every provider, whatever the routes, and
+// reports how each one went. It is neither suppressed nor escalated.
+func (d *Dispatcher) SendTest(ctx context.Context, event Event) []Delivery {
+	if d == nil {
+		return nil
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	immediate := d.escalator.immediate(event)
+	var deliveries []Delivery
+	for _, notifier := range d.notifiers {
+		delivery := Delivery{
+			Provider: notifier.Name(),
+			Routed:   immediate[notifier.Name()] || d.router.wants(notifier.Name(), event),
+		}
+		start := time.Now()
+		notifyCtx, cancel := context.WithTimeout(ctx, d.timeout)
+		delivery.Err = notifier.Notify(notifyCtx, event)
+		cancel()
+		delivery.Duration = time.Since(start)
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries
+}
+
+// Send delivers the event in the background so callers on the monitoring or
+// failover path are never blocked by a slow destination.
+func (d *Dispatcher) Send(event Event) {
+	if d == nil || len(d.notifiers) == 0 {
+		return
+	}
+
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.Notify(context.Background(), event)
+	}()
+}
+
+// Wait blocks until all background sends have finished.
+func (d *Dispatcher) Wait() {
+	if d == nil {
+		return
+	}
+	d.wg.Wait()
+}
@@ -0,0 +1,196 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+func TestNewDispatcher(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	tests := []struct {
+		name      string
+		providers []config.NotificationProvider
+		expectErr bool
+	}{
+		{"no providers", nil, false},
+		{"webhook", []config.NotificationProvider{{Name: "ops", Type: "webhook", URL: "http://localhost"}}, false},
+		{"pagerduty", []config.NotificationProvider{{Name: "pd", Type: "pagerduty", RoutingKey: "key"}}, false},
+		{"opsgenie", []config.NotificationProvider{{Name: "og", Type: "opsgenie", APIKey: "key"}}, false},
+		{"telegram", []config.NotificationProvider{{Name: "tg", Type: "telegram", BotToken: "token", ChatID: "-100"}}, false},
+		{"discord", []config.NotificationProvider{{Name: "dc", Type: "discord", URL: "http://localhost"}}, false},
+		{"teams", []config.NotificationProvider{{Name: "ms", Type: "teams", URL: "http://localhost"}}, false},
+		{"ntfy", []config.NotificationProvider{{Name: "push", Type: "ntfy", Topic: "proxwarden"}}, false},
+		{"gotify", []config.NotificationProvider{{Name: "push", Type: "gotify", URL: "http://localhost", Token: "token"}}, false},
+		{"unknown type", []config.NotificationProvider{{Name: "ops", Type: "carrier-pigeon"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewDispatcher(&config.NotificationsConfig{Providers: tt.providers}, logger)
+			if tt.expectErr && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestDispatcher_Webhook(t *testing.T) {
+	received := make(chan Event, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var event Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- event
+	}))
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	dispatcher, err := NewDispatcher(&config.NotificationsConfig{
+		Providers: []config.NotificationProvider{
+			{Name: "ops", Type: "webhook", URL: server.URL, Headers: map[string]string{"X-Token": "secret"}},
+		},
+	}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %v", err)
+	}
+
+	err = dispatcher.Notify(context.Background(), Event{
+		Type:        EventCheckWarning,
+		Severity:    SeverityWarning,
+		ContainerID: 100,
+		Message:     "disk almost full",
+	})
+	if err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	event := <-received
+	if event.Type != EventCheckWarning || event.ContainerID != 100 {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+	if event.Timestamp.IsZero() {
+		t.Error("Expected timestamp to be set")
+	}
+}
+
+func TestDispatcher_WebhookError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	dispatcher, err := NewDispatcher(&config.NotificationsConfig{
+		Providers: []config.NotificationProvider{{Name: "ops", Type: "webhook", URL: server.URL}},
+	}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %v", err)
+	}
+
+	if err := dispatcher.Notify(context.Background(), Event{Type: EventFailoverFailed}); err == nil {
+		t.Error("Expected error for non-2xx response")
+	}
+}
+
+func TestDispatcher_SendTest(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ok.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer failing.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	dispatcher, err := NewDispatcher(&config.NotificationsConfig{
+		Providers: []config.NotificationProvider{
+			{Name: "ops", Type: "webhook", URL: ok.URL},
+			{Name: "oncall", Type: "webhook", URL: failing.URL},
+		},
+		Routes: []config.NotificationRoute{{Providers: []string{"oncall"}, Severities: []string{"critical"}}},
+	}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %v", err)
+	}
+
+	deliveries := dispatcher.SendTest(context.Background(), SampleEvent(EventCheckWarning, 100, "web"))
+	if len(deliveries) != 2 {
+		t.Fatalf("SendTest() = %d deliveries, want 2", len(deliveries))
+	}
+	if deliveries[0].Provider != "ops" || deliveries[0].Err != nil || !deliveries[0].Routed {
+		t.Errorf("SendTest() ops = %+v", deliveries[0])
+	}
+	// Providers are tested whatever the routes
+	if deliveries[1].Provider != "oncall" || deliveries[1].Err == nil || deliveries[1].Routed {
+		t.Errorf("SendTest() oncall = %+v", deliveries[1])
+	}
+}
+
+func TestDispatcher_Nil(t *testing.T) {
+	var dispatcher *Dispatcher
+	dispatcher.Send(Event{Type: EventContainerFailed})
+	dispatcher.Wait()
+	if err := dispatcher.Notify(context.Background(), Event{}); err != nil {
+		t.Errorf("Expected nil dispatcher to discard events, got %v", err)
+	}
+}
+
+func TestDispatcher_Plugin(t *testing.T) {
+	dir := t.TempDir()
+	received := filepath.Join(dir, "request.json")
+	script := filepath.Join(dir, "notify.sh")
+	content := "#!/bin/sh\ncat > " + received + "\necho '{\"success\": true}'\n"
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatalf("Failed to write plugin: %v", err)
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	dispatcher, err := NewDispatcher(&config.NotificationsConfig{
+		Providers: []config.NotificationProvider{{Name: "pager", Type: "plugin", Command: script}},
+	}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %v", err)
+	}
+
+	if err := dispatcher.Notify(context.Background(), Event{Type: EventNodeFailed, Node: "node1"}); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	data, err := os.ReadFile(received)
+	if err != nil {
+		t.Fatalf("Plugin did not receive the request: %v", err)
+	}
+	var request PluginRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		t.Fatalf("Invalid request: %v", err)
+	}
+	if request.Kind != "notify" || request.Event.Type != EventNodeFailed || request.Event.Node != "node1" {
+		t.Errorf("Unexpected request: %+v", request)
+	}
+}
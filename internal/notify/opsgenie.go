@@ -0,0 +1,108 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+const opsgenieURL = "https://api.opsgenie.com"
+
+// Opsgenie opens and closes Opsgenie alerts through the Alert API, using
+// the incident key as alias.
+type Opsgenie struct {
+	name       string
+	url        string
+	apiKey     string
+	historyURL string
+	client     *http.Client
+}
+
+func NewOpsgenie(provider config.NotificationProvider) *Opsgenie {
+	base := provider.URL
+	if base == "" {
+		base = opsgenieURL
+	}
+	return &Opsgenie{
+		name:       provider.Name,
+		url:        strings.TrimSuffix(base, "/"),
+		apiKey:     provider.APIKey,
+		historyURL: provider.HistoryURL,
+		client:     &http.Client{},
+	}
+}
+
+func (o *Opsgenie) Name() string {
+	return o.name
+}
+
+type opsgenieAlert struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias"`
+	Description string            `json:"description,omitempty"`
+	Entity      string            `json:"entity,omitempty"`
+	Source      string            `json:"source"`
+	Priority    string            `json:"priority"`
+	Tags        []string          `json:"tags,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+}
+
+type opsgenieClose struct {
+	Source string `json:"source"`
+	Note   string `json:"note,omitempty"`
+}
+
+func (o *Opsgenie) Notify(ctx context.Context, event Event) error {
+	action, alias, ok := incident(event)
+	if !ok {
+		return nil
+	}
+
+	headers := map[string]string{"Authorization": "GenieKey " + o.apiKey}
+	if action == incidentResolve {
+		data, err := json.Marshal(opsgenieClose{Source: "proxwarden", Note: event.Message})
+		if err != nil {
+			return fmt.Errorf("failed to encode event: %w", err)
+		}
+		return postJSON(ctx, o.client, o.url+"/v2/alerts/"+url.PathEscape(alias)+"/close?identifierType=alias", headers, data)
+	}
+
+	alert := opsgenieAlert{
+		Message:     incidentSummary(event, 130),
+		Alias:       alias,
+		Description: event.Message,
+		Source:      "proxwarden",
+		Priority:    opsgeniePriority(event.Severity),
+		Tags:        []string{"proxwarden", string(event.Type)},
+		Details:     incidentDetails(event),
+	}
+	if event.ContainerID != 0 {
+		alert.Entity = "container " + strconv.Itoa(event.ContainerID)
+	}
+	if link := historyLink(o.historyURL, event); link != "" {
+		alert.Details["history"] = link
+	}
+
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	return postJSON(ctx, o.client, o.url+"/v2/alerts", headers, data)
+}
+
+func opsgeniePriority(severity Severity) string {
+	switch severity {
+	case SeverityCritical:
+		return "P1"
+	case SeverityWarning:
+		return "P3"
+	default:
+		return "P5"
+	}
+}
@@ -0,0 +1,112 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+const pagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDuty opens and resolves PagerDuty incidents through the Events API
+// v2.
+type PagerDuty struct {
+	name       string
+	url        string
+	routingKey string
+	historyURL string
+	client     *http.Client
+}
+
+func NewPagerDuty(provider config.NotificationProvider) *PagerDuty {
+	url := provider.URL
+	if url == "" {
+		url = pagerDutyURL
+	}
+	return &PagerDuty{
+		name:       provider.Name,
+		url:        url,
+		routingKey: provider.RoutingKey,
+		historyURL: provider.HistoryURL,
+		client:     &http.Client{},
+	}
+}
+
+func (p *PagerDuty) Name() string {
+	return p.name
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+	Links       []pagerDutyLink   `json:"links,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Timestamp     string            `json:"timestamp,omitempty"`
+	Component     string            `json:"component,omitempty"`
+	Class         string            `json:"class"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+type pagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+func (p *PagerDuty) Notify(ctx context.Context, event Event) error {
+	action, key, ok := incident(event)
+	if !ok {
+		return nil
+	}
+
+	body := pagerDutyEvent{RoutingKey: p.routingKey, EventAction: action, DedupKey: key}
+	if action == incidentTrigger {
+		source := event.Node
+		if source == "" {
+			source = "proxwarden"
+		}
+		body.Payload = &pagerDutyPayload{
+			Summary:       incidentSummary(event, 1024),
+			Source:        source,
+			Severity:      pagerDutySeverity(event.Severity),
+			Class:         string(event.Type),
+			CustomDetails: incidentDetails(event),
+		}
+		if !event.Timestamp.IsZero() {
+			body.Payload.Timestamp = event.Timestamp.Format("2006-01-02T15:04:05.000Z07:00")
+		}
+		if event.ContainerID != 0 {
+			body.Payload.Component = "container " + strconv.Itoa(event.ContainerID)
+		}
+		if link := historyLink(p.historyURL, event); link != "" {
+			body.Links = []pagerDutyLink{{Href: link, Text: "Failover history"}}
+		}
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	return postJSON(ctx, p.client, p.url, nil, data)
+}
+
+func pagerDutySeverity(severity Severity) string {
+	switch severity {
+	case SeverityCritical:
+		return "critical"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "info"
+	}
+}
@@ -0,0 +1,46 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/plugin"
+)
+
+// PluginRequest is written as JSON to the stdin of notification plugins.
+type PluginRequest struct {
+	Version int    `json:"version"`
+	Kind    string `json:"kind"`
+	Event   Event  `json:"event"`
+}
+
+// Plugin delivers events to an external executable implementing the plugin
+// protocol, which reports whether it delivered them.
+type Plugin struct {
+	name   string
+	plugin config.Plugin
+}
+
+func NewPlugin(provider config.NotificationProvider) *Plugin {
+	return &Plugin{
+		name:   provider.Name,
+		plugin: config.Plugin{Command: provider.Command, Args: provider.Args},
+	}
+}
+
+func (p *Plugin) Name() string {
+	return p.name
+}
+
+func (p *Plugin) Notify(ctx context.Context, event Event) error {
+	var result plugin.Result
+	err := plugin.Run(ctx, p.plugin, PluginRequest{
+		Version: plugin.ProtocolVersion,
+		Kind:    plugin.KindNotify,
+		Event:   event,
+	}, &result)
+	if err != nil {
+		return err
+	}
+	return result.Err()
+}
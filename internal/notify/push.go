@@ -0,0 +1,148 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+const ntfyURL = "https://ntfy.sh"
+
+// pushTitle returns the title of push notifications of an event, unless a
+// template set another.
+func pushTitle(event Event) string {
+	if event.Title != "" {
+		return event.Title
+	}
+	title := "ProxWarden " + string(event.Type)
+	if subject := chatSubject(event); subject != "" {
+		title += ": " + subject
+	}
+	return title
+}
+
+// Ntfy publishes events to a topic of an ntfy server.
+type Ntfy struct {
+	name   string
+	url    string
+	topic  string
+	token  string
+	client *http.Client
+}
+
+func NewNtfy(provider config.NotificationProvider) *Ntfy {
+	base := provider.URL
+	if base == "" {
+		base = ntfyURL
+	}
+	return &Ntfy{
+		name:   provider.Name,
+		url:    strings.TrimSuffix(base, "/"),
+		topic:  provider.Topic,
+		token:  provider.Token,
+		client: &http.Client{},
+	}
+}
+
+func (n *Ntfy) Name() string {
+	return n.name
+}
+
+type ntfyMessage struct {
+	Topic    string   `json:"topic"`
+	Title    string   `json:"title"`
+	Message  string   `json:"message"`
+	Priority int      `json:"priority"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+// ntfyPriorities map severities to ntfy priorities, from 1 (min) to 5
+// (max).
+var ntfyPriorities = map[Severity]int{
+	SeverityCritical: 5,
+	SeverityWarning:  4,
+	SeverityInfo:     3,
+}
+
+// ntfyTags are shown as emojis by ntfy clients.
+var ntfyTags = map[Severity]string{
+	SeverityCritical: "rotating_light",
+	SeverityWarning:  "warning",
+	SeverityInfo:     "white_check_mark",
+}
+
+func (n *Ntfy) Notify(ctx context.Context, event Event) error {
+	message := ntfyMessage{
+		Topic:    n.topic,
+		Title:    pushTitle(event),
+		Message:  event.Message,
+		Priority: ntfyPriorities[event.Severity],
+	}
+	if message.Priority == 0 {
+		message.Priority = 3
+	}
+	if tag, ok := ntfyTags[event.Severity]; ok {
+		message.Tags = []string{tag}
+	}
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	var headers map[string]string
+	if n.token != "" {
+		headers = map[string]string{"Authorization": "Bearer " + n.token}
+	}
+	return postJSON(ctx, n.client, n.url, headers, body)
+}
+
+// Gotify sends events as messages of a Gotify application.
+type Gotify struct {
+	name   string
+	url    string
+	token  string
+	client *http.Client
+}
+
+func NewGotify(provider config.NotificationProvider) *Gotify {
+	return &Gotify{
+		name:   provider.Name,
+		url:    strings.TrimSuffix(provider.URL, "/"),
+		token:  provider.Token,
+		client: &http.Client{},
+	}
+}
+
+func (g *Gotify) Name() string {
+	return g.name
+}
+
+type gotifyMessage struct {
+	Title    string `json:"title"`
+	Message  string `json:"message"`
+	Priority int    `json:"priority"`
+}
+
+// gotifyPriorities map severities to Gotify priorities, from 0 to 10.
+// Clients alert loudly from 8 and silently below 4.
+var gotifyPriorities = map[Severity]int{
+	SeverityCritical: 8,
+	SeverityWarning:  5,
+	SeverityInfo:     2,
+}
+
+func (g *Gotify) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(gotifyMessage{
+		Title:    pushTitle(event),
+		Message:  event.Message,
+		Priority: gotifyPriorities[event.Severity],
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	return postJSON(ctx, g.client, g.url+"/message", map[string]string{"X-Gotify-Key": g.token}, body)
+}
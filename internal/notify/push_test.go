@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+func TestNtfy(t *testing.T) {
+	received := make(chan ntfyMessage, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tk_secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var message ntfyMessage
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- message
+	}))
+	defer server.Close()
+
+	ntfy := NewNtfy(config.NotificationProvider{Name: "push", Type: "ntfy", URL: server.URL, Topic: "proxwarden", Token: "tk_secret"})
+	err := ntfy.Notify(context.Background(), Event{
+		Type:        EventFailoverFailed,
+		Severity:    SeverityCritical,
+		ContainerID: 100,
+		Message:     "Failover of container 100 failed",
+	})
+	if err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	message := <-received
+	if message.Topic != "proxwarden" || message.Priority != 5 || message.Title != "ProxWarden failover_failed: container 100" {
+		t.Errorf("Unexpected message: %+v", message)
+	}
+}
+
+func TestGotify(t *testing.T) {
+	tests := []struct {
+		severity     Severity
+		wantPriority int
+	}{
+		{SeverityCritical, 8},
+		{SeverityWarning, 5},
+		{SeverityInfo, 2},
+	}
+
+	received := make(chan gotifyMessage, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/message" || r.Header.Get("X-Gotify-Key") != "app-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var message gotifyMessage
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- message
+	}))
+	defer server.Close()
+
+	gotify := NewGotify(config.NotificationProvider{Name: "push", Type: "gotify", URL: server.URL + "/", Token: "app-token"})
+	for _, tt := range tests {
+		t.Run(string(tt.severity), func(t *testing.T) {
+			if err := gotify.Notify(context.Background(), Event{Type: EventNodeFailed, Severity: tt.severity, Node: "node1"}); err != nil {
+				t.Fatalf("Notify failed: %v", err)
+			}
+			if message := <-received; message.Priority != tt.wantPriority {
+				t.Errorf("Priority = %d, want %d", message.Priority, tt.wantPriority)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package notify
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+// eventTypes are the events routes and throttles may name.
+var eventTypes = map[EventType]bool{
+	EventCheckWarning:        true,
+	EventCheckWarningCleared: true,
+	EventContainerFailed:     true,
+	EventContainerFlapping:   true,
+	EventFlappingCleared:     true,
+	EventNodeFailed:          true,
+	EventQuorumLost:          true,
+	EventQuorumRestored:      true,
+	EventFailoverStarted:     true,
+	EventFailoverSucceeded:   true,
+	EventFailoverFailed:      true,
+	EventCircuitOpen:         true,
+	EventFailbackStarted:     true,
+	EventFailbackSucceeded:   true,
+	EventFailbackFailed:      true,
+	EventDNSUpdateFailed:     true,
+	EventFloatingIPFailed:    true,
+	EventStandbySyncFailed:   true,
+	EventBackupFailed:        true,
+	EventReplicationFailed:   true,
+	EventDrillStarted:        true,
+	EventDrillSucceeded:      true,
+	EventDrillFailed:         true,
+	EventFailoverPending:     true,
+	EventFailoverImminent:    true,
+	EventFailoverRejected:    true,
+	EventFailoverInterrupted: true,
+}
+
+func validateEventTypes(label string, events []string) error {
+	for _, event := range events {
+		if !eventTypes[EventType(event)] {
+			return fmt.Errorf("%s: unknown event %q", label, event)
+		}
+	}
+	return nil
+}
+
+// router picks the notifiers of each event from the configured routes.
+type router struct {
+	routes []config.NotificationRoute
+	// routed are the providers named by a route or an escalation step,
+	// which receive only the events of their routes
+	routed map[string]bool
+}
+
+func newRouter(cfg *config.NotificationsConfig) (router, error) {
+	r := router{routes: cfg.Routes, routed: make(map[string]bool)}
+	for i, route := range cfg.Routes {
+		if err := validateEventTypes(fmt.Sprintf("route %d", i+1), route.Events); err != nil {
+			return router{}, err
+		}
+		for _, name := range route.Providers {
+			r.routed[name] = true
+		}
+	}
+	// Providers of escalation steps wait for their step
+	for _, escalation := range cfg.Escalations {
+		for _, step := range escalation.Steps {
+			for _, name := range step.Providers {
+				r.routed[name] = true
+			}
+		}
+	}
+	return r, nil
+}
+
+// wants reports whether the provider named provider receives event.
+func (r router) wants(provider string, event Event) bool {
+	if !r.routed[provider] {
+		return true
+	}
+	for _, route := range r.routes {
+		if containsString(route.Providers, provider) && routeMatches(route, event) {
+			return true
+		}
+	}
+	return false
+}
+
+func routeMatches(route config.NotificationRoute, event Event) bool {
+	if len(route.Events) > 0 && !containsString(route.Events, string(event.Type)) {
+		return false
+	}
+	if len(route.Severities) > 0 && !containsString(route.Severities, string(event.Severity)) {
+		return false
+	}
+	if len(route.Containers) > 0 {
+		for _, id := range route.Containers {
+			if id == event.ContainerID {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// suppressor drops events repeating one sent recently: identical events
+// within the dedup window, and events of a throttled type for the same
+// container or node within the throttle interval.
+type suppressor struct {
+	mu          sync.Mutex
+	dedupWindow time.Duration
+	throttle    time.Duration
+	throttled   map[EventType]bool
+	// sent holds when events were last sent, by their dedup and throttle
+	// keys
+	sent map[string]time.Time
+}
+
+func newSuppressor(cfg *config.NotificationsConfig) (*suppressor, error) {
+	if err := validateEventTypes("throttle", cfg.Throttle.Events); err != nil {
+		return nil, err
+	}
+	s := &suppressor{
+		dedupWindow: cfg.DedupWindow,
+		throttle:    cfg.Throttle.Interval,
+		sent:        make(map[string]time.Time),
+	}
+	if len(cfg.Throttle.Events) > 0 {
+		s.throttled = make(map[EventType]bool)
+		for _, event := range cfg.Throttle.Events {
+			s.throttled[EventType(event)] = true
+		}
+	}
+	return s, nil
+}
+
+// allow reports whether event is to be sent at now, and records it if so.
+// It returns why the event is suppressed otherwise.
+func (s *suppressor) allow(event Event, now time.Time) (bool, string) {
+	if s.dedupWindow <= 0 && s.throttle <= 0 {
+		return true, ""
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subject := string(event.Type) + "|" + strconv.Itoa(event.ContainerID) + "|" + event.Node
+	dedupKey := "dedup|" + subject + "|" + event.Message
+	throttleKey := "throttle|" + subject
+	throttled := s.throttle > 0 && (s.throttled == nil || s.throttled[event.Type])
+
+	if s.dedupWindow > 0 {
+		if last, ok := s.sent[dedupKey]; ok && now.Sub(last) < s.dedupWindow {
+			return false, "duplicate"
+		}
+	}
+	if throttled {
+		if last, ok := s.sent[throttleKey]; ok && now.Sub(last) < s.throttle {
+			return false, "throttled"
+		}
+	}
+
+	s.prune(now)
+	if s.dedupWindow > 0 {
+		s.sent[dedupKey] = now
+	}
+	if throttled {
+		s.sent[throttleKey] = now
+	}
+	return true, ""
+}
+
+// prune forgets events too old to suppress anything.
+func (s *suppressor) prune(now time.Time) {
+	keep := s.dedupWindow
+	if s.throttle > keep {
+		keep = s.throttle
+	}
+	for key, last := range s.sent {
+		if now.Sub(last) >= keep {
+			delete(s.sent, key)
+		}
+	}
+}
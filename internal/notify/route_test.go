@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+func TestRouter_Wants(t *testing.T) {
+	r, err := newRouter(&config.NotificationsConfig{
+		Routes: []config.NotificationRoute{
+			{Providers: []string{"oncall"}, Severities: []string{"critical"}},
+			{Providers: []string{"oncall", "db-team"}, Events: []string{"check_warning"}, Containers: []int{200}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("newRouter() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		provider string
+		event    Event
+		want     bool
+	}{
+		{"unrouted provider gets everything", "chat", Event{Type: EventBackupFailed, Severity: SeverityWarning}, true},
+		{"critical to oncall", "oncall", Event{Type: EventFailoverFailed, Severity: SeverityCritical, ContainerID: 100}, true},
+		{"info not to oncall", "oncall", Event{Type: EventFailoverSucceeded, Severity: SeverityInfo, ContainerID: 100}, false},
+		{"warning of container 200 to db-team", "db-team", Event{Type: EventCheckWarning, Severity: SeverityWarning, ContainerID: 200}, true},
+		{"warning of container 100 not to db-team", "db-team", Event{Type: EventCheckWarning, Severity: SeverityWarning, ContainerID: 100}, false},
+		{"other events of container 200 not to db-team", "db-team", Event{Type: EventFailoverFailed, Severity: SeverityCritical, ContainerID: 200}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.wants(tt.provider, tt.event); got != tt.want {
+				t.Errorf("wants(%s) = %v, want %v", tt.provider, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRouter_UnknownEvent(t *testing.T) {
+	_, err := newRouter(&config.NotificationsConfig{
+		Routes: []config.NotificationRoute{{Providers: []string{"oncall"}, Events: []string{"failover_exploded"}}},
+	})
+	if err == nil {
+		t.Error("Expected error but got none")
+	}
+}
+
+func TestSuppressor_Allow(t *testing.T) {
+	s, err := newSuppressor(&config.NotificationsConfig{
+		DedupWindow: 5 * time.Minute,
+		Throttle:    config.NotificationThrottle{Interval: 30 * time.Minute, Events: []string{"container_failed"}},
+	})
+	if err != nil {
+		t.Fatalf("newSuppressor() error = %v", err)
+	}
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	steps := []struct {
+		name  string
+		event Event
+		at    time.Duration
+		want  bool
+	}{
+		{"first warning", Event{Type: EventCheckWarning, ContainerID: 100, Message: "disk 91%"}, 0, true},
+		{"identical warning", Event{Type: EventCheckWarning, ContainerID: 100, Message: "disk 91%"}, time.Minute, false},
+		{"different message", Event{Type: EventCheckWarning, ContainerID: 100, Message: "disk 95%"}, 2 * time.Minute, true},
+		{"identical warning after window", Event{Type: EventCheckWarning, ContainerID: 100, Message: "disk 91%"}, 6 * time.Minute, true},
+		{"first failure", Event{Type: EventContainerFailed, ContainerID: 100, Message: "failed 3 checks"}, 0, true},
+		{"still failing", Event{Type: EventContainerFailed, ContainerID: 100, Message: "failed 6 checks"}, 10 * time.Minute, false},
+		{"other container", Event{Type: EventContainerFailed, ContainerID: 101, Message: "failed 3 checks"}, 10 * time.Minute, true},
+		{"still failing after interval", Event{Type: EventContainerFailed, ContainerID: 100, Message: "failed 9 checks"}, 31 * time.Minute, true},
+	}
+
+	for _, step := range steps {
+		if got, _ := s.allow(step.event, start.Add(step.at)); got != step.want {
+			t.Errorf("%s: allow() = %v, want %v", step.name, got, step.want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+// Actions of the buttons of Teams cards, substituted for {action} in the
+// action URL.
+const (
+	actionView        = "view"
+	actionApprove     = "approve"
+	actionReject      = "reject"
+	actionAcknowledge = "acknowledge"
+)
+
+// Teams posts events as Adaptive Cards to a Microsoft Teams incoming webhook
+// or workflow.
+type Teams struct {
+	name       string
+	url        string
+	actionURL  string
+	historyURL string
+	client     *http.Client
+}
+
+func NewTeams(provider config.NotificationProvider) *Teams {
+	return &Teams{
+		name:       provider.Name,
+		url:        provider.URL,
+		actionURL:  provider.ActionURL,
+		historyURL: provider.HistoryURL,
+		client:     &http.Client{},
+	}
+}
+
+func (t *Teams) Name() string {
+	return t.name
+}
+
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+type teamsCard struct {
+	Schema  string         `json:"$schema"`
+	Type    string         `json:"type"`
+	Version string         `json:"version"`
+	Body    []teamsElement `json:"body"`
+	Actions []teamsOpenURL `json:"actions,omitempty"`
+	MSTeams teamsWidth     `json:"msteams"`
+}
+
+// teamsWidth lets cards use the full width of the channel.
+type teamsWidth struct {
+	Width string `json:"width"`
+}
+
+// teamsElement is a TextBlock or a FactSet.
+type teamsElement struct {
+	Type   string      `json:"type"`
+	Text   string      `json:"text,omitempty"`
+	Weight string      `json:"weight,omitempty"`
+	Size   string      `json:"size,omitempty"`
+	Color  string      `json:"color,omitempty"`
+	Wrap   bool        `json:"wrap,omitempty"`
+	Facts  []teamsFact `json:"facts,omitempty"`
+}
+
+type teamsFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+type teamsOpenURL struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+var teamsColors = map[Severity]string{
+	SeverityCritical: "Attention",
+	SeverityWarning:  "Warning",
+	SeverityInfo:     "Good",
+}
+
+func (t *Teams) Notify(ctx context.Context, event Event) error {
+	heading, subject := chatHeading(event)
+	if subject != "" {
+		heading += " · " + subject
+	}
+	card := teamsCard{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.4",
+		Body: []teamsElement{
+			{Type: "TextBlock", Text: heading, Weight: "Bolder", Size: "Medium", Color: teamsColors[event.Severity], Wrap: true},
+			{Type: "TextBlock", Text: event.Message, Wrap: true},
+			{Type: "FactSet", Facts: teamsFacts(event)},
+		},
+		Actions: t.actions(event),
+		MSTeams: teamsWidth{Width: "Full"},
+	}
+
+	body, err := json.Marshal(teamsMessage{
+		Type:        "message",
+		Attachments: []teamsAttachment{{ContentType: "application/vnd.microsoft.card.adaptive", Content: card}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	return postJSON(ctx, t.client, t.url, nil, body)
+}
+
+// teamsFacts returns the container, node, severity and details of an event
+// as the facts of its card.
+func teamsFacts(event Event) []teamsFact {
+	var facts []teamsFact
+	if event.ContainerID != 0 {
+		container := strconv.Itoa(event.ContainerID)
+		if event.ContainerName != "" {
+			container += " (" + event.ContainerName + ")"
+		}
+		facts = append(facts, teamsFact{Title: "Container", Value: container})
+	}
+	if event.Node != "" {
+		facts = append(facts, teamsFact{Title: "Node", Value: event.Node})
+	}
+	facts = append(facts, teamsFact{Title: "Severity", Value: string(event.Severity)})
+
+	keys := make([]string, 0, len(event.Details))
+	for key := range event.Details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		facts = append(facts, teamsFact{Title: key, Value: event.Details[key]})
+	}
+	if !event.Timestamp.IsZero() {
+		facts = append(facts, teamsFact{Title: "Time", Value: event.Timestamp.Format("2006-01-02 15:04:05 MST")})
+	}
+	return facts
+}
+
+// actions returns the buttons of the card of an event: acknowledging its
+// escalation, approving and rejecting failovers awaiting approval, viewing
+// the container and its failover history.
+func (t *Teams) actions(event Event) []teamsOpenURL {
+	var actions []teamsOpenURL
+	if t.actionURL != "" && event.Details["escalation_id"] != "" {
+		actions = append(actions, teamsOpenURL{Type: "Action.OpenUrl", Title: "Acknowledge", URL: t.action(actionAcknowledge, event)})
+	}
+	if t.actionURL != "" && event.ContainerID != 0 {
+		if event.Type == EventFailoverPending {
+			actions = append(actions,
+				teamsOpenURL{Type: "Action.OpenUrl", Title: "Approve", URL: t.action(actionApprove, event)},
+				teamsOpenURL{Type: "Action.OpenUrl", Title: "Reject", URL: t.action(actionReject, event)})
+		}
+		actions = append(actions, teamsOpenURL{Type: "Action.OpenUrl", Title: "View", URL: t.action(actionView, event)})
+	}
+	if link := historyLink(t.historyURL, event); link != "" {
+		actions = append(actions, teamsOpenURL{Type: "Action.OpenUrl", Title: "Failover history", URL: link})
+	}
+	return actions
+}
+
+func (t *Teams) action(action string, event Event) string {
+	return strings.NewReplacer(
+		"{container_id}", strconv.Itoa(event.ContainerID),
+		"{escalation_id}", event.Details["escalation_id"],
+		"{action}", action,
+	).Replace(t.actionURL)
+}
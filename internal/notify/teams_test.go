@@ -0,0 +1,87 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+func TestTeams(t *testing.T) {
+	received := make(chan teamsMessage, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message teamsMessage
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- message
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	teams := NewTeams(config.NotificationProvider{
+		Name:       "ms",
+		Type:       "teams",
+		URL:        server.URL,
+		ActionURL:  "https://ops.example.com/proxwarden/containers/{container_id}?action={action}",
+		HistoryURL: "https://ops.example.com/proxwarden/history/{container_id}",
+	})
+
+	tests := []struct {
+		name        string
+		event       Event
+		wantActions []string
+	}{
+		{
+			name:        "pending approval",
+			event:       Event{Type: EventFailoverPending, Severity: SeverityCritical, ContainerID: 100, ContainerName: "web", Node: "node1", Message: "Failover awaits approval"},
+			wantActions: []string{"Approve", "Reject", "View", "Failover history"},
+		},
+		{
+			name:        "failover failed",
+			event:       Event{Type: EventFailoverFailed, Severity: SeverityCritical, ContainerID: 100, Message: "Failover failed"},
+			wantActions: []string{"View", "Failover history"},
+		},
+		{
+			name:  "node failed",
+			event: Event{Type: EventNodeFailed, Severity: SeverityCritical, Node: "node1", Message: "Node node1 is down"},
+		},
+		{
+			name:        "escalated",
+			event:       Event{Type: EventNodeFailed, Severity: SeverityCritical, Node: "node1", Message: "Node node1 is down", Details: map[string]string{"escalation_id": "7"}},
+			wantActions: []string{"Acknowledge"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := teams.Notify(context.Background(), tt.event); err != nil {
+				t.Fatalf("Notify failed: %v", err)
+			}
+			message := <-received
+			if len(message.Attachments) != 1 {
+				t.Fatalf("Got %d attachments, want 1", len(message.Attachments))
+			}
+			card := message.Attachments[0].Content
+			if card.Type != "AdaptiveCard" || card.Body[0].Color != "Attention" || card.Body[1].Text != tt.event.Message {
+				t.Errorf("Unexpected card: %+v", card)
+			}
+			if len(card.Actions) != len(tt.wantActions) {
+				t.Fatalf("Actions = %+v, want %v", card.Actions, tt.wantActions)
+			}
+			for i, action := range card.Actions {
+				if action.Title != tt.wantActions[i] {
+					t.Errorf("Action %d = %s, want %s", i, action.Title, tt.wantActions[i])
+				}
+			}
+		})
+	}
+
+	if got := teams.action(actionApprove, Event{ContainerID: 100}); got != "https://ops.example.com/proxwarden/containers/100?action=approve" {
+		t.Errorf("action() = %s", got)
+	}
+}
@@ -0,0 +1,100 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// templates render the title and message of events. A nil template keeps
+// the default.
+type templates struct {
+	title   *template.Template
+	message *template.Template
+}
+
+// parseTemplates parses the templates of a provider, each falling back to
+// the one of all providers. It returns nil when neither sets any.
+func parseTemplates(global, provider config.NotificationTemplate) (*templates, error) {
+	title, message := global.Title, global.Message
+	if provider.Title != "" {
+		title = provider.Title
+	}
+	if provider.Message != "" {
+		message = provider.Message
+	}
+	if title == "" && message == "" {
+		return nil, nil
+	}
+
+	t := &templates{}
+	var err error
+	if title != "" {
+		if t.title, err = template.New("title").Parse(title); err != nil {
+			return nil, fmt.Errorf("invalid title template: %w", err)
+		}
+	}
+	if message != "" {
+		if t.message, err = template.New("message").Parse(message); err != nil {
+			return nil, fmt.Errorf("invalid message template: %w", err)
+		}
+	}
+	return t, nil
+}
+
+// apply returns event with its title and message rendered.
+func (t *templates) apply(event Event) (Event, error) {
+	if t == nil {
+		return event, nil
+	}
+	rendered := event
+	if t.title != nil {
+		title, err := execute(t.title, event)
+		if err != nil {
+			return event, err
+		}
+		rendered.Title = title
+	}
+	if t.message != nil {
+		message, err := execute(t.message, event)
+		if err != nil {
+			return event, err
+		}
+		rendered.Message = message
+	}
+	return rendered, nil
+}
+
+func execute(t *template.Template, event Event) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, event); err != nil {
+		return "", fmt.Errorf("failed to render %s template: %w", t.Name(), err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// templated renders events with the templates of its provider before
+// passing them on. Events whose templates fail to render are sent as they
+// are rather than not at all.
+type templated struct {
+	Notifier
+	templates *templates
+	logger    *logrus.Logger
+}
+
+func (t *templated) Notify(ctx context.Context, event Event) error {
+	rendered, err := t.templates.apply(event)
+	if err != nil {
+		t.logger.WithFields(logrus.Fields{
+			"notifier": t.Name(),
+			"event":    event.Type,
+			"error":    err,
+		}).Warn("Failed to render notification template, sending the default")
+	}
+	return t.Notifier.Notify(ctx, rendered)
+}
@@ -0,0 +1,114 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+func TestTemplates_Apply(t *testing.T) {
+	event := Event{
+		Type:          EventFailoverSucceeded,
+		ContainerID:   100,
+		ContainerName: "web",
+		Node:          "node1",
+		Message:       "Container 100 failed over from node1 to node2",
+		Details:       map[string]string{"target_node": "node2", "duration": "1m30s"},
+	}
+
+	tests := []struct {
+		name        string
+		global      config.NotificationTemplate
+		provider    config.NotificationTemplate
+		wantTitle   string
+		wantMessage string
+		expectErr   bool
+	}{
+		{
+			name:        "no templates",
+			wantMessage: "Container 100 failed over from node1 to node2",
+		},
+		{
+			name:        "global",
+			global:      config.NotificationTemplate{Title: "{{.ContainerName}} moved", Message: "{{.ContainerName}} now runs on {{.Details.target_node}} after {{.Details.duration}}"},
+			wantTitle:   "web moved",
+			wantMessage: "web now runs on node2 after 1m30s",
+		},
+		{
+			name:        "provider overrides message only",
+			global:      config.NotificationTemplate{Title: "{{.ContainerName}} moved", Message: "global"},
+			provider:    config.NotificationTemplate{Message: "[{{.Type}}] {{.Message}}"},
+			wantTitle:   "web moved",
+			wantMessage: "[failover_succeeded] Container 100 failed over from node1 to node2",
+		},
+		{
+			name:      "invalid",
+			global:    config.NotificationTemplate{Message: "{{.ContainerName"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templates, err := parseTemplates(tt.global, tt.provider)
+			if tt.expectErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseTemplates() error = %v", err)
+			}
+			rendered, err := templates.apply(event)
+			if err != nil {
+				t.Fatalf("apply() error = %v", err)
+			}
+			if rendered.Title != tt.wantTitle || rendered.Message != tt.wantMessage {
+				t.Errorf("apply() = %q, %q, want %q, %q", rendered.Title, rendered.Message, tt.wantTitle, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestDispatcher_Template(t *testing.T) {
+	received := make(chan Event, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- event
+	}))
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	dispatcher, err := NewDispatcher(&config.NotificationsConfig{
+		Providers: []config.NotificationProvider{{Name: "ops", Type: "webhook", URL: server.URL}},
+		Template:  config.NotificationTemplate{Message: "{{.Node}}: {{.Message}}"},
+	}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %v", err)
+	}
+
+	event := Event{Type: EventNodeFailed, Severity: SeverityCritical, Node: "node1", Message: "node is down"}
+	if err := dispatcher.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if got := <-received; got.Message != "node1: node is down" {
+		t.Errorf("Sent message = %q", got.Message)
+	}
+
+	renderings := dispatcher.Render(event)
+	if len(renderings) != 1 || renderings[0].Provider != "ops" || renderings[0].Event.Message != "node1: node is down" || !renderings[0].Routed {
+		t.Errorf("Render() = %+v", renderings)
+	}
+}
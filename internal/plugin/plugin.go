@@ -0,0 +1,96 @@
+// Package plugin runs external executables implementing the plugin
+// protocol: a JSON request is written to their stdin and a JSON response
+// read from their stdout. Health checks, notification providers, placement
+// and fencing can be extended this way without patching ProxWarden.
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+// ProtocolVersion is sent to plugins so they can reject requests they do
+// not understand.
+const ProtocolVersion = 1
+
+// Kinds of requests, sent to plugins so one executable can serve several.
+const (
+	KindNotify    = "notify"
+	KindPlacement = "placement"
+	KindFence     = "fence"
+)
+
+// DefaultTimeout bounds runs of plugins without a timeout of their own.
+const DefaultTimeout = 10 * time.Second
+
+// waitDelay bounds how long a killed plugin's children may keep its output
+// pipes open.
+const waitDelay = time.Second
+
+// Result is the response of plugins that only report whether they
+// succeeded.
+type Result struct {
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+}
+
+// Err returns the failure the result reports, if any.
+func (r Result) Err() error {
+	if r.Success {
+		return nil
+	}
+	if r.Message == "" {
+		return fmt.Errorf("plugin reported failure")
+	}
+	return fmt.Errorf("plugin reported failure: %s", r.Message)
+}
+
+// Run runs p with request and decodes its response, killing it after its
+// timeout.
+func Run(ctx context.Context, p config.Plugin, request, response interface{}) error {
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return Exec(ctx, p.Command, p.Args, request, response)
+}
+
+// Exec runs command with args, writes request as JSON to its stdin and
+// decodes its stdout into response. The plugin is killed when ctx is done.
+// The response decides the outcome; the exit status only matters when the
+// plugin printed no valid JSON.
+func Exec(ctx context.Context, command string, args []string, request, response interface{}) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode plugin request: %w", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = waitDelay
+
+	runErr := cmd.Run()
+	if ctx.Err() != nil {
+		return fmt.Errorf("plugin %s timed out: %w", command, ctx.Err())
+	}
+
+	if err := json.Unmarshal(bytes.TrimSpace(stdout.Bytes()), response); err != nil {
+		if runErr != nil {
+			return fmt.Errorf("plugin %s failed: %w: %s", command, runErr, strings.TrimSpace(stderr.String()))
+		}
+		return fmt.Errorf("plugin %s returned invalid response: %w", command, err)
+	}
+	return nil
+}
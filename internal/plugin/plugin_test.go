@@ -0,0 +1,73 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+func writePlugin(t *testing.T, script string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "plugin.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatalf("Failed to write plugin: %v", err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name          string
+		script        string
+		timeout       time.Duration
+		expectError   bool
+		expectSuccess bool
+	}{
+		{
+			name:          "reports success",
+			script:        `cat > /dev/null; echo '{"success": true}'`,
+			expectSuccess: true,
+		},
+		{
+			name:   "reports failure",
+			script: `cat > /dev/null; echo '{"success": false, "message": "ipmi unreachable"}'; exit 1`,
+		},
+		{
+			name:          "receives request on stdin",
+			script:        `grep -q '"kind":"fence"' && echo '{"success": true}' || echo '{"success": false}'`,
+			expectSuccess: true,
+		},
+		{
+			name:        "invalid output",
+			script:      `echo "not json"; exit 2`,
+			expectError: true,
+		},
+		{
+			name:        "timeout",
+			script:      `sleep 5; echo '{"success": true}'`,
+			timeout:     100 * time.Millisecond,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := config.Plugin{Command: writePlugin(t, tt.script), Timeout: tt.timeout}
+			request := map[string]interface{}{"version": ProtocolVersion, "kind": KindFence}
+
+			var result Result
+			err := Run(context.Background(), p, request, &result)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("Run() error = %v, want error %v", err, tt.expectError)
+			}
+			if err == nil && (result.Err() == nil) != tt.expectSuccess {
+				t.Errorf("Result.Err() = %v, want success %v", result.Err(), tt.expectSuccess)
+			}
+		})
+	}
+}
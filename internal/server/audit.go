@@ -0,0 +1,54 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/jbutlerdev/proxwarden/internal/audit"
+)
+
+// statusRecorder remembers the status code a handler answered with.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// audited records requests that change something in the audit log, with
+// the client that sent them as the actor, including those the client was
+// not allowed to make. Actions they take on the cluster are recorded as
+// taken by that client too.
+func (s *Server) audited(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		actor := "api:" + r.RemoteAddr
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			actor = "api:" + host
+		}
+		if name, _, ok := s.authenticate(r); ok {
+			actor = "api:" + name
+		}
+		ctx := audit.WithActor(r.Context(), actor)
+
+		entry := audit.Begin(ctx, audit.ActionAPIRequest, 0).
+			Param("method", r.Method).
+			Param("path", r.URL.Path)
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		var err error
+		if recorder.status >= http.StatusBadRequest {
+			err = fmt.Errorf("%d %s", recorder.status, http.StatusText(recorder.status))
+		}
+		entry.End(err)
+	})
+}
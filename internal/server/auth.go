@@ -0,0 +1,138 @@
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// requiredRole returns the role a request needs, or "" for the health
+// endpoints, which anyone may use.
+func requiredRole(r *http.Request) string {
+	switch {
+	case r.URL.Path == "/healthz" || r.URL.Path == "/readyz":
+		return ""
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		return config.RoleReadOnly
+	case strings.HasPrefix(r.URL.Path, apiPrefix+"/failovers/") &&
+		(strings.HasSuffix(r.URL.Path, "/approve") || strings.HasSuffix(r.URL.Path, "/resume")):
+		// Approving and resuming start a failover
+		return config.RoleAdmin
+	default:
+		return config.RoleOperator
+	}
+}
+
+// authenticate returns the name and role of the client of a request, from
+// its verified client certificate or its bearer token, and whether it has
+// either.
+func (s *Server) authenticate(r *http.Request) (string, string, bool) {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		commonName := r.TLS.VerifiedChains[0][0].Subject.CommonName
+		for _, cert := range s.config.Auth.ClientCerts {
+			if cert.CommonName == commonName {
+				return "cert:" + commonName, cert.Role, true
+			}
+		}
+	}
+
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || token == "" {
+		return "", "", false
+	}
+	// Comparing digests keeps the comparison constant-time whatever the
+	// lengths of the keys
+	digest := sha256.Sum256([]byte(token))
+	for _, key := range s.config.Auth.APIKeys {
+		expected := sha256.Sum256([]byte(key.Key))
+		if subtle.ConstantTimeCompare(digest[:], expected[:]) == 1 {
+			return "key:" + key.Name, key.Role, true
+		}
+	}
+	return "", "", false
+}
+
+// authorize rejects requests from clients without the role they need, when
+// authentication is enabled.
+func (s *Server) authorize(next http.Handler) http.Handler {
+	if !s.config.Auth.Enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		required := requiredRole(r)
+		if required == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		name, role, ok := s.authenticate(r)
+		if !ok {
+			s.logger.WithFields(logrus.Fields{
+				"remote": r.RemoteAddr,
+				"method": r.Method,
+				"path":   r.URL.Path,
+			}).Warn("Rejected unauthenticated API request")
+			w.Header().Set("WWW-Authenticate", `Bearer realm="proxwarden"`)
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+		if !config.RoleAllows(role, required) {
+			s.logger.WithFields(logrus.Fields{
+				"client":   name,
+				"role":     role,
+				"required": required,
+				"method":   r.Method,
+				"path":     r.URL.Path,
+			}).Warn("Rejected API request")
+			writeError(w, http.StatusForbidden, fmt.Sprintf("role %s cannot do this, %s is required", role, required))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// tlsConfig returns the TLS configuration of the API, or nil when it is
+// served over plain HTTP.
+func (s *Server) tlsConfig() (*tls.Config, error) {
+	if !s.config.TLS.Enabled() {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(s.config.TLS.CertFile, s.config.TLS.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if s.config.TLS.ClientCAFile != "" {
+		pool, err := loadCertPool(s.config.TLS.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client CA: %w", err)
+		}
+		tlsConfig.ClientCAs = pool
+		// Clients without a certificate may still use an API key
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsConfig, nil
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
@@ -0,0 +1,213 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+)
+
+func TestRequiredRole(t *testing.T) {
+	tests := []struct {
+		method   string
+		path     string
+		expected string
+	}{
+		{http.MethodGet, "/healthz", ""},
+		{http.MethodGet, "/readyz", ""},
+		{http.MethodGet, apiPrefix + "/containers", config.RoleReadOnly},
+		{http.MethodHead, apiPrefix + "/status", config.RoleReadOnly},
+		{http.MethodPost, apiPrefix + "/containers/100/failover", config.RoleOperator},
+		{http.MethodDelete, apiPrefix + "/failovers/100", config.RoleOperator},
+		{http.MethodPost, apiPrefix + "/failovers/100/approve", config.RoleAdmin},
+		{http.MethodPost, apiPrefix + "/failovers/100/resume", config.RoleAdmin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			if role := requiredRole(httptest.NewRequest(tt.method, tt.path, nil)); role != tt.expected {
+				t.Errorf("Expected role %q, got %q", tt.expected, role)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	cfg := &config.ServerConfig{}
+	cfg.Auth.APIKeys = []config.APIKeyConfig{
+		{Name: "dashboard", Key: "read-key", Role: config.RoleReadOnly},
+		{Name: "automation", Key: "operator-key", Role: config.RoleOperator},
+		{Name: "ops", Key: "admin-key", Role: config.RoleAdmin},
+	}
+	cfg.Auth.ClientCerts = []config.ClientCertConfig{
+		{CommonName: "ops-cli", Role: config.RoleAdmin},
+		{CommonName: "grafana", Role: config.RoleReadOnly},
+	}
+	s := &Server{config: cfg, logger: testLogger()}
+	handler := s.authorize(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	failover := apiPrefix + "/containers/100/failover"
+	approve := apiPrefix + "/failovers/100/approve"
+	resume := apiPrefix + "/failovers/100/resume"
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		cert   string
+		status int
+	}{
+		{"health without credentials", http.MethodGet, "/healthz", "", "", http.StatusOK},
+		{"unauthenticated", http.MethodGet, apiPrefix + "/containers", "", "", http.StatusUnauthorized},
+		{"unknown key", http.MethodGet, apiPrefix + "/containers", "wrong-key", "", http.StatusUnauthorized},
+		{"read-only reads", http.MethodGet, apiPrefix + "/containers", "read-key", "", http.StatusOK},
+		{"read-only refused on POST", http.MethodPost, failover, "read-key", "", http.StatusForbidden},
+		{"operator fails over", http.MethodPost, failover, "operator-key", "", http.StatusOK},
+		{"operator refused on approve", http.MethodPost, approve, "operator-key", "", http.StatusForbidden},
+		{"operator refused on resume", http.MethodPost, resume, "operator-key", "", http.StatusForbidden},
+		{"admin approves", http.MethodPost, approve, "admin-key", "", http.StatusOK},
+		{"admin certificate", http.MethodPost, resume, "", "ops-cli", http.StatusOK},
+		{"read-only certificate refused on POST", http.MethodPost, failover, "", "grafana", http.StatusForbidden},
+		{"unknown certificate", http.MethodGet, apiPrefix + "/containers", "", "intruder", http.StatusUnauthorized},
+		{"unknown certificate with a key", http.MethodPost, failover, "operator-key", "intruder", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			if tt.cert != "" {
+				cert := &x509.Certificate{Subject: pkix.Name{CommonName: tt.cert}}
+				r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, w.Code)
+			}
+			if tt.status == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("Expected a WWW-Authenticate challenge")
+			}
+		})
+	}
+}
+
+func TestAuthorize_Disabled(t *testing.T) {
+	s := &Server{config: &config.ServerConfig{}, logger: testLogger()}
+	handler := s.authorize(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, apiPrefix+"/failovers/100/approve", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected every client to be an admin without auth settings, got status %d", w.Code)
+	}
+}
+
+// writeCertificate writes a self-signed certificate and its key to dir and
+// returns their paths.
+func writeCertificate(t *testing.T, dir string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "proxwarden"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCertificate(t, dir)
+	empty := filepath.Join(dir, "empty.pem")
+	if err := os.WriteFile(empty, []byte("not a certificate\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		tls        config.ServerTLSConfig
+		expectNil  bool
+		expectErr  bool
+		clientAuth tls.ClientAuthType
+		expectPool bool
+	}{
+		{name: "plain HTTP", expectNil: true},
+		{name: "server certificate", tls: config.ServerTLSConfig{CertFile: certFile, KeyFile: keyFile}, clientAuth: tls.NoClientCert},
+		{name: "client certificates", tls: config.ServerTLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: certFile}, clientAuth: tls.VerifyClientCertIfGiven, expectPool: true},
+		{name: "missing key", tls: config.ServerTLSConfig{CertFile: certFile, KeyFile: filepath.Join(dir, "missing.pem")}, expectErr: true},
+		{name: "client CA without certificates", tls: config.ServerTLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: empty}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{config: &config.ServerConfig{TLS: tt.tls}, logger: testLogger()}
+			tlsConfig, err := s.tlsConfig()
+			if tt.expectErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("tlsConfig failed: %v", err)
+			}
+			if tt.expectNil {
+				if tlsConfig != nil {
+					t.Error("Expected no TLS configuration")
+				}
+				return
+			}
+			if tlsConfig.MinVersion != tls.VersionTLS12 {
+				t.Errorf("Expected TLS 1.2 or later, got minimum version %x", tlsConfig.MinVersion)
+			}
+			if tlsConfig.ClientAuth != tt.clientAuth {
+				t.Errorf("Expected client auth %v, got %v", tt.clientAuth, tlsConfig.ClientAuth)
+			}
+			if (tlsConfig.ClientCAs != nil) != tt.expectPool {
+				t.Errorf("Expected client CAs %v, got %v", tt.expectPool, tlsConfig.ClientCAs != nil)
+			}
+		})
+	}
+}
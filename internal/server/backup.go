@@ -0,0 +1,113 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jbutlerdev/proxwarden/internal/backup"
+	"github.com/sirupsen/logrus"
+)
+
+// SetBackups sets the backup scheduler whose schedules are listed, added,
+// removed and run.
+func (s *Server) SetBackups(scheduler *backup.Scheduler) {
+	s.backups = scheduler
+}
+
+func (s *Server) handleBackupSchedules(w http.ResponseWriter, r *http.Request) {
+	if s.backups == nil {
+		writeError(w, http.StatusServiceUnavailable, "backup scheduler not available")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.backups.Jobs())
+
+	case http.MethodPost:
+		var req BackupScheduleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+
+		job, err := s.backups.Add(req.ContainerID, req.Schedule)
+		if err != nil {
+			if errors.Is(err, backup.ErrNotMonitored) {
+				writeError(w, http.StatusNotFound, err.Error())
+				return
+			}
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		s.logger.WithFields(logrus.Fields{
+			"container_id": job.ContainerID,
+			"schedule":     job.Schedule,
+			"next":         job.Next,
+		}).Info("Backup schedule added")
+		writeJSON(w, http.StatusCreated, job)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleBackupSchedule removes the backup schedule of a container, or
+// starts a backup of it right away.
+func (s *Server) handleBackupSchedule(w http.ResponseWriter, r *http.Request) {
+	if s.backups == nil {
+		writeError(w, http.StatusServiceUnavailable, "backup scheduler not available")
+		return
+	}
+
+	idPart, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, apiPrefix+"/backup/schedules/"), "/")
+	containerID, err := strconv.Atoi(idPart)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid container ID")
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodDelete:
+		removed, err := s.backups.Remove(containerID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !removed {
+			writeError(w, http.StatusNotFound, backup.ErrNoSchedule.Error())
+			return
+		}
+		s.logger.WithField("container_id", containerID).Info("Backup schedule removed")
+		w.WriteHeader(http.StatusNoContent)
+
+	case action == "run" && r.Method == http.MethodPost:
+		job, err := s.backups.RunNow(containerID)
+		switch {
+		case errors.Is(err, backup.ErrNoSchedule):
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		case errors.Is(err, backup.ErrBackupRunning):
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		case errors.Is(err, backup.ErrNotRunning):
+			writeError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		case err != nil:
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		s.logger.WithField("container_id", containerID).Info("Scheduled backup started by request")
+		writeJSON(w, http.StatusAccepted, job)
+
+	case action != "" && action != "run":
+		writeError(w, http.StatusNotFound, "expected /backup/schedules/{id} or /backup/schedules/{id}/run")
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
@@ -0,0 +1,253 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/backup"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/maintenance"
+	"github.com/jbutlerdev/proxwarden/internal/notify"
+)
+
+// Client talks to a running daemon's API.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewClient returns a client of the daemon API served as cfg sets,
+// authenticating as its client settings say.
+func NewClient(cfg *config.ServerConfig) (*Client, error) {
+	client := &Client{
+		baseURL:    "http://" + cfg.Listen + apiPrefix,
+		apiKey:     cfg.Client.APIKey,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	if !cfg.TLS.Enabled() {
+		return client, nil
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName: cfg.Client.ServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+	if cfg.Client.CAFile != "" {
+		pool, err := loadCertPool(cfg.Client.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load daemon CA: %w", err)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.Client.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.Client.CertFile, cfg.Client.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	client.baseURL = "https://" + cfg.Listen + apiPrefix
+	client.httpClient.Transport = &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: tlsConfig,
+	}
+	return client, nil
+}
+
+func (c *Client) Containers(ctx context.Context) ([]ContainerStatus, error) {
+	var result []ContainerStatus
+	if err := c.get(ctx, "/containers", &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *Client) Container(ctx context.Context, containerID int) (*ContainerStatus, error) {
+	var result ContainerStatus
+	if err := c.get(ctx, fmt.Sprintf("/containers/%d", containerID), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *Client) Maintenance(ctx context.Context) ([]maintenance.Window, error) {
+	var result []maintenance.Window
+	if err := c.get(ctx, "/maintenance", &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *Client) EnableMaintenance(ctx context.Context, request MaintenanceRequest) (*maintenance.Window, error) {
+	var result maintenance.Window
+	if err := c.do(ctx, http.MethodPost, "/maintenance", request, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *Client) DisableMaintenance(ctx context.Context, kind maintenance.Kind, target string) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/maintenance/%s/%s", kind, url.PathEscape(target)), nil, nil)
+}
+
+func (c *Client) PauseStatus(ctx context.Context) (*PauseStatus, error) {
+	var result PauseStatus
+	if err := c.get(ctx, "/pause", &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *Client) Pause(ctx context.Context, reason string) (*PauseStatus, error) {
+	var result PauseStatus
+	if err := c.do(ctx, http.MethodPost, "/pause", PauseRequest{Reason: reason}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *Client) Resume(ctx context.Context) (*PauseStatus, error) {
+	var result PauseStatus
+	if err := c.do(ctx, http.MethodPost, "/resume", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *Client) Failovers(ctx context.Context) ([]FailoverStatus, error) {
+	var result []FailoverStatus
+	if err := c.get(ctx, "/failovers", &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *Client) CancelFailover(ctx context.Context, containerID int) error {
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/failovers/%d/cancel", containerID), nil, nil)
+}
+
+func (c *Client) ApproveFailover(ctx context.Context, containerID int) error {
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/failovers/%d/approve", containerID), nil, nil)
+}
+
+func (c *Client) RejectFailover(ctx context.Context, containerID int) error {
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/failovers/%d/reject", containerID), nil, nil)
+}
+
+func (c *Client) InterruptedFailovers(ctx context.Context) ([]InterruptedFailover, error) {
+	var result []InterruptedFailover
+	if err := c.get(ctx, "/failovers/interrupted", &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *Client) ResumeFailover(ctx context.Context, containerID int) error {
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/failovers/%d/resume", containerID), nil, nil)
+}
+
+func (c *Client) DiscardFailover(ctx context.Context, containerID int) error {
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/failovers/%d/discard", containerID), nil, nil)
+}
+
+func (c *Client) Escalations(ctx context.Context) ([]notify.Escalation, error) {
+	var result []notify.Escalation
+	if err := c.get(ctx, "/escalations", &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *Client) AcknowledgeEscalation(ctx context.Context, id int) (*notify.Escalation, error) {
+	var result notify.Escalation
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/escalations/%d/ack", id), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *Client) BackupSchedules(ctx context.Context) ([]backup.Job, error) {
+	var result []backup.Job
+	if err := c.get(ctx, "/backup/schedules", &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *Client) AddBackupSchedule(ctx context.Context, request BackupScheduleRequest) (*backup.Job, error) {
+	var result backup.Job
+	if err := c.do(ctx, http.MethodPost, "/backup/schedules", request, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *Client) RemoveBackupSchedule(ctx context.Context, containerID int) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/backup/schedules/%d", containerID), nil, nil)
+}
+
+func (c *Client) RunBackupSchedule(ctx context.Context, containerID int) (*backup.Job, error) {
+	var result backup.Job
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/backup/schedules/%d/run", containerID), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, v interface{}) error {
+	return c.do(ctx, http.MethodGet, path, nil, v)
+}
+
+// do sends body as JSON, if set, and decodes a successful response into v,
+// if set.
+func (c *Client) do(ctx context.Context, method, path string, body, v interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("daemon request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("daemon returned %d: %s", resp.StatusCode, apiErr.Error)
+	}
+
+	if v == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode daemon response: %w", err)
+	}
+	return nil
+}
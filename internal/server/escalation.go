@@ -0,0 +1,61 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jbutlerdev/proxwarden/internal/audit"
+	"github.com/jbutlerdev/proxwarden/internal/notify"
+)
+
+// SetNotifier sets the notification dispatcher whose escalations are
+// listed and acknowledged.
+func (s *Server) SetNotifier(notifier *notify.Dispatcher) {
+	s.notifier = notifier
+}
+
+func (s *Server) handleEscalations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	escalations := s.notifier.Escalations()
+	if escalations == nil {
+		escalations = []notify.Escalation{}
+	}
+	writeJSON(w, http.StatusOK, escalations)
+}
+
+// handleEscalation acknowledges an escalation, as the client of the
+// request.
+func (s *Server) handleEscalation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	idPart, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, apiPrefix+"/escalations/"), "/")
+	if action != "ack" {
+		writeError(w, http.StatusNotFound, "expected /escalations/{id}/ack")
+		return
+	}
+	id, err := strconv.Atoi(idPart)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid escalation ID")
+		return
+	}
+
+	escalation, err := s.notifier.Acknowledge(id, audit.Actor(r.Context()))
+	if err != nil {
+		if errors.Is(err, notify.ErrEscalationNotFound) {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, escalation)
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+)
+
+// readinessTimeout bounds the Proxmox request made by each readiness check.
+const readinessTimeout = 5 * time.Second
+
+// SetProxmox sets the Proxmox client whose reachability readiness checks.
+func (s *Server) SetProxmox(client api.ProxmoxClient) {
+	s.proxmox = client
+}
+
+// Leadership reports whether this daemon is the one acting on the cluster,
+// when several run with leader election.
+type Leadership interface {
+	IsLeader() bool
+}
+
+// SetLeadership sets what reports whether this daemon is the leader.
+func (s *Server) SetLeadership(leadership Leadership) {
+	s.leadership = leadership
+}
+
+// handleHealthz reports that the daemon is alive, which it is whenever it
+// answers.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, Readiness{Status: "ok"})
+}
+
+// handleReadyz reports whether the daemon is doing its job: its
+// configuration is loaded, Proxmox answers and the monitoring loop runs. It
+// answers 503 when any of them is not.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	// The daemon only serves the API once its configuration is loaded
+	checks := map[string]string{"config": "ok"}
+
+	checks["proxmox"] = "ok"
+	if s.proxmox == nil {
+		checks["proxmox"] = "no client"
+	} else {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		_, err := s.proxmox.GetNodes(ctx)
+		cancel()
+		if err != nil {
+			checks["proxmox"] = err.Error()
+		}
+	}
+
+	checks["monitor"] = "ok"
+	switch {
+	case s.leadership != nil && !s.leadership.IsLeader():
+		// Standby daemons only monitor once they are elected
+		checks["monitor"] = "standby"
+	case !s.monitor.Running():
+		checks["monitor"] = "not running"
+	}
+
+	result := Readiness{Status: "ready", Checks: checks}
+	status := http.StatusOK
+	for _, check := range checks {
+		if check != "ok" {
+			result.Status = "not ready"
+			status = http.StatusServiceUnavailable
+		}
+	}
+	writeJSON(w, status, result)
+}
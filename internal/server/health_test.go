@@ -0,0 +1,205 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/monitor"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeProxmox answers GetNodes, the only call readiness checks make.
+type fakeProxmox struct {
+	api.ProxmoxClient
+	err error
+}
+
+func (f *fakeProxmox) GetNodes(ctx context.Context) ([]*api.NodeInfo, error) {
+	return nil, f.err
+}
+
+type leadership bool
+
+func (l leadership) IsLeader() bool { return bool(l) }
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	return logger
+}
+
+// newTestServer returns a server whose monitor runs, when running is set,
+// until the test ends.
+func newTestServer(t *testing.T, cfg *config.ServerConfig, running bool) *Server {
+	t.Helper()
+	monitorConfig := &config.Config{}
+	monitorConfig.Monitoring.Interval = time.Hour
+	mon := monitor.New(monitorConfig, nil, testLogger())
+
+	if running {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			mon.Start(ctx)
+		}()
+		t.Cleanup(func() {
+			cancel()
+			<-done
+		})
+
+		deadline := time.Now().Add(5 * time.Second)
+		for !mon.Running() {
+			if time.Now().After(deadline) {
+				t.Fatal("Timed out waiting for the monitor to start")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	return New(cfg, mon, nil, nil, testLogger())
+}
+
+func TestHandleHealthz(t *testing.T) {
+	tests := []struct {
+		method string
+		status int
+	}{
+		{http.MethodGet, http.StatusOK},
+		{http.MethodHead, http.StatusOK},
+		{http.MethodPost, http.StatusMethodNotAllowed},
+	}
+
+	// Alive even before the monitor runs
+	s := newTestServer(t, &config.ServerConfig{}, false)
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.Handler().ServeHTTP(w, httptest.NewRequest(tt.method, "/healthz", nil))
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, w.Code)
+			}
+		})
+	}
+}
+
+func TestHandleReadyz(t *testing.T) {
+	tests := []struct {
+		name       string
+		running    bool
+		proxmox    api.ProxmoxClient
+		leadership Leadership
+		status     int
+		checks     map[string]string
+	}{
+		{
+			name:    "ready",
+			running: true,
+			proxmox: &fakeProxmox{},
+			status:  http.StatusOK,
+			checks:  map[string]string{"config": "ok", "proxmox": "ok", "monitor": "ok"},
+		},
+		{
+			name:       "leader",
+			running:    true,
+			proxmox:    &fakeProxmox{},
+			leadership: leadership(true),
+			status:     http.StatusOK,
+			checks:     map[string]string{"config": "ok", "proxmox": "ok", "monitor": "ok"},
+		},
+		{
+			name:       "not the leader",
+			running:    true,
+			proxmox:    &fakeProxmox{},
+			leadership: leadership(false),
+			status:     http.StatusServiceUnavailable,
+			checks:     map[string]string{"config": "ok", "proxmox": "ok", "monitor": "standby"},
+		},
+		{
+			name:    "monitor not started",
+			proxmox: &fakeProxmox{},
+			status:  http.StatusServiceUnavailable,
+			checks:  map[string]string{"config": "ok", "proxmox": "ok", "monitor": "not running"},
+		},
+		{
+			name:    "proxmox unreachable",
+			running: true,
+			proxmox: &fakeProxmox{err: errors.New("connection refused")},
+			status:  http.StatusServiceUnavailable,
+			checks:  map[string]string{"config": "ok", "proxmox": "connection refused", "monitor": "ok"},
+		},
+		{
+			name:    "no proxmox client",
+			running: true,
+			status:  http.StatusServiceUnavailable,
+			checks:  map[string]string{"config": "ok", "proxmox": "no client", "monitor": "ok"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, &config.ServerConfig{}, tt.running)
+			if tt.proxmox != nil {
+				s.SetProxmox(tt.proxmox)
+			}
+			if tt.leadership != nil {
+				s.SetLeadership(tt.leadership)
+			}
+
+			w := httptest.NewRecorder()
+			s.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, w.Code)
+			}
+
+			var readiness Readiness
+			if err := json.NewDecoder(w.Body).Decode(&readiness); err != nil {
+				t.Fatalf("Failed to decode readiness: %v", err)
+			}
+			expected := "ready"
+			if tt.status != http.StatusOK {
+				expected = "not ready"
+			}
+			if readiness.Status != expected {
+				t.Errorf("Expected status %q, got %q", expected, readiness.Status)
+			}
+			if !reflect.DeepEqual(readiness.Checks, tt.checks) {
+				t.Errorf("Expected checks %v, got %v", tt.checks, readiness.Checks)
+			}
+		})
+	}
+}
+
+func TestHealthAuthBypass(t *testing.T) {
+	cfg := &config.ServerConfig{}
+	cfg.Auth.APIKeys = []config.APIKeyConfig{{Name: "ops", Key: "secret", Role: config.RoleAdmin}}
+	s := newTestServer(t, cfg, true)
+	s.SetProxmox(&fakeProxmox{})
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/healthz", http.StatusOK},
+		{"/readyz", http.StatusOK},
+		{apiPrefix + "/containers", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			// No token
+			w := httptest.NewRecorder()
+			s.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d without a token, got %d", tt.status, w.Code)
+			}
+		})
+	}
+}
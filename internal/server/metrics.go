@@ -0,0 +1,149 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/catalog"
+	"github.com/jbutlerdev/proxwarden/internal/metrics"
+	"github.com/jbutlerdev/proxwarden/internal/monitor"
+)
+
+// containerStates are the states written for every container, so that each
+// has one series per state.
+var containerStates = []monitor.State{
+	monitor.StateUnknown,
+	monitor.StateHealthy,
+	monitor.StateDegraded,
+	monitor.StateFailing,
+	monitor.StateFailoverInProgress,
+	monitor.StateFailedOver,
+	monitor.StateMaintenance,
+}
+
+// SetCatalog sets the backup catalog whose newest backups are reported as
+// backup ages.
+func (s *Server) SetCatalog(store *catalog.Store) {
+	s.catalog = store
+}
+
+// handleMetrics writes the daemon's counters and the current state of its
+// containers, nodes and backups in the Prometheus text format.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	w.Header().Set("Content-Type", metrics.ContentType)
+	metrics.Write(w)
+
+	states := s.monitor.GetAllStates()
+	ids := make([]int, 0, len(states))
+	for id := range states {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var stateSamples, healthySamples, failureSamples, latencySamples []metrics.Sample
+	for _, id := range ids {
+		status := s.containerStatus(states[id])
+		containerID := strconv.Itoa(id)
+		for _, state := range containerStates {
+			stateSamples = append(stateSamples, metrics.Sample{
+				Labels: []string{"container_id", containerID, "name", status.Name, "state", string(state)},
+				Value:  boolValue(status.State == string(state)),
+			})
+		}
+		healthySamples = append(healthySamples, metrics.Sample{
+			Labels: []string{"container_id", containerID, "name", status.Name},
+			Value:  boolValue(states[id].State == monitor.StateHealthy || states[id].State == monitor.StateDegraded),
+		})
+		failureSamples = append(failureSamples, metrics.Sample{
+			Labels: []string{"container_id", containerID, "name", status.Name},
+			Value:  float64(states[id].FailureCount),
+		})
+		for _, result := range states[id].HealthResults {
+			if result == nil || result.Skipped {
+				continue
+			}
+			latencySamples = append(latencySamples, metrics.Sample{
+				Labels: []string{"container_id", containerID, "type", result.Type, "target", result.Target},
+				Value:  result.Duration.Seconds(),
+			})
+		}
+	}
+	metrics.WriteGauge(w, "proxwarden_container_state",
+		"Current state of monitored containers, 1 for the state they are in.", stateSamples)
+	metrics.WriteGauge(w, "proxwarden_container_healthy",
+		"Whether monitored containers passed their critical health checks, in or out of maintenance.", healthySamples)
+	metrics.WriteGauge(w, "proxwarden_container_consecutive_failures",
+		"Consecutive failed health checks of monitored containers.", failureSamples)
+	metrics.WriteGauge(w, "proxwarden_container_check_latency_seconds",
+		"Duration of the last run of each health check.", latencySamples)
+
+	nodes := s.monitor.GetNodeStates()
+	names := make([]string, 0, len(nodes))
+	for name := range nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	nodeSamples := make([]metrics.Sample, 0, len(names))
+	for _, name := range names {
+		nodeSamples = append(nodeSamples, metrics.Sample{
+			Labels: []string{"node", name},
+			Value:  boolValue(nodes[name].Online),
+		})
+	}
+	metrics.WriteGauge(w, "proxwarden_node_online", "Whether Proxmox nodes were online when last polled.", nodeSamples)
+
+	if s.catalog != nil {
+		s.writeBackupAges(w, ids)
+	}
+	if s.leadership != nil {
+		metrics.WriteGauge(w, "proxwarden_leader", "Whether this daemon is the one acting on the cluster.",
+			[]metrics.Sample{{Value: boolValue(s.leadership.IsLeader())}})
+	}
+}
+
+// writeBackupAges writes the age of the newest catalogued backup of each
+// monitored container that has one.
+func (s *Server) writeBackupAges(w http.ResponseWriter, ids []int) {
+	entries, err := s.catalog.List(catalog.Filter{})
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to read backup catalog for metrics")
+		return
+	}
+
+	// Entries are listed newest first
+	newest := make(map[int]time.Time)
+	for _, entry := range entries {
+		if _, ok := newest[entry.ContainerID]; !ok {
+			newest[entry.ContainerID] = entry.Created
+		}
+	}
+
+	now := time.Now()
+	var samples []metrics.Sample
+	for _, id := range ids {
+		created, ok := newest[id]
+		if !ok {
+			continue
+		}
+		samples = append(samples, metrics.Sample{
+			Labels: []string{"container_id", strconv.Itoa(id)},
+			Value:  now.Sub(created).Seconds(),
+		})
+	}
+	metrics.WriteGauge(w, "proxwarden_backup_age_seconds",
+		"Age of the newest catalogued backup of monitored containers.", samples)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,432 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/api"
+	"github.com/jbutlerdev/proxwarden/internal/backup"
+	"github.com/jbutlerdev/proxwarden/internal/catalog"
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	"github.com/jbutlerdev/proxwarden/internal/failover"
+	"github.com/jbutlerdev/proxwarden/internal/maintenance"
+	"github.com/jbutlerdev/proxwarden/internal/monitor"
+	"github.com/jbutlerdev/proxwarden/internal/notify"
+	"github.com/sirupsen/logrus"
+)
+
+const apiPrefix = "/api/v1"
+
+// Server exposes daemon state over a local HTTP API for CLI commands.
+type Server struct {
+	config      *config.ServerConfig
+	monitor     *monitor.Monitor
+	maintenance *maintenance.Manager
+	engine      *failover.Engine
+	catalog     *catalog.Store
+	backups     *backup.Scheduler
+	proxmox     api.ProxmoxClient
+	leadership  Leadership
+	notifier    *notify.Dispatcher
+	logger      *logrus.Logger
+	mux         *http.ServeMux
+}
+
+func New(cfg *config.ServerConfig, mon *monitor.Monitor, maint *maintenance.Manager, engine *failover.Engine, logger *logrus.Logger) *Server {
+	s := &Server{
+		config:      cfg,
+		monitor:     mon,
+		maintenance: maint,
+		engine:      engine,
+		logger:      logger,
+		mux:         http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc(apiPrefix+"/containers", s.handleContainers)
+	s.mux.HandleFunc(apiPrefix+"/containers/", s.handleContainer)
+	s.mux.HandleFunc(apiPrefix+"/maintenance", s.handleMaintenance)
+	s.mux.HandleFunc(apiPrefix+"/maintenance/", s.handleMaintenanceTarget)
+	s.mux.HandleFunc(apiPrefix+"/pause", s.handlePause)
+	s.mux.HandleFunc(apiPrefix+"/resume", s.handleResume)
+	s.mux.HandleFunc(apiPrefix+"/events", s.handleEvents)
+	s.mux.HandleFunc(apiPrefix+"/failovers", s.handleFailovers)
+	s.mux.HandleFunc(apiPrefix+"/failovers/", s.handleFailover)
+	s.mux.HandleFunc(apiPrefix+"/failovers/interrupted", s.handleInterrupted)
+	s.mux.HandleFunc(apiPrefix+"/escalations", s.handleEscalations)
+	s.mux.HandleFunc(apiPrefix+"/escalations/", s.handleEscalation)
+	s.mux.HandleFunc(apiPrefix+"/backup/schedules", s.handleBackupSchedules)
+	s.mux.HandleFunc(apiPrefix+"/backup/schedules/", s.handleBackupSchedule)
+	s.mux.HandleFunc("/metrics", s.handleMetrics)
+	s.mux.HandleFunc("/healthz", s.handleHealthz)
+	s.mux.HandleFunc("/readyz", s.handleReadyz)
+
+	return s
+}
+
+func (s *Server) Handler() http.Handler {
+	return s.audited(s.authorize(s.mux))
+}
+
+// Start serves the API, and metrics and health endpoints on their own
+// address if one is set, until ctx is cancelled.
+func (s *Server) Start(ctx context.Context) error {
+	tlsConfig, err := s.tlsConfig()
+	if err != nil {
+		return err
+	}
+
+	if s.config.MetricsListen != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.HandleFunc("/metrics", s.handleMetrics)
+		metricsMux.HandleFunc("/healthz", s.handleHealthz)
+		metricsMux.HandleFunc("/readyz", s.handleReadyz)
+		go func() {
+			s.logger.WithField("listen", s.config.MetricsListen).Info("Starting metrics server")
+			if err := serve(ctx, s.config.MetricsListen, s.authorize(metricsMux), tlsConfig); err != nil {
+				s.logger.WithError(err).Error("Metrics server failed")
+			}
+		}()
+	}
+
+	s.logger.WithField("listen", s.config.Listen).Info("Starting API server")
+	return serve(ctx, s.config.Listen, s.Handler(), tlsConfig)
+}
+
+func serve(ctx context.Context, addr string, handler http.Handler, tlsConfig *tls.Config) error {
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: 10 * time.Second,
+		// Streaming requests end with the server rather than outliving it
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	var err error
+	if tlsConfig != nil {
+		// The certificate is already loaded into the TLS configuration
+		err = httpServer.ListenAndServeTLS("", "")
+	} else {
+		err = httpServer.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) handleContainers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	states := s.monitor.GetAllStates()
+	result := make([]ContainerStatus, 0, len(states))
+	for _, state := range states {
+		result = append(result, s.containerStatus(state))
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleContainer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, apiPrefix+"/containers/"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid container ID")
+		return
+	}
+
+	state, exists := s.monitor.GetContainerState(id)
+	if !exists {
+		writeError(w, http.StatusNotFound, "container not monitored")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.containerStatus(state))
+}
+
+func (s *Server) containerStatus(state *monitor.ContainerState) ContainerStatus {
+	status := newContainerStatus(state)
+	// Maintenance applies immediately, before the next check updates the state
+	if window, ok := s.maintenance.Container(state.ID, state.Node); ok {
+		status.Maintenance = &window
+		status.State = string(monitor.StateMaintenance)
+		status.Health = string(monitor.StateMaintenance)
+	}
+	return status
+}
+
+func (s *Server) handleMaintenance(w http.ResponseWriter, r *http.Request) {
+	if s.maintenance == nil {
+		writeError(w, http.StatusServiceUnavailable, "maintenance not available")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		windows := s.maintenance.Active()
+		if windows == nil {
+			windows = []maintenance.Window{}
+		}
+		writeJSON(w, http.StatusOK, windows)
+
+	case http.MethodPost:
+		var req MaintenanceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+
+		var duration time.Duration
+		if req.Duration != "" {
+			var err error
+			duration, err = time.ParseDuration(req.Duration)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid duration")
+				return
+			}
+		}
+
+		window, err := s.maintenance.Enable(req.Kind, req.Target, duration, req.Reason)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		s.logger.WithFields(logrus.Fields{
+			"kind":    window.Kind,
+			"target":  window.Target,
+			"expires": window.Expires,
+			"reason":  window.Reason,
+		}).Info("Maintenance enabled")
+		writeJSON(w, http.StatusCreated, window)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) handleMaintenanceTarget(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.maintenance == nil {
+		writeError(w, http.StatusServiceUnavailable, "maintenance not available")
+		return
+	}
+
+	kind, target, found := strings.Cut(strings.TrimPrefix(r.URL.Path, apiPrefix+"/maintenance/"), "/")
+	if !found || target == "" {
+		writeError(w, http.StatusBadRequest, "expected /maintenance/{kind}/{target}")
+		return
+	}
+
+	disabled, err := s.maintenance.Disable(maintenance.Kind(kind), target)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !disabled {
+		writeError(w, http.StatusNotFound, "no active maintenance")
+		return
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"kind":   kind,
+		"target": target,
+	}).Info("Maintenance disabled")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handlePause(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, newPauseStatus(s.monitor.GetPauseState()))
+
+	case http.MethodPost:
+		var req PauseRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid request body")
+				return
+			}
+		}
+		s.monitor.Pause(req.Reason)
+		writeJSON(w, http.StatusOK, newPauseStatus(s.monitor.GetPauseState()))
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	s.monitor.Resume()
+	writeJSON(w, http.StatusOK, newPauseStatus(s.monitor.GetPauseState()))
+}
+
+func (s *Server) handleFailovers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.engine == nil {
+		writeError(w, http.StatusServiceUnavailable, "failover not available")
+		return
+	}
+
+	pending := make(map[int]*failover.PendingFailover)
+	awaiting := s.engine.Pending()
+	for i := range awaiting {
+		pending[awaiting[i].ContainerID] = &awaiting[i]
+	}
+
+	inFlight := s.engine.InFlight()
+	result := make([]FailoverStatus, 0, len(inFlight))
+	for _, running := range inFlight {
+		result = append(result, newFailoverStatus(running, pending[running.ContainerID]))
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleInterrupted lists the failovers a restart of the daemon left
+// unfinished.
+func (s *Server) handleInterrupted(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.engine == nil {
+		writeError(w, http.StatusServiceUnavailable, "failover not available")
+		return
+	}
+
+	entries, err := s.engine.Interrupted()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	result := make([]InterruptedFailover, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, newInterruptedFailover(entry))
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleFailover(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.engine == nil {
+		writeError(w, http.StatusServiceUnavailable, "failover not available")
+		return
+	}
+
+	idPart, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, apiPrefix+"/failovers/"), "/")
+	var apply func(int) error
+	switch action {
+	case "cancel":
+		apply = s.engine.Cancel
+	case "approve":
+		apply = s.engine.Approve
+	case "reject":
+		apply = s.engine.Reject
+	case "resume":
+		apply = s.engine.Resume
+	case "discard":
+		apply = s.engine.Discard
+	default:
+		writeError(w, http.StatusNotFound, "expected /failovers/{id}/cancel, approve, reject, resume or discard")
+		return
+	}
+	id, err := strconv.Atoi(idPart)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid container ID")
+		return
+	}
+
+	if err := apply(id); err != nil {
+		if errors.Is(err, failover.ErrNotInFlight) || errors.Is(err, failover.ErrNotPending) || errors.Is(err, failover.ErrNotInterrupted) {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleEvents streams monitor events as newline-delimited JSON until the
+// client disconnects.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	events := s.monitor.Subscribe()
+	defer s.monitor.Unsubscribe(events)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	encoder := json.NewEncoder(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := encoder.Encode(newEvent(event)); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
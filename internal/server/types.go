@@ -0,0 +1,275 @@
+package server
+
+import (
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/failover"
+	"github.com/jbutlerdev/proxwarden/internal/journal"
+	"github.com/jbutlerdev/proxwarden/internal/maintenance"
+	"github.com/jbutlerdev/proxwarden/internal/monitor"
+)
+
+// MaintenanceRequest enables maintenance for a container or node. Duration
+// uses Go duration syntax; empty means the configured default.
+type MaintenanceRequest struct {
+	Kind     maintenance.Kind `json:"kind"`
+	Target   string           `json:"target"`
+	Duration string           `json:"duration,omitempty"`
+	Reason   string           `json:"reason,omitempty"`
+}
+
+// BackupScheduleRequest schedules backups of a container, with a standard
+// cron expression.
+type BackupScheduleRequest struct {
+	ContainerID int    `json:"container_id"`
+	Schedule    string `json:"schedule"`
+}
+
+// PauseRequest pauses automatic failover cluster-wide.
+type PauseRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// PauseStatus reports whether automatic failover is paused.
+type PauseStatus struct {
+	Paused bool      `json:"paused"`
+	Since  time.Time `json:"since,omitempty"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+func newPauseStatus(state monitor.PauseState) PauseStatus {
+	return PauseStatus{
+		Paused: state.Paused,
+		Since:  state.Since,
+		Reason: state.Reason,
+	}
+}
+
+// Readiness is the answer of the liveness and readiness endpoints. Checks
+// maps each readiness check to "ok" or why it failed.
+type Readiness struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// FailoverStatus is a failover the daemon has queued or is running.
+type FailoverStatus struct {
+	ContainerID int       `json:"container_id"`
+	SourceNode  string    `json:"source_node"`
+	TargetNode  string    `json:"target_node"`
+	Trigger     string    `json:"trigger"`
+	Queued      bool      `json:"queued"`
+	Cancelled   bool      `json:"cancelled,omitempty"`
+	StartTime   time.Time `json:"start_time"`
+	// Approval is set while the failover awaits approval
+	Approval *ApprovalStatus `json:"approval,omitempty"`
+	// Step is the failover step running, empty before the failover starts
+	Step   string `json:"step,omitempty"`
+	Detail string `json:"detail,omitempty"`
+	// Percent is how far the step is, or -1 when unknown
+	Percent int `json:"percent"`
+}
+
+// ApprovalStatus is the approval a failover waits for.
+type ApprovalStatus struct {
+	ExpiresAt     time.Time  `json:"expires_at"`
+	AutoApproveAt *time.Time `json:"auto_approve_at,omitempty"`
+}
+
+func newFailoverStatus(inFlight failover.InFlightFailover, pending *failover.PendingFailover) FailoverStatus {
+	status := FailoverStatus{
+		ContainerID: inFlight.ContainerID,
+		SourceNode:  inFlight.SourceNode,
+		TargetNode:  inFlight.TargetNode,
+		Trigger:     inFlight.Trigger,
+		Queued:      inFlight.Queued,
+		Cancelled:   inFlight.Cancelled,
+		StartTime:   inFlight.StartTime,
+		Step:        inFlight.Progress.Step,
+		Detail:      inFlight.Progress.Detail,
+		Percent:     inFlight.Progress.Percent,
+	}
+	if status.Step == "" {
+		status.Percent = -1
+	}
+	if pending != nil {
+		status.Approval = &ApprovalStatus{ExpiresAt: pending.ExpiresAt}
+		if !pending.AutoApproveAt.IsZero() {
+			status.Approval.AutoApproveAt = &pending.AutoApproveAt
+		}
+	}
+	return status
+}
+
+// InterruptedFailover is a failover a restart of the daemon left unfinished,
+// awaiting an operator to resume or discard it.
+type InterruptedFailover struct {
+	ContainerID   int       `json:"container_id"`
+	ContainerName string    `json:"container_name,omitempty"`
+	Trigger       string    `json:"trigger"`
+	Strategy      string    `json:"strategy,omitempty"`
+	SourceNode    string    `json:"source_node"`
+	TargetNode    string    `json:"target_node"`
+	Step          string    `json:"step"`
+	StartTime     time.Time `json:"start_time"`
+	InterruptedAt time.Time `json:"interrupted_at"`
+}
+
+func newInterruptedFailover(entry journal.Entry) InterruptedFailover {
+	return InterruptedFailover{
+		ContainerID:   entry.ContainerID,
+		ContainerName: entry.ContainerName,
+		Trigger:       entry.Trigger,
+		Strategy:      entry.Strategy,
+		SourceNode:    entry.SourceNode,
+		TargetNode:    entry.TargetNode,
+		Step:          entry.Step,
+		StartTime:     entry.StartTime,
+		InterruptedAt: entry.Updated,
+	}
+}
+
+// Event is a monitor event as streamed by /events, one JSON object per line.
+type Event struct {
+	Type         string       `json:"type"`
+	ContainerID  int          `json:"container_id,omitempty"`
+	Node         string       `json:"node,omitempty"`
+	Timestamp    time.Time    `json:"timestamp"`
+	From         string       `json:"from,omitempty"`
+	To           string       `json:"to,omitempty"`
+	Check        *CheckResult `json:"check,omitempty"`
+	ContainerIDs []int        `json:"container_ids,omitempty"`
+}
+
+// CheckResult is the outcome of a single health check run.
+type CheckResult struct {
+	Type     string        `json:"type"`
+	Target   string        `json:"target"`
+	Severity string        `json:"severity"`
+	Success  bool          `json:"success"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+func newEvent(event monitor.Event) Event {
+	result := Event{
+		Type:         string(event.Type),
+		ContainerID:  event.ContainerID,
+		Node:         event.Node,
+		Timestamp:    event.Timestamp,
+		From:         string(event.From),
+		To:           string(event.To),
+		ContainerIDs: event.ContainerIDs,
+	}
+	if event.Result != nil {
+		result.Check = &CheckResult{
+			Type:     event.Result.Type,
+			Target:   event.Result.Target,
+			Severity: event.Result.Severity,
+			Success:  event.Result.Success,
+			Duration: event.Result.Duration,
+		}
+		if event.Result.Error != nil {
+			result.Check.Error = event.Result.Error.Error()
+		}
+	}
+	return result
+}
+
+// ContainerStatus is the API representation of a monitored container.
+type ContainerStatus struct {
+	ID              int                 `json:"id"`
+	Name            string              `json:"name"`
+	Node            string              `json:"node"`
+	Status          string              `json:"status"`
+	State           string              `json:"state"`
+	Health          string              `json:"health"`
+	FailureCount    int                 `json:"failure_count"`
+	HealthyCount    int                 `json:"healthy_count"`
+	Warnings        int                 `json:"warnings"`
+	SkippedReason   string              `json:"skipped_reason,omitempty"`
+	Flapping        bool                `json:"flapping,omitempty"`
+	BackedOff       bool                `json:"backed_off,omitempty"`
+	Maintenance     *maintenance.Window `json:"maintenance,omitempty"`
+	LastSeen        time.Time           `json:"last_seen"`
+	LastHealthCheck time.Time           `json:"last_health_check,omitempty"`
+	Checks          []CheckStatus       `json:"checks,omitempty"`
+}
+
+// CheckStatus combines the latest result of a health check with statistics
+// over its recent history.
+type CheckStatus struct {
+	Type              string        `json:"type"`
+	Target            string        `json:"target"`
+	Severity          string        `json:"severity"`
+	Success           bool          `json:"success"`
+	Skipped           bool          `json:"skipped,omitempty"`
+	Error             string        `json:"error,omitempty"`
+	Duration          time.Duration `json:"duration"`
+	Samples           int           `json:"samples"`
+	SuccessRate       float64       `json:"success_rate"`
+	P95Latency        time.Duration `json:"p95_latency"`
+	LastFailure       time.Time     `json:"last_failure,omitempty"`
+	LastFailureReason string        `json:"last_failure_reason,omitempty"`
+}
+
+func newContainerStatus(state *monitor.ContainerState) ContainerStatus {
+	status := ContainerStatus{
+		ID:              state.ID,
+		Name:            state.Name,
+		Node:            state.Node,
+		Status:          state.Status,
+		State:           string(state.State),
+		FailureCount:    state.FailureCount,
+		HealthyCount:    state.HealthyCount,
+		Warnings:        state.ActiveWarnings,
+		SkippedReason:   state.SkippedReason,
+		Flapping:        state.Flapping,
+		BackedOff:       state.BackedOff,
+		LastSeen:        state.LastSeen,
+		LastHealthCheck: state.LastHealthCheck,
+	}
+
+	// Health is the state, unless checks are skipped or the container flaps
+	switch {
+	case state.SkippedReason != "":
+		status.Health = "skipped"
+	case state.Flapping:
+		status.Health = "flapping"
+	default:
+		status.Health = string(state.State)
+	}
+
+	for i, stats := range state.CheckStats {
+		check := CheckStatus{
+			Type:              stats.Type,
+			Target:            stats.Target,
+			Samples:           stats.Samples,
+			SuccessRate:       stats.SuccessRate,
+			P95Latency:        stats.P95Latency,
+			LastFailure:       stats.LastFailure,
+			LastFailureReason: stats.LastFailureReason,
+		}
+		if stats.LastResult != nil {
+			check.Severity = stats.LastResult.Severity
+			check.Success = stats.LastResult.Success
+			check.Duration = stats.LastResult.Duration
+			if stats.LastResult.Error != nil {
+				check.Error = stats.LastResult.Error.Error()
+			}
+		}
+		// History only holds checks that ran, so take skips from the latest results
+		if i < len(state.HealthResults) && state.HealthResults[i] != nil && state.HealthResults[i].Skipped {
+			skipped := state.HealthResults[i]
+			check.Type = skipped.Type
+			check.Target = skipped.Target
+			check.Severity = skipped.Severity
+			check.Success = false
+			check.Skipped = true
+			check.Error = skipped.Error.Error()
+		}
+		status.Checks = append(status.Checks, check)
+	}
+
+	return status
+}
@@ -0,0 +1,148 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// The files of the file backend, imported into a new database.
+const (
+	historyFile = "failover-history.jsonl"
+	catalogFile = "backup-catalog.json"
+	journalFile = "failover-journal.json"
+	auditFile   = "audit.jsonl"
+)
+
+// migrations bring the database from each schema version to the next, the
+// first from an empty database. They are only ever appended to.
+var migrations = []func(tx *Tx, dataDir string) error{
+	createBuckets,
+	importFiles,
+}
+
+func (db *DB) migrate() error {
+	return db.Update(func(tx *Tx) error {
+		meta, err := tx.tx.CreateBucketIfNotExists([]byte(bucketMeta))
+		if err != nil {
+			return fmt.Errorf("failed to create store metadata: %w", err)
+		}
+
+		version := 0
+		if data := meta.Get([]byte("version")); data != nil {
+			if version, err = strconv.Atoi(string(data)); err != nil {
+				return fmt.Errorf("invalid store schema version %q", data)
+			}
+		}
+		if version > len(migrations) {
+			return fmt.Errorf("store schema version %d is newer than the supported %d", version, len(migrations))
+		}
+
+		for i := version; i < len(migrations); i++ {
+			if err := migrations[i](tx, db.dataDir); err != nil {
+				return fmt.Errorf("failed to migrate store to schema version %d: %w", i+1, err)
+			}
+		}
+		return meta.Put([]byte("version"), []byte(strconv.Itoa(len(migrations))))
+	})
+}
+
+func createBuckets(tx *Tx, _ string) error {
+	for _, name := range []string{BucketHistory, BucketCatalog, BucketJournal, BucketAudit, BucketMonitor} {
+		if _, err := tx.tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importFiles copies the records the file backend kept in dataDir. The
+// files are left in place but no longer written.
+func importFiles(tx *Tx, dataDir string) error {
+	if dataDir == "" {
+		return nil
+	}
+	if err := importLines(tx, BucketHistory, filepath.Join(dataDir, historyFile)); err != nil {
+		return err
+	}
+	if err := importLines(tx, BucketAudit, filepath.Join(dataDir, auditFile)); err != nil {
+		return err
+	}
+	err := importList(tx, BucketCatalog, filepath.Join(dataDir, catalogFile), func(raw json.RawMessage) (string, error) {
+		var entry struct {
+			VolID string `json:"volid"`
+		}
+		err := json.Unmarshal(raw, &entry)
+		return entry.VolID, err
+	})
+	if err != nil {
+		return err
+	}
+	return importList(tx, BucketJournal, filepath.Join(dataDir, journalFile), func(raw json.RawMessage) (string, error) {
+		var entry struct {
+			ContainerID int `json:"container_id"`
+		}
+		err := json.Unmarshal(raw, &entry)
+		return strconv.Itoa(entry.ContainerID), err
+	})
+}
+
+// importLines appends every line of a file of JSON lines to bucket,
+// skipping those that cannot be decoded.
+func importLines(tx *Tx, bucket, path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if !json.Valid(scanner.Bytes()) {
+			continue
+		}
+		line := append(json.RawMessage(nil), scanner.Bytes()...)
+		if err := tx.Append(bucket, line); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return nil
+}
+
+// importList puts every value of a file holding a JSON list in bucket,
+// under the key key returns for it.
+func importList(tx *Tx, bucket, path string, key func(raw json.RawMessage) (string, error)) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var list []json.RawMessage
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	for _, raw := range list {
+		k, err := key(raw)
+		if err != nil {
+			return fmt.Errorf("failed to decode %s: %w", path, err)
+		}
+		if err := tx.Put(bucket, k, raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
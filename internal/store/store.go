@@ -0,0 +1,186 @@
+// Package store keeps the records ProxWarden must remember across daemon
+// restarts in a single embedded bolt database, instead of a file of each in
+// the data directory.
+package store
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jbutlerdev/proxwarden/internal/config"
+	bolt "go.etcd.io/bbolt"
+)
+
+// FileName is the name of the database within the data directory.
+const FileName = "proxwarden.db"
+
+// Buckets of the database, one for each kind of record.
+const (
+	BucketHistory = "history"
+	BucketCatalog = "catalog"
+	BucketJournal = "journal"
+	BucketAudit   = "audit"
+	BucketMonitor = "monitor"
+	bucketMeta    = "meta"
+)
+
+// lockTimeout bounds how long a transaction waits for another process to
+// finish with the database.
+const lockTimeout = 10 * time.Second
+
+// txMu serializes the transactions of the process, which would otherwise
+// wait for each other's file lock by polling it.
+var txMu sync.Mutex
+
+// DB is a bolt database. Bolt locks the file while it is open, so it is
+// opened for each transaction only, letting the daemon and CLI commands
+// share it.
+type DB struct {
+	path    string
+	dataDir string
+}
+
+// Open opens the database at path, creating it if needed, and migrates it
+// to the current schema. The records of the file backend found in dataDir
+// are imported when the database is created.
+func Open(path, dataDir string) (*DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+	db := &DB{path: path, dataDir: dataDir}
+	if err := db.migrate(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Path returns the database cfg sets, or "" with the file backend.
+func Path(cfg *config.Config) string {
+	switch {
+	case cfg.Store.Backend != config.StoreBolt:
+		return ""
+	case cfg.Store.File != "":
+		return cfg.Store.File
+	default:
+		return filepath.Join(cfg.DataDir, FileName)
+	}
+}
+
+// OpenConfig opens the database cfg sets. It returns nil with the file
+// backend.
+func OpenConfig(cfg *config.Config) (*DB, error) {
+	path := Path(cfg)
+	if path == "" {
+		return nil, nil
+	}
+	return Open(path, cfg.DataDir)
+}
+
+// Update runs fn in a read-write transaction, committed if fn returns nil.
+func (db *DB) Update(fn func(tx *Tx) error) error {
+	txMu.Lock()
+	defer txMu.Unlock()
+
+	bdb, err := db.open()
+	if err != nil {
+		return err
+	}
+	defer bdb.Close()
+	return bdb.Update(func(tx *bolt.Tx) error {
+		return fn(&Tx{tx: tx})
+	})
+}
+
+// View runs fn in a read-only transaction.
+func (db *DB) View(fn func(tx *Tx) error) error {
+	txMu.Lock()
+	defer txMu.Unlock()
+
+	bdb, err := db.open()
+	if err != nil {
+		return err
+	}
+	defer bdb.Close()
+	return bdb.View(func(tx *bolt.Tx) error {
+		return fn(&Tx{tx: tx})
+	})
+}
+
+func (db *DB) open() (*bolt.DB, error) {
+	bdb, err := bolt.Open(db.path, 0o640, &bolt.Options{Timeout: lockTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store %s: %w", db.path, err)
+	}
+	return bdb, nil
+}
+
+// Tx is a transaction on the database. Values are stored as JSON.
+type Tx struct {
+	tx *bolt.Tx
+}
+
+func (t *Tx) bucket(name string) (*bolt.Bucket, error) {
+	bucket := t.tx.Bucket([]byte(name))
+	if bucket == nil {
+		return nil, fmt.Errorf("store has no %s bucket", name)
+	}
+	return bucket, nil
+}
+
+// Put sets the value of key.
+func (t *Tx) Put(bucket, key string, value interface{}) error {
+	b, err := t.bucket(bucket)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s %s: %w", bucket, key, err)
+	}
+	return b.Put([]byte(key), data)
+}
+
+// Append adds value after every value appended before it.
+func (t *Tx) Append(bucket string, value interface{}) error {
+	b, err := t.bucket(bucket)
+	if err != nil {
+		return err
+	}
+	seq, err := b.NextSequence()
+	if err != nil {
+		return fmt.Errorf("failed to append to %s: %w", bucket, err)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s entry: %w", bucket, err)
+	}
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return b.Put(key, data)
+}
+
+// ForEach calls fn with every value of bucket, in the order of their keys,
+// which is the order appended values were appended in.
+func (t *Tx) ForEach(bucket string, fn func(data []byte) error) error {
+	b, err := t.bucket(bucket)
+	if err != nil {
+		return err
+	}
+	return b.ForEach(func(_, data []byte) error {
+		return fn(data)
+	})
+}
+
+// Clear removes every value of bucket.
+func (t *Tx) Clear(bucket string) error {
+	if err := t.tx.DeleteBucket([]byte(bucket)); err != nil {
+		return fmt.Errorf("failed to clear %s: %w", bucket, err)
+	}
+	_, err := t.tx.CreateBucket([]byte(bucket))
+	return err
+}
@@ -0,0 +1,117 @@
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestOpen_ImportsFiles(t *testing.T) {
+	dataDir := t.TempDir()
+	files := map[string]string{
+		historyFile: `{"container_id":100,"trigger":"automatic"}` + "\n" + `{"container_id":101,"tri` + "\n" + `{"container_id":102,"trigger":"manual"}` + "\n",
+		catalogFile: `[{"volid":"local:backup/vzdump-lxc-100.tar.zst","container_id":100}]`,
+		journalFile: `[{"container_id":100,"step":"restore"}]`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dataDir, name), []byte(content), 0o640); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(dataDir, FileName)
+	db, err := Open(path, dataDir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	count := func(bucket string) int {
+		n := 0
+		err := db.View(func(tx *Tx) error {
+			return tx.ForEach(bucket, func([]byte) error {
+				n++
+				return nil
+			})
+		})
+		if err != nil {
+			t.Fatalf("ForEach(%s) error = %v", bucket, err)
+		}
+		return n
+	}
+
+	// The torn history line is skipped
+	if got := count(BucketHistory); got != 2 {
+		t.Errorf("history has %d records, want 2", got)
+	}
+	if got := count(BucketCatalog); got != 1 {
+		t.Errorf("catalog has %d entries, want 1", got)
+	}
+	if got := count(BucketJournal); got != 1 {
+		t.Errorf("journal has %d entries, want 1", got)
+	}
+	if got := count(BucketAudit); got != 0 {
+		t.Errorf("audit has %d entries, want 0", got)
+	}
+
+	// Files are imported once, when the database is created
+	if _, err := Open(path, dataDir); err != nil {
+		t.Fatalf("Open() again error = %v", err)
+	}
+	if got := count(BucketHistory); got != 2 {
+		t.Errorf("history has %d records after reopening, want 2", got)
+	}
+}
+
+func TestTx_AppendOrder(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), FileName), "")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	for i := 1; i <= 300; i++ {
+		err := db.Update(func(tx *Tx) error {
+			return tx.Append(BucketAudit, i)
+		})
+		if err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	want := 1
+	err = db.View(func(tx *Tx) error {
+		return tx.ForEach(BucketAudit, func(data []byte) error {
+			var got int
+			if err := json.Unmarshal(data, &got); err != nil {
+				return err
+			}
+			if got != want {
+				t.Errorf("value %d = %d, appended in another order", want, got)
+			}
+			want++
+			return nil
+		})
+	})
+	if err != nil || want != 301 {
+		t.Errorf("ForEach() read %d values, error = %v", want-1, err)
+	}
+}
+
+func TestOpen_NewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	db, err := Open(path, "")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	err = db.Update(func(tx *Tx) error {
+		return tx.tx.Bucket([]byte(bucketMeta)).Put([]byte("version"), []byte(strconv.Itoa(len(migrations)+1)))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Open(path, ""); err == nil {
+		t.Error("Open() of a database with a newer schema succeeded")
+	}
+}